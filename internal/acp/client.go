@@ -123,6 +123,8 @@ func (c *Client) SessionUpdate(ctx context.Context, params acp.SessionNotificati
 }
 
 // WriteTextFile handles file write requests from the agent.
+// No snapshot is taken: the CLI doesn't record conversations, so there is no
+// store to keep it in (see WebClient.WriteTextFile for recorded sessions).
 func (c *Client) WriteTextFile(ctx context.Context, params acp.WriteTextFileRequest) (acp.WriteTextFileResponse, error) {
 	if err := DefaultFileSystem.WriteTextFile(params.Path, params.Content); err != nil {
 		return acp.WriteTextFileResponse{}, err
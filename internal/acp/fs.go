@@ -2,6 +2,7 @@
 package acp
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -80,5 +81,44 @@ func (fs *OSFileSystem) WriteTextFile(path, content string) error {
 	return nil
 }
 
+// MaxSnapshotSize is the largest file content (in bytes) kept in a FileSnapshot.
+const MaxSnapshotSize = 1024 * 1024
+
+// FileSnapshot is the content of a file before and after a write.
+type FileSnapshot struct {
+	Path string
+	// Existed is false when the write created the file.
+	Existed bool
+	Before  string
+	After   string
+}
+
+// WriteTextFileWithSnapshot writes content to a text file using fsys, and returns
+// the content of the file before and after the write.
+// The snapshot is nil when the previous content could not be read or when either
+// version is larger than MaxSnapshotSize; the file is written regardless.
+func WriteTextFileWithSnapshot(fsys FileSystem, path, content string) (*FileSnapshot, error) {
+	snap := &FileSnapshot{Path: path, After: content}
+	before, err := fsys.ReadTextFile(path, nil, nil)
+	switch {
+	case err == nil:
+		snap.Existed = true
+		snap.Before = before
+	case errors.Is(err, os.ErrNotExist):
+		// The write creates the file
+	default:
+		snap = nil
+	}
+
+	if err := fsys.WriteTextFile(path, content); err != nil {
+		return nil, err
+	}
+
+	if snap != nil && (len(snap.Before) > MaxSnapshotSize || len(snap.After) > MaxSnapshotSize) {
+		snap = nil
+	}
+	return snap, nil
+}
+
 // DefaultFileSystem is the default FileSystem implementation using the real OS.
 var DefaultFileSystem FileSystem = &OSFileSystem{}
@@ -3,6 +3,7 @@ package acp
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -176,3 +177,40 @@ func TestDefaultFileSystem(t *testing.T) {
 		t.Errorf("DefaultFileSystem should be *OSFileSystem, got %T", DefaultFileSystem)
 	}
 }
+
+func TestWriteTextFileWithSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "test.txt")
+	fs := &OSFileSystem{}
+
+	// Creating a file
+	snap, err := WriteTextFileWithSnapshot(fs, path, "one\n")
+	if err != nil {
+		t.Fatalf("WriteTextFileWithSnapshot failed: %v", err)
+	}
+	if snap == nil || snap.Existed || snap.Before != "" || snap.After != "one\n" {
+		t.Errorf("snapshot = %+v, want a created file", snap)
+	}
+
+	// Overwriting it
+	snap, err = WriteTextFileWithSnapshot(fs, path, "two\n")
+	if err != nil {
+		t.Fatalf("WriteTextFileWithSnapshot failed: %v", err)
+	}
+	if snap == nil || !snap.Existed || snap.Before != "one\n" || snap.After != "two\n" {
+		t.Errorf("snapshot = %+v, want the previous content", snap)
+	}
+
+	// Large files are written without a snapshot
+	large := strings.Repeat("x", MaxSnapshotSize+1)
+	snap, err = WriteTextFileWithSnapshot(fs, path, large)
+	if err != nil {
+		t.Fatalf("WriteTextFileWithSnapshot failed: %v", err)
+	}
+	if snap != nil {
+		t.Error("snapshot should be nil for large files")
+	}
+	if b, _ := os.ReadFile(path); len(b) != len(large) {
+		t.Errorf("file size = %d, want %d", len(b), len(large))
+	}
+}
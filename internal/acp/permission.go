@@ -39,6 +39,24 @@ func AutoApprovePermission(options []acp.PermissionOption) acp.RequestPermission
 	}
 }
 
+// DenyPermission selects a reject option to deny a permission request,
+// preferring RejectOnce over RejectAlways so that the agent may ask again later.
+// If no reject option is available, it returns a cancelled response.
+func DenyPermission(options []acp.PermissionOption) acp.RequestPermissionResponse {
+	for _, kind := range []acp.PermissionOptionKind{acp.PermissionOptionKindRejectOnce, acp.PermissionOptionKindRejectAlways} {
+		for _, opt := range options {
+			if opt.Kind == kind {
+				return acp.RequestPermissionResponse{
+					Outcome: acp.RequestPermissionOutcome{
+						Selected: &acp.RequestPermissionOutcomeSelected{OptionId: opt.OptionId},
+					},
+				}
+			}
+		}
+	}
+	return CancelledPermissionResponse()
+}
+
 // CancelledPermissionResponse returns a cancelled permission response.
 func CancelledPermissionResponse() acp.RequestPermissionResponse {
 	return acp.RequestPermissionResponse{
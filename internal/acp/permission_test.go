@@ -85,3 +85,21 @@ func TestCancelledPermissionResponse(t *testing.T) {
 		t.Error("Selected should be nil")
 	}
 }
+
+func TestDenyPermission(t *testing.T) {
+	options := []acp.PermissionOption{
+		{OptionId: "allow-once", Name: "Allow Once", Kind: acp.PermissionOptionKindAllowOnce},
+		{OptionId: "reject-always", Name: "Never", Kind: acp.PermissionOptionKindRejectAlways},
+		{OptionId: "reject-once", Name: "Deny", Kind: acp.PermissionOptionKindRejectOnce},
+	}
+
+	resp := DenyPermission(options)
+	if resp.Outcome.Selected == nil || resp.Outcome.Selected.OptionId != "reject-once" {
+		t.Errorf("outcome = %+v, want reject-once selected", resp.Outcome)
+	}
+
+	resp = DenyPermission(options[:1])
+	if resp.Outcome.Cancelled == nil {
+		t.Error("expected Cancelled outcome without reject options")
+	}
+}
@@ -0,0 +1,464 @@
+package acp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/coder/acp-go-sdk"
+	"github.com/creack/pty"
+
+	"github.com/inercia/mitto/internal/runner"
+)
+
+// DefaultTerminalOutputByteLimit is the output retention limit used when the
+// agent does not specify outputByteLimit in terminal/create.
+const DefaultTerminalOutputByteLimit = 1024 * 1024
+
+// terminalDrainTimeout bounds how long we wait for the output reader to drain
+// after the process exits. Background children that inherited the PTY or pipes
+// can keep them open indefinitely, so we stop waiting after this grace period.
+const terminalDrainTimeout = time.Second
+
+// TerminalManagerConfig holds configuration for creating a TerminalManager.
+type TerminalManagerConfig struct {
+	// Runner is the optional restricted runner. When set and restricted, commands
+	// are started through runner.RunWithPipes so sandbox restrictions still apply.
+	// When nil (or an unrestricted "exec" runner), commands run directly in a PTY.
+	Runner *runner.Runner
+	// DefaultCwd is the working directory used when the request has no cwd.
+	DefaultCwd string
+	// Env holds extra environment variables applied to every terminal
+	// (e.g., MITTO_* variables). Per-request env takes precedence.
+	Env map[string]string
+	// IDPrefix is prepended to generated terminal IDs. Defaults to "term-".
+	IDPrefix string
+	// Logger for terminal lifecycle logging (optional).
+	Logger *slog.Logger
+	// OnOutput is called with each chunk of output as it is produced (optional).
+	OnOutput func(terminalID, chunk string)
+	// OnExit is called once when a terminal's command exits (optional).
+	OnExit func(terminalID string, status acp.TerminalExitStatus)
+}
+
+// TerminalManager implements TerminalHandler with real processes.
+//
+// Each terminal runs a command in a PTY (direct execution) or through the
+// restricted runner's pipes, buffers its output up to the ACP byte limit and
+// records the exit status once the command finishes.
+type TerminalManager struct {
+	config TerminalManagerConfig
+
+	mu        sync.Mutex
+	terminals map[string]*terminal
+	nextID    int
+	closed    bool
+}
+
+// Ensure TerminalManager implements TerminalHandler at compile time.
+var _ TerminalHandler = (*TerminalManager)(nil)
+
+// NewTerminalManager creates a new TerminalManager.
+func NewTerminalManager(config TerminalManagerConfig) *TerminalManager {
+	if config.IDPrefix == "" {
+		config.IDPrefix = "term-"
+	}
+	return &TerminalManager{
+		config:    config,
+		terminals: make(map[string]*terminal),
+	}
+}
+
+// terminal is a single command started by the TerminalManager.
+type terminal struct {
+	id      string
+	command string
+
+	// kill terminates the process (and its process group when possible).
+	kill func()
+	// cancel releases the runner context (nil for direct execution).
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	output     []byte
+	limit      int
+	truncated  bool
+	exitStatus *acp.TerminalExitStatus
+	done       chan struct{}
+}
+
+// append adds a chunk to the output buffer, truncating from the beginning to
+// stay within the byte limit. Truncation happens at a character boundary.
+func (t *terminal) append(chunk []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.output = append(t.output, chunk...)
+	if len(t.output) <= t.limit {
+		return
+	}
+	start := len(t.output) - t.limit
+	for start < len(t.output) && !utf8.RuneStart(t.output[start]) {
+		start++
+	}
+	t.output = append([]byte(nil), t.output[start:]...)
+	t.truncated = true
+}
+
+// snapshot returns the current output, truncation flag and exit status.
+func (t *terminal) snapshot() (string, bool, *acp.TerminalExitStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.output), t.truncated, t.exitStatus
+}
+
+// CreateTerminal starts a new command and returns its terminal ID immediately.
+func (m *TerminalManager) CreateTerminal(ctx context.Context, params acp.CreateTerminalRequest) (acp.CreateTerminalResponse, error) {
+	if params.Command == "" {
+		return acp.CreateTerminalResponse{}, fmt.Errorf("command is required")
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return acp.CreateTerminalResponse{}, fmt.Errorf("terminal manager is closed")
+	}
+	m.nextID++
+	id := m.config.IDPrefix + strconv.Itoa(m.nextID)
+	m.mu.Unlock()
+
+	limit := DefaultTerminalOutputByteLimit
+	if params.OutputByteLimit != nil && *params.OutputByteLimit >= 0 {
+		limit = *params.OutputByteLimit
+	}
+
+	t := &terminal{
+		id:      id,
+		command: params.Command,
+		limit:   limit,
+		done:    make(chan struct{}),
+	}
+
+	env := make(map[string]string, len(m.config.Env)+len(params.Env))
+	for k, v := range m.config.Env {
+		env[k] = v
+	}
+	for _, e := range params.Env {
+		env[e.Name] = e.Value
+	}
+	processEnv := MergeEnv(os.Environ(), env)
+
+	cwd := m.config.DefaultCwd
+	if params.Cwd != nil && *params.Cwd != "" {
+		cwd = *params.Cwd
+	}
+
+	var err error
+	if m.config.Runner != nil && m.config.Runner.IsRestricted() {
+		err = m.startWithRunner(t, params.Args, processEnv, cwd)
+	} else {
+		err = m.startDirect(t, params.Args, processEnv, cwd)
+	}
+	if err != nil {
+		return acp.CreateTerminalResponse{}, err
+	}
+
+	m.mu.Lock()
+	m.terminals[id] = t
+	m.mu.Unlock()
+
+	if m.config.Logger != nil {
+		m.config.Logger.Debug("terminal created",
+			"terminal_id", id,
+			"command", params.Command,
+			"args", len(params.Args),
+			"cwd", cwd)
+	}
+
+	return acp.CreateTerminalResponse{TerminalId: id}, nil
+}
+
+// startDirect runs the command in a PTY, falling back to plain pipes on
+// platforms where PTYs are not supported.
+func (m *TerminalManager) startDirect(t *terminal, args, env []string, cwd string) error {
+	cmd := exec.Command(t.command, args...)
+	cmd.Env = env
+	cmd.Dir = cwd
+
+	var reader io.ReadCloser
+	ptmx, err := pty.Start(cmd)
+	switch {
+	case err == nil:
+		reader = ptmx
+	case errors.Is(err, pty.ErrUnsupported):
+		// No PTY support: capture combined stdout/stderr through a pipe instead.
+		pr, pw, pipeErr := os.Pipe()
+		if pipeErr != nil {
+			return fmt.Errorf("terminal pipe error: %w", pipeErr)
+		}
+		cmd = exec.Command(t.command, args...)
+		cmd.Env = env
+		cmd.Dir = cwd
+		cmd.Stdout = pw
+		cmd.Stderr = pw
+		if attr := newProcessGroupSysProcAttr(); attr != nil {
+			cmd.SysProcAttr = attr
+		}
+		startErr := cmd.Start()
+		// The child holds its own copy of the write end; close ours so the
+		// reader sees EOF once the command (and its children) exit.
+		_ = pw.Close()
+		if startErr != nil {
+			_ = pr.Close()
+			return fmt.Errorf("failed to start terminal command: %w", startErr)
+		}
+		reader = pr
+	default:
+		return fmt.Errorf("failed to start terminal command: %w", err)
+	}
+
+	pid := cmd.Process.Pid
+	t.kill = func() { KillProcessGroup(pid) }
+
+	readDone := make(chan struct{})
+	go m.readOutput(t, reader, readDone)
+	go func() {
+		waitErr := cmd.Wait()
+		m.finish(t, waitErr, cmd.ProcessState, readDone)
+		_ = reader.Close()
+	}()
+	return nil
+}
+
+// startWithRunner runs the command through the restricted runner so that
+// sandbox restrictions apply. Stdout and stderr are merged into the output.
+func (m *TerminalManager) startWithRunner(t *terminal, args, env []string, cwd string) error {
+	r := m.config.Runner
+	command := t.command
+	if cwd != "" {
+		// The runners have no working directory option: change to it with a
+		// shell inside the sandbox, failing the command if it is not accessible.
+		command, args = "/bin/sh", append([]string{"-c", `cd -- "$0" && exec "$@"`, cwd, t.command}, args...)
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	stdin, stdout, stderr, wait, err := r.RunWithPipes(runCtx, command, args, env)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to start terminal command with runner: %w", err)
+	}
+	// Terminals are output-only in ACP; close stdin so commands reading it see EOF.
+	_ = stdin.Close()
+
+	t.cancel = cancel
+	t.kill = cancel
+
+	stdoutDone := make(chan struct{})
+	stderrDone := make(chan struct{})
+	go m.readOutput(t, stdout, stdoutDone)
+	go m.readOutput(t, stderr, stderrDone)
+
+	readDone := make(chan struct{})
+	go func() {
+		<-stdoutDone
+		<-stderrDone
+		close(readDone)
+	}()
+
+	go func() {
+		// Wait for the process before draining: background children that
+		// inherited the pipes can keep them open after it exits, so finish
+		// bounds the drain like for direct execution. The runner closes the
+		// pipes on wait, which also unblocks the readers.
+		waitErr := wait()
+		var state *os.ProcessState
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) {
+			state = exitErr.ProcessState
+		}
+		m.finish(t, waitErr, state, readDone)
+		cancel()
+	}()
+	return nil
+}
+
+// readOutput copies process output into the terminal buffer and notifies OnOutput.
+func (m *TerminalManager) readOutput(t *terminal, r io.Reader, done chan struct{}) {
+	defer close(done)
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			t.append(chunk)
+			if m.config.OnOutput != nil {
+				m.config.OnOutput(t.id, string(chunk))
+			}
+		}
+		if err != nil {
+			// EIO is returned by the PTY master once the child side closes.
+			return
+		}
+	}
+}
+
+// finish records the exit status once the process exits and output is drained.
+func (m *TerminalManager) finish(t *terminal, waitErr error, state *os.ProcessState, readDone <-chan struct{}) {
+	select {
+	case <-readDone:
+	case <-time.After(terminalDrainTimeout):
+	}
+
+	status := exitStatusFrom(waitErr, state)
+
+	t.mu.Lock()
+	t.exitStatus = &status
+	t.mu.Unlock()
+	close(t.done)
+
+	if m.config.Logger != nil {
+		attrs := []any{"terminal_id", t.id, "command", t.command}
+		if status.ExitCode != nil {
+			attrs = append(attrs, "exit_code", *status.ExitCode)
+		}
+		if status.Signal != nil {
+			attrs = append(attrs, "signal", *status.Signal)
+		}
+		m.config.Logger.Debug("terminal exited", attrs...)
+	}
+
+	if m.config.OnExit != nil {
+		m.config.OnExit(t.id, status)
+	}
+}
+
+// exitStatusFrom converts a process state into an ACP exit status.
+// Signal-terminated processes report the signal and no exit code.
+func exitStatusFrom(waitErr error, state *os.ProcessState) acp.TerminalExitStatus {
+	if state != nil {
+		if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			sig := ws.Signal().String()
+			return acp.TerminalExitStatus{Signal: &sig}
+		}
+		code := state.ExitCode()
+		if code >= 0 {
+			return acp.TerminalExitStatus{ExitCode: &code}
+		}
+	}
+	// No process state available (e.g., runner context cancelled).
+	code := 0
+	if waitErr != nil {
+		code = -1
+		if errors.Is(waitErr, context.Canceled) {
+			sig := syscall.SIGKILL.String()
+			return acp.TerminalExitStatus{Signal: &sig}
+		}
+	}
+	return acp.TerminalExitStatus{ExitCode: &code}
+}
+
+// get returns the terminal with the given ID.
+func (m *TerminalManager) get(id string) (*terminal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.terminals[id]
+	if !ok {
+		return nil, fmt.Errorf("terminal not found: %s", id)
+	}
+	return t, nil
+}
+
+// TerminalOutput returns the output captured so far and the exit status, if any.
+func (m *TerminalManager) TerminalOutput(ctx context.Context, params acp.TerminalOutputRequest) (acp.TerminalOutputResponse, error) {
+	t, err := m.get(params.TerminalId)
+	if err != nil {
+		return acp.TerminalOutputResponse{}, err
+	}
+	output, truncated, status := t.snapshot()
+	return acp.TerminalOutputResponse{
+		Output:     output,
+		Truncated:  truncated,
+		ExitStatus: status,
+	}, nil
+}
+
+// WaitForTerminalExit blocks until the command exits or ctx is cancelled.
+func (m *TerminalManager) WaitForTerminalExit(ctx context.Context, params acp.WaitForTerminalExitRequest) (acp.WaitForTerminalExitResponse, error) {
+	t, err := m.get(params.TerminalId)
+	if err != nil {
+		return acp.WaitForTerminalExitResponse{}, err
+	}
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return acp.WaitForTerminalExitResponse{}, ctx.Err()
+	}
+	_, _, status := t.snapshot()
+	return acp.WaitForTerminalExitResponse{
+		ExitCode: status.ExitCode,
+		Signal:   status.Signal,
+	}, nil
+}
+
+// KillTerminal kills the command without releasing the terminal,
+// so its output and exit status remain available.
+func (m *TerminalManager) KillTerminal(ctx context.Context, params acp.KillTerminalRequest) (acp.KillTerminalResponse, error) {
+	t, err := m.get(params.TerminalId)
+	if err != nil {
+		return acp.KillTerminalResponse{}, err
+	}
+	select {
+	case <-t.done:
+	default:
+		t.kill()
+	}
+	return acp.KillTerminalResponse{}, nil
+}
+
+// ReleaseTerminal kills the command if still running and forgets the terminal.
+func (m *TerminalManager) ReleaseTerminal(ctx context.Context, params acp.ReleaseTerminalRequest) (acp.ReleaseTerminalResponse, error) {
+	m.mu.Lock()
+	t, ok := m.terminals[params.TerminalId]
+	delete(m.terminals, params.TerminalId)
+	m.mu.Unlock()
+	if !ok {
+		return acp.ReleaseTerminalResponse{}, fmt.Errorf("terminal not found: %s", params.TerminalId)
+	}
+	select {
+	case <-t.done:
+	default:
+		t.kill()
+	}
+	if t.cancel != nil {
+		t.cancel()
+	}
+	return acp.ReleaseTerminalResponse{}, nil
+}
+
+// Close kills all running terminals and rejects further CreateTerminal calls.
+func (m *TerminalManager) Close() {
+	m.mu.Lock()
+	m.closed = true
+	terminals := m.terminals
+	m.terminals = make(map[string]*terminal)
+	m.mu.Unlock()
+
+	for _, t := range terminals {
+		select {
+		case <-t.done:
+		default:
+			t.kill()
+		}
+		if t.cancel != nil {
+			t.cancel()
+		}
+	}
+}
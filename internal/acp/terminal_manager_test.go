@@ -0,0 +1,182 @@
+//go:build !windows
+
+package acp
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/acp-go-sdk"
+)
+
+func waitTerminal(t *testing.T, m *TerminalManager, id string) acp.WaitForTerminalExitResponse {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := m.WaitForTerminalExit(ctx, acp.WaitForTerminalExitRequest{TerminalId: id})
+	if err != nil {
+		t.Fatalf("WaitForTerminalExit failed: %v", err)
+	}
+	return resp
+}
+
+func TestTerminalManager_RunsCommand(t *testing.T) {
+	var mu sync.Mutex
+	var streamed strings.Builder
+	var exited []string
+	m := NewTerminalManager(TerminalManagerConfig{
+		DefaultCwd: t.TempDir(),
+		OnOutput: func(id, chunk string) {
+			mu.Lock()
+			streamed.WriteString(chunk)
+			mu.Unlock()
+		},
+		OnExit: func(id string, status acp.TerminalExitStatus) {
+			mu.Lock()
+			exited = append(exited, id)
+			mu.Unlock()
+		},
+	})
+	defer m.Close()
+
+	ctx := context.Background()
+	resp, err := m.CreateTerminal(ctx, acp.CreateTerminalRequest{
+		Command: "sh",
+		Args:    []string{"-c", "echo hello $GREETING; exit 3"},
+		Env:     []acp.EnvVariable{{Name: "GREETING", Value: "world"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTerminal failed: %v", err)
+	}
+	if resp.TerminalId != "term-1" {
+		t.Errorf("TerminalId = %q, want %q", resp.TerminalId, "term-1")
+	}
+
+	exit := waitTerminal(t, m, resp.TerminalId)
+	if exit.ExitCode == nil || *exit.ExitCode != 3 {
+		t.Errorf("ExitCode = %v, want 3", exit.ExitCode)
+	}
+
+	out, err := m.TerminalOutput(ctx, acp.TerminalOutputRequest{TerminalId: resp.TerminalId})
+	if err != nil {
+		t.Fatalf("TerminalOutput failed: %v", err)
+	}
+	if !strings.Contains(out.Output, "hello world") {
+		t.Errorf("Output = %q, want to contain %q", out.Output, "hello world")
+	}
+	if out.ExitStatus == nil || out.ExitStatus.ExitCode == nil || *out.ExitStatus.ExitCode != 3 {
+		t.Errorf("ExitStatus = %+v, want exit code 3", out.ExitStatus)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(streamed.String(), "hello world") {
+		t.Errorf("streamed output = %q, want to contain %q", streamed.String(), "hello world")
+	}
+	if len(exited) != 1 || exited[0] != resp.TerminalId {
+		t.Errorf("OnExit calls = %v, want [%s]", exited, resp.TerminalId)
+	}
+}
+
+func TestTerminalManager_OutputByteLimit(t *testing.T) {
+	m := NewTerminalManager(TerminalManagerConfig{})
+	defer m.Close()
+
+	limit := 10
+	ctx := context.Background()
+	resp, err := m.CreateTerminal(ctx, acp.CreateTerminalRequest{
+		Command:         "sh",
+		Args:            []string{"-c", "printf 'abcdefghij0123456789'"},
+		OutputByteLimit: &limit,
+	})
+	if err != nil {
+		t.Fatalf("CreateTerminal failed: %v", err)
+	}
+	waitTerminal(t, m, resp.TerminalId)
+
+	out, err := m.TerminalOutput(ctx, acp.TerminalOutputRequest{TerminalId: resp.TerminalId})
+	if err != nil {
+		t.Fatalf("TerminalOutput failed: %v", err)
+	}
+	if out.Output != "0123456789" {
+		t.Errorf("Output = %q, want %q", out.Output, "0123456789")
+	}
+	if !out.Truncated {
+		t.Error("Truncated = false, want true")
+	}
+}
+
+func TestTerminal_AppendTruncatesAtCharBoundary(t *testing.T) {
+	term := &terminal{limit: 4}
+	term.append([]byte("aé€")) // 1 + 2 + 3 bytes
+
+	output, truncated, _ := term.snapshot()
+	if output != "€" {
+		t.Errorf("output = %q, want %q", output, "€")
+	}
+	if !truncated {
+		t.Error("truncated = false, want true")
+	}
+}
+
+func TestTerminalManager_KillAndRelease(t *testing.T) {
+	m := NewTerminalManager(TerminalManagerConfig{})
+	defer m.Close()
+
+	ctx := context.Background()
+	resp, err := m.CreateTerminal(ctx, acp.CreateTerminalRequest{
+		Command: "sleep",
+		Args:    []string{"60"},
+	})
+	if err != nil {
+		t.Fatalf("CreateTerminal failed: %v", err)
+	}
+
+	if _, err := m.KillTerminal(ctx, acp.KillTerminalRequest{TerminalId: resp.TerminalId}); err != nil {
+		t.Fatalf("KillTerminal failed: %v", err)
+	}
+	exit := waitTerminal(t, m, resp.TerminalId)
+	if exit.Signal == nil {
+		t.Errorf("Signal = nil, want a signal (exit code %v)", exit.ExitCode)
+	}
+
+	// Output is still available after kill, until the terminal is released.
+	if _, err := m.TerminalOutput(ctx, acp.TerminalOutputRequest{TerminalId: resp.TerminalId}); err != nil {
+		t.Errorf("TerminalOutput after kill failed: %v", err)
+	}
+
+	if _, err := m.ReleaseTerminal(ctx, acp.ReleaseTerminalRequest{TerminalId: resp.TerminalId}); err != nil {
+		t.Fatalf("ReleaseTerminal failed: %v", err)
+	}
+	if _, err := m.TerminalOutput(ctx, acp.TerminalOutputRequest{TerminalId: resp.TerminalId}); err == nil {
+		t.Error("TerminalOutput after release should fail")
+	}
+}
+
+func TestTerminalManager_UnknownTerminal(t *testing.T) {
+	m := NewTerminalManager(TerminalManagerConfig{})
+	ctx := context.Background()
+
+	if _, err := m.TerminalOutput(ctx, acp.TerminalOutputRequest{TerminalId: "missing"}); err == nil {
+		t.Error("TerminalOutput for unknown terminal should fail")
+	}
+	if _, err := m.WaitForTerminalExit(ctx, acp.WaitForTerminalExitRequest{TerminalId: "missing"}); err == nil {
+		t.Error("WaitForTerminalExit for unknown terminal should fail")
+	}
+	if _, err := m.KillTerminal(ctx, acp.KillTerminalRequest{TerminalId: "missing"}); err == nil {
+		t.Error("KillTerminal for unknown terminal should fail")
+	}
+}
+
+func TestTerminalManager_CreateAfterClose(t *testing.T) {
+	m := NewTerminalManager(TerminalManagerConfig{})
+	m.Close()
+
+	_, err := m.CreateTerminal(context.Background(), acp.CreateTerminalRequest{Command: "true"})
+	if err == nil {
+		t.Error("CreateTerminal after Close should fail")
+	}
+}
@@ -34,6 +34,9 @@ const (
 	// SessionsDirName is the name of the sessions subdirectory.
 	SessionsDirName = "sessions"
 
+	// WorktreesDirName is the name of the subdirectory holding the per-session git worktrees.
+	WorktreesDirName = "worktrees"
+
 	// ProcessorsDirName is the name of the processors subdirectory.
 	ProcessorsDirName = "processors"
 
@@ -65,6 +68,29 @@ const (
 
 	// DefenseBlocklistFileName is the name of the scanner defense blocklist file.
 	DefenseBlocklistFileName = "scanner_blocklist.json"
+
+	// WebhookDeliveriesFileName is the name of the outbound webhook delivery log.
+	WebhookDeliveriesFileName = "webhook_deliveries.jsonl"
+
+	// RemoteCredentialsFileName is the name of the file with the session tokens
+	// of `mitto remote login`.
+	RemoteCredentialsFileName = "remote_credentials.json"
+
+	// APITokensFileName is the name of the file with the (hashed) API tokens.
+	APITokensFileName = "api_tokens.json"
+
+	// TOTPFileName is the name of the file with the two-factor authentication
+	// enrollments of the users.
+	TOTPFileName = "totp.json"
+
+	// SecretsFileName is the name of the encrypted file holding the credentials
+	// when no system secret store is available.
+	SecretsFileName = "secrets.enc"
+
+	// SecretsKeyFileName is the name of the file with the machine key that
+	// encrypts SecretsFileName when no passphrase is configured. It is kept
+	// in the configuration directory (see SecretsKeyPath).
+	SecretsKeyFileName = "secrets.key"
 )
 
 var (
@@ -295,6 +321,16 @@ func SessionsDir() (string, error) {
 	return filepath.Join(dir, SessionsDirName), nil
 }
 
+// WorktreesDir returns the full path to the directory of per-session git worktrees.
+// The directory is created on demand, when the first worktree is added.
+func WorktreesDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, WorktreesDirName), nil
+}
+
 // ProcessorsDir returns the full path to the processors directory.
 func ProcessorsDir() (string, error) {
 	dir, err := Dir()
@@ -399,6 +435,68 @@ func DefenseBlocklistPath() (string, error) {
 	return filepath.Join(dir, DefenseBlocklistFileName), nil
 }
 
+// WebhookDeliveriesPath returns the path to the outbound webhook delivery log.
+func WebhookDeliveriesPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, WebhookDeliveriesFileName), nil
+}
+
+// RemoteCredentialsPath returns the path to the session tokens of the remote
+// Mitto servers `mitto remote` is logged in to.
+func RemoteCredentialsPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, RemoteCredentialsFileName), nil
+}
+
+// APITokensPath returns the path to the API tokens file.
+func APITokensPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, APITokensFileName), nil
+}
+
+// TOTPPath returns the path to the two-factor authentication file.
+func TOTPPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, TOTPFileName), nil
+}
+
+// SecretsPath returns the path to the encrypted secrets file.
+func SecretsPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, SecretsFileName), nil
+}
+
+// SecretsKeyPath returns the path to the machine key of the encrypted secrets
+// file: $XDG_CONFIG_HOME/mitto/secrets.key (~/.config/mitto/secrets.key), out
+// of the Mitto directory so that copies of the directory don't carry the key.
+// The encrypted secrets file is only used on Linux.
+func SecretsKeyPath() (string, error) {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		configDir = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configDir, "mitto", SecretsKeyFileName), nil
+}
+
 // ResetCache clears the cached directory path.
 // This is primarily useful for testing.
 func ResetCache() {
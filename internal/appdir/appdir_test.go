@@ -230,6 +230,26 @@ func TestAuthSessionsPath(t *testing.T) {
 	}
 }
 
+func TestSecretsKeyPath(t *testing.T) {
+	customDir := t.TempDir()
+	configDir := t.TempDir()
+	t.Setenv(MittoDirEnv, customDir)
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	ResetCache()
+	t.Cleanup(ResetCache)
+
+	keyPath, err := SecretsKeyPath()
+	if err != nil {
+		t.Fatalf("SecretsKeyPath() failed: %v", err)
+	}
+	if expected := filepath.Join(configDir, "mitto", SecretsKeyFileName); keyPath != expected {
+		t.Errorf("SecretsKeyPath() = %q, want %q", keyPath, expected)
+	}
+	if strings.HasPrefix(keyPath, customDir) {
+		t.Errorf("SecretsKeyPath() = %q is in the Mitto directory", keyPath)
+	}
+}
+
 func TestUIPreferencesPath(t *testing.T) {
 	customDir := t.TempDir()
 	t.Setenv(MittoDirEnv, customDir)
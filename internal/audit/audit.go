@@ -0,0 +1,335 @@
+// Package audit writes the audit log of security-relevant actions: logins,
+// permission decisions, configuration changes, conversations created or
+// deleted by agents, callback triggers and scanner-defense blocks.
+//
+// The log is an append-only JSONL file, separate from the general log. Every
+// event carries the SHA-256 hash of its own contents and the hash of the
+// previous event, so editing, removing or reordering events breaks the chain
+// (see Verify). The file is rotated by size, and the chain continues across
+// the rotated files.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/inercia/mitto/internal/logging"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionLogin              = "auth.login"
+	ActionPermissionDecide   = "permission.decide"
+	ActionPermissionAnswer   = "permission.answer"
+	ActionConfigUpdate       = "config.update"
+	ActionConversationCreate = "conversation.create"
+	ActionConversationDelete = "conversation.delete"
+	ActionCallbackTrigger    = "callback.trigger"
+	ActionDefenseBlock       = "defense.block"
+)
+
+// Outcomes of an action.
+const (
+	// OutcomeSuccess is an action that was performed (or a permission approved).
+	OutcomeSuccess = "success"
+	// OutcomeFailure is an action that was attempted but failed.
+	OutcomeFailure = "failure"
+	// OutcomeDenied is an action that was refused (bad credentials, rate
+	// limiting, invalid signature, permission denied...).
+	OutcomeDenied = "denied"
+)
+
+// Default rotation settings.
+const (
+	DefaultMaxSizeMB = 10
+	// DefaultMaxBackups keeps every rotated file: deleting old files makes the
+	// start of the chain unverifiable.
+	DefaultMaxBackups = 0
+)
+
+// maxLineSize is the maximum size of an event line read back from the log.
+const maxLineSize = 1024 * 1024
+
+// Actor identifies who performed an action.
+type Actor struct {
+	// IP is the client IP of the request.
+	IP string `json:"ip,omitempty"`
+	// User is the authenticated user name.
+	User string `json:"user,omitempty"`
+	// Session is the conversation of the agent that performed the action.
+	Session string `json:"session,omitempty"`
+}
+
+// Event is an entry of the audit log.
+type Event struct {
+	// Seq is the position of the event in the chain, starting at 1.
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+
+	Actor   Actor  `json:"actor"`
+	Action  string `json:"action"`
+	Target  string `json:"target,omitempty"`
+	Outcome string `json:"outcome"`
+	// Details holds additional information about the action (reason, rule...).
+	Details map[string]string `json:"details,omitempty"`
+
+	// PrevHash is the hash of the previous event (empty for the first one).
+	PrevHash string `json:"prev_hash"`
+	// Hash is the hex SHA-256 of the JSON encoding of the event without Hash.
+	Hash string `json:"hash,omitempty"`
+}
+
+// computeHash returns the hash of the event, ignoring its Hash field.
+func (e Event) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Config is the configuration of an audit log.
+type Config struct {
+	// Path is the file of the log. Rotated files are kept next to it, with a
+	// timestamp in their name (audit-2006-01-02T15-04-05.000.jsonl).
+	Path string
+	// MaxSizeMB is the size in megabytes at which the file is rotated.
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files kept (0 keeps all).
+	MaxBackups int
+}
+
+// Log is an audit log open for writing. A nil *Log discards all events.
+type Log struct {
+	path string
+
+	mu       sync.Mutex
+	writer   *lumberjack.Logger
+	seq      uint64
+	lastHash string
+}
+
+// Open opens the audit log, continuing the chain of the events already in it.
+// It fails if the last event cannot be read, as appending to a damaged chain
+// would hide the damage.
+func Open(cfg Config) (*Log, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("audit log path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o700); err != nil {
+		return nil, fmt.Errorf("create audit log directory: %w", err)
+	}
+
+	last, err := lastEvent(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := cfg.MaxSizeMB
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSizeMB
+	}
+	maxBackups := cfg.MaxBackups
+	if maxBackups < 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	l := &Log{
+		path: cfg.Path,
+		writer: &lumberjack.Logger{
+			Filename:   cfg.Path,
+			MaxSize:    maxSize,    // megabytes
+			MaxBackups: maxBackups, // number of backups
+			MaxAge:     0,          // don't delete old files based on age
+			Compress:   false,      // Verify and Query read the rotated files
+		},
+	}
+	if last != nil {
+		l.seq = last.Seq
+		l.lastHash = last.Hash
+	}
+	return l, nil
+}
+
+// Path returns the file of the log.
+func (l *Log) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// Record appends an event to the log. Seq, PrevHash and Hash are set by the
+// log, and Time when it is zero.
+func (l *Log) Record(e Event) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	e.Seq = l.seq + 1
+	e.PrevHash = l.lastHash
+
+	hash, err := e.computeHash()
+	if err != nil {
+		return fmt.Errorf("hash audit event: %w", err)
+	}
+	e.Hash = hash
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode audit event: %w", err)
+	}
+	if _, err := l.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit event: %w", err)
+	}
+
+	l.seq = e.Seq
+	l.lastHash = e.Hash
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.writer.Close()
+}
+
+var (
+	defaultMu  sync.RWMutex
+	defaultLog *Log
+)
+
+// SetDefault sets the log written by Record. Passing nil disables auditing.
+func SetDefault(l *Log) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLog = l
+}
+
+// Default returns the log written by Record, or nil when auditing is disabled.
+func Default() *Log {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLog
+}
+
+// Record appends an event to the default log. Auditing must not break the
+// action audited, so errors are only logged.
+func Record(e Event) {
+	if err := Default().Record(e); err != nil {
+		logging.WithComponent("audit").Error("Failed to write audit event",
+			"action", e.Action,
+			"target", e.Target,
+			"error", err)
+	}
+}
+
+// files returns the files of the log at path, oldest first: the rotated
+// files, then the current one (when they exist).
+func files(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext) + "-"
+	matches, err := filepath.Glob(globEscape(prefix) + "*" + globEscape(ext))
+	if err != nil {
+		return nil, err
+	}
+	// The timestamps of the rotated files sort chronologically
+	sort.Strings(matches)
+	if _, err := os.Stat(path); err == nil {
+		matches = append(matches, path)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// globEscape escapes the glob metacharacters of a path.
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// readEvents calls fn with every line of file and the event decoded from it
+// (nil with an error if the line cannot be decoded). fn returns false to stop.
+func readEvents(file string, fn func(line int, e *Event, err error) bool) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for n := 1; scanner.Scan(); n++ {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			if !fn(n, nil, err) {
+				return nil
+			}
+			continue
+		}
+		if !fn(n, &e, nil) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// lastEvent returns the last event of the log at path, or nil if it is empty.
+func lastEvent(path string) (*Event, error) {
+	logFiles, err := files(path)
+	if err != nil {
+		return nil, fmt.Errorf("list audit log files: %w", err)
+	}
+	for i := len(logFiles) - 1; i >= 0; i-- {
+		var last *Event
+		var lastErr error
+		err := readEvents(logFiles[i], func(line int, e *Event, err error) bool {
+			last, lastErr = e, nil
+			if err != nil {
+				lastErr = fmt.Errorf("%s:%d: %w", logFiles[i], line, err)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read audit log: %w", err)
+		}
+		if lastErr != nil {
+			return nil, fmt.Errorf("last audit event is damaged (run 'mitto tools audit verify'): %w", lastErr)
+		}
+		if last != nil {
+			return last, nil
+		}
+	}
+	return nil, nil
+}
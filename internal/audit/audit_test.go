@@ -0,0 +1,228 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestLog(t *testing.T, path string) *Log {
+	t.Helper()
+	l, err := Open(Config{Path: path})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func record(t *testing.T, l *Log, e Event) {
+	t.Helper()
+	if err := l.Record(e); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+}
+
+func TestLog_ChainContinuesAfterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	l := openTestLog(t, path)
+	record(t, l, Event{Actor: Actor{IP: "10.0.0.1"}, Action: ActionLogin, Target: "alice", Outcome: OutcomeDenied})
+	record(t, l, Event{Actor: Actor{IP: "10.0.0.1", User: "alice"}, Action: ActionLogin, Target: "alice", Outcome: OutcomeSuccess})
+	l.Close()
+
+	l = openTestLog(t, path)
+	record(t, l, Event{Actor: Actor{Session: "s1"}, Action: ActionConversationDelete, Target: "s2", Outcome: OutcomeSuccess})
+	l.Close()
+
+	result, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if result.Events != 3 || result.FirstSeq != 1 || result.LastSeq != 3 || result.Truncated {
+		t.Errorf("Verify() = %+v", result)
+	}
+}
+
+func TestLog_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+
+	l := openTestLog(t, path)
+	record(t, l, Event{Action: ActionConfigUpdate, Outcome: OutcomeSuccess})
+	if err := l.writer.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	record(t, l, Event{Action: ActionConfigUpdate, Outcome: OutcomeFailure})
+	l.Close()
+
+	logFiles, err := files(path)
+	if err != nil || len(logFiles) != 2 {
+		t.Fatalf("files() = %v, %v; want a rotated file and the current one", logFiles, err)
+	}
+	result, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if result.Files != 2 || result.Events != 2 {
+		t.Errorf("Verify() = %+v", result)
+	}
+
+	// The chain continues from the rotated file when there is no current one
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	l = openTestLog(t, path)
+	record(t, l, Event{Action: ActionConfigUpdate, Outcome: OutcomeSuccess})
+	l.Close()
+	if result, err := Verify(path); err != nil || result.LastSeq != 2 {
+		t.Fatalf("Verify() = %+v, %v; want the chain to continue at event 2", result, err)
+	}
+
+	// Without the oldest files, the first event kept starts the chain
+	if err := os.Remove(logFiles[0]); err != nil {
+		t.Fatal(err)
+	}
+	result, err = Verify(path)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !result.Truncated || result.FirstSeq != 2 || result.LastSeq != 2 {
+		t.Errorf("Verify() = %+v, want a truncated chain starting at event 2", result)
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines []string) []string
+		want   string
+	}{
+		{"modified", func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], `"outcome":"denied"`, `"outcome":"success"`, 1)
+			return lines
+		}, "hash mismatch"},
+		{"removed", func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		}, "expected event 2"},
+		{"reordered", func(lines []string) []string {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, "expected event 2"},
+		{"malformed", func(lines []string) []string {
+			lines[2] = lines[2][:len(lines[2])/2]
+			return lines
+		}, "malformed event"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+			l := openTestLog(t, path)
+			record(t, l, Event{Action: ActionLogin, Outcome: OutcomeSuccess})
+			record(t, l, Event{Action: ActionLogin, Outcome: OutcomeDenied})
+			record(t, l, Event{Action: ActionLogin, Outcome: OutcomeSuccess})
+			l.Close()
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := tt.tamper(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+			if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = Verify(path)
+			var chainErr *ChainError
+			if !errors.As(err, &chainErr) || !strings.Contains(chainErr.Reason, tt.want) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpen_DamagedLastEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte(`{"seq":1,"action":`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(Config{Path: path}); err == nil {
+		t.Error("Open() of a log with a damaged last event should fail")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := openTestLog(t, path)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Actor: Actor{IP: "10.0.0.1"}, Action: ActionLogin, Target: "alice", Outcome: OutcomeDenied},
+		{Actor: Actor{IP: "10.0.0.1", User: "alice"}, Action: ActionLogin, Target: "alice", Outcome: OutcomeSuccess},
+		{Actor: Actor{IP: "10.0.0.1", User: "alice"}, Action: ActionConfigUpdate, Outcome: OutcomeSuccess},
+		{Actor: Actor{Session: "s1"}, Action: ActionPermissionDecide, Target: "s1", Outcome: OutcomeDenied},
+		{Actor: Actor{IP: "10.0.0.2"}, Action: ActionDefenseBlock, Target: "10.0.0.2", Outcome: OutcomeSuccess},
+	}
+	for i, e := range events {
+		e.Time = start.Add(time.Duration(i) * time.Hour)
+		record(t, l, e)
+	}
+	// A line that cannot be decoded is skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("not json\n")
+	f.Close()
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []uint64
+	}{
+		{"all, newest first", Query{}, []uint64{5, 4, 3, 2, 1}},
+		{"limit", Query{Limit: 2}, []uint64{5, 4}},
+		{"action", Query{Action: ActionLogin}, []uint64{2, 1}},
+		{"action group", Query{Action: "auth"}, []uint64{2, 1}},
+		{"action prefix is not a group", Query{Action: "aut"}, nil},
+		{"user", Query{User: "alice"}, []uint64{3, 2}},
+		{"ip and outcome", Query{IP: "10.0.0.1", Outcome: OutcomeSuccess}, []uint64{3, 2}},
+		{"session", Query{Session: "s1"}, []uint64{4}},
+		{"target", Query{Target: "10.0.0.2"}, []uint64{5}},
+		{"time range", Query{Since: start.Add(time.Hour), Until: start.Add(3 * time.Hour)}, []uint64{4, 3, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Search(path, tt.query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			var seqs []uint64
+			for _, e := range got {
+				seqs = append(seqs, e.Seq)
+			}
+			if len(seqs) != len(tt.want) {
+				t.Fatalf("Search() = %v, want %v", seqs, tt.want)
+			}
+			for i := range seqs {
+				if seqs[i] != tt.want[i] {
+					t.Fatalf("Search() = %v, want %v", seqs, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNilLog(t *testing.T) {
+	var l *Log
+	if err := l.Record(Event{Action: ActionLogin}); err != nil {
+		t.Errorf("Record() on a nil log = %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("Close() on a nil log = %v", err)
+	}
+	// Recording without a default log is a no-op
+	Record(Event{Action: ActionLogin})
+}
@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultQueryLimit is the number of events returned when Query.Limit is not set.
+const DefaultQueryLimit = 100
+
+// Query selects events of the audit log. Empty fields match all events.
+type Query struct {
+	// Action matches the action, or a group of actions ("auth" matches "auth.login").
+	Action  string
+	User    string
+	IP      string
+	Session string
+	Target  string
+	Outcome string
+	// Since and Until bound the time of the events (inclusive).
+	Since time.Time
+	Until time.Time
+	// Limit is the maximum number of events returned (DefaultQueryLimit if <= 0).
+	Limit int
+}
+
+// Matches returns true if the event is selected by the query.
+func (q Query) Matches(e *Event) bool {
+	switch {
+	case q.Action != "" && e.Action != q.Action && !strings.HasPrefix(e.Action, q.Action+"."):
+		return false
+	case q.User != "" && e.Actor.User != q.User:
+		return false
+	case q.IP != "" && e.Actor.IP != q.IP:
+		return false
+	case q.Session != "" && e.Actor.Session != q.Session:
+		return false
+	case q.Target != "" && e.Target != q.Target:
+		return false
+	case q.Outcome != "" && e.Outcome != q.Outcome:
+		return false
+	case !q.Since.IsZero() && e.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && e.Time.After(q.Until):
+		return false
+	}
+	return true
+}
+
+// Search returns the most recent events of the log at path selected by the
+// query, newest first. Lines that cannot be decoded are skipped (use Verify to
+// find them).
+func Search(path string, q Query) ([]Event, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+
+	logFiles, err := files(path)
+	if err != nil {
+		return nil, fmt.Errorf("list audit log files: %w", err)
+	}
+
+	// Keep the last matches, oldest first
+	var matches []Event
+	for _, file := range logFiles {
+		err := readEvents(file, func(_ int, e *Event, err error) bool {
+			if err == nil && q.Matches(e) {
+				matches = append(matches, *e)
+				if len(matches) > limit {
+					matches = matches[1:]
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read audit log: %w", err)
+		}
+	}
+
+	events := make([]Event, len(matches))
+	for i, e := range matches {
+		events[len(matches)-1-i] = e
+	}
+	return events, nil
+}
@@ -0,0 +1,93 @@
+package audit
+
+import "fmt"
+
+// VerifyResult summarizes an audit log whose chain is intact.
+type VerifyResult struct {
+	// Files is the number of files checked, including the rotated ones.
+	Files int
+	// Events is the number of events checked.
+	Events int
+	// FirstSeq and LastSeq are the sequence numbers of the first and last events.
+	FirstSeq uint64
+	LastSeq  uint64
+	// Truncated is true when the oldest events are no longer in the log
+	// (rotated files deleted): the first event kept is trusted as the start of
+	// the chain.
+	Truncated bool
+}
+
+// ChainError is the first break found in the chain of an audit log.
+type ChainError struct {
+	File string
+	Line int
+	// Seq is the sequence number of the event, when it could be decoded.
+	Seq    uint64
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	if e.Seq == 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Reason)
+	}
+	return fmt.Sprintf("%s:%d: event %d: %s", e.File, e.Line, e.Seq, e.Reason)
+}
+
+// Verify checks the chain of the audit log at path: every event must be
+// well formed, match its hash, follow the previous event in sequence and
+// reference its hash. It returns a *ChainError at the first break.
+func Verify(path string) (*VerifyResult, error) {
+	logFiles, err := files(path)
+	if err != nil {
+		return nil, fmt.Errorf("list audit log files: %w", err)
+	}
+
+	result := &VerifyResult{Files: len(logFiles)}
+	var prev *Event
+	var chainErr *ChainError
+	for _, file := range logFiles {
+		err := readEvents(file, func(line int, e *Event, err error) bool {
+			fail := func(reason string) bool {
+				chainErr = &ChainError{File: file, Line: line, Reason: reason}
+				if e != nil {
+					chainErr.Seq = e.Seq
+				}
+				return false
+			}
+
+			if err != nil {
+				return fail(fmt.Sprintf("malformed event: %v", err))
+			}
+			if hash, err := e.computeHash(); err != nil || hash != e.Hash {
+				return fail("hash mismatch (the event was modified)")
+			}
+			switch {
+			case prev != nil && e.Seq != prev.Seq+1:
+				return fail(fmt.Sprintf("expected event %d (events missing or reordered)", prev.Seq+1))
+			case prev != nil && e.PrevHash != prev.Hash:
+				return fail(fmt.Sprintf("previous hash does not match event %d", prev.Seq))
+			case prev == nil && e.Seq == 1 && e.PrevHash != "":
+				return fail("first event has a previous hash")
+			case prev == nil && e.Seq == 0:
+				return fail("invalid sequence number 0")
+			case prev == nil && e.Seq > 1:
+				result.Truncated = true
+			}
+
+			if prev == nil {
+				result.FirstSeq = e.Seq
+			}
+			result.LastSeq = e.Seq
+			result.Events++
+			prev = e
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read audit log: %w", err)
+		}
+		if chainErr != nil {
+			return nil, chainErr
+		}
+	}
+	return result, nil
+}
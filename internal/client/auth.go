@@ -0,0 +1,223 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// sessionCookieName is the name of the authentication cookie of the server.
+	sessionCookieName = "mitto_session"
+
+	// csrfCookieName is the name of the cookie holding the CSRF token.
+	csrfCookieName = "mitto_csrf"
+
+	// csrfTokenHeader is the header the CSRF token is sent in.
+	csrfTokenHeader = "X-CSRF-Token"
+)
+
+// ErrTOTPRequired is returned by Login when the password is valid but the user
+// has two-factor authentication enabled and no code was given.
+var ErrTOTPRequired = errors.New("login: two-factor authentication code required")
+
+// WithAPIToken authenticates the requests with an API token of the server
+// (see `mitto tokens create`), sent as an "Authorization: Bearer" header.
+// Requests made with API tokens don't need CSRF tokens.
+func WithAPIToken(token string) Option {
+	return func(client *Client) {
+		client.apiToken = token
+	}
+}
+
+// WithSessionToken authenticates the requests with a session token, the value
+// of the server's authentication cookie (see SessionToken).
+func WithSessionToken(token string) Option {
+	return func(client *Client) {
+		if token != "" {
+			client.setCookie(sessionCookieName, token)
+		}
+	}
+}
+
+// Login authenticates with the username and password of the server's simple
+// auth. The session cookie is kept by the client and used by the following
+// requests (and WebSocket connections).
+//
+// code is the code of the authenticator app (or a recovery code) of users
+// with two-factor authentication enabled; without it, their logins fail with
+// ErrTOTPRequired.
+func (c *Client) Login(username, password, code string) error {
+	req := map[string]string{
+		"username": username,
+		"password": password,
+	}
+	if code != "" {
+		req["code"] = code
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("login: marshal: %w", err)
+	}
+
+	resp, err := c.httpClient.Post(c.apiURL("/api/login"), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success      bool   `json:"success"`
+		Error        string `json:"error"`
+		TOTPRequired bool   `json:"totp_required"`
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	if json.Unmarshal(respBody, &result) != nil {
+		result.Error = string(respBody)
+	}
+	if result.TOTPRequired && code == "" {
+		return ErrTOTPRequired
+	}
+	if resp.StatusCode != http.StatusOK || !result.Success {
+		return fmt.Errorf("login: status %d: %s", resp.StatusCode, result.Error)
+	}
+	if c.SessionToken() == "" {
+		return fmt.Errorf("login: the server did not return a session cookie")
+	}
+	return nil
+}
+
+// Logout invalidates the session of the client on the server.
+func (c *Client) Logout() error {
+	resp, err := c.httpClient.Post(c.apiURL("/api/logout"), "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("logout: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("logout: status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// SessionToken returns the session token of the client (set by Login or
+// WithSessionToken), or "" if it has none.
+func (c *Client) SessionToken() string {
+	return c.cookie(sessionCookieName)
+}
+
+// cookie returns the value of a cookie of the server, or "".
+func (c *Client) cookie(name string) string {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return ""
+	}
+	for _, cookie := range c.jar.Cookies(u) {
+		if cookie.Name == name {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// setCookie stores a cookie for the server.
+func (c *Client) setCookie(name, value string) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return
+	}
+	c.jar.SetCookies(u, []*http.Cookie{{Name: name, Value: value, Path: "/"}})
+}
+
+// dialHeader returns the authentication headers of the WebSocket connections.
+func (c *Client) dialHeader() http.Header {
+	header := http.Header{}
+	if c.apiToken != "" {
+		header.Set("Authorization", "Bearer "+c.apiToken)
+	}
+	if u, err := url.Parse(c.baseURL); err == nil {
+		req := &http.Request{Header: header}
+		for _, cookie := range c.jar.Cookies(u) {
+			req.AddCookie(cookie)
+		}
+	}
+	if len(header) == 0 {
+		return nil
+	}
+	return header
+}
+
+// csrfToken returns the CSRF token of the client, getting one from the server
+// the first time.
+func (c *Client) csrfToken() (string, error) {
+	c.csrfMu.Lock()
+	defer c.csrfMu.Unlock()
+
+	if token := c.cookie(csrfCookieName); token != "" {
+		return token, nil
+	}
+
+	resp, err := c.httpClient.Get(c.apiURL("/api/csrf-token"))
+	if err != nil {
+		return "", fmt.Errorf("get CSRF token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("get CSRF token: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("get CSRF token: decode: %w", err)
+	}
+	// The cookie may not be kept by the jar (e.g. a Secure cookie over http)
+	c.setCookie(csrfCookieName, result.Token)
+	return result.Token, nil
+}
+
+// csrfTransport adds the CSRF token to the state-changing requests: the server
+// requires it (double-submit cookie pattern) on its external listener. With an
+// API token, it adds the token instead.
+type csrfTransport struct {
+	client *Client
+	base   http.RoundTripper
+}
+
+func (t *csrfTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.client.apiToken != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.client.apiToken)
+		return t.base.RoundTrip(req)
+	}
+
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return t.base.RoundTrip(req)
+	}
+	if req.Header.Get(csrfTokenHeader) != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	token, err := t.client.csrfToken()
+	if err != nil {
+		// Send the request anyway: internal connections don't need the token
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(csrfTokenHeader, token)
+	if _, err := req.Cookie(csrfCookieName); err != nil {
+		req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: token})
+	}
+	return t.base.RoundTrip(req)
+}
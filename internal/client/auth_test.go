@@ -0,0 +1,147 @@
+package client_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/inercia/mitto/internal/client"
+)
+
+// newAuthServer returns a server that requires the session cookie "secret" and,
+// on state-changing requests, a CSRF header matching the CSRF cookie.
+func newAuthServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mitto/api/csrf-token", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "mitto_csrf", Value: "csrf-1", Path: "/"})
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "csrf-1"})
+	})
+	mux.HandleFunc("/mitto/api/login", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Username, Password, Code string }
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if (req.Username != "admin" && req.Username != "alice") || req.Password != "pw" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"success": false, "error": "Invalid username or password"})
+			return
+		}
+		// alice has two-factor authentication enabled
+		if req.Username == "alice" && req.Code == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"success": false, "error": "Enter the code", "totp_required": true})
+			return
+		}
+		if req.Username == "alice" && req.Code != "123456" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"success": false, "error": "Invalid authentication code"})
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "mitto_session", Value: "secret", Path: "/"})
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true})
+	})
+	mux.HandleFunc("/mitto/api/sessions/", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("mitto_session"); err != nil || cookie.Value != "secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet {
+			cookie, err := r.Cookie("mitto_csrf")
+			if err != nil || r.Header.Get("X-CSRF-Token") != cookie.Value {
+				http.Error(w, "CSRF token required", http.StatusForbidden)
+				return
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"session_id": "s1"})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_Login(t *testing.T) {
+	server := newAuthServer(t)
+
+	c := client.New(server.URL)
+	if _, err := c.GetSession("s1"); err == nil {
+		t.Fatal("GetSession() without login succeeded, want error")
+	}
+	if err := c.Login("admin", "wrong", ""); err == nil {
+		t.Fatal("Login(wrong password) succeeded, want error")
+	}
+	if err := c.Login("admin", "pw", ""); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if got := c.SessionToken(); got != "secret" {
+		t.Errorf("SessionToken() = %q, want %q", got, "secret")
+	}
+	if _, err := c.GetSession("s1"); err != nil {
+		t.Errorf("GetSession() after login error = %v", err)
+	}
+	if err := c.ArchiveSession("s1", true); err != nil {
+		t.Errorf("ArchiveSession() after login error = %v", err)
+	}
+}
+
+func TestClient_LoginWithCode(t *testing.T) {
+	server := newAuthServer(t)
+
+	c := client.New(server.URL)
+	if err := c.Login("alice", "pw", ""); !errors.Is(err, client.ErrTOTPRequired) {
+		t.Fatalf("Login() without code error = %v, want ErrTOTPRequired", err)
+	}
+	if err := c.Login("alice", "pw", "000000"); err == nil || errors.Is(err, client.ErrTOTPRequired) {
+		t.Fatalf("Login() with a wrong code error = %v, want a login failure", err)
+	}
+	if err := c.Login("alice", "pw", "123456"); err != nil {
+		t.Fatalf("Login() with code error = %v", err)
+	}
+	if got := c.SessionToken(); got != "secret" {
+		t.Errorf("SessionToken() = %q, want %q", got, "secret")
+	}
+}
+
+func TestClient_WithSessionToken(t *testing.T) {
+	server := newAuthServer(t)
+
+	c := client.New(server.URL, client.WithSessionToken("secret"))
+	if _, err := c.GetSession("s1"); err != nil {
+		t.Errorf("GetSession() error = %v", err)
+	}
+	// The CSRF token is fetched on the first state-changing request
+	if err := c.ArchiveSession("s1", true); err != nil {
+		t.Errorf("ArchiveSession() error = %v", err)
+	}
+
+	c = client.New(server.URL, client.WithSessionToken("other"))
+	if _, err := c.GetSession("s1"); err == nil {
+		t.Error("GetSession() with a wrong token succeeded, want error")
+	}
+}
+
+func TestClient_WithAPIToken(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mitto/api/sessions/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mitto_pat_secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-CSRF-Token") != "" {
+			http.Error(w, "unexpected CSRF token", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"session_id": "s1"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := client.New(server.URL, client.WithAPIToken("mitto_pat_secret"))
+	if _, err := c.GetSession("s1"); err != nil {
+		t.Errorf("GetSession() error = %v", err)
+	}
+	// State-changing requests don't fetch a CSRF token
+	if err := c.ArchiveSession("s1", true); err != nil {
+		t.Errorf("ArchiveSession() error = %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 // Package client provides a Go client for connecting to the Mitto backend.
-// This client is designed for internal use (no authentication) and is useful
-// for integration testing and CLI tools.
+// It is useful for integration testing and CLI tools, and can authenticate
+// with the server's simple auth (see Client.Login and WithSessionToken) or
+// with API tokens (see WithAPIToken).
 package client
 
 import (
@@ -11,8 +12,10 @@ import (
 	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/http/cookiejar"
 	"net/textproto"
 	"net/url"
+	"sync"
 	"time"
 )
 
@@ -22,6 +25,10 @@ type Client struct {
 	baseURL    string
 	apiPrefix  string // API prefix (e.g., "/mitto")
 	httpClient *http.Client
+	jar        http.CookieJar // Session and CSRF cookies
+	apiToken   string         // API token sent as a bearer token (see WithAPIToken)
+
+	csrfMu sync.Mutex // Serializes getting the CSRF token
 }
 
 // Option configures the client.
@@ -34,15 +41,26 @@ func WithTimeout(d time.Duration) Option {
 	}
 }
 
+// WithAPIPrefix sets the API prefix of the server (default "/mitto").
+func WithAPIPrefix(prefix string) Option {
+	return func(client *Client) {
+		client.apiPrefix = prefix
+	}
+}
+
 // New creates a new Mitto client.
 // baseURL should be the Mitto server address (e.g., "http://localhost:8080").
 func New(baseURL string, opts ...Option) *Client {
+	jar, _ := cookiejar.New(nil) // Never fails without options
 	c := &Client{
 		baseURL:   baseURL,
 		apiPrefix: "/mitto", // Default API prefix
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		jar:       jar,
+	}
+	c.httpClient = &http.Client{
+		Timeout:   30 * time.Second,
+		Jar:       jar,
+		Transport: &csrfTransport{client: c, base: http.DefaultTransport},
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -70,6 +88,8 @@ type SessionInfo struct {
 	Status       string `json:"status,omitempty"`
 	CreatedAt    string `json:"created_at,omitempty"`
 	UpdatedAt    string `json:"updated_at,omitempty"`
+	EventCount   int    `json:"event_count,omitempty"`
+	Archived     bool   `json:"archived,omitempty"`
 }
 
 // CreateSessionRequest represents a request to create a new session.
@@ -460,6 +480,8 @@ type SetPeriodicRequest struct {
 	PromptName    string            `json:"prompt_name,omitempty"`
 	Prompt        string            `json:"prompt,omitempty"`
 	Frequency     PeriodicFrequency `json:"frequency"`
+	Cron          string            `json:"cron,omitempty"`     // 5-field cron expression, replaces Frequency
+	TimeZone      string            `json:"timezone,omitempty"` // IANA time zone of Cron and Frequency.At
 	Enabled       bool              `json:"enabled"`
 	MaxIterations int               `json:"max_iterations,omitempty"`
 }
@@ -469,6 +491,8 @@ type PeriodicConfig struct {
 	Prompt          string            `json:"prompt,omitempty"`
 	PromptName      string            `json:"prompt_name,omitempty"`
 	Frequency       PeriodicFrequency `json:"frequency"`
+	Cron            string            `json:"cron,omitempty"`
+	TimeZone        string            `json:"timezone,omitempty"`
 	Enabled         bool              `json:"enabled"`
 	MaxIterations   int               `json:"max_iterations,omitempty"`
 	NextScheduledAt string            `json:"next_scheduled_at,omitempty"`
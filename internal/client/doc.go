@@ -1,8 +1,7 @@
 // Package client provides a Go client for connecting to the Mitto backend.
 //
-// This client is designed for internal use (no authentication) and is useful
-// for integration testing and CLI tools that need to connect to a running
-// Mitto server.
+// This client is useful for integration testing and CLI tools that need to
+// connect to a running Mitto server.
 //
 // # Basic Usage
 //
@@ -18,6 +17,32 @@
 //	    WorkingDir: "/path/to/project",
 //	})
 //
+// # Authentication
+//
+// Connections from localhost to the internal listener need no authentication.
+// For servers that require it, log in with the simple auth credentials, or
+// reuse the session token of a previous login:
+//
+//	c := client.New("https://mitto.example.com")
+//	if err := c.Login("admin", password, ""); err != nil {
+//	    log.Fatal(err)
+//	}
+//	token := c.SessionToken() // Save it for later runs
+//
+//	c = client.New("https://mitto.example.com", client.WithSessionToken(token))
+//
+// Users with two-factor authentication pass the code of their authenticator
+// app as the last argument of Login, which fails with ErrTOTPRequired without
+// it.
+//
+// The CSRF token required by state-changing requests on the external listener
+// is obtained and sent automatically.
+//
+// Scripts can use an API token instead, limited to the scopes it was created
+// with:
+//
+//	c := client.New("https://mitto.example.com", client.WithAPIToken(os.Getenv("MITTO_TOKEN")))
+//
 // # WebSocket Session
 //
 // Connect to a session for real-time interaction:
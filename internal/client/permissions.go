@@ -0,0 +1,112 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// PermissionOption is an option of a permission request.
+type PermissionOption struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Kind  string `json:"kind,omitempty"` // allow_once, allow_always, reject_once, reject_always
+}
+
+// PendingPermission is a permission request waiting for a decision.
+type PendingPermission struct {
+	Token       string             `json:"token"`
+	SessionID   string             `json:"session_id"`
+	SessionName string             `json:"session_name,omitempty"`
+	RequestID   string             `json:"request_id"`
+	Title       string             `json:"title"`
+	Description string             `json:"description,omitempty"`
+	Options     []PermissionOption `json:"options"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+}
+
+// PermissionDecision is the decision on a permission request.
+// Either Action ("approve" or "deny") or OptionID must be set.
+type PermissionDecision struct {
+	Action   string `json:"action,omitempty"`
+	OptionID string `json:"option_id,omitempty"`
+}
+
+// PermissionDecisionResult is the result of a permission decision.
+type PermissionDecisionResult struct {
+	Status    string `json:"status"` // approved or denied
+	SessionID string `json:"session_id"`
+	RequestID string `json:"request_id"`
+	OptionID  string `json:"option_id,omitempty"`
+}
+
+// ListPermissions returns the permission requests waiting for a decision.
+func (c *Client) ListPermissions() ([]PendingPermission, error) {
+	resp, err := c.httpClient.Get(c.apiURL("/api/permissions"))
+	if err != nil {
+		return nil, fmt.Errorf("list permissions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list permissions: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Permissions []PendingPermission `json:"permissions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("list permissions: decode: %w", err)
+	}
+	return result.Permissions, nil
+}
+
+// GetPermission returns the permission request of an approval token.
+func (c *Client) GetPermission(token string) (*PendingPermission, error) {
+	resp, err := c.httpClient.Get(c.apiURL("/api/permissions/" + url.PathEscape(token)))
+	if err != nil {
+		return nil, fmt.Errorf("get permission: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get permission: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var permission PendingPermission
+	if err := json.NewDecoder(resp.Body).Decode(&permission); err != nil {
+		return nil, fmt.Errorf("get permission: decode: %w", err)
+	}
+	return &permission, nil
+}
+
+// DecidePermission approves or denies the permission request of an approval token.
+func (c *Client) DecidePermission(token string, decision PermissionDecision) (*PermissionDecisionResult, error) {
+	body, err := json.Marshal(decision)
+	if err != nil {
+		return nil, fmt.Errorf("decide permission: marshal: %w", err)
+	}
+
+	resp, err := c.httpClient.Post(c.apiURL("/api/permissions/"+url.PathEscape(token)), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("decide permission: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("decide permission: status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result PermissionDecisionResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decide permission: decode: %w", err)
+	}
+	return &result, nil
+}
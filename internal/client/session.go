@@ -38,6 +38,10 @@ type SessionCallbacks struct {
 	// OnPermission is called when the agent requests permission.
 	OnPermission func(requestID, title, description string)
 
+	// OnUIPrompt is called when the session shows a prompt to the user, such as
+	// a permission request (promptType "permission").
+	OnUIPrompt func(requestID, promptType, title, question string)
+
 	// OnPromptReceived is called when a prompt is acknowledged.
 	OnPromptReceived func(promptID string)
 
@@ -156,7 +160,7 @@ func (c *Client) Connect(ctx context.Context, sessionID string, callbacks Sessio
 	u.Path = c.apiPrefix + "/api/sessions/" + url.PathEscape(sessionID) + "/ws"
 
 	// Connect
-	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), c.dialHeader())
 	if err != nil {
 		return nil, fmt.Errorf("websocket connect: %w", err)
 	}
@@ -484,6 +488,17 @@ func (s *Session) handleMessage(msg wsMessage) {
 			s.callbacks.OnPermission(data.RequestID, data.Title, data.Description)
 		}
 
+	case "ui_prompt":
+		var data struct {
+			RequestID  string `json:"request_id"`
+			PromptType string `json:"prompt_type"`
+			Title      string `json:"title"`
+			Question   string `json:"question"`
+		}
+		if json.Unmarshal(msg.Data, &data) == nil && s.callbacks.OnUIPrompt != nil {
+			s.callbacks.OnUIPrompt(data.RequestID, data.PromptType, data.Title, data.Question)
+		}
+
 	case "prompt_received":
 		var data struct {
 			PromptID string `json:"prompt_id"`
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// APIToken is an API token of the server. The secret is only known on creation.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreateAPITokenRequest is a request to create an API token.
+type CreateAPITokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresIn is a Go duration or a number of days ("30d"), empty for no expiry.
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// CreatedAPIToken is a newly created API token, with its secret.
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// ListAPITokens returns the API tokens of the server.
+func (c *Client) ListAPITokens() ([]APIToken, error) {
+	resp, err := c.httpClient.Get(c.apiURL("/api/tokens"))
+	if err != nil {
+		return nil, fmt.Errorf("list API tokens: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list API tokens: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Tokens []APIToken `json:"tokens"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("list API tokens: decode: %w", err)
+	}
+	return result.Tokens, nil
+}
+
+// CreateAPIToken creates an API token and returns it with its secret.
+func (c *Client) CreateAPIToken(req CreateAPITokenRequest) (*CreatedAPIToken, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("create API token: marshal: %w", err)
+	}
+
+	resp, err := c.httpClient.Post(c.apiURL("/api/tokens"), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create API token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("create API token: status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var token CreatedAPIToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("create API token: decode: %w", err)
+	}
+	return &token, nil
+}
+
+// RevokeAPIToken revokes the API token with the given ID or name.
+func (c *Client) RevokeAPIToken(ref string) error {
+	req, err := http.NewRequest(http.MethodDelete, c.apiURL("/api/tokens/"+url.PathEscape(ref)), nil)
+	if err != nil {
+		return fmt.Errorf("revoke API token: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("revoke API token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("revoke API token: status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
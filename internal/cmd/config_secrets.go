@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/mitto/internal/secrets"
+)
+
+var (
+	configSecretsService       string
+	configSecretsPasswordStdin bool
+)
+
+// configSecretsCmd represents the config secrets subcommand
+var configSecretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the credentials in the secret store",
+	Long: `Manage the credentials Mitto keeps in the secret store of this machine.
+
+The secret store is the Keychain on macOS. On Linux it is the freedesktop
+Secret Service (GNOME Keyring, KWallet, KeePassXC...) when one is running,
+or an encrypted file in the Mitto directory otherwise. The file is encrypted
+with the passphrase in MITTO_SECRETS_PASSPHRASE, or with a random machine key
+(kept in ~/.config/mitto/secrets.key) when it is not set. Set MITTO_SECRETS_BACKEND to "secret-service", "file" or
+"none" to force a backend.
+
+Accounts used by Mitto:
+  external-access   password of the external access (web.auth.simple)
+
+Examples:
+  mitto config secrets set external-access
+  mitto config secrets get external-access
+  mitto config secrets delete external-access`,
+}
+
+var configSecretsSetCmd = &cobra.Command{
+	Use:   "set <account>",
+	Short: "Store a credential in the secret store",
+	Long: `Store a credential in the secret store, replacing any previous value.
+
+The value is read from standard input (use --password-stdin in scripts).`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigSecretsSet,
+}
+
+var configSecretsGetCmd = &cobra.Command{
+	Use:   "get <account>",
+	Short: "Print a credential from the secret store",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigSecretsGet,
+}
+
+var configSecretsDeleteCmd = &cobra.Command{
+	Use:   "delete <account>",
+	Short: "Remove a credential from the secret store",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigSecretsDelete,
+}
+
+func init() {
+	configCmd.AddCommand(configSecretsCmd)
+	configSecretsCmd.AddCommand(configSecretsSetCmd)
+	configSecretsCmd.AddCommand(configSecretsGetCmd)
+	configSecretsCmd.AddCommand(configSecretsDeleteCmd)
+
+	configSecretsCmd.PersistentFlags().StringVar(&configSecretsService, "service", secrets.ServiceName,
+		"Service the credential belongs to")
+	configSecretsSetCmd.Flags().BoolVar(&configSecretsPasswordStdin, "password-stdin", false,
+		"Read the value from standard input without prompting")
+}
+
+// checkSecretStore fails when there is no secret store on this machine.
+func checkSecretStore() error {
+	if !secrets.IsSupported() {
+		return fmt.Errorf("no secret store available on this machine (backend: %s)", secrets.Backend())
+	}
+	return nil
+}
+
+func runConfigSecretsSet(cmd *cobra.Command, args []string) error {
+	if err := checkSecretStore(); err != nil {
+		return err
+	}
+
+	if !configSecretsPasswordStdin {
+		fmt.Fprint(os.Stderr, "Value: ")
+	}
+	value, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && (err != io.EOF || value == "") {
+		return fmt.Errorf("failed to read value: %w", err)
+	}
+	value = strings.TrimRight(value, "\r\n")
+	if value == "" {
+		return fmt.Errorf("the value can't be empty")
+	}
+
+	if err := secrets.Set(configSecretsService, args[0], value); err != nil {
+		return fmt.Errorf("failed to store %s: %w", args[0], err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Stored %s in %s\n", args[0], secrets.Backend())
+	return nil
+}
+
+func runConfigSecretsGet(cmd *cobra.Command, args []string) error {
+	if err := checkSecretStore(); err != nil {
+		return err
+	}
+	value, err := secrets.Get(configSecretsService, args[0])
+	if errors.Is(err, secrets.ErrNotFound) {
+		return fmt.Errorf("%s is not in the secret store", args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", args[0], err)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), value)
+	return nil
+}
+
+func runConfigSecretsDelete(cmd *cobra.Command, args []string) error {
+	if err := checkSecretStore(); err != nil {
+		return err
+	}
+	err := secrets.Delete(configSecretsService, args[0])
+	if errors.Is(err, secrets.ErrNotFound) {
+		return fmt.Errorf("%s is not in the secret store", args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", args[0], err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Deleted %s from %s\n", args[0], secrets.Backend())
+	return nil
+}
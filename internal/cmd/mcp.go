@@ -81,8 +81,9 @@ func runStandaloneMCPServer(ctx context.Context) error {
 		return fmt.Errorf("failed to get sessions directory: %w", err)
 	}
 
-	// Create session store
-	store, err := session.NewStore(sessionsDir)
+	// Create session store. Config is not loaded in MCP mode, so use the
+	// backend of the existing sessions directory.
+	store, err := session.NewStoreWithBackend(sessionsDir, session.DetectStoreBackend(sessionsDir))
 	if err != nil {
 		return fmt.Errorf("failed to create session store: %w", err)
 	}
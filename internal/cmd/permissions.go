@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/mitto/internal/client"
+)
+
+var (
+	permissionsServerURL string
+	permissionsOptionID  string
+)
+
+var permissionsCmd = &cobra.Command{
+	Use:   "permissions",
+	Short: "Answer pending permission requests of a running Mitto server",
+	Long: `Answer the permission requests that block background conversations of a
+running Mitto server, without opening the web interface.
+
+Each pending request has a short-lived approval token (also sent in the
+permission_requested webhook notifications). The approve and deny commands
+take either the token or the ID of the conversation waiting for permission.
+
+Servers with authentication need a session token saved by 'mitto remote
+login' (which asks for the two-factor authentication code of users that
+enabled it, or takes it with --code).`,
+}
+
+var permissionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List pending permission requests",
+	Args:  cobra.NoArgs,
+	RunE:  runPermissionsList,
+}
+
+var permissionsApproveCmd = &cobra.Command{
+	Use:   "approve <token|session-id>",
+	Short: "Approve a pending permission request",
+	Long: `Approve a pending permission request.
+
+By default the agent's "allow once" option is selected (or "allow always" if
+that is the only allow option). Use --option to select a specific option.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPermissionsDecide(newServerClient(permissionsServerURL, ""), args[0], "approve", permissionsOptionID)
+	},
+}
+
+var permissionsDenyCmd = &cobra.Command{
+	Use:   "deny <token|session-id>",
+	Short: "Deny a pending permission request",
+	Long: `Deny a pending permission request.
+
+The agent's "reject once" option is selected (or "reject always"); requests
+without a reject option are cancelled.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPermissionsDecide(newServerClient(permissionsServerURL, ""), args[0], "deny", "")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(permissionsCmd)
+	permissionsCmd.AddCommand(permissionsListCmd)
+	permissionsCmd.AddCommand(permissionsApproveCmd)
+	permissionsCmd.AddCommand(permissionsDenyCmd)
+
+	permissionsCmd.PersistentFlags().StringVar(&permissionsServerURL, "url", "",
+		"URL of the Mitto server (default: http://127.0.0.1:<web.port>)")
+	permissionsApproveCmd.Flags().StringVar(&permissionsOptionID, "option", "",
+		"ID of the option to select (see 'mitto permissions list')")
+}
+
+// newServerClient returns a client for the Mitto server at url, or at the
+// local port of the configuration when url is empty. The client authenticates
+// with token (a session or API token), or with the token saved by
+// 'mitto remote login' for the server.
+func newServerClient(url, token string) *client.Client {
+	port := 8080
+	var opts []client.Option
+	if cfg != nil {
+		if cfg.Web.Port != 0 {
+			port = cfg.Web.Port
+		}
+		opts = append(opts, client.WithAPIPrefix(cfg.Web.GetAPIPrefix()))
+	}
+	if url == "" {
+		url = fmt.Sprintf("http://127.0.0.1:%d", port)
+	}
+	url = strings.TrimRight(url, "/")
+	if token == "" {
+		token = loadRemoteCredentials()[url].Token
+	}
+	if strings.HasPrefix(token, apiTokenPrefix) {
+		opts = append(opts, client.WithAPIToken(token))
+	} else {
+		opts = append(opts, client.WithSessionToken(token))
+	}
+	return client.New(url, opts...)
+}
+
+func runPermissionsList(cmd *cobra.Command, args []string) error {
+	permissions, err := newServerClient(permissionsServerURL, "").ListPermissions()
+	if err != nil {
+		return err
+	}
+	if len(permissions) == 0 {
+		fmt.Println("No pending permission requests.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, p := range permissions {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		name := p.SessionName
+		if name == "" {
+			name = "(untitled)"
+		}
+		fmt.Fprintf(w, "Session:\t%s  %s\n", p.SessionID, name)
+		fmt.Fprintf(w, "Request:\t%s\n", p.Title)
+		for _, opt := range p.Options {
+			fmt.Fprintf(w, "Option:\t%s  %s (%s)\n", opt.ID, opt.Label, opt.Kind)
+		}
+		fmt.Fprintf(w, "Expires:\tin %s\n", time.Until(p.ExpiresAt).Round(time.Second))
+		fmt.Fprintf(w, "Token:\t%s\n", p.Token)
+	}
+	return w.Flush()
+}
+
+func runPermissionsDecide(c *client.Client, ref, action, optionID string) error {
+	// Session IDs never contain a dot; approval tokens always do
+	token := ref
+	if !strings.Contains(ref, ".") {
+		permissions, err := c.ListPermissions()
+		if err != nil {
+			return err
+		}
+		token = ""
+		for _, p := range permissions {
+			if p.SessionID == ref {
+				token = p.Token
+				break
+			}
+		}
+		if token == "" {
+			return fmt.Errorf("no pending permission request for session %s", ref)
+		}
+	}
+
+	decision := client.PermissionDecision{Action: action}
+	if action == "approve" && optionID != "" {
+		decision = client.PermissionDecision{OptionID: optionID}
+	}
+	result, err := c.DecidePermission(token, decision)
+	if err != nil {
+		return err
+	}
+	if result.OptionID != "" {
+		fmt.Printf("Permission %s (option %s) in session %s\n", result.Status, result.OptionID, result.SessionID)
+	} else {
+		fmt.Printf("Permission %s in session %s\n", result.Status, result.SessionID)
+	}
+	return nil
+}
@@ -0,0 +1,915 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/client"
+	"github.com/inercia/mitto/internal/fileutil"
+	"github.com/inercia/mitto/internal/session"
+)
+
+const (
+	// remoteURLEnv and remoteTokenEnv are the environment variables with the
+	// defaults of the --url and --token flags of 'mitto remote'.
+	remoteURLEnv   = "MITTO_REMOTE_URL"
+	remoteTokenEnv = "MITTO_REMOTE_TOKEN"
+
+	// remoteErrorGracePeriod is how long 'mitto remote send' waits after an
+	// error before checking whether the agent is still working on the prompt
+	// (e.g. it was restarted and the prompt retried).
+	remoteErrorGracePeriod = 2 * time.Second
+)
+
+var (
+	remoteServerURL string
+	remoteToken     string
+
+	remoteLoginUsername      string
+	remoteLoginPasswordStdin bool
+	remoteLoginCode          string
+
+	remoteListAll bool
+
+	remoteNewWorkspace  string
+	remoteNewACPServer  string
+	remoteNewName       string
+	remoteNewPromptName string
+
+	remoteSendNoWait  bool
+	remoteSendTimeout time.Duration
+
+	remoteTailFollow bool
+	remoteTailLines  int
+
+	remoteQueuePromptName string
+
+	remotePeriodicPrompt        string
+	remotePeriodicPromptName    string
+	remotePeriodicEvery         time.Duration
+	remotePeriodicAt            string
+	remotePeriodicCron          string
+	remotePeriodicTimeZone      string
+	remotePeriodicMaxIterations int
+	remotePeriodicDisabled      bool
+
+	remoteArchiveUndo bool
+
+	remoteApproveOption string
+)
+
+var remoteCmd = &cobra.Command{
+	Use:   "remote",
+	Short: "Control the conversations of a running Mitto server",
+	Long: `Control the conversations of a running Mitto server from a terminal, an SSH
+session or a script, without the web interface.
+
+The server is given with --url (or $MITTO_REMOTE_URL), and defaults to the
+local port of the configuration. Servers that require authentication accept
+the session token saved by 'mitto remote login', or a session or API token
+(see 'mitto tokens') given with --token (or $MITTO_REMOTE_TOKEN).
+
+Example:
+  mitto remote --url https://mitto.example.com login --username admin
+  mitto remote ls
+  mitto remote new --workspace ~/src/project
+  mitto remote send <session-id> "Fix the failing tests"
+  mitto remote tail -f <session-id>`,
+}
+
+var remoteLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Log in to a Mitto server and save the session token",
+	Long: `Log in with the username and password of the server's simple auth, and save
+the session token for the next 'mitto remote' and 'mitto permissions' commands.
+
+The password is read from standard input, without echoing it on a terminal
+(use --password-stdin in scripts).
+Users with two-factor authentication are asked for the code of their
+authenticator app, unless given with --code; with --password-stdin, it is read
+from the line after the password.`,
+	Args: cobra.NoArgs,
+	RunE: runRemoteLogin,
+}
+
+var remoteLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Log out from a Mitto server and forget the session token",
+	Args:  cobra.NoArgs,
+	RunE:  runRemoteLogout,
+}
+
+var remoteListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List conversations",
+	Args:    cobra.NoArgs,
+	RunE:    runRemoteList,
+}
+
+var remoteNewCmd = &cobra.Command{
+	Use:   "new",
+	Short: "Create a conversation",
+	Long: `Create a conversation in the workspace of a folder, and print its ID.
+
+With --prompt-name, the named workspace prompt is queued as the first message.`,
+	Args: cobra.NoArgs,
+	RunE: runRemoteNew,
+}
+
+var remoteSendCmd = &cobra.Command{
+	Use:   "send <session-id> <message...|->",
+	Short: "Send a message and stream the response",
+	Long: `Send a message to a conversation and print the response of the agent as it
+arrives. Use "-" to read the message from standard input.
+
+The command exits when the agent finishes its turn, or with a non-zero status
+when the agent fails or --timeout expires (the turn is then cancelled).`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runRemoteSend,
+}
+
+var remoteTailCmd = &cobra.Command{
+	Use:   "tail <session-id>",
+	Short: "Print the last events of a conversation",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRemoteTail,
+}
+
+var remoteQueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Manage the message queue of a conversation",
+}
+
+var remoteQueueAddCmd = &cobra.Command{
+	Use:   "add <session-id> [message...|-]",
+	Short: "Queue a message, sent when the agent is idle",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runRemoteQueueAdd,
+}
+
+var remoteQueueListCmd = &cobra.Command{
+	Use:     "ls <session-id>",
+	Aliases: []string{"list"},
+	Short:   "List the queued messages",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runRemoteQueueList,
+}
+
+var remotePeriodicCmd = &cobra.Command{
+	Use:   "periodic",
+	Short: "Manage the periodic prompt of a conversation",
+}
+
+var remotePeriodicSetCmd = &cobra.Command{
+	Use:   "set <session-id>",
+	Short: "Set the periodic prompt of a conversation",
+	Long: `Set the prompt sent periodically to a conversation, with either a frequency
+(--every, and --at for daily prompts) or a cron expression (--cron).
+
+Example:
+  mitto remote periodic set <session-id> --prompt "Check the CI" --every 30m
+  mitto remote periodic set <session-id> --prompt-name standup --cron "0 9 * * 1-5" --timezone Europe/Madrid`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRemotePeriodicSet,
+}
+
+var remoteArchiveCmd = &cobra.Command{
+	Use:   "archive <session-id>",
+	Short: "Archive a conversation (stopping its agent)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRemoteArchive,
+}
+
+var remoteApproveCmd = &cobra.Command{
+	Use:   "approve <token|session-id>",
+	Short: "Approve a pending permission request",
+	Long: `Approve a pending permission request, like 'mitto permissions approve'.
+
+By default the agent's "allow once" option is selected. Use --option to select
+a specific option.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPermissionsDecide(newRemoteClient(), args[0], "approve", remoteApproveOption)
+	},
+}
+
+var remoteDenyCmd = &cobra.Command{
+	Use:   "deny <token|session-id>",
+	Short: "Deny a pending permission request",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPermissionsDecide(newRemoteClient(), args[0], "deny", "")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(remoteCmd)
+	remoteCmd.AddCommand(remoteLoginCmd, remoteLogoutCmd, remoteListCmd, remoteNewCmd, remoteSendCmd,
+		remoteTailCmd, remoteQueueCmd, remotePeriodicCmd, remoteArchiveCmd, remoteApproveCmd, remoteDenyCmd)
+	remoteQueueCmd.AddCommand(remoteQueueAddCmd, remoteQueueListCmd)
+	remotePeriodicCmd.AddCommand(remotePeriodicSetCmd)
+
+	remoteCmd.PersistentFlags().StringVar(&remoteServerURL, "url", os.Getenv(remoteURLEnv),
+		"URL of the Mitto server (default: $"+remoteURLEnv+" or http://127.0.0.1:<web.port>)")
+	remoteCmd.PersistentFlags().StringVar(&remoteToken, "token", os.Getenv(remoteTokenEnv),
+		"Session or API token (default: $"+remoteTokenEnv+" or the token saved by 'mitto remote login')")
+
+	remoteLoginCmd.Flags().StringVarP(&remoteLoginUsername, "username", "u", "", "Username")
+	remoteLoginCmd.Flags().BoolVar(&remoteLoginPasswordStdin, "password-stdin", false, "Read the password from standard input without prompting")
+	remoteLoginCmd.Flags().StringVar(&remoteLoginCode, "code", "", "Two-factor authentication code (or recovery code)")
+	_ = remoteLoginCmd.MarkFlagRequired("username")
+
+	remoteListCmd.Flags().BoolVarP(&remoteListAll, "all", "a", false, "Include archived conversations")
+
+	remoteNewCmd.Flags().StringVarP(&remoteNewWorkspace, "workspace", "w", "", "Folder of the workspace (default: the server's default workspace)")
+	remoteNewCmd.Flags().StringVar(&remoteNewACPServer, "agent", "", "ACP server of the workspace, for folders with several workspaces")
+	remoteNewCmd.Flags().StringVar(&remoteNewName, "name", "", "Name of the conversation")
+	remoteNewCmd.Flags().StringVar(&remoteNewPromptName, "prompt-name", "", "Workspace prompt to queue as the first message")
+
+	remoteSendCmd.Flags().BoolVar(&remoteSendNoWait, "no-wait", false, "Exit once the message is received, without waiting for the response")
+	remoteSendCmd.Flags().DurationVar(&remoteSendTimeout, "timeout", 0, "Maximum time to wait for the response (0 for no limit)")
+
+	remoteTailCmd.Flags().BoolVarP(&remoteTailFollow, "follow", "f", false, "Keep printing new events")
+	remoteTailCmd.Flags().IntVarP(&remoteTailLines, "lines", "n", 20, "Number of past events to print")
+
+	remoteQueueAddCmd.Flags().StringVar(&remoteQueuePromptName, "prompt-name", "", "Queue a workspace prompt by name instead of a message")
+
+	remotePeriodicSetCmd.Flags().StringVar(&remotePeriodicPrompt, "prompt", "", "Prompt to send")
+	remotePeriodicSetCmd.Flags().StringVar(&remotePeriodicPromptName, "prompt-name", "", "Workspace prompt to send")
+	remotePeriodicSetCmd.Flags().DurationVar(&remotePeriodicEvery, "every", 0, "Frequency (e.g. 30m, 2h, 24h)")
+	remotePeriodicSetCmd.Flags().StringVar(&remotePeriodicAt, "at", "", "Time of day (HH:MM) of daily prompts")
+	remotePeriodicSetCmd.Flags().StringVar(&remotePeriodicCron, "cron", "", "Cron expression (e.g. \"0 9 * * 1-5\")")
+	remotePeriodicSetCmd.Flags().StringVar(&remotePeriodicTimeZone, "timezone", "", "IANA time zone of --at and --cron (default: UTC)")
+	remotePeriodicSetCmd.Flags().IntVar(&remotePeriodicMaxIterations, "max-iterations", 0, "Number of runs before the prompt stops (0 for no limit)")
+	remotePeriodicSetCmd.Flags().BoolVar(&remotePeriodicDisabled, "disabled", false, "Save the periodic prompt disabled")
+
+	remoteArchiveCmd.Flags().BoolVar(&remoteArchiveUndo, "undo", false, "Unarchive the conversation")
+
+	remoteApproveCmd.Flags().StringVar(&remoteApproveOption, "option", "",
+		"ID of the option to select (see 'mitto permissions list')")
+}
+
+// newRemoteClient returns the client of the server of the --url flag.
+func newRemoteClient() *client.Client {
+	return newServerClient(remoteServerURL, remoteToken)
+}
+
+// remoteCredential is the saved session token of a server.
+type remoteCredential struct {
+	Username string    `json:"username,omitempty"`
+	Token    string    `json:"token"`
+	SavedAt  time.Time `json:"saved_at"`
+}
+
+// loadRemoteCredentials returns the saved session tokens, by server URL.
+func loadRemoteCredentials() map[string]remoteCredential {
+	credentials := map[string]remoteCredential{}
+	path, err := appdir.RemoteCredentialsPath()
+	if err != nil {
+		return credentials
+	}
+	_ = fileutil.ReadJSON(path, &credentials)
+	return credentials
+}
+
+// saveRemoteCredentials saves the session tokens, readable only by the user.
+func saveRemoteCredentials(credentials map[string]remoteCredential) error {
+	if err := appdir.EnsureDir(); err != nil {
+		return err
+	}
+	path, err := appdir.RemoteCredentialsPath()
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteJSONAtomic(path, credentials, 0o600)
+}
+
+func runRemoteLogin(cmd *cobra.Command, args []string) error {
+	input := bufio.NewReader(cmd.InOrStdin())
+	var password string
+	var err error
+	if f, ok := cmd.InOrStdin().(*os.File); ok && !remoteLoginPasswordStdin && term.IsTerminal(int(f.Fd())) {
+		password, err = readLoginPassword(f)
+	} else {
+		password, err = readLoginInput(input, "Password")
+	}
+	if err != nil {
+		return err
+	}
+
+	c := newServerClient(remoteServerURL, "")
+	err = c.Login(remoteLoginUsername, password, remoteLoginCode)
+	if errors.Is(err, client.ErrTOTPRequired) {
+		code, readErr := readLoginInput(input, "Authentication code")
+		if readErr != nil {
+			return fmt.Errorf("%w (use --code)", err)
+		}
+		err = c.Login(remoteLoginUsername, password, code)
+	}
+	if err != nil {
+		return err
+	}
+
+	credentials := loadRemoteCredentials()
+	credentials[c.BaseURL()] = remoteCredential{
+		Username: remoteLoginUsername,
+		Token:    c.SessionToken(),
+		SavedAt:  time.Now().UTC(),
+	}
+	if err := saveRemoteCredentials(credentials); err != nil {
+		return fmt.Errorf("failed to save session token: %w", err)
+	}
+	fmt.Printf("Logged in to %s as %s\n", c.BaseURL(), remoteLoginUsername)
+	return nil
+}
+
+// readLoginInput reads a line of the login input, prompting for it unless
+// --password-stdin is set.
+func readLoginInput(input *bufio.Reader, prompt string) (string, error) {
+	if !remoteLoginPasswordStdin {
+		fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	}
+	line, err := input.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("failed to read %s: %w", strings.ToLower(prompt), err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readLoginPassword prompts for the password on a terminal without echoing it.
+func readLoginPassword(tty *os.File) (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(password), nil
+}
+
+func runRemoteLogout(cmd *cobra.Command, args []string) error {
+	c := newRemoteClient()
+	credentials := loadRemoteCredentials()
+	if _, ok := credentials[c.BaseURL()]; !ok {
+		return fmt.Errorf("not logged in to %s", c.BaseURL())
+	}
+	if err := c.Logout(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	delete(credentials, c.BaseURL())
+	if err := saveRemoteCredentials(credentials); err != nil {
+		return fmt.Errorf("failed to save session tokens: %w", err)
+	}
+	fmt.Printf("Logged out from %s\n", c.BaseURL())
+	return nil
+}
+
+func runRemoteList(cmd *cobra.Command, args []string) error {
+	sessions, err := newRemoteClient().ListSessions()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tAGENT\tUPDATED\tNAME")
+	for _, s := range sessions {
+		if s.Archived && !remoteListAll {
+			continue
+		}
+		status := s.Status
+		if s.Archived {
+			status = "archived"
+		}
+		name := s.Name
+		if name == "" {
+			name = "(untitled)"
+		}
+		updated := s.UpdatedAt
+		if t, err := time.Parse(time.RFC3339Nano, s.UpdatedAt); err == nil {
+			updated = t.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.SessionID, status, s.ACPServer, updated, name)
+	}
+	return w.Flush()
+}
+
+func runRemoteNew(cmd *cobra.Command, args []string) error {
+	req := client.CreateSessionRequest{
+		Name:              remoteNewName,
+		WorkingDir:        remoteNewWorkspace,
+		ACPServer:         remoteNewACPServer,
+		InitialPromptName: remoteNewPromptName,
+	}
+	if req.WorkingDir != "" {
+		// The folder is on the server: only expand the home directory of local servers
+		if dir, err := expandRemoteDir(req.WorkingDir, remoteServerURL == ""); err == nil {
+			req.WorkingDir = dir
+		}
+	}
+	s, err := newRemoteClient().CreateSession(req)
+	if err != nil {
+		return err
+	}
+	fmt.Println(s.SessionID)
+	return nil
+}
+
+// expandRemoteDir expands a leading "~/" of dir with the home directory, when local.
+func expandRemoteDir(dir string, local bool) (string, error) {
+	if !local || !strings.HasPrefix(dir, "~/") {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return home + dir[1:], nil
+}
+
+func runRemoteSend(cmd *cobra.Command, args []string) error {
+	message, err := readRemoteMessage(args[1:], cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+	sessionID := args[0]
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	if remoteSendTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, remoteSendTimeout)
+		defer cancelTimeout()
+	}
+
+	printer := newRemotePrinter(os.Stdout, sessionID)
+	var mu sync.Mutex
+	var lastError string
+	var sent bool
+	connected := make(chan struct{})
+	received := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	finish := func(err error) {
+		select {
+		case done <- err:
+		default:
+		}
+	}
+
+	var sess *client.Session
+	callbacks := printer.callbacks()
+	callbacks.OnConnected = func(sessionID, clientID, acpServer string) {
+		close(connected)
+	}
+	callbacks.OnPromptReceived = func(promptID string) {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+	}
+	callbacks.OnPromptComplete = func(eventCount int) {
+		finish(nil)
+	}
+	callbacks.OnError = func(message string) {
+		printer.error(message)
+		mu.Lock()
+		lastError = message
+		mu.Unlock()
+		// Not every error ends the turn (the agent may be restarted and the
+		// prompt retried): check whether the agent is still working on it
+		time.AfterFunc(remoteErrorGracePeriod, func() {
+			mu.Lock()
+			s := sess
+			mu.Unlock()
+			if s != nil {
+				_ = s.LoadEvents(1, 0, 0)
+			}
+		})
+	}
+	callbacks.OnEventsLoaded = func(events []client.SyncEvent, hasMore, isPrompting bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if sent && lastError != "" && !isPrompting {
+			finish(errors.New(lastError))
+		}
+	}
+	callbacks.OnSessionGone = func(string) {
+		finish(errors.New("the conversation was deleted"))
+	}
+	callbacks.OnDisconnected = func(err error) {
+		finish(fmt.Errorf("disconnected: %w", err))
+	}
+
+	conn, err := newRemoteClient().Connect(ctx, sessionID, callbacks)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	mu.Lock()
+	sess = conn
+	mu.Unlock()
+
+	select {
+	case <-connected:
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	// Loading events registers the connection as an observer of the session
+	if err := conn.LoadEvents(1, 0, 0); err != nil {
+		return fmt.Errorf("load events: %w", err)
+	}
+	mu.Lock()
+	sent = true
+	mu.Unlock()
+	if err := conn.SendPrompt(message); err != nil {
+		return fmt.Errorf("send prompt: %w", err)
+	}
+
+	if remoteSendNoWait {
+		select {
+		case <-received:
+			return nil
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		_ = conn.Cancel()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s (the turn was cancelled)", remoteSendTimeout)
+		}
+		return errors.New("cancelled")
+	}
+}
+
+func runRemoteTail(cmd *cobra.Command, args []string) error {
+	sessionID := args[0]
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	printer := newRemotePrinter(os.Stdout, sessionID)
+	connected := make(chan struct{})
+	loaded := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	finish := func(err error) {
+		select {
+		case done <- err:
+		default:
+		}
+	}
+
+	callbacks := printer.callbacks()
+	callbacks.OnConnected = func(sessionID, clientID, acpServer string) {
+		close(connected)
+	}
+	var once sync.Once
+	callbacks.OnEventsLoaded = func(events []client.SyncEvent, hasMore, isPrompting bool) {
+		once.Do(func() {
+			for _, ev := range events {
+				printer.event(ev)
+			}
+			loaded <- struct{}{}
+		})
+	}
+	callbacks.OnSessionGone = func(string) {
+		finish(errors.New("the conversation was deleted"))
+	}
+	callbacks.OnDisconnected = func(err error) {
+		finish(fmt.Errorf("disconnected: %w", err))
+	}
+
+	sess, err := newRemoteClient().Connect(ctx, sessionID, callbacks)
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	select {
+	case <-connected:
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+	if err := sess.LoadEvents(int64(remoteTailLines), 0, 0); err != nil {
+		return fmt.Errorf("load events: %w", err)
+	}
+	select {
+	case <-loaded:
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+	if !remoteTailFollow {
+		return nil
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func runRemoteQueueAdd(cmd *cobra.Command, args []string) error {
+	c := newRemoteClient()
+	var msg *client.QueuedMessage
+	var err error
+	if remoteQueuePromptName != "" {
+		if len(args) > 1 {
+			return errors.New("a message can't be given with --prompt-name")
+		}
+		msg, err = c.AddToQueueNamed(args[0], remoteQueuePromptName)
+	} else {
+		var message string
+		if message, err = readRemoteMessage(args[1:], cmd.InOrStdin()); err != nil {
+			return err
+		}
+		msg, err = c.AddToQueue(args[0], message)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(msg.ID)
+	return nil
+}
+
+func runRemoteQueueList(cmd *cobra.Command, args []string) error {
+	queue, err := newRemoteClient().ListQueue(args[0])
+	if err != nil {
+		return err
+	}
+	if len(queue.Messages) == 0 {
+		fmt.Println("The queue is empty.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tQUEUED\tMESSAGE")
+	for _, m := range queue.Messages {
+		text := m.Title
+		if text == "" {
+			text = m.Message
+		}
+		if line, _, _ := strings.Cut(text, "\n"); len(line) > 60 {
+			text = line[:57] + "..."
+		} else {
+			text = line
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", m.ID, m.QueuedAt, text)
+	}
+	return w.Flush()
+}
+
+func runRemotePeriodicSet(cmd *cobra.Command, args []string) error {
+	req, err := remotePeriodicRequest()
+	if err != nil {
+		return err
+	}
+	periodic, err := newRemoteClient().SetPeriodic(args[0], req)
+	if err != nil {
+		return err
+	}
+
+	state := "enabled"
+	if !periodic.Enabled {
+		state = "disabled"
+	}
+	fmt.Printf("Periodic prompt %s", state)
+	if periodic.NextScheduledAt != "" {
+		fmt.Printf(", next run at %s", periodic.NextScheduledAt)
+	}
+	fmt.Println()
+	return nil
+}
+
+// remotePeriodicRequest builds the periodic prompt of the flags of 'periodic set'.
+func remotePeriodicRequest() (client.SetPeriodicRequest, error) {
+	req := client.SetPeriodicRequest{
+		Prompt:        remotePeriodicPrompt,
+		PromptName:    remotePeriodicPromptName,
+		Cron:          remotePeriodicCron,
+		TimeZone:      remotePeriodicTimeZone,
+		Enabled:       !remotePeriodicDisabled,
+		MaxIterations: remotePeriodicMaxIterations,
+	}
+	if (req.Prompt == "") == (req.PromptName == "") {
+		return req, errors.New("exactly one of --prompt and --prompt-name is required")
+	}
+
+	switch {
+	case req.Cron != "" && remotePeriodicEvery > 0:
+		return req, errors.New("--every and --cron can't be used together")
+	case req.Cron != "":
+		// The frequency is ignored, but must still be valid
+		req.Frequency = client.PeriodicFrequency{Value: 1, Unit: "days"}
+	case remotePeriodicEvery > 0:
+		frequency, err := periodicFrequency(remotePeriodicEvery)
+		if err != nil {
+			return req, err
+		}
+		req.Frequency = frequency
+	default:
+		return req, errors.New("one of --every and --cron is required")
+	}
+
+	if remotePeriodicAt != "" {
+		if req.Frequency.Unit != "days" || req.Cron != "" {
+			return req, errors.New("--at requires --every with a number of days (e.g. 24h)")
+		}
+		req.Frequency.At = remotePeriodicAt
+	}
+	return req, nil
+}
+
+// periodicFrequency converts a duration to the largest unit dividing it.
+func periodicFrequency(d time.Duration) (client.PeriodicFrequency, error) {
+	switch {
+	case d < time.Minute || d%time.Minute != 0:
+		return client.PeriodicFrequency{}, fmt.Errorf("invalid frequency %s (must be a number of minutes)", d)
+	case d%(24*time.Hour) == 0:
+		return client.PeriodicFrequency{Value: int(d / (24 * time.Hour)), Unit: "days"}, nil
+	case d%time.Hour == 0:
+		return client.PeriodicFrequency{Value: int(d / time.Hour), Unit: "hours"}, nil
+	default:
+		return client.PeriodicFrequency{Value: int(d / time.Minute), Unit: "minutes"}, nil
+	}
+}
+
+func runRemoteArchive(cmd *cobra.Command, args []string) error {
+	if err := newRemoteClient().ArchiveSession(args[0], !remoteArchiveUndo); err != nil {
+		return err
+	}
+	if remoteArchiveUndo {
+		fmt.Printf("Unarchived %s\n", args[0])
+	} else {
+		fmt.Printf("Archived %s\n", args[0])
+	}
+	return nil
+}
+
+// readRemoteMessage returns the message of args, read from stdin if it is "-".
+func readRemoteMessage(args []string, stdin io.Reader) (string, error) {
+	message := strings.Join(args, " ")
+	if len(args) == 1 && args[0] == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read message: %w", err)
+		}
+		message = string(data)
+	}
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return "", errors.New("the message is empty")
+	}
+	return message, nil
+}
+
+// remotePrinter prints the events of a conversation as text.
+type remotePrinter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	sessionID string
+	toolCalls map[string]string // Titles of the tool calls, by ID
+}
+
+func newRemotePrinter(w io.Writer, sessionID string) *remotePrinter {
+	return &remotePrinter{w: w, sessionID: sessionID, toolCalls: make(map[string]string)}
+}
+
+// callbacks returns the session callbacks printing the live events.
+func (p *remotePrinter) callbacks() client.SessionCallbacks {
+	return client.SessionCallbacks{
+		OnUserPrompt: func(senderID, promptID, message string) {
+			p.userPrompt(message)
+		},
+		OnAgentMessage: p.agentMessage,
+		OnAgentThought: p.agentThought,
+		OnToolCall:     p.toolCall,
+		OnToolUpdate:   p.toolUpdate,
+		OnFileWrite: func(path string, size int) {
+			p.printf("📝 %s (%d bytes)\n", path, size)
+		},
+		OnUIPrompt: func(requestID, promptType, title, question string) {
+			if promptType == "permission" {
+				p.printf("🔐 %s (mitto remote approve %s)\n", title, p.sessionID)
+			} else if question != "" {
+				p.printf("❓ %s\n", question)
+			}
+		},
+		OnError: p.error,
+	}
+}
+
+// event prints a recorded event.
+func (p *remotePrinter) event(ev client.SyncEvent) {
+	if ev.Data == nil {
+		return
+	}
+	data, err := session.DecodeEventData(session.Event{Type: session.EventType(ev.Type), Data: ev.Data})
+	if err != nil {
+		return
+	}
+	switch d := data.(type) {
+	case session.UserPromptData:
+		p.userPrompt(d.Message)
+	case session.AgentMessageData:
+		p.agentMessage(d.Text)
+	case session.AgentThoughtData:
+		p.agentThought(d.Text)
+	case session.ToolCallData:
+		p.toolCall(d.ToolCallID, d.Title, d.Status)
+	case session.ToolCallUpdateData:
+		if d.Status != nil {
+			p.toolUpdate(d.ToolCallID, *d.Status)
+		}
+	case session.PermissionData:
+		p.printf("🔐 %s: %s\n", d.Title, d.Outcome)
+	case session.FileOperationData:
+		if ev.Type == string(session.EventTypeFileWrite) {
+			p.printf("📝 %s (%d bytes)\n", d.Path, d.Size)
+		}
+	case session.ErrorData:
+		p.error(d.Message)
+	}
+}
+
+func (p *remotePrinter) printf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, format, args...)
+}
+
+func (p *remotePrinter) userPrompt(message string) {
+	p.printf("\n> %s\n\n", strings.ReplaceAll(strings.TrimSpace(message), "\n", "\n> "))
+}
+
+func (p *remotePrinter) agentMessage(html string) {
+	if text := htmlToText(html); text != "" {
+		p.printf("%s\n", text)
+	}
+}
+
+func (p *remotePrinter) agentThought(text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		p.printf("💭 %s\n", text)
+	}
+}
+
+func (p *remotePrinter) toolCall(id, title, status string) {
+	p.mu.Lock()
+	p.toolCalls[id] = title
+	p.mu.Unlock()
+	p.printf("🔧 %s (%s)\n", title, status)
+}
+
+// toolUpdate prints the tool calls that finished.
+func (p *remotePrinter) toolUpdate(id, status string) {
+	if status != "completed" && status != "failed" {
+		return
+	}
+	p.mu.Lock()
+	title := p.toolCalls[id]
+	p.mu.Unlock()
+	if title == "" {
+		title = id
+	}
+	p.printf("🔧 %s (%s)\n", title, status)
+}
+
+func (p *remotePrinter) error(message string) {
+	p.printf("❌ %s\n", message)
+}
+
+// htmlBlockEnd matches the tags ending a line of the HTML of agent messages.
+var htmlBlockEnd = regexp.MustCompile(`(?i)<br\s*/?>|</(p|li|h[1-6]|pre|div|tr|blockquote)>`)
+
+// htmlToText converts the HTML of an agent message to plain text.
+func htmlToText(html string) string {
+	return strings.TrimSpace(session.StripHTML(htmlBlockEnd.ReplaceAllString(html, "$0\n")))
+}
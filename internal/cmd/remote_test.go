@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/mitto/internal/client"
+)
+
+func TestPeriodicFrequency(t *testing.T) {
+	tests := []struct {
+		every   time.Duration
+		want    client.PeriodicFrequency
+		wantErr bool
+	}{
+		{30 * time.Minute, client.PeriodicFrequency{Value: 30, Unit: "minutes"}, false},
+		{90 * time.Minute, client.PeriodicFrequency{Value: 90, Unit: "minutes"}, false},
+		{2 * time.Hour, client.PeriodicFrequency{Value: 2, Unit: "hours"}, false},
+		{48 * time.Hour, client.PeriodicFrequency{Value: 2, Unit: "days"}, false},
+		{30 * time.Second, client.PeriodicFrequency{}, true},
+		{90 * time.Second, client.PeriodicFrequency{}, true},
+	}
+	for _, tt := range tests {
+		got, err := periodicFrequency(tt.every)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("periodicFrequency(%s) error = %v, wantErr %v", tt.every, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("periodicFrequency(%s) = %+v, want %+v", tt.every, got, tt.want)
+		}
+	}
+}
+
+func TestRemotePeriodicRequest(t *testing.T) {
+	defer func() {
+		remotePeriodicPrompt, remotePeriodicPromptName, remotePeriodicCron, remotePeriodicAt = "", "", "", ""
+		remotePeriodicEvery = 0
+	}()
+
+	tests := []struct {
+		name       string
+		prompt     string
+		promptName string
+		every      time.Duration
+		at         string
+		cron       string
+		wantErr    bool
+	}{
+		{"every", "Check the CI", "", 30 * time.Minute, "", "", false},
+		{"daily at", "", "standup", 24 * time.Hour, "09:00", "", false},
+		{"cron", "Check the CI", "", 0, "", "0 9 * * 1-5", false},
+		{"no prompt", "", "", time.Hour, "", "", true},
+		{"both prompts", "a", "b", time.Hour, "", "", true},
+		{"no schedule", "a", "", 0, "", "", true},
+		{"every and cron", "a", "", time.Hour, "", "0 9 * * *", true},
+		{"at without days", "a", "", time.Hour, "09:00", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remotePeriodicPrompt, remotePeriodicPromptName = tt.prompt, tt.promptName
+			remotePeriodicEvery, remotePeriodicAt, remotePeriodicCron = tt.every, tt.at, tt.cron
+			req, err := remotePeriodicRequest()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("remotePeriodicRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (req.Frequency.Value < 1 || req.Frequency.At != tt.at || req.Cron != tt.cron) {
+				t.Errorf("remotePeriodicRequest() = %+v", req)
+			}
+		})
+	}
+}
+
+func TestReadRemoteMessage(t *testing.T) {
+	if got, err := readRemoteMessage([]string{"Fix", "the", "tests"}, nil); err != nil || got != "Fix the tests" {
+		t.Errorf("readRemoteMessage(args) = %q, %v", got, err)
+	}
+	if got, err := readRemoteMessage([]string{"-"}, strings.NewReader("From stdin\n")); err != nil || got != "From stdin" {
+		t.Errorf("readRemoteMessage(-) = %q, %v", got, err)
+	}
+	if _, err := readRemoteMessage(nil, nil); err == nil {
+		t.Error("readRemoteMessage(no args) succeeded, want error")
+	}
+}
+
+func TestRemotePrinter_Event(t *testing.T) {
+	var buf bytes.Buffer
+	p := newRemotePrinter(&buf, "20260101-120000-abcd1234")
+	events := []client.SyncEvent{
+		{Seq: 1, Type: "user_prompt", Data: map[string]any{"message": "Run the tests"}},
+		{Seq: 2, Type: "tool_call", Data: map[string]any{"tool_call_id": "tc-1", "title": "go test ./...", "status": "pending"}},
+		{Seq: 3, Type: "tool_call_update", Data: map[string]any{"tool_call_id": "tc-1", "status": "completed"}},
+		{Seq: 4, Type: "agent_message", Data: map[string]any{"html": "<p>All tests <strong>pass</strong>.</p><ul><li>a &amp; b</li></ul>"}},
+		{Seq: 5, Type: "error", Data: map[string]any{"message": "rate limited"}},
+		{Seq: 6, Type: "session_end"},
+	}
+	for _, ev := range events {
+		p.event(ev)
+	}
+
+	want := "\n> Run the tests\n\n" +
+		"🔧 go test ./... (pending)\n" +
+		"🔧 go test ./... (completed)\n" +
+		"All tests pass.\na & b\n" +
+		"❌ rate limited\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
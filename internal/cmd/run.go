@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/logging"
+	"github.com/inercia/mitto/internal/web"
+)
+
+var (
+	runWorkspace  string
+	runPrompt     string
+	runPromptFile string
+	runPolicyFile string
+	runTimeout    time.Duration
+	runName       string
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run a prompt in a workspace without the web interface",
+	Long: `Run a single prompt in a workspace, unattended, for scripts and CI.
+
+Unlike 'mitto cli --once', the prompt runs in a recorded conversation created
+like the ones of the web interface: with the workspace agent, processors,
+restricted runner and permission rules. The conversation can be opened later
+in the web interface.
+
+Progress is written to standard output as JSON lines, ending with a "result"
+event. Permission requests are answered by the rules of --policy-file (and
+the configured permission rules); requests that would ask the user are
+denied. The command exits with a non-zero status when the agent fails or the
+timeout expires.
+
+Example:
+  mitto run --workspace ~/src/project --prompt "Fix the failing tests"
+  mitto run --workspace 5f0c... --prompt-file task.md --policy-file ci-policy.yaml --timeout 20m`,
+	Args: cobra.NoArgs,
+	RunE: runRun,
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringVarP(&runWorkspace, "workspace", "w", "", "Workspace UUID or folder (folders without a workspace use the --acp agent)")
+	runCmd.Flags().StringVarP(&runPrompt, "prompt", "p", "", "Prompt to send")
+	runCmd.Flags().StringVarP(&runPromptFile, "prompt-file", "f", "", "File with the prompt to send (- for standard input)")
+	runCmd.Flags().StringVar(&runPolicyFile, "policy-file", "", "Permission policy file (YAML or JSON) answering the permission requests")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 30*time.Minute, "Maximum duration of the run (0 for no limit)")
+	runCmd.Flags().StringVar(&runName, "name", "", "Name of the conversation (default: generated from the prompt)")
+	_ = runCmd.MarkFlagRequired("workspace")
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	prompt, err := readRunPrompt(cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+
+	var permissions *config.PermissionPolicyFile
+	if runPolicyFile != "" {
+		if permissions, err = config.LoadPermissionPolicyFile(runPolicyFile); err != nil {
+			return err
+		}
+	}
+
+	savedWorkspaces, err := config.LoadWorkspaces()
+	if err != nil {
+		return fmt.Errorf("failed to load workspaces: %w", err)
+	}
+	workspaces, workspaceUUID, err := resolveRunWorkspace(savedWorkspaces, runWorkspace, acpServerName, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	if runTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, runTimeout)
+		defer cancelTimeout()
+	}
+
+	out := newJSONLinesWriter(cmd.OutOrStdout())
+	start := time.Now()
+	run, err := web.NewHeadlessRun(ctx, web.HeadlessConfig{
+		MittoConfig: cfg,
+		Workspaces:  workspaces,
+		Workspace:   workspaceUUID,
+		SessionName: runName,
+		AutoApprove: GetEffectiveAutoApprove(cmd),
+		Permissions: permissions,
+		OnEvent:     out.write,
+		Logger:      logging.Web(),
+	})
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	runErr := run.Prompt(ctx, prompt)
+	result := web.HeadlessEvent{
+		Type:       web.HeadlessEventResult,
+		Time:       time.Now().UTC(),
+		SessionID:  run.SessionID(),
+		Status:     web.HeadlessStatusCompleted,
+		DurationMS: time.Since(start).Milliseconds(),
+		EventCount: run.EventCount(),
+	}
+	switch {
+	case runErr == nil:
+	case errors.Is(runErr, context.DeadlineExceeded):
+		result.Status = web.HeadlessStatusTimeout
+		runErr = fmt.Errorf("timed out after %s", runTimeout)
+	case errors.Is(runErr, context.Canceled):
+		result.Status = web.HeadlessStatusCancelled
+		runErr = errors.New("cancelled")
+	default:
+		result.Status = web.HeadlessStatusError
+	}
+	if runErr != nil {
+		result.Error = runErr.Error()
+	}
+	out.write(result)
+	if runErr != nil {
+		return fmt.Errorf("conversation %s: %w", result.SessionID, runErr)
+	}
+	return nil
+}
+
+// readRunPrompt returns the prompt of --prompt or --prompt-file.
+func readRunPrompt(stdin io.Reader) (string, error) {
+	if (runPrompt == "") == (runPromptFile == "") {
+		return "", errors.New("exactly one of --prompt and --prompt-file is required")
+	}
+	if runPrompt != "" {
+		return runPrompt, nil
+	}
+
+	var data []byte
+	var err error
+	if runPromptFile == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(runPromptFile)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt: %w", err)
+	}
+	prompt := strings.TrimSpace(string(data))
+	if prompt == "" {
+		return "", errors.New("the prompt is empty")
+	}
+	return prompt, nil
+}
+
+// resolveRunWorkspace finds the workspace of ref (a workspace UUID or folder)
+// and returns the workspaces with its UUID. A folder without a workspace gets
+// a temporary one with the agent acpServer (or the default agent).
+func resolveRunWorkspace(workspaces []config.WorkspaceSettings, ref, acpServer string, mittoConfig *config.Config) ([]config.WorkspaceSettings, string, error) {
+	for _, ws := range workspaces {
+		if ws.UUID != "" && ws.UUID == ref {
+			return workspaces, ws.UUID, nil
+		}
+	}
+
+	dir := ref
+	if strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[2:])
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid workspace %q: %w", ref, err)
+	}
+	for i := range workspaces {
+		ws := &workspaces[i]
+		if ws.WorkingDir == dir && (acpServer == "" || ws.ACPServer == acpServer) {
+			ws.EnsureUUID()
+			return workspaces, ws.UUID, nil
+		}
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, "", fmt.Errorf("workspace %q not found (not a workspace UUID or folder)", ref)
+	}
+	if mittoConfig == nil {
+		return nil, "", errors.New("configuration not loaded")
+	}
+	var server *config.ACPServer
+	if acpServer != "" {
+		if server, err = mittoConfig.GetServer(acpServer); err != nil {
+			return nil, "", err
+		}
+	} else if server = mittoConfig.DefaultServer(); server == nil {
+		return nil, "", errors.New("no ACP servers configured")
+	}
+	ws := config.WorkspaceSettings{ACPServer: server.Name, WorkingDir: dir}
+	ws.EnsureUUID()
+	return append(workspaces, ws), ws.UUID, nil
+}
+
+// jsonLinesWriter writes values as JSON lines, from any goroutine.
+type jsonLinesWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newJSONLinesWriter(w io.Writer) *jsonLinesWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &jsonLinesWriter{enc: enc}
+}
+
+func (w *jsonLinesWriter) write(ev web.HeadlessEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.enc.Encode(ev)
+}
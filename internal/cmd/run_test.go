@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+func TestResolveRunWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	other := t.TempDir()
+	mittoConfig := &config.Config{ACPServers: []config.ACPServer{{Name: "auggie", Command: "auggie --acp"}}}
+	saved := []config.WorkspaceSettings{
+		{UUID: "ws-claude", ACPServer: "claude", WorkingDir: dir},
+		{UUID: "ws-auggie", ACPServer: "auggie", WorkingDir: dir},
+	}
+
+	tests := []struct {
+		name      string
+		ref       string
+		acp       string
+		wantUUID  string
+		wantCount int
+	}{
+		{"uuid", "ws-auggie", "", "ws-auggie", 2},
+		{"folder", dir, "", "ws-claude", 2},
+		{"folder and agent", dir, "auggie", "ws-auggie", 2},
+		{"folder without workspace", other, "", "", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaces := append([]config.WorkspaceSettings(nil), saved...)
+			got, uuid, err := resolveRunWorkspace(workspaces, tt.ref, tt.acp, mittoConfig)
+			if err != nil {
+				t.Fatalf("resolveRunWorkspace() error = %v", err)
+			}
+			if len(got) != tt.wantCount {
+				t.Fatalf("got %d workspaces, want %d", len(got), tt.wantCount)
+			}
+			if tt.wantUUID != "" && uuid != tt.wantUUID {
+				t.Errorf("uuid = %q, want %q", uuid, tt.wantUUID)
+			}
+			if tt.wantUUID == "" {
+				added := got[len(got)-1]
+				if uuid == "" || added.UUID != uuid || added.WorkingDir != other || added.ACPServer != "auggie" {
+					t.Errorf("added workspace = %+v (uuid %q)", added, uuid)
+				}
+			}
+		})
+	}
+
+	if _, _, err := resolveRunWorkspace(saved, filepath.Join(dir, "missing"), "", mittoConfig); err == nil {
+		t.Error("resolveRunWorkspace(missing folder) succeeded, want error")
+	}
+}
+
+func TestReadRunPrompt(t *testing.T) {
+	defer func() { runPrompt, runPromptFile = "", "" }()
+
+	path := filepath.Join(t.TempDir(), "task.md")
+	if err := os.WriteFile(path, []byte("\nFix the tests\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		prompt  string
+		file    string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{"flag", "Hello", "", "", "Hello", false},
+		{"file", "", path, "", "Fix the tests", false},
+		{"stdin", "", "-", "  From stdin\n", "From stdin", false},
+		{"empty stdin", "", "-", "\n", "", true},
+		{"none", "", "", "", "", true},
+		{"both", "Hello", path, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runPrompt, runPromptFile = tt.prompt, tt.file
+			got, err := readRunPrompt(strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readRunPrompt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readRunPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
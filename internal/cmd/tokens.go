@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/mitto/internal/client"
+)
+
+// apiTokenPrefix is the prefix of the API tokens of the server, which tells
+// them apart from session tokens.
+const apiTokenPrefix = "mitto_pat_"
+
+var (
+	tokensServerURL string
+	tokensName      string
+	tokensScopes    []string
+	tokensExpires   string
+)
+
+var tokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Manage the API tokens of a running Mitto server",
+	Long: `Manage API tokens: named, revocable credentials for scripts, sent as
+"Authorization: Bearer <token>" headers (or with 'mitto remote --token').
+
+Each token carries one or more scopes:
+  sessions:read        list and read conversations
+  prompts:send         create conversations and send prompts to them
+  config:manage        change the configuration (workspaces, settings...)
+  permissions:approve  answer permission requests
+
+The server only stores a hash of the tokens: the token is shown once, when it
+is created. Tokens can't be used to manage tokens.
+
+Example:
+  mitto tokens create --name ci --scope sessions:read --scope prompts:send --expires 30d
+  mitto tokens list
+  mitto tokens revoke ci`,
+}
+
+var tokensListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	Args:  cobra.NoArgs,
+	RunE:  runTokensList,
+}
+
+var tokensCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an API token",
+	Args:  cobra.NoArgs,
+	RunE:  runTokensCreate,
+}
+
+var tokensRevokeCmd = &cobra.Command{
+	Use:   "revoke <id|name>",
+	Short: "Revoke an API token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := newServerClient(tokensServerURL, "").RevokeAPIToken(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked token %s\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tokensCmd)
+	tokensCmd.AddCommand(tokensListCmd)
+	tokensCmd.AddCommand(tokensCreateCmd)
+	tokensCmd.AddCommand(tokensRevokeCmd)
+
+	tokensCmd.PersistentFlags().StringVar(&tokensServerURL, "url", "",
+		"URL of the Mitto server (default: http://127.0.0.1:<web.port>)")
+	tokensCreateCmd.Flags().StringVar(&tokensName, "name", "", "Name of the token (required)")
+	tokensCreateCmd.Flags().StringSliceVar(&tokensScopes, "scope", nil,
+		"Scope of the token (repeatable: sessions:read, prompts:send, config:manage, permissions:approve)")
+	tokensCreateCmd.Flags().StringVar(&tokensExpires, "expires", "",
+		"Lifetime of the token, e.g. 30d or 12h (default: no expiry)")
+	_ = tokensCreateCmd.MarkFlagRequired("name")
+	_ = tokensCreateCmd.MarkFlagRequired("scope")
+}
+
+func runTokensCreate(cmd *cobra.Command, args []string) error {
+	token, err := newServerClient(tokensServerURL, "").CreateAPIToken(client.CreateAPITokenRequest{
+		Name:      tokensName,
+		Scopes:    tokensScopes,
+		ExpiresIn: tokensExpires,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Created token %s (%s). It won't be shown again.\n", token.Name, token.ID)
+	fmt.Println(token.Token)
+	return nil
+}
+
+func runTokensList(cmd *cobra.Command, args []string) error {
+	tokens, err := newServerClient(tokensServerURL, "").ListAPITokens()
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		fmt.Println("No API tokens.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSCOPES\tCREATED\tEXPIRES\tLAST USED")
+	for _, t := range tokens {
+		expires, lastUsed := "never", "never"
+		if t.ExpiresAt != nil {
+			expires = t.ExpiresAt.Local().Format(time.DateTime)
+			if time.Now().After(*t.ExpiresAt) {
+				expires += " (expired)"
+			}
+		}
+		if t.LastUsedAt != nil {
+			lastUsed = t.LastUsedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, strings.Join(t.Scopes, ","),
+			t.CreatedAt.Local().Format(time.DateTime), expires, lastUsed)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/mitto/internal/audit"
+	"github.com/inercia/mitto/internal/config"
+)
+
+var auditVerifyPath string
+
+// toolsAuditCmd represents the tools audit command
+var toolsAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Audit log tools",
+	Long: `Audit log tools.
+
+Commands for inspecting the audit log of security-relevant actions
+(enabled with web.audit_log in the configuration).`,
+}
+
+// toolsAuditVerifyCmd checks the hash chain of the audit log.
+var toolsAuditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the integrity of the audit log",
+	Long: `Check the integrity of the audit log.
+
+Every event of the audit log carries the hash of its contents and the hash
+of the previous event. This command checks the chain across the current
+file and the rotated ones, and reports the first event that was modified,
+removed, reordered or damaged.
+
+The log checked is the one configured in web.audit_log.path (audit.jsonl in
+the logs directory by default), or the one given with --path.
+
+Exits with an error when the chain is broken.`,
+	RunE: runAuditVerify,
+}
+
+func init() {
+	toolsCmd.AddCommand(toolsAuditCmd)
+	toolsAuditCmd.AddCommand(toolsAuditVerifyCmd)
+
+	toolsAuditVerifyCmd.Flags().StringVar(&auditVerifyPath, "path", "",
+		"Path of the audit log (defaults to the configured one)")
+}
+
+func runAuditVerify(_ *cobra.Command, _ []string) error {
+	path := auditVerifyPath
+	if path == "" {
+		var auditCfg *config.AuditLogConfig
+		if cfg != nil {
+			auditCfg = cfg.Web.AuditLog
+		}
+		var err error
+		if path, err = auditCfg.GetPath(); err != nil {
+			return fmt.Errorf("error getting the audit log path: %w", err)
+		}
+	}
+
+	fmt.Printf("📁 Audit log: %s\n", path)
+
+	result, err := audit.Verify(path)
+	if err != nil {
+		return fmt.Errorf("audit log verification failed: %w", err)
+	}
+
+	if result.Events == 0 {
+		fmt.Println("ℹ️  The audit log is empty")
+		return nil
+	}
+	fmt.Printf("✅ Chain intact: %d events in %d files (events %d to %d)\n",
+		result.Events, result.Files, result.FirstSeq, result.LastSeq)
+	if result.Truncated {
+		fmt.Printf("⚠️  Events before %d are no longer in the log (rotated files deleted)\n", result.FirstSeq)
+	}
+	return nil
+}
@@ -1,15 +1,13 @@
 package cmd
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/spf13/cobra"
 
 	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/session"
 )
 
 var (
@@ -32,8 +30,9 @@ The cleanup process:
 2. Identifies sessions with duplicate session_end events
 3. Removes duplicates, keeping only the first session_end
 4. Resequences all events with correct sequence numbers
-5. Updates the metadata.json event count
+5. Updates the event count in the session metadata
 
+Works with both the file and the SQLite session stores.
 Use --dry-run to preview changes without modifying files.`,
 	RunE: runSessionCleanup,
 }
@@ -59,29 +58,24 @@ func runSessionCleanup(_ *cobra.Command, _ []string) error {
 	}
 	fmt.Println()
 
-	entries, err := os.ReadDir(sessionsDir)
+	store, err := session.NewStoreWithBackend(sessionsDir, session.DetectStoreBackend(sessionsDir))
 	if err != nil {
-		return fmt.Errorf("error reading sessions directory: %w", err)
+		return fmt.Errorf("error opening session store: %w", err)
 	}
+	defer store.Close()
 
-	var totalSessions, cleanedSessions, duplicatesRemoved int
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		sessionID := entry.Name()
-		eventsPath := filepath.Join(sessionsDir, sessionID, "events.jsonl")
+	sessions, err := store.List()
+	if err != nil {
+		return fmt.Errorf("error listing sessions: %w", err)
+	}
 
-		if _, err := os.Stat(eventsPath); os.IsNotExist(err) {
-			continue
-		}
+	var totalSessions, cleanedSessions, duplicatesRemoved int
 
+	for _, meta := range sessions {
 		totalSessions++
-		removed, err := cleanupSession(eventsPath, sessionID)
+		removed, err := cleanupSession(store, meta.SessionID)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "⚠️  Error processing %s: %v\n", sessionID, err)
+			fmt.Fprintf(os.Stderr, "⚠️  Error processing %s: %v\n", meta.SessionID, err)
 			continue
 		}
 		if removed > 0 {
@@ -102,18 +96,10 @@ func runSessionCleanup(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-// cleanupEvent represents a session event (minimal for cleanup purposes).
-type cleanupEvent struct {
-	Seq       int64           `json:"seq"`
-	Type      string          `json:"type"`
-	Timestamp string          `json:"timestamp"`
-	Data      json.RawMessage `json:"data"`
-}
-
 // cleanupSession removes duplicate session_end events from a session.
 // Returns the number of duplicates removed.
-func cleanupSession(eventsPath, sessionID string) (int, error) {
-	events, err := readCleanupEvents(eventsPath)
+func cleanupSession(store *session.Store, sessionID string) (int, error) {
+	events, err := store.ReadEvents(sessionID)
 	if err != nil {
 		return 0, err
 	}
@@ -121,7 +107,7 @@ func cleanupSession(eventsPath, sessionID string) (int, error) {
 	// Find session_end events
 	var sessionEndIndices []int
 	for i, event := range events {
-		if event.Type == "session_end" {
+		if event.Type == session.EventTypeSessionEnd {
 			sessionEndIndices = append(sessionEndIndices, i)
 		}
 	}
@@ -151,9 +137,9 @@ func cleanupSession(eventsPath, sessionID string) (int, error) {
 
 	// Remove all but the first session_end
 	keepFirstSessionEnd := true
-	var newEvents []cleanupEvent
+	var newEvents []session.Event
 	for _, event := range events {
-		if event.Type == "session_end" {
+		if event.Type == session.EventTypeSessionEnd {
 			if keepFirstSessionEnd {
 				newEvents = append(newEvents, event)
 				keepFirstSessionEnd = false
@@ -169,94 +155,11 @@ func cleanupSession(eventsPath, sessionID string) (int, error) {
 		newEvents[i].Seq = int64(i + 1)
 	}
 
-	// Write back atomically
-	if err := writeCleanupEvents(eventsPath, newEvents); err != nil {
+	// Write back atomically, updating the metadata event count
+	if err := store.ReplaceEvents(sessionID, newEvents); err != nil {
 		return 0, fmt.Errorf("failed to write events: %w", err)
 	}
 
-	// Update metadata event count
-	if err := updateCleanupMetadata(eventsPath, len(newEvents)); err != nil {
-		fmt.Fprintf(os.Stderr, "   ⚠️  Warning: could not update metadata: %v\n", err)
-	}
-
 	fmt.Printf("   ✅ Removed %d duplicates, updated sequences\n", duplicates)
 	return duplicates, nil
 }
-
-// readCleanupEvents reads all events from a JSONL file.
-func readCleanupEvents(path string) ([]cleanupEvent, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	var events []cleanupEvent
-	scanner := bufio.NewScanner(f)
-	const maxScannerBuffer = 10 * 1024 * 1024
-	scanner.Buffer(make([]byte, 0, 64*1024), maxScannerBuffer)
-
-	for scanner.Scan() {
-		var event cleanupEvent
-		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
-		}
-		events = append(events, event)
-	}
-
-	return events, scanner.Err()
-}
-
-// writeCleanupEvents writes events to a JSONL file atomically.
-func writeCleanupEvents(path string, events []cleanupEvent) error {
-	tmpPath := path + ".tmp"
-	f, err := os.Create(tmpPath)
-	if err != nil {
-		return err
-	}
-
-	for _, event := range events {
-		data, err := json.Marshal(event)
-		if err != nil {
-			f.Close()
-			os.Remove(tmpPath)
-			return err
-		}
-		if _, err := f.Write(append(data, '\n')); err != nil {
-			f.Close()
-			os.Remove(tmpPath)
-			return err
-		}
-	}
-
-	if err := f.Close(); err != nil {
-		os.Remove(tmpPath)
-		return err
-	}
-
-	return os.Rename(tmpPath, path)
-}
-
-// updateCleanupMetadata updates the event_count in metadata.json.
-func updateCleanupMetadata(eventsPath string, count int) error {
-	metadataPath := filepath.Join(filepath.Dir(eventsPath), "metadata.json")
-
-	data, err := os.ReadFile(metadataPath)
-	if err != nil {
-		return err
-	}
-
-	var meta map[string]interface{}
-	if err := json.Unmarshal(data, &meta); err != nil {
-		return err
-	}
-
-	meta["event_count"] = count
-
-	newData, err := json.MarshalIndent(meta, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(metadataPath, append(newData, '\n'), 0644)
-}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/session"
+)
+
+var (
+	exportFormat string
+	exportZip    bool
+	exportOutput string
+)
+
+// toolsSessionExportCmd exports a conversation to a Markdown, HTML or JSON document.
+var toolsSessionExportCmd = &cobra.Command{
+	Use:   "export <session-id>",
+	Short: "Export a conversation to Markdown, HTML or JSON",
+	Long: `Export a conversation so it can be shared outside Mitto.
+
+Formats:
+  md    Markdown document (default)
+  html  Standalone HTML page, sanitized so it can be published safely
+  json  Session metadata and raw events, as a bundle that can be archived
+
+Images are inlined in HTML exports and embedded in JSON exports. Use --zip to
+write a zip archive with the document and all the images and files attached
+to the conversation instead.
+
+The document is written to standard output unless --output is given. With
+--zip and no --output, a file named after the conversation is created in the
+current directory.
+
+Examples:
+  # Print a conversation as Markdown
+  mitto tools session export 20240115-103000-abcd1234
+
+  # Save it as an HTML page
+  mitto tools session export 20240115-103000-abcd1234 --format html -o chat.html
+
+  # Create a zip with the Markdown document and its attachments
+  mitto tools session export 20240115-103000-abcd1234 --zip`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionExport,
+}
+
+func init() {
+	toolsSessionCmd.AddCommand(toolsSessionExportCmd)
+
+	toolsSessionExportCmd.Flags().StringVarP(&exportFormat, "format", "f", "md",
+		"Export format (md, html or json)")
+	toolsSessionExportCmd.Flags().BoolVar(&exportZip, "zip", false,
+		"Write a zip archive with the document and the conversation's images and files")
+	toolsSessionExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "",
+		"Output file (default: standard output, or a file named after the conversation with --zip)")
+}
+
+func runSessionExport(_ *cobra.Command, args []string) error {
+	sessionID := args[0]
+
+	format, err := session.ParseExportFormat(exportFormat)
+	if err != nil {
+		return err
+	}
+	opts := session.ExportOptions{Format: format, Zip: exportZip}
+
+	sessionsDir, err := appdir.SessionsDir()
+	if err != nil {
+		return fmt.Errorf("error getting sessions directory: %w", err)
+	}
+	store, err := session.NewStoreWithBackend(sessionsDir, session.DetectStoreBackend(sessionsDir))
+	if err != nil {
+		return fmt.Errorf("failed to open session store: %w", err)
+	}
+	defer store.Close()
+
+	meta, err := store.GetMetadata(sessionID)
+	if err != nil {
+		return fmt.Errorf("conversation %s: %w", sessionID, err)
+	}
+
+	output := exportOutput
+	if output == "" && opts.Zip {
+		output = session.ExportFileName(meta, opts)
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" && output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := store.Export(w, sessionID, opts); err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	if w != os.Stdout {
+		fmt.Fprintf(os.Stderr, "✅ Exported %s to %s\n", sessionID, output)
+	}
+	return nil
+}
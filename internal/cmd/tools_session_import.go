@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/session"
+)
+
+var (
+	importWorkingDir string
+	importACPServer  string
+)
+
+// toolsSessionImportCmd imports conversations exported from another Mitto instance.
+var toolsSessionImportCmd = &cobra.Command{
+	Use:   "import <bundle>...",
+	Short: "Import conversations exported with 'export --format json'",
+	Long: `Import conversations from JSON export bundles, to move work between machines.
+
+Bundles are created with:
+  mitto tools session export <session-id> --format json [--zip]
+
+Each imported conversation gets a new session ID. When a parent conversation
+and its children are imported together, the children are linked to the new
+parent; links to conversations that are not imported are dropped.
+
+The agent-side session can't be resumed on another machine, so imported
+conversations start a new ACP session (with the conversation history as
+context). Periodic prompts are imported disabled; re-enable them once the
+conversation should run on this machine.
+
+Examples:
+  # Import a conversation
+  mitto tools session import chat.zip
+
+  # Import a parent conversation and its child into a local project folder
+  mitto tools session import parent.json child.json --working-dir ~/src/project`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSessionImport,
+}
+
+func init() {
+	toolsSessionCmd.AddCommand(toolsSessionImportCmd)
+
+	toolsSessionImportCmd.Flags().StringVar(&importWorkingDir, "working-dir", "",
+		"Workspace folder for the imported conversations (default: keep the original folder)")
+	toolsSessionImportCmd.Flags().StringVar(&importACPServer, "acp", "",
+		"ACP server for the imported conversations (default: keep the original server)")
+}
+
+func runSessionImport(_ *cobra.Command, args []string) error {
+	bundles := make([]*session.ExportBundle, 0, len(args))
+	for _, path := range args {
+		bundle, err := readExportBundleFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		bundles = append(bundles, bundle)
+	}
+
+	opts := session.ImportOptions{
+		MigrationContext: buildMigrationContextFromConfig(cfg),
+		ACPServer:        importACPServer,
+	}
+	var defaultFlags map[string]bool
+	if cfg != nil && cfg.Conversations != nil {
+		defaultFlags = cfg.Conversations.DefaultFlags
+	}
+	opts.AdvancedSettings = session.DefaultFlagValues(defaultFlags)
+	if importWorkingDir != "" {
+		dir, err := filepath.Abs(importWorkingDir)
+		if err != nil {
+			return err
+		}
+		opts.WorkingDir = dir
+	}
+
+	sessionsDir, err := appdir.SessionsDir()
+	if err != nil {
+		return fmt.Errorf("error getting sessions directory: %w", err)
+	}
+	store, err := session.NewStoreWithBackend(sessionsDir, session.DetectStoreBackend(sessionsDir))
+	if err != nil {
+		return fmt.Errorf("failed to open session store: %w", err)
+	}
+	defer store.Close()
+
+	results, err := store.Import(bundles, opts)
+	if err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	for i, result := range results {
+		name := bundles[i].Metadata.Name
+		if name == "" {
+			name = result.OriginalSessionID
+		}
+		fmt.Printf("✅ %s → %s (%d events)\n", name, result.SessionID, result.Events)
+		if result.Renumbered {
+			fmt.Printf("   ⚠️  event sequence numbers were invalid and have been reassigned\n")
+		}
+	}
+	return nil
+}
+
+// readExportBundleFile reads an export bundle from a file.
+func readExportBundleFile(path string) (*session.ExportBundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return session.ReadExportBundleFrom(f, info.Size())
+}
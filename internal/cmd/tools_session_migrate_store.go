@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/session"
+)
+
+var (
+	migrateStoreFrom   string
+	migrateStoreTo     string
+	migrateStoreDryRun bool
+)
+
+// toolsSessionMigrateStoreCmd converts session data between store backends.
+var toolsSessionMigrateStoreCmd = &cobra.Command{
+	Use:   "migrate-store",
+	Short: "Convert session data between the file and SQLite store backends",
+	Long: `Convert session metadata and events between store backends.
+
+Mitto can persist conversations either as events.jsonl + metadata.json files
+in each session directory ("file", the default) or in an embedded SQLite
+database ("sqlite"). The backend is selected with session.store_backend in
+the configuration.
+
+This command copies every session from the source backend to the destination
+backend and then removes it from the source. Images, files, queues and other
+per-session data stay in the session directories and are shared by both
+backends.
+
+Stop Mitto before running this command, and update session.store_backend
+afterwards so that Mitto uses the converted data.
+
+Examples:
+  # Convert file sessions to SQLite
+  mitto tools session migrate-store --to sqlite
+
+  # Convert back to files
+  mitto tools session migrate-store --to file`,
+	RunE: runSessionMigrateStore,
+}
+
+func init() {
+	toolsSessionCmd.AddCommand(toolsSessionMigrateStoreCmd)
+
+	toolsSessionMigrateStoreCmd.Flags().StringVar(&migrateStoreTo, "to", "",
+		"Destination backend (file or sqlite)")
+	toolsSessionMigrateStoreCmd.Flags().StringVar(&migrateStoreFrom, "from", "",
+		"Source backend (default: the backend other than --to)")
+	toolsSessionMigrateStoreCmd.Flags().BoolVar(&migrateStoreDryRun, "dry-run", false,
+		"Show what would be converted without modifying anything")
+	_ = toolsSessionMigrateStoreCmd.MarkFlagRequired("to")
+}
+
+func runSessionMigrateStore(_ *cobra.Command, _ []string) error {
+	to, err := session.ParseStoreBackend(migrateStoreTo)
+	if err != nil {
+		return err
+	}
+	from := session.StoreBackendFile
+	if to == session.StoreBackendFile {
+		from = session.StoreBackendSQLite
+	}
+	if migrateStoreFrom != "" {
+		if from, err = session.ParseStoreBackend(migrateStoreFrom); err != nil {
+			return err
+		}
+	}
+
+	sessionsDir, err := appdir.SessionsDir()
+	if err != nil {
+		return fmt.Errorf("error getting sessions directory: %w", err)
+	}
+
+	fmt.Printf("📁 Sessions directory: %s\n", sessionsDir)
+	fmt.Printf("🔄 Converting %s → %s\n", from, to)
+	if migrateStoreDryRun {
+		fmt.Println("🔍 Dry-run mode - nothing will be modified")
+	}
+	fmt.Println()
+
+	result, err := session.ConvertStore(sessionsDir, from, to, buildMigrationContextFromConfig(cfg), migrateStoreDryRun)
+	if err != nil {
+		return fmt.Errorf("conversion failed: %w", err)
+	}
+
+	fmt.Printf("📊 Summary:\n")
+	fmt.Printf("   Sessions converted: %d\n", result.Sessions)
+	fmt.Printf("   Events converted: %d\n", result.Events)
+	if migrateStoreDryRun {
+		fmt.Println("\n💡 Run without --dry-run to apply changes")
+	} else if configuredStoreBackend() != to {
+		fmt.Printf("\n💡 Set session.store_backend to %q in your configuration to use the converted data\n", to)
+	}
+
+	return nil
+}
+
+// configuredStoreBackend returns the session store backend selected in the
+// loaded configuration (the file backend if unset or invalid).
+func configuredStoreBackend() session.StoreBackend {
+	if cfg == nil {
+		return session.StoreBackendFile
+	}
+	backend, err := session.ParseStoreBackend(cfg.Session.GetStoreBackend())
+	if err != nil {
+		return session.StoreBackendFile
+	}
+	return backend
+}
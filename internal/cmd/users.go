@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/web"
+)
+
+var usersPasswordStdin bool
+
+var usersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Help managing the user accounts of the web interface",
+	Long: `Help managing the user accounts of the web interface, configured in
+web.auth.users of the configuration file.
+
+Example:
+  mitto users hash-password
+  mitto users totp-reset alice`,
+}
+
+var usersHashPasswordCmd = &cobra.Command{
+	Use:   "hash-password",
+	Short: "Print the bcrypt hash of a password, for web.auth.users[].password_hash",
+	Long: `Print the bcrypt hash of a password, to be used as the password_hash of a
+user account in web.auth.users.
+
+The password is read from standard input (use --password-stdin in scripts).`,
+	Args: cobra.NoArgs,
+	RunE: runUsersHashPassword,
+}
+
+var usersTOTPResetCmd = &cobra.Command{
+	Use:   "totp-reset <username>",
+	Short: "Reset the two-factor authentication of a user",
+	Long: `Reset the two-factor authentication of a user who lost their authenticator
+app and recovery codes. The user logs in with the password alone, and can
+enroll again from the settings.
+
+This edits the data directory of this machine, and takes effect immediately,
+even if the server is running.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runUsersTOTPReset,
+}
+
+func init() {
+	rootCmd.AddCommand(usersCmd)
+	usersCmd.AddCommand(usersHashPasswordCmd)
+	usersCmd.AddCommand(usersTOTPResetCmd)
+
+	usersHashPasswordCmd.Flags().BoolVar(&usersPasswordStdin, "password-stdin", false,
+		"Read the password from standard input without prompting")
+}
+
+func runUsersHashPassword(cmd *cobra.Command, args []string) error {
+	if !usersPasswordStdin {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	password, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && (err != io.EOF || password == "") {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return fmt.Errorf("the password can't be empty")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(hash))
+	return nil
+}
+
+func runUsersTOTPReset(cmd *cobra.Command, args []string) error {
+	path, err := appdir.TOTPPath()
+	if err != nil {
+		return err
+	}
+	removed, err := web.NewTOTPStore(path).Reset(args[0])
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("user %s has no two-factor authentication", args[0])
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Reset the two-factor authentication of %s\n", args[0])
+	return nil
+}
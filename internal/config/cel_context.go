@@ -22,6 +22,9 @@ type PromptEnabledContext struct {
 	// Item contains the per-row item context for list menus (e.g. a beads issue row).
 	// All fields are empty strings when no item context is provided.
 	Item ItemContext
+	// ToolCall contains the tool call an agent asks permission for.
+	// Only populated when evaluating permission rules (see PermissionPolicy).
+	ToolCall ToolCallContext
 }
 
 // ACPContext holds ACP server context for CEL evaluation.
@@ -132,6 +135,28 @@ type ItemContext struct {
 	Kind string
 }
 
+// ToolCallContext holds the tool call of a permission request for CEL evaluation.
+type ToolCallContext struct {
+	// ID is the tool call identifier
+	ID string
+	// Kind is the ACP tool kind (e.g. "read", "edit", "delete", "execute", "fetch")
+	Kind string
+	// Title is the human-readable title of the tool call (e.g. "Run `make test`")
+	Title string
+	// Command is the command line the tool runs, from the raw input ("" if none)
+	Command string
+	// Paths are the absolute paths the tool call touches, from its locations and raw input
+	Paths []string
+	// OutsideWorkspace indicates whether any of Paths is outside the workspace folder
+	OutsideWorkspace bool
+	// Input is the raw input of the tool call, when it is a JSON object
+	Input map[string]any
+
+	// commandMatchAny makes commandMatches match when any segment of a chained
+	// command matches, instead of every segment (set for deny and ask rules)
+	commandMatchAny bool
+}
+
 // PermissionsContext holds session permission flags for CEL evaluation.
 // Values are resolved using session.GetFlagValue() which applies defaults.
 type PermissionsContext struct {
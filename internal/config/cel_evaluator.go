@@ -104,6 +104,18 @@ func NewCELEvaluator() (*CELEvaluator, error) {
 		// whether a compiled expression touches this namespace.
 		cel.Variable("item", cel.MapType(cel.StringType, cel.DynType)),
 
+		// Tool call variables (permission rules only)
+		cel.Variable("toolCall.id", cel.StringType),
+		cel.Variable("toolCall.kind", cel.StringType),
+		cel.Variable("toolCall.title", cel.StringType),
+		cel.Variable("toolCall.command", cel.StringType),
+		cel.Variable("toolCall.paths", cel.ListType(cel.StringType)),
+		cel.Variable("toolCall.outsideWorkspace", cel.BoolType),
+		cel.Variable("toolCall.input", cel.MapType(cel.StringType, cel.DynType)),
+		// Internal: how commandMatches treats chained commands (see
+		// ToolCallContext.commandMatchAny), injected by its macro.
+		cel.Variable("__mitto_commandMatchAny", cel.BoolType),
+
 		// commandExists(name) bool — context-free; bound once here.
 		// Returns true if the given command name is found in the system PATH.
 		cel.Function("commandExists",
@@ -163,6 +175,20 @@ func NewCELEvaluator() (*CELEvaluator, error) {
 				cel.FunctionBinding(mittoMatchesServerType),
 			),
 		),
+		cel.Function("__mitto_pathMatches",
+			cel.Overload("__mitto_pathMatches_list_string_string",
+				[]*cel.Type{cel.ListType(cel.StringType), cel.StringType, cel.StringType},
+				cel.BoolType,
+				cel.FunctionBinding(mittoPathMatches),
+			),
+		),
+		cel.Function("__mitto_commandMatches",
+			cel.Overload("__mitto_commandMatches_string_string_bool",
+				[]*cel.Type{cel.StringType, cel.StringType, cel.BoolType},
+				cel.BoolType,
+				cel.FunctionBinding(mittoCommandMatches),
+			),
+		),
 		cel.Function("__mitto_fileExists",
 			cel.Overload("__mitto_fileExists_string_string",
 				[]*cel.Type{cel.StringType, cel.StringType},
@@ -187,6 +213,8 @@ func NewCELEvaluator() (*CELEvaluator, error) {
 			cel.ReceiverMacro("hasAllPatterns", 1, toolsHasAllPatternsMacro),
 			cel.ReceiverMacro("hasAnyPattern", 1, toolsHasAnyPatternMacro),
 			cel.ReceiverMacro("matchesServerType", 1, acpMatchesServerTypeMacro),
+			cel.ReceiverMacro("pathMatches", 1, toolCallPathMatchesMacro),
+			cel.ReceiverMacro("commandMatches", 1, toolCallCommandMatchesMacro),
 			cel.GlobalMacro("fileExists", 1, fileExistsMacro),
 			cel.GlobalMacro("dirExists", 1, dirExistsMacro),
 		),
@@ -333,9 +361,27 @@ func buildActivation(ctx *PromptEnabledContext) map[string]any {
 			"priority": ctx.Item.Priority,
 			"kind":     ctx.Item.Kind,
 		},
+
+		"toolCall.id":               ctx.ToolCall.ID,
+		"toolCall.kind":             ctx.ToolCall.Kind,
+		"toolCall.title":            ctx.ToolCall.Title,
+		"toolCall.command":          ctx.ToolCall.Command,
+		"toolCall.paths":            ctx.ToolCall.Paths,
+		"toolCall.outsideWorkspace": ctx.ToolCall.OutsideWorkspace,
+		"toolCall.input":            toolCallInput(ctx.ToolCall.Input),
+		"__mitto_commandMatchAny":   ctx.ToolCall.commandMatchAny,
 	}
 }
 
+// toolCallInput returns the raw input of a tool call, never nil so that
+// expressions like has(toolCall.input.command) resolve cleanly.
+func toolCallInput(input map[string]any) map[string]any {
+	if input == nil {
+		return map[string]any{}
+	}
+	return input
+}
+
 // Global CEL evaluator singleton
 var (
 	globalCELEvaluator     *CELEvaluator
@@ -390,6 +436,24 @@ func acpMatchesServerTypeMacro(eh cel.MacroExprFactory, target celast.Expr, args
 	return eh.NewCall("__mitto_matchesServerType", eh.NewIdent("acp.name"), eh.NewIdent("acp.type"), args[0]), nil
 }
 
+// toolCallPathMatchesMacro rewrites toolCall.pathMatches(g) ->
+// __mitto_pathMatches(toolCall.paths, workspace.folder, g).
+func toolCallPathMatchesMacro(eh cel.MacroExprFactory, target celast.Expr, args []celast.Expr) (celast.Expr, *celcommon.Error) {
+	if !isIdent(target, "toolCall") {
+		return nil, nil
+	}
+	return eh.NewCall("__mitto_pathMatches", eh.NewIdent("toolCall.paths"), eh.NewIdent("workspace.folder"), args[0]), nil
+}
+
+// toolCallCommandMatchesMacro rewrites toolCall.commandMatches(g) ->
+// __mitto_commandMatches(toolCall.command, g, __mitto_commandMatchAny).
+func toolCallCommandMatchesMacro(eh cel.MacroExprFactory, target celast.Expr, args []celast.Expr) (celast.Expr, *celcommon.Error) {
+	if !isIdent(target, "toolCall") {
+		return nil, nil
+	}
+	return eh.NewCall("__mitto_commandMatches", eh.NewIdent("toolCall.command"), args[0], eh.NewIdent("__mitto_commandMatchAny")), nil
+}
+
 // fileExistsMacro rewrites fileExists(p) -> __mitto_fileExists(workspace.folder, p).
 func fileExistsMacro(eh cel.MacroExprFactory, _ celast.Expr, args []celast.Expr) (celast.Expr, *celcommon.Error) {
 	return eh.NewCall("__mitto_fileExists", eh.NewIdent("workspace.folder"), args[0]), nil
@@ -477,6 +541,36 @@ func mittoMatchesServerType(args ...ref.Val) ref.Val {
 	return types.Bool(false)
 }
 
+// mittoPathMatches reports whether any path (args[0], a list) matches the glob
+// pattern (args[2]). args[1] is the workspace folder: see MatchPathGlob.
+func mittoPathMatches(args ...ref.Val) ref.Val {
+	if len(args) != 3 {
+		return types.Bool(false)
+	}
+	folder := valToString(args[1])
+	pattern := valToString(args[2])
+	for _, path := range extractStringArgs(args[:1]) {
+		if MatchPathGlob(pattern, path, folder) {
+			return types.Bool(true)
+		}
+	}
+	return types.Bool(false)
+}
+
+// mittoCommandMatches reports whether the command (args[0]) matches the glob
+// pattern (args[1]), in every segment or, if args[2] is true, in any segment:
+// see MatchCommandGlob and MatchAnyCommandGlob.
+func mittoCommandMatches(args ...ref.Val) ref.Val {
+	if len(args) != 3 {
+		return types.Bool(false)
+	}
+	pattern, command := valToString(args[1]), valToString(args[0])
+	if matchAny, _ := args[2].Value().(bool); matchAny {
+		return types.Bool(MatchAnyCommandGlob(pattern, command))
+	}
+	return types.Bool(MatchCommandGlob(pattern, command))
+}
+
 // commandExistsImpl returns a CEL UnaryOp that checks whether a command
 // is available in the system PATH using exec.LookPath.
 func commandExistsImpl() func(ref.Val) ref.Val {
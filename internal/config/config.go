@@ -4,11 +4,15 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/inercia/mitto/internal/appdir"
 )
 
 // ACPServerConstraint defines a pattern-matching rule for auto-selecting config option values.
@@ -54,6 +58,11 @@ type ACPServer struct {
 	// The key is the config option category (e.g., "model", "mode").
 	// When a session starts, matching constraints auto-select the appropriate option value.
 	Constraints map[string]*ACPServerConstraint
+	// Pricing is the optional price table used to estimate the cost of token usage.
+	Pricing *ACPPricing
+	// PermissionRules decide how permission requests from this agent are handled.
+	// They are evaluated after the workspace rules and before the global rules.
+	PermissionRules []PermissionRule
 }
 
 // GetType returns the type identifier for prompt matching.
@@ -186,14 +195,111 @@ func (c *CloudflareAuth) Validate() error {
 	return nil
 }
 
+// OIDCAuth represents OpenID Connect login with an external identity provider
+// (Google, Okta, Keycloak...). Users are sent to the provider to log in, and
+// get a regular session once their ID token has been verified.
+type OIDCAuth struct {
+	Issuer       string `json:"issuer" yaml:"issuer"`                                   // e.g. "https://accounts.google.com"
+	ClientID     string `json:"client_id" yaml:"client_id"`                             // OAuth client ID registered with the provider
+	ClientSecret string `json:"client_secret,omitempty" yaml:"client_secret,omitempty"` // Optional for public clients (PKCE is always used)
+	// RedirectURL is the callback URL registered with the provider. By default
+	// it is derived from the request: https://<host><api_prefix>/api/auth/oidc/callback
+	RedirectURL string `json:"redirect_url,omitempty" yaml:"redirect_url,omitempty"`
+	// Scopes are requested besides "openid" (default: "email" and "profile").
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	// AllowedEmails are the emails ("alice@example.com") or email domains
+	// ("@example.com") allowed to log in.
+	AllowedEmails []string `json:"allowed_emails,omitempty" yaml:"allowed_emails,omitempty"`
+	// AllowedGroups are the groups allowed to log in, read from GroupsClaim.
+	AllowedGroups []string `json:"allowed_groups,omitempty" yaml:"allowed_groups,omitempty"`
+	// GroupsClaim is the ID token claim with the groups of the user (default: "groups").
+	GroupsClaim string `json:"groups_claim,omitempty" yaml:"groups_claim,omitempty"`
+	// AllowUnverifiedEmails accepts ID tokens whose email is not verified by
+	// the provider (no "email_verified" claim, or false). Only for providers
+	// that don't set the claim and verify emails otherwise.
+	AllowUnverifiedEmails bool `json:"allow_unverified_emails,omitempty" yaml:"allow_unverified_emails,omitempty"`
+}
+
+// Validate checks that the OpenID Connect configuration is valid.
+func (o *OIDCAuth) Validate() error {
+	if o.Issuer == "" {
+		return fmt.Errorf("oidc auth: issuer is required")
+	}
+	if u, err := url.Parse(o.Issuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("oidc auth: issuer must be an http(s) URL (e.g., 'https://accounts.google.com')")
+	}
+	if o.ClientID == "" {
+		return fmt.Errorf("oidc auth: client_id is required")
+	}
+	if o.RedirectURL != "" {
+		if u, err := url.Parse(o.RedirectURL); err != nil || !u.IsAbs() {
+			return fmt.Errorf("oidc auth: redirect_url must be an absolute URL")
+		}
+	}
+	return nil
+}
+
+// GetScopes returns the scopes to request, always including "openid".
+func (o *OIDCAuth) GetScopes() []string {
+	scopes := o.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+	result := []string{"openid"}
+	for _, scope := range scopes {
+		if scope != "openid" {
+			result = append(result, scope)
+		}
+	}
+	return result
+}
+
+// GetGroupsClaim returns the ID token claim with the groups of the user.
+func (o *OIDCAuth) GetGroupsClaim() string {
+	if o.GroupsClaim == "" {
+		return "groups"
+	}
+	return o.GroupsClaim
+}
+
+// IsAllowed returns true if an identity with the given email and groups can
+// log in. Without AllowedEmails and AllowedGroups every identity is allowed.
+func (o *OIDCAuth) IsAllowed(email string, groups []string) bool {
+	if len(o.AllowedEmails) == 0 && len(o.AllowedGroups) == 0 {
+		return true
+	}
+	if email != "" {
+		for _, allowed := range o.AllowedEmails {
+			if strings.HasPrefix(allowed, "@") {
+				if strings.HasSuffix(strings.ToLower(email), strings.ToLower(allowed)) {
+					return true
+				}
+			} else if strings.EqualFold(email, allowed) {
+				return true
+			}
+		}
+	}
+	for _, group := range groups {
+		if slices.Contains(o.AllowedGroups, group) {
+			return true
+		}
+	}
+	return false
+}
+
 // WebAuth represents authentication configuration for the web interface.
 type WebAuth struct {
 	// Simple enables simple username/password authentication when set
 	Simple *SimpleAuth `json:"simple,omitempty" yaml:"simple,omitempty"`
 	// Cloudflare enables Cloudflare Access JWT authentication when set
 	Cloudflare *CloudflareAuth `json:"cloudflare,omitempty" yaml:"cloudflare,omitempty"`
+	// OIDC enables OpenID Connect login when set
+	OIDC *OIDCAuth `json:"oidc,omitempty" yaml:"oidc,omitempty"`
 	// Allow contains IP addresses/CIDR ranges that bypass authentication
 	Allow *AuthAllow `json:"allow,omitempty" yaml:"allow,omitempty"`
+	// Users are the user accounts, with their roles and workspaces. Without
+	// users, every authenticated request has full access.
+	Users []WebUser `json:"users,omitempty" yaml:"users,omitempty"`
 }
 
 // HasCloudflareAuth returns true if Cloudflare Access authentication is configured and valid.
@@ -201,6 +307,27 @@ func (w *WebAuth) HasCloudflareAuth() bool {
 	return w != nil && w.Cloudflare != nil && w.Cloudflare.Validate() == nil
 }
 
+// HasOIDCAuth returns true if OpenID Connect login is configured and valid.
+func (w *WebAuth) HasOIDCAuth() bool {
+	return w != nil && w.OIDC != nil && w.OIDC.Validate() == nil
+}
+
+// ValidateOIDC checks the OpenID Connect configuration. Unless user accounts
+// are configured, it must restrict who can log in: otherwise anyone with an
+// account at the identity provider could.
+func (w *WebAuth) ValidateOIDC() error {
+	if w == nil || w.OIDC == nil {
+		return nil
+	}
+	if err := w.OIDC.Validate(); err != nil {
+		return err
+	}
+	if len(w.OIDC.AllowedEmails) == 0 && len(w.OIDC.AllowedGroups) == 0 && !w.HasUsers() {
+		return fmt.Errorf("oidc auth: allowed_emails, allowed_groups or users are required")
+	}
+	return nil
+}
+
 // WebSecurity represents security configuration for the web interface.
 type WebSecurity struct {
 	// TrustedProxies is a list of IP addresses or CIDR ranges of trusted reverse proxies.
@@ -437,6 +564,48 @@ type WebConfig struct {
 	Security *WebSecurity `json:"security,omitempty"`
 	// AccessLog contains access log configuration
 	AccessLog *AccessLogConfig `json:"access_log,omitempty"`
+	// Metrics contains the Prometheus metrics endpoint configuration
+	Metrics *WebMetrics `json:"metrics,omitempty"`
+	// AuditLog contains the audit log configuration
+	AuditLog *AuditLogConfig `json:"audit_log,omitempty"`
+}
+
+// DefaultMetricsPath is the default path of the Prometheus metrics endpoint.
+const DefaultMetricsPath = "/metrics"
+
+// WebMetrics represents the Prometheus metrics endpoint of the web server.
+// The endpoint is only served to localhost, or to authenticated requests
+// when authentication is configured.
+type WebMetrics struct {
+	// Enabled exposes the metrics endpoint (default: false).
+	Enabled bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// Path is the path of the endpoint (default: "/metrics"). It is not
+	// affected by the API prefix, as scrapers expect a fixed path.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// IsEnabled returns true if the metrics endpoint is enabled.
+func (m *WebMetrics) IsEnabled() bool {
+	return m != nil && m.Enabled
+}
+
+// GetPath returns the path of the metrics endpoint.
+func (m *WebMetrics) GetPath() string {
+	if m == nil || m.Path == "" {
+		return DefaultMetricsPath
+	}
+	return m.Path
+}
+
+// Validate checks that the metrics configuration is valid.
+func (m *WebMetrics) Validate() error {
+	if m == nil || m.Path == "" {
+		return nil
+	}
+	if !strings.HasPrefix(m.Path, "/") || strings.HasPrefix(m.Path, "/api/") {
+		return fmt.Errorf("web metrics: path must start with '/' and not be under /api/")
+	}
+	return nil
 }
 
 // AccessLogConfig represents access log configuration.
@@ -463,6 +632,54 @@ type AccessLogConfig struct {
 	LogAll *bool `json:"log_all,omitempty"`
 }
 
+// AuditLogConfig represents the audit log of security-relevant actions
+// (logins, permission decisions, configuration changes...).
+type AuditLogConfig struct {
+	// Enabled writes the audit log (default: false).
+	Enabled bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// Path is the file path for the audit log.
+	// If empty, defaults to audit.jsonl in the platform-specific logs directory.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// MaxSizeMB is the maximum size of the log file in megabytes before rotation.
+	// Default: 10MB
+	MaxSizeMB int `json:"max_size_mb,omitempty" yaml:"max_size_mb,omitempty"`
+	// MaxBackups is the maximum number of rotated files to retain.
+	// Default: 0 (keep all, so that the whole chain can be verified)
+	MaxBackups int `json:"max_backups,omitempty" yaml:"max_backups,omitempty"`
+}
+
+// IsEnabled returns true if the audit log is enabled.
+func (a *AuditLogConfig) IsEnabled() bool {
+	return a != nil && a.Enabled
+}
+
+// DefaultAuditLogFile is the name of the audit log in the logs directory.
+const DefaultAuditLogFile = "audit.jsonl"
+
+// GetPath returns the file path of the audit log, defaulting to the
+// platform-specific logs directory.
+func (a *AuditLogConfig) GetPath() (string, error) {
+	if a != nil && a.Path != "" {
+		return a.Path, nil
+	}
+	logsDir, err := appdir.LogsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(logsDir, DefaultAuditLogFile), nil
+}
+
+// Validate checks that the audit log configuration is valid.
+func (a *AuditLogConfig) Validate() error {
+	if a == nil {
+		return nil
+	}
+	if a.MaxSizeMB < 0 || a.MaxBackups < 0 {
+		return fmt.Errorf("web audit_log: max_size_mb and max_backups must not be negative")
+	}
+	return nil
+}
+
 // DefaultAPIPrefix is the default URL prefix for API endpoints.
 const DefaultAPIPrefix = "/mitto"
 
@@ -1072,6 +1289,11 @@ type PermissionsConfig struct {
 	// Default: true (until the permission UI is fully implemented)
 	// TODO: Change default to false once permission dialog is implemented.
 	AutoApprove *bool `json:"auto_approve,omitempty" yaml:"auto_approve,omitempty"`
+
+	// Rules decide how permission requests are handled, for all agents and workspaces.
+	// They are evaluated after the workspace and agent rules; the first matching
+	// rule approves, denies or asks. Requests matching no rule follow AutoApprove.
+	Rules []PermissionRule `json:"rules,omitempty" yaml:"rules,omitempty"`
 }
 
 // IsAutoApprove returns whether permission requests should be auto-approved.
@@ -1083,6 +1305,253 @@ func (p *PermissionsConfig) IsAutoApprove() bool {
 	return *p.AutoApprove
 }
 
+// ACPPricing is the price table of an ACP server, used to estimate the cost of
+// the tokens used by its conversations. Prices are per million tokens, in the
+// currency of the usage configuration (see UsageConfig.Currency).
+type ACPPricing struct {
+	// InputPerMTok is the price of one million input tokens.
+	InputPerMTok float64 `json:"input_per_mtok,omitempty" yaml:"input_per_mtok,omitempty"`
+	// OutputPerMTok is the price of one million output tokens (including thought tokens).
+	OutputPerMTok float64 `json:"output_per_mtok,omitempty" yaml:"output_per_mtok,omitempty"`
+	// CachedReadPerMTok is the price of one million tokens read from the prompt cache.
+	CachedReadPerMTok float64 `json:"cached_read_per_mtok,omitempty" yaml:"cached_read_per_mtok,omitempty"`
+	// CachedWritePerMTok is the price of one million tokens written to the prompt cache.
+	CachedWritePerMTok float64 `json:"cached_write_per_mtok,omitempty" yaml:"cached_write_per_mtok,omitempty"`
+}
+
+// Cost returns the cost of the given token counts.
+// Safe to call on nil receiver - returns 0.
+func (p *ACPPricing) Cost(input, output, cachedRead, cachedWrite int64) float64 {
+	if p == nil {
+		return 0
+	}
+	return (float64(input)*p.InputPerMTok +
+		float64(output)*p.OutputPerMTok +
+		float64(cachedRead)*p.CachedReadPerMTok +
+		float64(cachedWrite)*p.CachedWritePerMTok) / 1e6
+}
+
+// DefaultUsageCurrency is the currency of ACP server prices when none is configured.
+const DefaultUsageCurrency = "USD"
+
+// UsageConfig configures token usage accounting and budgets.
+// Budgets are global (all conversations) and use the server's local time zone:
+// a daily budget covers the current calendar day, a monthly budget the current
+// calendar month. While a budget is exceeded, queued messages and scheduled
+// periodic prompts are held back; prompts sent by the user are not affected.
+type UsageConfig struct {
+	// Currency is the currency of the ACP server prices (default: USD).
+	Currency string `json:"currency,omitempty" yaml:"currency,omitempty"`
+	// DailyBudget is the maximum cost per day (0 = no limit).
+	DailyBudget float64 `json:"daily_budget,omitempty" yaml:"daily_budget,omitempty"`
+	// MonthlyBudget is the maximum cost per month (0 = no limit).
+	MonthlyBudget float64 `json:"monthly_budget,omitempty" yaml:"monthly_budget,omitempty"`
+	// DailyTokenBudget is the maximum number of tokens per day (0 = no limit).
+	DailyTokenBudget int64 `json:"daily_token_budget,omitempty" yaml:"daily_token_budget,omitempty"`
+	// MonthlyTokenBudget is the maximum number of tokens per month (0 = no limit).
+	MonthlyTokenBudget int64 `json:"monthly_token_budget,omitempty" yaml:"monthly_token_budget,omitempty"`
+}
+
+// GetCurrency returns the configured currency, or DefaultUsageCurrency.
+// Safe to call on nil receiver.
+func (u *UsageConfig) GetCurrency() string {
+	if u == nil || u.Currency == "" {
+		return DefaultUsageCurrency
+	}
+	return u.Currency
+}
+
+// HasBudget returns true if any budget limit is configured.
+// Safe to call on nil receiver.
+func (u *UsageConfig) HasBudget() bool {
+	return u != nil && (u.DailyBudget > 0 || u.MonthlyBudget > 0 || u.DailyTokenBudget > 0 || u.MonthlyTokenBudget > 0)
+}
+
+// CheckBudget returns a description of the first budget exceeded by the given
+// daily and monthly totals, or an empty string if all budgets are respected.
+// Safe to call on nil receiver.
+func (u *UsageConfig) CheckBudget(dayCost, monthCost float64, dayTokens, monthTokens int64) string {
+	if u == nil {
+		return ""
+	}
+	currency := u.GetCurrency()
+	switch {
+	case u.DailyBudget > 0 && dayCost >= u.DailyBudget:
+		return fmt.Sprintf("daily budget of %.2f %s reached (%.2f %s used today)", u.DailyBudget, currency, dayCost, currency)
+	case u.MonthlyBudget > 0 && monthCost >= u.MonthlyBudget:
+		return fmt.Sprintf("monthly budget of %.2f %s reached (%.2f %s used this month)", u.MonthlyBudget, currency, monthCost, currency)
+	case u.DailyTokenBudget > 0 && dayTokens >= u.DailyTokenBudget:
+		return fmt.Sprintf("daily token budget of %d reached (%d tokens used today)", u.DailyTokenBudget, dayTokens)
+	case u.MonthlyTokenBudget > 0 && monthTokens >= u.MonthlyTokenBudget:
+		return fmt.Sprintf("monthly token budget of %d reached (%d tokens used this month)", u.MonthlyTokenBudget, monthTokens)
+	}
+	return ""
+}
+
+// Webhook event types, used in WebhookConfig.Events.
+const (
+	// WebhookEventTurnComplete is sent when the agent finishes a turn.
+	WebhookEventTurnComplete = "turn_complete"
+	// WebhookEventPermissionRequested is sent when a conversation waits for a permission decision.
+	WebhookEventPermissionRequested = "permission_requested"
+	// WebhookEventError is sent when a prompt fails.
+	WebhookEventError = "error"
+	// WebhookEventPeriodicMaxIterations is sent when a periodic conversation is
+	// stopped after reaching its maximum number of iterations.
+	WebhookEventPeriodicMaxIterations = "periodic_max_iterations"
+	// WebhookEventChildReport is sent when a child conversation reports to its
+	// parent with mitto_children_tasks_report.
+	WebhookEventChildReport = "child_report"
+)
+
+// WebhookEvents lists all the webhook event types.
+var WebhookEvents = []string{
+	WebhookEventTurnComplete,
+	WebhookEventPermissionRequested,
+	WebhookEventError,
+	WebhookEventPeriodicMaxIterations,
+	WebhookEventChildReport,
+}
+
+// Webhook payload formats.
+const (
+	// WebhookFormatGeneric sends the event as JSON.
+	WebhookFormatGeneric = "generic"
+	// WebhookFormatSlack sends a Slack-compatible {"text": ...} message.
+	WebhookFormatSlack = "slack"
+)
+
+// DefaultWebhookMaxAttempts is the default number of delivery attempts of a webhook.
+const DefaultWebhookMaxAttempts = 5
+
+// WebhookConfig is an outbound webhook notified of conversation lifecycle events.
+type WebhookConfig struct {
+	// Name identifies the webhook in logs and in the delivery log (default: the URL host).
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// URL is the http(s) endpoint events are POSTed to.
+	URL string `json:"url" yaml:"url"`
+	// Format is the payload format: "generic" (default) or "slack".
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+	// Secret, when set, signs the body with HMAC-SHA256 in the X-Mitto-Signature-256 header.
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+	// Events restricts the event types sent (default: all).
+	Events []string `json:"events,omitempty" yaml:"events,omitempty"`
+	// Headers are extra HTTP headers sent with each request (e.g. Authorization).
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// MaxAttempts is the number of delivery attempts before giving up (default: 5).
+	MaxAttempts int `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+}
+
+// DisplayName returns the name of the webhook, or the host of its URL.
+func (w *WebhookConfig) DisplayName() string {
+	if w.Name != "" {
+		return w.Name
+	}
+	if u, err := url.Parse(w.URL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return w.URL
+}
+
+// GetMaxAttempts returns the configured number of attempts, or DefaultWebhookMaxAttempts.
+func (w *WebhookConfig) GetMaxAttempts() int {
+	if w.MaxAttempts <= 0 {
+		return DefaultWebhookMaxAttempts
+	}
+	return w.MaxAttempts
+}
+
+// Wants reports whether the webhook is subscribed to an event type.
+func (w *WebhookConfig) Wants(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// Validate checks the URL, the format and the event types of the webhook.
+func (w *WebhookConfig) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook %q: url must be an http(s) URL", w.DisplayName())
+	}
+	switch w.Format {
+	case "", WebhookFormatGeneric, WebhookFormatSlack:
+	default:
+		return fmt.Errorf("webhook %q: unknown format %q (must be %q or %q)", w.DisplayName(), w.Format, WebhookFormatGeneric, WebhookFormatSlack)
+	}
+	for _, e := range w.Events {
+		if !slices.Contains(WebhookEvents, e) {
+			return fmt.Errorf("webhook %q: unknown event %q", w.DisplayName(), e)
+		}
+	}
+	return nil
+}
+
+// DefaultTracingEndpoint is the default OTLP/HTTP endpoint traces are exported to:
+// a collector on this machine.
+const DefaultTracingEndpoint = "http://localhost:4318"
+
+// DefaultTracingServiceName is the default service name of the exported traces.
+const DefaultTracingServiceName = "mitto"
+
+// TracingConfig configures the export of OpenTelemetry traces of the prompt
+// lifecycle (processors, ACP prompts, tool calls, MCP tools and markdown rendering).
+type TracingConfig struct {
+	// Enabled turns on trace export (default: false).
+	Enabled bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// Endpoint is the URL of the OTLP/HTTP collector (default: http://localhost:4318).
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// ServiceName is the service.name of the traces (default: mitto).
+	ServiceName string `json:"service_name,omitempty" yaml:"service_name,omitempty"`
+	// SampleRatio is the fraction of prompts traced, between 0 and 1 (default: 1).
+	SampleRatio float64 `json:"sample_ratio,omitempty" yaml:"sample_ratio,omitempty"`
+}
+
+// IsEnabled returns true if trace export is enabled.
+// Safe to call on nil receiver.
+func (t *TracingConfig) IsEnabled() bool {
+	return t != nil && t.Enabled
+}
+
+// GetEndpoint returns the configured endpoint, or DefaultTracingEndpoint.
+func (t *TracingConfig) GetEndpoint() string {
+	if t == nil || t.Endpoint == "" {
+		return DefaultTracingEndpoint
+	}
+	return t.Endpoint
+}
+
+// GetServiceName returns the configured service name, or DefaultTracingServiceName.
+func (t *TracingConfig) GetServiceName() string {
+	if t == nil || t.ServiceName == "" {
+		return DefaultTracingServiceName
+	}
+	return t.ServiceName
+}
+
+// GetSampleRatio returns the configured sample ratio, or 1 when not set.
+func (t *TracingConfig) GetSampleRatio() float64 {
+	if t == nil || t.SampleRatio <= 0 {
+		return 1
+	}
+	return t.SampleRatio
+}
+
+// Validate checks the endpoint and the sample ratio.
+func (t *TracingConfig) Validate() error {
+	if t == nil {
+		return nil
+	}
+	if t.Endpoint != "" {
+		u, err := url.Parse(t.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tracing: endpoint must be an http(s) URL")
+		}
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return fmt.Errorf("tracing: sample_ratio must be between 0 and 1")
+	}
+	return nil
+}
+
 // MCPConfig contains configuration for the MCP (Model Context Protocol) server.
 // The MCP server provides debugging tools and UI prompt functionality to AI agents.
 type MCPConfig struct {
@@ -1145,6 +1614,12 @@ type Config struct {
 	RestrictedRunners map[string]*WorkspaceRunnerConfig
 	// MCP contains MCP (Model Context Protocol) server configuration
 	MCP *MCPConfig
+	// Usage contains token usage accounting and budget configuration
+	Usage *UsageConfig
+	// Webhooks are the outbound webhooks notified of conversation lifecycle events
+	Webhooks []WebhookConfig
+	// Tracing contains the OpenTelemetry trace export configuration
+	Tracing *TracingConfig
 }
 
 // rawACPServerConfig is used for YAML unmarshaling of ACP server entries.
@@ -1168,6 +1643,8 @@ type rawACPServerConfig struct {
 		Periodic        *PromptPeriodic `yaml:"periodic,omitempty"`
 	} `yaml:"prompts"`
 	RestrictedRunners map[string]*WorkspaceRunnerConfig `yaml:"restricted_runners"`
+	Pricing           *ACPPricing                       `yaml:"pricing"`
+	PermissionRules   []PermissionRule                  `yaml:"permission_rules"`
 }
 
 // rawConfig is used for YAML unmarshaling to handle the map-based format.
@@ -1216,9 +1693,11 @@ type rawConfig struct {
 				Audience   string `yaml:"audience"`
 				CACertFile string `yaml:"ca_cert_file"`
 			} `yaml:"cloudflare"`
+			OIDC  *OIDCAuth `yaml:"oidc"`
 			Allow *struct {
 				IPs []string `yaml:"ips"`
 			} `yaml:"allow"`
+			Users []WebUser `yaml:"users"`
 		} `yaml:"auth"`
 		Security *struct {
 			TrustedProxies   []string `yaml:"trusted_proxies"`
@@ -1227,6 +1706,8 @@ type rawConfig struct {
 			RateLimitBurst   int      `yaml:"rate_limit_burst"`
 			MaxWSMessageSize int64    `yaml:"max_ws_message_size"`
 		} `yaml:"security"`
+		Metrics  *WebMetrics     `yaml:"metrics"`
+		AuditLog *AuditLogConfig `yaml:"audit_log"`
 	} `yaml:"web"`
 	UI *struct {
 		Confirmations *struct {
@@ -1293,7 +1774,8 @@ type rawConfig struct {
 	RestrictedRunners map[string]*WorkspaceRunnerConfig `yaml:"restricted_runners"`
 	// Permissions is the global permission handling configuration
 	Permissions *struct {
-		AutoApprove *bool `yaml:"auto_approve"`
+		AutoApprove *bool            `yaml:"auto_approve"`
+		Rules       []PermissionRule `yaml:"rules"`
 	} `yaml:"permissions"`
 	// Session is the session storage/startup configuration
 	Session *struct {
@@ -1305,6 +1787,7 @@ type rawConfig struct {
 		StartupPeriodicDelaySeconds int    `yaml:"startup_periodic_delay_seconds"`
 		PeriodicSuspendTimeout      string `yaml:"periodic_suspend_timeout"`
 		MemoryRecycleThreshold      string `yaml:"memory_recycle_threshold"`
+		StoreBackend                string `yaml:"store_backend"`
 	} `yaml:"session"`
 	// MCP is the MCP server configuration
 	MCP *struct {
@@ -1312,6 +1795,12 @@ type rawConfig struct {
 		Host    string `yaml:"host"`
 		Port    *int   `yaml:"port"`
 	} `yaml:"mcp"`
+	// Usage is the token usage accounting and budget configuration
+	Usage *UsageConfig `yaml:"usage"`
+	// Webhooks are the outbound webhooks notified of conversation lifecycle events
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+	// Tracing is the OpenTelemetry trace export configuration
+	Tracing *TracingConfig `yaml:"tracing"`
 }
 
 // Load reads and parses the configuration file from the given path.
@@ -1342,6 +1831,21 @@ func ParseJSON(data []byte) (*Config, error) {
 	}
 
 	cfg := settings.ToConfig()
+	if err := cfg.Web.Auth.ValidateUsers(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Web.Auth.ValidateOIDC(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Web.Metrics.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Web.AuditLog.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Tracing.Validate(); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
@@ -1366,7 +1870,9 @@ func Parse(data []byte) (*Config, error) {
 				Type:              server.Type, // Optional type for prompt matching
 				Env:               server.Env,  // Environment variables
 				RestrictedRunners: server.RestrictedRunners,
-				Tags:              server.Tags, // Optional categorization tags
+				Tags:              server.Tags,    // Optional categorization tags
+				Pricing:           server.Pricing, // Optional price table for cost accounting
+				PermissionRules:   server.PermissionRules,
 			}
 			// Copy server-specific prompts
 			for _, p := range server.Prompts {
@@ -1459,6 +1965,14 @@ func Parse(data []byte) (*Config, error) {
 				IPs: raw.Web.Auth.Allow.IPs,
 			}
 		}
+		cfg.Web.Auth.OIDC = raw.Web.Auth.OIDC
+		cfg.Web.Auth.Users = raw.Web.Auth.Users
+		if err := cfg.Web.Auth.ValidateUsers(); err != nil {
+			return nil, err
+		}
+		if err := cfg.Web.Auth.ValidateOIDC(); err != nil {
+			return nil, err
+		}
 	}
 
 	// Populate security config
@@ -1472,6 +1986,18 @@ func Parse(data []byte) (*Config, error) {
 		}
 	}
 
+	// Populate metrics config
+	cfg.Web.Metrics = raw.Web.Metrics
+	if err := cfg.Web.Metrics.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Populate audit log config
+	cfg.Web.AuditLog = raw.Web.AuditLog
+	if err := cfg.Web.AuditLog.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Populate UI config
 	if raw.UI != nil {
 		// Populate confirmations
@@ -1620,6 +2146,7 @@ func Parse(data []byte) (*Config, error) {
 	if raw.Permissions != nil {
 		cfg.Permissions = &PermissionsConfig{
 			AutoApprove: raw.Permissions.AutoApprove,
+			Rules:       raw.Permissions.Rules,
 		}
 	}
 
@@ -1634,6 +2161,7 @@ func Parse(data []byte) (*Config, error) {
 			StartupPeriodicDelaySeconds: raw.Session.StartupPeriodicDelaySeconds,
 			PeriodicSuspendTimeout:      raw.Session.PeriodicSuspendTimeout,
 			MemoryRecycleThreshold:      raw.Session.MemoryRecycleThreshold,
+			StoreBackend:                raw.Session.StoreBackend,
 		}
 	}
 
@@ -1646,6 +2174,14 @@ func Parse(data []byte) (*Config, error) {
 		}
 	}
 
+	cfg.Usage = raw.Usage
+	cfg.Webhooks = raw.Webhooks
+
+	cfg.Tracing = raw.Tracing
+	if err := cfg.Tracing.Validate(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -79,6 +80,106 @@ acp:
 	}
 }
 
+func TestParse_UsagePricing(t *testing.T) {
+	yaml := `
+acp:
+  - claude:
+      command: "claude-code --acp"
+      pricing:
+        input_per_mtok: 3
+        output_per_mtok: 15
+        cached_read_per_mtok: 0.3
+  - auggie:
+      command: "auggie --acp"
+usage:
+  currency: EUR
+  daily_budget: 10
+  monthly_token_budget: 5000000
+`
+	cfg, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	pricing := cfg.ACPServers[0].Pricing
+	if pricing == nil || pricing.InputPerMTok != 3 || pricing.OutputPerMTok != 15 {
+		t.Fatalf("pricing = %+v", pricing)
+	}
+	if cost := pricing.Cost(1_000_000, 100_000, 1_000_000, 0); cost != 4.8 {
+		t.Errorf("Cost = %v, want 4.8", cost)
+	}
+	if cfg.ACPServers[1].Pricing != nil || cfg.ACPServers[1].Pricing.Cost(1000, 1000, 0, 0) != 0 {
+		t.Error("server without pricing should have no cost")
+	}
+
+	if cfg.Usage == nil || cfg.Usage.GetCurrency() != "EUR" || !cfg.Usage.HasBudget() {
+		t.Fatalf("usage = %+v", cfg.Usage)
+	}
+	if reason := cfg.Usage.CheckBudget(9.99, 100, 0, 4_999_999); reason != "" {
+		t.Errorf("CheckBudget under the limits = %q, want none", reason)
+	}
+	if reason := cfg.Usage.CheckBudget(10, 10, 0, 0); !strings.Contains(reason, "daily budget") {
+		t.Errorf("CheckBudget over the daily budget = %q", reason)
+	}
+	if reason := cfg.Usage.CheckBudget(0, 0, 0, 5_000_000); !strings.Contains(reason, "monthly token budget") {
+		t.Errorf("CheckBudget over the monthly token budget = %q", reason)
+	}
+
+	var noUsage *UsageConfig
+	if noUsage.HasBudget() || noUsage.GetCurrency() != DefaultUsageCurrency || noUsage.CheckBudget(1e9, 1e9, 1e9, 1e9) != "" {
+		t.Error("nil usage config should have no budget")
+	}
+}
+
+func TestParse_NotificationWebhooks(t *testing.T) {
+	yaml := `
+acp:
+  - claude:
+      command: "claude-code --acp"
+webhooks:
+  - name: team-slack
+    url: https://hooks.slack.com/services/T000/B000/XXX
+    format: slack
+    events: [permission_requested, error]
+  - url: https://ci.example.com/mitto
+    secret: s3cret
+    max_attempts: 3
+`
+	cfg, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(cfg.Webhooks) != 2 {
+		t.Fatalf("webhooks = %+v", cfg.Webhooks)
+	}
+
+	slack, generic := cfg.Webhooks[0], cfg.Webhooks[1]
+	if err := slack.Validate(); err != nil {
+		t.Errorf("Validate(slack) = %v", err)
+	}
+	if !slack.Wants(WebhookEventError) || slack.Wants(WebhookEventTurnComplete) {
+		t.Errorf("slack webhook events = %v", slack.Events)
+	}
+	if slack.GetMaxAttempts() != DefaultWebhookMaxAttempts {
+		t.Errorf("GetMaxAttempts = %d, want the default", slack.GetMaxAttempts())
+	}
+	if generic.DisplayName() != "ci.example.com" || !generic.Wants(WebhookEventChildReport) || generic.GetMaxAttempts() != 3 {
+		t.Errorf("generic webhook = %+v", generic)
+	}
+
+	invalid := []WebhookConfig{
+		{URL: "not a url"},
+		{URL: "file:///tmp/hook"},
+		{URL: "https://example.com", Format: "teams"},
+		{URL: "https://example.com", Events: []string{"finished"}},
+	}
+	for _, wh := range invalid {
+		if err := wh.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", wh)
+		}
+	}
+}
+
 func TestParse_ACPServerType(t *testing.T) {
 	yaml := `
 acp:
@@ -2163,3 +2264,145 @@ conversations:
 		t.Errorf("GetMaxPeriodicIterations() = %d, want 0 (unlimited)", cfg.Conversations.GetMaxPeriodicIterations())
 	}
 }
+
+func TestOIDCAuth_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    WebAuth
+		wantErr string
+	}{
+		{"valid", WebAuth{OIDC: &OIDCAuth{Issuer: "https://accounts.google.com", ClientID: "id", AllowedEmails: []string{"@example.com"}}}, ""},
+		{"no issuer", WebAuth{OIDC: &OIDCAuth{ClientID: "id"}}, "issuer is required"},
+		{"bad issuer", WebAuth{OIDC: &OIDCAuth{Issuer: "accounts.google.com", ClientID: "id"}}, "http(s) URL"},
+		{"no client", WebAuth{OIDC: &OIDCAuth{Issuer: "https://accounts.google.com"}}, "client_id is required"},
+		{"bad redirect", WebAuth{OIDC: &OIDCAuth{Issuer: "https://idp", ClientID: "id", RedirectURL: "/callback"}}, "redirect_url"},
+		{"unrestricted", WebAuth{OIDC: &OIDCAuth{Issuer: "https://idp", ClientID: "id"}}, "allowed_emails, allowed_groups or users"},
+		{"users", WebAuth{
+			OIDC:  &OIDCAuth{Issuer: "https://idp", ClientID: "id"},
+			Users: []WebUser{{Username: "ann", Email: "ann@example.com", Role: RoleViewer}},
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.auth.ValidateOIDC()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateOIDC() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateOIDC() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCAuth_IsAllowed(t *testing.T) {
+	auth := &OIDCAuth{
+		AllowedEmails: []string{"alice@example.com", "@corp.example"},
+		AllowedGroups: []string{"mitto-users"},
+	}
+	tests := []struct {
+		email  string
+		groups []string
+		want   bool
+	}{
+		{"Alice@Example.com", nil, true},
+		{"bob@corp.example", nil, true},
+		{"bob@evilcorp.example", nil, false},
+		{"carol@other.example", []string{"staff", "mitto-users"}, true},
+		{"carol@other.example", []string{"staff"}, false},
+	}
+	for _, tt := range tests {
+		if got := auth.IsAllowed(tt.email, tt.groups); got != tt.want {
+			t.Errorf("IsAllowed(%q, %v) = %v, want %v", tt.email, tt.groups, got, tt.want)
+		}
+	}
+
+	if scopes := (&OIDCAuth{}).GetScopes(); strings.Join(scopes, " ") != "openid email profile" {
+		t.Errorf("GetScopes() = %v", scopes)
+	}
+}
+
+func TestParse_OIDCAuth(t *testing.T) {
+	yaml := `
+acp:
+  - test:
+      command: echo
+web:
+  auth:
+    oidc:
+      issuer: https://keycloak.example.com/realms/dev
+      client_id: mitto
+      client_secret: s3cret
+      allowed_groups: [developers]
+`
+	cfg, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !cfg.Web.Auth.HasOIDCAuth() || cfg.Web.Auth.OIDC.ClientSecret != "s3cret" || cfg.Web.Auth.OIDC.AllowedGroups[0] != "developers" {
+		t.Errorf("Parse() oidc = %+v", cfg.Web.Auth.OIDC)
+	}
+}
+
+func TestParse_WebMetrics(t *testing.T) {
+	yaml := `
+acp:
+  - test:
+      command: echo
+web:
+  metrics:
+    enabled: true
+`
+	cfg, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !cfg.Web.Metrics.IsEnabled() || cfg.Web.Metrics.GetPath() != DefaultMetricsPath {
+		t.Errorf("Parse() metrics = %+v", cfg.Web.Metrics)
+	}
+
+	var disabled *WebMetrics
+	if disabled.IsEnabled() {
+		t.Error("nil WebMetrics.IsEnabled() = true")
+	}
+
+	for _, path := range []string{"metrics", "/api/metrics"} {
+		if err := (&WebMetrics{Enabled: true, Path: path}).Validate(); err == nil {
+			t.Errorf("Validate() with path %q succeeded", path)
+		}
+	}
+}
+
+func TestParse_Tracing(t *testing.T) {
+	yaml := `
+acp:
+  - test:
+      command: echo
+tracing:
+  enabled: true
+  endpoint: http://collector:4318
+  sample_ratio: 0.25
+`
+	cfg, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tr := cfg.Tracing
+	if !tr.IsEnabled() || tr.GetEndpoint() != "http://collector:4318" || tr.GetSampleRatio() != 0.25 || tr.GetServiceName() != DefaultTracingServiceName {
+		t.Errorf("Parse() tracing = %+v", tr)
+	}
+
+	var disabled *TracingConfig
+	if disabled.IsEnabled() || disabled.GetEndpoint() != DefaultTracingEndpoint || disabled.GetSampleRatio() != 1 {
+		t.Errorf("nil TracingConfig defaults = %v, %q, %v", disabled.IsEnabled(), disabled.GetEndpoint(), disabled.GetSampleRatio())
+	}
+
+	for _, bad := range []TracingConfig{{Endpoint: "localhost:4318"}, {SampleRatio: 2}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded", bad)
+		}
+	}
+}
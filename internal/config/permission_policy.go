@@ -0,0 +1,388 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Permission rule actions.
+const (
+	// PermissionActionApprove approves the request without asking the user.
+	PermissionActionApprove = "approve"
+	// PermissionActionDeny rejects the request without asking the user.
+	PermissionActionDeny = "deny"
+	// PermissionActionAsk shows the permission dialog, even when auto-approve is enabled.
+	PermissionActionAsk = "ask"
+)
+
+// Scopes of permission rules, in evaluation order.
+const (
+	PermissionScopeWorkspace = "workspace"
+	PermissionScopeAgent     = "agent"
+	PermissionScopeGlobal    = "global"
+	// PermissionScopeRun is the scope of the rules of a `mitto run` policy file.
+	PermissionScopeRun = "run"
+)
+
+// PermissionRule decides how a permission request from an agent is handled.
+//
+// When is a CEL expression evaluated against the PromptEnabledContext of the
+// session, with the toolCall.* variables describing the request. For example:
+//
+//	when: toolCall.kind == "execute" && toolCall.commandMatches("rm -rf *")
+//	action: deny
+type PermissionRule struct {
+	// Name identifies the rule in permission events and logs (defaults to the expression).
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// When is the CEL expression the request must match.
+	When string `json:"when" yaml:"when"`
+	// Action is "approve", "deny" or "ask".
+	Action string `json:"action" yaml:"action"`
+}
+
+// DisplayName returns the name of the rule, or its expression if it has no name.
+func (r PermissionRule) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.When
+}
+
+// Validate checks the action and compiles the expression of the rule.
+func (r PermissionRule) Validate() error {
+	switch r.Action {
+	case PermissionActionApprove, PermissionActionDeny, PermissionActionAsk:
+	default:
+		return fmt.Errorf("permission rule %q: invalid action %q (must be approve, deny or ask)", r.DisplayName(), r.Action)
+	}
+	if strings.TrimSpace(r.When) == "" {
+		return fmt.Errorf("permission rule %q: missing when expression", r.DisplayName())
+	}
+	evaluator := GetCELEvaluator()
+	if evaluator == nil {
+		return errors.New("CEL evaluator not available")
+	}
+	if _, err := evaluator.Compile(r.When); err != nil {
+		return fmt.Errorf("permission rule %q: %w", r.DisplayName(), err)
+	}
+	return nil
+}
+
+// ValidatePermissionRules validates all the rules, returning the first error.
+func ValidatePermissionRules(rules []PermissionRule) error {
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PermissionDecision is the outcome of evaluating a PermissionPolicy.
+type PermissionDecision struct {
+	// Action is the action of the matching rule, or "" when no rule matched.
+	Action string `json:"action"`
+	// Rule is the name (or expression) of the matching rule.
+	Rule string `json:"rule,omitempty"`
+	// Scope is where the matching rule is configured: "workspace", "agent", "global" or "run".
+	Scope string `json:"scope,omitempty"`
+	// Error is set when the rule could not be evaluated; the decision is then "ask".
+	Error string `json:"error,omitempty"`
+}
+
+// Matched reports whether a rule matched the request.
+func (d PermissionDecision) Matched() bool {
+	return d.Action != ""
+}
+
+type scopedPermissionRule struct {
+	PermissionRule
+	scope string
+}
+
+// PermissionPolicy is the ordered list of permission rules that apply to a
+// session: the workspace rules, then the agent rules, then the global rules.
+// The first matching rule decides.
+type PermissionPolicy struct {
+	rules []scopedPermissionRule
+}
+
+// NewPermissionPolicy returns the policy made of the workspace, agent and global
+// rules, or nil if there are no rules at all.
+func NewPermissionPolicy(workspace, agent, global []PermissionRule) *PermissionPolicy {
+	p := &PermissionPolicy{}
+	for _, scoped := range []struct {
+		scope string
+		rules []PermissionRule
+	}{
+		{PermissionScopeWorkspace, workspace},
+		{PermissionScopeAgent, agent},
+		{PermissionScopeGlobal, global},
+	} {
+		for _, r := range scoped.rules {
+			p.rules = append(p.rules, scopedPermissionRule{PermissionRule: r, scope: scoped.scope})
+		}
+	}
+	if len(p.rules) == 0 {
+		return nil
+	}
+	return p
+}
+
+// Len returns the number of rules in the policy.
+func (p *PermissionPolicy) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.rules)
+}
+
+// Prepend returns a policy with rules evaluated before the rules of p.
+// Safe to call on nil receiver.
+func (p *PermissionPolicy) Prepend(scope string, rules []PermissionRule) *PermissionPolicy {
+	return p.extend(scope, rules, nil)
+}
+
+// Append returns a policy with rules evaluated after the rules of p.
+// Safe to call on nil receiver.
+func (p *PermissionPolicy) Append(scope string, rules []PermissionRule) *PermissionPolicy {
+	return p.extend(scope, nil, rules)
+}
+
+func (p *PermissionPolicy) extend(scope string, first, last []PermissionRule) *PermissionPolicy {
+	if len(first) == 0 && len(last) == 0 {
+		return p
+	}
+	result := &PermissionPolicy{}
+	for _, r := range first {
+		result.rules = append(result.rules, scopedPermissionRule{PermissionRule: r, scope: scope})
+	}
+	if p != nil {
+		result.rules = append(result.rules, p.rules...)
+	}
+	for _, r := range last {
+		result.rules = append(result.rules, scopedPermissionRule{PermissionRule: r, scope: scope})
+	}
+	return result
+}
+
+// Evaluate returns the decision of the first rule matching ctx, or a decision
+// with an empty Action if none matches (or the policy is nil).
+//
+// A rule that can't be evaluated (invalid expression or action, runtime error)
+// fails safe: the decision is "ask", with the error, so that a broken deny rule
+// never results in the request being approved.
+//
+// toolCall.commandMatches matches chained commands (see MatchCommandGlob) in
+// every segment for approve rules, and in any segment for deny and ask rules.
+func (p *PermissionPolicy) Evaluate(ctx *PromptEnabledContext) PermissionDecision {
+	if p == nil {
+		return PermissionDecision{}
+	}
+	evaluator := GetCELEvaluator()
+	var anyCtx *PromptEnabledContext
+	if ctx != nil {
+		c := *ctx
+		c.ToolCall.commandMatchAny = true
+		anyCtx = &c
+	}
+	for _, r := range p.rules {
+		decision := PermissionDecision{Rule: r.DisplayName(), Scope: r.scope}
+		if err := r.Validate(); err != nil {
+			decision.Action = PermissionActionAsk
+			decision.Error = err.Error()
+			return decision
+		}
+		compiled, _ := evaluator.Compile(r.When)
+		ruleCtx := ctx
+		if r.Action != PermissionActionApprove {
+			ruleCtx = anyCtx
+		}
+		matched, err := evaluator.Evaluate(compiled, ruleCtx)
+		if err != nil {
+			decision.Action = PermissionActionAsk
+			decision.Error = err.Error()
+			return decision
+		}
+		if matched {
+			decision.Action = r.Action
+			return decision
+		}
+	}
+	return PermissionDecision{}
+}
+
+// PermissionPolicyFile is a standalone file of permission rules, used to answer
+// the permission requests of unattended runs (`mitto run --policy-file`).
+//
+//	rules:
+//	  - name: allow-tests
+//	    when: toolCall.kind == "execute" && toolCall.commandMatches("go test *")
+//	    action: approve
+//	default: deny
+type PermissionPolicyFile struct {
+	// Rules are evaluated before the workspace, agent and global rules.
+	Rules []PermissionRule `json:"rules" yaml:"rules"`
+	// Default is the action for requests no rule decides: "approve" or "deny".
+	// It is evaluated after the workspace, agent and global rules.
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// LoadPermissionPolicyFile reads and validates a permission policy file.
+// Files with a .json extension are parsed as JSON, anything else as YAML.
+func LoadPermissionPolicyFile(path string) (*PermissionPolicyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read permission policy file %s: %w", path, err)
+	}
+	var file PermissionPolicyFile
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse permission policy file %s: %w", path, err)
+	}
+	if err := file.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid permission policy file %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// Validate checks the rules and the default action of the file.
+func (f *PermissionPolicyFile) Validate() error {
+	switch f.Default {
+	case "", PermissionActionApprove, PermissionActionDeny:
+	default:
+		return fmt.Errorf("invalid default action %q (must be approve or deny)", f.Default)
+	}
+	return ValidatePermissionRules(f.Rules)
+}
+
+// DefaultRules returns the catch-all rule of the default action, or nil if
+// the file has no default.
+func (f *PermissionPolicyFile) DefaultRules() []PermissionRule {
+	if f == nil || f.Default == "" {
+		return nil
+	}
+	return []PermissionRule{{Name: "default", When: "true", Action: f.Default}}
+}
+
+// MatchPathGlob reports whether path matches the glob pattern.
+// In patterns, "*" and "?" don't match "/", while "**" matches any number of
+// directories. Patterns without a "/" are matched against the base name of the
+// path (e.g. "*.go"). Other relative patterns are matched against the path
+// relative to the workspace folder, and absolute patterns against the path.
+func MatchPathGlob(pattern, path, folder string) bool {
+	if pattern == "" || path == "" {
+		return false
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	switch {
+	case !strings.Contains(pattern, "/"):
+		return matchGlob(pattern, filepath.Base(path), false)
+	case strings.HasPrefix(pattern, "/"):
+		return matchGlob(pattern, path, false)
+	}
+	if folder == "" {
+		return false
+	}
+	rel, err := filepath.Rel(folder, filepath.FromSlash(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return matchGlob(pattern, filepath.ToSlash(rel), false)
+}
+
+// commandSeparators are the shell operators that chain commands, substitute
+// their output or redirect them. Quoting is ignored, so operators inside quotes
+// split the command too.
+var commandSeparators = regexp.MustCompile("&&|\\|\\||\\$\\(|[;&|\n\r`()<>]")
+
+// SplitCommand splits a command line into the commands chained with shell
+// operators (";", "&&", "||", "|", "&", newlines, "`", "$(...)", subshells and
+// redirections), without leading and trailing whitespace or empty segments.
+func SplitCommand(command string) []string {
+	var segments []string
+	for _, segment := range commandSeparators.Split(command, -1) {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// MatchCommandGlob reports whether every command of the command line (see
+// SplitCommand) matches the glob pattern, where "*" matches any sequence of
+// characters (e.g. "go test *"), so "go test ./...; rm -rf ~" doesn't match.
+func MatchCommandGlob(pattern, command string) bool {
+	segments := SplitCommand(command)
+	if pattern == "" || len(segments) == 0 {
+		return false
+	}
+	for _, segment := range segments {
+		if !matchGlob(pattern, segment, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchAnyCommandGlob reports whether any command of the command line (see
+// SplitCommand) matches the glob pattern, so "git push --force*" matches
+// "cd repo && git push --force".
+func MatchAnyCommandGlob(pattern, command string) bool {
+	if pattern == "" {
+		return false
+	}
+	for _, segment := range SplitCommand(command) {
+		if matchGlob(pattern, segment, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches name against a glob pattern. When anySeparator is false,
+// "*" and "?" don't match "/" and "**" matches across directories.
+func matchGlob(pattern, name string, anySeparator bool) bool {
+	var re strings.Builder
+	re.WriteString("(?s)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			switch {
+			case anySeparator:
+				re.WriteString(".*")
+			case strings.HasPrefix(pattern[i:], "**/"):
+				// Zero or more directories
+				re.WriteString("(?:.*/)?")
+				i += 2
+			case strings.HasPrefix(pattern[i:], "**"):
+				re.WriteString(".*")
+				i++
+			default:
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			if anySeparator {
+				re.WriteString(".")
+			} else {
+				re.WriteString("[^/]")
+			}
+		default:
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	re.WriteString("$")
+	matched, err := regexp.MatchString(re.String(), name)
+	return err == nil && matched
+}
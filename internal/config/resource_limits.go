@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResourceLimits are hard limits on the agent process of a workspace (the ACP
+// server and all its descendants). When a limit is exceeded during a prompt,
+// the prompt is cancelled. All the limits are optional: zero or empty means
+// unlimited.
+type ResourceLimits struct {
+	// MaxMemory is the maximum memory of the process tree, as a size with an
+	// optional k, m or g suffix (e.g. "512m", "4g"). Enforced by the kernel
+	// when the process can be placed in its own cgroup (v2); polling cancels
+	// the prompts during which the RSS grows by more than the limit.
+	MaxMemory string `json:"max_memory,omitempty" yaml:"max_memory,omitempty"`
+	// MaxCPUSeconds is the maximum CPU time (user + system) the process tree
+	// can use while answering a single prompt. Not enforced on agents shared
+	// by several conversations.
+	MaxCPUSeconds float64 `json:"max_cpu_seconds,omitempty" yaml:"max_cpu_seconds,omitempty"`
+	// MaxPromptDuration is the maximum wall-clock time of a single prompt,
+	// as a Go duration (e.g. "30m").
+	MaxPromptDuration string `json:"max_prompt_duration,omitempty" yaml:"max_prompt_duration,omitempty"`
+	// MaxProcesses is the maximum number of processes the agent can have
+	// running at once, not counting the agent itself. Not enforced on agents
+	// shared by several conversations.
+	MaxProcesses int `json:"max_processes,omitempty" yaml:"max_processes,omitempty"`
+}
+
+// IsEnabled returns true if at least one limit is set.
+func (l *ResourceLimits) IsEnabled() bool {
+	return l != nil && (l.MaxMemory != "" || l.MaxCPUSeconds > 0 || l.MaxPromptDuration != "" || l.MaxProcesses > 0)
+}
+
+// GetMaxMemoryBytes returns the memory limit in bytes, or 0 if not set or invalid.
+func (l *ResourceLimits) GetMaxMemoryBytes() uint64 {
+	if l == nil || l.MaxMemory == "" {
+		return 0
+	}
+	n, err := ParseByteSize(l.MaxMemory)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// GetMaxCPUSeconds returns the CPU time limit per prompt, or 0 if not set.
+func (l *ResourceLimits) GetMaxCPUSeconds() float64 {
+	if l == nil || l.MaxCPUSeconds < 0 {
+		return 0
+	}
+	return l.MaxCPUSeconds
+}
+
+// GetMaxPromptDuration returns the wall-clock limit per prompt, or 0 if not set or invalid.
+func (l *ResourceLimits) GetMaxPromptDuration() time.Duration {
+	if l == nil || l.MaxPromptDuration == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(l.MaxPromptDuration)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// GetMaxProcesses returns the limit on the number of processes, or 0 if not set.
+func (l *ResourceLimits) GetMaxProcesses() int {
+	if l == nil || l.MaxProcesses < 0 {
+		return 0
+	}
+	return l.MaxProcesses
+}
+
+// Validate checks the limits.
+func (l *ResourceLimits) Validate() error {
+	if l == nil {
+		return nil
+	}
+	if l.MaxMemory != "" {
+		n, err := ParseByteSize(l.MaxMemory)
+		if err != nil {
+			return fmt.Errorf("resource_limits.max_memory: %w", err)
+		}
+		if n < 16*1024*1024 {
+			return fmt.Errorf("resource_limits.max_memory: %q is too low (minimum 16m)", l.MaxMemory)
+		}
+	}
+	if l.MaxCPUSeconds < 0 {
+		return fmt.Errorf("resource_limits.max_cpu_seconds: must not be negative")
+	}
+	if l.MaxPromptDuration != "" {
+		d, err := time.ParseDuration(l.MaxPromptDuration)
+		if err != nil {
+			return fmt.Errorf("resource_limits.max_prompt_duration: %w", err)
+		}
+		if d < time.Second {
+			return fmt.Errorf("resource_limits.max_prompt_duration: %q is too short (minimum 1s)", l.MaxPromptDuration)
+		}
+	}
+	if l.MaxProcesses < 0 {
+		return fmt.Errorf("resource_limits.max_processes: must not be negative")
+	}
+	return nil
+}
+
+// ParseByteSize parses a size in bytes with an optional binary suffix:
+// k (KiB), m (MiB), g (GiB) or t (TiB), case-insensitive, optionally
+// followed by "b" or "ib" (e.g. "1024", "512m", "1.5G", "4GiB").
+func ParseByteSize(s string) (uint64, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	str = strings.TrimSuffix(str, "ib")
+	str = strings.TrimSuffix(str, "b")
+
+	multiplier := uint64(1)
+	if str != "" {
+		switch str[len(str)-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		case 't':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			str = str[:len(str)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 512m or 4g)", s)
+	}
+	return uint64(value * float64(multiplier)), nil
+}
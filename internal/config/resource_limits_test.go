@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"512m", 512 << 20, false},
+		{"4g", 4 << 30, false},
+		{"4G", 4 << 30, false},
+		{"4GiB", 4 << 30, false},
+		{"4gb", 4 << 30, false},
+		{"1.5g", 3 << 29, false},
+		{"64k", 64 << 10, false},
+		{" 2t ", 2 << 40, false},
+		{"", 0, true},
+		{"g", 0, true},
+		{"-1g", 0, true},
+		{"lots", 0, true},
+		{"inf", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseByteSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResourceLimits_Getters(t *testing.T) {
+	var nilLimits *ResourceLimits
+	if nilLimits.IsEnabled() || nilLimits.GetMaxMemoryBytes() != 0 || nilLimits.GetMaxPromptDuration() != 0 {
+		t.Error("nil limits should be disabled")
+	}
+	if (&ResourceLimits{}).IsEnabled() {
+		t.Error("empty limits should be disabled")
+	}
+
+	l := &ResourceLimits{MaxMemory: "2g", MaxCPUSeconds: 90, MaxPromptDuration: "15m", MaxProcesses: 20}
+	if !l.IsEnabled() {
+		t.Error("IsEnabled() = false, want true")
+	}
+	if got := l.GetMaxMemoryBytes(); got != 2<<30 {
+		t.Errorf("GetMaxMemoryBytes() = %d", got)
+	}
+	if got := l.GetMaxCPUSeconds(); got != 90 {
+		t.Errorf("GetMaxCPUSeconds() = %v", got)
+	}
+	if got := l.GetMaxPromptDuration(); got != 15*time.Minute {
+		t.Errorf("GetMaxPromptDuration() = %v", got)
+	}
+	if got := l.GetMaxProcesses(); got != 20 {
+		t.Errorf("GetMaxProcesses() = %d", got)
+	}
+}
+
+func TestResourceLimits_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  *ResourceLimits
+		wantErr string
+	}{
+		{"nil", nil, ""},
+		{"valid", &ResourceLimits{MaxMemory: "1g", MaxCPUSeconds: 60, MaxPromptDuration: "10m", MaxProcesses: 8}, ""},
+		{"bad memory", &ResourceLimits{MaxMemory: "huge"}, "max_memory"},
+		{"tiny memory", &ResourceLimits{MaxMemory: "1m"}, "too low"},
+		{"negative cpu", &ResourceLimits{MaxCPUSeconds: -1}, "max_cpu_seconds"},
+		{"bad duration", &ResourceLimits{MaxPromptDuration: "forever"}, "max_prompt_duration"},
+		{"short duration", &ResourceLimits{MaxPromptDuration: "10ms"}, "too short"},
+		{"negative processes", &ResourceLimits{MaxProcesses: -3}, "max_processes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadWorkspacesFromFile_ResourceLimits(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "workspaces.yaml")
+	content := `workspaces:
+  - acp_server: claude-code
+    working_dir: /proj
+    resource_limits:
+      max_memory: 4g
+      max_prompt_duration: 30m
+      max_processes: 32
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workspaces, err := LoadWorkspacesFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadWorkspacesFromFile() error = %v", err)
+	}
+	limits := workspaces[0].ResourceLimits
+	if limits.GetMaxMemoryBytes() != 4<<30 || limits.GetMaxPromptDuration() != 30*time.Minute || limits.GetMaxProcesses() != 32 {
+		t.Errorf("ResourceLimits = %+v", limits)
+	}
+
+	invalid := strings.Replace(content, "4g", "lots", 1)
+	if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWorkspacesFromFile(path); err == nil || !strings.Contains(err.Error(), "max_memory") {
+		t.Errorf("LoadWorkspacesFromFile() error = %v, want max_memory error", err)
+	}
+}
@@ -67,6 +67,12 @@ type Settings struct {
 	Permissions *PermissionsConfig `json:"permissions,omitempty"`
 	// RestrictedRunners contains per-runner-type global configuration
 	RestrictedRunners map[string]*WorkspaceRunnerConfig `json:"restricted_runners,omitempty"`
+	// Usage contains token usage accounting and budget configuration
+	Usage *UsageConfig `json:"usage,omitempty"`
+	// Webhooks are the outbound webhooks notified of conversation lifecycle events
+	Webhooks []WebhookConfig `json:"webhooks,omitempty"`
+	// Tracing contains the OpenTelemetry trace export configuration
+	Tracing *TracingConfig `json:"tracing,omitempty"`
 }
 
 // DefaultStartupStaggerMs is the default stagger delay in milliseconds between
@@ -120,6 +126,12 @@ type SessionConfig struct {
 	// conversations resume transparently when focused. Values: "" (default,
 	// disabled), "disabled", "3g", "4g", "6g", "8g".
 	MemoryRecycleThreshold string `json:"memory_recycle_threshold,omitempty"`
+	// StoreBackend selects how conversation metadata and events are persisted.
+	// Values: "" or "file" (default - events.jsonl + metadata.json per conversation),
+	// "sqlite" (embedded SQLite database with indexed metadata).
+	// Existing data can be converted with `mitto tools session migrate-store`.
+	// Not exposed in the Settings dialog.
+	StoreBackend string `json:"store_backend,omitempty"`
 }
 
 // ArchiveRetentionNever is the value for keeping archived conversations forever.
@@ -136,6 +148,14 @@ func (c *SessionConfig) GetArchiveRetentionPeriod() string {
 	return c.ArchiveRetentionPeriod
 }
 
+// GetStoreBackend returns the session store backend, or "" (the default file backend) if not set.
+func (c *SessionConfig) GetStoreBackend() string {
+	if c == nil {
+		return ""
+	}
+	return c.StoreBackend
+}
+
 // GetAutoArchiveInactiveAfter returns the auto-archive inactive period string, or "" if not set.
 func (c *SessionConfig) GetAutoArchiveInactiveAfter() string {
 	if c == nil {
@@ -288,6 +308,10 @@ type ACPServerSettings struct {
 	// Constraints is an optional map of config option auto-selection rules.
 	// The key is the config option category (e.g., "model", "mode").
 	Constraints map[string]*ACPServerConstraint `json:"constraints,omitempty"`
+	// Pricing is the optional price table used to estimate the cost of token usage.
+	Pricing *ACPPricing `json:"pricing,omitempty"`
+	// PermissionRules decide how permission requests from this agent are handled.
+	PermissionRules []PermissionRule `json:"permission_rules,omitempty"`
 }
 
 // ToConfig converts Settings to the internal Config struct.
@@ -302,6 +326,9 @@ func (s *Settings) ToConfig() *Config {
 		Conversations:     s.Conversations,
 		Permissions:       s.Permissions,
 		RestrictedRunners: s.RestrictedRunners,
+		Usage:             s.Usage,
+		Webhooks:          s.Webhooks,
+		Tracing:           s.Tracing,
 	}
 	for i, srv := range s.ACPServers {
 		cfg.ACPServers[i] = ACPServer(srv)
@@ -321,6 +348,9 @@ func ConfigToSettings(cfg *Config) *Settings {
 		Conversations:     cfg.Conversations,
 		Permissions:       cfg.Permissions,
 		RestrictedRunners: cfg.RestrictedRunners,
+		Usage:             cfg.Usage,
+		Webhooks:          cfg.Webhooks,
+		Tracing:           cfg.Tracing,
 	}
 	for i, srv := range cfg.ACPServers {
 		s.ACPServers[i] = ACPServerSettings(srv)
@@ -614,6 +644,26 @@ func LoadSettingsWithFallback() (*LoadResult, error) {
 		mergedCfg.Web.Host = settingsCfg.Web.Host
 	}
 
+	// Audit log - use settings.json if not set in RC file
+	if mergedCfg.Web.AuditLog == nil {
+		mergedCfg.Web.AuditLog = settingsCfg.Web.AuditLog
+	}
+
+	// Usage accounting and budgets - use settings.json if not set in RC file
+	if mergedCfg.Usage == nil {
+		mergedCfg.Usage = settingsCfg.Usage
+	}
+
+	// Webhooks - use settings.json if not set in RC file
+	if len(mergedCfg.Webhooks) == 0 {
+		mergedCfg.Webhooks = settingsCfg.Webhooks
+	}
+
+	// Tracing - use settings.json if not set in RC file
+	if mergedCfg.Tracing == nil {
+		mergedCfg.Tracing = settingsCfg.Tracing
+	}
+
 	// Load keychain password for the merged config
 	// This loads the password from keychain if Auth is configured but password is empty
 	if err := loadKeychainPassword(mergedCfg); err != nil {
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Roles of the users of the web interface, from least to most privileged.
+const (
+	// RoleViewer can read the conversations of their workspaces.
+	RoleViewer = "viewer"
+	// RoleOperator can also create conversations, send prompts and answer
+	// permission requests in their workspaces.
+	RoleOperator = "operator"
+	// RoleAdmin can also change the configuration, and access all workspaces.
+	RoleAdmin = "admin"
+)
+
+// roleRanks orders the roles by privilege.
+var roleRanks = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// WebUser is a user account of the web interface. Users log in with a
+// username and password (checked against PasswordHash), or through Cloudflare
+// Access or OpenID Connect (matched by Email).
+type WebUser struct {
+	// Username is the login name of the user.
+	Username string `json:"username" yaml:"username"`
+	// PasswordHash is the bcrypt hash of the password (see 'mitto users hash-password').
+	// Users without one can only log in through Cloudflare Access or OpenID Connect.
+	PasswordHash string `json:"password_hash,omitempty" yaml:"password_hash,omitempty"`
+	// Email is the Cloudflare Access or OpenID Connect identity of the user.
+	Email string `json:"email,omitempty" yaml:"email,omitempty"`
+	// Role is "viewer", "operator" or "admin".
+	Role string `json:"role" yaml:"role"`
+	// Workspaces are the workspaces the user can access, as workspace UUIDs or
+	// folders (which include their subfolders). Empty means all workspaces.
+	// Admins can always access all workspaces.
+	Workspaces []string `json:"workspaces,omitempty" yaml:"workspaces,omitempty"`
+}
+
+// Validate checks that the user has a name, a valid role and a way to log in.
+func (u *WebUser) Validate() error {
+	if u.Username == "" {
+		return fmt.Errorf("web user: username is required")
+	}
+	if _, ok := roleRanks[u.Role]; !ok {
+		return fmt.Errorf("web user %q: invalid role %q (must be viewer, operator or admin)", u.Username, u.Role)
+	}
+	if u.PasswordHash == "" && u.Email == "" {
+		return fmt.Errorf("web user %q: password_hash or email is required", u.Username)
+	}
+	if u.PasswordHash != "" && !strings.HasPrefix(u.PasswordHash, "$2") {
+		return fmt.Errorf("web user %q: password_hash must be a bcrypt hash", u.Username)
+	}
+	return nil
+}
+
+// HasRole returns true if the user has the role or a more privileged one.
+// A nil user (authentication without user accounts) has every role.
+func (u *WebUser) HasRole(role string) bool {
+	return u == nil || roleRanks[u.Role] >= roleRanks[role]
+}
+
+// HasAllWorkspaces returns true if the user can access every workspace.
+func (u *WebUser) HasAllWorkspaces() bool {
+	return u == nil || u.Role == RoleAdmin || len(u.Workspaces) == 0
+}
+
+// CanAccessWorkspace returns true if the user can access the workspace with
+// the given UUID (may be empty) and folder.
+func (u *WebUser) CanAccessWorkspace(uuid, dir string) bool {
+	if u.HasAllWorkspaces() {
+		return true
+	}
+	dir = filepath.Clean(dir)
+	for _, grant := range u.Workspaces {
+		if uuid != "" && grant == uuid {
+			return true
+		}
+		if !filepath.IsAbs(grant) || dir == "." {
+			continue
+		}
+		grant = filepath.Clean(grant)
+		if dir == grant || strings.HasPrefix(dir, grant+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasUsers returns true if user accounts are configured.
+func (w *WebAuth) HasUsers() bool {
+	return w != nil && len(w.Users) > 0
+}
+
+// FindUser returns the user with the given username, or nil.
+func (w *WebAuth) FindUser(username string) *WebUser {
+	if w == nil {
+		return nil
+	}
+	for i := range w.Users {
+		if w.Users[i].Username == username {
+			return &w.Users[i]
+		}
+	}
+	return nil
+}
+
+// FindUserByEmail returns the user with the given Cloudflare Access or OpenID
+// Connect identity (case-insensitive), or nil.
+func (w *WebAuth) FindUserByEmail(email string) *WebUser {
+	if w == nil || email == "" {
+		return nil
+	}
+	for i := range w.Users {
+		if strings.EqualFold(w.Users[i].Email, email) {
+			return &w.Users[i]
+		}
+	}
+	return nil
+}
+
+// ValidateUsers checks the user accounts, and that usernames are unique.
+func (w *WebAuth) ValidateUsers() error {
+	if w == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(w.Users))
+	for i := range w.Users {
+		if err := w.Users[i].Validate(); err != nil {
+			return err
+		}
+		if seen[w.Users[i].Username] {
+			return fmt.Errorf("web user %q: duplicate username", w.Users[i].Username)
+		}
+		seen[w.Users[i].Username] = true
+	}
+	return nil
+}
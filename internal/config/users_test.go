@@ -0,0 +1,125 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWebUser_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    WebUser
+		wantErr string
+	}{
+		{"password", WebUser{Username: "ann", PasswordHash: "$2a$10$abc", Role: RoleViewer}, ""},
+		{"email", WebUser{Username: "bob", Email: "bob@example.com", Role: RoleAdmin}, ""},
+		{"no username", WebUser{PasswordHash: "$2a$10$abc", Role: RoleViewer}, "username is required"},
+		{"bad role", WebUser{Username: "ann", PasswordHash: "$2a$10$abc", Role: "root"}, "invalid role"},
+		{"no login", WebUser{Username: "ann", Role: RoleViewer}, "password_hash or email is required"},
+		{"plain password", WebUser{Username: "ann", PasswordHash: "secret", Role: RoleViewer}, "bcrypt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.user.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebUser_HasRole(t *testing.T) {
+	var none *WebUser
+	if !none.HasRole(RoleAdmin) {
+		t.Error("nil user should have every role")
+	}
+	operator := &WebUser{Role: RoleOperator}
+	if !operator.HasRole(RoleViewer) || !operator.HasRole(RoleOperator) || operator.HasRole(RoleAdmin) {
+		t.Error("operator roles are wrong")
+	}
+}
+
+func TestWebUser_CanAccessWorkspace(t *testing.T) {
+	user := &WebUser{
+		Username:   "ann",
+		Role:       RoleOperator,
+		Workspaces: []string{"ws-uuid", "/home/ann/projects"},
+	}
+	tests := []struct {
+		uuid, dir string
+		want      bool
+	}{
+		{"ws-uuid", "/elsewhere", true},
+		{"", "/home/ann/projects", true},
+		{"", "/home/ann/projects/app", true},
+		{"", "/home/ann/projects-old", false},
+		{"other", "/home/bob", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := user.CanAccessWorkspace(tt.uuid, tt.dir); got != tt.want {
+			t.Errorf("CanAccessWorkspace(%q, %q) = %v, want %v", tt.uuid, tt.dir, got, tt.want)
+		}
+	}
+
+	admin := &WebUser{Role: RoleAdmin, Workspaces: []string{"ws-uuid"}}
+	if !admin.CanAccessWorkspace("other", "/home/bob") {
+		t.Error("admins should access all workspaces")
+	}
+}
+
+func TestWebAuth_Users(t *testing.T) {
+	auth := &WebAuth{Users: []WebUser{
+		{Username: "ann", PasswordHash: "$2a$10$abc", Role: RoleViewer},
+		{Username: "bob", Email: "Bob@Example.com", Role: RoleAdmin},
+	}}
+	if err := auth.ValidateUsers(); err != nil {
+		t.Fatalf("ValidateUsers() error = %v", err)
+	}
+	if u := auth.FindUser("ann"); u == nil || u.Role != RoleViewer {
+		t.Errorf("FindUser(ann) = %+v", u)
+	}
+	if u := auth.FindUserByEmail("bob@example.com"); u == nil || u.Username != "bob" {
+		t.Errorf("FindUserByEmail() = %+v", u)
+	}
+	if auth.FindUser("carol") != nil || auth.FindUserByEmail("") != nil {
+		t.Error("unknown users should not be found")
+	}
+
+	auth.Users = append(auth.Users, WebUser{Username: "ann", Email: "ann@example.com", Role: RoleViewer})
+	if err := auth.ValidateUsers(); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("ValidateUsers() with duplicates error = %v", err)
+	}
+}
+
+func TestParse_WebUsers(t *testing.T) {
+	yaml := `
+acp:
+  - test:
+      command: echo
+web:
+  auth:
+    users:
+      - username: ann
+        password_hash: "$2a$10$abc"
+        role: operator
+        workspaces: [/home/ann]
+`
+	cfg, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if cfg.Web.Auth == nil || len(cfg.Web.Auth.Users) != 1 || cfg.Web.Auth.Users[0].Workspaces[0] != "/home/ann" {
+		t.Fatalf("Parse() users = %+v", cfg.Web.Auth)
+	}
+
+	if _, err := Parse([]byte(strings.Replace(yaml, "operator", "owner", 1))); err == nil {
+		t.Error("Parse() with an invalid role succeeded, want error")
+	}
+}
@@ -85,6 +85,13 @@ type WorkspaceSettings struct {
 	// When true, all permission requests (file writes, command execution, etc.) are auto-approved.
 	// When false or nil, the global auto_approve setting or per-conversation settings apply.
 	AutoApprove *bool `json:"auto_approve,omitempty" yaml:"auto_approve,omitempty"`
+	// PermissionRules decide how permission requests are handled in this workspace.
+	// They are evaluated before the agent and global rules (see PermissionsConfig.Rules).
+	PermissionRules []PermissionRule `json:"permission_rules,omitempty" yaml:"permission_rules,omitempty"`
+	// Worktree isolates each conversation in its own git worktree, on a per-session
+	// branch created under the Mitto data directory (WorkingDir must be in a git repository).
+	// The branch can be merged, rebased or discarded once the conversation is archived.
+	Worktree bool `json:"worktree,omitempty" yaml:"worktree,omitempty"`
 	// RestrictedRunnerConfig holds per-workspace runner restriction overrides.
 	// Applied as level 3 in the config hierarchy (global → agent → workspace).
 	// Only used when RestrictedRunner is not "exec".
@@ -111,6 +118,10 @@ type WorkspaceSettings struct {
 	// it distinguishes between workspaces in the same folder. At most one workspace
 	// per folder should set this; if several do, the first match wins.
 	IsDefault bool `json:"is_default,omitempty" yaml:"is_default,omitempty"`
+	// ResourceLimits are hard limits on the agent process of this workspace
+	// (memory, CPU time and wall-clock time per prompt, number of processes).
+	// Nil means unlimited. Per-workspace: never hoisted to folders.json.
+	ResourceLimits *ResourceLimits `json:"resource_limits,omitempty" yaml:"resource_limits,omitempty"`
 }
 
 // WorkspaceID returns a unique identifier for this workspace.
@@ -183,6 +194,11 @@ func (w *WorkspaceSettings) ValidateRestrictedRunner() error {
 	return fmt.Errorf("invalid restricted_runner %q: must be one of %v", w.RestrictedRunner, ValidRunnerTypes)
 }
 
+// ValidateResourceLimits validates the resource_limits field.
+func (w *WorkspaceSettings) ValidateResourceLimits() error {
+	return w.ResourceLimits.Validate()
+}
+
 // ValidateAutoChildren validates the auto-children configuration.
 // Returns errors for invalid entries. Pass allWorkspaces to validate target UUIDs.
 func (w *WorkspaceSettings) ValidateAutoChildren(allWorkspaces []WorkspaceSettings) []error {
@@ -236,7 +252,7 @@ func LoadWorkspaces() ([]WorkspaceSettings, error) {
 		return nil, fmt.Errorf("failed to read workspaces file %s: %w", workspacesPath, err)
 	}
 
-	// Ensure all workspaces have UUIDs and validate runner types
+	// Ensure all workspaces have UUIDs and validate runner types and resource limits
 	needsSave := false
 	for i := range file.Workspaces {
 		if file.Workspaces[i].EnsureUUID() {
@@ -247,6 +263,9 @@ func LoadWorkspaces() ([]WorkspaceSettings, error) {
 		if err := file.Workspaces[i].ValidateRestrictedRunner(); err != nil {
 			return nil, fmt.Errorf("workspace %q: %w", file.Workspaces[i].WorkspaceID(), err)
 		}
+		if err := file.Workspaces[i].ValidateResourceLimits(); err != nil {
+			return nil, fmt.Errorf("workspace %q: %w", file.Workspaces[i].WorkspaceID(), err)
+		}
 	}
 
 	// Load folder-level settings and merge them into the in-memory workspaces.
@@ -311,6 +330,9 @@ func LoadWorkspacesFromFile(path string) ([]WorkspaceSettings, error) {
 		if err := file.Workspaces[i].ValidateRestrictedRunner(); err != nil {
 			return nil, fmt.Errorf("workspace %q: %w", file.Workspaces[i].WorkspaceID(), err)
 		}
+		if err := file.Workspaces[i].ValidateResourceLimits(); err != nil {
+			return nil, fmt.Errorf("workspace %q: %w", file.Workspaces[i].WorkspaceID(), err)
+		}
 	}
 
 	return file.Workspaces, nil
@@ -197,6 +197,15 @@ func (c *Converter) ConvertToSafeHTML(markdown string) string {
 	return result
 }
 
+// Sanitize applies the converter's sanitization policy to already-rendered HTML.
+// If no sanitizer is configured, the HTML is escaped so that it is always safe to embed.
+func (c *Converter) Sanitize(html string) string {
+	if c.sanitizer == nil {
+		return EscapeHTML(html)
+	}
+	return c.sanitizer.Sanitize(html)
+}
+
 // EscapeHTML escapes special HTML characters.
 func EscapeHTML(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
@@ -165,6 +165,25 @@ func TestConverter_ConvertToSafeHTML(t *testing.T) {
 	}
 }
 
+// TestConverter_Sanitize tests sanitizing already-rendered HTML.
+func TestConverter_Sanitize(t *testing.T) {
+	input := `<p onclick="x()">Hi <script>alert(1)</script><strong>there</strong></p>`
+
+	result := DefaultConverter().Sanitize(input)
+	if strings.Contains(result, "script") || strings.Contains(result, "onclick") {
+		t.Errorf("Expected scripts and handlers to be removed, got: %s", result)
+	}
+	if !strings.Contains(result, "<strong>there</strong>") {
+		t.Errorf("Expected safe markup to be kept, got: %s", result)
+	}
+
+	// Without a sanitizer, the HTML is escaped
+	result = NewConverter().Sanitize(input)
+	if strings.Contains(result, "<script>") {
+		t.Errorf("Expected HTML to be escaped without a sanitizer, got: %s", result)
+	}
+}
+
 // TestEscapeHTML tests HTML escaping.
 func TestEscapeHTML(t *testing.T) {
 	tests := []struct {
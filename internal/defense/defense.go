@@ -3,9 +3,12 @@ package defense
 import (
 	"log/slog"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/inercia/mitto/internal/audit"
 )
 
 // NOTE: The Blocklist also exposes isWhitelisted() but that's private.
@@ -153,6 +156,19 @@ func (d *ScannerDefense) blockIP(ip, reason string) {
 		"error_rate", errorRate,
 	)
 
+	// The client whose requests triggered the block is the actor
+	audit.Record(audit.Event{
+		Actor:   audit.Actor{IP: ip},
+		Action:  audit.ActionDefenseBlock,
+		Target:  ip,
+		Outcome: audit.OutcomeSuccess,
+		Details: map[string]string{
+			"reason":        reason,
+			"duration":      blockDuration.String(),
+			"request_count": strconv.Itoa(requestCount),
+		},
+	})
+
 	// Persist blocklist if path is configured
 	d.persistBlocklist()
 
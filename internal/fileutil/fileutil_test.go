@@ -175,3 +175,24 @@ func TestWriteJSONAtomic_MissingParentDir(t *testing.T) {
 		t.Errorf("read data = %+v, want %+v", readData, data)
 	}
 }
+
+func TestWriteFileAtomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "script.sh")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(path, []byte("new"), 0755); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "new" {
+		t.Errorf("content = %q, want %q", got, "new")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the file (temp file left behind?)", len(entries))
+	}
+}
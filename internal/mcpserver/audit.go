@@ -0,0 +1,107 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/inercia/mitto/internal/audit"
+)
+
+// auditedTools maps the tools recorded in the audit log to their action.
+var auditedTools = map[string]string{
+	"mitto_conversation_new":    audit.ActionConversationCreate,
+	"mitto_conversation_fork":   audit.ActionConversationCreate,
+	"mitto_conversation_delete": audit.ActionConversationDelete,
+}
+
+// auditMiddleware records the tool calls that create or delete conversations
+// in the audit log, with the conversation of the calling agent as the actor.
+func (s *Server) auditMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		callReq, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok || callReq.Params == nil {
+			return next(ctx, method, req)
+		}
+		action, ok := auditedTools[callReq.Params.Name]
+		if !ok {
+			return next(ctx, method, req)
+		}
+
+		var args struct {
+			SelfID         string `json:"self_id"`
+			ConversationID string `json:"conversation_id"`
+		}
+		_ = json.Unmarshal(callReq.Params.Arguments, &args)
+		// Resolve the caller before the call (a conversation deleting itself
+		// can't be resolved afterwards), without waiting for the correlation
+		// of the ACP layer, which is left to the tool
+		caller := args.SelfID
+		if s.getSession(caller) == nil && callReq.Session != nil {
+			if cached := s.lookupMCPSession(callReq.Session.ID()); cached != "" {
+				caller = cached
+			}
+		}
+
+		result, err := next(ctx, method, req)
+
+		var out struct {
+			SessionID            string `json:"session_id"`
+			ConversationID       string `json:"conversation_id"`
+			ParentConversationID string `json:"parent_conversation_id"`
+			Error                string `json:"error"`
+		}
+		outcome, reason := audit.OutcomeSuccess, ""
+		res, _ := result.(*mcp.CallToolResult)
+		switch {
+		case err != nil:
+			outcome, reason = audit.OutcomeFailure, err.Error()
+		case res == nil:
+			outcome = audit.OutcomeFailure
+		default:
+			if data, err := json.Marshal(res.StructuredContent); err == nil {
+				_ = json.Unmarshal(data, &out)
+			}
+			if out.Error != "" {
+				outcome, reason = audit.OutcomeFailure, out.Error
+			} else if res.IsError {
+				outcome = audit.OutcomeFailure
+				if len(res.Content) > 0 {
+					if text, ok := res.Content[0].(*mcp.TextContent); ok {
+						reason = text.Text
+					}
+				}
+			}
+		}
+
+		details := map[string]string{"tool": callReq.Params.Name}
+		if reason != "" {
+			details["reason"] = reason
+		}
+		target := out.SessionID
+		switch {
+		case action == audit.ActionConversationDelete:
+			target = out.ConversationID
+			if target == "" {
+				target = args.ConversationID
+			}
+			if target == "self" {
+				target = caller
+			}
+		case out.ConversationID != "":
+			// A fork
+			target = out.ConversationID
+			details["source"] = out.ParentConversationID
+		}
+
+		audit.Record(audit.Event{
+			Actor:   audit.Actor{Session: caller},
+			Action:  action,
+			Target:  target,
+			Outcome: outcome,
+			Details: details,
+		})
+		return result, err
+	}
+}
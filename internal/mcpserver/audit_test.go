@@ -0,0 +1,75 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/inercia/mitto/internal/audit"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := audit.Open(audit.Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit.SetDefault(log)
+	t.Cleanup(func() {
+		audit.SetDefault(nil)
+		log.Close()
+	})
+
+	s := &Server{}
+	call := func(tool, args string, result *mcp.CallToolResult) {
+		t.Helper()
+		handler := s.auditMiddleware(func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			return result, nil
+		})
+		req := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: tool, Arguments: json.RawMessage(args)}}
+		if _, err := handler(context.Background(), "tools/call", req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	call("mitto_conversation_list", `{"self_id":"parent"}`, &mcp.CallToolResult{})
+	call("mitto_conversation_new", `{"self_id":"parent"}`,
+		&mcp.CallToolResult{StructuredContent: ConversationStartOutput{ConversationDetails: ConversationDetails{SessionID: "child"}}})
+	call("mitto_conversation_fork", `{"self_id":"parent"}`,
+		&mcp.CallToolResult{StructuredContent: ConversationForkOutput{Success: true, ConversationID: "fork", ParentConversationID: "parent"}})
+	call("mitto_conversation_delete", `{"self_id":"parent","conversation_id":"child"}`,
+		&mcp.CallToolResult{StructuredContent: DeleteConversationOutput{Error: "session not found"}})
+	call("mitto_conversation_delete", `{"self_id":"parent","conversation_id":"self"}`,
+		&mcp.CallToolResult{StructuredContent: DeleteConversationOutput{Success: true}})
+
+	events, err := audit.Search(path, audit.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		action, target, outcome string
+	}{
+		{audit.ActionConversationDelete, "parent", audit.OutcomeSuccess},
+		{audit.ActionConversationDelete, "child", audit.OutcomeFailure},
+		{audit.ActionConversationCreate, "fork", audit.OutcomeSuccess},
+		{audit.ActionConversationCreate, "child", audit.OutcomeSuccess},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Action != w.action || e.Target != w.target || e.Outcome != w.outcome || e.Actor.Session != "parent" {
+			t.Errorf("event %d = %+v, want %+v", i, e, w)
+		}
+	}
+	if events[1].Details["reason"] != "session not found" {
+		t.Errorf("reason = %q", events[1].Details["reason"])
+	}
+	if events[2].Details["source"] != "parent" {
+		t.Errorf("source = %q", events[2].Details["source"])
+	}
+}
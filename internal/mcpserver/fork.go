@@ -0,0 +1,118 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/inercia/mitto/internal/session"
+)
+
+// handleConversationFork handles the mitto_conversation_fork tool.
+func (s *Server) handleConversationFork(ctx context.Context, req *mcp.CallToolRequest, input ConversationForkInput) (*mcp.CallToolResult, ConversationForkOutput, error) {
+	var out ConversationForkOutput
+
+	realSessionID := s.resolveSelfIDWithMCP(input.SelfID, req)
+	if realSessionID == "" {
+		out.Error = "could not resolve session: provide a valid self_id"
+		return nil, out, nil
+	}
+	if input.Seq < 0 {
+		out.Error = "seq must be >= 0"
+		return nil, out, nil
+	}
+
+	s.mu.RLock()
+	store := s.store
+	sm := s.sessionManager
+	s.mu.RUnlock()
+
+	if store == nil {
+		out.Error = "session store not available"
+		return nil, out, nil
+	}
+
+	callerMeta, err := store.GetMetadata(realSessionID)
+	if err != nil {
+		out.Error = fmt.Sprintf("failed to get caller metadata: %v", err)
+		return nil, out, nil
+	}
+
+	// Forking creates a new conversation, so the same rules as mitto_conversation_new apply.
+	if !s.checkSessionFlag(realSessionID, session.FlagCanStartConversation) {
+		out.Error = fmt.Sprintf(
+			"the '%s' flag is not enabled for this session. Enable it in this session's Advanced Settings (gear icon) to allow creating new conversations",
+			session.FlagCanStartConversation)
+		return nil, out, nil
+	}
+	if callerMeta.ParentSessionID != "" {
+		out.Error = fmt.Sprintf(
+			"this session was created by another session (parent: %s) and cannot create new conversations to prevent infinite recursion",
+			callerMeta.ParentSessionID)
+		return nil, out, nil
+	}
+
+	sourceID := input.ConversationID
+	if sourceID == "" {
+		sourceID = realSessionID
+	}
+	sourceMeta := callerMeta
+	if sourceID != realSessionID {
+		if sourceMeta, err = store.GetMetadata(sourceID); err != nil {
+			out.Error = fmt.Sprintf("conversation not found: %s", sourceID)
+			return nil, out, nil
+		}
+		if sourceMeta.WorkingDir != callerMeta.WorkingDir &&
+			!s.checkSessionFlag(realSessionID, session.FlagCanInteractOtherWorkspaces) {
+			out.Error = fmt.Sprintf(
+				"cross-workspace operations require the 'Can interact with other workspaces' (%s) flag to be enabled in Advanced Settings",
+				session.FlagCanInteractOtherWorkspaces)
+			return nil, out, nil
+		}
+	}
+
+	forkMeta, err := store.Fork(sourceID, session.ForkOptions{AtSeq: input.Seq, Name: input.Title})
+	if err != nil {
+		if errors.Is(err, session.ErrInvalidForkSeq) {
+			out.Error = err.Error()
+		} else {
+			out.Error = fmt.Sprintf("failed to fork conversation: %v", err)
+		}
+		return nil, out, nil
+	}
+
+	s.logger.Info("Conversation forked via MCP",
+		"new_session_id", forkMeta.SessionID,
+		"source_session_id", sourceID,
+		"caller_session_id", realSessionID,
+		"seq", forkMeta.MaxSeq)
+
+	// Start the ACP process for the fork. The agent-side state can't be cloned, so
+	// this is a new ACP session that gets the copied history with its first prompt.
+	if sm != nil {
+		if _, err := sm.ResumeSession(forkMeta.SessionID, forkMeta.Name, forkMeta.WorkingDir); err != nil {
+			s.logger.Error("Failed to start ACP for forked conversation",
+				"session_id", forkMeta.SessionID,
+				"error", err)
+		}
+		sm.BroadcastSessionCreated(
+			forkMeta.SessionID,
+			forkMeta.Name,
+			forkMeta.ACPServer,
+			forkMeta.WorkingDir,
+			sourceID,
+			string(session.ChildOriginFork),
+		)
+	}
+
+	return nil, ConversationForkOutput{
+		Success:              true,
+		ConversationID:       forkMeta.SessionID,
+		Title:                forkMeta.Name,
+		ParentConversationID: sourceID,
+		ForkedAtSeq:          forkMeta.MaxSeq,
+		Events:               forkMeta.EventCount,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/inercia/mitto/internal/session"
+)
+
+// handleConversationSearch handles the mitto_conversation_search tool.
+func (s *Server) handleConversationSearch(ctx context.Context, req *mcp.CallToolRequest, input ConversationSearchInput) (*mcp.CallToolResult, ConversationSearchOutput, error) {
+	emptyOut := ConversationSearchOutput{Hits: []ConversationSearchHit{}}
+
+	realSessionID := s.resolveSelfIDWithMCP(input.SelfID, req)
+	if realSessionID == "" {
+		emptyOut.Error = "could not resolve session: provide a valid self_id"
+		return nil, emptyOut, nil
+	}
+	if strings.TrimSpace(input.Query) == "" {
+		emptyOut.Error = "query is required"
+		return nil, emptyOut, nil
+	}
+
+	s.mu.RLock()
+	store := s.store
+	sm := s.sessionManager
+	s.mu.RUnlock()
+
+	if store == nil {
+		emptyOut.Error = "session store not available"
+		return nil, emptyOut, nil
+	}
+
+	callerMeta, err := store.GetMetadata(realSessionID)
+	if err != nil {
+		emptyOut.Error = fmt.Sprintf("failed to get caller metadata: %v", err)
+		return nil, emptyOut, nil
+	}
+
+	// Scope the search to the caller's workspace unless it can interact with other workspaces.
+	hasXWPermissions := s.checkSessionFlag(realSessionID, session.FlagCanInteractOtherWorkspaces)
+	opts := session.SearchOptions{
+		Query:    input.Query,
+		Archived: input.Archived,
+		Limit:    input.Limit,
+	}
+	if input.Workspace != "" {
+		if sm == nil {
+			emptyOut.Error = "session manager not available"
+			return nil, emptyOut, nil
+		}
+		targetWS := sm.GetWorkspaceByUUID(input.Workspace)
+		if targetWS == nil {
+			emptyOut.Error = fmt.Sprintf("workspace not found: %s", input.Workspace)
+			return nil, emptyOut, nil
+		}
+		if targetWS.WorkingDir != callerMeta.WorkingDir && !hasXWPermissions {
+			emptyOut.Error = fmt.Sprintf(
+				"cross-workspace operations require the 'Can interact with other workspaces' (%s) flag to be enabled in Advanced Settings",
+				session.FlagCanInteractOtherWorkspaces)
+			return nil, emptyOut, nil
+		}
+		opts.WorkingDir = targetWS.WorkingDir
+	} else if !hasXWPermissions {
+		opts.WorkingDir = callerMeta.WorkingDir
+	}
+
+	if input.Since != "" {
+		if opts.Since, err = session.ParseHistoryTime(input.Since); err != nil {
+			emptyOut.Error = fmt.Sprintf("invalid since: %v", err)
+			return nil, emptyOut, nil
+		}
+	}
+	if input.Until != "" {
+		if opts.Until, err = session.ParseHistoryTime(input.Until); err != nil {
+			emptyOut.Error = fmt.Sprintf("invalid until: %v", err)
+			return nil, emptyOut, nil
+		}
+	}
+
+	result, err := store.SearchIndex().Search(opts)
+	if err != nil {
+		emptyOut.Error = fmt.Sprintf("search failed: %v", err)
+		return nil, emptyOut, nil
+	}
+
+	out := ConversationSearchOutput{
+		Success: true,
+		Total:   result.Total,
+		Hits:    make([]ConversationSearchHit, 0, len(result.Hits)),
+	}
+	for _, hit := range result.Hits {
+		out.Hits = append(out.Hits, ConversationSearchHit{
+			ConversationID: hit.SessionID,
+			Title:          hit.SessionName,
+			WorkingDir:     hit.WorkingDir,
+			Archived:       hit.Archived,
+			Seq:            hit.Seq,
+			Type:           string(hit.EventType),
+			Timestamp:      hit.Timestamp.Format(time.RFC3339),
+			Snippet:        hit.Snippet,
+			Score:          hit.Score,
+		})
+	}
+	return nil, out, nil
+}
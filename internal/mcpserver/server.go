@@ -68,6 +68,7 @@ type Server struct {
 	promptsCache   *config.PromptsCache
 	sessionManager SessionManager
 	periodicRunner PeriodicRunner // Optional — for triggering periodic runs via MCP
+	onChildReport  ChildReportCallback
 	running        bool
 	shutdown       bool
 
@@ -257,6 +258,7 @@ func NewServer(cfg Config, deps Dependencies) (*Server, error) {
 		Name:    ServerName,
 		Version: ServerVersion,
 	}, nil)
+	mcpSrv.AddReceivingMiddleware(tracingMiddleware, s.auditMiddleware)
 
 	// Register global tools (always available)
 	s.registerGlobalTools(mcpSrv, deps)
@@ -484,6 +486,17 @@ func (s *Server) SetPeriodicRunner(runner PeriodicRunner) {
 	s.periodicRunner = runner
 }
 
+// ChildReportCallback is called when a child conversation reports to its parent
+// via mitto_children_tasks_report.
+type ChildReportCallback func(childSessionID, parentSessionID, status, summary string)
+
+// SetOnChildReport sets the callback for child reports (optional).
+func (s *Server) SetOnChildReport(callback ChildReportCallback) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChildReport = callback
+}
+
 // RegisterSession registers a session with the MCP server.
 // This enables session-scoped tools to route UI prompts to the correct session.
 // The session must be registered before its tools can be used.
@@ -1222,7 +1235,11 @@ func (s *Server) registerSessionScopedTools(mcpSrv *mcp.Server) {
 			"User data is validated against the workspace's schema defined in .mittorc. " +
 			"Set 'user_data_merge' to true (default) to merge with existing attributes, or false to replace all. " +
 			"Periodic configuration: provide 'periodic_prompt', 'periodic_frequency_value', and 'periodic_frequency_unit' " +
-			"to configure or update periodic prompts. Use 'periodic_frequency_at' (HH:MM UTC) for daily schedules. " +
+			"to configure or update periodic prompts. Use 'periodic_frequency_at' (HH:MM) for daily schedules. " +
+			"Alternatively, set 'periodic_cron' to a standard 5-field cron expression (e.g. \"0 9 * * 1-5\" for weekdays at 09:00; empty string switches back to the frequency). " +
+			"'periodic_timezone' is the IANA time zone of the cron expression, 'periodic_frequency_at' and blackouts (default UTC). " +
+			"'periodic_blackouts' lists windows when the prompt is never sent, each with optional 'days' ([\"sat\",\"sun\"]), 'start'/'end' (HH:MM, may wrap midnight) and 'from'/'until' dates (YYYY-MM-DD). " +
+			"The result includes 'periodic_next_runs' with the next fire times ('periodic_preview_count' sets how many, default 5). " +
 			"Set 'periodic_enabled' to false to pause periodic execution without deleting the configuration. " +
 			"To disable periodic entirely, set 'periodic_enabled' to false. " +
 			"Set 'periodic_fresh_context' to true to start each run with a clean agent context (no history injection, new ACP session). " +
@@ -1284,6 +1301,28 @@ func (s *Server) registerSessionScopedTools(mcpSrv *mcp.Server) {
 			selfIDNote,
 	}, s.handleConversationHistory)
 
+	// mitto_conversation_search - Full-text search across all conversations
+	mcp.AddTool(mcpSrv, &mcp.Tool{
+		Name: "mitto_conversation_search",
+		Description: "Full-text search across the history of all Mitto conversations (user prompts, agent messages, agent thoughts and tool call titles). " +
+			"Use this to find prior work, decisions or discussions; then use 'mitto_conversation_history' with the returned conversation_id and seq to read the surrounding context. " +
+			"All words in 'query' must match; end a word with '*' to match a prefix. Results are ranked by relevance and include a snippet with matches wrapped in <mark> tags. " +
+			"Results are limited to your own workspace unless you pass a 'workspace' UUID or have the 'Can interact with other workspaces' flag, in which case all workspaces are searched. " +
+			"Filter by time range with 'since' and 'until' (RFC 3339 timestamp or relative duration meaning ago, e.g. '24h'). " +
+			selfIDNote,
+	}, s.handleConversationSearch)
+
+	// mitto_conversation_fork - Fork a conversation at an event sequence number
+	mcp.AddTool(mcpSrv, &mcp.Tool{
+		Name: "mitto_conversation_fork",
+		Description: "Fork a conversation: create a new child conversation whose history is a copy of the source conversation's events up to (and including) sequence number 'seq'. " +
+			"Use this to explore an alternative approach from an earlier point without losing the original conversation. " +
+			"Find sequence numbers with 'mitto_conversation_history' or 'mitto_conversation_search'; omit 'seq' to fork the whole conversation. " +
+			"'conversation_id' defaults to your own conversation. The agent-side state can't be cloned: the fork starts a new agent session that receives the copied history as context with its first prompt. " +
+			"Requires the 'Can start conversation' flag; forking a conversation in another workspace also requires the 'Can interact with other workspaces' flag. " +
+			selfIDNote,
+	}, s.handleConversationFork)
+
 	// mitto_prompt_list - List all prompts in a workspace
 	mcp.AddTool(mcpSrv, &mcp.Tool{
 		Name: "mitto_prompt_list",
@@ -3753,13 +3792,17 @@ func (s *Server) handleConversationUpdate(ctx context.Context, req *mcp.CallTool
 	}
 
 	// Update periodic configuration if any periodic fields provided
-	if input.PeriodicPrompt != nil || input.PeriodicFrequencyValue != nil || input.PeriodicFrequencyUnit != nil || input.PeriodicEnabled != nil || input.PeriodicFreshContext != nil || input.PeriodicMaxIterations != nil {
+	scheduleChanged := input.PeriodicCron != nil || input.PeriodicTimeZone != nil || input.PeriodicBlackouts != nil
+	if input.PeriodicPrompt != nil || input.PeriodicFrequencyValue != nil || input.PeriodicFrequencyUnit != nil || input.PeriodicEnabled != nil || input.PeriodicFreshContext != nil || input.PeriodicMaxIterations != nil || scheduleChanged {
 		periodicStore := store.Periodic(input.ConversationID)
 
 		// Check if this is an update to existing periodic config or a new setup
 		existing, existErr := periodicStore.Get()
 		isNew := existErr != nil || existing == nil
 
+		// A cron expression replaces the frequency
+		hasCron := input.PeriodicCron != nil && *input.PeriodicCron != ""
+
 		if isNew {
 			// Creating new periodic config — require all mandatory fields
 			if input.PeriodicPrompt == nil || *input.PeriodicPrompt == "" {
@@ -3768,46 +3811,48 @@ func (s *Server) handleConversationUpdate(ctx context.Context, req *mcp.CallTool
 					Error:   "periodic_prompt is required when creating new periodic configuration",
 				}, nil
 			}
-			if input.PeriodicFrequencyValue == nil || *input.PeriodicFrequencyValue < 1 {
+			if !hasCron && (input.PeriodicFrequencyValue == nil || *input.PeriodicFrequencyValue < 1) {
 				return nil, ConversationUpdateOutput{
 					Success: false,
-					Error:   "periodic_frequency_value (>= 1) is required when creating new periodic configuration",
+					Error:   "periodic_frequency_value (>= 1) or periodic_cron is required when creating new periodic configuration",
 				}, nil
 			}
-			if input.PeriodicFrequencyUnit == nil || *input.PeriodicFrequencyUnit == "" {
+			if !hasCron && (input.PeriodicFrequencyUnit == nil || *input.PeriodicFrequencyUnit == "") {
 				return nil, ConversationUpdateOutput{
 					Success: false,
-					Error:   "periodic_frequency_unit is required when creating new periodic configuration",
+					Error:   "periodic_frequency_unit or periodic_cron is required when creating new periodic configuration",
 				}, nil
 			}
 
-			var freqUnit session.FrequencyUnit
-			switch *input.PeriodicFrequencyUnit {
-			case "minutes":
-				freqUnit = session.FrequencyMinutes
-			case "hours":
-				freqUnit = session.FrequencyHours
-			case "days":
-				freqUnit = session.FrequencyDays
-			default:
-				return nil, ConversationUpdateOutput{
-					Success: false,
-					Error:   "periodic_frequency_unit must be 'minutes', 'hours', or 'days'",
-				}, nil
+			var freq session.Frequency
+			if input.PeriodicFrequencyValue != nil {
+				freq.Value = *input.PeriodicFrequencyValue
 			}
-
-			freq := session.Frequency{
-				Value: *input.PeriodicFrequencyValue,
-				Unit:  freqUnit,
+			if input.PeriodicFrequencyUnit != nil {
+				switch *input.PeriodicFrequencyUnit {
+				case "minutes":
+					freq.Unit = session.FrequencyMinutes
+				case "hours":
+					freq.Unit = session.FrequencyHours
+				case "days":
+					freq.Unit = session.FrequencyDays
+				default:
+					return nil, ConversationUpdateOutput{
+						Success: false,
+						Error:   "periodic_frequency_unit must be 'minutes', 'hours', or 'days'",
+					}, nil
+				}
 			}
 			if input.PeriodicFrequencyAt != nil {
 				freq.At = *input.PeriodicFrequencyAt
 			}
-			if err := freq.Validate(); err != nil {
-				return nil, ConversationUpdateOutput{
-					Success: false,
-					Error:   fmt.Sprintf("invalid periodic frequency: %v", err),
-				}, nil
+			if !hasCron {
+				if err := freq.Validate(); err != nil {
+					return nil, ConversationUpdateOutput{
+						Success: false,
+						Error:   fmt.Sprintf("invalid periodic frequency: %v", err),
+					}, nil
+				}
 			}
 
 			enabled := true
@@ -3831,6 +3876,7 @@ func (s *Server) handleConversationUpdate(ctx context.Context, req *mcp.CallTool
 				Enabled:       enabled,
 				FreshContext:  freshContext,
 				MaxIterations: maxIterations,
+				Schedule:      periodicSchedule(session.Schedule{}, input),
 			}
 
 			if err := periodicStore.Set(periodic); err != nil {
@@ -3880,7 +3926,13 @@ func (s *Server) handleConversationUpdate(ctx context.Context, req *mcp.CallTool
 				enabled = input.PeriodicEnabled
 			}
 
-			if err := periodicStore.Update(prompt, nil, freq, enabled, input.PeriodicFreshContext, input.PeriodicMaxIterations); err != nil {
+			var schedule *session.Schedule
+			if scheduleChanged {
+				sched := periodicSchedule(existing.Schedule, input)
+				schedule = &sched
+			}
+
+			if err := periodicStore.Update(prompt, nil, freq, enabled, input.PeriodicFreshContext, input.PeriodicMaxIterations, schedule); err != nil {
 				return nil, ConversationUpdateOutput{
 					Success: false,
 					Error:   fmt.Sprintf("failed to update periodic: %v", err),
@@ -3956,14 +4008,41 @@ func (s *Server) handleConversationUpdate(ctx context.Context, req *mcp.CallTool
 		output.PeriodicFreshContext = p.FreshContext
 		output.PeriodicMaxIterations = p.MaxIterations
 		output.PeriodicIterationCount = p.IterationCount
+		output.PeriodicCron = p.Cron
+		output.PeriodicTimeZone = p.TimeZone
+		output.PeriodicBlackouts = p.Blackouts
 		if p.NextScheduledAt != nil {
 			output.PeriodicNextRun = p.NextScheduledAt.Format("2006-01-02T15:04:05Z07:00")
 		}
+		previewCount := defaultPeriodicPreviewCount
+		if input.PeriodicPreviewCount != nil {
+			previewCount = *input.PeriodicPreviewCount
+		}
+		for _, t := range p.NextRuns(time.Now().UTC(), previewCount) {
+			output.PeriodicNextRuns = append(output.PeriodicNextRuns, t.In(p.Location()).Format(time.RFC3339))
+		}
 	}
 
 	return nil, output, nil
 }
 
+// defaultPeriodicPreviewCount is the number of upcoming runs returned by mitto_conversation_update.
+const defaultPeriodicPreviewCount = 5
+
+// periodicSchedule applies the schedule fields of a mitto_conversation_update input to a schedule.
+func periodicSchedule(schedule session.Schedule, input ConversationUpdateInput) session.Schedule {
+	if input.PeriodicCron != nil {
+		schedule.Cron = *input.PeriodicCron
+	}
+	if input.PeriodicTimeZone != nil {
+		schedule.TimeZone = *input.PeriodicTimeZone
+	}
+	if input.PeriodicBlackouts != nil {
+		schedule.Blackouts = input.PeriodicBlackouts
+	}
+	return schedule
+}
+
 // =============================================================================
 // Parent-Child Task Coordination Handlers
 // =============================================================================
@@ -4655,6 +4734,7 @@ func (s *Server) handleChildrenTasksReport(ctx context.Context, req *mcp.CallToo
 
 	s.mu.RLock()
 	store := s.store
+	onChildReport := s.onChildReport
 	s.mu.RUnlock()
 
 	if store == nil {
@@ -4685,6 +4765,10 @@ func (s *Server) handleChildrenTasksReport(ctx context.Context, req *mcp.CallToo
 	// Store the report (may also signal a waiting parent)
 	collector.addReport(realSessionID, input.TaskID, json.RawMessage(reportJSON))
 
+	if onChildReport != nil {
+		onChildReport(realSessionID, parentSessionID, input.Status, input.Summary)
+	}
+
 	// Detect orphaned reports: parent unregistered or not actively waiting
 	parentReg := s.getSession(parentSessionID)
 	if parentReg == nil {
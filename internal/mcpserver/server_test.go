@@ -8644,3 +8644,195 @@ func TestSendPrompt_BothEmpty_Error(t *testing.T) {
 		t.Errorf("Expected error mentioning 'prompt_name', got: %s", output.Error)
 	}
 }
+
+// =============================================================================
+// TestConversationSearch tests
+// =============================================================================
+
+// setupSearchServer creates a cross-workspace test server where every session
+// has a user prompt mentioning "deploy".
+func setupSearchServer(t *testing.T, flags map[string]bool) (*Server, string) {
+	t.Helper()
+
+	srv, store, sourceID := setupListConversationsTestServer(t, flags)
+	sessions, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	for _, meta := range sessions {
+		if err := store.AppendEvent(meta.SessionID, session.Event{
+			Type: session.EventTypeUserPrompt,
+			Data: session.UserPromptData{Message: "How do we deploy " + meta.Name + "?"},
+		}); err != nil {
+			t.Fatalf("Failed to append event: %v", err)
+		}
+	}
+	return srv, sourceID
+}
+
+func TestConversationSearch_ScopedToOwnWorkspace(t *testing.T) {
+	srv, sourceID := setupSearchServer(t, nil)
+
+	_, out, err := srv.handleConversationSearch(context.Background(), nil, ConversationSearchInput{
+		SelfID: sourceID,
+		Query:  "deploy",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !out.Success {
+		t.Fatalf("expected success, got error: %s", out.Error)
+	}
+	if out.Total != 2 {
+		t.Fatalf("Total = %d, want 2 (own workspace only)", out.Total)
+	}
+	for _, hit := range out.Hits {
+		if hit.WorkingDir != "/workspace-a" {
+			t.Errorf("unexpected hit from %s", hit.WorkingDir)
+		}
+		if !strings.Contains(hit.Snippet, "<mark>deploy</mark>") {
+			t.Errorf("Snippet = %q, want highlighted match", hit.Snippet)
+		}
+	}
+
+	// Other workspaces require cross-workspace permission
+	_, out, _ = srv.handleConversationSearch(context.Background(), nil, ConversationSearchInput{
+		SelfID:    sourceID,
+		Query:     "deploy",
+		Workspace: "ws-b-uuid",
+	})
+	if out.Success || out.Error == "" {
+		t.Error("expected cross-workspace search to fail without permission")
+	}
+	if out.Hits == nil {
+		t.Error("Hits must be a non-nil slice")
+	}
+}
+
+func TestConversationSearch_WithPermissions(t *testing.T) {
+	srv, sourceID := setupSearchServer(t, map[string]bool{
+		session.FlagCanInteractOtherWorkspaces: true,
+	})
+
+	_, out, _ := srv.handleConversationSearch(context.Background(), nil, ConversationSearchInput{
+		SelfID: sourceID,
+		Query:  "deploy",
+	})
+	if !out.Success || out.Total != 3 {
+		t.Fatalf("Total = %d (error %q), want 3 across all workspaces", out.Total, out.Error)
+	}
+
+	_, out, _ = srv.handleConversationSearch(context.Background(), nil, ConversationSearchInput{
+		SelfID:    sourceID,
+		Query:     "deploy sess*",
+		Workspace: "ws-b-uuid",
+	})
+	if !out.Success || out.Total != 1 || out.Hits[0].Title != "Session B" {
+		t.Errorf("workspace search = %+v, want the Session B prompt", out)
+	}
+}
+
+func TestConversationSearch_InvalidInput(t *testing.T) {
+	srv, sourceID := setupSearchServer(t, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		input ConversationSearchInput
+	}{
+		{"unknown self_id", ConversationSearchInput{SelfID: "nope", Query: "deploy"}},
+		{"empty query", ConversationSearchInput{SelfID: sourceID, Query: " "}},
+		{"invalid since", ConversationSearchInput{SelfID: sourceID, Query: "deploy", Since: "yesterday"}},
+		{"unknown workspace", ConversationSearchInput{SelfID: sourceID, Query: "deploy", Workspace: "nope"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, out, err := srv.handleConversationSearch(ctx, nil, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.Success || out.Error == "" {
+				t.Errorf("expected an error, got %+v", out)
+			}
+		})
+	}
+}
+
+func TestConversationFork(t *testing.T) {
+	srv, sourceID := setupSearchServer(t, nil)
+	if err := srv.store.AppendEvent(sourceID, session.Event{
+		Type: session.EventTypeAgentMessage,
+		Data: session.AgentMessageData{Text: "Use the deploy script"},
+	}); err != nil {
+		t.Fatalf("Failed to append event: %v", err)
+	}
+
+	_, out, err := srv.handleConversationFork(context.Background(), nil, ConversationForkInput{
+		SelfID: sourceID,
+		Seq:    1,
+		Title:  "Other approach",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !out.Success {
+		t.Fatalf("expected success, got error: %s", out.Error)
+	}
+	if out.ParentConversationID != sourceID || out.ForkedAtSeq != 1 || out.Events != 1 || out.Title != "Other approach" {
+		t.Errorf("output = %+v", out)
+	}
+
+	meta, err := srv.store.GetMetadata(out.ConversationID)
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if meta.ParentSessionID != sourceID || meta.ChildOrigin != session.ChildOriginFork {
+		t.Errorf("fork metadata = %+v", meta)
+	}
+
+	mockSM := srv.sessionManager.(*mockSessionManagerCrossWorkspace)
+	if len(mockSM.broadcastCalls) != 1 || mockSM.broadcastCalls[0].sessionID != out.ConversationID {
+		t.Errorf("broadcast calls = %+v", mockSM.broadcastCalls)
+	}
+
+	// The fork is a child, so it can't fork again
+	_, out, _ = srv.handleConversationFork(context.Background(), nil, ConversationForkInput{SelfID: out.ConversationID})
+	if out.Success || out.Error == "" {
+		t.Errorf("fork from a child should fail, got %+v", out)
+	}
+}
+
+func TestConversationFork_Errors(t *testing.T) {
+	srv, sourceID := setupSearchServer(t, nil)
+	sessions, _ := srv.store.List()
+	var otherWorkspaceID string
+	for _, meta := range sessions {
+		if meta.WorkingDir == "/workspace-b" {
+			otherWorkspaceID = meta.SessionID
+		}
+	}
+
+	for name, input := range map[string]ConversationForkInput{
+		"invalid seq":     {SelfID: sourceID, Seq: 5},
+		"negative seq":    {SelfID: sourceID, Seq: -1},
+		"unknown source":  {SelfID: sourceID, ConversationID: "missing"},
+		"other workspace": {SelfID: sourceID, ConversationID: otherWorkspaceID},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, out, err := srv.handleConversationFork(context.Background(), nil, input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.Success || out.Error == "" {
+				t.Errorf("expected an error, got %+v", out)
+			}
+		})
+	}
+
+	// Without the flag, conversations can't be forked
+	srv, sourceID = setupSearchServer(t, map[string]bool{session.FlagCanStartConversation: false})
+	_, out, _ := srv.handleConversationFork(context.Background(), nil, ConversationForkInput{SelfID: sourceID})
+	if out.Success || !strings.Contains(out.Error, session.FlagCanStartConversation) {
+		t.Errorf("expected a flag error, got %+v", out)
+	}
+}
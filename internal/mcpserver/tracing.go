@@ -0,0 +1,39 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/inercia/mitto/internal/tracing"
+)
+
+// tracingMiddleware traces the tool calls in an "mcp.tool" span, child of the
+// span propagated by the client in the W3C Trace Context headers (if any).
+func tracingMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != "tools/call" {
+			return next(ctx, method, req)
+		}
+
+		if extra := req.GetExtra(); extra != nil && extra.Header != nil {
+			ctx = tracing.Extract(ctx, propagation.HeaderCarrier(extra.Header))
+		}
+		toolName := ""
+		if params, ok := req.GetParams().(*mcp.CallToolParamsRaw); ok {
+			toolName = params.Name
+		}
+		ctx, span := tracing.Start(ctx, "mcp.tool", attribute.String("mcp.tool_name", toolName))
+
+		result, err := next(ctx, method, req)
+		spanErr := err
+		if res, ok := result.(*mcp.CallToolResult); ok && err == nil && res.IsError {
+			spanErr = errors.New("tool returned an error")
+		}
+		tracing.End(span, spanErr)
+		return result, err
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/inercia/mitto/internal/appdir"
 	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
 )
 
 // ListConversationsInput contains optional filter criteria for mitto_conversation_list.
@@ -356,10 +357,16 @@ type ConversationUpdateInput struct {
 	PeriodicPrompt         *string `json:"periodic_prompt,omitempty"`          // The prompt to send periodically
 	PeriodicFrequencyValue *int    `json:"periodic_frequency_value,omitempty"` // Number of units between sends
 	PeriodicFrequencyUnit  *string `json:"periodic_frequency_unit,omitempty"`  // Time unit: "minutes", "hours", or "days"
-	PeriodicFrequencyAt    *string `json:"periodic_frequency_at,omitempty"`    // Time of day HH:MM (UTC), only for "days"
+	PeriodicFrequencyAt    *string `json:"periodic_frequency_at,omitempty"`    // Time of day HH:MM (in periodic_timezone, default UTC), only for "days"
 	PeriodicEnabled        *bool   `json:"periodic_enabled,omitempty"`         // Whether periodic is active (defaults to true)
 	PeriodicFreshContext   *bool   `json:"periodic_fresh_context,omitempty"`   // Start each run with a fresh agent context (default false)
 	PeriodicMaxIterations  *int    `json:"periodic_max_iterations,omitempty"`  // Maximum number of scheduled runs (0 = unlimited)
+
+	// Calendar schedule — optional, replaces the frequency when periodic_cron is set
+	PeriodicCron         *string                  `json:"periodic_cron,omitempty"`          // 5-field cron expression, e.g. "0 9 * * 1-5" (empty string clears it)
+	PeriodicTimeZone     *string                  `json:"periodic_timezone,omitempty"`      // IANA time zone, e.g. "Europe/Madrid" (default UTC)
+	PeriodicBlackouts    []session.BlackoutWindow `json:"periodic_blackouts,omitempty"`     // Windows when the prompt is never sent (replaces existing; [] clears)
+	PeriodicPreviewCount *int                     `json:"periodic_preview_count,omitempty"` // Number of upcoming runs to return (default 5)
 }
 
 // UserDataAttributeUpdate represents a single user data attribute to set.
@@ -386,7 +393,12 @@ type ConversationUpdateOutput struct {
 	PeriodicMaxIterations  int    `json:"periodic_max_iterations,omitempty"`
 	PeriodicIterationCount int    `json:"periodic_iteration_count,omitempty"`
 	PeriodicNextRun        string `json:"periodic_next_run,omitempty"` // RFC3339 format
-	Error                  string `json:"error,omitempty"`
+	// Calendar schedule and the upcoming runs (RFC3339, in the schedule time zone)
+	PeriodicCron      string                   `json:"periodic_cron,omitempty"`
+	PeriodicTimeZone  string                   `json:"periodic_timezone,omitempty"`
+	PeriodicBlackouts []session.BlackoutWindow `json:"periodic_blackouts,omitempty"`
+	PeriodicNextRuns  []string                 `json:"periodic_next_runs,omitempty"`
+	Error             string                   `json:"error,omitempty"`
 }
 
 // UITextboxInput is the input for the mitto_ui_textbox tool.
@@ -795,6 +807,65 @@ type ConversationHistoryEvent struct {
 	Data      interface{} `json:"data,omitempty"` // Full event data (if include_data is true)
 }
 
+// =============================================================================
+// Conversation Search Types
+// =============================================================================
+
+// ConversationSearchInput is the input for mitto_conversation_search tool.
+type ConversationSearchInput struct {
+	SelfID    string `json:"self_id"`             // YOUR session ID (the caller)
+	Query     string `json:"query"`               // Search text: all words must match; a trailing '*' matches a prefix (e.g. "pars*")
+	Workspace string `json:"workspace,omitempty"` // Optional workspace UUID (defaults to the caller's workspace, or all workspaces with cross-workspace permission)
+	Since     string `json:"since,omitempty"`     // Only events at/after this time (RFC 3339 timestamp or relative duration "ago", e.g. "3m", "1h")
+	Until     string `json:"until,omitempty"`     // Only events at/before this time (RFC 3339 timestamp or relative duration "ago", e.g. "3m", "1h")
+	Archived  *bool  `json:"archived,omitempty"`  // Filter by archived status (omit for both)
+	Limit     int    `json:"limit,omitempty"`     // Maximum number of hits (default: 20, max: 200)
+}
+
+// ConversationSearchOutput is the output for mitto_conversation_search tool.
+type ConversationSearchOutput struct {
+	Success bool                    `json:"success"`
+	Total   int                     `json:"total"` // Number of matching events (before applying the limit)
+	Hits    []ConversationSearchHit `json:"hits"`  // Must be empty array, not nil — ACP validates this
+	Error   string                  `json:"error,omitempty"`
+}
+
+// ConversationSearchHit is a single matching event in the search response.
+type ConversationSearchHit struct {
+	ConversationID string  `json:"conversation_id"`
+	Title          string  `json:"title,omitempty"`
+	WorkingDir     string  `json:"working_dir"`
+	Archived       bool    `json:"archived,omitempty"`
+	Seq            int64   `json:"seq"`
+	Type           string  `json:"type"`
+	Timestamp      string  `json:"timestamp"` // ISO 8601
+	Snippet        string  `json:"snippet"`   // Matching text, with matches wrapped in <mark>...</mark>
+	Score          float64 `json:"score"`
+}
+
+// =============================================================================
+// Conversation Fork Types
+// =============================================================================
+
+// ConversationForkInput is the input for mitto_conversation_fork tool.
+type ConversationForkInput struct {
+	SelfID         string `json:"self_id"`                   // YOUR session ID (the caller)
+	ConversationID string `json:"conversation_id,omitempty"` // Conversation to fork (defaults to the caller)
+	Seq            int64  `json:"seq,omitempty"`             // Sequence number of the last event to copy (0 = the whole conversation)
+	Title          string `json:"title,omitempty"`           // Optional title for the fork (defaults to the source title with a "(fork)" suffix)
+}
+
+// ConversationForkOutput is the output for mitto_conversation_fork tool.
+type ConversationForkOutput struct {
+	Success              bool   `json:"success"`
+	ConversationID       string `json:"conversation_id,omitempty"`        // ID of the new conversation
+	Title                string `json:"title,omitempty"`                  // Title of the new conversation
+	ParentConversationID string `json:"parent_conversation_id,omitempty"` // The conversation that was forked
+	ForkedAtSeq          int64  `json:"forked_at_seq,omitempty"`          // Sequence number of the last copied event
+	Events               int    `json:"events"`                           // Number of events copied
+	Error                string `json:"error,omitempty"`
+}
+
 // =============================================================================
 // Prompt Management Tool Types
 // =============================================================================
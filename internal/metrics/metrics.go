@@ -0,0 +1,353 @@
+// Package metrics implements the small subset of Prometheus instrumentation
+// Mitto needs: counters, gauges and histograms with labels, exposed in the
+// Prometheus text format (version 0.0.4).
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are histogram buckets for durations in seconds, from 100ms to 10min.
+var DefaultBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// Collector is a metric family that can write itself in the text format.
+type Collector interface {
+	// Name returns the name of the metric family.
+	Name() string
+	// Write writes the HELP and TYPE lines and the samples of the family.
+	Write(w io.Writer) error
+}
+
+// Registry is a set of collectors exposed together.
+// It is safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// MustRegister adds collectors to the registry.
+// It panics if a collector with the same name is already registered.
+func (r *Registry) MustRegister(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range collectors {
+		for _, existing := range r.collectors {
+			if existing.Name() == c.Name() {
+				panic(fmt.Sprintf("metrics: duplicate metric %q", c.Name()))
+			}
+		}
+		r.collectors = append(r.collectors, c)
+	}
+}
+
+// WriteText writes all the metrics in the Prometheus text format, sorted by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	collectors := slices.Clone(r.collectors)
+	r.mu.RUnlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].Name() < collectors[j].Name() })
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		if err := c.Write(bw); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler returns an HTTP handler that serves the metrics of the registry.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		_ = r.WriteText(w)
+	})
+}
+
+// desc holds what all metric families share: name, help and label names.
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d *desc) Name() string { return d.name }
+
+func (d *desc) writeHeader(w io.Writer, typ string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, typ)
+	return err
+}
+
+// key joins label values into a map key.
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs formats the labels of a sample, with optional extra pairs (such as le).
+func (d *desc) labelPairs(values []string, extra ...string) string {
+	if len(d.labels) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	n := 0
+	write := func(name, value string) {
+		if n > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(value))
+		b.WriteByte('"')
+		n++
+	}
+	for i, name := range d.labels {
+		write(name, values[i])
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		write(extra[i], extra[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// sortedKeys returns the keys of a series map in a stable order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitKey is the inverse of desc.key.
+func splitKey(key string, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	return strings.SplitN(key, "\xff", n)
+}
+
+// Counter is a monotonically increasing value, partitioned by labels.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter creates a counter with the given label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]float64)}
+}
+
+// Inc increments the counter of the given label values by one.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter of the given label values. Negative values are ignored.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// Value returns the current value of the counter of the given label values.
+func (c *Counter) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+// Write implements Collector.
+func (c *Counter) Write(w io.Writer) error {
+	if err := c.writeHeader(w, "counter"); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		values := splitKey(key, len(c.labels))
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(values), formatFloat(c.values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sample is a value of a gauge with its label values.
+type Sample struct {
+	Value       float64
+	LabelValues []string
+}
+
+// GaugeFunc is a gauge whose samples are computed when the metrics are collected,
+// for values that already live elsewhere (such as the number of connected clients).
+type GaugeFunc struct {
+	desc
+	collect func() []Sample
+}
+
+// NewGaugeFunc creates a gauge without labels, whose value is computed by fn.
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return &GaugeFunc{
+		desc:    desc{name: name, help: help},
+		collect: func() []Sample { return []Sample{{Value: fn()}} },
+	}
+}
+
+// NewGaugeVecFunc creates a gauge with the given label names, whose samples are
+// returned by fn.
+func NewGaugeVecFunc(name, help string, labels []string, fn func() []Sample) *GaugeFunc {
+	return &GaugeFunc{desc: desc{name: name, help: help, labels: labels}, collect: fn}
+}
+
+// Write implements Collector.
+func (g *GaugeFunc) Write(w io.Writer) error {
+	if err := g.writeHeader(w, "gauge"); err != nil {
+		return err
+	}
+	samples := g.collect()
+	sort.Slice(samples, func(i, j int) bool {
+		return slices.Compare(samples[i].LabelValues, samples[j].LabelValues) < 0
+	})
+	for _, s := range samples {
+		if len(s.LabelValues) != len(g.labels) {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", g.name, g.labelPairs(s.LabelValues), formatFloat(s.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Histogram counts observations in buckets, partitioned by labels.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates a histogram with the given upper bounds of the buckets
+// (sorted, without +Inf) and label names.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+}
+
+// Observe adds an observation to the histogram of the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+// Count returns the number of observations of the given label values.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[key]; ok {
+		return s.count
+	}
+	return 0
+}
+
+// Write implements Collector.
+func (h *Histogram) Write(w io.Writer) error {
+	if err := h.writeHeader(w, "histogram"); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.series) {
+		values := splitKey(key, len(h.labels))
+		s := h.series[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(values, "le", formatFloat(bound)), cumulative); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.name, h.labelPairs(values, "le", "+Inf"), s.count,
+			h.name, h.labelPairs(values), formatFloat(s.sum),
+			h.name, h.labelPairs(values), s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelEscaper.Replace(s) }
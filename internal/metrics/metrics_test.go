@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	requests := NewCounter("test_requests_total", "Requests by code.", "code")
+	requests.Inc("200")
+	requests.Inc("200")
+	requests.Add(3, "500")
+	requests.Add(-1, "500") // ignored
+
+	clients := NewGaugeFunc("test_clients", "Connected clients.", func() float64 { return 4 })
+	rss := NewGaugeVecFunc("test_rss_bytes", "RSS.", []string{"workspace"}, func() []Sample {
+		return []Sample{
+			{Value: 200, LabelValues: []string{"b"}},
+			{Value: 100, LabelValues: []string{"a"}},
+			{Value: 1, LabelValues: []string{"bad", "arity"}}, // dropped
+		}
+	})
+
+	latency := NewHistogram("test_latency_seconds", "Latency.", []float64{1, 5}, "agent")
+	latency.Observe(0.5, "claude")
+	latency.Observe(1, "claude")
+	latency.Observe(3, "claude")
+	latency.Observe(100, "claude")
+
+	r := NewRegistry()
+	r.MustRegister(requests, clients, rss, latency)
+
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	want := `# HELP test_clients Connected clients.
+# TYPE test_clients gauge
+test_clients 4
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{agent="claude",le="1"} 2
+test_latency_seconds_bucket{agent="claude",le="5"} 3
+test_latency_seconds_bucket{agent="claude",le="+Inf"} 4
+test_latency_seconds_sum{agent="claude"} 104.5
+test_latency_seconds_count{agent="claude"} 4
+# HELP test_requests_total Requests by code.
+# TYPE test_requests_total counter
+test_requests_total{code="200"} 2
+test_requests_total{code="500"} 3
+# HELP test_rss_bytes RSS.
+# TYPE test_rss_bytes gauge
+test_rss_bytes{workspace="a"} 100
+test_rss_bytes{workspace="b"} 200
+`
+	if out.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	if got := requests.Value("200"); got != 2 {
+		t.Errorf("Value() = %v, want 2", got)
+	}
+	if got := latency.Count("claude"); got != 4 {
+		t.Errorf("Count() = %v, want 4", got)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	c := NewCounter("test_total", "Help with \\ and\nnewline.", "path")
+	c.Inc(`a"b\c` + "\n")
+
+	var out strings.Builder
+	if err := c.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `# HELP test_total Help with \\ and\nnewline.`) {
+		t.Errorf("help not escaped: %s", out.String())
+	}
+	if !strings.Contains(out.String(), `test_total{path="a\"b\\c\n"} 1`) {
+		t.Errorf("label value not escaped: %s", out.String())
+	}
+}
+
+func TestMustRegister_Duplicate(t *testing.T) {
+	r := NewRegistry()
+	r.MustRegister(NewCounter("dup_total", "Dup."))
+	defer func() {
+		if recover() == nil {
+			t.Error("MustRegister() with a duplicate name did not panic")
+		}
+	}()
+	r.MustRegister(NewCounter("dup_total", "Dup."))
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.MustRegister(NewGaugeFunc("test_up", "Up.", func() float64 { return 1 }))
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	if !strings.Contains(rec.Body.String(), "test_up 1\n") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
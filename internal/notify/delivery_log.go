@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxDeliveryLogBytes is the size above which the delivery log is rotated
+// (the previous log is kept with a ".1" suffix).
+const maxDeliveryLogBytes = 5 << 20
+
+// Delivery is a delivery attempt of an event to a webhook.
+type Delivery struct {
+	// ID identifies the delivery; all the attempts of a delivery share it.
+	ID string `json:"id"`
+	// EventID and Event are the ID and the type of the event delivered.
+	EventID string `json:"event_id"`
+	Event   string `json:"event"`
+	// Webhook is the name of the webhook (the URL is not recorded, as it may hold a secret).
+	Webhook string `json:"webhook"`
+	// Attempt is the attempt number, starting at 1.
+	Attempt int `json:"attempt"`
+	// StatusCode is the HTTP status of the response (0 if there was none).
+	StatusCode int `json:"status_code,omitempty"`
+	// Success is true when the webhook answered with a 2xx status.
+	Success bool `json:"success"`
+	// WillRetry is true when the attempt failed and will be retried.
+	WillRetry bool `json:"will_retry,omitempty"`
+	// Error describes why the attempt failed.
+	Error string `json:"error,omitempty"`
+	// Time is when the attempt started.
+	Time time.Time `json:"time"`
+	// DurationMs is how long the request took, in milliseconds.
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// DeliveryLog records delivery attempts in a JSON Lines file.
+// It is safe for concurrent use. A nil *DeliveryLog discards all records.
+type DeliveryLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewDeliveryLog creates a delivery log stored at path.
+func NewDeliveryLog(path string) *DeliveryLog {
+	return &DeliveryLog{path: path}
+}
+
+// Append records a delivery attempt, rotating the file when it grows too large.
+// Safe to call on nil receiver.
+func (l *DeliveryLog) Append(d Delivery) error {
+	if l == nil {
+		return nil
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if info, err := os.Stat(l.path); err == nil && info.Size() > maxDeliveryLogBytes {
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate delivery log: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create delivery log directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open delivery log: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Recent returns the last limit delivery attempts, newest first.
+// Safe to call on nil receiver.
+func (l *DeliveryLog) Recent(limit int) ([]Delivery, error) {
+	if l == nil || limit <= 0 {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open delivery log: %w", err)
+	}
+	defer f.Close()
+
+	// Keep a ring of the last limit records
+	ring := make([]Delivery, 0, limit)
+	next := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var d Delivery
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			continue // Skip corrupt lines
+		}
+		if len(ring) < limit {
+			ring = append(ring, d)
+		} else {
+			ring[next] = d
+		}
+		next = (next + 1) % limit
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read delivery log: %w", err)
+	}
+
+	deliveries := make([]Delivery, 0, len(ring))
+	for i := 0; i < len(ring); i++ {
+		idx := (next - 1 - i + len(ring)) % len(ring)
+		deliveries = append(deliveries, ring[idx])
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,337 @@
+// Package notify delivers conversation lifecycle events to outbound webhooks.
+//
+// Events are POSTed as JSON (generic or Slack-compatible format), optionally
+// signed with HMAC-SHA256. Failed deliveries are retried with exponential
+// backoff, and every attempt is recorded in a local delivery log.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+// EventTest is the type of the events sent by Notifier.Test.
+const EventTest = "test"
+
+// HTTP headers sent with each delivery.
+const (
+	HeaderEvent     = "X-Mitto-Event"
+	HeaderDelivery  = "X-Mitto-Delivery"
+	HeaderSignature = "X-Mitto-Signature-256"
+)
+
+const (
+	// defaultRetryDelay is the delay before the first retry; it doubles after each attempt.
+	defaultRetryDelay = 2 * time.Second
+	// maxRetryDelay caps the delay between two attempts.
+	maxRetryDelay = time.Minute
+	// requestTimeout is the timeout of a single delivery attempt.
+	requestTimeout = 10 * time.Second
+	// maxResponseBytes is how much of a response body is kept in the delivery log.
+	maxResponseBytes = 512
+)
+
+// Event is a conversation lifecycle event.
+type Event struct {
+	// ID uniquely identifies the event (set by Notify if empty).
+	ID string `json:"id"`
+	// Type is one of the config.WebhookEvent* constants.
+	Type string `json:"type"`
+	// Time is when the event happened (set by Notify if zero).
+	Time time.Time `json:"time"`
+	// SessionID is the conversation the event is about.
+	SessionID string `json:"session_id,omitempty"`
+	// SessionName is the title of the conversation.
+	SessionName string `json:"session_name,omitempty"`
+	// WorkingDir is the workspace folder of the conversation.
+	WorkingDir string `json:"working_dir,omitempty"`
+	// ParentSessionID is the parent of a child conversation.
+	ParentSessionID string `json:"parent_session_id,omitempty"`
+	// Message is a human-readable description of the event.
+	Message string `json:"message,omitempty"`
+	// Data holds event-specific details.
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// Notifier sends events to the configured webhooks.
+// A nil *Notifier is valid and discards all events.
+type Notifier struct {
+	webhooks []config.WebhookConfig
+	client   *http.Client
+	log      *DeliveryLog
+	logger   *slog.Logger
+
+	// retryDelay is the delay before the first retry (overridden in tests).
+	retryDelay time.Duration
+
+	// mu orders Notify and Close, so no delivery starts once Close waits.
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a notifier for the valid webhooks; invalid ones are logged and
+// skipped. Returns nil when there is no valid webhook.
+// log may be nil to disable the delivery log.
+func New(webhooks []config.WebhookConfig, log *DeliveryLog, logger *slog.Logger) *Notifier {
+	var valid []config.WebhookConfig
+	for _, wh := range webhooks {
+		if err := wh.Validate(); err != nil {
+			if logger != nil {
+				logger.Warn("Ignoring invalid webhook", "error", err)
+			}
+			continue
+		}
+		valid = append(valid, wh)
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Notifier{
+		webhooks:   valid,
+		client:     &http.Client{Timeout: requestTimeout},
+		log:        log,
+		logger:     logger,
+		retryDelay: defaultRetryDelay,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Notify sends an event, asynchronously, to the webhooks subscribed to its type.
+// Safe to call on nil receiver.
+func (n *Notifier) Notify(ev Event) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ctx.Err() != nil {
+		return
+	}
+	fillEvent(&ev)
+	for _, wh := range n.webhooks {
+		if !wh.Wants(ev.Type) {
+			continue
+		}
+		n.wg.Add(1)
+		go func(wh config.WebhookConfig) {
+			defer n.wg.Done()
+			n.deliver(wh, ev)
+		}(wh)
+	}
+}
+
+// Test sends a test event to the webhook with the given name (all webhooks if
+// name is empty), with a single attempt, and returns the deliveries.
+// Safe to call on nil receiver.
+func (n *Notifier) Test(ctx context.Context, name string) []Delivery {
+	if n == nil {
+		return nil
+	}
+	ev := Event{Type: EventTest, Message: "Test notification from Mitto"}
+	fillEvent(&ev)
+	var deliveries []Delivery
+	for _, wh := range n.webhooks {
+		if name != "" && wh.DisplayName() != name {
+			continue
+		}
+		d, _ := n.attempt(ctx, wh, ev, newID(), 1)
+		n.record(d)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries
+}
+
+// Close cancels in-flight deliveries and pending retries, and waits for their
+// goroutines to finish. Events notified afterwards are discarded.
+// Safe to call on nil receiver.
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	n.cancel()
+	n.mu.Unlock()
+	n.wg.Wait()
+}
+
+// deliver sends an event to a webhook, retrying with exponential backoff.
+func (n *Notifier) deliver(wh config.WebhookConfig, ev Event) {
+	deliveryID := newID()
+	delay := n.retryDelay
+	maxAttempts := wh.GetMaxAttempts()
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		d, retry := n.attempt(n.ctx, wh, ev, deliveryID, attempt)
+		d.WillRetry = retry && attempt < maxAttempts && n.ctx.Err() == nil
+		n.record(d)
+		if !d.WillRetry {
+			if !d.Success && n.logger != nil {
+				n.logger.Warn("Webhook delivery failed",
+					"webhook", d.Webhook,
+					"event", ev.Type,
+					"session_id", ev.SessionID,
+					"attempts", attempt,
+					"status_code", d.StatusCode,
+					"error", d.Error)
+			}
+			return
+		}
+
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// attempt makes a single delivery attempt. It returns the delivery record and
+// whether a failed attempt can be retried (network errors, 429 and 5xx).
+func (n *Notifier) attempt(ctx context.Context, wh config.WebhookConfig, ev Event, deliveryID string, attempt int) (Delivery, bool) {
+	d := Delivery{
+		ID:      deliveryID,
+		EventID: ev.ID,
+		Event:   ev.Type,
+		Webhook: wh.DisplayName(),
+		Attempt: attempt,
+		Time:    time.Now().UTC(),
+	}
+
+	body, err := Format(wh.Format, ev)
+	if err != nil {
+		d.Error = err.Error()
+		return d, false
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		d.Error = deliveryError(err)
+		return d, false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mitto-Webhook")
+	req.Header.Set(HeaderEvent, ev.Type)
+	req.Header.Set(HeaderDelivery, deliveryID)
+	if wh.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(wh.Secret, body))
+	}
+	for k, v := range wh.Headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := n.client.Do(req)
+	d.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		d.Error = deliveryError(err)
+		return d, true
+	}
+	defer resp.Body.Close()
+
+	d.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		d.Success = true
+		return d, false
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	d.Error = strings.TrimSpace(fmt.Sprintf("HTTP %d: %s", resp.StatusCode, snippet))
+	return d, resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// deliveryError returns the description of a request error without the URL
+// of the webhook, which may hold a secret.
+func deliveryError(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Op + ": " + urlErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// record appends a delivery to the delivery log.
+func (n *Notifier) record(d Delivery) {
+	if err := n.log.Append(d); err != nil && n.logger != nil {
+		n.logger.Warn("Failed to record webhook delivery", "error", err)
+	}
+}
+
+// Sign returns the X-Mitto-Signature-256 header value of a body:
+// "sha256=" followed by the hex HMAC-SHA256 of the body with the secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Format renders an event in a webhook payload format.
+func Format(format string, ev Event) ([]byte, error) {
+	if format == config.WebhookFormatSlack {
+		return json.Marshal(map[string]string{"text": SlackText(ev)})
+	}
+	return json.Marshal(ev)
+}
+
+// SlackText returns the text of the Slack message for an event.
+func SlackText(ev Event) string {
+	name := ev.SessionName
+	if name == "" {
+		name = ev.SessionID
+	}
+	var title string
+	switch ev.Type {
+	case config.WebhookEventTurnComplete:
+		title = fmt.Sprintf("*%s* finished a turn", name)
+	case config.WebhookEventPermissionRequested:
+		title = fmt.Sprintf("*%s* is waiting for permission", name)
+	case config.WebhookEventError:
+		title = fmt.Sprintf("*%s* failed", name)
+	case config.WebhookEventPeriodicMaxIterations:
+		title = fmt.Sprintf("*%s* reached its maximum number of periodic runs", name)
+	case config.WebhookEventChildReport:
+		title = fmt.Sprintf("*%s* reported to its parent", name)
+	default:
+		title = "*Mitto*"
+	}
+	if ev.Message == "" {
+		return title
+	}
+	return title + "\n" + ev.Message
+}
+
+// fillEvent sets the ID and time of an event when missing.
+func fillEvent(ev *Event) {
+	if ev.ID == "" {
+		ev.ID = newID()
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+}
+
+// newID returns a random identifier for events and deliveries.
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,279 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+// recordingServer is a webhook endpoint that fails the first failures requests.
+type recordingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newRecordingServer(t *testing.T, failures int) *recordingServer {
+	rs := &recordingServer{failures: failures}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		rs.requests = append(rs.requests, r)
+		rs.bodies = append(rs.bodies, body)
+		if len(rs.requests) <= rs.failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func (rs *recordingServer) count() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return len(rs.requests)
+}
+
+func TestNew_SkipsInvalidWebhooks(t *testing.T) {
+	if n := New(nil, nil, nil); n != nil {
+		t.Errorf("New() without webhooks = %v, want nil", n)
+	}
+	n := New([]config.WebhookConfig{
+		{URL: "ftp://example.com"},
+		{URL: "https://example.com/hook", Format: "teams"},
+		{URL: "https://example.com/hook", Events: []string{"finished"}},
+		{URL: "https://example.com/hook", Events: []string{config.WebhookEventError}},
+	}, nil, nil)
+	if n == nil || len(n.webhooks) != 1 {
+		t.Fatalf("New() kept %v, want only the valid webhook", n)
+	}
+	n.Close()
+
+	// A nil notifier discards events
+	var nilNotifier *Notifier
+	nilNotifier.Notify(Event{Type: config.WebhookEventError})
+	nilNotifier.Close()
+}
+
+func TestNotifier_DeliversSignedEvents(t *testing.T) {
+	rs := newRecordingServer(t, 0)
+	log := NewDeliveryLog(filepath.Join(t.TempDir(), "deliveries.jsonl"))
+	n := New([]config.WebhookConfig{
+		{Name: "generic", URL: rs.URL, Secret: "s3cret", Headers: map[string]string{"Authorization": "Bearer x"}},
+		{Name: "errors-only", URL: rs.URL, Events: []string{config.WebhookEventError}},
+	}, log, nil)
+
+	n.Notify(Event{Type: config.WebhookEventTurnComplete, SessionID: "s1", SessionName: "Fix the build"})
+	n.wg.Wait()
+	n.Close()
+
+	if rs.count() != 1 {
+		t.Fatalf("requests = %d, want 1 (the errors-only webhook is not subscribed)", rs.count())
+	}
+	req, body := rs.requests[0], rs.bodies[0]
+	if got := req.Header.Get(HeaderSignature); got != Sign("s3cret", body) {
+		t.Errorf("signature = %q, want %q", got, Sign("s3cret", body))
+	}
+	if req.Header.Get(HeaderEvent) != config.WebhookEventTurnComplete || req.Header.Get("Authorization") != "Bearer x" {
+		t.Errorf("headers = %v", req.Header)
+	}
+	var ev Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		t.Fatalf("body is not an event: %v", err)
+	}
+	if ev.ID == "" || ev.Time.IsZero() || ev.SessionID != "s1" || ev.SessionName != "Fix the build" {
+		t.Errorf("event = %+v", ev)
+	}
+
+	deliveries, err := log.Recent(10)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+	if len(deliveries) != 1 || !deliveries[0].Success || deliveries[0].StatusCode != http.StatusNoContent || deliveries[0].Webhook != "generic" {
+		t.Errorf("deliveries = %+v", deliveries)
+	}
+}
+
+func TestNotifier_RetriesWithBackoff(t *testing.T) {
+	rs := newRecordingServer(t, 2)
+	log := NewDeliveryLog(filepath.Join(t.TempDir(), "deliveries.jsonl"))
+	n := New([]config.WebhookConfig{{URL: rs.URL, MaxAttempts: 4}}, log, nil)
+	n.retryDelay = time.Millisecond
+
+	n.Notify(Event{Type: config.WebhookEventError, Message: "boom"})
+	n.wg.Wait()
+	n.Close()
+
+	if rs.count() != 3 {
+		t.Fatalf("requests = %d, want 3 (two failures, then success)", rs.count())
+	}
+	deliveries, err := log.Recent(10)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+	if len(deliveries) != 3 {
+		t.Fatalf("deliveries = %+v, want 3 attempts", deliveries)
+	}
+	// Newest first
+	if !deliveries[0].Success || deliveries[0].Attempt != 3 {
+		t.Errorf("last attempt = %+v", deliveries[0])
+	}
+	if deliveries[2].Success || !deliveries[2].WillRetry || deliveries[2].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("first attempt = %+v", deliveries[2])
+	}
+	if deliveries[0].ID != deliveries[2].ID {
+		t.Errorf("attempts have different delivery IDs: %q, %q", deliveries[0].ID, deliveries[2].ID)
+	}
+}
+
+func TestNotifier_GivesUpOnClientErrors(t *testing.T) {
+	rs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such hook", http.StatusNotFound)
+	}))
+	defer rs.Close()
+	log := NewDeliveryLog(filepath.Join(t.TempDir(), "deliveries.jsonl"))
+	n := New([]config.WebhookConfig{{URL: rs.URL}}, log, nil)
+	n.retryDelay = time.Millisecond
+
+	n.Notify(Event{Type: config.WebhookEventError})
+	n.wg.Wait()
+	n.Close()
+
+	deliveries, _ := log.Recent(10)
+	if len(deliveries) != 1 || deliveries[0].WillRetry || !strings.Contains(deliveries[0].Error, "no such hook") {
+		t.Errorf("deliveries = %+v, want a single failed attempt", deliveries)
+	}
+}
+
+func TestNotifier_FailedDeliveriesOmitTheURL(t *testing.T) {
+	rs := httptest.NewServer(http.NotFoundHandler())
+	rs.Close() // Nothing listens on the URL
+	log := NewDeliveryLog(filepath.Join(t.TempDir(), "deliveries.jsonl"))
+	n := New([]config.WebhookConfig{{Name: "ops", URL: rs.URL + "/hooks/s3cr3t?token=t0k3n"}}, log, nil)
+	defer n.Close()
+
+	deliveries := n.Test(context.Background(), "ops")
+	if len(deliveries) != 1 || deliveries[0].Success || deliveries[0].Error == "" {
+		t.Fatalf("Test() = %+v, want a failed attempt", deliveries)
+	}
+	recorded, _ := log.Recent(10)
+	for _, d := range append(deliveries, recorded...) {
+		if strings.Contains(d.Error, "s3cr3t") || strings.Contains(d.Error, "t0k3n") {
+			t.Errorf("delivery error %q contains the webhook URL", d.Error)
+		}
+	}
+}
+
+func TestNotifier_CloseCancelsDeliveries(t *testing.T) {
+	release := make(chan struct{})
+	rs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer rs.Close()
+	defer close(release)
+	log := NewDeliveryLog(filepath.Join(t.TempDir(), "deliveries.jsonl"))
+	n := New([]config.WebhookConfig{{URL: rs.URL}}, log, nil)
+
+	// Notify races with Close: events are either delivered or discarded
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.Notify(Event{Type: config.WebhookEventError})
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		n.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(requestTimeout / 2):
+		t.Fatal("Close() waited for the in-flight deliveries")
+	}
+	wg.Wait()
+	n.Notify(Event{Type: config.WebhookEventError})
+	n.wg.Wait()
+
+	deliveries, _ := log.Recent(20)
+	for _, d := range deliveries {
+		if d.Success || d.WillRetry {
+			t.Errorf("delivery = %+v, want a cancelled attempt without retry", d)
+		}
+	}
+}
+
+func TestNotifier_Test(t *testing.T) {
+	rs := newRecordingServer(t, 0)
+	n := New([]config.WebhookConfig{
+		{Name: "a", URL: rs.URL, Events: []string{config.WebhookEventError}},
+		{Name: "b", URL: rs.URL},
+	}, nil, nil)
+	defer n.Close()
+
+	deliveries := n.Test(context.Background(), "a")
+	if len(deliveries) != 1 || !deliveries[0].Success || deliveries[0].Event != EventTest {
+		t.Errorf("Test(a) = %+v", deliveries)
+	}
+	if got := n.Test(context.Background(), ""); len(got) != 2 {
+		t.Errorf("Test() = %+v, want both webhooks", got)
+	}
+}
+
+func TestFormat_Slack(t *testing.T) {
+	data, err := Format(config.WebhookFormatSlack, Event{
+		Type:        config.WebhookEventPermissionRequested,
+		SessionName: "Deploy",
+		Message:     "Run kubectl apply",
+	})
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	var msg map[string]string
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if msg["text"] != "*Deploy* is waiting for permission\nRun kubectl apply" {
+		t.Errorf("text = %q", msg["text"])
+	}
+}
+
+func TestDeliveryLog_Recent(t *testing.T) {
+	log := NewDeliveryLog(filepath.Join(t.TempDir(), "sub", "deliveries.jsonl"))
+	if got, err := log.Recent(5); err != nil || len(got) != 0 {
+		t.Fatalf("Recent() on a missing log = %v, %v", got, err)
+	}
+	for i := 1; i <= 7; i++ {
+		if err := log.Append(Delivery{Attempt: i}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	got, err := log.Recent(3)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+	if len(got) != 3 || got[0].Attempt != 7 || got[2].Attempt != 5 {
+		t.Errorf("Recent(3) = %+v, want attempts 7, 6, 5", got)
+	}
+}
@@ -11,12 +11,21 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/inercia/mitto/internal/tracing"
 )
 
 // executeAfterCommand runs a command-mode processor in the agentResponded phase.
 // It marshals the AfterProcessorInput as JSON to stdin, captures stdout, and
 // returns the raw stdout string. Timeout is taken from proc.GetTimeout().
-func executeAfterCommand(ctx context.Context, proc *Processor, processorsDir string, input AfterProcessorInput, logger *slog.Logger) (string, error) {
+func executeAfterCommand(ctx context.Context, proc *Processor, processorsDir string, input AfterProcessorInput, logger *slog.Logger) (output string, err error) {
+	ctx, span := tracing.Start(ctx, "processor.execute_after",
+		attribute.String("mitto.processor", proc.Name),
+		attribute.String("mitto.session_id", input.SessionID))
+	defer func() { tracing.End(span, err) }()
+
 	timeout := proc.GetTimeout().Duration()
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -33,7 +42,7 @@ func executeAfterCommand(ctx context.Context, proc *Processor, processorsDir str
 		cmd.Dir = proc.HookDir
 	}
 
-	cmd.Env = buildAfterEnvironment(proc, processorsDir, input)
+	cmd.Env = append(buildAfterEnvironment(proc, processorsDir, input), tracing.Environ(ctx)...)
 
 	if proc.GetInput() != InputNone {
 		data, err := json.Marshal(input)
@@ -48,7 +57,7 @@ func executeAfterCommand(ctx context.Context, proc *Processor, processorsDir str
 	cmd.Stderr = &stderr
 
 	start := time.Now()
-	err := cmd.Run()
+	err = cmd.Run()
 	duration := time.Since(start)
 
 	if logger != nil {
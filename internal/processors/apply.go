@@ -9,7 +9,10 @@ import (
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/tracing"
 )
 
 const (
@@ -517,7 +520,17 @@ func (m *Manager) Processors() []*Processor {
 // Handles rerun logic for "when.sent: first" processors: if a processor has a when.rerun config,
 // it tracks when the processor last ran and re-fires it when a threshold is reached.
 // Returns the processor result containing the transformed message and any attachments.
-func (m *Manager) Apply(ctx context.Context, input *ProcessorInput) (*ProcessorResult, error) {
+func (m *Manager) Apply(ctx context.Context, input *ProcessorInput) (result *ProcessorResult, err error) {
+	ctx, span := tracing.Start(ctx, "processors.apply",
+		attribute.String("mitto.session_id", input.SessionID),
+		attribute.Bool("mitto.first_message", input.IsFirstMessage))
+	defer func() {
+		if result != nil {
+			span.SetAttributes(attribute.StringSlice("mitto.processors.applied", result.AppliedNames))
+		}
+		tracing.End(span, err)
+	}()
+
 	// Pre-pass: check rerun eligibility for when.sent:first processors.
 	// We temporarily override isFirstMessage for processors that are due for re-run.
 	rerunOverrides := m.checkRerunEligibility(input)
@@ -533,7 +546,7 @@ func (m *Manager) Apply(ctx context.Context, input *ProcessorInput) (*ProcessorR
 		return m.applyWithRerun(ctx, input, origIsFirst, rerunOverrides)
 	}
 
-	result, err := ApplyProcessors(ctx, m.processors, input, m.processorsDir, m.logger)
+	result, err = ApplyProcessors(ctx, m.processors, input, m.processorsDir, m.logger)
 
 	// Track pipeline activation
 	m.statsMu.Lock()
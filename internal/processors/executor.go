@@ -10,6 +10,10 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/inercia/mitto/internal/tracing"
 )
 
 // Executor runs processors and processes their output.
@@ -30,7 +34,12 @@ func NewExecutor(processorsDir string, logger *slog.Logger) *Executor {
 }
 
 // Execute runs a processor with the given input and returns the output.
-func (e *Executor) Execute(ctx context.Context, proc *Processor, input *ProcessorInput) (*ProcessorOutput, error) {
+func (e *Executor) Execute(ctx context.Context, proc *Processor, input *ProcessorInput) (output *ProcessorOutput, err error) {
+	ctx, span := tracing.Start(ctx, "processor.execute",
+		attribute.String("mitto.processor", proc.Name),
+		attribute.String("mitto.session_id", input.SessionID))
+	defer func() { tracing.End(span, err) }()
+
 	// Create timeout context
 	timeout := proc.GetTimeout().Duration()
 	ctx, cancel := context.WithTimeout(ctx, timeout)
@@ -50,8 +59,8 @@ func (e *Executor) Execute(ctx context.Context, proc *Processor, input *Processo
 		cmd.Dir = proc.HookDir
 	}
 
-	// Set environment
-	cmd.Env = e.buildEnvironment(proc, input)
+	// Set environment, propagating the trace context to the command
+	cmd.Env = append(e.buildEnvironment(proc, input), tracing.Environ(ctx)...)
 
 	// Prepare stdin if needed
 	if proc.GetInput() != InputNone {
@@ -69,7 +78,7 @@ func (e *Executor) Execute(ctx context.Context, proc *Processor, input *Processo
 
 	// Execute
 	start := time.Now()
-	err := cmd.Run()
+	err = cmd.Run()
 	duration := time.Since(start)
 
 	e.logger.Info("processor executed",
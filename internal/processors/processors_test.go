@@ -10,8 +10,13 @@ import (
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
 	rootconfig "github.com/inercia/mitto/config"
 	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/tracing"
 )
 
 func TestProcessorIsEnabled(t *testing.T) {
@@ -1696,6 +1701,49 @@ echo '{"message": "executed"}'
 	}
 }
 
+func TestExecutorExecute_TraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "trace.sh")
+	scriptContent := `#!/bin/sh
+echo "{\"message\": \"$TRACEPARENT\"}"
+`
+	if err := os.WriteFile(scriptPath, []byte(scriptContent), 0755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	executor := NewExecutor(tmpDir, nil)
+	hook := &Processor{
+		Name:    "trace-test",
+		Command: scriptPath,
+		Output:  OutputTransform,
+		Input:   InputNone,
+		HookDir: tmpDir,
+	}
+	input := &ProcessorInput{Message: "test", WorkingDir: tmpDir}
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	output, err := executor.Execute(ctx, hook, input)
+	parent.End()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "processor.execute" {
+		t.Fatalf("ended spans = %v, want processor.execute and parent", spans)
+	}
+	sc := spans[0].SpanContext()
+	want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+	if output.Message != want {
+		t.Errorf("TRACEPARENT = %q, want %q", output.Message, want)
+	}
+}
+
 func TestExecutorExecuteTimeout(t *testing.T) {
 	tmpDir := t.TempDir()
 
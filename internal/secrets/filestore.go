@@ -0,0 +1,280 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/inercia/mitto/internal/fileutil"
+)
+
+// PassphraseEnv is the environment variable with the passphrase that encrypts
+// the FileStore. When it is not set, a random machine key is used instead.
+const PassphraseEnv = "MITTO_SECRETS_PASSPHRASE"
+
+// Key derivation methods recorded in the encrypted file.
+const (
+	kdfScrypt     = "scrypt"
+	kdfMachineKey = "machine-key"
+)
+
+// fileStoreVersion is the version of the encrypted file format.
+const fileStoreVersion = 1
+
+// ErrWrongKey is returned when the encrypted secrets file can't be decrypted
+// with the configured passphrase or machine key.
+var ErrWrongKey = errors.New("failed to decrypt the secrets file: wrong passphrase or machine key")
+
+// encryptedFile is the on-disk format of the FileStore.
+// The plaintext is the JSON encoding of a service -> account -> password map.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// FileStore implements SecretStore with an AES-256-GCM encrypted file.
+// It is the fallback when no system secret store is available.
+//
+// The key is derived with scrypt from a passphrase when one is given, or is
+// a random machine key (readable only by the owner) otherwise. The machine key
+// is kept in another directory than the file, so it protects the credentials
+// from leaking with copies of the data directory, but not from other processes
+// of the same user.
+//
+// Reads and writes take an advisory lock on a lock file next to the file, so
+// that other processes (e.g. `mitto config secrets set` next to the server)
+// don't overwrite each other's changes.
+type FileStore struct {
+	mu         sync.Mutex
+	path       string
+	keyPath    string
+	passphrase string
+}
+
+// NewFileStore creates a FileStore that keeps the credentials in path.
+// If passphrase is empty, the key is the machine key stored in keyPath,
+// which is created on the first write.
+func NewFileStore(path, keyPath, passphrase string) *FileStore {
+	return &FileStore{path: path, keyPath: keyPath, passphrase: passphrase}
+}
+
+// Get retrieves a password from the encrypted file.
+func (f *FileStore) Get(service, account string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lockFile(false)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	entries, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	password, ok := entries[service][account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return password, nil
+}
+
+// Set stores a password in the encrypted file.
+// If the credential already exists, it is updated.
+func (f *FileStore) Set(service, account, password string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lockFile(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := f.load()
+	if err != nil {
+		return err
+	}
+	if entries[service] == nil {
+		entries[service] = make(map[string]string)
+	}
+	entries[service][account] = password
+	return f.save(entries)
+}
+
+// Delete removes a credential from the encrypted file.
+func (f *FileStore) Delete(service, account string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lockFile(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[service][account]; !ok {
+		return ErrNotFound
+	}
+	delete(entries[service], account)
+	if len(entries[service]) == 0 {
+		delete(entries, service)
+	}
+	return f.save(entries)
+}
+
+// IsSupported returns true for FileStore, as it only needs the filesystem.
+func (f *FileStore) IsSupported() bool {
+	return true
+}
+
+// Name returns the name of the backend.
+func (f *FileStore) Name() string {
+	return "encrypted file " + f.path
+}
+
+// lockFile locks the lock file of the store, shared for reads and exclusive
+// for writes, and returns the function that unlocks it. Reads of a store whose
+// directory doesn't exist yet need no lock.
+func (f *FileStore) lockFile(exclusive bool) (func(), error) {
+	if exclusive {
+		if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create secrets directory: %w", err)
+		}
+	}
+	unlock, err := flock(f.path+".lock", exclusive)
+	if err != nil {
+		if !exclusive && errors.Is(err, os.ErrNotExist) {
+			return func() {}, nil
+		}
+		return nil, fmt.Errorf("failed to lock secrets file: %w", err)
+	}
+	return unlock, nil
+}
+
+// load reads and decrypts the file. A missing file holds no credentials.
+func (f *FileStore) load() (map[string]map[string]string, error) {
+	entries := make(map[string]map[string]string)
+
+	var file encryptedFile
+	if err := fileutil.ReadJSON(f.path, &file); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	if file.Version != fileStoreVersion {
+		return nil, fmt.Errorf("unsupported secrets file version %d", file.Version)
+	}
+
+	if file.KDF == kdfMachineKey && f.passphrase != "" {
+		return nil, fmt.Errorf("the secrets file is encrypted with the machine key: unset %s", PassphraseEnv)
+	}
+	if file.KDF == kdfScrypt && f.passphrase == "" {
+		return nil, fmt.Errorf("the secrets file is encrypted with a passphrase: set %s", PassphraseEnv)
+	}
+
+	key, err := f.key(file.KDF, file.Salt, false)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	if err := json.Unmarshal(plaintext, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+	return entries, nil
+}
+
+// save encrypts the credentials with a fresh salt and nonce, and writes the file atomically.
+func (f *FileStore) save(entries map[string]map[string]string) error {
+	plaintext, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	file := encryptedFile{Version: fileStoreVersion, KDF: kdfMachineKey}
+	if f.passphrase != "" {
+		file.KDF = kdfScrypt
+		file.Salt = make([]byte, 16)
+		if _, err := rand.Read(file.Salt); err != nil {
+			return err
+		}
+	}
+
+	key, err := f.key(file.KDF, file.Salt, true)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, nil)
+
+	return fileutil.WriteJSONAtomic(f.path, &file, 0600)
+}
+
+// key returns the encryption key for the given derivation method.
+// The machine key is created when missing only if create is set.
+func (f *FileStore) key(kdf string, salt []byte, create bool) ([]byte, error) {
+	switch kdf {
+	case kdfScrypt:
+		return scrypt.Key([]byte(f.passphrase), salt, 1<<15, 8, 1, 32)
+	case kdfMachineKey:
+		key, err := os.ReadFile(f.keyPath)
+		if err == nil {
+			if len(key) != 32 {
+				return nil, fmt.Errorf("invalid machine key in %s", f.keyPath)
+			}
+			return key, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) || !create {
+			return nil, fmt.Errorf("failed to read machine key: %w", err)
+		}
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(f.keyPath), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create machine key directory: %w", err)
+		}
+		if err := os.WriteFile(f.keyPath, key, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write machine key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key derivation %q in secrets file", kdf)
+	}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,151 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFileStore_SetGetDelete(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, "secrets.enc"), filepath.Join(dir, "secrets.key"), "")
+
+	if _, err := store.Get("Mitto", "external-access"); err != ErrNotFound {
+		t.Fatalf("Get() on missing file error = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := store.Set("Mitto", "external-access", "s3cret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set("Mitto", "other", "value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	got, err := store.Get("Mitto", "external-access")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got != "s3cret" {
+		t.Errorf("Get() = %q, want %q", got, "s3cret")
+	}
+
+	if err := store.Delete("Mitto", "external-access"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("Mitto", "external-access"); err != ErrNotFound {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete("Mitto", "external-access"); err != ErrNotFound {
+		t.Errorf("Delete() twice error = %v, want %v", err, ErrNotFound)
+	}
+	if got, _ := store.Get("Mitto", "other"); got != "value" {
+		t.Errorf("Get() other account = %q, want %q", got, "value")
+	}
+}
+
+func TestFileStore_EncryptedAtRest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.enc")
+	keyPath := filepath.Join(dir, "secrets.key")
+	store := NewFileStore(path, keyPath, "")
+
+	if err := store.Set("Mitto", "external-access", "plaintext-password"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if bytes.Contains(data, []byte("plaintext-password")) {
+		t.Error("secrets file contains the password in plaintext")
+	}
+
+	for _, p := range []string{path, keyPath} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("Stat(%s) error = %v", p, err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("%s permissions = %o, want 600", filepath.Base(p), perm)
+		}
+	}
+
+	// A new store with the same machine key reads the credential back
+	reopened := NewFileStore(path, keyPath, "")
+	if got, err := reopened.Get("Mitto", "external-access"); err != nil || got != "plaintext-password" {
+		t.Errorf("Get() after reopen = %q, %v", got, err)
+	}
+
+	// Losing the machine key makes the file unreadable
+	if err := os.WriteFile(keyPath, bytes.Repeat([]byte{1}, 32), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get("Mitto", "external-access"); err != ErrWrongKey {
+		t.Errorf("Get() with another machine key error = %v, want %v", err, ErrWrongKey)
+	}
+}
+
+func TestFileStore_Passphrase(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.enc")
+	keyPath := filepath.Join(dir, "secrets.key")
+
+	store := NewFileStore(path, keyPath, "correct horse")
+	if err := store.Set("Mitto", "external-access", "s3cret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := os.Stat(keyPath); !os.IsNotExist(err) {
+		t.Errorf("machine key created with a passphrase: %v", err)
+	}
+	if got, err := NewFileStore(path, keyPath, "correct horse").Get("Mitto", "external-access"); err != nil || got != "s3cret" {
+		t.Errorf("Get() = %q, %v", got, err)
+	}
+
+	if _, err := NewFileStore(path, keyPath, "wrong").Get("Mitto", "external-access"); err != ErrWrongKey {
+		t.Errorf("Get() with wrong passphrase error = %v, want %v", err, ErrWrongKey)
+	}
+
+	_, err := NewFileStore(path, keyPath, "").Get("Mitto", "external-access")
+	if err == nil || !strings.Contains(err.Error(), PassphraseEnv) {
+		t.Errorf("Get() without passphrase error = %v, want a hint about %s", err, PassphraseEnv)
+	}
+}
+
+func TestFileStore_ConcurrentProcesses(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.enc")
+	keyPath := filepath.Join(dir, "secrets.key")
+
+	// Stores sharing the file, as separate processes do, don't lose updates
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store := NewFileStore(path, keyPath, "")
+			if err := store.Set("Mitto", fmt.Sprintf("account-%d", i), "s3cret"); err != nil {
+				t.Errorf("Set() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	store := NewFileStore(path, keyPath, "")
+	for i := range 8 {
+		if got, err := store.Get("Mitto", fmt.Sprintf("account-%d", i)); err != nil || got != "s3cret" {
+			t.Errorf("Get(account-%d) = %q, %v", i, got, err)
+		}
+	}
+}
+
+func TestFileStore_IsSupported(t *testing.T) {
+	store := NewFileStore("secrets.enc", "secrets.key", "")
+	if !store.IsSupported() {
+		t.Error("FileStore.IsSupported() = false, want true")
+	}
+}
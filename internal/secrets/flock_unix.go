@@ -0,0 +1,36 @@
+//go:build !windows
+
+package secrets
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// flock takes an advisory lock on path, creating the file if needed, and
+// returns the function that releases it.
+func flock(path string, exclusive bool) (func(), error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err = syscall.Flock(int(file.Fd()), how)
+		if !errors.Is(err, syscall.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package secrets
+
+// flock does nothing on Windows, where the encrypted file isn't used.
+func flock(path string, exclusive bool) (func(), error) {
+	return func() {}, nil
+}
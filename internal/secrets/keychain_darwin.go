@@ -91,6 +91,11 @@ func (k *KeychainStore) Delete(service, account string) error {
 	return err
 }
 
+// Name returns the name of the backend.
+func (k *KeychainStore) Name() string {
+	return "Keychain"
+}
+
 // IsSupported returns true for KeychainStore on macOS.
 func (k *KeychainStore) IsSupported() bool {
 	return true
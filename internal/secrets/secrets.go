@@ -1,5 +1,7 @@
 // Package secrets provides a platform-abstracted interface for secure credential storage.
 // On macOS, credentials are stored in the system Keychain.
+// On Linux, they are stored in the freedesktop Secret Service when one is running
+// on the session bus, or in an encrypted file otherwise (see BackendEnv).
 // On other platforms, a no-op fallback is used (credentials remain in settings.json).
 package secrets
 
@@ -43,6 +45,20 @@ type SecretStore interface {
 // It is set by the platform-specific init() function.
 var store SecretStore
 
+// namedStore is implemented by the stores that can describe their backend.
+type namedStore interface {
+	Name() string
+}
+
+// Backend returns a human-readable name of the backend of the default store,
+// such as "Keychain" or "Secret Service".
+func Backend() string {
+	if n, ok := Default().(namedStore); ok {
+		return n.Name()
+	}
+	return "none"
+}
+
 // Default returns the default SecretStore for the current platform.
 // This function always returns a valid store; on unsupported platforms,
 // it returns a NoopStore that returns ErrNotSupported for all operations.
@@ -0,0 +1,237 @@
+//go:build linux
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// D-Bus names of the freedesktop Secret Service API.
+// See https://specifications.freedesktop.org/secret-service-spec/latest/
+const (
+	secretServiceDest        = "org.freedesktop.secrets"
+	secretServicePath        = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretServiceIface       = "org.freedesktop.Secret.Service"
+	secretCollectionIface    = "org.freedesktop.Secret.Collection"
+	secretItemIface          = "org.freedesktop.Secret.Item"
+	secretSessionIface       = "org.freedesktop.Secret.Session"
+	secretDefaultCollection  = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	secretItemLabelProp      = "org.freedesktop.Secret.Item.Label"
+	secretItemAttributesProp = "org.freedesktop.Secret.Item.Attributes"
+
+	// noPrompt is the object path returned when an operation needs no prompt.
+	noPrompt = dbus.ObjectPath("/")
+)
+
+// secretServiceTimeout bounds every call to the Secret Service, so a stuck
+// keyring daemon can't block the server.
+const secretServiceTimeout = 5 * time.Second
+
+// ErrLocked is returned when the keyring is locked and unlocking it needs
+// the user to answer a prompt.
+var ErrLocked = errors.New("the keyring is locked: unlock it in the desktop session")
+
+// secretValue is the Secret struct of the Secret Service API (signature (oayays)).
+type secretValue struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// SecretServiceStore implements SecretStore using the freedesktop Secret
+// Service D-Bus API, provided by GNOME Keyring, KWallet and KeePassXC among others.
+// Credentials are stored in the default collection, and identified by
+// their "service" and "account" attributes.
+type SecretServiceStore struct {
+	conn *dbus.Conn
+}
+
+// NewSecretServiceStore creates a SecretServiceStore that talks to the
+// Secret Service over conn.
+func NewSecretServiceStore(conn *dbus.Conn) *SecretServiceStore {
+	return &SecretServiceStore{conn: conn}
+}
+
+// ConnectSecretService connects to the session bus and checks that a
+// Secret Service is running (or can be activated) on it.
+// It never launches a session bus, as headless machines don't have one.
+func ConnectSecretService() (*SecretServiceStore, error) {
+	conn, err := dbus.SessionBusPrivateNoAutoStartup()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to authenticate on the session bus: %w", err)
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+	s := NewSecretServiceStore(conn)
+	session, err := s.openSession()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	s.closeSession(session)
+	return s, nil
+}
+
+// Get retrieves a password from the Secret Service.
+func (s *SecretServiceStore) Get(service, account string) (string, error) {
+	item, err := s.findItem(service, account)
+	if err != nil {
+		return "", err
+	}
+
+	session, err := s.openSession()
+	if err != nil {
+		return "", err
+	}
+	defer s.closeSession(session)
+
+	var secret secretValue
+	if err := s.call(item, secretItemIface+".GetSecret", session).Store(&secret); err != nil {
+		return "", fmt.Errorf("failed to get secret: %w", err)
+	}
+	return string(secret.Value), nil
+}
+
+// Set stores a password in the default collection of the Secret Service.
+// If the credential already exists, it is replaced.
+func (s *SecretServiceStore) Set(service, account, password string) error {
+	if err := s.unlock(secretDefaultCollection); err != nil {
+		return err
+	}
+
+	session, err := s.openSession()
+	if err != nil {
+		return err
+	}
+	defer s.closeSession(session)
+
+	properties := map[string]dbus.Variant{
+		secretItemLabelProp:      dbus.MakeVariant(service + " - " + account),
+		secretItemAttributesProp: dbus.MakeVariant(itemAttributes(service, account)),
+	}
+	secret := secretValue{
+		Session:     session,
+		Value:       []byte(password),
+		ContentType: "text/plain; charset=utf8",
+	}
+
+	var item, prompt dbus.ObjectPath
+	err = s.call(secretDefaultCollection, secretCollectionIface+".CreateItem", properties, secret, true).
+		Store(&item, &prompt)
+	if err != nil {
+		return fmt.Errorf("failed to create secret: %w", err)
+	}
+	if prompt != noPrompt {
+		return ErrLocked
+	}
+	return nil
+}
+
+// Delete removes a credential from the Secret Service.
+func (s *SecretServiceStore) Delete(service, account string) error {
+	item, err := s.findItem(service, account)
+	if err != nil {
+		return err
+	}
+
+	var prompt dbus.ObjectPath
+	if err := s.call(item, secretItemIface+".Delete").Store(&prompt); err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	if prompt != noPrompt {
+		return ErrLocked
+	}
+	return nil
+}
+
+// IsSupported returns true for SecretServiceStore; use ConnectSecretService
+// to check that a Secret Service is available.
+func (s *SecretServiceStore) IsSupported() bool {
+	return true
+}
+
+// Name returns the name of the backend.
+func (s *SecretServiceStore) Name() string {
+	return "Secret Service"
+}
+
+// Close closes the connection to the session bus.
+func (s *SecretServiceStore) Close() error {
+	return s.conn.Close()
+}
+
+// findItem returns the unlocked item with the credential for service and account.
+func (s *SecretServiceStore) findItem(service, account string) (dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	err := s.call(secretServicePath, secretServiceIface+".SearchItems", itemAttributes(service, account)).
+		Store(&unlocked, &locked)
+	if err != nil {
+		return "", fmt.Errorf("failed to search secrets: %w", err)
+	}
+	if len(unlocked) > 0 {
+		return unlocked[0], nil
+	}
+	if len(locked) == 0 {
+		return "", ErrNotFound
+	}
+	if err := s.unlock(locked[0]); err != nil {
+		return "", err
+	}
+	return locked[0], nil
+}
+
+// unlock unlocks an item or collection, failing with ErrLocked when that
+// needs a prompt.
+func (s *SecretServiceStore) unlock(object dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	err := s.call(secretServicePath, secretServiceIface+".Unlock", []dbus.ObjectPath{object}).
+		Store(&unlocked, &prompt)
+	if err != nil {
+		return fmt.Errorf("failed to unlock keyring: %w", err)
+	}
+	if prompt != noPrompt {
+		return ErrLocked
+	}
+	return nil
+}
+
+// openSession opens a session to transfer secrets. Secrets travel
+// unencrypted ("plain"), as the session bus is private to the user.
+func (s *SecretServiceStore) openSession() (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err := s.call(secretServicePath, secretServiceIface+".OpenSession", "plain", dbus.MakeVariant("")).
+		Store(&output, &session)
+	if err != nil {
+		return "", fmt.Errorf("failed to open Secret Service session: %w", err)
+	}
+	return session, nil
+}
+
+func (s *SecretServiceStore) closeSession(session dbus.ObjectPath) {
+	_ = s.call(session, secretSessionIface+".Close").Err
+}
+
+func (s *SecretServiceStore) call(path dbus.ObjectPath, method string, args ...any) *dbus.Call {
+	ctx, cancel := context.WithTimeout(context.Background(), secretServiceTimeout)
+	defer cancel()
+	return s.conn.Object(secretServiceDest, path).CallWithContext(ctx, method, 0, args...)
+}
+
+// itemAttributes returns the lookup attributes of the item of a credential.
+func itemAttributes(service, account string) map[string]string {
+	return map[string]string{"service": service, "account": account}
+}
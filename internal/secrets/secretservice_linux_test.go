@@ -0,0 +1,250 @@
+//go:build linux
+
+package secrets
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeSecretService is a minimal in-memory Secret Service, exported on a
+// private session bus to stand in for GNOME Keyring in tests.
+type fakeSecretService struct {
+	conn *dbus.Conn
+
+	mu     sync.Mutex
+	locked bool
+	nextID int
+	items  map[dbus.ObjectPath]*fakeItem
+}
+
+type fakeItem struct {
+	svc        *fakeSecretService
+	path       dbus.ObjectPath
+	attributes map[string]string
+	secret     []byte
+}
+
+func (f *fakeSecretService) OpenSession(algorithm string, input dbus.Variant) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
+	if algorithm != "plain" {
+		return dbus.Variant{}, "", dbus.MakeFailedError(fmt.Errorf("unsupported algorithm %s", algorithm))
+	}
+	return dbus.MakeVariant(""), "/org/freedesktop/secrets/session/1", nil
+}
+
+func (f *fakeSecretService) SearchItems(attributes map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	matches := []dbus.ObjectPath{}
+	for path, item := range f.items {
+		if item.matches(attributes) {
+			matches = append(matches, path)
+		}
+	}
+	if f.locked {
+		return []dbus.ObjectPath{}, matches, nil
+	}
+	return matches, []dbus.ObjectPath{}, nil
+}
+
+func (f *fakeSecretService) Unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.locked {
+		return []dbus.ObjectPath{}, "/org/freedesktop/secrets/prompt/1", nil
+	}
+	return objects, noPrompt, nil
+}
+
+func (f *fakeSecretService) Close() *dbus.Error {
+	return nil
+}
+
+func (f *fakeSecretService) CreateItem(properties map[string]dbus.Variant, secret secretValue, replace bool) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	attributes, ok := properties[secretItemAttributesProp].Value().(map[string]string)
+	if !ok {
+		return "", "", dbus.MakeFailedError(fmt.Errorf("missing attributes"))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if replace {
+		for _, item := range f.items {
+			if item.matches(attributes) {
+				item.secret = secret.Value
+				return item.path, noPrompt, nil
+			}
+		}
+	}
+	f.nextID++
+	item := &fakeItem{
+		svc:        f,
+		path:       dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/secrets/collection/login/%d", f.nextID)),
+		attributes: attributes,
+		secret:     secret.Value,
+	}
+	f.items[item.path] = item
+	if err := f.conn.Export(item, item.path, secretItemIface); err != nil {
+		return "", "", dbus.MakeFailedError(err)
+	}
+	return item.path, noPrompt, nil
+}
+
+func (i *fakeItem) GetSecret(session dbus.ObjectPath) (secretValue, *dbus.Error) {
+	i.svc.mu.Lock()
+	defer i.svc.mu.Unlock()
+	return secretValue{Session: session, Value: i.secret, ContentType: "text/plain"}, nil
+}
+
+func (i *fakeItem) Delete() (dbus.ObjectPath, *dbus.Error) {
+	i.svc.mu.Lock()
+	defer i.svc.mu.Unlock()
+	delete(i.svc.items, i.path)
+	_ = i.svc.conn.Export(nil, i.path, secretItemIface)
+	return noPrompt, nil
+}
+
+func (i *fakeItem) matches(attributes map[string]string) bool {
+	for k, v := range attributes {
+		if i.attributes[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// startSessionBus starts a private dbus-daemon and returns its address.
+func startSessionBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	cmd := exec.Command(daemon, "--session", "--nofork", "--print-address=1",
+		"--address=unix:dir="+t.TempDir())
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("failed to start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read the bus address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// newTestSecretService starts a session bus with a fake Secret Service, and
+// returns the fake and a SecretServiceStore connected to it.
+func newTestSecretService(t *testing.T) (*fakeSecretService, *SecretServiceStore) {
+	t.Helper()
+	address := startSessionBus(t)
+
+	serviceConn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("failed to connect to the bus: %v", err)
+	}
+	t.Cleanup(func() { serviceConn.Close() })
+
+	fake := &fakeSecretService{conn: serviceConn, items: make(map[dbus.ObjectPath]*fakeItem)}
+	exports := []struct {
+		path  dbus.ObjectPath
+		iface string
+	}{
+		{secretServicePath, secretServiceIface},
+		{"/org/freedesktop/secrets/session/1", secretSessionIface},
+		{secretDefaultCollection, secretCollectionIface},
+	}
+	for _, e := range exports {
+		if err := serviceConn.Export(fake, e.path, e.iface); err != nil {
+			t.Fatalf("failed to export %s: %v", e.path, err)
+		}
+	}
+	reply, err := serviceConn.RequestName(secretServiceDest, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("failed to own %s: %v", secretServiceDest, err)
+	}
+
+	clientConn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("failed to connect to the bus: %v", err)
+	}
+	s := NewSecretServiceStore(clientConn)
+	t.Cleanup(func() { s.Close() })
+	return fake, s
+}
+
+func TestSecretServiceStore_SetGetDelete(t *testing.T) {
+	_, store := newTestSecretService(t)
+
+	if _, err := store.Get("Mitto", "external-access"); err != ErrNotFound {
+		t.Fatalf("Get() before Set() error = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := store.Set("Mitto", "external-access", "s3cret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	got, err := store.Get("Mitto", "external-access")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got != "s3cret" {
+		t.Errorf("Get() = %q, want %q", got, "s3cret")
+	}
+
+	// Set replaces the existing item
+	if err := store.Set("Mitto", "external-access", "updated"); err != nil {
+		t.Fatalf("Set() update error = %v", err)
+	}
+	if got, _ := store.Get("Mitto", "external-access"); got != "updated" {
+		t.Errorf("Get() after update = %q, want %q", got, "updated")
+	}
+
+	// Other accounts are not affected
+	if _, err := store.Get("Mitto", "other"); err != ErrNotFound {
+		t.Errorf("Get() other account error = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := store.Delete("Mitto", "external-access"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("Mitto", "external-access"); err != ErrNotFound {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete("Mitto", "external-access"); err != ErrNotFound {
+		t.Errorf("Delete() twice error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestSecretServiceStore_Locked(t *testing.T) {
+	fake, store := newTestSecretService(t)
+
+	if err := store.Set("Mitto", "external-access", "s3cret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	fake.mu.Lock()
+	fake.locked = true
+	fake.mu.Unlock()
+
+	if _, err := store.Get("Mitto", "external-access"); err != ErrLocked {
+		t.Errorf("Get() on locked keyring error = %v, want %v", err, ErrLocked)
+	}
+	if err := store.Set("Mitto", "external-access", "other"); err != ErrLocked {
+		t.Errorf("Set() on locked keyring error = %v, want %v", err, ErrLocked)
+	}
+}
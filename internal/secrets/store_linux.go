@@ -0,0 +1,120 @@
+//go:build linux
+
+package secrets
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/inercia/mitto/internal/appdir"
+)
+
+// BackendEnv is the environment variable that selects the secret store on Linux:
+//   - "auto" (default): the Secret Service if available, the encrypted file otherwise
+//   - "secret-service": only the Secret Service
+//   - "file": only the encrypted file (see PassphraseEnv)
+//   - "none": no secret store; credentials remain in settings.json
+const BackendEnv = "MITTO_SECRETS_BACKEND"
+
+func init() {
+	// Initialize the package-level store with a store that picks the backend on first use,
+	// so that merely importing the package doesn't connect to the session bus.
+	store = &autoStore{}
+}
+
+// autoStore selects the backend of the SecretStore lazily, according to BackendEnv.
+type autoStore struct {
+	once          sync.Once
+	secretService *SecretServiceStore
+	useFile       bool
+
+	mu   sync.Mutex
+	file *FileStore
+}
+
+// Get retrieves a password from the selected backend.
+func (a *autoStore) Get(service, account string) (string, error) {
+	return a.backend().Get(service, account)
+}
+
+// Set stores a password in the selected backend.
+func (a *autoStore) Set(service, account, password string) error {
+	return a.backend().Set(service, account, password)
+}
+
+// Delete removes a credential from the selected backend.
+func (a *autoStore) Delete(service, account string) error {
+	return a.backend().Delete(service, account)
+}
+
+// IsSupported returns true when a backend is available.
+func (a *autoStore) IsSupported() bool {
+	return a.backend().IsSupported()
+}
+
+// Name returns the name of the selected backend.
+func (a *autoStore) Name() string {
+	if n, ok := a.backend().(namedStore); ok {
+		return n.Name()
+	}
+	return "none"
+}
+
+// backend returns the selected backend, probing the Secret Service on the first call.
+func (a *autoStore) backend() SecretStore {
+	a.once.Do(func() {
+		mode := os.Getenv(BackendEnv)
+		switch mode {
+		case "", "auto", "secret-service":
+			s, err := ConnectSecretService()
+			if err == nil {
+				a.secretService = s
+				return
+			}
+			if mode == "secret-service" {
+				slog.Warn("Secret Service not available, credentials will not be stored securely", "error", err)
+				return
+			}
+			slog.Debug("Secret Service not available, using the encrypted secrets file", "error", err)
+			a.useFile = true
+		case "file":
+			a.useFile = true
+		case "none":
+		default:
+			slog.Warn("Unknown secret store backend, credentials will not be stored securely",
+				"env", BackendEnv, "value", mode)
+		}
+	})
+
+	if a.secretService != nil {
+		return a.secretService
+	}
+	if a.useFile {
+		if f := a.fileStore(); f != nil {
+			return f
+		}
+	}
+	return &NoopStore{}
+}
+
+// fileStore returns the FileStore in the current Mitto directory. The paths are
+// resolved on every call, as the directory can change (MITTO_DIR in tests).
+func (a *autoStore) fileStore() *FileStore {
+	path, err := appdir.SecretsPath()
+	if err != nil {
+		return nil
+	}
+	keyPath, err := appdir.SecretsKeyPath()
+	if err != nil {
+		return nil
+	}
+	passphrase := os.Getenv(PassphraseEnv)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil || a.file.path != path || a.file.passphrase != passphrase {
+		a.file = NewFileStore(path, keyPath, passphrase)
+	}
+	return a.file
+}
@@ -1,8 +1,8 @@
-//go:build !darwin
+//go:build !darwin && !linux
 
 package secrets
 
 func init() {
-	// Initialize the package-level store with NoopStore on non-macOS platforms
+	// Initialize the package-level store with NoopStore on platforms without a secret store
 	store = &NoopStore{}
 }
@@ -0,0 +1,112 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// StoreBackend identifies how a Store persists session metadata and events.
+// Side data (images, files, queue, periodic config, locks, ...) always lives in
+// the per-session directory regardless of the backend.
+type StoreBackend string
+
+const (
+	// StoreBackendFile stores each session as events.jsonl + metadata.json files.
+	// This is the default backend.
+	StoreBackendFile StoreBackend = "file"
+	// StoreBackendSQLite stores metadata and events in an embedded SQLite database
+	// (sessions.db in the base directory) with indexed metadata columns.
+	StoreBackendSQLite StoreBackend = "sqlite"
+)
+
+// ValidStoreBackends contains all supported store backends.
+var ValidStoreBackends = []StoreBackend{StoreBackendFile, StoreBackendSQLite}
+
+// ParseStoreBackend converts a configuration value to a StoreBackend.
+// An empty string selects the default file backend.
+func ParseStoreBackend(value string) (StoreBackend, error) {
+	switch StoreBackend(value) {
+	case "", StoreBackendFile:
+		return StoreBackendFile, nil
+	case StoreBackendSQLite:
+		return StoreBackendSQLite, nil
+	default:
+		return "", fmt.Errorf("unknown session store backend: %q (valid: %s, %s)",
+			value, StoreBackendFile, StoreBackendSQLite)
+	}
+}
+
+// DetectStoreBackend guesses the backend used by an existing sessions directory.
+// It returns StoreBackendSQLite when a SQLite database is present and
+// StoreBackendFile otherwise. This is used by commands that run without the
+// user configuration (e.g. the standalone MCP server).
+func DetectStoreBackend(baseDir string) StoreBackend {
+	if _, err := os.Stat(filepath.Join(baseDir, sqliteFileName)); err == nil {
+		return StoreBackendSQLite
+	}
+	return StoreBackendFile
+}
+
+// storeBackend persists session metadata and event logs.
+// Implementations are not required to be safe for concurrent use:
+// the Store serializes all calls with its own lock.
+type storeBackend interface {
+	// kind returns the backend identifier.
+	kind() StoreBackend
+
+	// create initializes an empty event log and writes the metadata for a new session.
+	create(meta Metadata) error
+
+	// readMetadata returns ErrSessionNotFound if the session does not exist.
+	readMetadata(sessionID string) (Metadata, error)
+	writeMetadata(meta Metadata) error
+
+	// appendEvent appends an event to the session's log and stores the updated metadata.
+	appendEvent(meta Metadata, event Event) error
+
+	// readEventsFrom returns events with seq > afterSeq in log order,
+	// stopping after limit events (0 = unlimited).
+	readEventsFrom(sessionID string, afterSeq int64, limit int) ([]Event, error)
+
+	// readEventsLast returns the last limit events (0 = all) with seq < beforeSeq
+	// (beforeSeq <= 0 = no bound), in log order.
+	readEventsLast(sessionID string, limit int, beforeSeq int64) ([]Event, error)
+
+	// replaceEvents atomically replaces the whole event log and stores the updated metadata.
+	replaceEvents(meta Metadata, events []Event) error
+
+	// eventsSize returns the serialized size of the event log in bytes.
+	eventsSize(sessionID string) (int64, error)
+
+	// list returns metadata for all sessions, skipping unreadable entries.
+	list() ([]Metadata, error)
+
+	// listChildren returns metadata for the direct children of parentID.
+	listChildren(parentID string) ([]Metadata, error)
+
+	// count returns the number of stored sessions.
+	count() (int, error)
+
+	// exists reports whether a session's metadata is stored.
+	exists(sessionID string) bool
+
+	// remove deletes the session's metadata and events. The session directory
+	// itself is removed by the Store.
+	remove(sessionID string) error
+
+	// close releases any resources held by the backend.
+	close() error
+}
+
+// newStoreBackend creates the backend of the given kind rooted at baseDir.
+func newStoreBackend(kind StoreBackend, baseDir string) (storeBackend, error) {
+	switch kind {
+	case "", StoreBackendFile:
+		return &fileBackend{baseDir: baseDir}, nil
+	case StoreBackendSQLite:
+		return openSQLiteBackend(baseDir)
+	default:
+		return nil, fmt.Errorf("unknown session store backend: %q", kind)
+	}
+}
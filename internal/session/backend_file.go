@@ -0,0 +1,280 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/inercia/mitto/internal/fileutil"
+)
+
+// maxEventLineSize is the maximum size of a single line in events.jsonl.
+// The default bufio.Scanner limit is 64KB, which is too small for agent
+// messages with large code blocks.
+const maxEventLineSize = 10 * 1024 * 1024
+
+// fileBackend stores each session as events.jsonl + metadata.json inside the
+// session directory.
+type fileBackend struct {
+	baseDir string
+}
+
+func (b *fileBackend) kind() StoreBackend { return StoreBackendFile }
+
+func (b *fileBackend) eventsPath(sessionID string) string {
+	return filepath.Join(b.baseDir, sessionID, eventsFileName)
+}
+
+func (b *fileBackend) metadataPath(sessionID string) string {
+	return filepath.Join(b.baseDir, sessionID, metadataFileName)
+}
+
+func (b *fileBackend) create(meta Metadata) error {
+	eventsFile, err := os.Create(b.eventsPath(meta.SessionID))
+	if err != nil {
+		return fmt.Errorf("failed to create events file: %w", err)
+	}
+	eventsFile.Close()
+	return b.writeMetadata(meta)
+}
+
+// readMetadata reads metadata from disk.
+// Automatically migrates ChildOrigin for backward compatibility with old metadata files.
+func (b *fileBackend) readMetadata(sessionID string) (Metadata, error) {
+	var meta Metadata
+	if err := fileutil.ReadJSON(b.metadataPath(sessionID), &meta); err != nil {
+		if os.IsNotExist(err) {
+			return Metadata{}, ErrSessionNotFound
+		}
+		return Metadata{}, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	// Migrate old metadata that has IsAutoChild/ParentSessionID but no ChildOrigin
+	meta.MigrateChildOrigin()
+	return meta, nil
+}
+
+func (b *fileBackend) writeMetadata(meta Metadata) error {
+	if err := fileutil.WriteJSON(b.metadataPath(meta.SessionID), meta, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+func (b *fileBackend) appendEvent(meta Metadata, event Event) error {
+	f, err := os.OpenFile(b.eventsPath(meta.SessionID), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to open events file: %w", err)
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return b.writeMetadata(meta)
+}
+
+// scanEvents calls fn for each event in the session's log, in file order,
+// until fn returns false.
+func (b *fileBackend) scanEvents(sessionID string, fn func(Event) bool) error {
+	f, err := os.Open(b.eventsPath(sessionID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to open events file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventLineSize)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+		if !fn(event) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+	return nil
+}
+
+func (b *fileBackend) readEventsFrom(sessionID string, afterSeq int64, limit int) ([]Event, error) {
+	var events []Event
+	err := b.scanEvents(sessionID, func(event Event) bool {
+		// Only include events after the specified sequence number
+		if event.Seq > afterSeq {
+			events = append(events, event)
+			// Stop early if we've reached the limit (0 = unlimited for backward compat)
+			if limit > 0 && len(events) >= limit {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (b *fileBackend) readEventsLast(sessionID string, limit int, beforeSeq int64) ([]Event, error) {
+	// Read all matching events first (we need to know total count to get last N)
+	var events []Event
+	err := b.scanEvents(sessionID, func(event Event) bool {
+		// If beforeSeq is specified, only include events before it
+		if beforeSeq <= 0 || event.Seq < beforeSeq {
+			events = append(events, event)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(events) > limit {
+		return events[len(events)-limit:], nil
+	}
+	return events, nil
+}
+
+func (b *fileBackend) replaceEvents(meta Metadata, events []Event) error {
+	eventsPath := b.eventsPath(meta.SessionID)
+	tmpPath := eventsPath + ".tmp"
+	tmpFile, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create temp events file: %w", err)
+	}
+
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			tmpFile.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		if _, err := tmpFile.Write(append(data, '\n')); err != nil {
+			tmpFile.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to write event: %w", err)
+		}
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync temp events file: %w", err)
+	}
+	tmpFile.Close()
+
+	// Rename temp file to replace original
+	if err := os.Rename(tmpPath, eventsPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp events file: %w", err)
+	}
+
+	return b.writeMetadata(meta)
+}
+
+func (b *fileBackend) eventsSize(sessionID string) (int64, error) {
+	info, err := os.Stat(b.eventsPath(sessionID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, ErrSessionNotFound
+		}
+		return 0, fmt.Errorf("failed to stat events file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// sessionIDs returns the names of all directories in the base directory.
+func (b *fileBackend) sessionIDs() ([]string, error) {
+	entries, err := os.ReadDir(b.baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
+
+func (b *fileBackend) list() ([]Metadata, error) {
+	ids, err := b.sessionIDs()
+	if err != nil {
+		return nil, err
+	}
+	var sessions []Metadata
+	for _, id := range ids {
+		meta, err := b.readMetadata(id)
+		if err != nil {
+			// Skip sessions with invalid metadata
+			continue
+		}
+		sessions = append(sessions, meta)
+	}
+	return sessions, nil
+}
+
+func (b *fileBackend) listChildren(parentID string) ([]Metadata, error) {
+	sessions, err := b.list()
+	if err != nil {
+		return nil, err
+	}
+	children := []Metadata{}
+	for _, meta := range sessions {
+		if meta.ParentSessionID == parentID {
+			children = append(children, meta)
+		}
+	}
+	return children, nil
+}
+
+func (b *fileBackend) count() (int, error) {
+	ids, err := b.sessionIDs()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, id := range ids {
+		// Only count directories with a metadata file (valid sessions)
+		if b.exists(id) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (b *fileBackend) exists(sessionID string) bool {
+	_, err := os.Stat(b.metadataPath(sessionID))
+	return err == nil
+}
+
+// remove deletes the events and metadata files, leaving the rest of the
+// session directory in place.
+func (b *fileBackend) remove(sessionID string) error {
+	for _, path := range []string{b.eventsPath(sessionID), b.metadataPath(sessionID)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+func (b *fileBackend) close() error {
+	return nil
+}
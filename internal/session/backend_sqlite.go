@@ -0,0 +1,330 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	// Pure-Go SQLite driver (no cgo), registered as "sqlite".
+	_ "modernc.org/sqlite"
+)
+
+// sqliteFileName is the name of the SQLite database in the sessions directory.
+const sqliteFileName = "sessions.db"
+
+// sqliteSchema creates the tables used by the SQLite backend.
+// Metadata is stored as JSON, with the columns used for filtering extracted
+// and indexed. Events are stored as their JSON line, exactly as in events.jsonl,
+// ordered by insertion (id).
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	session_id        TEXT PRIMARY KEY,
+	parent_session_id TEXT NOT NULL DEFAULT '',
+	archived          INTEGER NOT NULL DEFAULT 0,
+	updated_at        INTEGER NOT NULL DEFAULT 0,
+	metadata          TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sessions_parent_idx ON sessions(parent_session_id);
+CREATE INDEX IF NOT EXISTS sessions_archived_idx ON sessions(archived, updated_at);
+
+CREATE TABLE IF NOT EXISTS events (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL,
+	seq        INTEGER NOT NULL,
+	data       TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_session_seq_idx ON events(session_id, seq);
+`
+
+// sqliteBackend stores session metadata and events in an embedded SQLite database.
+type sqliteBackend struct {
+	db *sql.DB
+}
+
+// openSQLiteBackend opens (creating if needed) the SQLite database in baseDir.
+func openSQLiteBackend(baseDir string) (*sqliteBackend, error) {
+	path := filepath.Join(baseDir, sqliteFileName)
+	// WAL + busy timeout allow other Mitto processes (e.g. the standalone MCP
+	// server) to read and write the same database concurrently.
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %w", err)
+	}
+	// The Store serializes access; a single connection avoids SQLITE_BUSY
+	// between connections of the same process.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize session database: %w", err)
+	}
+	return &sqliteBackend{db: db}, nil
+}
+
+func (b *sqliteBackend) kind() StoreBackend { return StoreBackendSQLite }
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func (b *sqliteBackend) upsertMetadata(ex execer, meta Metadata) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	_, err = ex.Exec(`
+		INSERT INTO sessions (session_id, parent_session_id, archived, updated_at, metadata)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET
+			parent_session_id = excluded.parent_session_id,
+			archived = excluded.archived,
+			updated_at = excluded.updated_at,
+			metadata = excluded.metadata`,
+		meta.SessionID, meta.ParentSessionID, meta.Archived, meta.UpdatedAt.UnixNano(), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+func insertEvent(ex execer, sessionID string, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if _, err := ex.Exec(`INSERT INTO events (session_id, seq, data) VALUES (?, ?, ?)`,
+		sessionID, event.Seq, string(data)); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing on success.
+func (b *sqliteBackend) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (b *sqliteBackend) create(meta Metadata) error {
+	return b.inTx(func(tx *sql.Tx) error {
+		// Recreating a session starts with an empty event log, like the file backend.
+		if _, err := tx.Exec(`DELETE FROM events WHERE session_id = ?`, meta.SessionID); err != nil {
+			return fmt.Errorf("failed to reset events: %w", err)
+		}
+		return b.upsertMetadata(tx, meta)
+	})
+}
+
+func decodeMetadata(data string) (Metadata, error) {
+	var meta Metadata
+	if err := json.Unmarshal([]byte(data), &meta); err != nil {
+		return Metadata{}, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	meta.MigrateChildOrigin()
+	return meta, nil
+}
+
+func (b *sqliteBackend) readMetadata(sessionID string) (Metadata, error) {
+	var data string
+	err := b.db.QueryRow(`SELECT metadata FROM sessions WHERE session_id = ?`, sessionID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Metadata{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	return decodeMetadata(data)
+}
+
+func (b *sqliteBackend) writeMetadata(meta Metadata) error {
+	return b.upsertMetadata(b.db, meta)
+}
+
+func (b *sqliteBackend) appendEvent(meta Metadata, event Event) error {
+	return b.inTx(func(tx *sql.Tx) error {
+		if err := insertEvent(tx, meta.SessionID, event); err != nil {
+			return err
+		}
+		return b.upsertMetadata(tx, meta)
+	})
+}
+
+// queryEvents runs an events query and decodes the JSON rows.
+func (b *sqliteBackend) queryEvents(query string, args ...any) ([]Event, error) {
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read events: %w", err)
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+	return events, nil
+}
+
+func (b *sqliteBackend) readEventsFrom(sessionID string, afterSeq int64, limit int) ([]Event, error) {
+	if !b.exists(sessionID) {
+		return nil, ErrSessionNotFound
+	}
+	// A negative LIMIT means no limit in SQLite.
+	if limit <= 0 {
+		limit = -1
+	}
+	return b.queryEvents(`
+		SELECT data FROM events
+		WHERE session_id = ? AND seq > ?
+		ORDER BY id
+		LIMIT ?`, sessionID, afterSeq, limit)
+}
+
+func (b *sqliteBackend) readEventsLast(sessionID string, limit int, beforeSeq int64) ([]Event, error) {
+	if !b.exists(sessionID) {
+		return nil, ErrSessionNotFound
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	events, err := b.queryEvents(`
+		SELECT data FROM events
+		WHERE session_id = ? AND (? <= 0 OR seq < ?)
+		ORDER BY id DESC
+		LIMIT ?`, sessionID, beforeSeq, beforeSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	// Restore chronological order
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, nil
+}
+
+func (b *sqliteBackend) replaceEvents(meta Metadata, events []Event) error {
+	return b.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM events WHERE session_id = ?`, meta.SessionID); err != nil {
+			return fmt.Errorf("failed to delete events: %w", err)
+		}
+		for _, event := range events {
+			if err := insertEvent(tx, meta.SessionID, event); err != nil {
+				return err
+			}
+		}
+		return b.upsertMetadata(tx, meta)
+	})
+}
+
+// eventsSize returns the size the event log would have as events.jsonl
+// (one JSON line per event), so that size-based pruning behaves the same
+// with both backends.
+func (b *sqliteBackend) eventsSize(sessionID string) (int64, error) {
+	if !b.exists(sessionID) {
+		return 0, ErrSessionNotFound
+	}
+	var size int64
+	err := b.db.QueryRow(`SELECT COALESCE(SUM(LENGTH(CAST(data AS BLOB)) + 1), 0) FROM events WHERE session_id = ?`,
+		sessionID).Scan(&size)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute events size: %w", err)
+	}
+	return size, nil
+}
+
+// queryMetadata runs a sessions query and decodes the metadata rows,
+// skipping rows with invalid metadata.
+func (b *sqliteBackend) queryMetadata(query string, args ...any) ([]Metadata, error) {
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Metadata
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to query sessions: %w", err)
+		}
+		meta, err := decodeMetadata(data)
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, meta)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func (b *sqliteBackend) list() ([]Metadata, error) {
+	return b.queryMetadata(`SELECT metadata FROM sessions ORDER BY session_id`)
+}
+
+func (b *sqliteBackend) listChildren(parentID string) ([]Metadata, error) {
+	children, err := b.queryMetadata(
+		`SELECT metadata FROM sessions WHERE parent_session_id = ? ORDER BY session_id`, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if children == nil {
+		children = []Metadata{}
+	}
+	return children, nil
+}
+
+func (b *sqliteBackend) count() (int, error) {
+	var count int
+	if err := b.db.QueryRow(`SELECT COUNT(*) FROM sessions`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	return count, nil
+}
+
+func (b *sqliteBackend) exists(sessionID string) bool {
+	var one int
+	err := b.db.QueryRow(`SELECT 1 FROM sessions WHERE session_id = ?`, sessionID).Scan(&one)
+	return err == nil
+}
+
+func (b *sqliteBackend) remove(sessionID string) error {
+	return b.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM events WHERE session_id = ?`, sessionID); err != nil {
+			return fmt.Errorf("failed to delete events: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM sessions WHERE session_id = ?`, sessionID); err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+		return nil
+	})
+}
+
+func (b *sqliteBackend) close() error {
+	return b.db.Close()
+}
@@ -18,6 +18,8 @@ const (
 	callbackFileName    = "callback.json"
 	callbackTokenPrefix = "cb_"
 	callbackTokenBytes  = 32 // 256-bit entropy
+	// callbackFileMode keeps callback.json private: it holds the token and the secret.
+	callbackFileMode = 0600
 )
 
 var (
@@ -30,6 +32,25 @@ type CallbackConfig struct {
 	Token     string    `json:"token"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Template renders the request payload into the prompt (Go text/template).
+	// When empty, the periodic prompt is sent unchanged.
+	Template string `json:"template,omitempty"`
+	// Secret, when set, requires requests to be signed (GitHub X-Hub-Signature-256)
+	// or to carry it as a token (GitLab X-Gitlab-Token).
+	Secret string `json:"secret,omitempty"`
+	// Filter is a JSONPath expression evaluated against the payload, optionally
+	// with operators (e.g. `$.ref == "refs/heads/main"`): the request only
+	// triggers the prompt when it evaluates to a non-empty, non-false value.
+	Filter string `json:"filter,omitempty"`
+}
+
+// CallbackOptions holds changes to the payload options of a callback.
+// Nil fields are left unchanged and empty strings clear the option.
+type CallbackOptions struct {
+	Template *string `json:"template,omitempty"`
+	Secret   *string `json:"secret,omitempty"`
+	Filter   *string `json:"filter,omitempty"`
 }
 
 // CallbackStore manages the callback token for a single session.
@@ -91,8 +112,8 @@ func (cs *CallbackStore) GenerateToken() (string, error) {
 
 	var config CallbackConfig
 	if err == nil && existing != nil {
-		// Update: preserve created_at
-		config.CreatedAt = existing.CreatedAt
+		// Update: preserve created_at and the payload options
+		config = *existing
 	} else {
 		// Create: set created_at
 		config.CreatedAt = now
@@ -101,13 +122,44 @@ func (cs *CallbackStore) GenerateToken() (string, error) {
 	config.Token = token
 	config.UpdatedAt = now
 
-	if err := fileutil.WriteJSONAtomic(cs.callbackPath(), &config, 0644); err != nil {
+	if err := fileutil.WriteJSONAtomic(cs.callbackPath(), &config, callbackFileMode); err != nil {
 		return "", fmt.Errorf("failed to write callback file: %w", err)
 	}
 
 	return token, nil
 }
 
+// Configure updates the payload options of the callback.
+// Returns ErrCallbackNotFound if no callback exists, or an ErrInvalidCallback
+// error if the template or the filter are invalid.
+func (cs *CallbackStore) Configure(opts CallbackOptions) (*CallbackConfig, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	config, err := cs.getUnlocked()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Template != nil {
+		config.Template = *opts.Template
+	}
+	if opts.Secret != nil {
+		config.Secret = *opts.Secret
+	}
+	if opts.Filter != nil {
+		config.Filter = *opts.Filter
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config.UpdatedAt = time.Now().UTC()
+
+	if err := fileutil.WriteJSONAtomic(cs.callbackPath(), config, callbackFileMode); err != nil {
+		return nil, fmt.Errorf("failed to write callback file: %w", err)
+	}
+	return config, nil
+}
+
 // Revoke deletes the callback configuration.
 // Returns ErrCallbackNotFound if no callback exists.
 func (cs *CallbackStore) Revoke() error {
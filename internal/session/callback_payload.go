@@ -0,0 +1,167 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/PaesslerAG/jsonpath"
+)
+
+var (
+	// ErrInvalidCallback is returned when the template or filter of a callback is invalid.
+	ErrInvalidCallback = errors.New("invalid callback")
+
+	// ErrCallbackSignature is returned when a callback request is not signed with the callback secret.
+	ErrCallbackSignature = errors.New("invalid callback signature")
+
+	// ErrCallbackTemplate is returned when the prompt template fails to render a payload.
+	ErrCallbackTemplate = errors.New("callback template failed")
+)
+
+// callbackSignaturePrefix is the prefix of GitHub-style HMAC signatures.
+const callbackSignaturePrefix = "sha256="
+
+// callbackFilterLanguage evaluates callback filters: JSONPath expressions
+// ($.ref, $.commits[?(@.added)]) combined with comparison and logical operators.
+var callbackFilterLanguage = gval.NewLanguage(gval.Full(), jsonpath.Language())
+
+// callbackTemplateFuncs are the extra functions available in callback templates.
+var callbackTemplateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// CallbackPayload is the decoded body of a callback request.
+type CallbackPayload struct {
+	// Event is the event name sent by the webhook provider (e.g. "push"), if any.
+	Event string
+	// Body is the decoded JSON body (nil when the request has no body).
+	Body any
+}
+
+// HasPayloadOptions reports whether the callback uses the request payload
+// (a template or a filter).
+func (c *CallbackConfig) HasPayloadOptions() bool {
+	return c.Template != "" || c.Filter != ""
+}
+
+// Validate checks that the template and the filter can be parsed.
+func (c *CallbackConfig) Validate() error {
+	if c.Template != "" {
+		if _, err := c.parseTemplate(); err != nil {
+			return fmt.Errorf("%w: template: %v", ErrInvalidCallback, err)
+		}
+	}
+	if c.Filter != "" {
+		if _, err := callbackFilterLanguage.NewEvaluable(c.Filter); err != nil {
+			return fmt.Errorf("%w: filter: %v", ErrInvalidCallback, err)
+		}
+	}
+	return nil
+}
+
+// parseTemplate parses the prompt template. Reading a missing key fails
+// instead of rendering "<no value>" into the prompt.
+func (c *CallbackConfig) parseTemplate() (*template.Template, error) {
+	return template.New("callback").Option("missingkey=error").Funcs(callbackTemplateFuncs).Parse(c.Template)
+}
+
+// VerifySignature checks a request body against the callback secret.
+// signature is a GitHub-style "sha256=<hex HMAC-SHA256 of the body>" header
+// value and token a GitLab-style shared secret; one of them must match.
+// Callbacks without a secret accept any request.
+func (c *CallbackConfig) VerifySignature(body []byte, signature, token string) error {
+	if c.Secret == "" {
+		return nil
+	}
+	if signature != "" {
+		got, err := hex.DecodeString(strings.TrimPrefix(signature, callbackSignaturePrefix))
+		if err != nil {
+			return ErrCallbackSignature
+		}
+		mac := hmac.New(sha256.New, []byte(c.Secret))
+		mac.Write(body)
+		if !hmac.Equal(got, mac.Sum(nil)) {
+			return ErrCallbackSignature
+		}
+		return nil
+	}
+	if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.Secret)) == 1 {
+		return nil
+	}
+	return ErrCallbackSignature
+}
+
+// Matches reports whether a payload passes the callback filter.
+// The filter matches when it evaluates to a non-empty value other than false
+// or zero. A filter that fails to evaluate (e.g. a missing key) does not match,
+// and the error is returned for logging.
+func (c *CallbackConfig) Matches(payload CallbackPayload) (bool, error) {
+	if c.Filter == "" {
+		return true, nil
+	}
+	value, err := callbackFilterLanguage.Evaluate(c.Filter, payload.Body)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// RenderPrompt renders the prompt template for a payload. The template can
+// use .payload (the JSON body), .event (the event name) and .prompt (the
+// periodic prompt of the conversation). Without a template, prompt is returned.
+func (c *CallbackConfig) RenderPrompt(prompt string, payload CallbackPayload) (string, error) {
+	if c.Template == "" {
+		return prompt, nil
+	}
+	tmpl, err := c.parseTemplate()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrCallbackTemplate, err)
+	}
+	var sb strings.Builder
+	data := map[string]any{
+		"payload": payload.Body,
+		"event":   payload.Event,
+		"prompt":  prompt,
+	}
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrCallbackTemplate, err)
+	}
+	rendered := strings.TrimSpace(sb.String())
+	if rendered == "" {
+		return "", fmt.Errorf("%w: rendered prompt is empty", ErrCallbackTemplate)
+	}
+	return rendered, nil
+}
+
+// truthy reports whether a filter result selects the event.
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	case int:
+		return v != 0
+	case []any:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,192 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// githubPush is a trimmed-down GitHub push event.
+const githubPush = `{
+	"ref": "refs/heads/main",
+	"repository": {"full_name": "inercia/mitto"},
+	"head_commit": {"message": "Fix the build"},
+	"commits": [{"id": "a1", "added": []}, {"id": "b2", "added": ["README.md"]}]
+}`
+
+func decodePayload(t *testing.T, event, body string) CallbackPayload {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	return CallbackPayload{Event: event, Body: v}
+}
+
+func TestCallbackConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  CallbackConfig
+		wantErr bool
+	}{
+		{"empty", CallbackConfig{}, false},
+		{"template", CallbackConfig{Template: "Push to {{.payload.repository.full_name}}"}, false},
+		{"bad template", CallbackConfig{Template: "{{.payload"}, true},
+		{"filter", CallbackConfig{Filter: `$.ref == "refs/heads/main"`}, false},
+		{"bad filter", CallbackConfig{Filter: "$.ref =="}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidCallback) {
+				t.Errorf("Validate() = %v, want ErrInvalidCallback", err)
+			}
+		})
+	}
+}
+
+func TestCallbackConfig_VerifySignature(t *testing.T) {
+	body := []byte(githubPush)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	valid := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	cb := &CallbackConfig{Secret: "s3cret"}
+	tests := []struct {
+		name      string
+		signature string
+		token     string
+		wantErr   bool
+	}{
+		{"github signature", valid, "", false},
+		{"gitlab token", "", "s3cret", false},
+		{"wrong signature", "sha256=" + strings.Repeat("0", 64), "", true},
+		{"malformed signature", "sha256=xyz", "", true},
+		{"wrong token", "", "guess", true},
+		{"missing", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := cb.VerifySignature(body, tt.signature, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifySignature() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := (&CallbackConfig{}).VerifySignature(body, "", ""); err != nil {
+		t.Errorf("VerifySignature() without secret = %v, want nil", err)
+	}
+}
+
+func TestCallbackConfig_Matches(t *testing.T) {
+	payload := decodePayload(t, "push", githubPush)
+	tests := []struct {
+		filter  string
+		want    bool
+		wantErr bool
+	}{
+		{"", true, false},
+		{`$.ref == "refs/heads/main"`, true, false},
+		{`$.ref == "refs/heads/dev"`, false, false},
+		{`$.repository.full_name`, true, false},
+		{`$.commits[?(@.id == "b2")]`, true, false},
+		{`$.commits[?(@.id == "zz")]`, false, false},
+		{`$.pull_request`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, err := (&CallbackConfig{Filter: tt.filter}).Matches(payload)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("Matches() = %v, %v, want %v (error: %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCallbackConfig_RenderPrompt(t *testing.T) {
+	payload := decodePayload(t, "push", githubPush)
+
+	cb := &CallbackConfig{Template: `{{.prompt}}
+New {{.event}} to {{.payload.repository.full_name}}: {{.payload.head_commit.message}} ({{len .payload.commits}} commits)`}
+	got, err := cb.RenderPrompt("Review the changes.", payload)
+	if err != nil {
+		t.Fatalf("RenderPrompt() error = %v", err)
+	}
+	want := "Review the changes.\nNew push to inercia/mitto: Fix the build (2 commits)"
+	if got != want {
+		t.Errorf("RenderPrompt() = %q, want %q", got, want)
+	}
+
+	got, err = (&CallbackConfig{Template: `{{json .payload.repository}}`}).RenderPrompt("", payload)
+	if err != nil || got != `{"full_name":"inercia/mitto"}` {
+		t.Errorf("RenderPrompt() with json = %q, %v", got, err)
+	}
+
+	if got, _ := (&CallbackConfig{}).RenderPrompt("unchanged", payload); got != "unchanged" {
+		t.Errorf("RenderPrompt() without template = %q", got)
+	}
+
+	if _, err := (&CallbackConfig{Template: `{{if false}}x{{end}}`}).RenderPrompt("p", payload); !errors.Is(err, ErrCallbackTemplate) {
+		t.Errorf("RenderPrompt() with empty output = %v, want ErrCallbackTemplate", err)
+	}
+
+	if _, err := (&CallbackConfig{Template: `Pushed by {{.payload.pusher}}`}).RenderPrompt("p", payload); !errors.Is(err, ErrCallbackTemplate) {
+		t.Errorf("RenderPrompt() with a missing key = %v, want ErrCallbackTemplate", err)
+	}
+	got, err = (&CallbackConfig{Template: `Push{{with index .payload "pusher"}} by {{.}}{{end}}`}).RenderPrompt("p", payload)
+	if err != nil || got != "Push" {
+		t.Errorf("RenderPrompt() with an optional key = %q, %v", got, err)
+	}
+}
+
+func TestCallbackStore_Configure(t *testing.T) {
+	cs := NewCallbackStore(t.TempDir())
+
+	template := "{{.payload.ref}}"
+	if _, err := cs.Configure(CallbackOptions{Template: &template}); err != ErrCallbackNotFound {
+		t.Fatalf("Configure() without callback = %v, want ErrCallbackNotFound", err)
+	}
+
+	if _, err := cs.GenerateToken(); err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	secret, filter := "s3cret", "$.ref"
+	if _, err := cs.Configure(CallbackOptions{Template: &template, Secret: &secret, Filter: &filter}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	bad := "$.ref =="
+	if _, err := cs.Configure(CallbackOptions{Filter: &bad}); !errors.Is(err, ErrInvalidCallback) {
+		t.Fatalf("Configure() with invalid filter = %v, want ErrInvalidCallback", err)
+	}
+
+	// Rotating the token keeps the payload options
+	if _, err := cs.GenerateToken(); err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	cb, err := cs.Get()
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if cb.Template != template || cb.Secret != secret || cb.Filter != filter {
+		t.Errorf("options after rotation = %+v", cb)
+	}
+
+	// Empty strings clear options, nil leaves them unchanged
+	empty := ""
+	cb, err = cs.Configure(CallbackOptions{Secret: &empty})
+	if err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if cb.Secret != "" || cb.Template != template {
+		t.Errorf("options after clearing the secret = %+v", cb)
+	}
+}
@@ -17,9 +17,15 @@ func DefaultSessionDir() (string, error) {
 
 // DefaultStore creates a new store using the default session directory.
 func DefaultStore() (*Store, error) {
+	return DefaultStoreWithBackend(StoreBackendFile)
+}
+
+// DefaultStoreWithBackend creates a new store using the default session directory
+// and the given metadata/events backend.
+func DefaultStoreWithBackend(backend StoreBackend) (*Store, error) {
 	dir, err := DefaultSessionDir()
 	if err != nil {
 		return nil, err
 	}
-	return NewStore(dir)
+	return NewStoreWithBackend(dir, backend)
 }
@@ -0,0 +1,628 @@
+package session
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/inercia/mitto/internal/conversion"
+)
+
+// ExportFormat is the document format of a conversation export.
+type ExportFormat string
+
+const (
+	// ExportFormatMarkdown exports the conversation as a Markdown document.
+	ExportFormatMarkdown ExportFormat = "md"
+	// ExportFormatHTML exports the conversation as a standalone, sanitized HTML page.
+	ExportFormatHTML ExportFormat = "html"
+	// ExportFormatJSON exports the metadata and raw events as a JSON bundle.
+	ExportFormatJSON ExportFormat = "json"
+)
+
+// ExportBundleVersion is the version of the JSON export bundle layout.
+const ExportBundleVersion = 1
+
+// ParseExportFormat parses an export format name ("md", "markdown", "html" or "json").
+func ParseExportFormat(value string) (ExportFormat, error) {
+	switch strings.ToLower(value) {
+	case "md", "markdown":
+		return ExportFormatMarkdown, nil
+	case "html":
+		return ExportFormatHTML, nil
+	case "json":
+		return ExportFormatJSON, nil
+	}
+	return "", fmt.Errorf("invalid export format %q: must be md, html or json", value)
+}
+
+// ContentType returns the MIME type of documents in this format.
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatHTML:
+		return "text/html; charset=utf-8"
+	case ExportFormatJSON:
+		return "application/json"
+	}
+	return "text/markdown; charset=utf-8"
+}
+
+// ExportOptions configures a conversation export.
+type ExportOptions struct {
+	Format ExportFormat
+	// Zip writes a zip archive with the document and the session's images and files
+	// (under images/ and files/). Without it, images are inlined in HTML exports,
+	// images and files are embedded (base64) in JSON exports, and only referenced
+	// by name in Markdown exports.
+	Zip bool
+}
+
+// ExportFileName returns a suggested file name for an export of the given session.
+func ExportFileName(meta Metadata, opts ExportOptions) string {
+	base := exportSlug(meta.Name)
+	if base == "" {
+		base = "conversation"
+	}
+	if id := meta.SessionID; id != "" {
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		base += "-" + id
+	}
+	if opts.Zip {
+		return base + ".zip"
+	}
+	return base + "." + string(opts.Format)
+}
+
+// ExportBundle is the document written by JSON exports.
+type ExportBundle struct {
+	Version     int                `json:"version"`
+	ExportedAt  time.Time          `json:"exported_at"`
+	Metadata    Metadata           `json:"metadata"`
+	Events      []Event            `json:"events"`
+	Attachments []ExportAttachment `json:"attachments,omitempty"`
+	// Queue holds the messages waiting to be sent.
+	Queue []QueuedMessage `json:"queue,omitempty"`
+	// Periodic is the periodic prompt configuration, if any.
+	Periodic *PeriodicPrompt `json:"periodic,omitempty"`
+}
+
+// ExportAttachment is an image or file of an exported session.
+type ExportAttachment struct {
+	// Kind is "image" or "file".
+	Kind     string `json:"kind"`
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mime_type"`
+	// Path is the location of the attachment in a zip export.
+	Path string `json:"path,omitempty"`
+	// Data is the attachment content, only set in non-zip exports.
+	Data []byte `json:"data,omitempty"`
+
+	localPath string
+}
+
+// exportDocumentName is the base name of the document inside zip exports.
+const exportDocumentName = "conversation"
+
+// Export writes a conversation export of a session to w.
+func (s *Store) Export(w io.Writer, sessionID string, opts ExportOptions) error {
+	format, err := ParseExportFormat(string(opts.Format))
+	if err != nil {
+		return err
+	}
+	opts.Format = format
+
+	meta, err := s.GetMetadata(sessionID)
+	if err != nil {
+		return err
+	}
+	events, err := s.ReadEvents(sessionID)
+	if err != nil {
+		return err
+	}
+	attachments, err := s.exportAttachments(sessionID)
+	if err != nil {
+		return err
+	}
+
+	e := &exporter{
+		meta:        meta,
+		events:      events,
+		attachments: make(map[string]*ExportAttachment, len(attachments)),
+		zip:         opts.Zip,
+	}
+	if opts.Format == ExportFormatJSON {
+		if e.queue, err = s.Queue(sessionID).List(); err != nil {
+			return err
+		}
+		if e.periodic, err = s.Periodic(sessionID).Get(); err != nil && !errors.Is(err, ErrPeriodicNotFound) {
+			return err
+		}
+	}
+	for i := range attachments {
+		e.attachments[attachments[i].Kind+"/"+attachments[i].ID] = &attachments[i]
+	}
+
+	if !opts.Zip {
+		if opts.Format != ExportFormatMarkdown {
+			for i := range attachments {
+				if opts.Format == ExportFormatHTML && attachments[i].Kind != "image" {
+					continue
+				}
+				if attachments[i].Data, err = os.ReadFile(attachments[i].localPath); err != nil {
+					return fmt.Errorf("failed to read %s %s: %w", attachments[i].Kind, attachments[i].ID, err)
+				}
+			}
+		}
+		return e.render(w, opts.Format, attachments)
+	}
+
+	zw := zip.NewWriter(w)
+	doc, err := zw.Create(exportDocumentName + "." + string(opts.Format))
+	if err != nil {
+		return err
+	}
+	if err := e.render(doc, opts.Format, attachments); err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		f, err := zw.Create(a.Path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(a.localPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s %s: %w", a.Kind, a.ID, err)
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// exportAttachments lists the images and files stored for a session.
+func (s *Store) exportAttachments(sessionID string) ([]ExportAttachment, error) {
+	images, err := s.ListImages(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	files, err := s.ListFiles(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	attachments := make([]ExportAttachment, 0, len(images)+len(files))
+	for _, img := range images {
+		attachments = append(attachments, ExportAttachment{
+			Kind:      "image",
+			ID:        img.ID,
+			Name:      img.Name,
+			MimeType:  img.MimeType,
+			Path:      imagesDirName + "/" + img.ID,
+			localPath: filepath.Join(s.imagesDir(sessionID), img.ID),
+		})
+	}
+	for _, f := range files {
+		attachments = append(attachments, ExportAttachment{
+			Kind:      "file",
+			ID:        f.ID,
+			Name:      f.Name,
+			MimeType:  f.MimeType,
+			Path:      filesDirName + "/" + f.ID,
+			localPath: filepath.Join(s.filesDir(sessionID), f.ID),
+		})
+	}
+	return attachments, nil
+}
+
+// exporter renders a session's events into an export document.
+type exporter struct {
+	meta        Metadata
+	events      []Event
+	attachments map[string]*ExportAttachment // "kind/id" -> attachment
+	zip         bool
+	queue       []QueuedMessage
+	periodic    *PeriodicPrompt
+}
+
+func (e *exporter) render(w io.Writer, format ExportFormat, attachments []ExportAttachment) error {
+	switch format {
+	case ExportFormatJSON:
+		bundle := ExportBundle{
+			Version:     ExportBundleVersion,
+			ExportedAt:  time.Now().UTC(),
+			Metadata:    e.meta,
+			Events:      e.events,
+			Attachments: attachments,
+			Queue:       e.queue,
+			Periodic:    e.periodic,
+		}
+		if !e.zip {
+			for i := range bundle.Attachments {
+				bundle.Attachments[i].Path = ""
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(bundle)
+	case ExportFormatHTML:
+		_, err := io.WriteString(w, e.renderHTML())
+		return err
+	default:
+		_, err := io.WriteString(w, e.renderMarkdown())
+		return err
+	}
+}
+
+// exportToolCall is a tool call with the updates received for it merged in.
+type exportToolCall struct {
+	ToolCallData
+	rendered bool
+}
+
+// mergeToolCalls returns the tool calls of the conversation with their updates
+// applied, keyed by tool call ID.
+func (e *exporter) mergeToolCalls() map[string]*exportToolCall {
+	calls := make(map[string]*exportToolCall)
+	for _, event := range e.events {
+		data, err := DecodeEventData(event)
+		if err != nil {
+			continue
+		}
+		switch d := data.(type) {
+		case ToolCallData:
+			calls[d.ToolCallID] = &exportToolCall{ToolCallData: d}
+		case ToolCallUpdateData:
+			call := calls[d.ToolCallID]
+			if call == nil {
+				call = &exportToolCall{ToolCallData: ToolCallData{ToolCallID: d.ToolCallID}}
+				calls[d.ToolCallID] = call
+			}
+			if d.Title != nil {
+				call.Title = *d.Title
+			}
+			if d.Status != nil {
+				call.Status = *d.Status
+			}
+		}
+	}
+	return calls
+}
+
+// toolCallForEvent returns the merged tool call to render at this event, or nil if it
+// has already been rendered (tool calls are rendered once, at their first event).
+func toolCallForEvent(calls map[string]*exportToolCall, id string) *exportToolCall {
+	call := calls[id]
+	if call == nil || call.rendered {
+		return nil
+	}
+	call.rendered = true
+	return call
+}
+
+func (e *exporter) title() string {
+	if e.meta.Name != "" {
+		return e.meta.Name
+	}
+	return "Conversation " + e.meta.SessionID
+}
+
+// =============================================================================
+// Markdown
+// =============================================================================
+
+func (e *exporter) renderMarkdown() string {
+	var b strings.Builder
+	calls := e.mergeToolCalls()
+
+	fmt.Fprintf(&b, "# %s\n\n", e.title())
+	fmt.Fprintf(&b, "- **Session:** `%s`\n", e.meta.SessionID)
+	fmt.Fprintf(&b, "- **Agent:** %s\n", e.meta.ACPServer)
+	if e.meta.WorkingDir != "" {
+		fmt.Fprintf(&b, "- **Folder:** `%s`\n", e.meta.WorkingDir)
+	}
+	fmt.Fprintf(&b, "- **Created:** %s\n", e.meta.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- **Updated:** %s\n", e.meta.UpdatedAt.Format(time.RFC3339))
+
+	for _, event := range e.events {
+		data, err := DecodeEventData(event)
+		if err != nil {
+			continue
+		}
+		switch d := data.(type) {
+		case UserPromptData:
+			fmt.Fprintf(&b, "\n---\n\n## 🧑 User\n\n%s\n", strings.TrimSpace(d.Message))
+			for _, img := range d.Images {
+				fmt.Fprintf(&b, "\n%s\n", e.markdownAttachment("image", img.ID, img.Name))
+			}
+			for _, f := range d.Files {
+				fmt.Fprintf(&b, "\n%s\n", e.markdownAttachment("file", f.ID, f.Name))
+			}
+		case AgentMessageData:
+			fmt.Fprintf(&b, "\n## 🤖 Agent\n\n%s\n", htmlToMarkdown(d.Text))
+		case AgentThoughtData:
+			fmt.Fprintf(&b, "\n<details><summary>💭 Thought</summary>\n\n%s\n\n</details>\n", strings.TrimSpace(d.Text))
+		case ToolCallData:
+			e.markdownToolCall(&b, toolCallForEvent(calls, d.ToolCallID))
+		case ToolCallUpdateData:
+			e.markdownToolCall(&b, toolCallForEvent(calls, d.ToolCallID))
+		case PlanData:
+			b.WriteString("\n**📋 Plan**\n\n")
+			for _, entry := range d.Entries {
+				check := " "
+				if entry.Status == "completed" {
+					check = "x"
+				}
+				fmt.Fprintf(&b, "- [%s] %s\n", check, entry.Content)
+			}
+		case PermissionData:
+			fmt.Fprintf(&b, "\n> 🔐 **Permission:** %s — %s", d.Title, d.Outcome)
+			if d.SelectedOption != "" {
+				fmt.Fprintf(&b, " (%s)", d.SelectedOption)
+			}
+			b.WriteString("\n")
+		case FileOperationData:
+			verb := "Read"
+			if event.Type == EventTypeFileWrite {
+				verb = "Wrote"
+			}
+			fmt.Fprintf(&b, "\n📄 %s `%s`\n", verb, d.Path)
+		case ErrorData:
+			fmt.Fprintf(&b, "\n> ⚠️ **Error:** %s\n", d.Message)
+		}
+	}
+	return b.String()
+}
+
+func (e *exporter) markdownToolCall(b *strings.Builder, call *exportToolCall) {
+	if call == nil {
+		return
+	}
+	fmt.Fprintf(b, "\n<details><summary>🔧 %s", call.Title)
+	if call.Status != "" {
+		fmt.Fprintf(b, " (%s)", call.Status)
+	}
+	b.WriteString("</summary>\n\n")
+	if call.RawInput != nil {
+		fmt.Fprintf(b, "Input:\n\n```json\n%s\n```\n\n", exportJSON(call.RawInput))
+	}
+	if call.RawOutput != nil {
+		fmt.Fprintf(b, "Output:\n\n```json\n%s\n```\n\n", exportJSON(call.RawOutput))
+	}
+	b.WriteString("</details>\n")
+}
+
+func (e *exporter) markdownAttachment(kind, id, name string) string {
+	if name == "" {
+		name = id
+	}
+	a := e.attachments[kind+"/"+id]
+	switch {
+	case a != nil && e.zip && kind == "image":
+		return fmt.Sprintf("![%s](%s)", name, a.Path)
+	case a != nil && e.zip:
+		return fmt.Sprintf("📎 [%s](%s)", name, a.Path)
+	case kind == "image":
+		return fmt.Sprintf("🖼️ *%s (image not included)*", name)
+	}
+	return fmt.Sprintf("📎 *%s (file not included)*", name)
+}
+
+// =============================================================================
+// HTML
+// =============================================================================
+
+// exportHTMLStyle is the stylesheet embedded in HTML exports.
+const exportHTMLStyle = `body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",sans-serif;max-width:860px;margin:2em auto;padding:0 1em;color:#1f2328;line-height:1.5}
+header{border-bottom:1px solid #d0d7de;margin-bottom:1.5em}header dl{display:grid;grid-template-columns:max-content 1fr;gap:.2em 1em;font-size:.9em}header dt{font-weight:600}
+.msg{margin:1em 0;padding:.6em 1em;border-radius:8px}.user{background:#ddf4ff}.agent{background:#f6f8fa}.role{font-weight:600;font-size:.85em;color:#57606a}
+.thought{color:#57606a;font-style:italic}details{margin:.5em 0}summary{cursor:pointer}
+pre{background:#f6f8fa;padding:.6em;overflow-x:auto;border-radius:6px}.permission,.error,.fileop{font-size:.9em;margin:.5em 0}.error{color:#cf222e}
+img{max-width:100%}ul.plan{list-style:none;padding-left:1em}`
+
+func (e *exporter) renderHTML() string {
+	var b strings.Builder
+	calls := e.mergeToolCalls()
+	conv := conversion.DefaultConverter()
+
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n", html.EscapeString(e.title()), exportHTMLStyle)
+	fmt.Fprintf(&b, "<header>\n<h1>%s</h1>\n<dl>\n", html.EscapeString(e.title()))
+	fmt.Fprintf(&b, "<dt>Session</dt><dd><code>%s</code></dd>\n", html.EscapeString(e.meta.SessionID))
+	fmt.Fprintf(&b, "<dt>Agent</dt><dd>%s</dd>\n", html.EscapeString(e.meta.ACPServer))
+	if e.meta.WorkingDir != "" {
+		fmt.Fprintf(&b, "<dt>Folder</dt><dd><code>%s</code></dd>\n", html.EscapeString(e.meta.WorkingDir))
+	}
+	fmt.Fprintf(&b, "<dt>Created</dt><dd>%s</dd>\n", e.meta.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "<dt>Updated</dt><dd>%s</dd>\n</dl>\n</header>\n<main>\n", e.meta.UpdatedAt.Format(time.RFC3339))
+
+	for _, event := range e.events {
+		data, err := DecodeEventData(event)
+		if err != nil {
+			continue
+		}
+		switch d := data.(type) {
+		case UserPromptData:
+			fmt.Fprintf(&b, "<section class=\"msg user\"><div class=\"role\">🧑 User</div>\n%s", conv.ConvertToSafeHTML(d.Message))
+			for _, img := range d.Images {
+				b.WriteString(e.htmlAttachment("image", img.ID, img.Name, img.MimeType))
+			}
+			for _, f := range d.Files {
+				b.WriteString(e.htmlAttachment("file", f.ID, f.Name, f.MimeType))
+			}
+			b.WriteString("</section>\n")
+		case AgentMessageData:
+			fmt.Fprintf(&b, "<section class=\"msg agent\"><div class=\"role\">🤖 Agent</div>\n%s</section>\n", conv.Sanitize(d.Text))
+		case AgentThoughtData:
+			fmt.Fprintf(&b, "<details class=\"thought\"><summary>💭 Thought</summary>%s</details>\n", conv.ConvertToSafeHTML(d.Text))
+		case ToolCallData:
+			e.htmlToolCall(&b, toolCallForEvent(calls, d.ToolCallID))
+		case ToolCallUpdateData:
+			e.htmlToolCall(&b, toolCallForEvent(calls, d.ToolCallID))
+		case PlanData:
+			b.WriteString("<div class=\"plan\"><strong>📋 Plan</strong><ul class=\"plan\">\n")
+			for _, entry := range d.Entries {
+				check := "☐"
+				if entry.Status == "completed" {
+					check = "☑"
+				}
+				fmt.Fprintf(&b, "<li>%s %s</li>\n", check, html.EscapeString(entry.Content))
+			}
+			b.WriteString("</ul></div>\n")
+		case PermissionData:
+			fmt.Fprintf(&b, "<div class=\"permission\">🔐 <strong>Permission:</strong> %s — %s", html.EscapeString(d.Title), html.EscapeString(d.Outcome))
+			if d.SelectedOption != "" {
+				fmt.Fprintf(&b, " (%s)", html.EscapeString(d.SelectedOption))
+			}
+			b.WriteString("</div>\n")
+		case FileOperationData:
+			verb := "Read"
+			if event.Type == EventTypeFileWrite {
+				verb = "Wrote"
+			}
+			fmt.Fprintf(&b, "<div class=\"fileop\">📄 %s <code>%s</code></div>\n", verb, html.EscapeString(d.Path))
+		case ErrorData:
+			fmt.Fprintf(&b, "<div class=\"error\">⚠️ <strong>Error:</strong> %s</div>\n", html.EscapeString(d.Message))
+		}
+	}
+
+	b.WriteString("</main>\n</body>\n</html>\n")
+	return b.String()
+}
+
+func (e *exporter) htmlToolCall(b *strings.Builder, call *exportToolCall) {
+	if call == nil {
+		return
+	}
+	fmt.Fprintf(b, "<details class=\"tool\"><summary>🔧 %s", html.EscapeString(call.Title))
+	if call.Status != "" {
+		fmt.Fprintf(b, " (%s)", html.EscapeString(call.Status))
+	}
+	b.WriteString("</summary>\n")
+	if call.RawInput != nil {
+		fmt.Fprintf(b, "<div>Input:</div><pre><code>%s</code></pre>\n", html.EscapeString(exportJSON(call.RawInput)))
+	}
+	if call.RawOutput != nil {
+		fmt.Fprintf(b, "<div>Output:</div><pre><code>%s</code></pre>\n", html.EscapeString(exportJSON(call.RawOutput)))
+	}
+	b.WriteString("</details>\n")
+}
+
+func (e *exporter) htmlAttachment(kind, id, name, mimeType string) string {
+	if name == "" {
+		name = id
+	}
+	a := e.attachments[kind+"/"+id]
+	switch {
+	case a != nil && kind == "image" && e.zip:
+		return fmt.Sprintf("<p><img src=\"%s\" alt=\"%s\"></p>\n", html.EscapeString(a.Path), html.EscapeString(name))
+	case a != nil && kind == "image" && IsSupportedImageType(mimeType) && mimeType != "image/svg+xml":
+		return fmt.Sprintf("<p><img src=\"data:%s;base64,%s\" alt=\"%s\"></p>\n",
+			mimeType, base64.StdEncoding.EncodeToString(a.Data), html.EscapeString(name))
+	case a != nil && e.zip:
+		return fmt.Sprintf("<p>📎 <a href=\"%s\">%s</a></p>\n", html.EscapeString(a.Path), html.EscapeString(name))
+	}
+	return fmt.Sprintf("<p>📎 <em>%s (not included)</em></p>\n", html.EscapeString(name))
+}
+
+// =============================================================================
+// Helpers
+// =============================================================================
+
+// exportJSON formats tool call input/output for display.
+func exportJSON(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+var exportSlugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// exportSlug turns a session name into a file-name-safe slug.
+func exportSlug(name string) string {
+	slug := strings.Trim(exportSlugRe.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 50 {
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+	return slug
+}
+
+var (
+	mdPreOpenRe   = regexp.MustCompile(`(?i)<pre[^>]*>\s*(<code[^>]*>)?`)
+	mdPreCloseRe  = regexp.MustCompile(`(?i)(</code>\s*)?</pre>`)
+	mdHeadingRe   = regexp.MustCompile(`(?i)<h([1-6])[^>]*>`)
+	mdListItemRe  = regexp.MustCompile(`(?i)<li[^>]*>`)
+	mdBreakRe     = regexp.MustCompile(`(?i)<br\s*/?>`)
+	mdBlockEndRe  = regexp.MustCompile(`(?i)</(p|div|ul|ol|h[1-6]|blockquote|table)>`)
+	mdRowEndRe    = regexp.MustCompile(`(?i)</(li|tr)>`)
+	mdStrongRe    = regexp.MustCompile(`(?i)</?(strong|b)>`)
+	mdEmRe        = regexp.MustCompile(`(?i)</?(em|i)>`)
+	mdCodeRe      = regexp.MustCompile(`(?i)</?code[^>]*>`)
+	mdLinkRe      = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	mdBlankLineRe = regexp.MustCompile(`\n{3,}`)
+)
+
+// Placeholders (Unicode private use characters) used by htmlToMarkdown to protect code blocks.
+const (
+	mdPreOpenMark  = "\uE000pre\uE000"
+	mdPreCloseMark = "\uE000/pre\uE000"
+	mdBlockMark    = "\uE000block%d\uE000"
+)
+
+// htmlToMarkdown converts the HTML of agent messages back into readable Markdown.
+// It handles the elements produced by the markdown renderer (paragraphs, headings,
+// lists, code, emphasis and links) and strips everything else.
+func htmlToMarkdown(s string) string {
+	// Code blocks first, so that their content is left untouched by inline rules
+	var blocks []string
+	s = mdPreOpenRe.ReplaceAllString(s, mdPreOpenMark)
+	s = mdPreCloseRe.ReplaceAllString(s, mdPreCloseMark)
+	parts := strings.Split(s, mdPreOpenMark)
+	var out bytes.Buffer
+	out.WriteString(parts[0])
+	for _, part := range parts[1:] {
+		code, rest, _ := strings.Cut(part, mdPreCloseMark)
+		blocks = append(blocks, stripHTML(code))
+		fmt.Fprintf(&out, "\n\n"+mdBlockMark+"\n\n%s", len(blocks)-1, rest)
+	}
+	s = out.String()
+
+	s = mdHeadingRe.ReplaceAllStringFunc(s, func(m string) string {
+		level := int(mdHeadingRe.FindStringSubmatch(m)[1][0] - '0')
+		return "\n\n" + strings.Repeat("#", level) + " "
+	})
+	s = mdListItemRe.ReplaceAllString(s, "\n- ")
+	s = mdBreakRe.ReplaceAllString(s, "\n")
+	s = mdBlockEndRe.ReplaceAllString(s, "\n\n")
+	s = mdRowEndRe.ReplaceAllString(s, "\n")
+	s = mdStrongRe.ReplaceAllString(s, "**")
+	s = mdEmRe.ReplaceAllString(s, "_")
+	s = mdCodeRe.ReplaceAllString(s, "`")
+	s = mdLinkRe.ReplaceAllString(s, "[$2]($1)")
+	s = stripHTML(s)
+
+	for i, code := range blocks {
+		s = strings.Replace(s, fmt.Sprintf(mdBlockMark, i), "```\n"+strings.TrimRight(code, "\n")+"\n```", 1)
+	}
+	s = mdBlankLineRe.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
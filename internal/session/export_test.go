@@ -0,0 +1,206 @@
+package session
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// pngData is a minimal PNG header, enough for image storage.
+var pngData = []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+
+func newExportTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	sessionID := "20240115-103000-abcd1234"
+	if err := store.Create(Metadata{SessionID: sessionID, Name: "Fix the Parser!", ACPServer: "auggie", WorkingDir: "/proj"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	img, err := store.SaveImage(sessionID, pngData, "image/png", "screenshot.png")
+	if err != nil {
+		t.Fatalf("SaveImage failed: %v", err)
+	}
+	file, err := store.SaveFile(sessionID, []byte("some notes"), "text/plain", "notes.txt")
+	if err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	done := "completed"
+	title := "Read parser.go"
+	for _, event := range []Event{
+		{Type: EventTypeUserPrompt, Data: UserPromptData{
+			Message: "Why does the **parser** crash?",
+			Images:  []ImageRef{{ID: img.ID, Name: img.Name, MimeType: img.MimeType}},
+			Files:   []FileRef{{ID: file.ID, Name: file.Name, MimeType: file.MimeType}},
+		}},
+		{Type: EventTypeAgentThought, Data: AgentThoughtData{Text: "Looking at the parser"}},
+		{Type: EventTypeToolCall, Data: ToolCallData{ToolCallID: "t1", Title: "Read file", Status: "running", RawInput: map[string]any{"path": "parser.go"}}},
+		{Type: EventTypeToolCallUpdate, Data: ToolCallUpdateData{ToolCallID: "t1", Title: &title, Status: &done}},
+		{Type: EventTypePlan, Data: PlanData{Entries: []PlanEntry{{Content: "Find bug", Status: "completed"}, {Content: "Fix bug", Status: "pending"}}}},
+		{Type: EventTypePermission, Data: PermissionData{Title: "Edit parser.go", SelectedOption: "Allow once", Outcome: "approved"}},
+		{Type: EventTypeAgentMessage, Data: AgentMessageData{Text: `<p>The <strong>bug</strong> is here:</p><pre><code class="language-go">x := a &lt; b
+</code></pre><script>alert(1)</script><ul><li>one</li></ul>`}},
+	} {
+		if err := store.AppendEvent(sessionID, event); err != nil {
+			t.Fatalf("AppendEvent failed: %v", err)
+		}
+	}
+	return store, sessionID
+}
+
+func TestParseExportFormat(t *testing.T) {
+	for value, want := range map[string]ExportFormat{"md": ExportFormatMarkdown, "Markdown": ExportFormatMarkdown, "html": ExportFormatHTML, "json": ExportFormatJSON} {
+		if got, err := ParseExportFormat(value); err != nil || got != want {
+			t.Errorf("ParseExportFormat(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseExportFormat("pdf"); err == nil {
+		t.Error("ParseExportFormat(pdf) should fail")
+	}
+}
+
+func TestExportFileName(t *testing.T) {
+	meta := Metadata{SessionID: "20240115-103000-abcd1234", Name: "Fix the Parser!"}
+	if got := ExportFileName(meta, ExportOptions{Format: ExportFormatHTML}); got != "fix-the-parser-20240115.html" {
+		t.Errorf("ExportFileName = %q", got)
+	}
+	if got := ExportFileName(Metadata{}, ExportOptions{Format: ExportFormatMarkdown, Zip: true}); got != "conversation.zip" {
+		t.Errorf("ExportFileName = %q", got)
+	}
+}
+
+func TestStore_ExportMarkdown(t *testing.T) {
+	store, sessionID := newExportTestStore(t)
+
+	var buf bytes.Buffer
+	if err := store.Export(&buf, sessionID, ExportOptions{Format: ExportFormatMarkdown}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	md := buf.String()
+
+	for _, want := range []string{
+		"# Fix the Parser!",
+		"## 🧑 User\n\nWhy does the **parser** crash?",
+		"screenshot.png (image not included)",
+		"Looking at the parser",
+		"🔧 Read parser.go (completed)",
+		`"path": "parser.go"`,
+		"- [x] Find bug",
+		"- [ ] Fix bug",
+		"🔐 **Permission:** Edit parser.go — approved (Allow once)",
+		"The **bug** is here:",
+		"```\nx := a < b\n```",
+		"- one",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown export missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "<script>") || strings.Contains(md, "Read file") {
+		t.Errorf("markdown export has unexpected content:\n%s", md)
+	}
+}
+
+func TestStore_ExportHTML(t *testing.T) {
+	store, sessionID := newExportTestStore(t)
+
+	var buf bytes.Buffer
+	if err := store.Export(&buf, sessionID, ExportOptions{Format: ExportFormatHTML}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	page := buf.String()
+
+	for _, want := range []string{
+		"<title>Fix the Parser!</title>",
+		"<strong>parser</strong>",
+		`<img src="data:image/png;base64,`,
+		"notes.txt (not included)",
+		"Read parser.go (completed)",
+		"<strong>bug</strong>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("HTML export missing %q", want)
+		}
+	}
+	if strings.Contains(page, "alert(1)") {
+		t.Error("HTML export should be sanitized")
+	}
+}
+
+func TestStore_ExportJSON(t *testing.T) {
+	store, sessionID := newExportTestStore(t)
+
+	var buf bytes.Buffer
+	if err := store.Export(&buf, sessionID, ExportOptions{Format: ExportFormatJSON}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	var bundle ExportBundle
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if bundle.Version != ExportBundleVersion || bundle.Metadata.SessionID != sessionID || len(bundle.Events) != 7 {
+		t.Errorf("bundle = version %d, session %q, %d events", bundle.Version, bundle.Metadata.SessionID, len(bundle.Events))
+	}
+	if len(bundle.Attachments) != 2 {
+		t.Fatalf("attachments = %d, want 2", len(bundle.Attachments))
+	}
+	for _, a := range bundle.Attachments {
+		if len(a.Data) == 0 || a.Path != "" {
+			t.Errorf("attachment %s should be embedded, got %d bytes and path %q", a.ID, len(a.Data), a.Path)
+		}
+	}
+}
+
+func TestStore_ExportZip(t *testing.T) {
+	store, sessionID := newExportTestStore(t)
+
+	var buf bytes.Buffer
+	if err := store.Export(&buf, sessionID, ExportOptions{Format: ExportFormatMarkdown, Zip: true}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open %s failed: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	if len(files) != 3 {
+		t.Errorf("zip entries = %v, want document, image and file", len(files))
+	}
+	md, ok := files["conversation.md"]
+	if !ok {
+		t.Fatal("zip has no conversation.md")
+	}
+	if !strings.Contains(md, "![screenshot.png](images/") || !strings.Contains(md, "📎 [notes.txt](files/") {
+		t.Errorf("markdown should link attachments:\n%s", md)
+	}
+	for name, data := range files {
+		if strings.HasPrefix(name, "files/") && data != "some notes" {
+			t.Errorf("%s = %q, want file content", name, data)
+		}
+	}
+}
+
+func TestStore_ExportNotFound(t *testing.T) {
+	store := newSearchTestStore(t)
+	if err := store.Export(io.Discard, "missing", ExportOptions{Format: ExportFormatJSON}); err != ErrSessionNotFound {
+		t.Errorf("Export of missing session = %v, want ErrSessionNotFound", err)
+	}
+}
@@ -0,0 +1,240 @@
+package session
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+
+	"github.com/inercia/mitto/internal/fileutil"
+)
+
+// changesDirName is the session subdirectory holding the file changes of each tool call.
+const changesDirName = "changes"
+
+var (
+	// ErrToolCallChangesNotFound is returned when a tool call made no recorded file changes.
+	ErrToolCallChangesNotFound = errors.New("no file changes recorded for tool call")
+	// ErrAlreadyReverted is returned when reverting a tool call that was already reverted.
+	ErrAlreadyReverted = errors.New("tool call changes already reverted")
+	// ErrRevertConflict is returned when a file was modified after the tool call wrote it.
+	ErrRevertConflict = errors.New("file modified since the tool call")
+)
+
+// FileChange is the content of a file before and after a single write by the agent.
+type FileChange struct {
+	// Seq is the sequence number of the file_write event.
+	Seq  int64  `json:"seq"`
+	Path string `json:"path"`
+	// Existed is false when the write created the file.
+	Existed bool      `json:"existed"`
+	Before  string    `json:"before,omitempty"`
+	After   string    `json:"after"`
+	Time    time.Time `json:"time"`
+}
+
+// ToolCallChanges contains the file writes made during a tool call, in order.
+type ToolCallChanges struct {
+	ToolCallID string       `json:"tool_call_id"`
+	Changes    []FileChange `json:"changes"`
+	RevertedAt *time.Time   `json:"reverted_at,omitempty"`
+}
+
+// FileDiff is the net change of a file over a tool call.
+type FileDiff struct {
+	Path string `json:"path"`
+	// Status is "A" for files created by the tool call and "M" for modified files.
+	Status    string `json:"status"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	// Diff is the unified diff of the file.
+	Diff string `json:"diff"`
+}
+
+// netChanges returns, for each path in order of first write, the content
+// before the first write of the tool call and after its last write.
+func (c *ToolCallChanges) netChanges() []FileChange {
+	var net []FileChange
+	index := make(map[string]int)
+	for _, ch := range c.Changes {
+		if i, ok := index[ch.Path]; ok {
+			net[i].After = ch.After
+			net[i].Seq = ch.Seq
+			net[i].Time = ch.Time
+			continue
+		}
+		index[ch.Path] = len(net)
+		net = append(net, ch)
+	}
+	return net
+}
+
+// Diffs returns the unified diff of each file written by the tool call.
+func (c *ToolCallChanges) Diffs() []FileDiff {
+	net := c.netChanges()
+	diffs := make([]FileDiff, 0, len(net))
+	for _, ch := range net {
+		from, status := ch.Path, "M"
+		if !ch.Existed {
+			from, status = "/dev/null", "A"
+		}
+		edits := myers.ComputeEdits(span.URIFromPath(ch.Path), ch.Before, ch.After)
+		unified := gotextdiff.ToUnified(from, ch.Path, ch.Before, edits)
+
+		d := FileDiff{Path: ch.Path, Status: status, Diff: fmt.Sprint(unified)}
+		for _, hunk := range unified.Hunks {
+			for _, line := range hunk.Lines {
+				switch line.Kind {
+				case gotextdiff.Insert:
+					d.Additions++
+				case gotextdiff.Delete:
+					d.Deletions++
+				}
+			}
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// toolCallChangesPath returns the path of the file changes of a tool call.
+// Tool call IDs are chosen by the agent, so they are encoded to be safe file names.
+func (s *Store) toolCallChangesPath(sessionID, toolCallID string) string {
+	name := base64.RawURLEncoding.EncodeToString([]byte(toolCallID)) + ".json"
+	return filepath.Join(s.sessionDir(sessionID), changesDirName, name)
+}
+
+// readToolCallChanges reads the file changes of a tool call (must be called with lock held).
+func (s *Store) readToolCallChanges(sessionID, toolCallID string) (*ToolCallChanges, error) {
+	var c ToolCallChanges
+	if err := fileutil.ReadJSON(s.toolCallChangesPath(sessionID, toolCallID), &c); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrToolCallChangesNotFound
+		}
+		return nil, fmt.Errorf("failed to read tool call changes: %w", err)
+	}
+	return &c, nil
+}
+
+// writeToolCallChanges writes the file changes of a tool call (must be called with lock held).
+func (s *Store) writeToolCallChanges(sessionID string, c *ToolCallChanges) error {
+	if err := fileutil.WriteJSONAtomic(s.toolCallChangesPath(sessionID, c.ToolCallID), c, 0644); err != nil {
+		return fmt.Errorf("failed to write tool call changes: %w", err)
+	}
+	return nil
+}
+
+// RecordFileChange appends a file write to the changes of a tool call.
+func (s *Store) RecordFileChange(sessionID, toolCallID string, change FileChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStoreClosed
+	}
+
+	c, err := s.readToolCallChanges(sessionID, toolCallID)
+	if errors.Is(err, ErrToolCallChangesNotFound) {
+		c, err = &ToolCallChanges{ToolCallID: toolCallID}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if change.Time.IsZero() {
+		change.Time = time.Now()
+	}
+	c.Changes = append(c.Changes, change)
+	return s.writeToolCallChanges(sessionID, c)
+}
+
+// GetToolCallChanges returns the file writes made during a tool call.
+// Returns ErrToolCallChangesNotFound if the tool call wrote no (recorded) files.
+func (s *Store) GetToolCallChanges(sessionID, toolCallID string) (*ToolCallChanges, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+	return s.readToolCallChanges(sessionID, toolCallID)
+}
+
+// RevertToolCallChanges restores the files written during a tool call to their
+// content before the tool call, removing the files it created. It works on the
+// recorded snapshots, so it doesn't need the files to be under version control.
+//
+// Unless force is set, nothing is reverted (ErrRevertConflict) when a file was
+// modified after the tool call last wrote it, so later edits are not lost.
+// Files are restored atomically, keeping their current permissions.
+func (s *Store) RevertToolCallChanges(sessionID, toolCallID string, force bool) (*ToolCallChanges, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+
+	c, err := s.readToolCallChanges(sessionID, toolCallID)
+	if err != nil {
+		return nil, err
+	}
+	if c.RevertedAt != nil {
+		return nil, ErrAlreadyReverted
+	}
+
+	// Check every file before writing any, so that a revert is not left half done
+	net := c.netChanges()
+	targets := make([]string, len(net))
+	modes := make([]os.FileMode, len(net))
+	for i, ch := range net {
+		targets[i], modes[i] = ch.Path, 0644
+		if info, err := os.Lstat(ch.Path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			if targets[i], err = filepath.EvalSymlinks(ch.Path); err != nil {
+				return nil, fmt.Errorf("failed to resolve %s: %w", ch.Path, err)
+			}
+		}
+		info, err := os.Stat(targets[i])
+		switch {
+		case err == nil && !info.Mode().IsRegular():
+			return nil, fmt.Errorf("cannot revert %s: not a regular file", ch.Path)
+		case err == nil:
+			modes[i] = info.Mode().Perm()
+		case !os.IsNotExist(err):
+			return nil, fmt.Errorf("cannot revert %s: %w", ch.Path, err)
+		}
+		if !force {
+			current, err := os.ReadFile(targets[i])
+			if err != nil || string(current) != ch.After {
+				return nil, fmt.Errorf("%w: %s", ErrRevertConflict, ch.Path)
+			}
+		}
+	}
+
+	for i, ch := range net {
+		if !ch.Existed {
+			if err := os.Remove(ch.Path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove %s: %w", ch.Path, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(targets[i]), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", ch.Path, err)
+		}
+		if err := fileutil.WriteFileAtomic(targets[i], []byte(ch.Before), modes[i]); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", ch.Path, err)
+		}
+	}
+
+	now := time.Now()
+	c.RevertedAt = &now
+	if err := s.writeToolCallChanges(sessionID, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_ToolCallChanges(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+
+	dir := t.TempDir()
+	if err := store.Create(Metadata{SessionID: "s1", ACPServer: "test", WorkingDir: dir}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	modified := filepath.Join(dir, "main.go")
+	created := filepath.Join(dir, "new.txt")
+
+	if _, err := store.GetToolCallChanges("s1", "tc/1"); !errors.Is(err, ErrToolCallChangesNotFound) {
+		t.Errorf("GetToolCallChanges before recording: err = %v, want ErrToolCallChangesNotFound", err)
+	}
+
+	// The tool call modifies main.go twice and creates new.txt
+	writes := []FileChange{
+		{Seq: 3, Path: modified, Existed: true, Before: "a\nb\n", After: "a\nB\n"},
+		{Seq: 4, Path: created, After: "new\n"},
+		{Seq: 5, Path: modified, Existed: true, Before: "a\nB\n", After: "a\nB\nc\n"},
+	}
+	for _, ch := range writes {
+		if err := os.WriteFile(ch.Path, []byte(ch.After), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.RecordFileChange("s1", "tc/1", ch); err != nil {
+			t.Fatalf("RecordFileChange failed: %v", err)
+		}
+	}
+
+	changes, err := store.GetToolCallChanges("s1", "tc/1")
+	if err != nil {
+		t.Fatalf("GetToolCallChanges failed: %v", err)
+	}
+	if len(changes.Changes) != 3 || changes.Changes[0].Time.IsZero() {
+		t.Fatalf("changes = %+v, want 3 timestamped writes", changes.Changes)
+	}
+
+	diffs := changes.Diffs()
+	if len(diffs) != 2 {
+		t.Fatalf("diffs = %+v, want one per file", diffs)
+	}
+	if d := diffs[0]; d.Path != modified || d.Status != "M" || d.Additions != 2 || d.Deletions != 1 ||
+		!strings.Contains(d.Diff, "-b\n+B\n+c\n") {
+		t.Errorf("diff of the modified file = %+v", d)
+	}
+	if d := diffs[1]; d.Status != "A" || d.Additions != 1 || !strings.HasPrefix(d.Diff, "--- /dev/null\n") {
+		t.Errorf("diff of the created file = %+v", d)
+	}
+
+	// Files modified after the tool call are not reverted unless forced
+	if err := os.WriteFile(created, []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.RevertToolCallChanges("s1", "tc/1", false); !errors.Is(err, ErrRevertConflict) {
+		t.Fatalf("RevertToolCallChanges: err = %v, want ErrRevertConflict", err)
+	}
+	if got, _ := os.ReadFile(modified); string(got) != "a\nB\nc\n" {
+		t.Errorf("conflicting revert changed %s: %q", modified, got)
+	}
+
+	// Nothing is reverted either when a file can't be restored
+	if err := os.Remove(created); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(created, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(modified, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.RevertToolCallChanges("s1", "tc/1", true); err == nil {
+		t.Fatal("RevertToolCallChanges should fail when a file is a directory")
+	}
+	if got, _ := os.ReadFile(modified); string(got) != "a\nB\nc\n" {
+		t.Errorf("failed revert changed %s: %q", modified, got)
+	}
+	if err := os.Remove(created); err != nil {
+		t.Fatal(err)
+	}
+
+	reverted, err := store.RevertToolCallChanges("s1", "tc/1", true)
+	if err != nil {
+		t.Fatalf("RevertToolCallChanges failed: %v", err)
+	}
+	if reverted.RevertedAt == nil {
+		t.Error("RevertedAt should be set")
+	}
+	if got, _ := os.ReadFile(modified); string(got) != "a\nb\n" {
+		t.Errorf("%s = %q, want the content before the tool call", modified, got)
+	}
+	if info, err := os.Stat(modified); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("%s lost its permissions: %v (err %v)", modified, info.Mode(), err)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("created file should be removed, stat err = %v", err)
+	}
+
+	if _, err := store.RevertToolCallChanges("s1", "tc/1", false); !errors.Is(err, ErrAlreadyReverted) {
+		t.Errorf("second revert: err = %v, want ErrAlreadyReverted", err)
+	}
+}
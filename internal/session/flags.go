@@ -75,7 +75,7 @@ const (
 	FlagCanPromptUser = "can_prompt_user"
 
 	// FlagCanStartConversation controls whether the conversation can create
-	// new conversations via the mitto_conversation_new MCP tool.
+	// new conversations via the mitto_conversation_new and mitto_conversation_fork MCP tools.
 	// Child conversations (those with a ParentSessionID) cannot start further conversations
 	// while the parent exists. When the parent is deleted, orphaned children have this
 	// flag restored to the default value so they can start conversations again.
@@ -115,3 +115,18 @@ func GetFlagValue(settings map[string]bool, flagName string) bool {
 	}
 	return GetFlagDefault(flagName)
 }
+
+// DefaultFlagValues returns the flags of a new session: the configured default
+// flags, plus the flags whose compile-time default is true.
+func DefaultFlagValues(configured map[string]bool) map[string]bool {
+	settings := make(map[string]bool, len(configured)+len(AvailableFlags))
+	for name, value := range configured {
+		settings[name] = value
+	}
+	for _, flag := range AvailableFlags {
+		if _, exists := settings[flag.Name]; !exists && flag.Default {
+			settings[flag.Name] = true
+		}
+	}
+	return settings
+}
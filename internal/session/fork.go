@@ -0,0 +1,187 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/inercia/mitto/internal/logging"
+)
+
+// ErrInvalidForkSeq is returned when a fork point is not a sequence number of the session.
+var ErrInvalidForkSeq = errors.New("invalid fork sequence number")
+
+// ForkOptions configures a session fork.
+type ForkOptions struct {
+	// AtSeq is the sequence number of the last event copied to the fork.
+	// Zero means the whole conversation.
+	AtSeq int64
+	// Name is the name of the new session. Defaults to the source name with a "(fork)" suffix.
+	Name string
+}
+
+// Fork creates a new session whose event log is a copy of the source session's
+// events up to (and including) opts.AtSeq.
+//
+// The fork is a child of the source session (ChildOriginFork) in the same workspace,
+// with the same advanced settings. Images and files referenced by the copied events
+// are copied too. The ACP session ID is not copied: agent-side state can't be
+// cloned, so the fork starts a new ACP session that gets the copied conversation
+// history as context when it is resumed.
+func (s *Store) Fork(sessionID string, opts ForkOptions) (Metadata, error) {
+	log := logging.Session()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return Metadata{}, ErrStoreClosed
+	}
+
+	src, err := s.backend.readMetadata(sessionID)
+	if err != nil {
+		return Metadata{}, err
+	}
+	events, err := s.backend.readEventsFrom(sessionID, 0, 0)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	atSeq := opts.AtSeq
+	if atSeq == 0 && len(events) > 0 {
+		atSeq = events[len(events)-1].Seq
+	}
+	var copied []Event
+	found := atSeq == 0
+	for _, e := range events {
+		if e.Seq > atSeq {
+			continue
+		}
+		copied = append(copied, e)
+		if e.Seq == atSeq {
+			found = true
+		}
+	}
+	if atSeq < 0 || !found {
+		return Metadata{}, fmt.Errorf("%w: %d (session has events 1-%d)", ErrInvalidForkSeq, opts.AtSeq, src.MaxSeq)
+	}
+
+	newID := GenerateSessionID()
+	copied, _ = normalizeImportedEvents(copied, newID)
+
+	name := opts.Name
+	if name == "" {
+		name = src.Name
+		if name == "" {
+			name = "Conversation"
+		}
+		name += " (fork)"
+	}
+
+	var settings map[string]bool
+	if len(src.AdvancedSettings) > 0 {
+		settings = make(map[string]bool, len(src.AdvancedSettings))
+		for k, v := range src.AdvancedSettings {
+			settings[k] = v
+		}
+	}
+
+	now := time.Now()
+	meta := Metadata{
+		SessionID:        newID,
+		Name:             name,
+		ACPServer:        src.ACPServer,
+		WorkingDir:       src.WorkingDir,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		EventCount:       len(copied),
+		Status:           SessionStatusActive,
+		RunnerType:       src.RunnerType,
+		RunnerRestricted: src.RunnerRestricted,
+		CurrentModeID:    src.CurrentModeID,
+		BeadsIssue:       src.BeadsIssue,
+		AdvancedSettings: settings,
+		ParentSessionID:  sessionID,
+		ChildOrigin:      ChildOriginFork,
+	}
+	for _, e := range copied {
+		if e.Type == EventTypeUserPrompt {
+			meta.LastUserMessageAt = e.Timestamp
+		}
+	}
+	if len(copied) > 0 {
+		meta.MaxSeq = copied[len(copied)-1].Seq
+	}
+
+	newDir := s.sessionDir(newID)
+	if err := os.MkdirAll(newDir, 0755); err != nil {
+		return Metadata{}, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if err := s.copyForkAttachments(sessionID, newID, copied); err != nil {
+		os.RemoveAll(newDir)
+		return Metadata{}, err
+	}
+	if err := s.backend.replaceEvents(meta, copied); err != nil {
+		os.RemoveAll(newDir)
+		return Metadata{}, err
+	}
+
+	log.Info("session forked",
+		"source_session_id", sessionID,
+		"session_id", newID,
+		"at_seq", atSeq,
+		"events", len(copied))
+	return meta, nil
+}
+
+// copyForkAttachments copies the images and files referenced by user prompts
+// in the forked events to the new session.
+// Note: This method assumes the caller holds s.mu.Lock().
+func (s *Store) copyForkAttachments(srcID, dstID string, events []Event) error {
+	copyOne := func(srcDir, dstDir, id string) error {
+		if id == "" || id != filepath.Base(id) {
+			return nil
+		}
+		data, err := os.ReadFile(filepath.Join(srcDir, id))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // Attachment was deleted; the event still refers to it by name
+			}
+			return fmt.Errorf("failed to read attachment %s: %w", id, err)
+		}
+		if err := os.MkdirAll(dstDir, 0755); err != nil {
+			return fmt.Errorf("failed to create attachment directory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dstDir, id), data, 0644); err != nil {
+			return fmt.Errorf("failed to write attachment %s: %w", id, err)
+		}
+		return nil
+	}
+
+	for _, e := range events {
+		if e.Type != EventTypeUserPrompt {
+			continue
+		}
+		decoded, err := DecodeEventData(e)
+		if err != nil {
+			continue
+		}
+		prompt, ok := decoded.(UserPromptData)
+		if !ok {
+			continue
+		}
+		for _, img := range prompt.Images {
+			if err := copyOne(s.imagesDir(srcID), s.imagesDir(dstID), img.ID); err != nil {
+				return err
+			}
+		}
+		for _, f := range prompt.Files {
+			if err := copyOne(s.filesDir(srcID), s.filesDir(dstID), f.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStore_Fork(t *testing.T) {
+	store, sessionID := newExportTestStore(t)
+	if err := store.UpdateMetadata(sessionID, func(m *Metadata) {
+		m.ACPSessionID = "acp-123"
+		m.AdvancedSettings = map[string]bool{FlagCanInteractOtherWorkspaces: true}
+	}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+
+	meta, err := store.Fork(sessionID, ForkOptions{AtSeq: 3})
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if meta.SessionID == sessionID || meta.Name != "Fix the Parser! (fork)" {
+		t.Errorf("fork = %q named %q", meta.SessionID, meta.Name)
+	}
+
+	got, err := store.GetMetadata(meta.SessionID)
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if got.ParentSessionID != sessionID || got.ChildOrigin != ChildOriginFork || got.ACPSessionID != "" ||
+		got.WorkingDir != "/proj" || got.ACPServer != "auggie" || got.EventCount != 3 || got.MaxSeq != 3 ||
+		!got.AdvancedSettings[FlagCanInteractOtherWorkspaces] {
+		t.Errorf("fork metadata = %+v", got)
+	}
+
+	events, err := store.ReadEvents(meta.SessionID)
+	if err != nil || len(events) != 3 || events[2].Type != EventTypeToolCall {
+		t.Fatalf("fork events = %+v (err %v)", events, err)
+	}
+	if images, _ := store.ListImages(meta.SessionID); len(images) != 1 {
+		t.Errorf("fork images = %d, want 1", len(images))
+	}
+	if files, _ := store.ListFiles(meta.SessionID); len(files) != 1 {
+		t.Errorf("fork files = %d, want 1", len(files))
+	}
+
+	// The source session is unchanged and the fork can be extended independently
+	if err := store.AppendEvent(meta.SessionID, Event{Type: EventTypeUserPrompt, Data: UserPromptData{Message: "another way"}}); err != nil {
+		t.Fatalf("AppendEvent on fork failed: %v", err)
+	}
+	if events, _ := store.ReadEvents(sessionID); len(events) != 7 {
+		t.Errorf("source events = %d, want 7", len(events))
+	}
+	if got, _ := store.GetMetadata(meta.SessionID); got.MaxSeq != 4 {
+		t.Errorf("fork MaxSeq after append = %d, want 4", got.MaxSeq)
+	}
+}
+
+func TestStore_ForkWholeConversation(t *testing.T) {
+	store, sessionID := newExportTestStore(t)
+
+	meta, err := store.Fork(sessionID, ForkOptions{Name: "Alternative"})
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if meta.Name != "Alternative" || meta.EventCount != 7 || meta.MaxSeq != 7 {
+		t.Errorf("fork = %+v", meta)
+	}
+}
+
+func TestStore_ForkInvalidSeq(t *testing.T) {
+	store, sessionID := newExportTestStore(t)
+
+	for _, seq := range []int64{-1, 8} {
+		if _, err := store.Fork(sessionID, ForkOptions{AtSeq: seq}); !errors.Is(err, ErrInvalidForkSeq) {
+			t.Errorf("Fork at %d = %v, want ErrInvalidForkSeq", seq, err)
+		}
+	}
+	if _, err := store.Fork("missing", ForkOptions{}); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Fork of missing session = %v, want ErrSessionNotFound", err)
+	}
+}
@@ -0,0 +1,396 @@
+package session
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/inercia/mitto/internal/fileutil"
+	"github.com/inercia/mitto/internal/logging"
+)
+
+// ErrNotExportBundle is returned when import data is not a JSON export bundle.
+var ErrNotExportBundle = errors.New("not a Mitto JSON export bundle (export with format json)")
+
+// ErrExportBundleTooLarge is returned when an export bundle, or the uncompressed
+// contents of a zipped one, exceed MaxExportBundleSize.
+var ErrExportBundleTooLarge = errors.New("export bundle is too large")
+
+// MaxExportBundleSize is the maximum size of an export bundle, and of the
+// uncompressed contents of a zipped one, accepted by ReadExportBundle.
+const MaxExportBundleSize = 512 * 1024 * 1024 // 512 MB
+
+// ReadExportBundle parses a JSON export bundle, either as a JSON document or as a
+// zip archive created with ExportOptions.Zip (attachments are loaded from the archive).
+func ReadExportBundle(data []byte) (*ExportBundle, error) {
+	return ReadExportBundleFrom(bytes.NewReader(data), int64(len(data)))
+}
+
+// ReadExportBundleFrom is like ReadExportBundle, reading the bundle from r
+// (e.g. a file) instead of memory.
+func ReadExportBundleFrom(r io.ReaderAt, size int64) (*ExportBundle, error) {
+	var bundle ExportBundle
+
+	if size > MaxExportBundleSize {
+		return nil, ErrExportBundleTooLarge
+	}
+	header := make([]byte, 4)
+	if n, _ := r.ReadAt(header, 0); !bytes.Equal(header[:n], []byte("PK\x03\x04")) {
+		if err := json.NewDecoder(io.NewSectionReader(r, 0, size)).Decode(&bundle); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotExportBundle, err)
+		}
+		return &bundle, validateExportBundle(&bundle)
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	// The size of the entries is checked as they are read: the sizes in the
+	// archive headers can't be trusted.
+	remaining := int64(MaxExportBundleSize)
+
+	doc := entries[exportDocumentName+"."+string(ExportFormatJSON)]
+	if doc == nil {
+		return nil, ErrNotExportBundle
+	}
+	docData, err := readZipEntry(doc, &remaining)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(docData, &bundle); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotExportBundle, err)
+	}
+
+	for i := range bundle.Attachments {
+		a := &bundle.Attachments[i]
+		if a.Path == "" || len(a.Data) > 0 {
+			continue
+		}
+		f := entries[a.Path]
+		if f == nil {
+			return nil, fmt.Errorf("attachment %s not found in archive", a.Path)
+		}
+		if a.Data, err = readZipEntry(f, &remaining); err != nil {
+			return nil, err
+		}
+	}
+	return &bundle, validateExportBundle(&bundle)
+}
+
+// readZipEntry reads a zip entry of at most *remaining bytes once
+// uncompressed, and subtracts its size from *remaining.
+func readZipEntry(f *zip.File, remaining *int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(*remaining) {
+		return nil, ErrExportBundleTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, *remaining+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	if int64(len(data)) > *remaining {
+		return nil, ErrExportBundleTooLarge
+	}
+	*remaining -= int64(len(data))
+	return data, nil
+}
+
+// validateExportBundle checks that a bundle can be imported.
+func validateExportBundle(bundle *ExportBundle) error {
+	if bundle.Version < 1 || bundle.Version > ExportBundleVersion {
+		return fmt.Errorf("unsupported export bundle version %d (supported: 1-%d)", bundle.Version, ExportBundleVersion)
+	}
+	if bundle.Metadata.SessionID == "" {
+		return fmt.Errorf("%w: missing session metadata", ErrNotExportBundle)
+	}
+	for _, a := range bundle.Attachments {
+		if a.Kind != "image" && a.Kind != "file" {
+			return fmt.Errorf("invalid attachment kind %q", a.Kind)
+		}
+		if a.ID == "" || a.ID != filepath.Base(a.ID) || a.ID == "." || a.ID == ".." {
+			return fmt.Errorf("invalid attachment ID %q", a.ID)
+		}
+	}
+	return nil
+}
+
+// ImportOptions configures a session import.
+type ImportOptions struct {
+	// MigrationContext is passed to the session migrations run on imported data,
+	// so that sessions from other machines or older versions are normalized
+	// (e.g. ACP server names) to the local configuration.
+	MigrationContext *MigrationContext
+	// WorkingDir, if set, replaces the workspace folder of the imported sessions
+	// (the original folder usually doesn't exist on this machine).
+	WorkingDir string
+	// ACPServer, if set, replaces the ACP server of the imported sessions.
+	ACPServer string
+	// AdvancedSettings are the flags of the imported sessions, usually the
+	// defaults of this machine (see DefaultFlagValues). The flags in the bundle
+	// are never imported.
+	AdvancedSettings map[string]bool
+}
+
+// ImportResult describes an imported session.
+type ImportResult struct {
+	// OriginalSessionID is the session ID in the bundle.
+	OriginalSessionID string `json:"original_session_id"`
+	// SessionID is the ID of the new session.
+	SessionID string `json:"session_id"`
+	// Events is the number of events imported.
+	Events int `json:"events"`
+	// Renumbered is true if event sequence numbers had to be reassigned.
+	Renumbered bool `json:"renumbered,omitempty"`
+}
+
+// Import creates new sessions from export bundles.
+//
+// Every imported session gets a new session ID. ParentSessionID links between
+// sessions imported together are remapped to the new IDs; links to sessions that
+// are not part of the import are dropped. The ACP session ID is cleared, since
+// agent-side sessions can't be resumed on another machine, and periodic prompts
+// are imported disabled so that they don't run on two machines at once.
+// Event sequence numbers are re-validated and the session migrations are run on
+// the imported data before it is written to the store.
+//
+// Either all the sessions are imported or none: if writing one fails, the
+// sessions already written are deleted.
+func (s *Store) Import(bundles []*ExportBundle, opts ImportOptions) ([]ImportResult, error) {
+	log := logging.Session()
+
+	idMap := make(map[string]string, len(bundles))
+	for _, bundle := range bundles {
+		if err := validateExportBundle(bundle); err != nil {
+			return nil, err
+		}
+		if _, dup := idMap[bundle.Metadata.SessionID]; dup {
+			return nil, fmt.Errorf("session %s appears more than once in the import", bundle.Metadata.SessionID)
+		}
+		idMap[bundle.Metadata.SessionID] = GenerateSessionID()
+	}
+
+	// Materialize the sessions in a temporary file store to run the migrations on them.
+	tmpDir, err := os.MkdirTemp("", "mitto-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	staging, err := newStoreBackend(StoreBackendFile, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	defer staging.close()
+
+	results := make([]ImportResult, 0, len(bundles))
+	for _, bundle := range bundles {
+		newID := idMap[bundle.Metadata.SessionID]
+		events, renumbered := normalizeImportedEvents(bundle.Events, newID)
+
+		meta := bundle.Metadata
+		meta.SessionID = newID
+		meta.ACPSessionID = ""
+		meta.Worktree = nil // The worktree belongs to the exporting machine
+		// Settings and state of the exporting machine
+		meta.AdvancedSettings = maps.Clone(opts.AdvancedSettings)
+		meta.RunnerType = ""
+		meta.RunnerRestricted = false
+		meta.Usage = nil
+		meta.Resources = nil
+		meta.ACPStartFailureCount = 0
+		if parentID, ok := idMap[meta.ParentSessionID]; ok {
+			meta.ParentSessionID = parentID
+		} else {
+			meta.ParentSessionID = ""
+			meta.ChildOrigin = ""
+			meta.IsAutoChild = false
+		}
+		if opts.WorkingDir != "" {
+			meta.WorkingDir = opts.WorkingDir
+		}
+		if opts.ACPServer != "" {
+			meta.ACPServer = opts.ACPServer
+		}
+		if meta.CreatedAt.IsZero() {
+			meta.CreatedAt = time.Now()
+		}
+		if meta.UpdatedAt.IsZero() {
+			meta.UpdatedAt = meta.CreatedAt
+		}
+		meta.EventCount = len(events)
+		meta.MaxSeq = 0
+		if len(events) > 0 {
+			meta.MaxSeq = events[len(events)-1].Seq
+		}
+
+		if err := os.MkdirAll(filepath.Join(tmpDir, newID), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create session directory: %w", err)
+		}
+		if err := staging.replaceEvents(meta, events); err != nil {
+			return nil, fmt.Errorf("failed to stage session %s: %w", bundle.Metadata.SessionID, err)
+		}
+		results = append(results, ImportResult{
+			OriginalSessionID: bundle.Metadata.SessionID,
+			SessionID:         newID,
+			Events:            len(events),
+			Renumbered:        renumbered,
+		})
+	}
+
+	if err := RunMigrations(tmpDir, opts.MigrationContext); err != nil {
+		return nil, fmt.Errorf("failed to migrate imported sessions: %w", err)
+	}
+
+	imported := 0
+	rollback := func() {
+		for _, result := range results[:imported] {
+			if err := s.Delete(result.SessionID); err != nil {
+				log.Warn("failed to roll back imported session", "session_id", result.SessionID, "error", err)
+			}
+		}
+	}
+	for i, bundle := range bundles {
+		newID := results[i].SessionID
+		meta, err := staging.readMetadata(newID)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		events, err := staging.readEventsFrom(newID, 0, 0)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		if err := s.importSession(meta, events, bundle); err != nil {
+			rollback()
+			return nil, fmt.Errorf("failed to import session %s: %w", bundle.Metadata.SessionID, err)
+		}
+		imported++
+		log.Info("session imported",
+			"original_session_id", bundle.Metadata.SessionID,
+			"session_id", newID,
+			"events", len(events),
+			"renumbered", results[i].Renumbered)
+	}
+	return results, nil
+}
+
+// importSession writes an imported session and its per-session data to the store.
+// Nothing is left behind if it fails.
+func (s *Store) importSession(meta Metadata, events []Event, bundle *ExportBundle) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStoreClosed
+	}
+	if s.backend.exists(meta.SessionID) {
+		return fmt.Errorf("session %s already exists", meta.SessionID)
+	}
+
+	sessionDir := s.sessionDir(meta.SessionID)
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(sessionDir)
+		}
+	}()
+
+	for _, a := range bundle.Attachments {
+		dir := s.imagesDir(meta.SessionID)
+		if a.Kind == "file" {
+			dir = s.filesDir(meta.SessionID)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s directory: %w", a.Kind, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, a.ID), a.Data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s %s: %w", a.Kind, a.ID, err)
+		}
+	}
+
+	if len(bundle.Queue) > 0 {
+		if err := NewQueue(sessionDir).writeQueue(&QueueFile{Messages: bundle.Queue}); err != nil {
+			return err
+		}
+	}
+	if bundle.Periodic != nil {
+		periodic := *bundle.Periodic
+		periodic.Enabled = false
+		periodic.NextScheduledAt = nil
+		if err := fileutil.WriteJSONAtomic(filepath.Join(sessionDir, periodicFileName), &periodic, 0644); err != nil {
+			return fmt.Errorf("failed to write periodic file: %w", err)
+		}
+	}
+
+	// Write the event log last: the session only becomes visible once it is complete.
+	return s.backend.replaceEvents(meta, events)
+}
+
+// normalizeImportedEvents sorts events by sequence number and checks that the
+// sequence numbers are positive and unique. If they aren't, all events are
+// renumbered from 1 in their original order. Session start events are updated
+// to refer to the new session ID.
+func normalizeImportedEvents(events []Event, newSessionID string) ([]Event, bool) {
+	result := make([]Event, len(events))
+	copy(result, events)
+
+	valid := true
+	for _, e := range result {
+		if e.Seq <= 0 {
+			valid = false
+			break
+		}
+	}
+	if valid {
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Seq < result[j].Seq })
+		for i := 1; i < len(result); i++ {
+			if result[i].Seq == result[i-1].Seq {
+				valid = false
+				break
+			}
+		}
+	}
+	if !valid {
+		// Keep the original (file) order, which is the order events were recorded in.
+		copy(result, events)
+		for i := range result {
+			result[i].Seq = int64(i + 1)
+		}
+	}
+
+	for i := range result {
+		if result[i].Type != EventTypeSessionStart {
+			continue
+		}
+		if data, ok := result[i].Data.(map[string]any); ok {
+			updated := make(map[string]any, len(data))
+			for k, v := range data {
+				updated[k] = v
+			}
+			updated["session_id"] = newSessionID
+			result[i].Data = updated
+		}
+	}
+	return result, !valid
+}
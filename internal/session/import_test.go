@@ -0,0 +1,259 @@
+package session
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportBundle(t *testing.T, store *Store, sessionID string, zip bool) *ExportBundle {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := store.Export(&buf, sessionID, ExportOptions{Format: ExportFormatJSON, Zip: zip}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	bundle, err := ReadExportBundle(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadExportBundle failed: %v", err)
+	}
+	return bundle
+}
+
+func TestStore_ImportRoundTrip(t *testing.T) {
+	for _, zip := range []bool{false, true} {
+		src, sessionID := newExportTestStore(t)
+		if _, err := src.Queue(sessionID).Add("queued message", nil, nil, "", nil, 0, nil, ""); err != nil {
+			t.Fatalf("Queue Add failed: %v", err)
+		}
+		if err := src.Periodic(sessionID).Set(&PeriodicPrompt{
+			Prompt:    "check status",
+			Frequency: Frequency{Value: 1, Unit: FrequencyHours},
+			Enabled:   true,
+		}); err != nil {
+			t.Fatalf("Periodic Set failed: %v", err)
+		}
+		if err := src.UpdateMetadata(sessionID, func(m *Metadata) { m.ACPSessionID = "acp-123" }); err != nil {
+			t.Fatalf("UpdateMetadata failed: %v", err)
+		}
+		bundle := exportBundle(t, src, sessionID, zip)
+
+		dst := newSearchTestStore(t)
+		results, err := dst.Import([]*ExportBundle{bundle}, ImportOptions{WorkingDir: "/elsewhere"})
+		if err != nil {
+			t.Fatalf("Import (zip=%v) failed: %v", zip, err)
+		}
+		if len(results) != 1 || results[0].OriginalSessionID != sessionID || results[0].SessionID == sessionID || results[0].Events != 7 {
+			t.Fatalf("results = %+v", results)
+		}
+		newID := results[0].SessionID
+
+		meta, err := dst.GetMetadata(newID)
+		if err != nil {
+			t.Fatalf("GetMetadata failed: %v", err)
+		}
+		if meta.Name != "Fix the Parser!" || meta.WorkingDir != "/elsewhere" || meta.ACPSessionID != "" || meta.EventCount != 7 || meta.MaxSeq != 7 {
+			t.Errorf("imported metadata = %+v", meta)
+		}
+
+		events, err := dst.ReadEvents(newID)
+		if err != nil || len(events) != 7 || events[0].Seq != 1 || events[6].Seq != 7 {
+			t.Errorf("imported events = %d (err %v)", len(events), err)
+		}
+		if images, _ := dst.ListImages(newID); len(images) != 1 {
+			t.Errorf("imported images = %d, want 1", len(images))
+		}
+		if files, _ := dst.ListFiles(newID); len(files) != 1 {
+			t.Errorf("imported files = %d, want 1", len(files))
+		}
+		if queue, _ := dst.Queue(newID).List(); len(queue) != 1 || queue[0].Message != "queued message" {
+			t.Errorf("imported queue = %+v", queue)
+		}
+		periodic, err := dst.Periodic(newID).Get()
+		if err != nil || periodic.Prompt != "check status" || periodic.Enabled {
+			t.Errorf("imported periodic = %+v (err %v), want disabled copy", periodic, err)
+		}
+	}
+}
+
+func TestStore_ImportRemapsParents(t *testing.T) {
+	src := newSearchTestStore(t)
+	for _, meta := range []Metadata{
+		{SessionID: "parent", ACPServer: "auggie"},
+		{SessionID: "child", ACPServer: "auggie", ParentSessionID: "parent", ChildOrigin: ChildOriginMCP},
+		{SessionID: "orphan", ACPServer: "auggie", ParentSessionID: "not-exported", ChildOrigin: ChildOriginAuto},
+	} {
+		if err := src.Create(meta); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	bundles := []*ExportBundle{
+		exportBundle(t, src, "child", false),
+		exportBundle(t, src, "parent", false),
+		exportBundle(t, src, "orphan", false),
+	}
+
+	dst := newSearchTestStore(t)
+	results, err := dst.Import(bundles, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	ids := make(map[string]string)
+	for _, r := range results {
+		ids[r.OriginalSessionID] = r.SessionID
+	}
+
+	child, _ := dst.GetMetadata(ids["child"])
+	if child.ParentSessionID != ids["parent"] || child.ChildOrigin != ChildOriginMCP {
+		t.Errorf("child parent = %q (%q), want %q", child.ParentSessionID, child.ChildOrigin, ids["parent"])
+	}
+	orphan, _ := dst.GetMetadata(ids["orphan"])
+	if orphan.ParentSessionID != "" || orphan.ChildOrigin != "" {
+		t.Errorf("orphan parent = %q (%q), want none", orphan.ParentSessionID, orphan.ChildOrigin)
+	}
+
+	// Importing the same session twice in one call is rejected
+	if _, err := dst.Import([]*ExportBundle{bundles[0], bundles[0]}, ImportOptions{}); err == nil {
+		t.Error("Import with duplicate sessions should fail")
+	}
+}
+
+func TestStore_ImportRunsMigrations(t *testing.T) {
+	bundle := &ExportBundle{
+		Version:  ExportBundleVersion,
+		Metadata: Metadata{SessionID: "old", ACPServer: "auggie"},
+	}
+	dst := newSearchTestStore(t)
+	results, err := dst.Import([]*ExportBundle{bundle}, ImportOptions{
+		MigrationContext: NewMigrationContext([]string{"Auggie (Opus 4.5)"}),
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	meta, _ := dst.GetMetadata(results[0].SessionID)
+	if meta.ACPServer != "Auggie (Opus 4.5)" {
+		t.Errorf("ACPServer = %q, want it normalized by migrations", meta.ACPServer)
+	}
+}
+
+func TestStore_ImportResetsLocalState(t *testing.T) {
+	bundle := &ExportBundle{
+		Version: ExportBundleVersion,
+		Metadata: Metadata{
+			SessionID:            "old",
+			AdvancedSettings:     map[string]bool{FlagAutoApprovePermissions: true},
+			RunnerType:           "exec",
+			RunnerRestricted:     true,
+			ACPStartFailureCount: 3,
+			Usage:                map[string]UsageTotals{"2024-01-15": {Turns: 4}},
+			Resources:            map[string]ResourceTotals{"2024-01-15": {Prompts: 4}},
+		},
+	}
+	dst := newSearchTestStore(t)
+	results, err := dst.Import([]*ExportBundle{bundle}, ImportOptions{
+		AdvancedSettings: map[string]bool{FlagCanSendPrompt: true},
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	meta, _ := dst.GetMetadata(results[0].SessionID)
+	if len(meta.AdvancedSettings) != 1 || !meta.AdvancedSettings[FlagCanSendPrompt] {
+		t.Errorf("AdvancedSettings = %v, want the import defaults", meta.AdvancedSettings)
+	}
+	if meta.RunnerType != "" || meta.RunnerRestricted || meta.ACPStartFailureCount != 0 || meta.Usage != nil || meta.Resources != nil {
+		t.Errorf("imported metadata kept the state of the exporting machine: %+v", meta)
+	}
+}
+
+func TestNormalizeImportedEvents(t *testing.T) {
+	ts := time.Now()
+	// Out of order but valid: sorted, gaps preserved
+	events, renumbered := normalizeImportedEvents([]Event{
+		{Seq: 5, Type: EventTypeUserPrompt, Timestamp: ts},
+		{Seq: 2, Type: EventTypeSessionStart, Data: map[string]any{"session_id": "old"}},
+	}, "new")
+	if renumbered || events[0].Seq != 2 || events[1].Seq != 5 {
+		t.Errorf("events = %+v, renumbered %v; want sorted seqs 2, 5", events, renumbered)
+	}
+	if data := events[0].Data.(map[string]any); data["session_id"] != "new" {
+		t.Errorf("session_start session_id = %v, want new", data["session_id"])
+	}
+
+	// Duplicates and missing seqs: renumbered in original order
+	events, renumbered = normalizeImportedEvents([]Event{
+		{Seq: 3, Type: EventTypeUserPrompt},
+		{Seq: 3, Type: EventTypeAgentMessage},
+		{Seq: 0, Type: EventTypeAgentThought},
+	}, "new")
+	if !renumbered || events[0].Seq != 1 || events[2].Seq != 3 || events[2].Type != EventTypeAgentThought {
+		t.Errorf("events = %+v, renumbered %v; want 1..3 in original order", events, renumbered)
+	}
+}
+
+func TestReadExportBundle_Invalid(t *testing.T) {
+	src, sessionID := newExportTestStore(t)
+	var buf bytes.Buffer
+	if err := src.Export(&buf, sessionID, ExportOptions{Format: ExportFormatMarkdown, Zip: true}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if _, err := ReadExportBundle(buf.Bytes()); !errors.Is(err, ErrNotExportBundle) {
+		t.Errorf("ReadExportBundle(markdown zip) = %v, want ErrNotExportBundle", err)
+	}
+	if _, err := ReadExportBundle([]byte("# not json")); !errors.Is(err, ErrNotExportBundle) {
+		t.Errorf("ReadExportBundle(markdown) = %v, want ErrNotExportBundle", err)
+	}
+	if _, err := ReadExportBundle([]byte(`{"version": 99, "metadata": {"session_id": "x"}}`)); err == nil {
+		t.Error("ReadExportBundle with a future version should fail")
+	}
+	if _, err := ReadExportBundle([]byte(`{"version": 1, "metadata": {"session_id": "x"}, "attachments": [{"kind": "image", "id": "../evil"}]}`)); err == nil {
+		t.Error("ReadExportBundle with a path in an attachment ID should fail")
+	}
+}
+
+func TestStore_ImportRollsBackOnFailure(t *testing.T) {
+	bundles := []*ExportBundle{
+		{Version: ExportBundleVersion, Metadata: Metadata{SessionID: "first"}},
+		// The attachment can't be written: its name is too long for the file system
+		{Version: ExportBundleVersion, Metadata: Metadata{SessionID: "second"}, Attachments: []ExportAttachment{
+			{Kind: "file", ID: strings.Repeat("x", 300), Data: []byte("data")},
+		}},
+	}
+	dst := newSearchTestStore(t)
+	if _, err := dst.Import(bundles, ImportOptions{}); err == nil {
+		t.Fatal("Import should fail")
+	}
+	if sessions, _ := dst.List(); len(sessions) != 0 {
+		t.Errorf("sessions after a failed import = %+v, want none", sessions)
+	}
+	entries, _ := os.ReadDir(dst.BaseDir())
+	for _, e := range entries {
+		if e.IsDir() {
+			t.Errorf("session directory %s left behind by a failed import", e.Name())
+		}
+	}
+}
+
+func TestReadZipEntry_SizeLimit(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("data")
+	w.Write(bytes.Repeat([]byte("a"), 1000))
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := zr.File[0]
+
+	remaining := int64(1500)
+	if data, err := readZipEntry(entry, &remaining); err != nil || len(data) != 1000 || remaining != 500 {
+		t.Fatalf("readZipEntry() = %d bytes, %v, remaining %d", len(data), err, remaining)
+	}
+	if _, err := readZipEntry(entry, &remaining); !errors.Is(err, ErrExportBundleTooLarge) {
+		t.Errorf("readZipEntry() over the limit = %v, want ErrExportBundleTooLarge", err)
+	}
+
+}
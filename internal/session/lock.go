@@ -234,8 +234,7 @@ func (s *Store) writeLockFile(path string, info LockInfo) error {
 
 // sessionExistsLocked checks if a session exists (must be called with lock held).
 func (s *Store) sessionExistsLocked(sessionID string) bool {
-	_, err := os.Stat(s.metadataPath(sessionID))
-	return err == nil
+	return s.backend.exists(sessionID)
 }
 
 // --- Lock methods ---
@@ -1,8 +1,6 @@
 package session
 
 import (
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/inercia/mitto/internal/logging"
@@ -23,7 +21,7 @@ func init() {
 //
 // The migration uses the ACPServerNames map from context to determine mappings.
 // If no context is provided or no mapping matches, names are left unchanged.
-func migrateNormalizeACPServerNames(baseDir string, ctx *MigrationContext) (int, error) {
+func migrateNormalizeACPServerNames(store *Store, ctx *MigrationContext) (int, error) {
 	log := logging.Session()
 
 	// If no context or no server name mappings, skip this migration
@@ -39,25 +37,14 @@ func migrateNormalizeACPServerNames(baseDir string, ctx *MigrationContext) (int,
 		lowerToCanonical[strings.ToLower(old)] = new
 	}
 
-	entries, err := os.ReadDir(baseDir)
+	// Sessions without valid metadata are skipped by the backend
+	sessions, err := store.backend.list()
 	if err != nil {
 		return 0, err
 	}
 
 	modified := 0
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		// Skip non-session directories (like migrations.json if it were a dir)
-		sessionDir := filepath.Join(baseDir, entry.Name())
-		meta, err := readSessionMetadata(sessionDir)
-		if err != nil {
-			// Skip directories without valid metadata
-			continue
-		}
-
+	for _, meta := range sessions {
 		// Check if this session's ACP server needs normalization
 		oldName := meta.ACPServer
 		if oldName == "" {
@@ -82,7 +69,7 @@ func migrateNormalizeACPServerNames(baseDir string, ctx *MigrationContext) (int,
 			"new_name", newName)
 
 		meta.ACPServer = newName
-		if err := writeSessionMetadata(sessionDir, meta); err != nil {
+		if err := store.backend.writeMetadata(meta); err != nil {
 			log.Warn("failed to update session metadata",
 				"session_id", meta.SessionID,
 				"error", err)
@@ -58,7 +58,7 @@ func TestMigrateNormalizeACPServerNames(t *testing.T) {
 	}
 
 	// Run the migration
-	modified, err := migrateNormalizeACPServerNames(tmpDir, ctx)
+	modified, err := migrateNormalizeACPServerNames(openMigrationTestStore(t, tmpDir), ctx)
 	if err != nil {
 		t.Fatalf("migration failed: %v", err)
 	}
@@ -91,7 +91,7 @@ func TestMigrateNormalizeACPServerNames_NoContext(t *testing.T) {
 	defer os.RemoveAll(tmpDir)
 
 	// With nil context, should return 0 and not error
-	modified, err := migrateNormalizeACPServerNames(tmpDir, nil)
+	modified, err := migrateNormalizeACPServerNames(openMigrationTestStore(t, tmpDir), nil)
 	if err != nil {
 		t.Fatalf("migration with nil context failed: %v", err)
 	}
@@ -100,7 +100,7 @@ func TestMigrateNormalizeACPServerNames_NoContext(t *testing.T) {
 	}
 
 	// With empty context, should return 0 and not error
-	modified, err = migrateNormalizeACPServerNames(tmpDir, &MigrationContext{})
+	modified, err = migrateNormalizeACPServerNames(openMigrationTestStore(t, tmpDir), &MigrationContext{})
 	if err != nil {
 		t.Fatalf("migration with empty context failed: %v", err)
 	}
@@ -137,7 +137,7 @@ func TestMigrateNormalizeACPServerNames_CaseInsensitive(t *testing.T) {
 		},
 	}
 
-	modified, err := migrateNormalizeACPServerNames(tmpDir, ctx)
+	modified, err := migrateNormalizeACPServerNames(openMigrationTestStore(t, tmpDir), ctx)
 	if err != nil {
 		t.Fatalf("migration failed: %v", err)
 	}
@@ -151,3 +151,37 @@ func TestMigrateNormalizeACPServerNames_CaseInsensitive(t *testing.T) {
 		t.Errorf("expected ACPServer='Auggie (New Name)', got %q", meta.ACPServer)
 	}
 }
+
+func openMigrationTestStore(t *testing.T, baseDir string) *Store {
+	t.Helper()
+	store, err := NewStore(baseDir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestMigrateNormalizeACPServerNames_SQLite(t *testing.T) {
+	store, err := NewStoreWithBackend(t.TempDir(), StoreBackendSQLite)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Create(Metadata{SessionID: "session-1", ACPServer: "auggie"}); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	if err := store.RunMigrations(NewMigrationContext([]string{"Auggie (Opus 4.5)"})); err != nil {
+		t.Fatalf("RunMigrations failed: %v", err)
+	}
+
+	meta, err := store.GetMetadata("session-1")
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if meta.ACPServer != "Auggie (Opus 4.5)" {
+		t.Errorf("expected ACPServer='Auggie (Opus 4.5)', got %q", meta.ACPServer)
+	}
+}
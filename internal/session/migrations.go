@@ -68,8 +68,9 @@ type Migration struct {
 	// Description explains what this migration does
 	Description string
 	// Run executes the migration on all sessions. Returns the number of sessions modified.
-	// The function receives the base directory containing all session folders.
-	Run func(baseDir string, ctx *MigrationContext) (int, error)
+	// The function receives the store, with its lock held: it must use the
+	// store backend directly rather than the Store methods.
+	Run func(store *Store, ctx *MigrationContext) (int, error)
 }
 
 // MigrationState tracks which migrations have been applied.
@@ -87,9 +88,10 @@ func RegisterMigration(m Migration) {
 	migrationRegistry = append(migrationRegistry, m)
 }
 
-// RunMigrations runs all pending migrations on the session store.
+// RunMigrations runs all pending migrations on the file sessions in baseDir.
 // It tracks which migrations have been applied to avoid re-running them.
 // The context parameter is optional and provides external information to migrations.
+// Use Store.RunMigrations for stores with other backends.
 //
 // NOTE: Migrations do not support rollback. If a migration fails, it may leave
 // sessions in a partially modified state. Migrations should be designed to be
@@ -97,10 +99,22 @@ func RegisterMigration(m Migration) {
 // TODO: Consider adding rollback capability or backup-before-migrate for
 // data-modifying migrations in the future.
 func RunMigrations(baseDir string, ctx *MigrationContext) error {
+	store, err := NewStore(baseDir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return store.RunMigrations(ctx)
+}
+
+// runMigrations runs the pending migrations on a store (must be called with
+// the store lock held). The migration state is kept in the base directory of
+// the store for every backend.
+func runMigrations(store *Store, ctx *MigrationContext) error {
 	log := logging.Session()
 
 	// Load migration state
-	statePath := filepath.Join(baseDir, migrationsFileName)
+	statePath := filepath.Join(store.baseDir, migrationsFileName)
 	state, err := loadMigrationState(statePath)
 	if err != nil {
 		return fmt.Errorf("failed to load migration state: %w", err)
@@ -116,7 +130,7 @@ func RunMigrations(baseDir string, ctx *MigrationContext) error {
 			"name", migration.Name,
 			"description", migration.Description)
 
-		modified, err := migration.Run(baseDir, ctx)
+		modified, err := migration.Run(store, ctx)
 		if err != nil {
 			return fmt.Errorf("migration %s failed: %w", migration.Name, err)
 		}
@@ -71,7 +71,7 @@ func TestRunMigrations(t *testing.T) {
 	RegisterMigration(Migration{
 		Name:        "001_first",
 		Description: "First test migration",
-		Run: func(baseDir string, ctx *MigrationContext) (int, error) {
+		Run: func(store *Store, ctx *MigrationContext) (int, error) {
 			ran = append(ran, "001_first")
 			return 1, nil
 		},
@@ -79,7 +79,7 @@ func TestRunMigrations(t *testing.T) {
 	RegisterMigration(Migration{
 		Name:        "002_second",
 		Description: "Second test migration",
-		Run: func(baseDir string, ctx *MigrationContext) (int, error) {
+		Run: func(store *Store, ctx *MigrationContext) (int, error) {
 			ran = append(ran, "002_second")
 			return 2, nil
 		},
@@ -42,7 +42,8 @@ type Frequency struct {
 	Value int `json:"value"`
 	// Unit is the time unit (minutes, hours, days).
 	Unit FrequencyUnit `json:"unit"`
-	// At is the time of day in HH:MM format (UTC). Only valid for days unit.
+	// At is the time of day in HH:MM format, in the time zone of the prompt
+	// (UTC by default). Only valid for days unit.
 	At string `json:"at,omitempty"`
 }
 
@@ -107,6 +108,8 @@ type PeriodicPrompt struct {
 	PromptName string `json:"prompt_name,omitempty"`
 	// Frequency defines how often the prompt should be sent.
 	Frequency Frequency `json:"frequency"`
+	// Schedule holds the cron expression, time zone and blackout windows.
+	Schedule
 	// Enabled indicates whether the periodic prompt is active.
 	Enabled bool `json:"enabled"`
 	// FreshContext indicates whether each scheduled run should start with a clean
@@ -140,7 +143,12 @@ func (p *PeriodicPrompt) Validate() error {
 	if p.MaxIterations < 0 {
 		return ErrInvalidMaxIterations
 	}
-	return p.Frequency.Validate()
+	if p.Cron == "" {
+		if err := p.Frequency.Validate(); err != nil {
+			return err
+		}
+	}
+	return p.Schedule.Validate()
 }
 
 // PeriodicStore manages the periodic prompt for a single session.
@@ -214,9 +222,10 @@ func (ps *PeriodicStore) Set(p *PeriodicPrompt) error {
 }
 
 // Update applies a partial update to the periodic prompt.
-// Only non-nil fields in the update are applied.
+// Only non-nil fields in the update are applied; a schedule replaces the
+// cron expression, time zone and blackout windows.
 // IterationCount is never modified by Update — it is managed exclusively by RecordSent.
-func (ps *PeriodicStore) Update(prompt *string, promptName *string, frequency *Frequency, enabled *bool, freshContext *bool, maxIterations *int) error {
+func (ps *PeriodicStore) Update(prompt *string, promptName *string, frequency *Frequency, enabled *bool, freshContext *bool, maxIterations *int, schedule *Schedule) error {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
@@ -243,6 +252,9 @@ func (ps *PeriodicStore) Update(prompt *string, promptName *string, frequency *F
 	if maxIterations != nil {
 		existing.MaxIterations = *maxIterations
 	}
+	if schedule != nil {
+		existing.Schedule = *schedule
+	}
 
 	if err := existing.Validate(); err != nil {
 		return err
@@ -294,6 +306,26 @@ func (ps *PeriodicStore) RecordSent() error {
 	return nil
 }
 
+// Reschedule recomputes next_scheduled_at as of now, without recording a run.
+// It is used to skip a run that became due inside a blackout window.
+func (ps *PeriodicStore) Reschedule() (*PeriodicPrompt, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	existing, err := ps.getUnlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	existing.UpdatedAt = time.Now().UTC()
+	existing.NextScheduledAt = ps.computeNextScheduledTime(existing)
+
+	if err := fileutil.WriteJSONAtomic(ps.periodicPath(), existing, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write periodic file: %w", err)
+	}
+	return existing, nil
+}
+
 // getUnlocked reads the periodic file without locking (caller must hold lock).
 func (ps *PeriodicStore) getUnlocked() (*PeriodicPrompt, error) {
 	var p PeriodicPrompt
@@ -309,54 +341,5 @@ func (ps *PeriodicStore) getUnlocked() (*PeriodicPrompt, error) {
 
 // computeNextScheduledTime calculates when the next prompt should be sent.
 func (ps *PeriodicStore) computeNextScheduledTime(p *PeriodicPrompt) *time.Time {
-	if !p.Enabled {
-		return nil
-	}
-
-	now := time.Now().UTC()
-	var next time.Time
-
-	if p.LastSentAt == nil {
-		// Never sent before - schedule based on current time
-		if p.Frequency.Unit == FrequencyDays && p.Frequency.At != "" {
-			// For days with 'at', schedule for next occurrence of that time
-			next = ps.nextTimeAt(now, p.Frequency.At, p.Frequency.Value)
-		} else {
-			// For other units, schedule after one interval
-			next = now.Add(p.Frequency.Duration())
-		}
-	} else {
-		// Sent before - schedule next based on last sent time
-		if p.Frequency.Unit == FrequencyDays && p.Frequency.At != "" {
-			next = ps.nextTimeAt(*p.LastSentAt, p.Frequency.At, p.Frequency.Value)
-		} else {
-			next = p.LastSentAt.Add(p.Frequency.Duration())
-		}
-		// If computed time is in the past, schedule from now
-		if next.Before(now) {
-			if p.Frequency.Unit == FrequencyDays && p.Frequency.At != "" {
-				next = ps.nextTimeAt(now, p.Frequency.At, p.Frequency.Value)
-			} else {
-				next = now.Add(p.Frequency.Duration())
-			}
-		}
-	}
-
-	return &next
-}
-
-// nextTimeAt computes the next occurrence of a specific time (HH:MM UTC).
-func (ps *PeriodicStore) nextTimeAt(from time.Time, at string, days int) time.Time {
-	var h, m int
-	fmt.Sscanf(at, "%d:%d", &h, &m)
-
-	// Start with today at the specified time
-	next := time.Date(from.Year(), from.Month(), from.Day(), h, m, 0, 0, time.UTC)
-
-	// If that time has passed today, move to next occurrence
-	if !next.After(from) {
-		next = next.AddDate(0, 0, days)
-	}
-
-	return next
+	return p.nextScheduledTime(time.Now().UTC())
 }
@@ -0,0 +1,324 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ErrInvalidSchedule is returned when the cron expression, time zone or blackout
+// windows of a periodic prompt are invalid.
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+const (
+	// maxScheduleSteps bounds the search for a fire time outside the blackout windows.
+	maxScheduleSteps = 10000
+	// MaxPreviewRuns is the maximum number of fire times returned by NextRuns.
+	MaxPreviewRuns = 100
+)
+
+// Schedule holds the calendar options of a periodic prompt.
+type Schedule struct {
+	// Cron is a standard 5-field cron expression (minute, hour, day of month,
+	// month, day of week), e.g. "0 9 * * 1-5". When set, Frequency is ignored.
+	Cron string `json:"cron,omitempty"`
+	// TimeZone is the IANA time zone (e.g. "Europe/Madrid") of Cron, Frequency.At
+	// and the blackout windows. Defaults to UTC.
+	TimeZone string `json:"timezone,omitempty"`
+	// Blackouts are windows during which the prompt is never sent: runs falling
+	// inside a window are moved to the first fire time after it.
+	Blackouts []BlackoutWindow `json:"blackouts,omitempty"`
+}
+
+// Validate checks the cron expression, the time zone and the blackout windows.
+func (s *Schedule) Validate() error {
+	if s.Cron != "" {
+		if _, err := cron.ParseStandard(s.Cron); err != nil {
+			return fmt.Errorf("%w: cron: %v", ErrInvalidSchedule, err)
+		}
+	}
+	if s.TimeZone != "" {
+		if _, err := time.LoadLocation(s.TimeZone); err != nil {
+			return fmt.Errorf("%w: unknown time zone %q", ErrInvalidSchedule, s.TimeZone)
+		}
+	}
+	for i := range s.Blackouts {
+		if err := s.Blackouts[i].Validate(); err != nil {
+			return fmt.Errorf("%w: blackout %d: %v", ErrInvalidSchedule, i+1, err)
+		}
+	}
+	return nil
+}
+
+// Location returns the time zone of the schedule (UTC if unset or invalid).
+func (s *Schedule) Location() *time.Location {
+	if s.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// InBlackout reports whether t falls inside one of the blackout windows.
+func (s *Schedule) InBlackout(t time.Time) bool {
+	return s.blackoutAt(t.In(s.Location())) != nil
+}
+
+// blackoutAt returns the blackout window containing t (in the schedule location), if any.
+func (s *Schedule) blackoutAt(t time.Time) *BlackoutWindow {
+	for i := range s.Blackouts {
+		if s.Blackouts[i].Contains(t) {
+			return &s.Blackouts[i]
+		}
+	}
+	return nil
+}
+
+// BlackoutWindow is a recurring period of time, in the schedule time zone.
+type BlackoutWindow struct {
+	// Days restricts the window to some days of the week ("mon", "tue"...).
+	// Empty means every day.
+	Days []string `json:"days,omitempty"`
+	// Start and End are the times of day (HH:MM) the window starts and ends.
+	// An End before the Start ends the window on the next day. Both empty means
+	// the whole day.
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// From and Until restrict the window to a range of dates (YYYY-MM-DD, inclusive).
+	From  string `json:"from,omitempty"`
+	Until string `json:"until,omitempty"`
+}
+
+// weekdays maps the accepted day names to weekdays.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Validate checks the days, times and dates of the window.
+func (b *BlackoutWindow) Validate() error {
+	for _, d := range b.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("unknown day %q", d)
+		}
+	}
+	if (b.Start == "") != (b.End == "") {
+		return errors.New("start and end must be set together")
+	}
+	for _, hm := range []string{b.Start, b.End} {
+		if _, err := parseClock(hm); hm != "" && err != nil {
+			return err
+		}
+	}
+	for _, date := range []string{b.From, b.Until} {
+		if _, err := time.Parse(time.DateOnly, date); date != "" && err != nil {
+			return fmt.Errorf("date %q must be in YYYY-MM-DD format", date)
+		}
+	}
+	return nil
+}
+
+// bounds returns the start and end of the window as minutes since midnight.
+func (b *BlackoutWindow) bounds() (start, end int) {
+	if b.Start == "" {
+		return 0, 24 * 60
+	}
+	start, _ = parseClock(b.Start)
+	end, _ = parseClock(b.End)
+	return start, end
+}
+
+// activeOn reports whether a window starting on the day of t applies.
+func (b *BlackoutWindow) activeOn(t time.Time) bool {
+	if len(b.Days) > 0 {
+		found := false
+		for _, d := range b.Days {
+			if weekdays[strings.ToLower(d)] == t.Weekday() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	date := t.Format(time.DateOnly)
+	return (b.From == "" || date >= b.From) && (b.Until == "" || date <= b.Until)
+}
+
+// occurrence returns the start of the occurrence of the window containing t,
+// and whether t is inside the window.
+func (b *BlackoutWindow) occurrence(t time.Time) (time.Time, bool) {
+	start, end := b.bounds()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	minute := t.Hour()*60 + t.Minute()
+	if start < end {
+		return day, minute >= start && minute < end && b.activeOn(day)
+	}
+	// The window wraps past midnight (or lasts 24 hours when start == end)
+	if minute >= start && b.activeOn(day) {
+		return day, true
+	}
+	prev := day.AddDate(0, 0, -1)
+	return prev, minute < end && b.activeOn(prev)
+}
+
+// Contains reports whether t (in the schedule time zone) is inside the window.
+func (b *BlackoutWindow) Contains(t time.Time) bool {
+	_, in := b.occurrence(t)
+	return in
+}
+
+// endAfter returns the end of the occurrence of the window containing t.
+func (b *BlackoutWindow) endAfter(t time.Time) time.Time {
+	day, _ := b.occurrence(t)
+	start, end := b.bounds()
+	if start >= end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, end, 0, 0, day.Location())
+}
+
+// parseClock parses a time of day in HH:MM format into minutes since midnight.
+func parseClock(hm string) (int, error) {
+	t, err := time.Parse("15:04", hm)
+	if err != nil {
+		return 0, fmt.Errorf("time %q must be in HH:MM format", hm)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// nextAfter returns the first fire time after t, according to the cron
+// expression or the frequency, ignoring the blackout windows.
+// It returns the zero time when there is none.
+func (p *PeriodicPrompt) nextAfter(t time.Time) time.Time {
+	loc := p.Location()
+	switch {
+	case p.Cron != "":
+		sched, err := cron.ParseStandard(p.Cron)
+		if err != nil {
+			return time.Time{}
+		}
+		return sched.Next(t.In(loc)).UTC()
+	case p.Frequency.Unit == FrequencyDays && p.Frequency.At != "":
+		return nextTimeAt(t.In(loc), p.Frequency.At, p.Frequency.Value).UTC()
+	default:
+		if p.Frequency.Duration() <= 0 {
+			return time.Time{}
+		}
+		return t.Add(p.Frequency.Duration()).UTC()
+	}
+}
+
+// skipBlackouts moves a fire time falling inside a blackout window to the
+// first fire time after the window. Interval schedules fire when the window
+// ends; cron and daily schedules at their next time after it.
+func (p *PeriodicPrompt) skipBlackouts(t time.Time) (time.Time, bool) {
+	loc := p.Location()
+	anchored := p.Cron != "" || (p.Frequency.Unit == FrequencyDays && p.Frequency.At != "")
+	for i := 0; i < maxScheduleSteps; i++ {
+		if t.IsZero() {
+			return t, false
+		}
+		w := p.blackoutAt(t.In(loc))
+		if w == nil {
+			return t, true
+		}
+		end := w.endAfter(t.In(loc))
+		if anchored {
+			t = p.nextAfter(end.Add(-time.Nanosecond))
+		} else {
+			t = end.UTC()
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextScheduledTime calculates when the next prompt should be sent, as of now.
+// Returns nil when the prompt is disabled or never fires.
+func (p *PeriodicPrompt) nextScheduledTime(now time.Time) *time.Time {
+	if !p.Enabled {
+		return nil
+	}
+
+	var next time.Time
+	if p.LastSentAt == nil {
+		// Never sent before - schedule based on current time
+		next = p.nextAfter(now)
+	} else {
+		// Sent before - schedule next based on last sent time,
+		// or from now if the computed time is in the past
+		next = p.nextAfter(*p.LastSentAt)
+		if next.Before(now) {
+			next = p.nextAfter(now)
+		}
+	}
+
+	next, ok := p.skipBlackouts(next)
+	if !ok {
+		return nil
+	}
+	return &next
+}
+
+// NextRuns returns the next n times the prompt would be sent after from,
+// taking the blackout windows into account (at most MaxPreviewRuns).
+// The prompt is previewed as enabled, even if it is currently paused.
+func (p *PeriodicPrompt) NextRuns(from time.Time, n int) []time.Time {
+	n = min(n, MaxPreviewRuns)
+	sim := *p
+	sim.Enabled = true
+	var runs []time.Time
+	now := from
+	for len(runs) < n {
+		next := sim.nextScheduledTime(now)
+		if next == nil {
+			break
+		}
+		runs = append(runs, *next)
+		sim.LastSentAt = next
+		now = *next
+	}
+	return runs
+}
+
+// MissedRuns returns the number of fire times after scheduledAt and up to now
+// (at most maxScheduleSteps).
+func (p *PeriodicPrompt) MissedRuns(scheduledAt, now time.Time) int {
+	missed := 0
+	for t := scheduledAt; missed < maxScheduleSteps; missed++ {
+		t = p.nextAfter(t)
+		if t.IsZero() || t.After(now) {
+			break
+		}
+	}
+	return missed
+}
+
+// nextTimeAt computes the next occurrence of a specific time of day (HH:MM),
+// in the location of from.
+func nextTimeAt(from time.Time, at string, days int) time.Time {
+	var h, m int
+	fmt.Sscanf(at, "%d:%d", &h, &m)
+
+	// Start with today at the specified time
+	next := time.Date(from.Year(), from.Month(), from.Day(), h, m, 0, 0, from.Location())
+
+	// If that time has passed today, move to next occurrence
+	if !next.After(from) {
+		next = next.AddDate(0, 0, days)
+	}
+
+	return next
+}
@@ -0,0 +1,191 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		wantErr  bool
+	}{
+		{"empty", Schedule{}, false},
+		{"cron", Schedule{Cron: "0 9 * * 1-5"}, false},
+		{"cron descriptor", Schedule{Cron: "@daily"}, false},
+		{"cron six fields", Schedule{Cron: "0 0 9 * * 1-5"}, true},
+		{"cron garbage", Schedule{Cron: "every day"}, true},
+		{"time zone", Schedule{TimeZone: "Europe/Madrid"}, false},
+		{"unknown time zone", Schedule{TimeZone: "Mars/Olympus"}, true},
+		{"blackout", Schedule{Blackouts: []BlackoutWindow{{Days: []string{"sat", "Sunday"}}}}, false},
+		{"blackout unknown day", Schedule{Blackouts: []BlackoutWindow{{Days: []string{"someday"}}}}, true},
+		{"blackout start only", Schedule{Blackouts: []BlackoutWindow{{Start: "18:00"}}}, true},
+		{"blackout bad time", Schedule{Blackouts: []BlackoutWindow{{Start: "18:00", End: "25:00"}}}, true},
+		{"blackout bad date", Schedule{Blackouts: []BlackoutWindow{{From: "24/12/2026"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSchedule) {
+				t.Errorf("Validate() = %v, want ErrInvalidSchedule", err)
+			}
+		})
+	}
+}
+
+func TestPeriodicPrompt_ValidateCronReplacesFrequency(t *testing.T) {
+	p := &PeriodicPrompt{Prompt: "check", Schedule: Schedule{Cron: "0 9 * * *"}}
+	if err := p.Validate(); err != nil {
+		t.Errorf("Validate() with cron and no frequency = %v", err)
+	}
+	p.Cron = ""
+	if err := p.Validate(); !errors.Is(err, ErrInvalidFrequency) {
+		t.Errorf("Validate() without cron nor frequency = %v, want ErrInvalidFrequency", err)
+	}
+}
+
+func TestBlackoutWindow_Contains(t *testing.T) {
+	// 2026-10-16 is a Friday
+	at := func(day int, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name   string
+		window BlackoutWindow
+		t      time.Time
+		want   bool
+	}{
+		{"whole weekend day", BlackoutWindow{Days: []string{"sat", "sun"}}, at(17, 12, 0), true},
+		{"weekday", BlackoutWindow{Days: []string{"sat", "sun"}}, at(16, 12, 0), false},
+		{"inside hours", BlackoutWindow{Start: "12:00", End: "14:00"}, at(16, 13, 59), true},
+		{"end is exclusive", BlackoutWindow{Start: "12:00", End: "14:00"}, at(16, 14, 0), false},
+		{"night before midnight", BlackoutWindow{Start: "18:00", End: "08:00"}, at(16, 23, 0), true},
+		{"night after midnight", BlackoutWindow{Start: "18:00", End: "08:00"}, at(17, 7, 59), true},
+		{"daytime", BlackoutWindow{Start: "18:00", End: "08:00"}, at(16, 9, 0), false},
+		{"friday night into saturday", BlackoutWindow{Days: []string{"fri"}, Start: "22:00", End: "06:00"}, at(17, 3, 0), true},
+		{"saturday night", BlackoutWindow{Days: []string{"fri"}, Start: "22:00", End: "06:00"}, at(17, 23, 0), false},
+		{"date range", BlackoutWindow{From: "2026-10-15", Until: "2026-10-16"}, at(16, 12, 0), true},
+		{"after date range", BlackoutWindow{From: "2026-10-15", Until: "2026-10-16"}, at(17, 0, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Contains(tt.t); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.t.Format(time.RFC3339), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriodicPrompt_NextRunsCronTimeZone(t *testing.T) {
+	madrid := mustLoadLocation(t, "Europe/Madrid")
+	p := &PeriodicPrompt{
+		Schedule: Schedule{Cron: "0 9 * * 1-5", TimeZone: "Europe/Madrid"},
+	}
+	// Friday 2026-10-16 10:00 in Madrid
+	from := time.Date(2026, 10, 16, 10, 0, 0, 0, madrid)
+	runs := p.NextRuns(from, 3)
+	want := []time.Time{
+		time.Date(2026, 10, 19, 9, 0, 0, 0, madrid), // Monday
+		time.Date(2026, 10, 20, 9, 0, 0, 0, madrid),
+		time.Date(2026, 10, 21, 9, 0, 0, 0, madrid),
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("NextRuns() = %v, want %v", runs, want)
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) {
+			t.Errorf("run %d = %s, want %s", i, runs[i].In(madrid), want[i])
+		}
+		if runs[i].Location() != time.UTC {
+			t.Errorf("run %d is not in UTC: %s", i, runs[i])
+		}
+	}
+}
+
+func TestPeriodicPrompt_NextRunsIntervalWithBlackout(t *testing.T) {
+	// Every 2 hours between 08:00 and 18:00
+	p := &PeriodicPrompt{
+		Frequency: Frequency{Value: 2, Unit: FrequencyHours},
+		Schedule:  Schedule{Blackouts: []BlackoutWindow{{Start: "18:00", End: "08:00"}}},
+	}
+	from := time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC)
+	runs := p.NextRuns(from, 4)
+	want := []string{"16:00", "08:00", "10:00", "12:00"}
+	if len(runs) != len(want) {
+		t.Fatalf("NextRuns() = %v", runs)
+	}
+	for i := range want {
+		if got := runs[i].Format("15:04"); got != want[i] {
+			t.Errorf("run %d = %s, want %s", i, runs[i].Format(time.RFC3339), want[i])
+		}
+	}
+	if runs[1].Day() != 17 {
+		t.Errorf("run after the blackout = %s, want the next morning", runs[1])
+	}
+}
+
+func TestPeriodicPrompt_NextRunsDailyAtSkipsWeekend(t *testing.T) {
+	madrid := mustLoadLocation(t, "Europe/Madrid")
+	p := &PeriodicPrompt{
+		Frequency: Frequency{Value: 1, Unit: FrequencyDays, At: "09:00"},
+		Schedule: Schedule{
+			TimeZone:  "Europe/Madrid",
+			Blackouts: []BlackoutWindow{{Days: []string{"sat", "sun"}}},
+		},
+	}
+	// Friday 2026-10-16 10:00 in Madrid: the next run is on Monday at 09:00, not at midnight
+	runs := p.NextRuns(time.Date(2026, 10, 16, 10, 0, 0, 0, madrid), 1)
+	want := time.Date(2026, 10, 19, 9, 0, 0, 0, madrid)
+	if len(runs) != 1 || !runs[0].Equal(want) {
+		t.Errorf("NextRuns() = %v, want %s", runs, want)
+	}
+}
+
+func TestPeriodicPrompt_NextRunsAlwaysBlackedOut(t *testing.T) {
+	p := &PeriodicPrompt{
+		Frequency: Frequency{Value: 1, Unit: FrequencyHours},
+		Schedule:  Schedule{Blackouts: []BlackoutWindow{{}}},
+	}
+	if runs := p.NextRuns(time.Now(), 3); len(runs) != 0 {
+		t.Errorf("NextRuns() = %v, want none", runs)
+	}
+}
+
+func TestPeriodicPrompt_MissedRuns(t *testing.T) {
+	p := &PeriodicPrompt{Schedule: Schedule{Cron: "0 * * * *"}}
+	scheduledAt := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	if got := p.MissedRuns(scheduledAt, scheduledAt.Add(3*time.Hour+30*time.Minute)); got != 3 {
+		t.Errorf("MissedRuns() = %d, want 3", got)
+	}
+}
+
+func TestPeriodicStore_Reschedule(t *testing.T) {
+	ps := NewPeriodicStore(t.TempDir())
+	if err := ps.Set(&PeriodicPrompt{Prompt: "p", Enabled: true, Schedule: Schedule{Cron: "*/5 * * * *"}}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	p, err := ps.Reschedule()
+	if err != nil {
+		t.Fatalf("Reschedule: %v", err)
+	}
+	if p.NextScheduledAt == nil || p.NextScheduledAt.Minute()%5 != 0 || !p.NextScheduledAt.After(time.Now()) {
+		t.Errorf("NextScheduledAt = %v, want the next multiple of 5 minutes", p.NextScheduledAt)
+	}
+	if p.IterationCount != 0 || p.LastSentAt != nil {
+		t.Errorf("Reschedule recorded a run: %+v", p)
+	}
+}
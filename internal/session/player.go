@@ -44,6 +44,7 @@ var eventDataTypes = map[EventType]reflect.Type{
 	EventTypeError:          reflect.TypeOf(ErrorData{}),
 	EventTypeSessionStart:   reflect.TypeOf(SessionStartData{}),
 	EventTypeSessionEnd:     reflect.TypeOf(SessionEndData{}),
+	EventTypeUsage:          reflect.TypeOf(UsageData{}),
 }
 
 // DecodeEventData decodes the event data into the appropriate type.
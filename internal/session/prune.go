@@ -1,7 +1,6 @@
 package session
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -81,13 +80,11 @@ func (s *Store) pruneInternal(sessionID string, config *PruneConfig) (*PruneResu
 		return nil, nil
 	}
 
-	// Calculate current size of events file
-	eventsPath := s.eventsPath(sessionID)
-	fileInfo, err := os.Stat(eventsPath)
+	// Calculate current size of the event log
+	currentSize, err := s.backend.eventsSize(sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to stat events file: %w", err)
+		return nil, err
 	}
-	currentSize := fileInfo.Size()
 
 	// Also account for images in size calculation
 	_, imagesSize, err := s.listImagesInternal(sessionID)
@@ -146,30 +143,7 @@ func (s *Store) pruneInternal(sessionID string, config *PruneConfig) (*PruneResu
 
 // readEventsInternal reads events without locking (caller must hold lock).
 func (s *Store) readEventsInternal(sessionID string) ([]Event, error) {
-	f, err := os.Open(s.eventsPath(sessionID))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, ErrSessionNotFound
-		}
-		return nil, fmt.Errorf("failed to open events file: %w", err)
-	}
-	defer f.Close()
-
-	var events []Event
-	scanner := bufio.NewScanner(f)
-	const maxScannerBuffer = 10 * 1024 * 1024
-	scanner.Buffer(make([]byte, 0, 64*1024), maxScannerBuffer)
-	for scanner.Scan() {
-		var event Event
-		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
-		}
-		events = append(events, event)
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read events: %w", err)
-	}
-	return events, nil
+	return s.backend.readEventsFrom(sessionID, 0, 0)
 }
 
 // estimateEventsSize estimates the size of serialized events.
@@ -215,57 +189,35 @@ func (s *Store) performPrune(
 ) (*PruneResult, error) {
 	result := &PruneResult{}
 
-	// Get original file size for bytes reclaimed calculation
-	eventsPath := s.eventsPath(sessionID)
-	originalInfo, _ := os.Stat(eventsPath)
-	var originalSize int64
-	if originalInfo != nil {
-		originalSize = originalInfo.Size()
-	}
+	// Get original event log size for bytes reclaimed calculation
+	originalSize, _ := s.backend.eventsSize(sessionID)
 
-	// Rewrite events file preserving original sequence numbers.
-	// Seqs are monotonic global identifiers used by the WebSocket sync protocol
-	// (load_events after_seq). Renumbering breaks the invariant that seq values
-	// are stable identifiers — clients that have already seen seq N would never
-	// receive events between the pruned-away seq and the new file's max_seq.
-	tmpPath := eventsPath + ".tmp"
-	tmpFile, err := os.Create(tmpPath)
+	meta, err := s.readMetadata(sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp events file: %w", err)
-	}
-
-	for _, event := range remainingEvents {
-		// Keep original seq — do NOT renumber.
-		data, err := json.Marshal(event)
-		if err != nil {
-			tmpFile.Close()
-			os.Remove(tmpPath)
-			return nil, fmt.Errorf("failed to marshal event: %w", err)
-		}
-		if _, err := tmpFile.Write(append(data, '\n')); err != nil {
-			tmpFile.Close()
-			os.Remove(tmpPath)
-			return nil, fmt.Errorf("failed to write event: %w", err)
-		}
+		return nil, err
 	}
-
-	if err := tmpFile.Sync(); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return nil, fmt.Errorf("failed to sync temp events file: %w", err)
+	meta.EventCount = len(remainingEvents)
+	// Preserve MaxSeq as the highest seq among remaining events.
+	// Seqs are monotonic identifiers, not array positions, so MaxSeq must
+	// reflect the actual highest seq value in the log (not the event count).
+	if len(remainingEvents) > 0 {
+		meta.MaxSeq = remainingEvents[len(remainingEvents)-1].Seq
+	} else {
+		meta.MaxSeq = 0
 	}
-	tmpFile.Close()
 
-	// Rename temp file to replace original
-	if err := os.Rename(tmpPath, eventsPath); err != nil {
-		os.Remove(tmpPath)
-		return nil, fmt.Errorf("failed to rename temp events file: %w", err)
+	// Rewrite the event log preserving original sequence numbers.
+	// Seqs are monotonic global identifiers used by the WebSocket sync protocol
+	// (load_events after_seq). Renumbering breaks the invariant that seq values
+	// are stable identifiers — clients that have already seen seq N would never
+	// receive events between the pruned-away seq and the new log's max_seq.
+	if err := s.backend.replaceEvents(meta, remainingEvents); err != nil {
+		return nil, err
 	}
 
-	// Calculate bytes reclaimed from events file
-	newInfo, _ := os.Stat(eventsPath)
-	if newInfo != nil {
-		result.BytesReclaimed = originalSize - newInfo.Size()
+	// Calculate bytes reclaimed from the event log
+	if newSize, err := s.backend.eventsSize(sessionID); err == nil {
+		result.BytesReclaimed = originalSize - newSize
 	}
 
 	// Clean up orphaned images
@@ -281,23 +233,5 @@ func (s *Store) performPrune(
 		}
 	}
 
-	// Update metadata
-	meta, err := s.readMetadata(sessionID)
-	if err != nil {
-		return result, err
-	}
-	meta.EventCount = len(remainingEvents)
-	// Preserve MaxSeq as the highest seq among remaining events.
-	// Seqs are monotonic identifiers, not array positions, so MaxSeq must
-	// reflect the actual highest seq value in the file (not the event count).
-	if len(remainingEvents) > 0 {
-		meta.MaxSeq = remainingEvents[len(remainingEvents)-1].Seq
-	} else {
-		meta.MaxSeq = 0
-	}
-	if err := s.writeMetadata(meta); err != nil {
-		return result, err
-	}
-
 	return result, nil
 }
@@ -97,6 +97,9 @@ type QueuedMessage struct {
 	// PromptName is the name of the workspace prompt to send by name (resolved to
 	// full text at dispatch). Empty for ad-hoc messages.
 	PromptName string `json:"prompt_name,omitempty"`
+	// SentBy is the user who queued the message, when authenticated. It is
+	// recorded as the sender of the prompt.
+	SentBy string `json:"sent_by,omitempty"`
 }
 
 // QueueFile represents the persisted queue state.
@@ -173,6 +176,20 @@ func (q *Queue) writeQueue(qf *QueueFile) error {
 // If promptName is non-empty, the message is stored by name and resolved to full text
 // at dispatch via PromptWithMeta (message should be empty in this case).
 func (q *Queue) Add(message string, imageIDs, fileIDs []string, clientID string, scheduledTime *time.Time, maxSize int, arguments map[string]string, promptName string) (QueuedMessage, error) {
+	return q.AddMessage(QueuedMessage{
+		Message:       message,
+		ImageIDs:      imageIDs,
+		FileIDs:       fileIDs,
+		ClientID:      clientID,
+		ScheduledTime: scheduledTime,
+		Arguments:     arguments,
+		PromptName:    promptName,
+	}, maxSize)
+}
+
+// AddMessage adds a message to the end of the queue, assigning its ID and
+// QueuedAt time. It returns ErrQueueFull if the queue has maxSize messages.
+func (q *Queue) AddMessage(msg QueuedMessage, maxSize int) (QueuedMessage, error) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
@@ -186,17 +203,8 @@ func (q *Queue) Add(message string, imageIDs, fileIDs []string, clientID string,
 		return QueuedMessage{}, ErrQueueFull
 	}
 
-	msg := QueuedMessage{
-		ID:            generateMessageID(),
-		Message:       message,
-		ImageIDs:      imageIDs,
-		FileIDs:       fileIDs,
-		QueuedAt:      time.Now(),
-		ClientID:      clientID,
-		ScheduledTime: scheduledTime,
-		Arguments:     arguments,
-		PromptName:    promptName,
-	}
+	msg.ID = generateMessageID()
+	msg.QueuedAt = time.Now()
 
 	qf.Messages = append(qf.Messages, msg)
 
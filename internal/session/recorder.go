@@ -160,10 +160,15 @@ func (r *Recorder) RecordUserPromptWithImages(message string, images []ImageRef)
 // The promptID is a client-generated ID used for delivery confirmation on reconnect.
 // The promptName is the name of the workspace prompt used (for UI rendering); empty string means no named prompt.
 func (r *Recorder) RecordUserPromptComplete(message string, images []ImageRef, files []FileRef, promptID string, promptName string) error {
+	return r.RecordUserPromptData(UserPromptData{Message: message, Images: images, Files: files, PromptID: promptID, PromptName: promptName})
+}
+
+// RecordUserPromptData records a user prompt event with all its data (including who sent it).
+func (r *Recorder) RecordUserPromptData(data UserPromptData) error {
 	return r.recordEvent(Event{
 		Type:      EventTypeUserPrompt,
 		Timestamp: time.Now(),
-		Data:      UserPromptData{Message: message, Images: images, Files: files, PromptID: promptID, PromptName: promptName},
+		Data:      data,
 	})
 }
 
@@ -225,14 +230,19 @@ func (r *Recorder) RecordPlan(entries []PlanEntry) error {
 
 // RecordPermission records a permission event.
 func (r *Recorder) RecordPermission(title, selectedOption, outcome string) error {
+	return r.RecordPermissionData(PermissionData{
+		Title:          title,
+		SelectedOption: selectedOption,
+		Outcome:        outcome,
+	})
+}
+
+// RecordPermissionData records a permission event, including the rule that decided it.
+func (r *Recorder) RecordPermissionData(data PermissionData) error {
 	return r.recordEvent(Event{
 		Type:      EventTypePermission,
 		Timestamp: time.Now(),
-		Data: PermissionData{
-			Title:          title,
-			SelectedOption: selectedOption,
-			Outcome:        outcome,
-		},
+		Data:      data,
 	})
 }
 
@@ -263,6 +273,20 @@ func (r *Recorder) RecordFileWrite(path string, size int) error {
 	})
 }
 
+// RecordUsage records the token usage of a prompt and adds it to the
+// session's usage totals.
+func (r *Recorder) RecordUsage(data UsageData) error {
+	now := time.Now()
+	if err := r.recordEvent(Event{
+		Type:      EventTypeUsage,
+		Timestamp: now,
+		Data:      data,
+	}); err != nil {
+		return err
+	}
+	return r.store.AddUsage(r.sessionID, now, data)
+}
+
 // Suspend suspends the recording session but keeps it active for later resumption.
 // This is used when the connection is temporarily closed (e.g., browser refresh).
 // The session remains "active" so it can be resumed without creating a new session.
@@ -0,0 +1,58 @@
+package session
+
+import "time"
+
+// ResourceData is the resource usage of the agent process while answering a prompt.
+type ResourceData struct {
+	// CPUSeconds is the CPU time (user + system) used by the agent process tree.
+	CPUSeconds float64 `json:"cpu_seconds"`
+	// WallSeconds is the wall-clock time of the prompt.
+	WallSeconds float64 `json:"wall_seconds"`
+	// PeakMemoryBytes is the highest memory of the process tree sampled during the prompt.
+	PeakMemoryBytes uint64 `json:"peak_memory_bytes"`
+	// PeakProcesses is the highest number of processes of the agent (not counting
+	// the agent itself) sampled during the prompt.
+	PeakProcesses int `json:"peak_processes"`
+	// LimitExceeded is the resource limit that cancelled the prompt, if any
+	// ("memory", "cpu", "duration" or "processes").
+	LimitExceeded string `json:"limit_exceeded,omitempty"`
+}
+
+// ResourceTotals accumulates the resource usage of the agent over a number of prompts.
+// The agent process is shared by the conversations of a workspace, so the CPU
+// and memory of concurrent prompts of other conversations are included.
+type ResourceTotals struct {
+	Prompts         int     `json:"prompts"`
+	CPUSeconds      float64 `json:"cpu_seconds"`
+	WallSeconds     float64 `json:"wall_seconds"`
+	PeakMemoryBytes uint64  `json:"peak_memory_bytes"`
+	PeakProcesses   int     `json:"peak_processes"`
+	// LimitsExceeded counts the prompts cancelled for exceeding a resource limit.
+	LimitsExceeded int `json:"limits_exceeded,omitempty"`
+}
+
+// Add adds the resource usage of a single prompt.
+func (t *ResourceTotals) Add(d ResourceData) {
+	t.Prompts++
+	t.CPUSeconds += d.CPUSeconds
+	t.WallSeconds += d.WallSeconds
+	t.PeakMemoryBytes = max(t.PeakMemoryBytes, d.PeakMemoryBytes)
+	t.PeakProcesses = max(t.PeakProcesses, d.PeakProcesses)
+	if d.LimitExceeded != "" {
+		t.LimitsExceeded++
+	}
+}
+
+// AddResources adds the resource usage of a prompt made at the given time to the
+// session's daily totals (keyed by UsageDayFormat, like the token usage).
+func (s *Store) AddResources(sessionID string, at time.Time, data ResourceData) error {
+	day := at.Local().Format(UsageDayFormat)
+	return s.UpdateMetadata(sessionID, func(m *Metadata) {
+		if m.Resources == nil {
+			m.Resources = make(map[string]ResourceTotals)
+		}
+		totals := m.Resources[day]
+		totals.Add(data)
+		m.Resources[day] = totals
+	})
+}
@@ -0,0 +1,34 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore_AddResources(t *testing.T) {
+	store := newSearchTestStore(t)
+	recorder := NewRecorder(store)
+	if err := recorder.Start("auggie", "/proj", ""); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	now := time.Now()
+	for _, d := range []ResourceData{
+		{CPUSeconds: 1.5, WallSeconds: 10, PeakMemoryBytes: 200 << 20, PeakProcesses: 3},
+		{CPUSeconds: 0.5, WallSeconds: 5, PeakMemoryBytes: 100 << 20, PeakProcesses: 5, LimitExceeded: "processes"},
+	} {
+		if err := store.AddResources(recorder.SessionID(), now, d); err != nil {
+			t.Fatalf("AddResources failed: %v", err)
+		}
+	}
+
+	meta, err := store.GetMetadata(recorder.SessionID())
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	today := meta.Resources[now.Format(UsageDayFormat)]
+	if len(meta.Resources) != 1 || today.Prompts != 2 || today.CPUSeconds != 2 || today.WallSeconds != 15 ||
+		today.PeakMemoryBytes != 200<<20 || today.PeakProcesses != 5 || today.LimitsExceeded != 1 {
+		t.Errorf("Resources = %+v", meta.Resources)
+	}
+}
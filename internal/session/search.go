@@ -0,0 +1,596 @@
+package session
+
+import (
+	"errors"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/inercia/mitto/internal/logging"
+)
+
+const (
+	// DefaultSearchLimit is the default number of hits returned by a search.
+	DefaultSearchLimit = 20
+	// MaxSearchLimit is the maximum number of hits returned by a search.
+	MaxSearchLimit = 200
+
+	// searchSnippetContext is the number of characters shown before the first match in a snippet.
+	searchSnippetContext = 60
+	// searchSnippetLength is the maximum number of characters in a snippet.
+	searchSnippetLength = 200
+
+	// searchFullRefreshInterval is how often a search rescans all sessions.
+	// Writes made through the store are picked up by the next search; the
+	// rescan catches the writes of other processes sharing the sessions directory.
+	searchFullRefreshInterval = 30 * time.Second
+)
+
+// SearchOptions configures a full-text search over conversation history.
+type SearchOptions struct {
+	// Query is the search text. All terms must match (AND). A term ending
+	// with '*' matches any word with that prefix.
+	Query string
+	// WorkingDir restricts results to conversations in this workspace folder (exact match).
+	WorkingDir string
+	// ACPServer restricts results to conversations using this ACP server (exact match).
+	ACPServer string
+	// Archived filters by archived status (nil = both, true = only archived, false = only active).
+	Archived *bool
+	// Since and Until restrict results to events in this time range (zero = unbounded).
+	Since time.Time
+	Until time.Time
+	// Limit is the maximum number of hits to return (default DefaultSearchLimit, max MaxSearchLimit).
+	Limit int
+	// CanAccess, if set, restricts results to conversations in the folders it
+	// accepts (e.g. the workspaces of a user). It is applied before Limit, and
+	// called with the index locked.
+	CanAccess func(workingDir string) bool
+}
+
+// SearchHit is a single matching event.
+type SearchHit struct {
+	SessionID   string    `json:"session_id"`
+	SessionName string    `json:"session_name,omitempty"`
+	WorkingDir  string    `json:"working_dir"`
+	ACPServer   string    `json:"acp_server"`
+	Archived    bool      `json:"archived"`
+	Seq         int64     `json:"seq"`
+	EventType   EventType `json:"event_type"`
+	Timestamp   time.Time `json:"timestamp"`
+	// Snippet is an HTML-escaped excerpt of the matching text,
+	// with matched words wrapped in <mark>...</mark>.
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+// SearchResult is the result of a search.
+type SearchResult struct {
+	// Total is the number of matching events (before applying the limit).
+	Total int         `json:"total"`
+	Hits  []SearchHit `json:"hits"`
+}
+
+// searchDoc is a single indexed event.
+type searchDoc struct {
+	sessionID string
+	seq       int64
+	eventType EventType
+	timestamp time.Time
+	text      string
+	terms     []string // unique terms in text
+}
+
+// indexedSession tracks what has been indexed for a session.
+type indexedSession struct {
+	meta   Metadata
+	docIDs []int
+	// maxSeq is the highest sequence number actually read, which can be
+	// ahead of meta when events were appended while reading.
+	maxSeq int64
+}
+
+// SearchIndex is an incremental in-memory inverted index over conversation history.
+// It indexes user prompts, agent messages (HTML-stripped), agent thoughts and
+// tool call titles. The index is brought up to date with the store before every
+// search: only the sessions written through the store since the last search are
+// read (and only their new events), and all sessions are rescanned every
+// searchFullRefreshInterval. Sessions that were pruned, deleted or rewritten
+// are re-indexed or dropped.
+type SearchIndex struct {
+	store *Store
+
+	mu             sync.Mutex
+	docs           map[int]*searchDoc
+	postings       map[string]map[int]int // term -> doc ID -> term frequency
+	sessions       map[string]*indexedSession
+	nextDocID      int
+	lastFullRescan time.Time
+
+	// changed holds the sessions written through the store since the last
+	// refresh. It has its own lock, as it is updated with the store lock held.
+	changedMu sync.Mutex
+	changed   map[string]struct{}
+}
+
+// NewSearchIndex creates an empty search index over the given store.
+// Use Store.SearchIndex to get the store's shared index.
+func NewSearchIndex(store *Store) *SearchIndex {
+	return &SearchIndex{
+		store:    store,
+		docs:     make(map[int]*searchDoc),
+		postings: make(map[string]map[int]int),
+		sessions: make(map[string]*indexedSession),
+		changed:  make(map[string]struct{}),
+	}
+}
+
+// markChanged records that a session was written through the store.
+func (idx *SearchIndex) markChanged(sessionID string) {
+	idx.changedMu.Lock()
+	idx.changed[sessionID] = struct{}{}
+	idx.changedMu.Unlock()
+}
+
+// takeChanged returns and clears the sessions written since the last call.
+func (idx *SearchIndex) takeChanged() map[string]struct{} {
+	idx.changedMu.Lock()
+	defer idx.changedMu.Unlock()
+	changed := idx.changed
+	idx.changed = make(map[string]struct{})
+	return changed
+}
+
+// searchTrackingBackend wraps the backend of a store to tell its search index
+// which sessions are written, so searches don't rescan every session.
+type searchTrackingBackend struct {
+	storeBackend
+	store *Store
+}
+
+func (b *searchTrackingBackend) changed(sessionID string) {
+	b.store.SearchIndex().markChanged(sessionID)
+}
+
+func (b *searchTrackingBackend) create(meta Metadata) error {
+	defer b.changed(meta.SessionID)
+	return b.storeBackend.create(meta)
+}
+
+func (b *searchTrackingBackend) writeMetadata(meta Metadata) error {
+	defer b.changed(meta.SessionID)
+	return b.storeBackend.writeMetadata(meta)
+}
+
+func (b *searchTrackingBackend) appendEvent(meta Metadata, event Event) error {
+	defer b.changed(meta.SessionID)
+	return b.storeBackend.appendEvent(meta, event)
+}
+
+func (b *searchTrackingBackend) replaceEvents(meta Metadata, events []Event) error {
+	defer b.changed(meta.SessionID)
+	return b.storeBackend.replaceEvents(meta, events)
+}
+
+func (b *searchTrackingBackend) remove(sessionID string) error {
+	defer b.changed(sessionID)
+	return b.storeBackend.remove(sessionID)
+}
+
+// SearchIndex returns the store's shared full-text search index, creating it on first use.
+func (s *Store) SearchIndex() *SearchIndex {
+	s.searchOnce.Do(func() {
+		s.search = NewSearchIndex(s)
+	})
+	return s.search
+}
+
+// Refresh brings the index up to date with the store, rescanning all sessions.
+func (idx *SearchIndex) Refresh() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.refreshLocked(true)
+}
+
+// refreshLocked updates the index with the sessions written since the last
+// refresh, or with all sessions when full is set or the last rescan is older
+// than searchFullRefreshInterval.
+func (idx *SearchIndex) refreshLocked(full bool) error {
+	changed := idx.takeChanged()
+	if full || time.Since(idx.lastFullRescan) >= searchFullRefreshInterval {
+		sessions, err := idx.store.List()
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(sessions))
+		for _, meta := range sessions {
+			seen[meta.SessionID] = true
+			idx.refreshSessionLocked(meta)
+		}
+		for sessionID := range idx.sessions {
+			if !seen[sessionID] {
+				idx.dropSessionLocked(sessionID)
+			}
+		}
+		idx.lastFullRescan = time.Now()
+		return nil
+	}
+
+	for sessionID := range changed {
+		meta, err := idx.store.GetMetadata(sessionID)
+		switch {
+		case errors.Is(err, ErrSessionNotFound):
+			idx.dropSessionLocked(sessionID)
+		case err != nil:
+			return err
+		default:
+			idx.refreshSessionLocked(meta)
+		}
+	}
+	return nil
+}
+
+// refreshSessionLocked indexes the events of a session added since the last refresh.
+func (idx *SearchIndex) refreshSessionLocked(meta Metadata) {
+	indexed := idx.sessions[meta.SessionID]
+	var afterSeq int64
+	switch {
+	case indexed == nil:
+		indexed = &indexedSession{}
+		idx.sessions[meta.SessionID] = indexed
+	case meta.MaxSeq < indexed.maxSeq || meta.EventCount < indexed.meta.EventCount:
+		// Pruned or rewritten: re-index from scratch
+		idx.removeDocsLocked(indexed)
+	case meta.MaxSeq == indexed.maxSeq:
+		// No new events; keep metadata (name, archived, ...) current for filters
+		indexed.meta = meta
+		return
+	default:
+		afterSeq = indexed.maxSeq
+	}
+
+	events, err := idx.store.ReadEventsFrom(meta.SessionID, afterSeq, 0)
+	if err != nil {
+		logging.Session().Debug("search index: failed to read events", "session_id", meta.SessionID, "error", err)
+		return
+	}
+	for _, event := range events {
+		idx.addEventLocked(indexed, meta.SessionID, event)
+		// Track what was read rather than the metadata, which can be behind
+		indexed.maxSeq = max(indexed.maxSeq, event.Seq)
+	}
+	indexed.meta = meta
+}
+
+// dropSessionLocked removes a deleted session from the index.
+func (idx *SearchIndex) dropSessionLocked(sessionID string) {
+	if indexed := idx.sessions[sessionID]; indexed != nil {
+		idx.removeDocsLocked(indexed)
+		delete(idx.sessions, sessionID)
+	}
+}
+
+// addEventLocked indexes a single event if it contains searchable text.
+func (idx *SearchIndex) addEventLocked(indexed *indexedSession, sessionID string, event Event) {
+	text := searchableText(event)
+	if text == "" {
+		return
+	}
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return
+	}
+
+	freqs := make(map[string]int, len(tokens))
+	for _, tok := range tokens {
+		freqs[tok.term]++
+	}
+
+	id := idx.nextDocID
+	idx.nextDocID++
+	doc := &searchDoc{
+		sessionID: sessionID,
+		seq:       event.Seq,
+		eventType: event.Type,
+		timestamp: event.Timestamp,
+		text:      text,
+		terms:     make([]string, 0, len(freqs)),
+	}
+	for term, freq := range freqs {
+		doc.terms = append(doc.terms, term)
+		postings := idx.postings[term]
+		if postings == nil {
+			postings = make(map[int]int)
+			idx.postings[term] = postings
+		}
+		postings[id] = freq
+	}
+	idx.docs[id] = doc
+	indexed.docIDs = append(indexed.docIDs, id)
+}
+
+// removeDocsLocked removes all indexed events of a session.
+func (idx *SearchIndex) removeDocsLocked(indexed *indexedSession) {
+	for _, id := range indexed.docIDs {
+		doc := idx.docs[id]
+		if doc == nil {
+			continue
+		}
+		for _, term := range doc.terms {
+			if postings := idx.postings[term]; postings != nil {
+				delete(postings, id)
+				if len(postings) == 0 {
+					delete(idx.postings, term)
+				}
+			}
+		}
+		delete(idx.docs, id)
+	}
+	indexed.docIDs = nil
+	indexed.meta = Metadata{}
+	indexed.maxSeq = 0
+}
+
+// Search refreshes the index and returns the events matching opts, best matches first.
+func (idx *SearchIndex) Search(opts SearchOptions) (*SearchResult, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.refreshLocked(false); err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult{Hits: []SearchHit{}}
+	queryTerms := parseSearchQuery(opts.Query)
+	if len(queryTerms) == 0 {
+		return result, nil
+	}
+
+	// Score = sum over query terms of tf * idf, requiring every term to match.
+	totalDocs := float64(len(idx.docs))
+	var scores map[int]float64
+	for _, qt := range queryTerms {
+		termScores := make(map[int]float64)
+		for term, postings := range idx.postings {
+			if !qt.matches(term) {
+				continue
+			}
+			idf := math.Log(1 + totalDocs/float64(len(postings)))
+			for id, freq := range postings {
+				termScores[id] += float64(freq) * idf
+			}
+		}
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for id := range scores {
+			if s, ok := termScores[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	accessible := make(map[string]bool)
+	for id, score := range scores {
+		doc := idx.docs[id]
+		meta := idx.sessions[doc.sessionID].meta
+		if opts.WorkingDir != "" && meta.WorkingDir != opts.WorkingDir {
+			continue
+		}
+		if opts.CanAccess != nil {
+			ok, checked := accessible[meta.WorkingDir]
+			if !checked {
+				ok = opts.CanAccess(meta.WorkingDir)
+				accessible[meta.WorkingDir] = ok
+			}
+			if !ok {
+				continue
+			}
+		}
+		if opts.ACPServer != "" && meta.ACPServer != opts.ACPServer {
+			continue
+		}
+		if opts.Archived != nil && meta.Archived != *opts.Archived {
+			continue
+		}
+		if !opts.Since.IsZero() && doc.timestamp.Before(opts.Since) {
+			continue
+		}
+		if !opts.Until.IsZero() && doc.timestamp.After(opts.Until) {
+			continue
+		}
+		result.Hits = append(result.Hits, SearchHit{
+			SessionID:   doc.sessionID,
+			SessionName: meta.Name,
+			WorkingDir:  meta.WorkingDir,
+			ACPServer:   meta.ACPServer,
+			Archived:    meta.Archived,
+			Seq:         doc.seq,
+			EventType:   doc.eventType,
+			Timestamp:   doc.timestamp,
+			Score:       score,
+		})
+	}
+
+	sort.Slice(result.Hits, func(i, j int) bool {
+		a, b := result.Hits[i], result.Hits[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.After(b.Timestamp)
+		}
+		if a.SessionID != b.SessionID {
+			return a.SessionID < b.SessionID
+		}
+		return a.Seq < b.Seq
+	})
+
+	result.Total = len(result.Hits)
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	} else if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+	if len(result.Hits) > limit {
+		result.Hits = result.Hits[:limit]
+	}
+
+	// Build snippets only for returned hits
+	docsBySessionSeq := make(map[string]map[int64]*searchDoc)
+	for _, hit := range result.Hits {
+		docsBySessionSeq[hit.SessionID] = nil
+	}
+	for id := range scores {
+		doc := idx.docs[id]
+		if m, ok := docsBySessionSeq[doc.sessionID]; ok {
+			if m == nil {
+				m = make(map[int64]*searchDoc)
+				docsBySessionSeq[doc.sessionID] = m
+			}
+			m[doc.seq] = doc
+		}
+	}
+	for i := range result.Hits {
+		if doc := docsBySessionSeq[result.Hits[i].SessionID][result.Hits[i].Seq]; doc != nil {
+			result.Hits[i].Snippet = buildSnippet(doc.text, queryTerms)
+		}
+	}
+
+	return result, nil
+}
+
+// searchableText returns the text indexed for an event, or "" if the event is not searchable.
+func searchableText(event Event) string {
+	data, err := DecodeEventData(event)
+	if err != nil {
+		return ""
+	}
+	switch d := data.(type) {
+	case UserPromptData:
+		return d.Message
+	case AgentMessageData:
+		return strings.TrimSpace(StripHTML(d.Text))
+	case AgentThoughtData:
+		return d.Text
+	case ToolCallData:
+		return d.Title
+	case ToolCallUpdateData:
+		if d.Title != nil {
+			return *d.Title
+		}
+	}
+	return ""
+}
+
+// searchToken is a word in a text, with its byte offsets.
+type searchToken struct {
+	term       string // lowercased word
+	start, end int
+}
+
+// tokenize splits text into lowercased words made of letters and digits.
+func tokenize(text string) []searchToken {
+	var tokens []searchToken
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			tokens = append(tokens, searchToken{term: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, searchToken{term: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// queryTerm is a parsed search query term.
+type queryTerm struct {
+	term   string
+	prefix bool
+}
+
+func (q queryTerm) matches(term string) bool {
+	if q.prefix {
+		return strings.HasPrefix(term, q.term)
+	}
+	return term == q.term
+}
+
+// parseSearchQuery splits a query into terms, tokenizing them like indexed text.
+// A trailing '*' on a query word makes its last term a prefix match.
+func parseSearchQuery(query string) []queryTerm {
+	var terms []queryTerm
+	for _, word := range strings.Fields(query) {
+		prefix := strings.HasSuffix(word, "*")
+		tokens := tokenize(strings.TrimRight(word, "*"))
+		for i, tok := range tokens {
+			terms = append(terms, queryTerm{term: tok.term, prefix: prefix && i == len(tokens)-1})
+		}
+	}
+	return terms
+}
+
+// buildSnippet returns an HTML-escaped excerpt of text around the first match,
+// with matched words wrapped in <mark> tags.
+func buildSnippet(text string, queryTerms []queryTerm) string {
+	tokens := tokenize(text)
+	var matches []searchToken
+	for _, tok := range tokens {
+		for _, qt := range queryTerms {
+			if qt.matches(tok.term) {
+				matches = append(matches, tok)
+				break
+			}
+		}
+	}
+
+	// Choose a window of searchSnippetLength characters starting a little before the first match.
+	start := 0
+	if len(matches) > 0 {
+		start = matches[0].start
+		for n := 0; n < searchSnippetContext && start > 0; n++ {
+			_, size := utf8.DecodeLastRuneInString(text[:start])
+			start -= size
+		}
+	}
+	end := start
+	for n := 0; n < searchSnippetLength && end < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m.start < start || m.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[m.start:m.end]))
+		b.WriteString("</mark>")
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,212 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSearchTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func appendSearchTestEvent(t *testing.T, store *Store, sessionID string, event Event) {
+	t.Helper()
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if err := store.AppendEvent(sessionID, event); err != nil {
+		t.Fatalf("AppendEvent failed: %v", err)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := tokenize("Fix the Parser-bug in café.go, v2!")
+	var terms []string
+	for _, tok := range tokens {
+		terms = append(terms, tok.term)
+	}
+	want := []string{"fix", "the", "parser", "bug", "in", "café", "go", "v2"}
+	if strings.Join(terms, ",") != strings.Join(want, ",") {
+		t.Errorf("tokenize = %v, want %v", terms, want)
+	}
+}
+
+func TestBuildSnippet(t *testing.T) {
+	text := strings.Repeat("lorem ipsum ", 20) + "the <b>needle</b> is here " + strings.Repeat("dolor sit ", 30)
+	snippet := buildSnippet(text, parseSearchQuery("needle"))
+
+	if !strings.Contains(snippet, "<mark>needle</mark>") {
+		t.Errorf("snippet should highlight match: %q", snippet)
+	}
+	if !strings.Contains(snippet, "&lt;b&gt;") {
+		t.Errorf("snippet should escape HTML: %q", snippet)
+	}
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") {
+		t.Errorf("truncated snippet should have ellipses: %q", snippet)
+	}
+}
+
+func TestSearchIndex_Search(t *testing.T) {
+	store := newSearchTestStore(t)
+	for _, meta := range []Metadata{
+		{SessionID: "s1", Name: "Parser work", ACPServer: "auggie", WorkingDir: "/proj/a"},
+		{SessionID: "s2", Name: "Docs", ACPServer: "claude", WorkingDir: "/proj/b"},
+	} {
+		if err := store.Create(meta); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	title := "Edit parser.go"
+	appendSearchTestEvent(t, store, "s1", Event{Type: EventTypeUserPrompt, Data: UserPromptData{Message: "Please fix the parser crash"}})
+	appendSearchTestEvent(t, store, "s1", Event{Type: EventTypeAgentMessage, Data: AgentMessageData{Text: "<p>The <strong>parser</strong> crash is fixed</p>"}})
+	appendSearchTestEvent(t, store, "s1", Event{Type: EventTypeToolCallUpdate, Data: ToolCallUpdateData{ToolCallID: "t1", Title: &title}})
+	appendSearchTestEvent(t, store, "s2", Event{Type: EventTypeAgentThought, Data: AgentThoughtData{Text: "The docs mention the parser"}})
+	appendSearchTestEvent(t, store, "s2", Event{Type: EventTypeToolCall, Data: ToolCallData{ToolCallID: "t2", Title: "Read README"}})
+
+	idx := store.SearchIndex()
+
+	result, err := idx.Search(SearchOptions{Query: "parser"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Total != 4 {
+		t.Errorf("Total = %d, want 4", result.Total)
+	}
+
+	// AND semantics
+	result, _ = idx.Search(SearchOptions{Query: "parser crash"})
+	if result.Total != 2 {
+		t.Errorf("'parser crash' Total = %d, want 2", result.Total)
+	}
+	for _, hit := range result.Hits {
+		if hit.SessionID != "s1" || hit.SessionName != "Parser work" {
+			t.Errorf("unexpected hit %+v", hit)
+		}
+	}
+
+	// HTML is stripped before indexing
+	result, _ = idx.Search(SearchOptions{Query: "strong"})
+	if result.Total != 0 {
+		t.Errorf("HTML tags should not be indexed, got %d hits", result.Total)
+	}
+
+	// Prefix match
+	result, _ = idx.Search(SearchOptions{Query: "read*"})
+	if result.Total != 1 || result.Hits[0].EventType != EventTypeToolCall {
+		t.Errorf("prefix search = %+v, want the tool call", result.Hits)
+	}
+
+	// Filters
+	result, _ = idx.Search(SearchOptions{Query: "parser", WorkingDir: "/proj/b"})
+	if result.Total != 1 || result.Hits[0].SessionID != "s2" {
+		t.Errorf("working dir filter = %+v, want the s2 thought", result.Hits)
+	}
+	result, _ = idx.Search(SearchOptions{Query: "parser", ACPServer: "auggie", Limit: 1})
+	if result.Total != 3 || len(result.Hits) != 1 {
+		t.Errorf("ACP server filter with limit: Total=%d hits=%d, want 3 and 1", result.Total, len(result.Hits))
+	}
+	result, _ = idx.Search(SearchOptions{
+		Query:     "parser",
+		Limit:     1,
+		CanAccess: func(dir string) bool { return dir == "/proj/b" },
+	})
+	if result.Total != 1 || len(result.Hits) != 1 || result.Hits[0].SessionID != "s2" {
+		t.Errorf("access filter with limit = %+v (Total %d), want the s2 thought", result.Hits, result.Total)
+	}
+	result, _ = idx.Search(SearchOptions{Query: "parser", Since: time.Now().Add(time.Hour)})
+	if result.Total != 0 {
+		t.Errorf("since filter: Total = %d, want 0", result.Total)
+	}
+
+	// Empty query
+	result, _ = idx.Search(SearchOptions{Query: "  "})
+	if result.Total != 0 || result.Hits == nil {
+		t.Errorf("empty query = %+v, want no hits", result)
+	}
+}
+
+func TestSearchIndex_Incremental(t *testing.T) {
+	store := newSearchTestStore(t)
+	if err := store.Create(Metadata{SessionID: "s1", ACPServer: "auggie", WorkingDir: "/proj"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		appendSearchTestEvent(t, store, "s1", Event{Type: EventTypeUserPrompt, Data: UserPromptData{Message: "old message"}})
+	}
+
+	idx := store.SearchIndex()
+	if result, _ := idx.Search(SearchOptions{Query: "old"}); result.Total != 5 {
+		t.Fatalf("Total = %d, want 5", result.Total)
+	}
+
+	// New events are picked up
+	appendSearchTestEvent(t, store, "s1", Event{Type: EventTypeUserPrompt, Data: UserPromptData{Message: "new message"}})
+	if result, _ := idx.Search(SearchOptions{Query: "new"}); result.Total != 1 || result.Hits[0].Seq != 6 {
+		t.Errorf("after append: %+v, want one hit at seq 6", result)
+	}
+
+	// Metadata changes are reflected in filters
+	if err := store.UpdateMetadata("s1", func(m *Metadata) { m.Archived = true }); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	archived := false
+	if result, _ := idx.Search(SearchOptions{Query: "message", Archived: &archived}); result.Total != 0 {
+		t.Errorf("archived filter: Total = %d, want 0", result.Total)
+	}
+
+	// Pruned events are dropped
+	if _, err := store.PruneKeepLast("s1", 2); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if result, _ := idx.Search(SearchOptions{Query: "old"}); result.Total != 1 {
+		t.Errorf("after prune: Total = %d, want 1", result.Total)
+	}
+
+	// Deleted sessions are dropped
+	if err := store.Delete("s1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if result, _ := idx.Search(SearchOptions{Query: "message"}); result.Total != 0 {
+		t.Errorf("after delete: Total = %d, want 0", result.Total)
+	}
+}
+
+func TestSearchIndex_EventsAppendedWhileRefreshing(t *testing.T) {
+	store := newSearchTestStore(t)
+	if err := store.Create(Metadata{SessionID: "s1"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	appendSearchTestEvent(t, store, "s1", Event{Type: EventTypeUserPrompt, Data: UserPromptData{Message: "first message"}})
+	idx := store.SearchIndex()
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	// Metadata read before an event is appended, as when the append happens
+	// between listing the sessions and reading their events
+	stale, err := store.GetMetadata("s1")
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	appendSearchTestEvent(t, store, "s1", Event{Type: EventTypeUserPrompt, Data: UserPromptData{Message: "second message"}})
+	idx.mu.Lock()
+	idx.refreshSessionLocked(stale)
+	idx.mu.Unlock()
+
+	if result, _ := idx.Search(SearchOptions{Query: "message"}); result.Total != 2 {
+		t.Errorf("Total = %d, want 2 (no duplicate hits)", result.Total)
+	}
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if result, _ := idx.Search(SearchOptions{Query: "message"}); result.Total != 2 {
+		t.Errorf("after full refresh: Total = %d, want 2 (no duplicate hits)", result.Total)
+	}
+}
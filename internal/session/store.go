@@ -1,8 +1,6 @@
 package session
 
 import (
-	"bufio"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -10,7 +8,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/inercia/mitto/internal/fileutil"
 	"github.com/inercia/mitto/internal/logging"
 )
 
@@ -30,28 +27,59 @@ var (
 var _ SessionStore = (*Store)(nil)
 
 // Store provides session persistence operations.
+// Session metadata and events are persisted by a pluggable backend
+// (see StoreBackend); all other per-session data lives in the session directory.
 type Store struct {
 	baseDir string
+	backend storeBackend
 	mu      sync.RWMutex
 	closed  bool
+
+	// usageMu serializes access to the usage ledger.
+	usageMu sync.Mutex
+
+	// search is the full-text search index, created lazily by SearchIndex.
+	search     *SearchIndex
+	searchOnce sync.Once
 }
 
-// NewStore creates a new session store with the given base directory.
+// NewStore creates a new session store with the given base directory,
+// using the default file backend.
 func NewStore(baseDir string) (*Store, error) {
+	return NewStoreWithBackend(baseDir, StoreBackendFile)
+}
+
+// NewStoreWithBackend creates a new session store with the given base directory
+// and metadata/events backend.
+func NewStoreWithBackend(baseDir string, kind StoreBackend) (*Store, error) {
 	log := logging.Session()
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create session directory: %w", err)
 	}
-	log.Debug("session store initialized", "base_dir", baseDir)
-	return &Store{baseDir: baseDir}, nil
+	backend, err := newStoreBackend(kind, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("session store initialized", "base_dir", baseDir, "backend", backend.kind())
+	store := &Store{baseDir: baseDir}
+	store.backend = &searchTrackingBackend{storeBackend: backend, store: store}
+	return store, nil
 }
 
 // RunMigrations runs any pending data migrations on the session store.
 // The context parameter is optional and provides external information to migrations
 // (e.g., ACP server name mappings for the normalize migration).
 // This should be called after NewStore and before the store is used.
+//
+// Migrations go through the store backend, so they apply to every backend.
 func (s *Store) RunMigrations(ctx *MigrationContext) error {
-	return RunMigrations(s.baseDir, ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStoreClosed
+	}
+	return runMigrations(s, ctx)
 }
 
 // BaseDir returns the base directory of the store.
@@ -59,6 +87,11 @@ func (s *Store) BaseDir() string {
 	return s.baseDir
 }
 
+// Backend returns the backend used to persist session metadata and events.
+func (s *Store) Backend() StoreBackend {
+	return s.backend.kind()
+}
+
 // sessionDir returns the directory path for a session.
 func (s *Store) sessionDir(sessionID string) string {
 	return filepath.Join(s.baseDir, sessionID)
@@ -69,16 +102,6 @@ func (s *Store) SessionDir(sessionID string) string {
 	return s.sessionDir(sessionID)
 }
 
-// eventsPath returns the events file path for a session.
-func (s *Store) eventsPath(sessionID string) string {
-	return filepath.Join(s.sessionDir(sessionID), eventsFileName)
-}
-
-// metadataPath returns the metadata file path for a session.
-func (s *Store) metadataPath(sessionID string) string {
-	return filepath.Join(s.sessionDir(sessionID), metadataFileName)
-}
-
 // lockPath returns the lock file path for a session.
 func (s *Store) lockPath(sessionID string) string {
 	return filepath.Join(s.sessionDir(sessionID), lockFileName)
@@ -123,20 +146,13 @@ func (s *Store) Create(meta Metadata) error {
 		return fmt.Errorf("failed to create session directory: %w", err)
 	}
 
-	// Create empty events file
-	eventsFile, err := os.Create(s.eventsPath(meta.SessionID))
-	if err != nil {
-		return fmt.Errorf("failed to create events file: %w", err)
-	}
-	eventsFile.Close()
-
-	// Write metadata
+	// Create an empty event log and write metadata
 	meta.CreatedAt = time.Now()
 	meta.UpdatedAt = meta.CreatedAt
 	meta.EventCount = 0
 	meta.Status = SessionStatusActive
 
-	if err := s.writeMetadata(meta); err != nil {
+	if err := s.backend.create(meta); err != nil {
 		return err
 	}
 
@@ -164,16 +180,6 @@ func (s *Store) AppendEvent(sessionID string, event Event) error {
 		return err
 	}
 
-	eventsPath := s.eventsPath(sessionID)
-	f, err := os.OpenFile(eventsPath, os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return ErrSessionNotFound
-		}
-		return fmt.Errorf("failed to open events file: %w", err)
-	}
-	defer f.Close()
-
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
@@ -187,23 +193,6 @@ func (s *Store) AppendEvent(sessionID string, event Event) error {
 	}
 	event.Seq = nextSeq
 
-	data, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
-	}
-
-	if _, err := f.Write(append(data, '\n')); err != nil {
-		return fmt.Errorf("failed to write event: %w", err)
-	}
-
-	// L1: Structured logging for event persistence
-	log := logging.Session()
-	log.Debug("event_persisted",
-		"session_id", sessionID,
-		"seq", event.Seq,
-		"event_type", event.Type,
-		"event_count", meta.EventCount+1)
-
 	// Update metadata
 	meta.EventCount++
 	if event.Seq > meta.MaxSeq {
@@ -214,7 +203,19 @@ func (s *Store) AppendEvent(sessionID string, event Event) error {
 	if event.Type == EventTypeUserPrompt {
 		meta.LastUserMessageAt = event.Timestamp
 	}
-	return s.writeMetadata(meta)
+
+	if err := s.backend.appendEvent(meta, event); err != nil {
+		return err
+	}
+
+	// L1: Structured logging for event persistence
+	log := logging.Session()
+	log.Debug("event_persisted",
+		"session_id", sessionID,
+		"seq", event.Seq,
+		"event_type", event.Type,
+		"event_count", meta.EventCount)
+	return nil
 }
 
 // RecordEvent persists an event with its pre-assigned sequence number.
@@ -257,37 +258,12 @@ func (s *Store) RecordEvent(sessionID string, event Event) error {
 		// Continue anyway - the event has the seq assigned at streaming time
 	}
 
-	eventsPath := s.eventsPath(sessionID)
-	f, err := os.OpenFile(eventsPath, os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return ErrSessionNotFound
-		}
-		return fmt.Errorf("failed to open events file: %w", err)
-	}
-	defer f.Close()
-
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
 
 	// Note: We do NOT reassign event.Seq - it's already set by the caller
 
-	data, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
-	}
-
-	if _, err := f.Write(append(data, '\n')); err != nil {
-		return fmt.Errorf("failed to write event: %w", err)
-	}
-
-	log.Debug("event_recorded",
-		"session_id", sessionID,
-		"seq", event.Seq,
-		"event_type", event.Type,
-		"event_count", meta.EventCount+1)
-
 	// Update metadata
 	meta.EventCount++
 	meta.UpdatedAt = time.Now()
@@ -299,7 +275,17 @@ func (s *Store) RecordEvent(sessionID string, event Event) error {
 	if event.Type == EventTypeUserPrompt {
 		meta.LastUserMessageAt = event.Timestamp
 	}
-	return s.writeMetadata(meta)
+
+	if err := s.backend.appendEvent(meta, event); err != nil {
+		return err
+	}
+
+	log.Debug("event_recorded",
+		"session_id", sessionID,
+		"seq", event.Seq,
+		"event_type", event.Type,
+		"event_count", meta.EventCount)
+	return nil
 }
 
 // GetMetadata retrieves the metadata for a session.
@@ -314,27 +300,15 @@ func (s *Store) GetMetadata(sessionID string) (Metadata, error) {
 	return s.readMetadata(sessionID)
 }
 
-// readMetadata reads metadata from disk (must be called with lock held).
-// Automatically migrates ChildOrigin for backward compatibility with old metadata files.
+// readMetadata reads metadata from the backend (must be called with lock held).
+// Automatically migrates ChildOrigin for backward compatibility with old metadata.
 func (s *Store) readMetadata(sessionID string) (Metadata, error) {
-	var meta Metadata
-	if err := fileutil.ReadJSON(s.metadataPath(sessionID), &meta); err != nil {
-		if os.IsNotExist(err) {
-			return Metadata{}, ErrSessionNotFound
-		}
-		return Metadata{}, fmt.Errorf("failed to read metadata: %w", err)
-	}
-	// Migrate old metadata that has IsAutoChild/ParentSessionID but no ChildOrigin
-	meta.MigrateChildOrigin()
-	return meta, nil
+	return s.backend.readMetadata(sessionID)
 }
 
-// writeMetadata writes metadata to disk (must be called with lock held).
+// writeMetadata writes metadata to the backend (must be called with lock held).
 func (s *Store) writeMetadata(meta Metadata) error {
-	if err := fileutil.WriteJSON(s.metadataPath(meta.SessionID), meta, 0644); err != nil {
-		return fmt.Errorf("failed to write metadata: %w", err)
-	}
-	return nil
+	return s.backend.writeMetadata(meta)
 }
 
 // UpdateMetadata updates the metadata for a session.
@@ -372,41 +346,7 @@ func (s *Store) ReadEventsFrom(sessionID string, afterSeq int64, limit int) ([]E
 		return nil, ErrStoreClosed
 	}
 
-	f, err := os.Open(s.eventsPath(sessionID))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, ErrSessionNotFound
-		}
-		return nil, fmt.Errorf("failed to open events file: %w", err)
-	}
-	defer f.Close()
-
-	var events []Event
-	scanner := bufio.NewScanner(f)
-	// Increase buffer size to handle large events (e.g., agent messages with code blocks)
-	// Default is 64KB, increase to 10MB to handle very long lines
-	const maxScannerBuffer = 10 * 1024 * 1024
-	scanner.Buffer(make([]byte, 0, 64*1024), maxScannerBuffer)
-	for scanner.Scan() {
-		var event Event
-		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
-		}
-		// Only include events after the specified sequence number
-		if event.Seq > afterSeq {
-			events = append(events, event)
-			// Stop early if we've reached the limit (0 = unlimited for backward compat)
-			if limit > 0 && len(events) >= limit {
-				break
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read events: %w", err)
-	}
-
-	return events, nil
+	return s.backend.readEventsFrom(sessionID, afterSeq, limit)
 }
 
 // ReadEventsLast reads the last N events from a session's event log.
@@ -420,43 +360,7 @@ func (s *Store) ReadEventsLast(sessionID string, limit int, beforeSeq int64) ([]
 		return nil, ErrStoreClosed
 	}
 
-	f, err := os.Open(s.eventsPath(sessionID))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, ErrSessionNotFound
-		}
-		return nil, fmt.Errorf("failed to open events file: %w", err)
-	}
-	defer f.Close()
-
-	// Read all matching events first (we need to know total count to get last N)
-	var allEvents []Event
-	scanner := bufio.NewScanner(f)
-	// Increase buffer size to handle large events (e.g., agent messages with code blocks)
-	// Default is 64KB, increase to 10MB to handle very long lines
-	const maxScannerBuffer = 10 * 1024 * 1024
-	scanner.Buffer(make([]byte, 0, 64*1024), maxScannerBuffer)
-	for scanner.Scan() {
-		var event Event
-		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
-		}
-		// If beforeSeq is specified, only include events before it
-		if beforeSeq > 0 && event.Seq >= beforeSeq {
-			continue
-		}
-		allEvents = append(allEvents, event)
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read events: %w", err)
-	}
-
-	// Return last N events
-	if limit > 0 && len(allEvents) > limit {
-		return allEvents[len(allEvents)-limit:], nil
-	}
-	return allEvents, nil
+	return s.backend.readEventsLast(sessionID, limit, beforeSeq)
 }
 
 // ReadEventsLastReverse reads the last N events in reverse chronological order (newest first).
@@ -477,34 +381,39 @@ func (s *Store) ReadEventsLastReverse(sessionID string, limit int, beforeSeq int
 	return events, nil
 }
 
-// List returns metadata for all sessions.
-func (s *Store) List() ([]Metadata, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// ReplaceEvents replaces the whole event log of a session, updating the event
+// count and the highest sequence number in its metadata.
+// It is meant for maintenance tools; events must be in sequence order.
+func (s *Store) ReplaceEvents(sessionID string, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	if s.closed {
-		return nil, ErrStoreClosed
+		return ErrStoreClosed
 	}
 
-	entries, err := os.ReadDir(s.baseDir)
+	meta, err := s.readMetadata(sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read sessions directory: %w", err)
+		return err
+	}
+	meta.EventCount = len(events)
+	meta.MaxSeq = 0
+	if len(events) > 0 {
+		meta.MaxSeq = events[len(events)-1].Seq
 	}
+	return s.backend.replaceEvents(meta, events)
+}
 
-	var sessions []Metadata
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		meta, err := s.readMetadata(entry.Name())
-		if err != nil {
-			// Skip sessions with invalid metadata
-			continue
-		}
-		sessions = append(sessions, meta)
+// List returns metadata for all sessions.
+func (s *Store) List() ([]Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStoreClosed
 	}
 
-	return sessions, nil
+	return s.backend.list()
 }
 
 // FindAutoChildrenRecursive returns all auto-child session IDs recursively.
@@ -518,38 +427,7 @@ func (s *Store) FindAutoChildrenRecursive(sessionID string) ([]string, error) {
 		return nil, ErrStoreClosed
 	}
 
-	return s.findAutoChildrenRecursive(sessionID, make(map[string]bool))
-}
-
-func (s *Store) findAutoChildrenRecursive(sessionID string, visited map[string]bool) ([]string, error) {
-	if visited[sessionID] {
-		return nil, nil // Prevent cycles
-	}
-	visited[sessionID] = true
-
-	entries, err := os.ReadDir(s.baseDir)
-	if err != nil {
-		return nil, err
-	}
-
-	var result []string
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		childID := entry.Name()
-		meta, err := s.readMetadata(childID)
-		if err != nil {
-			continue
-		}
-		if meta.ParentSessionID == sessionID && meta.IsAutoChild {
-			result = append(result, childID)
-			// Recurse to find grandchildren
-			grandchildren, _ := s.findAutoChildrenRecursive(childID, visited)
-			result = append(result, grandchildren...)
-		}
-	}
-	return result, nil
+	return s.findChildrenRecursive(sessionID, true, make(map[string]bool))
 }
 
 // FindAllChildrenRecursive returns all child session IDs recursively, regardless of origin.
@@ -563,36 +441,31 @@ func (s *Store) FindAllChildrenRecursive(sessionID string) ([]string, error) {
 		return nil, ErrStoreClosed
 	}
 
-	return s.findAllChildrenRecursive(sessionID, make(map[string]bool))
+	return s.findChildrenRecursive(sessionID, false, make(map[string]bool))
 }
 
-func (s *Store) findAllChildrenRecursive(sessionID string, visited map[string]bool) ([]string, error) {
+// findChildrenRecursive returns the IDs of all descendants of sessionID.
+// If autoOnly is true, only auto-children (and their auto-children) are followed.
+func (s *Store) findChildrenRecursive(sessionID string, autoOnly bool, visited map[string]bool) ([]string, error) {
 	if visited[sessionID] {
 		return nil, nil // Prevent cycles
 	}
 	visited[sessionID] = true
 
-	entries, err := os.ReadDir(s.baseDir)
+	children, err := s.backend.listChildren(sessionID)
 	if err != nil {
 		return nil, err
 	}
 
 	var result []string
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		childID := entry.Name()
-		meta, err := s.readMetadata(childID)
-		if err != nil {
+	for _, meta := range children {
+		if autoOnly && !meta.IsAutoChild {
 			continue
 		}
-		if meta.ParentSessionID == sessionID {
-			result = append(result, childID)
-			// Recurse to find grandchildren
-			grandchildren, _ := s.findAllChildrenRecursive(childID, visited)
-			result = append(result, grandchildren...)
-		}
+		result = append(result, meta.SessionID)
+		// Recurse to find grandchildren
+		grandchildren, _ := s.findChildrenRecursive(meta.SessionID, autoOnly, visited)
+		result = append(result, grandchildren...)
 	}
 	return result, nil
 }
@@ -608,30 +481,10 @@ func (s *Store) ListChildSessions(parentID string) ([]Metadata, error) {
 		return nil, ErrStoreClosed
 	}
 
-	entries, err := os.ReadDir(s.baseDir)
-	if err != nil {
-		return nil, err
-	}
-
-	children := []Metadata{}
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		sessionID := entry.Name()
-		meta, err := s.readMetadata(sessionID)
-		if err != nil {
-			continue // Skip sessions with unreadable metadata
-		}
-		if meta.ParentSessionID == parentID {
-			children = append(children, meta)
-		}
-	}
-	return children, nil
+	return s.backend.listChildren(parentID)
 }
 
 // CountChildSessions returns the count of direct child sessions.
-// More efficient than ListChildSessions when only the count is needed.
 func (s *Store) CountChildSessions(parentID string) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -640,30 +493,15 @@ func (s *Store) CountChildSessions(parentID string) (int, error) {
 		return 0, ErrStoreClosed
 	}
 
-	entries, err := os.ReadDir(s.baseDir)
+	children, err := s.backend.listChildren(parentID)
 	if err != nil {
 		return 0, err
 	}
-
-	count := 0
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		sessionID := entry.Name()
-		meta, err := s.readMetadata(sessionID)
-		if err != nil {
-			continue
-		}
-		if meta.ParentSessionID == parentID {
-			count++
-		}
-	}
-	return count, nil
+	return len(children), nil
 }
 
 // CountMCPChildSessions returns the count of direct non-archived child sessions that were
-// created via MCP (ChildOriginMCP) or by a human (ChildOriginHuman).
+// created via MCP (ChildOriginMCP), by a human (ChildOriginHuman) or by forking (ChildOriginFork).
 // Auto-children (ChildOriginAuto) and archived children are excluded from the count.
 // This is used for enforcing the max_child_conversations limit.
 func (s *Store) CountMCPChildSessions(parentID string) (int, error) {
@@ -674,34 +512,23 @@ func (s *Store) CountMCPChildSessions(parentID string) (int, error) {
 		return 0, ErrStoreClosed
 	}
 
-	entries, err := os.ReadDir(s.baseDir)
+	children, err := s.backend.listChildren(parentID)
 	if err != nil {
 		return 0, err
 	}
 
 	count := 0
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		sessionID := entry.Name()
-		meta, err := s.readMetadata(sessionID)
-		if err != nil {
-			continue
-		}
-		if meta.ParentSessionID == parentID {
-			meta.MigrateChildOrigin()
-			// Exclude auto-children and archived children from the count
-			if meta.ChildOrigin != ChildOriginAuto && !meta.Archived {
-				count++
-			}
+	for _, meta := range children {
+		meta.MigrateChildOrigin()
+		// Exclude auto-children and archived children from the count
+		if meta.ChildOrigin != ChildOriginAuto && !meta.Archived {
+			count++
 		}
 	}
 	return count, nil
 }
 
 // HasChildSessions returns true if the session has at least one child.
-// More efficient than CountChildSessions when only existence check is needed.
 func (s *Store) HasChildSessions(parentID string) (bool, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -710,25 +537,11 @@ func (s *Store) HasChildSessions(parentID string) (bool, error) {
 		return false, ErrStoreClosed
 	}
 
-	entries, err := os.ReadDir(s.baseDir)
+	children, err := s.backend.listChildren(parentID)
 	if err != nil {
 		return false, err
 	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		sessionID := entry.Name()
-		meta, err := s.readMetadata(sessionID)
-		if err != nil {
-			continue
-		}
-		if meta.ParentSessionID == parentID {
-			return true, nil
-		}
-	}
-	return false, nil
+	return len(children) > 0, nil
 }
 
 // Delete removes a session and all its data from local storage.
@@ -753,7 +566,7 @@ func (s *Store) Delete(sessionID string) error {
 	}
 
 	sessionDir := s.sessionDir(sessionID)
-	if _, err := os.Stat(sessionDir); os.IsNotExist(err) {
+	if _, err := os.Stat(sessionDir); os.IsNotExist(err) && !s.backend.exists(sessionID) {
 		return ErrSessionNotFound
 	}
 
@@ -764,7 +577,7 @@ func (s *Store) Delete(sessionID string) error {
 		// Continue with deletion even if cleanup fails - we don't want to block deletion
 	}
 
-	if err := os.RemoveAll(sessionDir); err != nil {
+	if err := s.removeSessionLocked(sessionID); err != nil {
 		return err
 	}
 
@@ -772,6 +585,15 @@ func (s *Store) Delete(sessionID string) error {
 	return nil
 }
 
+// removeSessionLocked removes a session's backend records and its directory
+// (must be called with lock held).
+func (s *Store) removeSessionLocked(sessionID string) error {
+	if err := s.backend.remove(sessionID); err != nil {
+		return err
+	}
+	return os.RemoveAll(s.sessionDir(sessionID))
+}
+
 // Exists checks if a session exists.
 func (s *Store) Exists(sessionID string) bool {
 	s.mu.RLock()
@@ -781,8 +603,7 @@ func (s *Store) Exists(sessionID string) bool {
 		return false
 	}
 
-	_, err := os.Stat(s.metadataPath(sessionID))
-	return err == nil
+	return s.backend.exists(sessionID)
 }
 
 // handleChildSessionsOnParentDelete cascade-deletes ALL child sessions when parent is deleted.
@@ -801,53 +622,39 @@ func (s *Store) handleChildSessionsOnParentDelete(parentSessionID string, visite
 	}
 	visited[parentSessionID] = true
 
-	// Read all session directories
-	entries, err := os.ReadDir(s.baseDir)
+	children, err := s.backend.listChildren(parentSessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read sessions directory: %w", err)
+		return nil, err
 	}
 
 	var deletedIDs []string
 	var deleteErrors []error
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		sessionID := entry.Name()
+	for _, meta := range children {
+		sessionID := meta.SessionID
 		if sessionID == parentSessionID {
 			continue
 		}
 
-		meta, err := s.readMetadata(sessionID)
-		if err != nil {
+		// CASCADE DELETE: Recursively delete this child and all its descendants
+		// First, handle this child's own children
+		grandchildDeleted, _ := s.handleChildSessionsOnParentDelete(sessionID, visited)
+		deletedIDs = append(deletedIDs, grandchildDeleted...)
+
+		// Now delete this child
+		if err := s.removeSessionLocked(sessionID); err != nil {
+			deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete child %s: %w", sessionID, err))
 			continue
 		}
+		deletedIDs = append(deletedIDs, sessionID)
 
-		// Check if this session has the parent we're deleting
-		if meta.ParentSessionID == parentSessionID {
-			// CASCADE DELETE: Recursively delete this child and all its descendants
-			// First, handle this child's own children
-			grandchildDeleted, _ := s.handleChildSessionsOnParentDelete(sessionID, visited)
-			deletedIDs = append(deletedIDs, grandchildDeleted...)
-
-			// Now delete this child
-			sessionDir := s.sessionDir(sessionID)
-			if err := os.RemoveAll(sessionDir); err != nil {
-				deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete child %s: %w", sessionID, err))
-				continue
-			}
-			deletedIDs = append(deletedIDs, sessionID)
-
-			// Migrate for logging purposes
-			meta.MigrateChildOrigin()
-			log.Info("Cascade deleted child session",
-				"parent_session_id", parentSessionID,
-				"deleted_session_id", sessionID,
-				"session_name", meta.Name,
-				"child_origin", string(meta.ChildOrigin))
-		}
+		// Migrate for logging purposes
+		meta.MigrateChildOrigin()
+		log.Info("Cascade deleted child session",
+			"parent_session_id", parentSessionID,
+			"deleted_session_id", sessionID,
+			"session_name", meta.Name,
+			"child_origin", string(meta.ChildOrigin))
 	}
 
 	if len(deleteErrors) > 0 {
@@ -874,22 +681,7 @@ func (s *Store) CountSessions() (int, error) {
 		return 0, ErrStoreClosed
 	}
 
-	entries, err := os.ReadDir(s.baseDir)
-	if err != nil {
-		return 0, fmt.Errorf("failed to read sessions directory: %w", err)
-	}
-
-	count := 0
-	for _, entry := range entries {
-		if entry.IsDir() {
-			// Check if it has a metadata file (valid session)
-			metaPath := filepath.Join(s.baseDir, entry.Name(), "metadata.json")
-			if _, err := os.Stat(metaPath); err == nil {
-				count++
-			}
-		}
-	}
-	return count, nil
+	return s.backend.count()
 }
 
 // CleanupArchivedSessions deletes archived sessions older than the specified retention period.
@@ -916,28 +708,16 @@ func (s *Store) CleanupArchivedSessions(retentionPeriod string) (int, error) {
 		return 0, ErrStoreClosed
 	}
 
-	sessions, err := os.ReadDir(s.baseDir)
+	sessions, err := s.backend.list()
 	if err != nil {
-		return 0, fmt.Errorf("failed to read sessions directory: %w", err)
+		return 0, err
 	}
 
 	now := time.Now()
 	var totalDeleted int
 	var deleteErrors []error
 
-	for _, sessionEntry := range sessions {
-		if !sessionEntry.IsDir() {
-			continue
-		}
-
-		sessionID := sessionEntry.Name()
-
-		// Read session metadata
-		meta, err := s.readMetadata(sessionID)
-		if err != nil {
-			continue // Skip sessions with invalid metadata
-		}
-
+	for _, meta := range sessions {
 		// Only process archived sessions
 		if !meta.Archived {
 			continue
@@ -956,15 +736,14 @@ func (s *Store) CleanupArchivedSessions(retentionPeriod string) (int, error) {
 		}
 
 		// Delete the session
-		sessionDir := s.sessionDir(sessionID)
-		if err := os.RemoveAll(sessionDir); err != nil {
-			deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete session %s: %w", sessionID, err))
+		if err := s.removeSessionLocked(meta.SessionID); err != nil {
+			deleteErrors = append(deleteErrors, fmt.Errorf("failed to delete session %s: %w", meta.SessionID, err))
 			continue
 		}
 
 		totalDeleted++
 		log.Info("deleted archived session",
-			"session_id", sessionID,
+			"session_id", meta.SessionID,
 			"archived_at", meta.ArchivedAt,
 			"age", now.Sub(meta.ArchivedAt))
 	}
@@ -1001,12 +780,15 @@ func parseRetentionPeriod(period string) (time.Duration, error) {
 	}
 }
 
-// Close closes the store.
+// Close closes the store and its backend.
 func (s *Store) Close() error {
 	log := logging.Session()
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
 	s.closed = true
 	log.Debug("session store closed", "base_dir", s.baseDir)
-	return nil
+	return s.backend.close()
 }
@@ -0,0 +1,119 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/inercia/mitto/internal/logging"
+)
+
+// ConvertStoreResult summarizes a store backend conversion.
+type ConvertStoreResult struct {
+	// Sessions is the number of sessions converted.
+	Sessions int
+	// Events is the total number of events converted.
+	Events int
+}
+
+// ConvertStore copies the metadata and events of every session in baseDir from
+// one backend to another, then removes them from the source backend.
+// Other per-session data (images, files, queue, ...) lives in the session
+// directories and is not touched.
+//
+// Pending file migrations are run before converting from the file backend.
+// If dryRun is true, nothing is written and the result reports what would be converted.
+// No Mitto process should be using the store while it is being converted.
+func ConvertStore(baseDir string, from, to StoreBackend, ctx *MigrationContext, dryRun bool) (ConvertStoreResult, error) {
+	log := logging.Session()
+	var result ConvertStoreResult
+
+	if from == to {
+		return result, fmt.Errorf("source and destination backends are the same: %s", from)
+	}
+
+	if from == StoreBackendSQLite && DetectStoreBackend(baseDir) != StoreBackendSQLite {
+		return result, fmt.Errorf("no session database found in %s", baseDir)
+	}
+
+	if from == StoreBackendFile && !dryRun {
+		if err := RunMigrations(baseDir, ctx); err != nil {
+			return result, fmt.Errorf("failed to run migrations: %w", err)
+		}
+	}
+
+	src, err := newStoreBackend(from, baseDir)
+	if err != nil {
+		return result, err
+	}
+	defer src.close()
+
+	sessions, err := src.list()
+	if err != nil {
+		return result, err
+	}
+
+	var dst storeBackend
+	if !dryRun {
+		dst, err = newStoreBackend(to, baseDir)
+		if err != nil {
+			return result, err
+		}
+		defer dst.close()
+	}
+
+	for _, meta := range sessions {
+		events, err := src.readEventsFrom(meta.SessionID, 0, 0)
+		if err != nil {
+			return result, fmt.Errorf("failed to read events of session %s: %w", meta.SessionID, err)
+		}
+		if !dryRun {
+			if err := os.MkdirAll(filepath.Join(baseDir, meta.SessionID), 0755); err != nil {
+				return result, fmt.Errorf("failed to create session directory: %w", err)
+			}
+			if err := dst.replaceEvents(meta, events); err != nil {
+				return result, fmt.Errorf("failed to write session %s: %w", meta.SessionID, err)
+			}
+		}
+		result.Sessions++
+		result.Events += len(events)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	// Only remove the source data once everything has been copied.
+	for _, meta := range sessions {
+		if err := src.remove(meta.SessionID); err != nil {
+			return result, fmt.Errorf("failed to remove session %s from %s backend: %w", meta.SessionID, from, err)
+		}
+	}
+	if from == StoreBackendSQLite {
+		if err := src.close(); err != nil {
+			return result, err
+		}
+		if err := removeSQLiteFiles(baseDir); err != nil {
+			return result, err
+		}
+	}
+
+	log.Info("session store converted",
+		"base_dir", baseDir,
+		"from", from,
+		"to", to,
+		"sessions", result.Sessions,
+		"events", result.Events)
+	return result, nil
+}
+
+// removeSQLiteFiles removes the SQLite database and its WAL/SHM side files.
+func removeSQLiteFiles(baseDir string) error {
+	path := filepath.Join(baseDir, sqliteFileName)
+	for _, p := range []string{path, path + "-wal", path + "-shm"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", filepath.Base(p), err)
+		}
+	}
+	return nil
+}
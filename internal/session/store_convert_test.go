@@ -0,0 +1,181 @@
+package session
+
+import (
+	"testing"
+)
+
+func TestParseStoreBackend(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    StoreBackend
+		wantErr bool
+	}{
+		{"", StoreBackendFile, false},
+		{"file", StoreBackendFile, false},
+		{"sqlite", StoreBackendSQLite, false},
+		{"postgres", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStoreBackend(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStoreBackend(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStoreBackend(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestConvertStore_RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	store, err := NewStore(tmpDir)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	for _, meta := range []Metadata{
+		{SessionID: "parent", ACPServer: "server", Name: "Parent"},
+		{SessionID: "child", ACPServer: "server", ParentSessionID: "parent", ChildOrigin: ChildOriginMCP},
+	} {
+		if err := store.Create(meta); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if err := store.AppendEvent("parent", Event{Type: EventTypeAgentMessage, Data: AgentMessageData{Text: "hi"}}); err != nil {
+			t.Fatalf("AppendEvent failed: %v", err)
+		}
+	}
+	wantMeta, _ := store.GetMetadata("parent")
+	store.Close()
+
+	if got := DetectStoreBackend(tmpDir); got != StoreBackendFile {
+		t.Errorf("DetectStoreBackend before conversion = %q, want %q", got, StoreBackendFile)
+	}
+
+	// Dry run doesn't change anything
+	result, err := ConvertStore(tmpDir, StoreBackendFile, StoreBackendSQLite, nil, true)
+	if err != nil {
+		t.Fatalf("ConvertStore (dry run) failed: %v", err)
+	}
+	if result.Sessions != 2 || result.Events != 3 {
+		t.Errorf("dry run result = %+v, want 2 sessions and 3 events", result)
+	}
+	if got := DetectStoreBackend(tmpDir); got != StoreBackendFile {
+		t.Errorf("DetectStoreBackend after dry run = %q, want %q", got, StoreBackendFile)
+	}
+
+	// file -> sqlite
+	if _, err := ConvertStore(tmpDir, StoreBackendFile, StoreBackendSQLite, nil, false); err != nil {
+		t.Fatalf("ConvertStore (file -> sqlite) failed: %v", err)
+	}
+	if got := DetectStoreBackend(tmpDir); got != StoreBackendSQLite {
+		t.Errorf("DetectStoreBackend after conversion = %q, want %q", got, StoreBackendSQLite)
+	}
+
+	fileStore, err := NewStore(tmpDir)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	if n, _ := fileStore.CountSessions(); n != 0 {
+		t.Errorf("file backend still has %d sessions after conversion", n)
+	}
+	fileStore.Close()
+
+	sqliteStore, err := NewStoreWithBackend(tmpDir, StoreBackendSQLite)
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend failed: %v", err)
+	}
+	gotMeta, err := sqliteStore.GetMetadata("parent")
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if !gotMeta.CreatedAt.Equal(wantMeta.CreatedAt) || gotMeta.EventCount != 3 || gotMeta.Name != "Parent" {
+		t.Errorf("converted metadata = %+v, want %+v", gotMeta, wantMeta)
+	}
+	children, err := sqliteStore.ListChildSessions("parent")
+	if err != nil || len(children) != 1 || children[0].SessionID != "child" {
+		t.Errorf("ListChildSessions = %v, %v; want [child]", children, err)
+	}
+	sqliteStore.Close()
+
+	// sqlite -> file
+	result, err = ConvertStore(tmpDir, StoreBackendSQLite, StoreBackendFile, nil, false)
+	if err != nil {
+		t.Fatalf("ConvertStore (sqlite -> file) failed: %v", err)
+	}
+	if result.Sessions != 2 || result.Events != 3 {
+		t.Errorf("result = %+v, want 2 sessions and 3 events", result)
+	}
+	if got := DetectStoreBackend(tmpDir); got != StoreBackendFile {
+		t.Errorf("DetectStoreBackend after converting back = %q, want %q", got, StoreBackendFile)
+	}
+
+	fileStore, err = NewStore(tmpDir)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer fileStore.Close()
+	events, err := fileStore.ReadEvents("parent")
+	if err != nil {
+		t.Fatalf("ReadEvents failed: %v", err)
+	}
+	if len(events) != 3 || events[0].Seq != 1 || events[2].Seq != 3 {
+		t.Errorf("events after round trip = %+v, want seqs 1..3", events)
+	}
+}
+
+func TestConvertStore_SameBackend(t *testing.T) {
+	if _, err := ConvertStore(t.TempDir(), StoreBackendFile, StoreBackendFile, nil, false); err == nil {
+		t.Error("ConvertStore with same source and destination should fail")
+	}
+}
+
+func TestConvertStore_MissingDatabase(t *testing.T) {
+	if _, err := ConvertStore(t.TempDir(), StoreBackendSQLite, StoreBackendFile, nil, false); err == nil {
+		t.Error("ConvertStore from sqlite without a database should fail")
+	}
+}
+
+func TestStore_PruneSQLite(t *testing.T) {
+	store, err := NewStoreWithBackend(t.TempDir(), StoreBackendSQLite)
+	if err != nil {
+		t.Fatalf("NewStoreWithBackend failed: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Create(Metadata{SessionID: "s", ACPServer: "server"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := store.AppendEvent("s", Event{Type: EventTypeAgentMessage, Data: AgentMessageData{Text: "msg"}}); err != nil {
+			t.Fatalf("AppendEvent failed: %v", err)
+		}
+	}
+
+	result, err := store.PruneKeepLast("s", 4)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if result == nil || result.EventsRemoved != 6 || result.BytesReclaimed <= 0 {
+		t.Fatalf("Prune result = %+v, want 6 events removed and bytes reclaimed", result)
+	}
+
+	events, err := store.ReadEvents("s")
+	if err != nil {
+		t.Fatalf("ReadEvents failed: %v", err)
+	}
+	if len(events) != 4 || events[0].Seq != 7 {
+		t.Errorf("remaining events = %d (first seq %d), want 4 starting at seq 7", len(events), events[0].Seq)
+	}
+
+	// New events continue after the preserved MaxSeq
+	if err := store.AppendEvent("s", Event{Type: EventTypeAgentMessage, Data: AgentMessageData{Text: "next"}}); err != nil {
+		t.Fatalf("AppendEvent failed: %v", err)
+	}
+	meta, _ := store.GetMetadata("s")
+	if meta.MaxSeq != 11 || meta.EventCount != 5 {
+		t.Errorf("metadata MaxSeq=%d EventCount=%d, want 11 and 5", meta.MaxSeq, meta.EventCount)
+	}
+}
@@ -90,6 +90,7 @@ const (
 	EventTypeSessionStart   EventType = "session_start"
 	EventTypeSessionEnd     EventType = "session_end"
 	EventTypeUIPromptAnswer EventType = "ui_prompt_answer"
+	EventTypeUsage          EventType = "usage"
 )
 
 // SessionStatus represents the status of a session.
@@ -131,6 +132,7 @@ type UserPromptData struct {
 	Files      []FileRef  `json:"files,omitempty"`
 	PromptID   string     `json:"prompt_id,omitempty"`   // Client-generated ID for delivery confirmation
 	PromptName string     `json:"prompt_name,omitempty"` // Name of the workspace prompt used (for UI rendering)
+	SentBy     string     `json:"sent_by,omitempty"`     // User who sent the prompt, when authenticated (e.g. "alice", "cf:bob@example.com")
 }
 
 // AgentMessageData contains data for an agent message event.
@@ -184,6 +186,12 @@ type PermissionData struct {
 	Title          string `json:"title"`
 	SelectedOption string `json:"selected_option"`
 	Outcome        string `json:"outcome"` // "approved", "denied", "cancelled"
+	// Rule is the permission rule that matched the request (empty if none matched).
+	Rule string `json:"rule,omitempty"`
+	// RuleScope is where the rule is configured: "workspace", "agent" or "global".
+	RuleScope string `json:"rule_scope,omitempty"`
+	// RuleError is set when the rule could not be evaluated.
+	RuleError string `json:"rule_error,omitempty"`
 }
 
 // FileOperationData contains data for file read/write events.
@@ -253,13 +261,36 @@ type Metadata struct {
 	IsAutoChild bool `json:"is_auto_child,omitempty"`
 	// ChildOrigin indicates how a child conversation was created.
 	// Empty string means this is a top-level session (not a child).
-	// Possible values: ChildOriginAuto, ChildOriginMCP, ChildOriginHuman.
+	// Possible values: ChildOriginAuto, ChildOriginMCP, ChildOriginHuman, ChildOriginFork.
 	ChildOrigin ChildOrigin `json:"child_origin,omitempty"`
 	// ACPStartFailureCount tracks consecutive ACP process start failures across restarts.
 	// Incremented each time ResumeSession fails to start the ACP process.
 	// Reset to 0 on successful start. When it reaches ACPStartFailureThreshold,
 	// the session is auto-archived to prevent infinite retry loops.
 	ACPStartFailureCount int `json:"acp_start_failure_count,omitempty"`
+	// Usage holds the token usage and cost of the session per local day
+	// (keyed by UsageDayFormat). It is kept in the metadata, rather than only in
+	// usage events, so that totals survive event pruning and can be aggregated
+	// without reading event logs.
+	Usage map[string]UsageTotals `json:"usage,omitempty"`
+	// Resources holds the CPU time, wall-clock time and peak memory of the agent
+	// process while answering the prompts of the session, per local day (keyed
+	// by UsageDayFormat).
+	Resources map[string]ResourceTotals `json:"resources,omitempty"`
+	// Worktree describes the git worktree the session is isolated in, when its
+	// workspace has worktree isolation enabled. WorkingDir is then inside the worktree.
+	// It is cleared once the session branch is merged, rebased or discarded.
+	Worktree *WorktreeInfo `json:"worktree,omitempty"`
+}
+
+// WorktreeInfo describes the git worktree of an isolated session.
+type WorktreeInfo struct {
+	Path         string `json:"path"`                  // Root of the worktree
+	RepoDir      string `json:"repo_dir"`              // Top-level directory of the main repository
+	WorkspaceDir string `json:"workspace_dir"`         // Workspace folder the worktree stands in for
+	Branch       string `json:"branch"`                // Per-session branch checked out in the worktree
+	BaseBranch   string `json:"base_branch,omitempty"` // Branch the worktree was created from (empty if detached)
+	BaseCommit   string `json:"base_commit"`           // Commit the worktree was created from
 }
 
 // ChildOrigin represents how a child conversation was created.
@@ -275,6 +306,9 @@ const (
 	// ChildOriginHuman means the child was manually created by the user.
 	// These are orphaned when the parent is deleted.
 	ChildOriginHuman ChildOrigin = "human"
+	// ChildOriginFork means the child was forked from its parent at some event,
+	// starting with a copy of the parent's events up to that point.
+	ChildOriginFork ChildOrigin = "fork"
 )
 
 // MigrateChildOrigin ensures ChildOrigin is populated for backward compatibility.
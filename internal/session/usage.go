@@ -0,0 +1,224 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// UsageDayFormat is the format of the day keys in Metadata.Usage (local time).
+const UsageDayFormat = "2006-01-02"
+
+// UsageData contains data for a usage event, recorded after each prompt.
+type UsageData struct {
+	InputTokens       int64 `json:"input_tokens"`
+	OutputTokens      int64 `json:"output_tokens"`
+	CachedReadTokens  int64 `json:"cached_read_tokens,omitempty"`
+	CachedWriteTokens int64 `json:"cached_write_tokens,omitempty"`
+	ThoughtTokens     int64 `json:"thought_tokens,omitempty"`
+	TotalTokens       int64 `json:"total_tokens"`
+	// Estimated is true when the agent didn't report usage and the token
+	// counts were estimated from the prompt and response text.
+	Estimated bool `json:"estimated,omitempty"`
+	// Cost is the cost of the prompt, computed from the ACP server's price table
+	// (zero when the server has no pricing configured).
+	Cost     float64 `json:"cost,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// UsageTotals accumulates token usage and cost over a number of prompts.
+type UsageTotals struct {
+	Turns             int   `json:"turns"`
+	EstimatedTurns    int   `json:"estimated_turns,omitempty"`
+	InputTokens       int64 `json:"input_tokens"`
+	OutputTokens      int64 `json:"output_tokens"`
+	CachedReadTokens  int64 `json:"cached_read_tokens,omitempty"`
+	CachedWriteTokens int64 `json:"cached_write_tokens,omitempty"`
+	ThoughtTokens     int64 `json:"thought_tokens,omitempty"`
+	TotalTokens       int64 `json:"total_tokens"`
+	// EstimatedTokens is the part of TotalTokens that was estimated
+	// (see UsageData.Estimated) rather than reported by the agent.
+	EstimatedTokens int64   `json:"estimated_tokens,omitempty"`
+	Cost            float64 `json:"cost"`
+}
+
+// Add adds the usage of a single prompt.
+func (t *UsageTotals) Add(d UsageData) {
+	t.Turns++
+	if d.Estimated {
+		t.EstimatedTurns++
+		t.EstimatedTokens += d.TotalTokens
+	}
+	t.InputTokens += d.InputTokens
+	t.OutputTokens += d.OutputTokens
+	t.CachedReadTokens += d.CachedReadTokens
+	t.CachedWriteTokens += d.CachedWriteTokens
+	t.ThoughtTokens += d.ThoughtTokens
+	t.TotalTokens += d.TotalTokens
+	t.Cost += d.Cost
+}
+
+// Merge adds other totals.
+func (t *UsageTotals) Merge(o UsageTotals) {
+	t.Turns += o.Turns
+	t.EstimatedTurns += o.EstimatedTurns
+	t.InputTokens += o.InputTokens
+	t.OutputTokens += o.OutputTokens
+	t.CachedReadTokens += o.CachedReadTokens
+	t.CachedWriteTokens += o.CachedWriteTokens
+	t.ThoughtTokens += o.ThoughtTokens
+	t.TotalTokens += o.TotalTokens
+	t.EstimatedTokens += o.EstimatedTokens
+	t.Cost += o.Cost
+}
+
+// AddUsage adds the usage of a prompt made at the given time to the session's
+// daily totals, and appends it to the usage ledger (see UsageLedger).
+func (s *Store) AddUsage(sessionID string, at time.Time, data UsageData) error {
+	day := at.Local().Format(UsageDayFormat)
+	entry := UsageLedgerEntry{Time: at, SessionID: sessionID, UsageData: data}
+	err := s.UpdateMetadata(sessionID, func(m *Metadata) {
+		if m.Usage == nil {
+			m.Usage = make(map[string]UsageTotals)
+		}
+		totals := m.Usage[day]
+		totals.Add(data)
+		m.Usage[day] = totals
+		entry.WorkingDir = m.WorkingDir
+		entry.ACPServer = m.ACPServer
+	})
+	// The prompt was made even if the session can't be updated
+	if ledgerErr := s.appendUsageLedger(entry); err == nil {
+		err = ledgerErr
+	}
+	return err
+}
+
+// UsageGroupBy is the dimension a usage report is grouped by.
+type UsageGroupBy string
+
+const (
+	UsageGroupBySession   UsageGroupBy = "session"
+	UsageGroupByWorkspace UsageGroupBy = "workspace"
+	UsageGroupByAgent     UsageGroupBy = "agent"
+	UsageGroupByDay       UsageGroupBy = "day"
+)
+
+// ParseUsageGroupBy parses a group-by dimension. An empty string means UsageGroupBySession.
+func ParseUsageGroupBy(s string) (UsageGroupBy, error) {
+	switch g := UsageGroupBy(s); g {
+	case "":
+		return UsageGroupBySession, nil
+	case UsageGroupBySession, UsageGroupByWorkspace, UsageGroupByAgent, UsageGroupByDay:
+		return g, nil
+	default:
+		return "", fmt.Errorf("invalid group_by %q (expected session, workspace, agent or day)", s)
+	}
+}
+
+// UsageQuery selects the usage to include in a report.
+type UsageQuery struct {
+	GroupBy UsageGroupBy
+	// Since and Until restrict the report to the days in this range, inclusive
+	// (zero = unbounded). Only the day (in local time) is taken into account.
+	Since time.Time
+	Until time.Time
+	// WorkingDir restricts the report to sessions in this workspace folder (exact match).
+	WorkingDir string
+	// ACPServer restricts the report to sessions using this ACP server (exact match).
+	ACPServer string
+}
+
+// UsageGroup is the usage of one group in a report.
+type UsageGroup struct {
+	// Key is the session ID, workspace folder, ACP server name or day, depending on the grouping.
+	Key string `json:"key"`
+	// Name is the session name, when grouping by session.
+	Name string `json:"name,omitempty"`
+	UsageTotals
+}
+
+// UsageReport is the token usage and cost of a set of sessions.
+type UsageReport struct {
+	GroupBy UsageGroupBy `json:"group_by"`
+	Total   UsageTotals  `json:"total"`
+	Groups  []UsageGroup `json:"groups"`
+}
+
+// SummarizeUsage aggregates the usage recorded in the sessions' metadata.
+// Days are sorted chronologically; other groups by decreasing cost and tokens.
+func SummarizeUsage(sessions []Metadata, q UsageQuery) UsageReport {
+	if q.GroupBy == "" {
+		q.GroupBy = UsageGroupBySession
+	}
+	var since, until string
+	if !q.Since.IsZero() {
+		since = q.Since.Local().Format(UsageDayFormat)
+	}
+	if !q.Until.IsZero() {
+		until = q.Until.Local().Format(UsageDayFormat)
+	}
+
+	report := UsageReport{GroupBy: q.GroupBy, Groups: []UsageGroup{}}
+	groups := make(map[string]*UsageGroup)
+	for _, meta := range sessions {
+		if q.WorkingDir != "" && meta.WorkingDir != q.WorkingDir {
+			continue
+		}
+		if q.ACPServer != "" && meta.ACPServer != q.ACPServer {
+			continue
+		}
+		for day, totals := range meta.Usage {
+			if (since != "" && day < since) || (until != "" && day > until) {
+				continue
+			}
+			var key string
+			switch q.GroupBy {
+			case UsageGroupByWorkspace:
+				key = meta.WorkingDir
+			case UsageGroupByAgent:
+				key = meta.ACPServer
+			case UsageGroupByDay:
+				key = day
+			default:
+				key = meta.SessionID
+			}
+			g := groups[key]
+			if g == nil {
+				g = &UsageGroup{Key: key}
+				if q.GroupBy == UsageGroupBySession {
+					g.Name = meta.Name
+				}
+				groups[key] = g
+			}
+			g.Merge(totals)
+			report.Total.Merge(totals)
+		}
+	}
+
+	for _, g := range groups {
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if q.GroupBy != UsageGroupByDay {
+			if a.Cost != b.Cost {
+				return a.Cost > b.Cost
+			}
+			if a.TotalTokens != b.TotalTokens {
+				return a.TotalTokens > b.TotalTokens
+			}
+		}
+		return a.Key < b.Key
+	})
+	return report
+}
+
+// UsageReport aggregates the usage of all the sessions in the store.
+func (s *Store) UsageReport(q UsageQuery) (UsageReport, error) {
+	sessions, err := s.List()
+	if err != nil {
+		return UsageReport{}, err
+	}
+	return SummarizeUsage(sessions, q), nil
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// usageLedgerMonthFormat is the format of the month in the ledger file names (local time).
+const usageLedgerMonthFormat = "2006-01"
+
+// UsageLedgerEntry is the usage of one prompt in the usage ledger.
+type UsageLedgerEntry struct {
+	Time       time.Time `json:"time"`
+	SessionID  string    `json:"session_id"`
+	WorkingDir string    `json:"working_dir,omitempty"`
+	ACPServer  string    `json:"acp_server,omitempty"`
+	UsageData
+}
+
+// usageLedgerPath returns the ledger file of the month of t.
+// The ledger is kept in the base directory, outside the session directories,
+// so that it doesn't depend on the lifetime of the sessions.
+func (s *Store) usageLedgerPath(t time.Time) string {
+	return filepath.Join(s.baseDir, "usage-"+t.Local().Format(usageLedgerMonthFormat)+".jsonl")
+}
+
+// appendUsageLedger appends an entry to the usage ledger.
+func (s *Store) appendUsageLedger(entry UsageLedgerEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	f, err := os.OpenFile(s.usageLedgerPath(entry.Time), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write usage ledger: %w", err)
+	}
+	return f.Close()
+}
+
+// UsageLedger returns the usage of the prompts made since the given time,
+// oldest first. Unlike the totals in the session metadata, the ledger is
+// append-only: deleting or archiving a session doesn't remove its usage.
+func (s *Store) UsageLedger(since time.Time) ([]UsageLedgerEntry, error) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	var entries []UsageLedgerEntry
+	now := time.Now().Local()
+	since = since.Local()
+	for month := time.Date(since.Year(), since.Month(), 1, 0, 0, 0, 0, time.Local); !month.After(now); month = month.AddDate(0, 1, 0) {
+		f, err := os.Open(s.usageLedgerPath(month))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry UsageLedgerEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				// Skip a line damaged by an interrupted write
+				continue
+			}
+			if !entry.Time.Before(since) {
+				entries = append(entries, entry)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+		}
+	}
+	return entries, nil
+}
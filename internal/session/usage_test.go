@@ -0,0 +1,115 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecorder_RecordUsage(t *testing.T) {
+	store := newSearchTestStore(t)
+	recorder := NewRecorder(store)
+	if err := recorder.Start("auggie", "/proj", ""); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	for _, d := range []UsageData{
+		{InputTokens: 1000, OutputTokens: 200, TotalTokens: 1200, Cost: 0.5},
+		{TotalTokens: 300, Estimated: true},
+	} {
+		if err := recorder.RecordUsage(d); err != nil {
+			t.Fatalf("RecordUsage failed: %v", err)
+		}
+	}
+
+	events, err := store.ReadEvents(recorder.SessionID())
+	if err != nil {
+		t.Fatalf("ReadEvents failed: %v", err)
+	}
+	last := events[len(events)-1]
+	decoded, err := DecodeEventData(last)
+	if err != nil {
+		t.Fatalf("DecodeEventData failed: %v", err)
+	}
+	if data, ok := decoded.(UsageData); !ok || data.TotalTokens != 300 || !data.Estimated {
+		t.Errorf("last event = %+v, want the estimated usage event", decoded)
+	}
+
+	meta, err := store.GetMetadata(recorder.SessionID())
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	today := meta.Usage[time.Now().Format(UsageDayFormat)]
+	if len(meta.Usage) != 1 || today.Turns != 2 || today.EstimatedTurns != 1 || today.TotalTokens != 1500 || today.Cost != 0.5 {
+		t.Errorf("Usage = %+v", meta.Usage)
+	}
+	if today.EstimatedTokens != 300 {
+		t.Errorf("EstimatedTokens = %d, want 300", today.EstimatedTokens)
+	}
+
+	// The ledger keeps the usage after the session is deleted
+	if err := store.Delete(recorder.SessionID()); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	entries, err := store.UsageLedger(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("UsageLedger failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].SessionID != recorder.SessionID() || entries[0].WorkingDir != "/proj" || entries[1].TotalTokens != 300 {
+		t.Errorf("UsageLedger() = %+v", entries)
+	}
+	if entries, _ := store.UsageLedger(time.Now().Add(time.Hour)); len(entries) != 0 {
+		t.Errorf("UsageLedger(future) = %+v, want none", entries)
+	}
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	sessions := []Metadata{
+		{SessionID: "a", Name: "A", WorkingDir: "/p1", ACPServer: "claude", Usage: map[string]UsageTotals{
+			"2026-01-01": {Turns: 1, TotalTokens: 100, Cost: 1},
+			"2026-01-02": {Turns: 2, TotalTokens: 200, Cost: 2},
+		}},
+		{SessionID: "b", WorkingDir: "/p2", ACPServer: "claude", Usage: map[string]UsageTotals{
+			"2026-01-02": {Turns: 1, TotalTokens: 1000, Cost: 5},
+		}},
+		{SessionID: "c", WorkingDir: "/p1", ACPServer: "auggie", Usage: map[string]UsageTotals{
+			"2026-01-03": {Turns: 1, TotalTokens: 50},
+		}},
+		{SessionID: "no-usage", WorkingDir: "/p1", ACPServer: "auggie"},
+	}
+
+	report := SummarizeUsage(sessions, UsageQuery{})
+	if report.GroupBy != UsageGroupBySession || report.Total.TotalTokens != 1350 || report.Total.Cost != 8 || len(report.Groups) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.Groups[0].Key != "b" || report.Groups[1].Key != "a" || report.Groups[1].Name != "A" || report.Groups[1].Turns != 3 {
+		t.Errorf("groups = %+v, want sorted by cost", report.Groups)
+	}
+
+	report = SummarizeUsage(sessions, UsageQuery{GroupBy: UsageGroupByDay})
+	if len(report.Groups) != 3 || report.Groups[0].Key != "2026-01-01" || report.Groups[1].TotalTokens != 1200 {
+		t.Errorf("day groups = %+v", report.Groups)
+	}
+
+	report = SummarizeUsage(sessions, UsageQuery{GroupBy: UsageGroupByWorkspace, ACPServer: "claude"})
+	if len(report.Groups) != 2 || report.Groups[0].Key != "/p2" || report.Groups[1].Key != "/p1" || report.Total.Cost != 8 {
+		t.Errorf("workspace groups = %+v", report.Groups)
+	}
+
+	since := time.Date(2026, 1, 2, 12, 0, 0, 0, time.Local)
+	report = SummarizeUsage(sessions, UsageQuery{GroupBy: UsageGroupByAgent, Since: since, Until: since})
+	if len(report.Groups) != 1 || report.Groups[0].Key != "claude" || report.Total.TotalTokens != 1200 {
+		t.Errorf("agent groups = %+v", report.Groups)
+	}
+}
+
+func TestParseUsageGroupBy(t *testing.T) {
+	if g, err := ParseUsageGroupBy(""); err != nil || g != UsageGroupBySession {
+		t.Errorf("ParseUsageGroupBy(\"\") = %q, %v", g, err)
+	}
+	if g, err := ParseUsageGroupBy("workspace"); err != nil || g != UsageGroupByWorkspace {
+		t.Errorf("ParseUsageGroupBy(workspace) = %q, %v", g, err)
+	}
+	if _, err := ParseUsageGroupBy("month"); err == nil {
+		t.Error("ParseUsageGroupBy(month) should fail")
+	}
+}
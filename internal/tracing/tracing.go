@@ -0,0 +1,111 @@
+// Package tracing exports OpenTelemetry traces of the prompt lifecycle to an
+// OTLP/HTTP collector.
+//
+// Tracing is off unless Setup is called with an enabled configuration: until
+// then the global tracer provider is a no-op, and the spans started with
+// Start cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+// instrumentationName is the name of the tracer of Mitto.
+const instrumentationName = "github.com/inercia/mitto"
+
+// Environment variables carrying the trace context to external commands,
+// following the W3C Trace Context format.
+const (
+	EnvTraceParent = "TRACEPARENT"
+	EnvTraceState  = "TRACESTATE"
+)
+
+// propagator propagates the trace context in W3C Trace Context headers.
+var propagator = propagation.TraceContext{}
+
+// Setup installs the global tracer provider exporting to the collector of the
+// configuration. It returns a function that flushes the pending spans and
+// stops the export; when tracing is disabled, it does nothing.
+func Setup(ctx context.Context, cfg *config.TracingConfig) (shutdown func(context.Context) error, err error) {
+	if !cfg.IsEnabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.GetEndpoint()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.GetServiceName()),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.GetSampleRatio()))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span, child of the span in ctx (if any).
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends a span, recording err (if not nil) as its error.
+// Context cancellations are recorded without marking the span as failed.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !errors.Is(err, context.Canceled) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+// Environ returns the environment variables that propagate the trace context
+// of ctx to a child process (TRACEPARENT and TRACESTATE), or nil when ctx has
+// no span.
+func Environ(ctx context.Context) []string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	keys := carrier.Keys()
+	sort.Strings(keys)
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, strings.ToUpper(key)+"="+carrier.Get(key))
+	}
+	return env
+}
+
+// Extract returns ctx with the remote span context found in the W3C Trace
+// Context headers of a request, if any.
+func Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return propagator.Extract(ctx, carrier)
+}
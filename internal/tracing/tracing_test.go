@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+// useRecorder installs a tracer provider that records the ended spans.
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), &config.TracingConfig{})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
+
+func TestStartEnd(t *testing.T) {
+	recorder := useRecorder(t)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("boom"))
+	_, cancelled := Start(ctx, "cancelled")
+	End(cancelled, context.Canceled)
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("ended spans = %d, want 3", len(spans))
+	}
+	if spans[0].Name() != "child" || spans[0].Parent().SpanID() != spans[2].SpanContext().SpanID() {
+		t.Errorf("child span = %s, parent %v", spans[0].Name(), spans[0].Parent().SpanID())
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "boom" {
+		t.Errorf("child status = %+v, want error", spans[0].Status())
+	}
+	if spans[1].Status().Code == codes.Error {
+		t.Error("cancelled span marked as failed")
+	}
+	if spans[2].Status().Code != codes.Unset {
+		t.Errorf("parent status = %+v, want unset", spans[2].Status())
+	}
+}
+
+func TestEnvironAndExtract(t *testing.T) {
+	useRecorder(t)
+
+	if env := Environ(context.Background()); env != nil {
+		t.Errorf("Environ() without span = %v, want nil", env)
+	}
+
+	ctx, span := Start(context.Background(), "processor")
+	defer span.End()
+	env := Environ(ctx)
+	if len(env) != 1 || !strings.HasPrefix(env[0], EnvTraceParent+"=00-"+span.SpanContext().TraceID().String()) {
+		t.Fatalf("Environ() = %v", env)
+	}
+
+	// The trace context round-trips through HTTP headers
+	header := http.Header{}
+	header.Set("Traceparent", strings.TrimPrefix(env[0], EnvTraceParent+"="))
+	remote := Extract(context.Background(), propagation.HeaderCarrier(header))
+	_, child := Start(remote, "tool")
+	defer child.End()
+	if child.SpanContext().TraceID() != span.SpanContext().TraceID() {
+		t.Error("span started from the extracted context is in another trace")
+	}
+}
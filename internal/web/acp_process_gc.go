@@ -430,12 +430,14 @@ gcTier1:
 				// the suspend/resume thrashing loop where reconnectAllSessionsStaggered
 				// immediately re-opens what the GC just closed.
 				m.MarkGCSuspended(s.SessionID)
+				metricGCActions.Inc(gcActionSuspendPeriodicSession)
 			} else {
 				if m.logger != nil {
 					m.logger.Info("GC: closing idle session",
 						"session_id", s.SessionID,
 						"workspace_uuid", workspaceUUID)
 				}
+				metricGCActions.Inc(gcActionCloseIdleSession)
 			}
 			m.sessionClose(s.SessionID)
 			closedCount++
@@ -508,6 +510,7 @@ gcTier1:
 					"active_rpcs", p.ActiveRPCs())
 			}
 			m.lastSessionSeen[workspaceUUID] = now
+			metricGCActions.Inc(gcActionDeferProcessStop)
 			continue
 		}
 
@@ -524,6 +527,7 @@ gcTier1:
 					"grace_period", m.gcConfig.GracePeriod))
 		}
 		m.StopProcess(workspaceUUID)
+		metricGCActions.Inc(gcActionStopIdleProcess)
 
 		m.gcMu.Lock()
 	}
@@ -642,6 +646,7 @@ gcTier1:
 			}
 			// Stop the now-sessionless process to reclaim memory.
 			m.StopProcess(workspaceUUID)
+			metricMemoryRecycles.Inc()
 			// Keep sessionless bookkeeping consistent.
 			m.gcMu.Lock()
 			delete(m.lastSessionSeen, workspaceUUID)
@@ -330,6 +330,7 @@ func (m *ACPProcessManager) GetOrCreateProcess(workspace *config.WorkspaceSettin
 		Logger:           processLogger,
 		CanRestartGlobal: m.CanRestartGlobally,
 		RecordRestart:    m.RecordGlobalRestart,
+		ResourceLimits:   workspace.ResourceLimits,
 	})
 	createDuration := time.Since(createStart)
 
@@ -492,6 +493,17 @@ func (m *ACPProcessManager) ProcessCount() int {
 	return len(m.processes)
 }
 
+// Processes returns a snapshot of the active shared processes, keyed by workspace UUID.
+func (m *ACPProcessManager) Processes() map[string]*SharedACPProcess {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	processes := make(map[string]*SharedACPProcess, len(m.processes))
+	for uuid, p := range m.processes {
+		processes[uuid] = p
+	}
+	return processes
+}
+
 // ============================================================================
 // Auxiliary Session Management (implements auxiliary.ProcessProvider)
 // ============================================================================
@@ -0,0 +1,238 @@
+//go:build linux
+
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unicode/utf8"
+)
+
+// cgroupRoot is where the cgroup v2 hierarchy is mounted.
+const cgroupRoot = "/sys/fs/cgroup"
+
+var (
+	agentCgroupParentOnce sync.Once
+	agentCgroupParent     string
+	agentCgroupParentErr  error
+)
+
+// agentCgroupParentDir returns the cgroup under which the agent cgroups are
+// created: the cgroup of the Mitto process, with the memory controller enabled
+// for its children. Cgroups with processes cannot enable controllers for their
+// children, so if needed Mitto first moves itself to a "mitto" leaf cgroup.
+// This only works when the cgroup of Mitto is delegated to its user (e.g. a
+// systemd service with Delegate=yes, or a container).
+func agentCgroupParentDir() (string, error) {
+	agentCgroupParentOnce.Do(func() {
+		agentCgroupParent, agentCgroupParentErr = prepareAgentCgroupParent()
+	})
+	return agentCgroupParent, agentCgroupParentErr
+}
+
+func prepareAgentCgroupParent() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", errors.New("cgroup v2 is not available")
+	}
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	// With cgroup v2 there is a single line: "0::/path".
+	var own string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			own = path
+		}
+	}
+	if own == "" {
+		return "", errors.New("cgroup v2 path of the process not found")
+	}
+	parent := filepath.Join(cgroupRoot, own)
+
+	controllers, err := os.ReadFile(filepath.Join(parent, "cgroup.controllers"))
+	if err != nil {
+		return "", err
+	}
+	if !hasCgroupController(controllers, "memory") {
+		return "", fmt.Errorf("memory controller not available in %s", parent)
+	}
+	if enabled, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control")); err == nil && hasCgroupController(enabled, "memory") {
+		return parent, nil
+	}
+
+	err = os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory"), 0)
+	if errors.Is(err, syscall.EBUSY) {
+		// The cgroup has processes (Mitto itself): move them to a leaf and retry.
+		leaf := filepath.Join(parent, "mitto")
+		if err := os.Mkdir(leaf, 0o755); err != nil && !os.IsExist(err) {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+			return "", fmt.Errorf("move to %s: %w", leaf, err)
+		}
+		err = os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory"), 0)
+	}
+	if err != nil {
+		return "", fmt.Errorf("enable the memory controller in %s: %w", parent, err)
+	}
+	// The pids controller enforces max_processes; without it, the processes
+	// are only checked by polling.
+	if hasCgroupController(controllers, "pids") {
+		_ = os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+pids"), 0)
+	}
+	return parent, nil
+}
+
+// hasCgroupController returns true if a cgroup.controllers or
+// cgroup.subtree_control file lists the controller.
+func hasCgroupController(data []byte, controller string) bool {
+	for _, c := range strings.Fields(string(data)) {
+		if c == controller {
+			return true
+		}
+	}
+	return false
+}
+
+// cgroupSampler samples the usage of an agent placed in its own cgroup v2. The
+// kernel enforces the memory limit (memory.max) by killing processes of the
+// cgroup, which is reported in memory.events. Unlike polling, the CPU time of
+// processes that have already exited is included.
+type cgroupSampler struct {
+	dir string
+
+	mu     sync.Mutex
+	last   resourceUsage
+	closed bool
+}
+
+// newCgroupSampler creates a cgroup for the process with the given limits (0 =
+// no limit) and moves the process into it.
+func newCgroupSampler(pid int, name string, maxMemory uint64, maxProcesses int) (*cgroupSampler, error) {
+	parent, err := agentCgroupParentDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(parent, cgroupName(name, pid))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if maxMemory > 0 {
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatUint(maxMemory, 10)), 0); err != nil {
+			_ = os.Remove(dir)
+			return nil, fmt.Errorf("set memory.max: %w", err)
+		}
+		// Swap would let the agent grow past the limit without being killed.
+		_ = os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0)
+	}
+	if maxProcesses > 0 {
+		// The agent itself is not counted. Fails without the pids controller,
+		// in which case the processes are still checked by polling.
+		_ = os.WriteFile(filepath.Join(dir, "pids.max"), []byte(strconv.Itoa(maxProcesses+1)), 0)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0); err != nil {
+		_ = os.Remove(dir)
+		return nil, fmt.Errorf("move process %d to the cgroup: %w", pid, err)
+	}
+	return &cgroupSampler{dir: dir}, nil
+}
+
+// cgroupName returns the name of the cgroup of an agent. Characters other than
+// letters, digits, '-', '_' and '.' in name are replaced, as it may be a path.
+func cgroupName(name string, pid int) string {
+	name = strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (r == '-' || r == '_' || r == '.' ||
+			'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" {
+		return fmt.Sprintf("mitto-acp-%d", pid)
+	}
+	return fmt.Sprintf("mitto-acp-%s-%d", name, pid)
+}
+
+func (s *cgroupSampler) Sample() (resourceUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return s.last, nil
+	}
+	usage, err := readCgroupUsage(s.dir)
+	if err != nil {
+		return s.last, err
+	}
+	s.last = usage
+	return usage, nil
+}
+
+// Close takes a last sample and removes the cgroup. It must be called once the
+// agent has exited: a cgroup with processes cannot be removed.
+func (s *cgroupSampler) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if usage, err := readCgroupUsage(s.dir); err == nil {
+		s.last = usage
+	}
+	return os.Remove(s.dir)
+}
+
+// readCgroupUsage reads the usage of the cgroup at dir.
+func readCgroupUsage(dir string) (resourceUsage, error) {
+	var usage resourceUsage
+
+	data, err := os.ReadFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return usage, err
+	}
+	if usage.MemoryBytes, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err != nil {
+		return usage, fmt.Errorf("parse memory.current: %w", err)
+	}
+
+	if usec, ok := readCgroupKey(filepath.Join(dir, "cpu.stat"), "usage_usec"); ok {
+		usage.CPUSeconds = float64(usec) / 1e6
+	}
+	if kills, ok := readCgroupKey(filepath.Join(dir, "memory.events"), "oom_kill"); ok {
+		usage.OOMKills = kills
+	}
+
+	if procs, err := os.ReadFile(filepath.Join(dir, "cgroup.procs")); err == nil {
+		// The agent itself is not counted.
+		usage.Processes = max(len(bytes.Fields(procs))-1, 0)
+	}
+	return usage, nil
+}
+
+// readCgroupKey reads the value of a key from a flat keyed cgroup file
+// (e.g. cpu.stat, memory.events).
+func readCgroupKey(path, key string) (uint64, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			n, err := strconv.ParseUint(fields[1], 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+//go:build linux
+
+package web
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCgroupUsage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"memory.current": "1073741824\n",
+		"cpu.stat":       "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n",
+		"memory.events":  "low 0\nhigh 0\nmax 12\noom 1\noom_kill 1\n",
+		"cgroup.procs":   "100\n101\n102\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := readCgroupUsage(dir)
+	if err != nil {
+		t.Fatalf("readCgroupUsage() error = %v", err)
+	}
+	want := resourceUsage{MemoryBytes: 1 << 30, CPUSeconds: 2.5, Processes: 2, OOMKills: 1}
+	if usage != want {
+		t.Errorf("readCgroupUsage() = %+v, want %+v", usage, want)
+	}
+
+	if _, err := readCgroupUsage(t.TempDir()); err == nil {
+		t.Error("readCgroupUsage() of an empty directory should fail")
+	}
+}
+
+func TestHasCgroupController(t *testing.T) {
+	if !hasCgroupController([]byte("cpuset cpu io memory pids\n"), "memory") {
+		t.Error("memory controller not found")
+	}
+	if hasCgroupController([]byte("cpu io pids\n"), "memory") {
+		t.Error("memory controller found")
+	}
+}
+
+func TestCgroupName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"ws-1234", "mitto-acp-ws-1234-42"},
+		{"../../etc", "mitto-acp-.._.._etc-42"},
+		{"my workspace/ü", "mitto-acp-my_workspace__-42"},
+		{"", "mitto-acp-42"},
+	}
+	for _, tt := range tests {
+		if got := cgroupName(tt.name, 42); got != tt.want {
+			t.Errorf("cgroupName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package web
+
+import "errors"
+
+// cgroupSampler is only available on Linux.
+type cgroupSampler struct {
+	dir string
+}
+
+func newCgroupSampler(pid int, name string, maxMemory uint64, maxProcesses int) (*cgroupSampler, error) {
+	return nil, errors.New("cgroups are only available on Linux")
+}
+
+func (s *cgroupSampler) Sample() (resourceUsage, error) {
+	return resourceUsage{}, errors.New("cgroups are only available on Linux")
+}
+
+func (s *cgroupSampler) Close() error {
+	return nil
+}
@@ -0,0 +1,302 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+// resourceLimitCheckInterval is how often the resource usage of the agent is
+// sampled while a prompt is running. Declared as a var so tests can shorten it.
+var resourceLimitCheckInterval = 2 * time.Second
+
+// Resource limits, as reported in session.ResourceData.LimitExceeded.
+const (
+	resourceLimitMemory    = "memory"
+	resourceLimitCPU       = "cpu"
+	resourceLimitDuration  = "duration"
+	resourceLimitProcesses = "processes"
+)
+
+// resourceUsage is a sample of the resource usage of an ACP agent process tree.
+type resourceUsage struct {
+	// MemoryBytes is the memory of the process tree (RSS, or the cgroup's memory.current).
+	MemoryBytes uint64
+	// CPUSeconds is the CPU time (user + system) used since the agent started.
+	CPUSeconds float64
+	// Processes is the number of processes of the agent, not counting the agent itself.
+	Processes int
+	// OOMKills is the number of processes killed by the kernel for exceeding
+	// the memory limit of the cgroup (always 0 when polling).
+	OOMKills uint64
+}
+
+// resourceSampler samples the resource usage of an ACP agent process tree.
+type resourceSampler interface {
+	Sample() (resourceUsage, error)
+	// Close releases the sampler. Samples taken after Close return the last
+	// usage seen, so the usage of an agent that was just killed can still be read.
+	Close() error
+}
+
+// newResourceSampler returns a sampler for the process tree rooted at pid. When
+// a memory or process limit is set, the process is placed in its own cgroup (v2)
+// with those limits, so the kernel enforces them; if that is not possible (not
+// Linux, no cgroup v2, no delegation), they are enforced by polling the tree.
+func newResourceSampler(pid int, name string, limits *config.ResourceLimits, logger *slog.Logger) resourceSampler {
+	maxMemory, maxProcesses := limits.GetMaxMemoryBytes(), limits.GetMaxProcesses()
+	if maxMemory > 0 || maxProcesses > 0 {
+		sampler, err := newCgroupSampler(pid, name, maxMemory, maxProcesses)
+		if err == nil {
+			if logger != nil {
+				logger.Info("ACP process placed in a cgroup with resource limits",
+					"pid", pid,
+					"cgroup", sampler.dir,
+					"max_memory", limits.MaxMemory,
+					"max_processes", maxProcesses)
+			}
+			return sampler
+		}
+		if logger != nil {
+			logger.Info("Could not place the ACP process in a cgroup, enforcing the resource limits by polling",
+				"pid", pid,
+				"max_memory", limits.MaxMemory,
+				"max_processes", maxProcesses,
+				"error", err)
+		}
+	}
+	return &processTreeSampler{pid: pid}
+}
+
+// processTreeSampler samples the process tree rooted at a pid with gopsutil.
+// The CPU time of descendants that have already exited is not included.
+type processTreeSampler struct {
+	pid int
+
+	mu     sync.Mutex
+	last   resourceUsage
+	closed bool
+}
+
+func (s *processTreeSampler) Sample() (resourceUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return s.last, nil
+	}
+
+	root, err := process.NewProcess(int32(s.pid))
+	if err != nil {
+		return s.last, fmt.Errorf("lookup root process %d: %w", s.pid, err)
+	}
+	var usage resourceUsage
+	addProcessUsage(root, &usage)
+	usage.Processes = 0 // the agent itself is not counted
+	addDescendantsUsage(root, &usage)
+	s.last = usage
+	return usage, nil
+}
+
+func (s *processTreeSampler) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// addProcessUsage adds the memory and CPU time of p to usage. Errors are
+// skipped, as the process may exit while it is sampled.
+func addProcessUsage(p *process.Process, usage *resourceUsage) {
+	usage.Processes++
+	if mi, err := p.MemoryInfo(); err == nil && mi != nil {
+		usage.MemoryBytes += mi.RSS
+	}
+	if times, err := p.Times(); err == nil && times != nil {
+		usage.CPUSeconds += times.User + times.System
+	}
+}
+
+// addDescendantsUsage recursively adds the usage of all descendants of p.
+func addDescendantsUsage(p *process.Process, usage *resourceUsage) {
+	children, err := p.Children()
+	if err != nil {
+		return
+	}
+	for _, child := range children {
+		addProcessUsage(child, usage)
+		addDescendantsUsage(child, usage)
+	}
+}
+
+// resourceLimitExceeded describes the resource limit that cancelled a prompt.
+type resourceLimitExceeded struct {
+	// Limit is the limit exceeded (resourceLimitMemory, resourceLimitCPU, ...).
+	Limit string
+	// Max is the configured limit, as shown to the user.
+	Max string
+	// Observed is the usage that exceeded the limit, as shown to the user.
+	Observed string
+}
+
+// classifiedError returns the permanent error shown to the user.
+func (e *resourceLimitExceeded) classifiedError() *ACPClassifiedError {
+	var what string
+	switch e.Limit {
+	case resourceLimitMemory:
+		what = "memory limit"
+	case resourceLimitCPU:
+		what = "CPU time limit"
+	case resourceLimitDuration:
+		what = "time limit"
+	case resourceLimitProcesses:
+		what = "process limit"
+	}
+	message := fmt.Sprintf("The AI agent exceeded the %s of this workspace (%s), so the prompt was cancelled.", what, e.Max)
+	if e.Observed != "" {
+		message = fmt.Sprintf("The AI agent exceeded the %s of this workspace (%s, used %s), so the prompt was cancelled.", what, e.Max, e.Observed)
+	}
+	return &ACPClassifiedError{
+		Class:         ACPErrorPermanent,
+		OriginalError: fmt.Errorf("resource limit exceeded: %s", e.Limit),
+		UserMessage:   message,
+		UserGuidance:  "Raise resource_limits in the workspace settings, or split the task into smaller prompts.",
+	}
+}
+
+// promptResourceMonitor tracks the resource usage of the agent during a prompt
+// and checks it against the workspace limits.
+//
+// The memory is checked as the growth over the usage at the start of the
+// prompt, so that the memory the agent already held (e.g. for other
+// conversations) is not charged to the prompt; the cgroup, when there is one,
+// enforces max_memory on the whole agent and its OOM kills are reported too.
+// The CPU time and processes of a shared agent can't be told apart between the
+// conversations using it, so their limits are not enforced on shared agents.
+type promptResourceMonitor struct {
+	sampler   resourceSampler // nil when the agent process is not known (restricted runner)
+	limits    *config.ResourceLimits
+	startedAt time.Time
+	shared    bool // the agent process is shared with other conversations
+
+	mu            sync.Mutex
+	baseline      resourceUsage
+	current       resourceUsage
+	peakMemory    uint64
+	peakProcesses int
+	exceeded      *resourceLimitExceeded
+}
+
+// newPromptResourceMonitor starts monitoring a prompt. sampler may be nil, in
+// which case only the wall-clock limit is checked. shared is set when the agent
+// process is shared with other conversations.
+func newPromptResourceMonitor(sampler resourceSampler, limits *config.ResourceLimits, startedAt time.Time, shared bool) *promptResourceMonitor {
+	m := &promptResourceMonitor{sampler: sampler, limits: limits, startedAt: startedAt, shared: shared}
+	if sampler != nil {
+		if usage, err := sampler.Sample(); err == nil {
+			m.baseline = usage
+			m.current = usage
+			m.peakMemory = usage.MemoryBytes
+			m.peakProcesses = usage.Processes
+		}
+	}
+	return m
+}
+
+// check samples the usage of the agent and returns the limit exceeded, if any.
+// Once a limit has been exceeded, the same result is returned on every call.
+func (m *promptResourceMonitor) check(now time.Time) *resourceLimitExceeded {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sampleLocked()
+	if m.exceeded != nil || !m.limits.IsEnabled() {
+		return m.exceeded
+	}
+
+	maxMemory := m.limits.GetMaxMemoryBytes()
+	var memoryGrowth uint64
+	if m.current.MemoryBytes > m.baseline.MemoryBytes {
+		memoryGrowth = m.current.MemoryBytes - m.baseline.MemoryBytes
+	}
+	switch {
+	case maxMemory > 0 && m.current.OOMKills > m.baseline.OOMKills:
+		m.exceeded = &resourceLimitExceeded{Limit: resourceLimitMemory, Max: m.limits.MaxMemory}
+	case maxMemory > 0 && memoryGrowth > maxMemory:
+		m.exceeded = &resourceLimitExceeded{Limit: resourceLimitMemory, Max: m.limits.MaxMemory,
+			Observed: formatBytes(memoryGrowth)}
+	case !m.shared && m.limits.GetMaxCPUSeconds() > 0 && m.current.CPUSeconds-m.baseline.CPUSeconds > m.limits.GetMaxCPUSeconds():
+		m.exceeded = &resourceLimitExceeded{Limit: resourceLimitCPU,
+			Max:      fmt.Sprintf("%gs", m.limits.GetMaxCPUSeconds()),
+			Observed: fmt.Sprintf("%.0fs", m.current.CPUSeconds-m.baseline.CPUSeconds)}
+	case !m.shared && m.limits.GetMaxProcesses() > 0 && m.current.Processes > m.limits.GetMaxProcesses():
+		m.exceeded = &resourceLimitExceeded{Limit: resourceLimitProcesses,
+			Max:      fmt.Sprintf("%d processes", m.limits.GetMaxProcesses()),
+			Observed: fmt.Sprintf("%d", m.current.Processes)}
+	case m.limits.GetMaxPromptDuration() > 0 && now.Sub(m.startedAt) > m.limits.GetMaxPromptDuration():
+		m.exceeded = &resourceLimitExceeded{Limit: resourceLimitDuration, Max: m.limits.MaxPromptDuration}
+	}
+	return m.exceeded
+}
+
+// sampleLocked samples the usage of the agent. On errors (the agent exited),
+// the last usage seen is kept. m.mu must be held.
+func (m *promptResourceMonitor) sampleLocked() {
+	if m.sampler == nil {
+		return
+	}
+	if usage, err := m.sampler.Sample(); err == nil {
+		m.current = usage
+		m.peakMemory = max(m.peakMemory, usage.MemoryBytes)
+		m.peakProcesses = max(m.peakProcesses, usage.Processes)
+	}
+}
+
+// Exceeded returns the limit exceeded during the prompt, if any.
+func (m *promptResourceMonitor) Exceeded() *resourceLimitExceeded {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.exceeded
+}
+
+// finish takes a last sample and returns the usage of the prompt. Only the
+// processes killed by the kernel for exceeding the memory limit are checked: a
+// prompt that completed between two checks is not failed after the fact.
+func (m *promptResourceMonitor) finish(endedAt time.Time) session.ResourceData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sampleLocked()
+	if m.exceeded == nil && m.limits.GetMaxMemoryBytes() > 0 && m.current.OOMKills > m.baseline.OOMKills {
+		m.exceeded = &resourceLimitExceeded{Limit: resourceLimitMemory, Max: m.limits.MaxMemory}
+	}
+	data := session.ResourceData{
+		CPUSeconds:      max(m.current.CPUSeconds-m.baseline.CPUSeconds, 0),
+		WallSeconds:     endedAt.Sub(m.startedAt).Seconds(),
+		PeakMemoryBytes: m.peakMemory,
+		PeakProcesses:   m.peakProcesses,
+	}
+	if m.exceeded != nil {
+		data.LimitExceeded = m.exceeded.Limit
+	}
+	return data
+}
+
+// formatBytes formats a size in bytes for the user (e.g. "1.5 GiB").
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
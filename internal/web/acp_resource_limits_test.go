@@ -0,0 +1,219 @@
+package web
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+// fakeResourceSampler returns the usage set by the test.
+type fakeResourceSampler struct {
+	mu    sync.Mutex
+	usage resourceUsage
+}
+
+func (s *fakeResourceSampler) Sample() (resourceUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage, nil
+}
+
+func (s *fakeResourceSampler) Close() error { return nil }
+
+func (s *fakeResourceSampler) set(usage resourceUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage = usage
+}
+
+func TestPromptResourceMonitor_Check(t *testing.T) {
+	start := time.Now()
+	baseline := resourceUsage{MemoryBytes: 100 << 20, CPUSeconds: 50, Processes: 1}
+
+	tests := []struct {
+		name   string
+		limits *config.ResourceLimits
+		usage  resourceUsage
+		after  time.Duration
+		want   string
+	}{
+		{"no limits", nil, resourceUsage{MemoryBytes: 10 << 30, CPUSeconds: 1e6, Processes: 100}, time.Hour, ""},
+		{"within limits", &config.ResourceLimits{MaxMemory: "1g", MaxCPUSeconds: 10, MaxPromptDuration: "1m", MaxProcesses: 4},
+			resourceUsage{MemoryBytes: 500 << 20, CPUSeconds: 59, Processes: 4}, 30 * time.Second, ""},
+		{"memory", &config.ResourceLimits{MaxMemory: "1g"}, resourceUsage{MemoryBytes: 2 << 30}, 0, resourceLimitMemory},
+		{"oom kill", &config.ResourceLimits{MaxMemory: "1g"}, resourceUsage{MemoryBytes: 1 << 20, OOMKills: 1}, 0, resourceLimitMemory},
+		// The CPU time is counted from the start of the prompt
+		{"cpu", &config.ResourceLimits{MaxCPUSeconds: 10}, resourceUsage{CPUSeconds: 61}, 0, resourceLimitCPU},
+		{"processes", &config.ResourceLimits{MaxProcesses: 4}, resourceUsage{Processes: 5}, 0, resourceLimitProcesses},
+		{"duration", &config.ResourceLimits{MaxPromptDuration: "1m"}, baseline, 2 * time.Minute, resourceLimitDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler := &fakeResourceSampler{usage: baseline}
+			m := newPromptResourceMonitor(sampler, tt.limits, start, false)
+			sampler.set(tt.usage)
+
+			exceeded := m.check(start.Add(tt.after))
+			got := ""
+			if exceeded != nil {
+				got = exceeded.Limit
+			}
+			if got != tt.want {
+				t.Errorf("check() = %+v, want limit %q", exceeded, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptResourceMonitor_CheckBaselineAboveLimit(t *testing.T) {
+	// The agent already holds more memory than the limit when the prompt starts
+	// (e.g. for the other conversations sharing it): only the growth counts.
+	start := time.Now()
+	sampler := &fakeResourceSampler{usage: resourceUsage{MemoryBytes: 3 << 30}}
+	m := newPromptResourceMonitor(sampler, &config.ResourceLimits{MaxMemory: "1g"}, start, true)
+
+	sampler.set(resourceUsage{MemoryBytes: 3<<30 + 512<<20})
+	if exceeded := m.check(start.Add(time.Second)); exceeded != nil {
+		t.Fatalf("check() = %+v, want nil", exceeded)
+	}
+	sampler.set(resourceUsage{MemoryBytes: 2 << 30})
+	if exceeded := m.check(start.Add(2 * time.Second)); exceeded != nil {
+		t.Fatalf("check() after the memory shrank = %+v, want nil", exceeded)
+	}
+	sampler.set(resourceUsage{MemoryBytes: 4<<30 + 512<<20})
+	exceeded := m.check(start.Add(3 * time.Second))
+	if exceeded == nil || exceeded.Limit != resourceLimitMemory || exceeded.Observed != "1.5 GiB" {
+		t.Errorf("check() = %+v, want the memory limit with 1.5 GiB used", exceeded)
+	}
+}
+
+func TestPromptResourceMonitor_CheckShared(t *testing.T) {
+	// The CPU time and processes of a shared agent are not charged to the prompt
+	start := time.Now()
+	limits := &config.ResourceLimits{MaxCPUSeconds: 10, MaxProcesses: 4, MaxPromptDuration: "1m"}
+	sampler := &fakeResourceSampler{usage: resourceUsage{CPUSeconds: 50, Processes: 1}}
+	m := newPromptResourceMonitor(sampler, limits, start, true)
+
+	sampler.set(resourceUsage{CPUSeconds: 500, Processes: 20})
+	if exceeded := m.check(start.Add(time.Second)); exceeded != nil {
+		t.Fatalf("check() = %+v, want nil", exceeded)
+	}
+	if exceeded := m.check(start.Add(2 * time.Minute)); exceeded == nil || exceeded.Limit != resourceLimitDuration {
+		t.Errorf("check() = %+v, want the duration limit", exceeded)
+	}
+}
+
+func TestPromptResourceMonitor_Finish(t *testing.T) {
+	start := time.Now()
+	sampler := &fakeResourceSampler{usage: resourceUsage{MemoryBytes: 100 << 20, CPUSeconds: 50}}
+	m := newPromptResourceMonitor(sampler, &config.ResourceLimits{MaxMemory: "1g", MaxPromptDuration: "1m"}, start, false)
+
+	sampler.set(resourceUsage{MemoryBytes: 300 << 20, CPUSeconds: 52, Processes: 3})
+	if exceeded := m.check(start.Add(time.Second)); exceeded != nil {
+		t.Fatalf("check() = %+v, want nil", exceeded)
+	}
+	sampler.set(resourceUsage{MemoryBytes: 200 << 20, CPUSeconds: 53.5, Processes: 1})
+
+	// A prompt that completed past its duration between two checks is not failed.
+	data := m.finish(start.Add(90 * time.Second))
+	if data.CPUSeconds != 3.5 || data.WallSeconds != 90 || data.PeakMemoryBytes != 300<<20 ||
+		data.PeakProcesses != 3 || data.LimitExceeded != "" {
+		t.Errorf("finish() = %+v", data)
+	}
+
+	// An agent killed by the kernel is reported even if it happens after the last check.
+	m = newPromptResourceMonitor(sampler, &config.ResourceLimits{MaxMemory: "1g"}, start, false)
+	sampler.set(resourceUsage{OOMKills: 1})
+	if data := m.finish(start.Add(time.Second)); data.LimitExceeded != resourceLimitMemory {
+		t.Errorf("finish().LimitExceeded = %q, want %q", data.LimitExceeded, resourceLimitMemory)
+	}
+	if m.Exceeded() == nil {
+		t.Error("Exceeded() = nil after an OOM kill")
+	}
+}
+
+func TestResourceLimitExceeded_ClassifiedError(t *testing.T) {
+	e := &resourceLimitExceeded{Limit: resourceLimitMemory, Max: "1g", Observed: formatBytes(1536 << 20)}
+	classified := e.classifiedError()
+	if classified.IsRetryable() {
+		t.Error("resource limit errors should be permanent")
+	}
+	if !strings.Contains(classified.UserMessage, "memory limit") || !strings.Contains(classified.UserMessage, "1.5 GiB") {
+		t.Errorf("UserMessage = %q", classified.UserMessage)
+	}
+	if !strings.Contains(classified.UserGuidance, "resource_limits") {
+		t.Errorf("UserGuidance = %q", classified.UserGuidance)
+	}
+}
+
+func TestStartResourceLimitWatchdog_CancelsPrompt(t *testing.T) {
+	orig := resourceLimitCheckInterval
+	resourceLimitCheckInterval = 10 * time.Millisecond
+	defer func() { resourceLimitCheckInterval = orig }()
+
+	rec := newCapturingLogHandler()
+	bs := &BackgroundSession{logger: slog.New(rec), persistedID: "test-limits"}
+	sampler := &fakeResourceSampler{}
+	monitor := newPromptResourceMonitor(sampler, &config.ResourceLimits{MaxProcesses: 2}, time.Now(), false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bs.startResourceLimitWatchdog(ctx, cancel, monitor)
+
+	time.Sleep(50 * time.Millisecond)
+	if ctx.Err() != nil {
+		t.Fatal("prompt cancelled within the limits")
+	}
+
+	sampler.set(resourceUsage{Processes: 3})
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("prompt not cancelled after exceeding the process limit")
+	}
+	if exceeded := monitor.Exceeded(); exceeded == nil || exceeded.Limit != resourceLimitProcesses {
+		t.Errorf("Exceeded() = %+v, want the process limit", exceeded)
+	}
+	if len(rec.entriesAt(slog.LevelError)) == 0 {
+		t.Error("expected an ERROR log entry")
+	}
+}
+
+func TestProcessTreeSampler(t *testing.T) {
+	s := &processTreeSampler{pid: os.Getpid()}
+	usage, err := s.Sample()
+	if err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+	if usage.MemoryBytes == 0 {
+		t.Error("MemoryBytes = 0 for the test process")
+	}
+
+	// After Close, the last usage is returned
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	s.pid = -1
+	if after, err := s.Sample(); err != nil || after != usage {
+		t.Errorf("Sample() after Close = %+v, %v; want %+v", after, err, usage)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[uint64]string{
+		512:        "512 B",
+		2048:       "2.0 KiB",
+		300 << 20:  "300.0 MiB",
+		1536 << 20: "1.5 GiB",
+	} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
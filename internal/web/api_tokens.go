@@ -0,0 +1,337 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inercia/mitto/internal/fileutil"
+	"github.com/inercia/mitto/internal/logging"
+)
+
+// API token scopes.
+const (
+	// ScopeSessionsRead allows listing and reading conversations.
+	ScopeSessionsRead = "sessions:read"
+	// ScopePromptsSend allows creating conversations and sending prompts to them.
+	ScopePromptsSend = "prompts:send"
+	// ScopeConfigManage allows changing the configuration (workspaces, settings...).
+	ScopeConfigManage = "config:manage"
+	// ScopePermissionsApprove allows answering permission requests.
+	ScopePermissionsApprove = "permissions:approve"
+)
+
+// APITokenScopes are the valid API token scopes.
+var APITokenScopes = []string{ScopeSessionsRead, ScopePromptsSend, ScopeConfigManage, ScopePermissionsApprove}
+
+const (
+	// apiTokenPrefix is the prefix of the API token secrets, so that they can
+	// be recognized (e.g. by secret scanners).
+	apiTokenPrefix = "mitto_pat_"
+
+	// apiTokenLastUsedInterval is how often the last use of a token is persisted.
+	apiTokenLastUsedInterval = time.Minute
+)
+
+var (
+	// ErrInvalidAPIToken is returned for unknown or revoked API tokens.
+	ErrInvalidAPIToken = errors.New("invalid API token")
+
+	// ErrAPITokenExpired is returned for API tokens past their expiry.
+	ErrAPITokenExpired = errors.New("API token expired")
+
+	// ErrAPITokenNotFound is returned when revoking an unknown API token.
+	ErrAPITokenNotFound = errors.New("API token not found")
+)
+
+// APIToken is a named, revocable credential for scripts, sent as an
+// "Authorization: Bearer" header. Only the hash of the secret is stored.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // First characters of the secret, to recognize it
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	hash string
+}
+
+// HasScope returns true if the token carries the scope.
+func (t *APIToken) HasScope(scope string) bool {
+	return t != nil && slices.Contains(t.Scopes, scope)
+}
+
+// Expired returns true if the token has an expiry before now.
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// persistedAPIToken is the JSON-serializable version of APIToken.
+type persistedAPIToken struct {
+	APIToken
+	Hash string `json:"hash"`
+}
+
+// persistedAPITokensFile is the structure of the api_tokens.json file.
+type persistedAPITokensFile struct {
+	Tokens []persistedAPIToken `json:"tokens"`
+}
+
+// APITokens stores the API tokens, persisted in a JSON file.
+type APITokens struct {
+	path   string // "" for in-memory only
+	tokens map[string]*APIToken
+	mu     sync.RWMutex
+	now    func() time.Time
+}
+
+// NewAPITokens creates a token store persisted at path, loading the existing
+// tokens. An empty path keeps the tokens in memory only.
+func NewAPITokens(path string) *APITokens {
+	t := &APITokens{
+		path:   path,
+		tokens: make(map[string]*APIToken),
+		now:    time.Now,
+	}
+	t.load()
+	return t
+}
+
+// load reads the tokens from disk.
+func (t *APITokens) load() {
+	if t.path == "" {
+		return
+	}
+	var file persistedAPITokensFile
+	if err := fileutil.ReadJSON(t.path, &file); err != nil {
+		if !os.IsNotExist(err) {
+			logging.Auth().Warn("AUTH: Failed to load API tokens", "error", err, "path", t.path)
+		}
+		return
+	}
+	for _, pt := range file.Tokens {
+		token := pt.APIToken
+		token.hash = pt.Hash
+		t.tokens[pt.Hash] = &token
+	}
+}
+
+// saveLocked writes the tokens to disk. Must be called with t.mu held.
+func (t *APITokens) saveLocked() error {
+	if t.path == "" {
+		return nil
+	}
+	file := persistedAPITokensFile{Tokens: make([]persistedAPIToken, 0, len(t.tokens))}
+	for _, token := range t.tokens {
+		file.Tokens = append(file.Tokens, persistedAPIToken{APIToken: *token, Hash: token.hash})
+	}
+	sort.Slice(file.Tokens, func(i, j int) bool {
+		return file.Tokens[i].CreatedAt.Before(file.Tokens[j].CreatedAt)
+	})
+	return fileutil.WriteJSONAtomic(t.path, &file, 0600)
+}
+
+// hashAPIToken returns the hash a token secret is stored as.
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateAPITokenScopes checks that scopes is a non-empty list of valid scopes.
+func ValidateAPITokenScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(APITokenScopes, scope) {
+			return fmt.Errorf("invalid scope %q (valid scopes: %s)", scope, strings.Join(APITokenScopes, ", "))
+		}
+	}
+	return nil
+}
+
+// Create creates a token and returns it along with its secret, which is not
+// stored and can't be retrieved later.
+func (t *APITokens) Create(name string, scopes []string, expiresAt *time.Time) (*APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.New("name is required")
+	}
+	if err := ValidateAPITokenScopes(scopes); err != nil {
+		return nil, "", err
+	}
+	now := t.now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, "", errors.New("expiry must be in the future")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, existing := range t.tokens {
+		if existing.Name == name {
+			return nil, "", fmt.Errorf("a token named %q already exists", name)
+		}
+	}
+	token := &APIToken{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Prefix:    secret[:len(apiTokenPrefix)+4],
+		Scopes:    slices.Clone(scopes),
+		CreatedAt: now.UTC().Truncate(time.Second),
+		ExpiresAt: expiresAt,
+		hash:      hashAPIToken(secret),
+	}
+	t.tokens[token.hash] = token
+	if err := t.saveLocked(); err != nil {
+		delete(t.tokens, token.hash)
+		return nil, "", fmt.Errorf("failed to save API tokens: %w", err)
+	}
+	created := *token
+	return &created, secret, nil
+}
+
+// List returns the tokens, oldest first.
+func (t *APITokens) List() []APIToken {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	tokens := make([]APIToken, 0, len(t.tokens))
+	for _, token := range t.tokens {
+		tokens = append(tokens, *token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens
+}
+
+// Revoke deletes the token with the given ID or name.
+func (t *APITokens) Revoke(ref string) (APIToken, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for hash, token := range t.tokens {
+		if token.ID != ref && token.Name != ref {
+			continue
+		}
+		delete(t.tokens, hash)
+		if err := t.saveLocked(); err != nil {
+			t.tokens[hash] = token
+			return APIToken{}, fmt.Errorf("failed to save API tokens: %w", err)
+		}
+		return *token, nil
+	}
+	return APIToken{}, ErrAPITokenNotFound
+}
+
+// Verify returns the token of a secret, recording its use.
+// Safe to call on nil receiver (no token is valid).
+func (t *APITokens) Verify(secret string) (*APIToken, error) {
+	if t == nil || !strings.HasPrefix(secret, apiTokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+	hash := hashAPIToken(secret)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	token, ok := t.tokens[hash]
+	if !ok {
+		return nil, ErrInvalidAPIToken
+	}
+	now := t.now()
+	if token.Expired(now) {
+		return nil, ErrAPITokenExpired
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenLastUsedInterval {
+		used := now.UTC().Truncate(time.Second)
+		token.LastUsedAt = &used
+		if err := t.saveLocked(); err != nil {
+			logging.Auth().Warn("AUTH: Failed to save API tokens", "error", err, "path", t.path)
+		}
+	}
+	verified := *token
+	return &verified, nil
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or "".
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// contextKeyAPIToken is the context key used to store the *APIToken of requests
+// authenticated with an API token.
+const contextKeyAPIToken contextKey = "apiToken"
+
+// apiTokenFromContext returns the API token a request was authenticated with,
+// or nil for other kinds of authentication.
+func apiTokenFromContext(ctx context.Context) *APIToken {
+	token, _ := ctx.Value(contextKeyAPIToken).(*APIToken)
+	return token
+}
+
+// apiTokenScopeForRequest returns the scope an API token needs for a request,
+// or ok=false if the request can't be made with API tokens at all. The metrics
+// at metricsPath (if set) need config:manage.
+func apiTokenScopeForRequest(r *http.Request, apiPrefix, metricsPath string) (scope string, ok bool) {
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	if metricsPath != "" && path == metricsPath {
+		return ScopeConfigManage, true
+	}
+	if !strings.HasPrefix(path, "/api/") {
+		// Static files
+		return ScopeSessionsRead, true
+	}
+
+	readOrSend := func() (string, bool) {
+		if isStateChangingMethod(r.Method) {
+			return ScopePromptsSend, true
+		}
+		return ScopeSessionsRead, true
+	}
+
+	switch {
+	case path == "/api/tokens" || strings.HasPrefix(path, "/api/tokens/"):
+		// Tokens can't be used to create more tokens
+		return "", false
+	case path == "/api/auth/totp" || strings.HasPrefix(path, "/api/auth/totp/"):
+		// Nor to manage the second factor of the password logins
+		return "", false
+	case path == "/api/permissions" || strings.HasPrefix(path, "/api/permissions/"):
+		return ScopePermissionsApprove, true
+	case path == "/api/sessions" || strings.HasPrefix(path, "/api/sessions/"):
+		return readOrSend()
+	case path == "/api/search", path == "/api/usage", path == "/api/files", path == "/api/events":
+		return readOrSend()
+	default:
+		return ScopeConfigManage, true
+	}
+}
@@ -0,0 +1,122 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APITokenCreateRequest is the body of POST /api/tokens.
+type APITokenCreateRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresIn is the lifetime of the token, as a Go duration or a number of
+	// days ("30d"). Empty for tokens that don't expire.
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// APITokenCreateResponse is the response of POST /api/tokens.
+type APITokenCreateResponse struct {
+	APIToken
+	// Token is the secret of the token. It is only returned on creation.
+	Token string `json:"token"`
+}
+
+// APITokensResponse is the response of GET /api/tokens.
+type APITokensResponse struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+// ParseAPITokenExpiry parses the lifetime of an API token: a Go duration
+// ("720h") or a number of days ("30d"). An empty string means no expiry.
+func ParseAPITokenExpiry(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid expiry %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid expiry %q", s)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid expiry %q: must be positive", s)
+	}
+	return d, nil
+}
+
+// handleAPITokens handles GET and POST /api/tokens
+// GET lists the API tokens, POST creates one and returns its secret.
+func (s *Server) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSONOK(w, APITokensResponse{Tokens: s.apiTokens.List()})
+
+	case http.MethodPost:
+		var req APITokenCreateRequest
+		if !parseJSONBody(w, r, &req) {
+			return
+		}
+		lifetime, err := ParseAPITokenExpiry(req.ExpiresIn)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		var expiresAt *time.Time
+		if lifetime > 0 {
+			t := time.Now().Add(lifetime).UTC().Truncate(time.Second)
+			expiresAt = &t
+		}
+		token, secret, err := s.apiTokens.Create(req.Name, req.Scopes, expiresAt)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if s.logger != nil {
+			s.logger.Info("API token created", "name", token.Name, "scopes", token.Scopes)
+		}
+		writeJSONCreated(w, APITokenCreateResponse{APIToken: *token, Token: secret})
+
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// handleAPITokenDetail handles DELETE /api/tokens/{id}
+// The token can also be referred to by its name.
+func (s *Server) handleAPITokenDetail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		methodNotAllowed(w)
+		return
+	}
+	ref := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, s.apiPrefix), "/api/tokens/")
+	if ref == "" || strings.Contains(ref, "/") {
+		http.Error(w, "Invalid token path", http.StatusBadRequest)
+		return
+	}
+
+	token, err := s.apiTokens.Revoke(ref)
+	if errors.Is(err, ErrAPITokenNotFound) {
+		writeErrorJSON(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	if err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	if s.logger != nil {
+		s.logger.Info("API token revoked", "name", token.Name)
+	}
+	writeNoContent(w)
+}
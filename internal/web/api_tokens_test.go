@@ -0,0 +1,182 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+func TestAPITokens_CreateVerifyRevoke(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_tokens.json")
+	tokens := NewAPITokens(path)
+
+	token, secret, err := tokens.Create("ci", []string{ScopeSessionsRead, ScopePromptsSend}, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if token.ID == "" || token.Prefix != secret[:len(token.Prefix)] {
+		t.Errorf("Create() token = %+v", token)
+	}
+	if _, _, err := tokens.Create("ci", []string{ScopeSessionsRead}, nil); err == nil {
+		t.Error("Create() with a duplicate name succeeded, want error")
+	}
+	if _, _, err := tokens.Create("bad", []string{"sessions:write"}, nil); err == nil {
+		t.Error("Create() with an invalid scope succeeded, want error")
+	}
+
+	// Tokens survive a restart, and only the hash is stored
+	tokens = NewAPITokens(path)
+	got, err := tokens.Verify(secret)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got.Name != "ci" || !got.HasScope(ScopePromptsSend) || got.HasScope(ScopeConfigManage) {
+		t.Errorf("Verify() = %+v", got)
+	}
+	if got.LastUsedAt == nil {
+		t.Error("Verify() did not record the last use")
+	}
+	if _, err := tokens.Verify(secret + "x"); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("Verify(wrong secret) error = %v, want %v", err, ErrInvalidAPIToken)
+	}
+
+	if _, err := tokens.Revoke("ci"); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if _, err := tokens.Verify(secret); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("Verify(revoked) error = %v, want %v", err, ErrInvalidAPIToken)
+	}
+	if _, err := tokens.Revoke("ci"); !errors.Is(err, ErrAPITokenNotFound) {
+		t.Errorf("Revoke(revoked) error = %v, want %v", err, ErrAPITokenNotFound)
+	}
+}
+
+func TestAPITokens_Expiry(t *testing.T) {
+	tokens := NewAPITokens("")
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tokens.now = func() time.Time { return now }
+
+	expiresAt := now.Add(time.Hour)
+	_, secret, err := tokens.Create("short", []string{ScopeSessionsRead}, &expiresAt)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := tokens.Verify(secret); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if _, err := tokens.Verify(secret); !errors.Is(err, ErrAPITokenExpired) {
+		t.Errorf("Verify(expired) error = %v, want %v", err, ErrAPITokenExpired)
+	}
+}
+
+func TestParseAPITokenExpiry(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAPITokenExpiry(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAPITokenExpiry(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestAPITokenScopeForRequest(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+		ok     bool
+	}{
+		{"GET", "/mitto/api/sessions", ScopeSessionsRead, true},
+		{"GET", "/mitto/api/sessions/abc/ws", ScopeSessionsRead, true},
+		{"POST", "/mitto/api/sessions", ScopePromptsSend, true},
+		{"POST", "/mitto/api/sessions/abc/queue", ScopePromptsSend, true},
+		{"GET", "/mitto/api/search", ScopeSessionsRead, true},
+		{"POST", "/mitto/api/permissions/tok", ScopePermissionsApprove, true},
+		{"POST", "/mitto/api/config", ScopeConfigManage, true},
+		{"GET", "/mitto/api/workspaces", ScopeConfigManage, true},
+		{"GET", "/mitto/index.html", ScopeSessionsRead, true},
+		{"GET", "/metrics", ScopeConfigManage, true},
+		{"GET", "/mitto/metrics", ScopeConfigManage, true},
+		{"GET", "/mitto/api/tokens", "", false},
+		{"DELETE", "/mitto/api/tokens/abc", "", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		got, ok := apiTokenScopeForRequest(req, "/mitto", "/metrics")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("apiTokenScopeForRequest(%s %s) = %q, %v, want %q, %v", tt.method, tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAuthMiddleware_APIToken(t *testing.T) {
+	am := NewAuthManager(&config.WebAuth{
+		Simple: &config.SimpleAuth{Username: "admin", Password: "password"},
+	})
+	defer am.Close()
+	am.SetAPIPrefix("/mitto")
+	tokens := NewAPITokens("")
+	am.SetAPITokens(tokens)
+
+	_, secret, err := tokens.Create("reader", []string{ScopeSessionsRead}, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	var gotUser string
+	var gotToken *APIToken
+	middleware := am.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = r.Context().Value(contextKeyAuthUser).(string)
+		gotToken = apiTokenFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		want   int
+	}{
+		{"read", "GET", "/mitto/api/sessions", "Bearer " + secret, http.StatusOK},
+		{"missing scope", "POST", "/mitto/api/sessions", "Bearer " + secret, http.StatusForbidden},
+		{"token management", "GET", "/mitto/api/tokens", "Bearer " + secret, http.StatusForbidden},
+		{"unknown token", "GET", "/mitto/api/sessions", "Bearer " + apiTokenPrefix + "nope", http.StatusUnauthorized},
+		{"no token", "GET", "/mitto/api/sessions", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUser, gotToken = "", nil
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.RemoteAddr = "192.168.1.100:12345"
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			middleware.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && (gotUser != "token:reader" || gotToken == nil || gotToken.Name != "reader") {
+				t.Errorf("context user = %q, token = %+v", gotUser, gotToken)
+			}
+		})
+	}
+}
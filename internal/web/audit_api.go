@@ -0,0 +1,93 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/inercia/mitto/internal/audit"
+	"github.com/inercia/mitto/internal/session"
+)
+
+// maxAuditQueryLimit is the maximum number of events returned by GET /api/audit.
+const maxAuditQueryLimit = 1000
+
+// AuditResponse is the response of GET /api/audit.
+type AuditResponse struct {
+	// Events are the matching events, newest first.
+	Events []audit.Event `json:"events"`
+}
+
+// requestActor returns the actor of a request for the audit log.
+func requestActor(r *http.Request) audit.Actor {
+	return audit.Actor{IP: getClientIPWithProxyCheck(r), User: authUserName(r.Context())}
+}
+
+// auditRequest records an action performed by a request in the audit log.
+// The reason is added to the details when not empty.
+func auditRequest(r *http.Request, action, target, outcome, reason string) {
+	e := audit.Event{Actor: requestActor(r), Action: action, Target: target, Outcome: outcome}
+	if reason != "" {
+		e.Details = map[string]string{"reason": reason}
+	}
+	audit.Record(e)
+}
+
+// handleAudit handles GET /api/audit
+// It returns the most recent events of the audit log (admins only).
+//
+// Query parameters (all optional):
+//   - action: action, or group of actions (e.g. "auth.login" or "auth")
+//   - user, ip, session: actor of the action
+//   - target, outcome: target and outcome (success, failure or denied) of the action
+//   - since, until: time range, as RFC 3339 timestamps or durations ago like "24h"
+//   - limit: maximum number of events (default 100, at most 1000)
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	if s.auditLog == nil {
+		writeErrorJSON(w, http.StatusNotFound, "audit_log_disabled", "The audit log is not enabled")
+		return
+	}
+
+	query := r.URL.Query()
+	q := audit.Query{
+		Action:  query.Get("action"),
+		User:    query.Get("user"),
+		IP:      query.Get("ip"),
+		Session: query.Get("session"),
+		Target:  query.Get("target"),
+		Outcome: query.Get("outcome"),
+	}
+	var err error
+	if v := query.Get("since"); v != "" {
+		if q.Since, err = session.ParseHistoryTime(v); err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_since", err.Error())
+			return
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		if q.Until, err = session.ParseHistoryTime(v); err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_until", err.Error())
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit <= 0 {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_limit", "limit must be a positive integer")
+			return
+		}
+		q.Limit = min(q.Limit, maxAuditQueryLimit)
+	}
+
+	events, err := audit.Search(s.auditLog.Path(), q)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("Failed to search the audit log", "error", err)
+		}
+		http.Error(w, "Failed to search the audit log", http.StatusInternalServerError)
+		return
+	}
+	writeJSONOK(w, AuditResponse{Events: events})
+}
@@ -0,0 +1,86 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/inercia/mitto/internal/audit"
+)
+
+func TestHandleAudit(t *testing.T) {
+	rec := httptest.NewRecorder()
+	(&Server{}).handleAudit(rec, httptest.NewRequest(http.MethodGet, "/api/audit", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("disabled audit log: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	log, err := audit.Open(audit.Config{Path: filepath.Join(t.TempDir(), "audit.jsonl")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit.SetDefault(log)
+	t.Cleanup(func() {
+		audit.SetDefault(nil)
+		log.Close()
+	})
+	server := &Server{auditLog: log, config: Config{ConfigReadOnly: true}}
+
+	// Recorded from the requests that perform the actions
+	auditLogin("10.0.0.1", "alice", "password", audit.OutcomeDenied, "invalid_credentials")
+	auditLogin("10.0.0.1", "alice", "password", audit.OutcomeSuccess, "")
+	server.handleSaveConfig(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/config", nil))
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		want     []string // actions and outcomes, newest first
+	}{
+		{"all", "", http.StatusOK, []string{"config.update denied", "auth.login success", "auth.login denied"}},
+		{"action group", "action=auth", http.StatusOK, []string{"auth.login success", "auth.login denied"}},
+		{"user", "user=alice", http.StatusOK, []string{"auth.login success"}},
+		{"ip and outcome", "ip=10.0.0.1&outcome=denied", http.StatusOK, []string{"auth.login denied"}},
+		{"limit", "limit=1", http.StatusOK, []string{"config.update denied"}},
+		{"since", "since=1h", http.StatusOK, []string{"config.update denied", "auth.login success", "auth.login denied"}},
+		{"until", "until=2020-01-01T00:00:00Z", http.StatusOK, nil},
+		{"invalid since", "since=yesterday", http.StatusBadRequest, nil},
+		{"invalid limit", "limit=0", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.handleAudit(rec, httptest.NewRequest(http.MethodGet, "/api/audit?"+tt.query, nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var resp AuditResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range resp.Events {
+				got = append(got, e.Action+" "+e.Outcome)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("events = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("events = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleAudit(rec, httptest.NewRequest(http.MethodPost, "/api/audit", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -14,15 +14,18 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/audit"
 	"github.com/inercia/mitto/internal/config"
 	"github.com/inercia/mitto/internal/fileutil"
 	"github.com/inercia/mitto/internal/logging"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -84,6 +87,11 @@ type AuthManager struct {
 	apiPrefix   string           // API prefix for URL matching (e.g., "/mitto")
 
 	cfVerifier *oidc.IDTokenVerifier // Cloudflare Access JWT verifier (nil if not configured)
+	oidc       *oidcLogin            // OpenID Connect login (nil if not configured)
+
+	apiTokens   *APITokens // API tokens accepted as bearer tokens (nil if none)
+	metricsPath string     // Path of the metrics endpoint (empty if disabled)
+	totp        *TOTPStore // Second factor of the password logins (nil if none)
 
 	// Cleanup goroutine control
 	stopCleanup chan struct{}
@@ -172,6 +180,14 @@ func NewAuthManager(authConfig *config.WebAuth) *AuthManager {
 		)
 	}
 
+	// Initialize OpenID Connect login if configured
+	if authConfig.HasOIDCAuth() {
+		am.oidc = newOIDCLogin(authConfig.OIDC)
+		slog.Info("OpenID Connect authentication enabled",
+			"issuer", authConfig.OIDC.Issuer,
+		)
+	}
+
 	// Start session cleanup goroutine
 	go am.cleanupLoop()
 
@@ -287,6 +303,27 @@ func (a *AuthManager) SetAPIPrefix(prefix string) {
 	a.apiPrefix = prefix
 }
 
+// SetMetricsPath sets the path of the metrics endpoint, which API tokens can
+// only read with the config:manage scope.
+// This must be called before the middleware is used.
+func (a *AuthManager) SetMetricsPath(path string) {
+	a.metricsPath = path
+}
+
+// SetAPITokens sets the store of the API tokens accepted in
+// "Authorization: Bearer" headers.
+// This must be called before the middleware is used.
+func (a *AuthManager) SetAPITokens(tokens *APITokens) {
+	a.apiTokens = tokens
+}
+
+// SetTOTPStore sets the store of the two-factor authentication enrollments
+// checked by password logins.
+// This must be called before the middleware is used.
+func (a *AuthManager) SetTOTPStore(store *TOTPStore) {
+	a.totp = store
+}
+
 // UpdateConfig updates the auth configuration dynamically.
 // This allows changing auth settings without restarting the server.
 func (a *AuthManager) UpdateConfig(authConfig *config.WebAuth) {
@@ -295,6 +332,14 @@ func (a *AuthManager) UpdateConfig(authConfig *config.WebAuth) {
 
 	a.config = authConfig
 
+	// Keep the pending OpenID Connect logins unless the provider changed
+	switch {
+	case !authConfig.HasOIDCAuth():
+		a.oidc = nil
+	case a.oidc == nil || !reflect.DeepEqual(*a.oidc.config, *authConfig.OIDC):
+		a.oidc = newOIDCLogin(authConfig.OIDC)
+	}
+
 	// Re-parse the allow list
 	a.allowedNets = nil
 	a.allowedIPs = nil
@@ -368,7 +413,7 @@ func (a *AuthManager) IsEnabled() bool {
 	if a == nil || a.config == nil {
 		return false
 	}
-	return a.HasValidCredentials() || a.HasCloudflareAccess()
+	return a.HasValidCredentials() || a.HasCloudflareAccess() || a.HasOIDC()
 }
 
 // HasCloudflareAccess returns true if Cloudflare Access JWT validation is configured.
@@ -380,7 +425,15 @@ func (am *AuthManager) HasCloudflareAccess() bool {
 // This is used to validate that credentials are properly configured before
 // enabling external access.
 func (a *AuthManager) HasValidCredentials() bool {
-	if a.config == nil || a.config.Simple == nil {
+	if a.config == nil {
+		return false
+	}
+	for _, user := range a.config.Users {
+		if user.PasswordHash != "" {
+			return true
+		}
+	}
+	if a.config.Simple == nil {
 		return false
 	}
 	return a.config.Simple.Username != "" && a.config.Simple.Password != ""
@@ -428,6 +481,9 @@ func (am *AuthManager) ValidateCFAccessToken(r *http.Request) (string, error) {
 // CredentialError returns an error describing why credentials are invalid,
 // or nil if credentials are valid.
 func (a *AuthManager) CredentialError() error {
+	if a.config != nil && a.config.Simple == nil && a.HasValidCredentials() {
+		return nil // User accounts with passwords
+	}
 	if a.config == nil || a.config.Simple == nil {
 		return ErrNoCredentials
 	}
@@ -499,9 +555,16 @@ func generateToken() (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
-// ValidateCredentials checks if the username and password match the config.
+// ValidateCredentials checks if the username and password match the simple
+// auth credentials or the password hash of a user account.
 func (a *AuthManager) ValidateCredentials(username, password string) bool {
-	if a.config == nil || a.config.Simple == nil {
+	if a.config == nil {
+		return false
+	}
+	if user := a.config.FindUser(username); user != nil && user.PasswordHash != "" {
+		return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+	}
+	if a.config.Simple == nil {
 		return false
 	}
 	// Use constant-time comparison to prevent timing attacks
@@ -510,6 +573,63 @@ func (a *AuthManager) ValidateCredentials(username, password string) bool {
 	return usernameMatch && passwordMatch
 }
 
+// lookupUser returns the user account of an authenticated username. A nil
+// user has full access: no user accounts are configured, or the username is
+// the one of the simple auth (an admin). ok is false for unknown usernames.
+func (a *AuthManager) lookupUser(username string) (user *config.WebUser, ok bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if !a.config.HasUsers() {
+		return nil, true
+	}
+	if user := a.config.FindUser(username); user != nil {
+		return user, true
+	}
+	if a.config.Simple != nil && a.config.Simple.Username == username {
+		return nil, true
+	}
+	return nil, false
+}
+
+// lookupUserByEmail returns the user account of a Cloudflare Access or OpenID
+// Connect identity. A nil user has full access (no user accounts are
+// configured); ok is false for identities without a user account.
+func (a *AuthManager) lookupUserByEmail(email string) (user *config.WebUser, ok bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if !a.config.HasUsers() {
+		return nil, true
+	}
+	if user := a.config.FindUserByEmail(email); user != nil {
+		return user, true
+	}
+	return nil, false
+}
+
+// serveAsUser serves an authenticated request after checking that the user
+// account (nil for full access) has the role the request needs.
+func (a *AuthManager) serveAsUser(w http.ResponseWriter, r *http.Request, next http.Handler, identity string, user *config.WebUser) {
+	if role := requiredRoleForRequest(r, a.apiPrefix); !user.HasRole(role) {
+		logging.Auth().Info("AUTH: User lacks role",
+			"user", identity,
+			"role", user.Role,
+			"required", role,
+			"method", r.Method,
+			"path", r.URL.Path,
+		)
+		metricAuthFailures.Inc(authFailureForbidden)
+		http.Error(w, "Forbidden: the "+role+" role is required", http.StatusForbidden)
+		return
+	}
+	ctx := context.WithValue(r.Context(), contextKeyAuthUser, identity)
+	if user != nil {
+		ctx = context.WithValue(ctx, contextKeyWebUser, user)
+	}
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // CreateSession creates a new authenticated session for the user.
 // If the user has too many sessions, the oldest ones are evicted.
 func (a *AuthManager) CreateSession(username string) (*AuthSession, error) {
@@ -703,6 +823,8 @@ var publicAPIPaths = map[string]bool{
 	"/api/supported-runners": true, // Platform information endpoint (no sensitive data)
 	"/api/auth-info":         true, // Auth info endpoint must be accessible before login (used by login page)
 	"/api/health":            true, // Health check must be accessible without auth for tunnel monitoring
+	oidcLoginPath:            true, // OpenID Connect login starts before being authenticated
+	oidcCallbackPath:         true, // OpenID Connect login completes before being authenticated
 }
 
 // isPublicPath checks if a path is public (no auth required).
@@ -795,9 +917,11 @@ const ContextKeyExternalConnection contextKey = "external_connection"
 
 // contextKeyAuthUser is the context key used to store the authenticated user identity.
 // The value is a string in one of these forms:
-//   - session username (e.g. "alice") for session-cookie auth
+//   - session username (e.g. "alice") for session-cookie auth, which is
+//     "oidc:<email>" for OpenID Connect logins without a user account
 //   - "cf:<email>" for Cloudflare Access JWT auth
 //   - "allowlist:<ip>" for IP allow list bypass
+//   - "token:<name>" for API token auth
 const contextKeyAuthUser contextKey = "authUser"
 
 // IsExternalConnection returns true if the request came through the external listener.
@@ -877,6 +1001,44 @@ func (a *AuthManager) AuthMiddleware(next http.Handler) http.Handler {
 			"api_prefix", a.apiPrefix,
 		)
 
+		// Check API tokens. Requests with a token are never authenticated by
+		// other means, so that a revoked or expired token doesn't silently fall
+		// back to a session cookie.
+		if bearer := bearerToken(r); strings.HasPrefix(bearer, apiTokenPrefix) {
+			token, err := a.apiTokens.Verify(bearer)
+			if err != nil {
+				logger.Info("AUTH: API token rejected",
+					"error", err,
+					"path", r.URL.Path,
+					"client_ip", clientIP,
+				)
+				metricAuthFailures.Inc(authFailureAPIToken)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			scope, allowed := apiTokenScopeForRequest(r, a.apiPrefix, a.metricsPath)
+			if !allowed || !token.HasScope(scope) {
+				logger.Info("AUTH: API token lacks scope",
+					"token", token.Name,
+					"scope", scope,
+					"method", r.Method,
+					"path", r.URL.Path,
+				)
+				metricAuthFailures.Inc(authFailureForbidden)
+				http.Error(w, "Forbidden: the API token lacks the required scope", http.StatusForbidden)
+				return
+			}
+			logger.Debug("AUTH: API token authenticated",
+				"token", token.Name,
+				"path", r.URL.Path,
+				"client_ip", clientIP,
+			)
+			ctx := context.WithValue(r.Context(), contextKeyAuthUser, "token:"+token.Name)
+			r = r.WithContext(context.WithValue(ctx, contextKeyAPIToken, token))
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Check Cloudflare Access JWT
 		if a.HasCloudflareAccess() {
 			hasJWTHeader := r.Header.Get("Cf-Access-Jwt-Assertion") != ""
@@ -886,8 +1048,17 @@ func (a *AuthManager) AuthMiddleware(next http.Handler) http.Handler {
 					"path", r.URL.Path,
 					"remote_addr", r.RemoteAddr,
 				)
-				r = r.WithContext(context.WithValue(r.Context(), contextKeyAuthUser, "cf:"+email))
-				next.ServeHTTP(w, r)
+				user, ok := a.lookupUserByEmail(email)
+				if !ok {
+					logger.Warn("AUTH: No user account for Cloudflare Access identity",
+						"email", email,
+						"path", r.URL.Path,
+					)
+					metricAuthFailures.Inc(authFailureForbidden)
+					http.Error(w, "Forbidden: no user account for "+email, http.StatusForbidden)
+					return
+				}
+				a.serveAsUser(w, r, next, "cf:"+email, user)
 				return
 			} else if hasJWTHeader {
 				// Only log at warn level if a JWT was actually provided but failed
@@ -922,12 +1093,14 @@ func (a *AuthManager) AuthMiddleware(next http.Handler) http.Handler {
 			// For API requests, return 401
 			if isAPIRequest {
 				logger.Info("AUTH: Returning 401 for API request", "path", r.URL.Path, "raw_uri", r.RequestURI)
+				metricAuthFailures.Inc(authFailureSession)
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 			// For WebSocket requests, return 401
 			if r.URL.Path == "/ws" || strings.HasSuffix(r.URL.Path, "/ws") {
 				logger.Info("AUTH: Returning 401 for WebSocket request", "path", r.URL.Path)
+				metricAuthFailures.Inc(authFailureSession)
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
@@ -942,8 +1115,16 @@ func (a *AuthManager) AuthMiddleware(next http.Handler) http.Handler {
 			"client_ip", clientIP,
 			"username", session.Username,
 		)
-		r = r.WithContext(context.WithValue(r.Context(), contextKeyAuthUser, session.Username))
-		next.ServeHTTP(w, r)
+		user, ok := a.lookupUser(session.Username)
+		if !ok {
+			// The user account was removed from the configuration
+			logger.Info("AUTH: Session of an unknown user", "username", session.Username)
+			a.InvalidateSession(session.Token)
+			metricAuthFailures.Inc(authFailureSession)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		a.serveAsUser(w, r, next, session.Username, user)
 	})
 }
 
@@ -951,6 +1132,7 @@ func (a *AuthManager) AuthMiddleware(next http.Handler) http.Handler {
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Code     string `json:"code,omitempty"` // Two-factor authentication or recovery code
 }
 
 // LoginResponse represents a login response.
@@ -958,6 +1140,7 @@ type LoginResponse struct {
 	Success       bool   `json:"success"`
 	Error         string `json:"error,omitempty"`
 	RetryAfterSec int    `json:"retry_after_sec,omitempty"` // Seconds until retry allowed (when rate limited)
+	TOTPRequired  bool   `json:"totp_required,omitempty"`   // The password is valid, but a code is required
 }
 
 // HandleLogin handles POST /api/login.
@@ -1022,6 +1205,7 @@ func (a *AuthManager) HandleLogin(w http.ResponseWriter, r *http.Request) {
 			"client_ip", ipKey,
 			"retry_after_sec", retryAfter,
 		)
+		auditLogin(ipKey, "", "password", audit.OutcomeDenied, "rate_limited")
 		w.Header().Set("Retry-After", fmt.Sprintf("%d", retryAfter))
 		writeJSON(w, http.StatusTooManyRequests, LoginResponse{
 			Success:       false,
@@ -1060,41 +1244,40 @@ func (a *AuthManager) HandleLogin(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debug("Validating credentials",
 		"username", req.Username,
-		"config_username", a.config.Simple.Username,
-		"config_has_password", a.config.Simple.Password != "",
+		"has_simple_auth", a.config.Simple != nil,
+		"users", len(a.config.Users),
 	)
 
 	if !a.ValidateCredentials(req.Username, req.Password) {
-		// Record the failure and check if now blocked
-		nowBlocked, lockoutDuration := a.rateLimiter.RecordFailure(ipKey)
+		// Use a generic error message to prevent username enumeration
+		metricAuthFailures.Inc(authFailurePassword)
+		auditLogin(ipKey, req.Username, "password", audit.OutcomeDenied, "invalid_credentials")
+		a.loginFailed(w, ipKey, req.Username, "Invalid username or password")
+		return
+	}
 
-		if nowBlocked {
-			retryAfter := int(lockoutDuration.Seconds()) + 1
-			logger.Warn("Login failed - IP now rate limited",
-				"client_ip", ipKey,
-				"username", req.Username,
-				"lockout_duration_sec", int(lockoutDuration.Seconds()),
-			)
-			w.Header().Set("Retry-After", fmt.Sprintf("%d", retryAfter))
-			writeJSON(w, http.StatusTooManyRequests, LoginResponse{
-				Success:       false,
-				Error:         "Too many failed attempts. Please try again later.",
-				RetryAfterSec: retryAfter,
+	// Second factor, for users that enrolled one
+	if a.totp != nil && a.totp.IsEnabled(req.Username) {
+		if req.Code == "" {
+			logger.Debug("Login requires a two-factor authentication code", "username", req.Username)
+			writeJSON(w, http.StatusUnauthorized, LoginResponse{
+				Success:      false,
+				Error:        "Enter the code of your authenticator app",
+				TOTPRequired: true,
 			})
-		} else {
-			remaining := a.rateLimiter.RemainingAttempts(ipKey)
-			logger.Warn("Login failed - invalid credentials",
+			return
+		}
+		if err := a.totp.Verify(req.Username, req.Code); err != nil {
+			logger.Warn("Login failed - invalid two-factor authentication code",
 				"client_ip", ipKey,
 				"username", req.Username,
-				"remaining_attempts", remaining,
+				"error", err,
 			)
-			// Use a generic error message to prevent username enumeration
-			writeJSON(w, http.StatusUnauthorized, LoginResponse{
-				Success: false,
-				Error:   "Invalid username or password",
-			})
+			metricAuthFailures.Inc(authFailureTOTP)
+			auditLogin(ipKey, req.Username, "password", audit.OutcomeDenied, "invalid_totp_code")
+			a.loginFailed(w, ipKey, req.Username, "Invalid authentication code")
+			return
 		}
-		return
 	}
 
 	// Successful login - clear any failure records for this IP
@@ -1107,6 +1290,7 @@ func (a *AuthManager) HandleLogin(w http.ResponseWriter, r *http.Request) {
 			"username", req.Username,
 			"error", err,
 		)
+		auditLogin(ipKey, req.Username, "password", audit.OutcomeFailure, "session_error")
 		writeJSON(w, http.StatusInternalServerError, LoginResponse{
 			Success: false,
 			Error:   "Failed to create session",
@@ -1118,11 +1302,67 @@ func (a *AuthManager) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		"client_ip", ipKey,
 		"username", req.Username,
 	)
+	auditLogin(ipKey, req.Username, "password", audit.OutcomeSuccess, "")
 
 	a.SetSessionCookie(w, r, session)
 	writeJSON(w, http.StatusOK, LoginResponse{Success: true})
 }
 
+// auditLogin records a login attempt in the audit log. The user is only set
+// as the actor of successful logins.
+func auditLogin(clientIP, username, method, outcome, reason string) {
+	e := audit.Event{
+		Actor:   audit.Actor{IP: clientIP},
+		Action:  audit.ActionLogin,
+		Target:  username,
+		Outcome: outcome,
+		Details: map[string]string{"method": method},
+	}
+	if outcome == audit.OutcomeSuccess {
+		e.Actor.User = username
+	}
+	if reason != "" {
+		e.Details["reason"] = reason
+	}
+	audit.Record(e)
+}
+
+// loginFailed records a failed login attempt and writes its response: a
+// 429 once the IP is rate limited, a 401 with the message otherwise.
+func (a *AuthManager) loginFailed(w http.ResponseWriter, ipKey, username, message string) {
+	logger := logging.Auth()
+
+	// Record the failure and check if now blocked
+	nowBlocked, lockoutDuration := a.rateLimiter.RecordFailure(ipKey)
+
+	if nowBlocked {
+		retryAfter := int(lockoutDuration.Seconds()) + 1
+		logger.Warn("Login failed - IP now rate limited",
+			"client_ip", ipKey,
+			"username", username,
+			"lockout_duration_sec", int(lockoutDuration.Seconds()),
+		)
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", retryAfter))
+		writeJSON(w, http.StatusTooManyRequests, LoginResponse{
+			Success:       false,
+			Error:         "Too many failed attempts. Please try again later.",
+			RetryAfterSec: retryAfter,
+		})
+		return
+	}
+
+	remaining := a.rateLimiter.RemainingAttempts(ipKey)
+	logger.Warn("Login failed - invalid credentials",
+		"client_ip", ipKey,
+		"username", username,
+		"remaining_attempts", remaining,
+	)
+	writeJSON(w, http.StatusUnauthorized, LoginResponse{
+		Success: false,
+		Error:   message,
+	})
+}
+
 // HandleLogout handles POST /api/logout.
 func (a *AuthManager) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
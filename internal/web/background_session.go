@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/coder/acp-go-sdk"
+	"go.opentelemetry.io/otel/attribute"
 
 	mittoAcp "github.com/inercia/mitto/internal/acp"
 	"github.com/inercia/mitto/internal/auxiliary"
@@ -24,9 +25,11 @@ import (
 	"github.com/inercia/mitto/internal/conversion"
 	"github.com/inercia/mitto/internal/logging"
 	"github.com/inercia/mitto/internal/mcpserver"
+	"github.com/inercia/mitto/internal/notify"
 	"github.com/inercia/mitto/internal/processors"
 	"github.com/inercia/mitto/internal/runner"
 	"github.com/inercia/mitto/internal/session"
+	"github.com/inercia/mitto/internal/tracing"
 )
 
 // BackgroundSession manages an ACP session that runs independently of WebSocket connections.
@@ -148,6 +151,10 @@ type BackgroundSession struct {
 	// Restricted runner for sandboxed execution
 	runner *runner.Runner // Optional runner for restricted execution (nil = direct execution)
 
+	// terminals runs commands requested by the agent via ACP terminal/* methods.
+	// Recreated with each WebClient so terminals never outlive the agent that owns them.
+	terminals *mittoAcp.TerminalManager
+
 	// onStreamingStateChanged is called when the session's streaming state changes.
 	onStreamingStateChanged func(sessionID string, isStreaming bool)
 
@@ -162,6 +169,13 @@ type BackgroundSession struct {
 	// Used to cache plan state in SessionManager for restoration on conversation switch.
 	onPlanStateChanged func(sessionID string, entries []PlanEntry)
 
+	// onPromptFailed is called when a prompt fails, with the error shown to the user.
+	onPromptFailed func(sessionID, message string)
+
+	// onResourceLimitExceeded is called when a prompt is cancelled for exceeding
+	// a resource limit of the workspace.
+	onResourceLimitExceeded func(sessionID string, err *ACPClassifiedError)
+
 	// onTitleGenerated is called when a title is auto-generated for this session.
 	// Used to broadcast session_renamed events to all clients.
 	onTitleGenerated func(sessionID, title string)
@@ -187,6 +201,14 @@ type BackgroundSession struct {
 	acpCwd               string                                 // Working directory for ACP process (for restart)
 	serverEnv            map[string]string                      // Server-specific env vars from settings.json (for restart)
 	acpServerConstraints map[string]*config.ACPServerConstraint // Auto-selection constraints from the ACP server config
+	acpServerPricing     *config.ACPPricing                     // Price table of the ACP server (for usage costs)
+	acpServer            string                                 // Name of the ACP server (for metrics)
+	usageCurrency        string                                 // Currency of the usage costs
+	usageBudget          *UsageBudget                           // Usage budgets (pauses queue processing when exceeded)
+	permissionPolicy     *config.PermissionPolicy               // Permission rules of the workspace, agent and global config (nil if none)
+	resourceLimits       *config.ResourceLimits                 // Resource limits of the workspace (nil = unlimited)
+	notifier             *notify.Notifier                       // Outbound webhook notifier (nil if no webhook is configured)
+	permissionTokens     *PermissionTokens                      // Issues approval tokens of permission requests (optional)
 	restartCount         int                                    // Total number of restarts across the session lifetime
 	restartTimes         []time.Time                            // Timestamps of recent restarts (for rate limiting)
 	restartReasons       []RestartReason                        // Reasons for recent restarts (parallel to restartTimes)
@@ -217,6 +239,11 @@ type BackgroundSession struct {
 	lastUsage   *acp.Usage
 	lastUsageMu sync.Mutex
 
+	// Trace context of the prompt in progress (carrying its "mitto.prompt" span),
+	// the parent of the tool call and markdown flush spans. Nil when idle.
+	promptTraceCtx   context.Context
+	promptTraceCtxMu sync.Mutex
+
 	// Context window usage — updated from SessionUsageUpdate notifications.
 	contextSize    int
 	contextUsed    int
@@ -290,9 +317,28 @@ type BackgroundSessionConfig struct {
 	APIPrefix           string                      // URL prefix for API endpoints (for HTTP file links)
 	WorkspaceUUID       string                      // Workspace UUID for secure file links
 
+	// Worktree is the git worktree the session works in, recorded in its
+	// metadata when the session is created. Optional.
+	Worktree *session.WorktreeInfo
+
 	// MittoConfig is the full Mitto configuration (used for default flags)
 	MittoConfig *config.Config
 
+	// UsageBudget enforces the usage budgets; queued messages are not sent while
+	// a budget is exceeded. Optional.
+	UsageBudget *UsageBudget
+
+	// PermissionPolicy decides permission requests before auto-approve and the
+	// permission dialog. Optional.
+	PermissionPolicy *config.PermissionPolicy
+
+	// Notifier sends lifecycle events to the outbound webhooks. Optional.
+	Notifier *notify.Notifier
+
+	// PermissionTokens issues the approval tokens sent with permission_requested
+	// notifications. Optional.
+	PermissionTokens *PermissionTokens
+
 	// AvailableACPServers is the pre-computed list of ACP servers that have workspaces
 	// configured for the session's working directory. Populated by SessionManager using
 	// the same logic as the mitto_conversation_get_current MCP tool.
@@ -313,6 +359,18 @@ type BackgroundSessionConfig struct {
 	// Used to cache plan state in SessionManager for restoration on conversation switch.
 	OnPlanStateChanged func(sessionID string, entries []PlanEntry)
 
+	// OnPromptFailed is called when a prompt fails for good (not when the agent is
+	// restarted and the prompt retried), with the error shown to the user.
+	// Used by headless runs to tell the end of a failed turn.
+	OnPromptFailed func(sessionID, message string)
+
+	// ResourceLimits are the resource limits of the workspace. Optional.
+	ResourceLimits *config.ResourceLimits
+
+	// OnResourceLimitExceeded is called when a prompt is cancelled for exceeding
+	// a resource limit, with the permanent error shown to the user.
+	OnResourceLimitExceeded func(sessionID string, err *ACPClassifiedError)
+
 	// OnConfigOptionChanged is called when any session config option changes.
 	// Used to broadcast config changes to all connected clients.
 	// The configID identifies which option changed, and value is the new value.
@@ -386,6 +444,8 @@ func NewBackgroundSession(cfg BackgroundSessionConfig) (*BackgroundSession, erro
 		onUIPromptStateChanged:  cfg.OnUIPromptStateChanged,
 		onUIPromptTimeout:       cfg.OnUIPromptTimeout,
 		onPlanStateChanged:      cfg.OnPlanStateChanged,
+		onPromptFailed:          cfg.OnPromptFailed,
+		onResourceLimitExceeded: cfg.OnResourceLimitExceeded,
 		onConfigChanged:         cfg.OnConfigOptionChanged,
 		onTitleGenerated:        cfg.OnTitleGenerated,
 		onSelfDestruct:          cfg.OnSelfDestruct,
@@ -402,6 +462,16 @@ func NewBackgroundSession(cfg BackgroundSessionConfig) (*BackgroundSession, erro
 
 	// Look up ACP server constraints from config
 	bs.acpServerConstraints = lookupACPServerConstraints(cfg.MittoConfig, cfg.ACPServer)
+	bs.acpServerPricing = lookupACPServerPricing(cfg.MittoConfig, cfg.ACPServer)
+	bs.acpServer = cfg.ACPServer
+	if cfg.MittoConfig != nil {
+		bs.usageCurrency = cfg.MittoConfig.Usage.GetCurrency()
+	}
+	bs.usageBudget = cfg.UsageBudget
+	bs.permissionPolicy = cfg.PermissionPolicy
+	bs.resourceLimits = cfg.ResourceLimits
+	bs.notifier = cfg.Notifier
+	bs.permissionTokens = cfg.PermissionTokens
 
 	// Wire prompt-mode processor execution to auxiliary sessions
 	if bs.processorManager != nil && bs.auxiliaryManager != nil {
@@ -442,10 +512,11 @@ func NewBackgroundSession(cfg BackgroundSessionConfig) (*BackgroundSession, erro
 			runnerType = cfg.Runner.Type()
 			isRestricted = cfg.Runner.IsRestricted()
 		}
-		cfg.Store.UpdateMetadata(bs.persistedID, func(meta *session.Metadata) {
+		err := cfg.Store.UpdateMetadata(bs.persistedID, func(meta *session.Metadata) {
 			if cfg.SessionName != "" {
 				meta.Name = cfg.SessionName
 			}
+			meta.Worktree = cfg.Worktree
 			meta.RunnerType = runnerType
 			meta.RunnerRestricted = isRestricted
 
@@ -455,27 +526,26 @@ func NewBackgroundSession(cfg BackgroundSessionConfig) (*BackgroundSession, erro
 				meta.AdvancedSettings = make(map[string]bool)
 			}
 
-			// Apply configured default flags from config
+			// Apply the configured default flags and the compile-time defaults,
+			// preserving existing values
+			var defaultFlags map[string]bool
 			if cfg.MittoConfig != nil && cfg.MittoConfig.Conversations != nil {
-				for flagName, flagValue := range cfg.MittoConfig.Conversations.DefaultFlags {
-					// Only set the flag if it's not already set (preserve existing values)
-					if _, exists := meta.AdvancedSettings[flagName]; !exists {
-						meta.AdvancedSettings[flagName] = flagValue
-					}
-				}
+				defaultFlags = cfg.MittoConfig.Conversations.DefaultFlags
 			}
-
-			// Apply compile-time defaults for flags not explicitly configured
-			// This ensures all flags have a value (either from config or compile-time default)
-			for _, flagDef := range session.AvailableFlags {
-				if _, exists := meta.AdvancedSettings[flagDef.Name]; !exists {
-					// Only set if the compile-time default is true (false is the zero value)
-					if flagDef.Default {
-						meta.AdvancedSettings[flagDef.Name] = true
-					}
+			for flagName, flagValue := range session.DefaultFlagValues(defaultFlags) {
+				if _, exists := meta.AdvancedSettings[flagName]; !exists {
+					meta.AdvancedSettings[flagName] = flagValue
 				}
 			}
 		})
+		// A worktree that isn't recorded would be removed as orphaned
+		if err != nil && cfg.Worktree != nil {
+			cancel()
+			if delErr := cfg.Store.Delete(bs.persistedID); delErr != nil && cfg.Logger != nil {
+				cfg.Logger.Warn("Failed to delete session", "session_id", bs.persistedID, "error", delErr)
+			}
+			return nil, fmt.Errorf("failed to record session worktree: %w", err)
+		}
 
 		// Initialize nextSeq from MaxSeq if available, otherwise from EventCount
 		// MaxSeq tracks the highest seq persisted, which may be higher than EventCount
@@ -588,6 +658,8 @@ func ResumeBackgroundSession(config BackgroundSessionConfig) (*BackgroundSession
 		onUIPromptStateChanged:  config.OnUIPromptStateChanged,
 		onUIPromptTimeout:       config.OnUIPromptTimeout,
 		onPlanStateChanged:      config.OnPlanStateChanged,
+		onPromptFailed:          config.OnPromptFailed,
+		onResourceLimitExceeded: config.OnResourceLimitExceeded,
 		onConfigChanged:         config.OnConfigOptionChanged,
 		onTitleGenerated:        config.OnTitleGenerated,
 		onSelfDestruct:          config.OnSelfDestruct,
@@ -604,6 +676,16 @@ func ResumeBackgroundSession(config BackgroundSessionConfig) (*BackgroundSession
 
 	// Look up ACP server constraints from config
 	bs.acpServerConstraints = lookupACPServerConstraints(config.MittoConfig, config.ACPServer)
+	bs.acpServerPricing = lookupACPServerPricing(config.MittoConfig, config.ACPServer)
+	bs.acpServer = config.ACPServer
+	if config.MittoConfig != nil {
+		bs.usageCurrency = config.MittoConfig.Usage.GetCurrency()
+	}
+	bs.usageBudget = config.UsageBudget
+	bs.permissionPolicy = config.PermissionPolicy
+	bs.resourceLimits = config.ResourceLimits
+	bs.notifier = config.Notifier
+	bs.permissionTokens = config.PermissionTokens
 
 	// Wire prompt-mode processor execution to auxiliary sessions
 	if bs.processorManager != nil && bs.auxiliaryManager != nil {
@@ -884,6 +966,13 @@ func (bs *BackgroundSession) WaitForResponseComplete(timeout time.Duration) bool
 	}
 }
 
+// promptFailed reports a prompt that failed for good to the OnPromptFailed callback.
+func (bs *BackgroundSession) promptFailed(message string) {
+	if bs.onPromptFailed != nil {
+		bs.onPromptFailed(bs.persistedID, message)
+	}
+}
+
 // GetEventCount returns the current event count for the session.
 // Returns 0 if the recorder is not available or there's an error.
 func (bs *BackgroundSession) GetEventCount() int {
@@ -1199,6 +1288,11 @@ func (bs *BackgroundSession) Close(reason string) {
 		bs.acpClient.Close()
 	}
 
+	// Kill any commands the agent left running in terminals
+	if bs.terminals != nil {
+		bs.terminals.Close()
+	}
+
 	// Kill ACP process and clean up resources
 	bs.killACPProcess()
 
@@ -1446,6 +1540,7 @@ func (bs *BackgroundSession) recordRestart(reason RestartReason) {
 	now := time.Now()
 	bs.restartTimes = append(bs.restartTimes, now)
 	bs.restartReasons = append(bs.restartReasons, reason)
+	metricSessionRestarts.Inc(string(reason))
 
 	// Log restart reason for telemetry
 	if bs.logger != nil {
@@ -1494,6 +1589,26 @@ func (bs *BackgroundSession) GetProcessorStats() (count int, activations int, la
 	return bs.processorManager.ProcessorCount(), bs.processorManager.TotalActivations(), bs.processorManager.LastActivationAt(), bs.processorManager.LastAppliedNames()
 }
 
+// promptTraceContext returns the trace context of the prompt in progress, or
+// the session context when there is none.
+// This method is thread-safe.
+func (bs *BackgroundSession) promptTraceContext() context.Context {
+	bs.promptTraceCtxMu.Lock()
+	defer bs.promptTraceCtxMu.Unlock()
+	if bs.promptTraceCtx != nil {
+		return bs.promptTraceCtx
+	}
+	return bs.ctx
+}
+
+// setPromptTraceContext sets (or clears, with nil) the trace context of the
+// prompt in progress.
+func (bs *BackgroundSession) setPromptTraceContext(ctx context.Context) {
+	bs.promptTraceCtxMu.Lock()
+	bs.promptTraceCtx = ctx
+	bs.promptTraceCtxMu.Unlock()
+}
+
 // GetLastUsage returns the last prompt's token usage, or nil if no prompt has completed yet.
 // This method is thread-safe.
 func (bs *BackgroundSession) GetLastUsage() *acp.Usage {
@@ -2064,6 +2179,54 @@ func (bs *BackgroundSession) startPromptInactivityWatchdog(ctx context.Context,
 	}()
 }
 
+// resourceSampler returns the sampler of the resource usage of the agent, or nil
+// if the agent process is not known (restricted runner).
+func (bs *BackgroundSession) resourceSampler() resourceSampler {
+	if bs.sharedProcess != nil {
+		return bs.sharedProcess.ResourceSampler()
+	}
+	if bs.acpCmd != nil && bs.acpCmd.Process != nil {
+		return &processTreeSampler{pid: bs.acpCmd.Process.Pid}
+	}
+	return nil
+}
+
+// startResourceLimitWatchdog launches a background goroutine that samples the
+// resource usage of the agent every resourceLimitCheckInterval while the prompt
+// runs, and cancels the prompt when a resource limit is exceeded. The samples are
+// also used for the usage accounting, so it runs even without limits.
+func (bs *BackgroundSession) startResourceLimitWatchdog(ctx context.Context, cancel context.CancelFunc, monitor *promptResourceMonitor) {
+	if monitor.sampler == nil && !monitor.limits.IsEnabled() {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(resourceLimitCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				exceeded := monitor.check(now)
+				if exceeded == nil {
+					continue
+				}
+				if bs.logger != nil {
+					bs.logger.Error("Agent exceeded a resource limit of the workspace, cancelling prompt",
+						"session_id", bs.persistedID,
+						"limit", exceeded.Limit,
+						"max", exceeded.Max,
+						"observed", exceeded.Observed)
+				}
+				cancel()
+				return
+			}
+		}
+	}()
+}
+
 // buildACPProcessEnv constructs the environment slice for an ACP subprocess.
 // Keys are replaced in-place via mittoAcp.MergeEnv; precedence is:
 //
@@ -2404,6 +2567,7 @@ func (bs *BackgroundSession) doStartACPProcess(acpCommand, acpCwd, workingDir, a
 				ReadTextFile:  true,
 				WriteTextFile: true,
 			},
+			Terminal: true,
 		},
 	})
 	if err != nil {
@@ -2587,7 +2751,7 @@ func (bs *BackgroundSession) doStartACPProcess(acpCommand, acpCwd, workingDir, a
 // Used by both the per-session and shared-process paths to create a WebClient.
 func (bs *BackgroundSession) buildWebClientConfig() WebClientConfig {
 	cfg := WebClientConfig{
-		AutoApprove:          bs.autoApprove,
+		AutoApprove:          bs.autoApprove && bs.permissionPolicy == nil, // Rules are evaluated in onPermission
 		SeqProvider:          bs,
 		Logger:               bs.logger,
 		OnAgentMessage:       bs.onAgentMessage,
@@ -2603,6 +2767,11 @@ func (bs *BackgroundSession) buildWebClientConfig() WebClientConfig {
 		OnMittoToolCall:      bs.onMittoToolCall,
 		OnContextUsageUpdate: bs.onContextUsageUpdate,
 		OnActivity:           bs.signalAgentActivity,
+		TraceContext:         bs.promptTraceContext,
+		Terminals:            bs.newTerminalManager(),
+	}
+	if bs.store != nil {
+		cfg.OnFileChange = bs.onFileChange
 	}
 	if bs.fileLinksConfig.IsEnabled() {
 		cfg.FileLinksConfig = &conversion.FileLinkerConfig{
@@ -2617,6 +2786,38 @@ func (bs *BackgroundSession) buildWebClientConfig() WebClientConfig {
 	return cfg
 }
 
+// newTerminalManager creates the terminal manager for a new WebClient, closing
+// the previous one (if any) so terminals from a dead agent process are killed.
+// Terminals run through the session's restricted runner and stream their
+// output to observers.
+func (bs *BackgroundSession) newTerminalManager() *mittoAcp.TerminalManager {
+	if bs.terminals != nil {
+		bs.terminals.Close()
+	}
+	bs.terminals = mittoAcp.NewTerminalManager(mittoAcp.TerminalManagerConfig{
+		Runner:     bs.runner,
+		DefaultCwd: bs.workingDir,
+		Env:        mittoAcp.BuildMittoEnv(bs.persistedID, bs.workingDir, "", bs.workspaceUUID),
+		Logger:     bs.logger,
+		OnOutput: func(terminalID, chunk string) {
+			bs.notifyObservers(func(o SessionObserver) {
+				o.OnTerminalOutput(TerminalOutputUpdate{TerminalID: terminalID, Output: chunk})
+			})
+		},
+		OnExit: func(terminalID string, status acp.TerminalExitStatus) {
+			bs.notifyObservers(func(o SessionObserver) {
+				o.OnTerminalOutput(TerminalOutputUpdate{
+					TerminalID: terminalID,
+					Exited:     true,
+					ExitCode:   status.ExitCode,
+					Signal:     status.Signal,
+				})
+			})
+		},
+	})
+	return bs.terminals
+}
+
 // creationRPCCtx returns a context suitable for the initial ACP session creation RPC.
 // It uses CreationCtx from the config if it already has a deadline; otherwise it
 // applies sessionCreationRPCTimeout.  The returned cancel function must be called.
@@ -3130,6 +3331,7 @@ func (bs *BackgroundSession) SetPromptResolver(resolver PromptResolverFunc) {
 // PromptMeta contains optional metadata about the prompt source.
 type PromptMeta struct {
 	SenderID         string          // Unique identifier of the sending client (for broadcast deduplication)
+	SentBy           string          // Identity of the user who sent the prompt (see contextKeyAuthUser), recorded in the event
 	PromptID         string          // Client-generated prompt ID (for delivery confirmation)
 	PromptName       string          // Name of workspace prompt (resolved to full text before ACP; empty for ad-hoc prompts)
 	ImageIDs         []string        // IDs of images attached to the prompt
@@ -3432,13 +3634,22 @@ retryAfterRestart:
 	// User prompts are persisted immediately (not buffered), so we need to
 	// refresh nextSeq after persistence to get the correct seq for the prompt
 	// The prompt ID is included so clients can clear pending prompts on reconnect
-	var userPromptSeq int64
+	var userPromptSeq, promptFirstSeq int64
 	if bs.recorder != nil {
-		if err := bs.recorder.RecordUserPromptComplete(message, imageRefs, fileRefs, meta.PromptID, meta.PromptName); err != nil && bs.logger != nil {
+		if err := bs.recorder.RecordUserPromptData(session.UserPromptData{
+			Message:    message,
+			Images:     imageRefs,
+			Files:      fileRefs,
+			PromptID:   meta.PromptID,
+			PromptName: meta.PromptName,
+			SentBy:     meta.SentBy,
+		}); err != nil && bs.logger != nil {
 			bs.logger.Error("Failed to persist user prompt", "error", err)
 		}
 		// Get the seq that was assigned to the user prompt (it's the current event count)
 		userPromptSeq = int64(bs.recorder.EventCount())
+		// The events of this prompt start at the user prompt, used to estimate usage
+		promptFirstSeq = bs.recorder.MaxSeq()
 		// Update nextSeq for subsequent agent events
 		bs.refreshNextSeq()
 	}
@@ -3563,8 +3774,15 @@ retryAfterRestart:
 		UserDataJSON:           userDataJSON,
 	}
 
+	// Root span of the prompt lifecycle, ended when the prompt goroutine returns.
+	traceCtx, promptSpan := tracing.Start(bs.ctx, "mitto.prompt",
+		attribute.String("mitto.session_id", bs.persistedID),
+		attribute.String("mitto.acp_server", bs.acpServer),
+		attribute.String("mitto.sender_id", meta.SenderID))
+	bs.setPromptTraceContext(traceCtx)
+
 	if bs.processorManager != nil {
-		procResult, procErr := bs.processorManager.Apply(bs.ctx, processorInput)
+		procResult, procErr := bs.processorManager.Apply(traceCtx, processorInput)
 		if procErr != nil {
 			if bs.logger != nil {
 				bs.logger.Error("Processor execution failed", "error", procErr)
@@ -3647,6 +3865,12 @@ retryAfterRestart:
 		// "please resend" message instead of looping forever.
 		autoRetried := false
 
+		var spanErr error
+		defer func() {
+			bs.setPromptTraceContext(nil)
+			tracing.End(promptSpan, spanErr)
+		}()
+
 		// For shared-process sessions, complete the deferred session/new handshake
 		// before the first prompt. This runs after the HTTP create path has already
 		// returned, so a busy agent delays the prompt — not conversation creation.
@@ -3655,14 +3879,17 @@ retryAfterRestart:
 		// idempotent and a no-op in that case.
 		if bs.sharedProcess != nil {
 			if err := bs.completeDeferredHandshake(); err != nil {
+				spanErr = err
 				if bs.logger != nil {
 					bs.logger.Error("Deferred session/new failed",
 						"session_id", bs.persistedID,
 						"error", err)
 				}
+				errMsg := "Could not start the agent session: " + err.Error() + ". Please try again."
 				bs.notifyObservers(func(o SessionObserver) {
-					o.OnError("Could not start the agent session: " + err.Error() + ". Please try again.")
+					o.OnError(errMsg)
 				})
+				bs.promptFailed(errMsg)
 				bs.promptMu.Lock()
 				bs.isPrompting = false
 				bs.promptStartTime = time.Time{}
@@ -3721,6 +3948,10 @@ retryAfterRestart:
 			// The error-handling path below reads it to surface a recoverable message and
 			// skip the crash-restart logic (the process is alive, not dead).
 			inactivityWatchdogFired atomic.Bool
+			// resourceMonitor samples the usage of the agent during the prompt and
+			// cancels it when a resource limit of the workspace is exceeded.
+			resourceMonitor *promptResourceMonitor
+			resourceData    session.ResourceData
 		)
 
 	retryPrompt:
@@ -3730,7 +3961,7 @@ retryAfterRestart:
 		// This ensures we fail fast instead of waiting for the ACP server's internal
 		// 60-second control request timeout when the CLI subprocess has crashed.
 		// See: claude-code-agent-sdk DEFAULT_CONTROL_REQUEST_TIMEOUT (60s)
-		promptCtx, promptCancel = context.WithCancel(bs.ctx)
+		promptCtx, promptCancel = context.WithCancel(traceCtx)
 		// NOTE: no defer — we call promptCancel() explicitly after the prompt
 		// returns so that (a) we clean up the health-monitor goroutine eagerly,
 		// and (b) a goto back to retryPrompt doesn't accumulate extra defers.
@@ -3774,6 +4005,10 @@ retryAfterRestart:
 		// "stuck, still responding" state that the process-death/connection monitors miss.
 		bs.startPromptInactivityWatchdog(promptCtx, promptCancel, &inactivityWatchdogFired)
 
+		// Monitor the resource usage of the agent against the workspace limits.
+		resourceMonitor = newPromptResourceMonitor(bs.resourceSampler(), bs.resourceLimits, time.Now(), bs.sharedProcess != nil)
+		bs.startResourceLimitWatchdog(promptCtx, promptCancel, resourceMonitor)
+
 		// On retry after ACP crash, freshContextSessionID is from the old (dead)
 		// connection; fall back to bs.acpID which holds the new session.
 		acpSessionIDForPrompt := bs.acpID
@@ -3792,6 +4027,13 @@ retryAfterRestart:
 		}
 		promptCancel()             // cancel context to unblock the health-monitor goroutine
 		promptEndedAt = time.Now() // captured for after-phase processors
+		resourceData = resourceMonitor.finish(promptEndedAt)
+		spanErr = err
+		promptOutcome := string(promptResp.StopReason)
+		if err != nil {
+			promptOutcome = "error"
+		}
+		metricPromptDuration.Observe(promptEndedAt.Sub(promptStartedAt).Seconds(), bs.acpServer, promptOutcome)
 
 		// Store token usage from the prompt response (if available).
 		if promptResp.Usage != nil {
@@ -3853,12 +4095,19 @@ retryAfterRestart:
 					"session_id", bs.persistedID)
 			}
 			bs.acpClient.FlushMarkdown()
+			bs.acpClient.EndToolCallSpans()
 			if bs.logger != nil {
 				bs.logger.Debug("prompt_completion_flush_markdown_done",
 					"session_id", bs.persistedID)
 			}
 		}
 
+		// Record the token usage of the prompt, once the response has been persisted.
+		if err == nil || promptResp.Usage != nil {
+			bs.recordPromptUsage(promptResp.Usage, message, promptFirstSeq)
+		}
+		bs.recordPromptResources(resourceData)
+
 		// Notify all observers
 		eventCount := bs.GetEventCount()
 		observerCount := bs.ObserverCount()
@@ -3903,7 +4152,28 @@ retryAfterRestart:
 				}
 			}
 
-			if inactivityWatchdogFired.Load() {
+			if exceeded := resourceMonitor.Exceeded(); exceeded != nil {
+				// A resource limit of the workspace was exceeded: the watchdog cancelled
+				// the prompt (or the kernel killed the agent for exceeding its memory
+				// limit). Do NOT auto-restart and retry: the retry would hit the same limit.
+				if bs.logger != nil {
+					bs.logger.Warn("prompt_cancelled_by_resource_limit",
+						"session_id", bs.persistedID,
+						"limit", exceeded.Limit,
+						"max", exceeded.Max,
+						"observed", exceeded.Observed)
+				}
+				metricResourceLimitExceeded.Inc(exceeded.Limit)
+				classified := exceeded.classifiedError()
+				errMsg := formatClassifiedError(classified)
+				bs.notifyObservers(func(o SessionObserver) {
+					o.OnError(errMsg)
+				})
+				if bs.onResourceLimitExceeded != nil {
+					bs.onResourceLimitExceeded(bs.persistedID, classified)
+				}
+				bs.promptFailed(errMsg)
+			} else if inactivityWatchdogFired.Load() {
 				// The agent stayed alive and connected but stopped streaming updates.
 				// The watchdog already cancelled the prompt and is_prompting was cleared
 				// above. Surface a recoverable message and do NOT auto-restart (the
@@ -3913,9 +4183,11 @@ retryAfterRestart:
 					bs.logger.Warn("prompt_cancelled_by_inactivity_watchdog",
 						"session_id", bs.persistedID)
 				}
+				errMsg := "The AI agent stopped responding (no activity for a while), so the conversation was reset. Please resend your message. If this keeps happening, switch to another conversation and back to restart the agent."
 				bs.notifyObservers(func(o SessionObserver) {
-					o.OnError("The AI agent stopped responding (no activity for a while), so the conversation was reset. Please resend your message. If this keeps happening, switch to another conversation and back to restart the agent.")
+					o.OnError(errMsg)
 				})
+				bs.promptFailed(errMsg)
 			} else if acpDead && autoRetried {
 				// The auto-retry already happened and the process crashed again.
 				// Don't consume another restart slot — let the next user-triggered prompt
@@ -3924,6 +4196,7 @@ retryAfterRestart:
 				bs.notifyObservers(func(o SessionObserver) {
 					o.OnError("AI agent restarted. Please resend your message.")
 				})
+				bs.promptFailed("AI agent restarted. Please resend your message.")
 			} else if acpDead && bs.canRestartACP() {
 				// First crash on this prompt — restart and automatically retry.
 				restartInfo := bs.getRestartInfo()
@@ -3940,6 +4213,7 @@ retryAfterRestart:
 					bs.notifyObservers(func(o SessionObserver) {
 						o.OnError(errMsg)
 					})
+					bs.promptFailed(errMsg)
 				} else {
 					// Restart succeeded — automatically retry the prompt.
 					autoRetried = true
@@ -3966,11 +4240,15 @@ retryAfterRestart:
 				bs.notifyObservers(func(o SessionObserver) {
 					o.OnError("The AI agent keeps crashing. Please switch to another conversation and back to restart.")
 				})
+				bs.notifyEvent(config.WebhookEventError, "The AI agent keeps crashing", map[string]any{"error": err.Error()})
+				bs.promptFailed("The AI agent keeps crashing. Please switch to another conversation and back to restart.")
 			} else {
 				userFriendlyErr := formatACPError(err)
 				bs.notifyObservers(func(o SessionObserver) {
 					o.OnError(userFriendlyErr)
 				})
+				bs.notifyEvent(config.WebhookEventError, userFriendlyErr, map[string]any{"error": err.Error()})
+				bs.promptFailed(userFriendlyErr)
 
 				// Advance the queue for transient errors where the ACP process is
 				// still healthy.  Skip queue processing for errors that indicate a
@@ -3998,6 +4276,10 @@ retryAfterRestart:
 			bs.notifyObservers(func(o SessionObserver) {
 				o.OnPromptComplete(eventCount)
 			})
+			bs.notifyEvent(config.WebhookEventTurnComplete, "", map[string]any{
+				"stop_reason": string(promptResp.StopReason),
+				"event_count": eventCount,
+			})
 
 			// Process next queued message if queue processing is enabled.
 			// dispatched is true when another queued turn was started (the session is
@@ -4039,7 +4321,7 @@ retryAfterRestart:
 			// sessionIdle is true when no further queued message was dispatched, so
 			// agentIdle processors fire only once the queue has drained.
 			if bs.processorManager != nil {
-				bs.applyAfterProcessors(bs.ctx, message, meta.SenderID,
+				bs.applyAfterProcessors(traceCtx, message, meta.SenderID,
 					string(promptResp.StopReason), promptStartedAt, promptEndedAt, promptResp, !dispatched)
 			}
 		}
@@ -4657,6 +4939,11 @@ func (bs *BackgroundSession) processNextQueuedMessage() bool {
 		return false
 	}
 
+	// Pause the queue while a usage budget is exceeded
+	if bs.usageBudgetExceeded() {
+		return false
+	}
+
 	// Get the queue for this session
 	if bs.store == nil {
 		return false
@@ -4684,6 +4971,80 @@ func (bs *BackgroundSession) processNextQueuedMessage() bool {
 	return true
 }
 
+// recordPromptUsage records a usage event with the token usage and cost of a prompt.
+// When the agent doesn't report usage, tokens are estimated from the prompt and
+// the agent's response, read from the events of the prompt (from firstSeq on).
+func (bs *BackgroundSession) recordPromptUsage(usage *acp.Usage, message string, firstSeq int64) {
+	if bs.recorder == nil {
+		return
+	}
+
+	var data session.UsageData
+	if usage != nil {
+		data = session.UsageData{
+			InputTokens:  int64(usage.InputTokens),
+			OutputTokens: int64(usage.OutputTokens),
+			TotalTokens:  int64(usage.TotalTokens),
+		}
+		if usage.CachedReadTokens != nil {
+			data.CachedReadTokens = int64(*usage.CachedReadTokens)
+		}
+		if usage.CachedWriteTokens != nil {
+			data.CachedWriteTokens = int64(*usage.CachedWriteTokens)
+		}
+		if usage.ThoughtTokens != nil {
+			data.ThoughtTokens = int64(*usage.ThoughtTokens)
+		}
+	} else {
+		data.Estimated = true
+		data.InputTokens = int64(processors.EstimateTokens(message))
+		if bs.store != nil {
+			if events, err := bs.store.ReadEventsFrom(bs.persistedID, max(firstSeq-1, 0), 0); err == nil {
+				data.OutputTokens = int64(processors.EstimateTokens(session.GetLastAgentMessage(events)))
+			}
+		}
+		data.TotalTokens = data.InputTokens + data.OutputTokens
+	}
+	if data.TotalTokens == 0 {
+		return
+	}
+	if bs.acpServerPricing != nil {
+		data.Cost = bs.acpServerPricing.Cost(data.InputTokens, data.OutputTokens, data.CachedReadTokens, data.CachedWriteTokens)
+		data.Currency = bs.usageCurrency
+	}
+
+	if err := bs.recorder.RecordUsage(data); err != nil {
+		if bs.logger != nil {
+			bs.logger.Warn("Failed to record prompt usage", "session_id", bs.persistedID, "error", err)
+		}
+		return
+	}
+	bs.refreshNextSeq()
+	bs.usageBudget.Invalidate()
+}
+
+// recordPromptResources adds the resource usage of the agent during a prompt to
+// the daily totals of the session.
+func (bs *BackgroundSession) recordPromptResources(data session.ResourceData) {
+	if bs.store == nil || bs.persistedID == "" {
+		return
+	}
+	if err := bs.store.AddResources(bs.persistedID, time.Now(), data); err != nil && bs.logger != nil {
+		bs.logger.Warn("Failed to record prompt resource usage", "session_id", bs.persistedID, "error", err)
+	}
+}
+
+// usageBudgetExceeded reports whether a usage budget is exceeded, logging why.
+// Queued messages are held back while it returns true.
+func (bs *BackgroundSession) usageBudgetExceeded() bool {
+	exceeded, reason := bs.usageBudget.Exceeded()
+	if exceeded && bs.logger != nil {
+		bs.logger.Debug("Usage budget exceeded, not sending queued message",
+			"session_id", bs.persistedID, "reason", reason)
+	}
+	return exceeded
+}
+
 // TryProcessQueuedMessage checks if the session is idle and enough time has passed since the last
 // response, then processes the next queued message. This is used for startup initialization
 // and periodic queue checking. Returns true if a message was sent.
@@ -4715,6 +5076,11 @@ func (bs *BackgroundSession) TryProcessQueuedMessage() bool {
 		return false
 	}
 
+	// Pause the queue while a usage budget is exceeded
+	if bs.usageBudgetExceeded() {
+		return false
+	}
+
 	// Check if delay has elapsed since last response
 	delaySeconds := 0
 	if bs.queueConfig != nil {
@@ -4765,6 +5131,7 @@ func (bs *BackgroundSession) sendQueuedMessage(queue *session.Queue, msg session
 	// Send the queued message
 	meta := PromptMeta{
 		SenderID:   "queue",
+		SentBy:     msg.SentBy,
 		PromptID:   msg.ID,
 		ImageIDs:   msg.ImageIDs,
 		Arguments:  msg.Arguments,
@@ -5022,6 +5389,24 @@ func (bs *BackgroundSession) onPlan(seq int64, entries []PlanEntry) {
 	})
 }
 
+// onFileChange records the content of a file before and after a write by a tool call,
+// so its changes can be diffed and reverted later.
+func (bs *BackgroundSession) onFileChange(seq int64, toolCallID string, snap *mittoAcp.FileSnapshot) {
+	if bs.IsClosed() || bs.store == nil || toolCallID == "" || snap == nil {
+		return
+	}
+	change := session.FileChange{
+		Seq:     seq,
+		Path:    snap.Path,
+		Existed: snap.Existed,
+		Before:  snap.Before,
+		After:   snap.After,
+	}
+	if err := bs.store.RecordFileChange(bs.persistedID, toolCallID, change); err != nil && bs.logger != nil {
+		bs.logger.Warn("Failed to record file change", "seq", seq, "tool_call_id", toolCallID, "path", snap.Path, "error", err)
+	}
+}
+
 func (bs *BackgroundSession) onFileWrite(seq int64, path string, size int) {
 	if bs.IsClosed() {
 		return
@@ -5097,9 +5482,32 @@ func (bs *BackgroundSession) onPermission(ctx context.Context, params acp.Reques
 		"has_observers", bs.HasObservers(),
 		"options_count", len(params.Options))
 
-	// Check if auto-approve is enabled (global flag OR per-session setting)
-	autoApprove := bs.autoApprove
-	if !autoApprove && bs.store != nil && bs.persistedID != "" {
+	// Permission rules take precedence over auto-approve and the dialog
+	decision := bs.evaluatePermissionPolicy(params)
+	switch decision.Action {
+	case config.PermissionActionApprove, config.PermissionActionDeny:
+		outcome, resp := "policy_approved", mittoAcp.AutoApprovePermission(params.Options)
+		if decision.Action == config.PermissionActionDeny {
+			outcome, resp = "policy_denied", mittoAcp.DenyPermission(params.Options)
+		}
+		selectedOption := ""
+		if resp.Outcome.Selected != nil {
+			selectedOption = string(resp.Outcome.Selected.OptionId)
+		}
+		bs.logger.Info("permission_"+outcome,
+			"title", title,
+			"tool_call_id", params.ToolCall.ToolCallId,
+			"rule", decision.Rule,
+			"scope", decision.Scope,
+			"selected_option", selectedOption)
+		bs.recordPermission(title, selectedOption, outcome, decision)
+		return resp, nil
+	}
+
+	// Check if auto-approve is enabled (global flag OR per-session setting),
+	// unless a rule requires asking the user
+	autoApprove := bs.autoApprove && decision.Action != config.PermissionActionAsk
+	if !autoApprove && decision.Action != config.PermissionActionAsk && bs.store != nil && bs.persistedID != "" {
 		// Check per-session auto-approve flag
 		if meta, err := bs.store.GetMetadata(bs.persistedID); err == nil {
 			autoApprove = session.GetFlagValue(meta.AdvancedSettings, session.FlagAutoApprovePermissions)
@@ -5123,8 +5531,8 @@ func (bs *BackgroundSession) onPermission(ctx context.Context, params acp.Reques
 			"tool_call_id", params.ToolCall.ToolCallId,
 			"selected_option", selectedOption)
 		// Record the permission decision
-		if bs.recorder != nil && resp.Outcome.Selected != nil {
-			bs.recorder.RecordPermission(title, string(resp.Outcome.Selected.OptionId), "auto_approved")
+		if resp.Outcome.Selected != nil {
+			bs.recordPermission(title, selectedOption, "auto_approved", decision)
 		}
 		return resp, nil
 	}
@@ -5179,6 +5587,14 @@ func (bs *BackgroundSession) onPermission(ctx context.Context, params acp.Reques
 		"tool_call_id", params.ToolCall.ToolCallId,
 		"option_count", len(options))
 
+	if bs.notifier != nil {
+		data := map[string]any{"tool_call_id": toolCallID}
+		if token := bs.permissionTokens.Issue(bs.persistedID, toolCallID); token != "" {
+			data["approval_token"] = token
+		}
+		bs.notifyEvent(config.WebhookEventPermissionRequested, title, data)
+	}
+
 	// Use the unified UIPrompt system to show the permission dialog and wait for response
 	resp, err := bs.UIPrompt(ctx, promptReq)
 	if err != nil {
@@ -5194,9 +5610,7 @@ func (bs *BackgroundSession) onPermission(ctx context.Context, params acp.Reques
 		bs.logger.Warn("permission_timed_out",
 			"title", title,
 			"tool_call_id", params.ToolCall.ToolCallId)
-		if bs.recorder != nil {
-			bs.recorder.RecordPermission(title, "", "timed_out")
-		}
+		bs.recordPermission(title, "", "timed_out", decision)
 		return mittoAcp.CancelledPermissionResponse(), nil
 	}
 
@@ -5207,9 +5621,7 @@ func (bs *BackgroundSession) onPermission(ctx context.Context, params acp.Reques
 		"selected_option", resp.OptionID)
 
 	// Record the permission decision
-	if bs.recorder != nil {
-		bs.recorder.RecordPermission(title, resp.OptionID, "user_selected")
-	}
+	bs.recordPermission(title, resp.OptionID, "user_selected", decision)
 
 	// Build ACP response
 	return acp.RequestPermissionResponse{
@@ -5410,6 +5822,19 @@ func lookupACPServerConstraints(cfg *config.Config, serverName string) map[strin
 	return nil
 }
 
+// lookupACPServerPricing returns the price table of the named ACP server, or nil if none.
+func lookupACPServerPricing(cfg *config.Config, serverName string) *config.ACPPricing {
+	if cfg == nil {
+		return nil
+	}
+	for _, srv := range cfg.ACPServers {
+		if srv.Name == serverName {
+			return srv.Pricing
+		}
+	}
+	return nil
+}
+
 // applyConfigConstraints checks ACP server constraints and auto-selects matching config option values.
 // Called after config options (like models) become available during ACP initialization.
 // Only applies constraints for config option categories that are present in the constraints map.
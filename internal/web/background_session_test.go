@@ -497,6 +497,10 @@ func (m *mockSessionObserver) OnContextUsageUpdate(size, used int) {
 	// no-op for testing
 }
 
+func (m *mockSessionObserver) OnTerminalOutput(update TerminalOutputUpdate) {
+	// no-op for testing
+}
+
 func (m *mockSessionObserver) getACPStoppedReasons() []string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -2011,6 +2015,8 @@ func (o *trackingObserver) OnNotification(req UINotifyRequest) {}
 
 func (o *trackingObserver) OnContextUsageUpdate(size, used int) {}
 
+func (o *trackingObserver) OnTerminalOutput(update TerminalOutputUpdate) {}
+
 // =============================================================================
 // GetMaxAssignedSeq Tests
 // =============================================================================
@@ -2,6 +2,7 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 
 	"golang.org/x/time/rate"
 
+	"github.com/inercia/mitto/internal/audit"
 	"github.com/inercia/mitto/internal/session"
 )
 
@@ -81,8 +83,20 @@ type CallbackRateLimiter struct {
 const (
 	// callbackBurst is the burst size (allows up to 3 requests in quick succession).
 	callbackBurst = 3
+
+	// maxCallbackBodySize is the maximum size of a callback request body (webhook payload).
+	maxCallbackBodySize = 1 << 20
+)
+
+// Webhook headers used by the callback endpoint.
+const (
+	headerGitHubSignature = "X-Hub-Signature-256"
+	headerGitLabToken     = "X-Gitlab-Token"
 )
 
+// callbackEventHeaders are the headers webhook providers send the event name in.
+var callbackEventHeaders = []string{"X-GitHub-Event", "X-Gitlab-Event", "X-Gitea-Event"}
+
 var (
 	// callbackRateLimit is the per-token rate limit (1 request per 10 seconds).
 	callbackRateLimit = rate.Every(10 * time.Second)
@@ -125,6 +139,32 @@ type CallbackTriggerRequest struct {
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// CallbackTestRequest is the request body for POST /api/sessions/{id}/callback/test.
+// Template and Filter, when set, are used instead of the saved ones.
+type CallbackTestRequest struct {
+	Payload  any     `json:"payload"`
+	Event    string  `json:"event,omitempty"`
+	Template *string `json:"template,omitempty"`
+	Filter   *string `json:"filter,omitempty"`
+}
+
+// CallbackTestResponse is the result of testing a payload against a callback.
+type CallbackTestResponse struct {
+	Triggered bool   `json:"triggered"`
+	Prompt    string `json:"prompt,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// callbackEvent returns the webhook event name of a request, if any.
+func callbackEvent(r *http.Request) string {
+	for _, h := range callbackEventHeaders {
+		if v := r.Header.Get(h); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // handleCallbackTrigger handles POST /api/callback/{token}
 // This is a PUBLIC endpoint (no auth required) that triggers a periodic prompt delivery.
 func (s *Server) handleCallbackTrigger(w http.ResponseWriter, r *http.Request) {
@@ -158,16 +198,23 @@ func (s *Server) handleCallbackTrigger(w http.ResponseWriter, r *http.Request) {
 
 	// 5. Check rate limit
 	if !s.callbackRateLimiter.Allow(token) {
+		auditRequest(r, audit.ActionCallbackTrigger, sessionID, audit.OutcomeDenied, "rate_limited")
 		writeErrorJSON(w, http.StatusTooManyRequests, "rate_limited", "Too many requests")
 		return
 	}
 
-	// 6. Parse optional metadata from request body (best-effort)
-	var req CallbackTriggerRequest
+	// 6. Read the request body (the webhook payload)
+	var body []byte
 	if r.Body != nil {
-		bodyBytes, _ := io.ReadAll(r.Body)
-		if len(bodyBytes) > 0 {
-			_ = json.Unmarshal(bodyBytes, &req) // Ignore errors - metadata is optional
+		var err error
+		body, err = io.ReadAll(io.LimitReader(r.Body, maxCallbackBodySize+1))
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_payload", "Failed to read request body")
+			return
+		}
+		if len(body) > maxCallbackBodySize {
+			writeErrorJSON(w, http.StatusRequestEntityTooLarge, "payload_too_large", "Request body is too large")
+			return
 		}
 	}
 
@@ -179,7 +226,8 @@ func (s *Server) handleCallbackTrigger(w http.ResponseWriter, r *http.Request) {
 	}
 
 	cs := store.Callback(sessionID)
-	if _, err := cs.Get(); err != nil {
+	cb, err := cs.Get()
+	if err != nil {
 		if err == session.ErrCallbackNotFound {
 			// Clean up stale index entry
 			s.callbackIndex.Remove(token)
@@ -190,7 +238,41 @@ func (s *Server) handleCallbackTrigger(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 8. Check periodic config exists and is enabled
+	// 8. Verify the signature when the callback has a secret
+	if err := cb.VerifySignature(body, r.Header.Get(headerGitHubSignature), r.Header.Get(headerGitLabToken)); err != nil {
+		if s.logger != nil {
+			s.logger.Warn("Callback signature rejected", "session_id", sessionID, "client_ip", r.RemoteAddr)
+		}
+		auditRequest(r, audit.ActionCallbackTrigger, sessionID, audit.OutcomeDenied, "invalid_signature")
+		writeErrorJSON(w, http.StatusUnauthorized, "invalid_signature", "Invalid or missing signature")
+		return
+	}
+
+	// 9. Decode the payload. It is only required to be JSON when the callback
+	// renders or filters it; otherwise only the optional metadata is used.
+	var req CallbackTriggerRequest
+	payload := session.CallbackPayload{Event: callbackEvent(r)}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &payload.Body); err != nil && cb.HasPayloadOptions() {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_payload", "Request body must be JSON")
+			return
+		}
+		_ = json.Unmarshal(body, &req) // Ignore errors - metadata is optional
+	}
+
+	// 10. Apply the filter: events it doesn't select are acknowledged but ignored
+	if matched, err := cb.Matches(payload); !matched {
+		if s.logger != nil {
+			s.logger.Debug("Callback filtered out",
+				"session_id", sessionID,
+				"event", payload.Event,
+				"error", err)
+		}
+		writeJSONOK(w, map[string]string{"status": "filtered"})
+		return
+	}
+
+	// 11. Check periodic config exists and is enabled
 	periodicStore := store.Periodic(sessionID)
 	periodic, err := periodicStore.Get()
 	if err != nil {
@@ -207,20 +289,29 @@ func (s *Server) handleCallbackTrigger(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 9. Trigger the periodic prompt via the runner
+	// 12. Trigger the periodic prompt via the runner, rendering the payload into it
 	if s.periodicRunner == nil {
 		writeErrorJSON(w, http.StatusInternalServerError, "internal", "Periodic runner not available")
 		return
 	}
 
-	if err := s.periodicRunner.TriggerNow(sessionID, true); err != nil {
-		switch err {
-		case ErrSessionBusy:
+	var transform PromptTransformFunc
+	if cb.Template != "" {
+		transform = func(prompt string) (string, error) {
+			return cb.RenderPrompt(prompt, payload)
+		}
+	}
+	if err := s.periodicRunner.TriggerNowWithPrompt(sessionID, true, transform); err != nil {
+		auditRequest(r, audit.ActionCallbackTrigger, sessionID, audit.OutcomeFailure, err.Error())
+		switch {
+		case errors.Is(err, ErrSessionBusy):
 			writeErrorJSON(w, http.StatusConflict, "session_busy", "Session is currently processing")
-		case ErrPeriodicNotEnabled:
+		case errors.Is(err, ErrPeriodicNotEnabled):
 			writeErrorJSON(w, http.StatusGone, "periodic_disabled", "Periodic is not enabled")
-		case session.ErrPeriodicNotFound:
+		case errors.Is(err, session.ErrPeriodicNotFound):
 			writeErrorJSON(w, http.StatusGone, "periodic_disabled", "No periodic prompt configured")
+		case errors.Is(err, session.ErrCallbackTemplate):
+			writeErrorJSON(w, http.StatusUnprocessableEntity, "template_error", err.Error())
 		default:
 			if s.logger != nil {
 				s.logger.Error("Failed to trigger callback", "error", err, "session_id", sessionID)
@@ -230,7 +321,7 @@ func (s *Server) handleCallbackTrigger(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 10. Log successful trigger
+	// 13. Log successful trigger
 	if s.logger != nil {
 		tokenPrefix := token
 		if len(tokenPrefix) > 10 {
@@ -240,18 +331,29 @@ func (s *Server) handleCallbackTrigger(w http.ResponseWriter, r *http.Request) {
 			"token_prefix", tokenPrefix,
 			"session_id", sessionID,
 			"client_ip", r.RemoteAddr,
+			"event", payload.Event,
 			"metadata", req.Metadata)
 	}
 
-	// 11. Return success
+	audit.Record(audit.Event{
+		Actor:   requestActor(r),
+		Action:  audit.ActionCallbackTrigger,
+		Target:  sessionID,
+		Outcome: audit.OutcomeSuccess,
+		Details: map[string]string{"event": payload.Event},
+	})
+
+	// 14. Return success
 	writeJSONOK(w, map[string]string{"status": "triggered"})
 }
 
 // handleSessionCallback handles callback token management operations:
-// GET    /api/sessions/{id}/callback - Get callback status
-// POST   /api/sessions/{id}/callback - Generate/rotate token
-// DELETE /api/sessions/{id}/callback - Revoke callback
-func (s *Server) handleSessionCallback(w http.ResponseWriter, r *http.Request, sessionID string) {
+// GET    /api/sessions/{id}/callback      - Get callback status
+// POST   /api/sessions/{id}/callback      - Generate/rotate token
+// PATCH  /api/sessions/{id}/callback      - Set the template, secret and filter
+// DELETE /api/sessions/{id}/callback      - Revoke callback
+// POST   /api/sessions/{id}/callback/test - Test a payload against the callback
+func (s *Server) handleSessionCallback(w http.ResponseWriter, r *http.Request, sessionID, subPath string) {
 	store := s.Store()
 	if store == nil {
 		http.Error(w, "Session store not available", http.StatusInternalServerError)
@@ -270,11 +372,26 @@ func (s *Server) handleSessionCallback(w http.ResponseWriter, r *http.Request, s
 
 	cs := store.Callback(sessionID)
 
+	if subPath == "test" {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w)
+			return
+		}
+		s.handleTestCallback(w, r, cs, sessionID)
+		return
+	}
+	if subPath != "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		s.handleGetCallback(w, cs)
 	case http.MethodPost:
 		s.handleGenerateCallback(w, cs, sessionID)
+	case http.MethodPatch:
+		s.handleConfigureCallback(w, r, cs, sessionID)
 	case http.MethodDelete:
 		s.handleRevokeCallback(w, cs, sessionID)
 	default:
@@ -297,10 +414,102 @@ func (s *Server) handleGetCallback(w http.ResponseWriter, cs *session.CallbackSt
 		return
 	}
 
-	writeJSONOK(w, map[string]interface{}{
-		"callback_url": s.buildCallbackURL(cb.Token),
-		"created_at":   cb.CreatedAt,
-	})
+	writeJSONOK(w, s.callbackStatus(cb))
+}
+
+// callbackStatus returns the public view of a callback config (the secret is never returned).
+func (s *Server) callbackStatus(cb *session.CallbackConfig) map[string]interface{} {
+	return map[string]interface{}{
+		"callback_url":      s.buildCallbackURL(cb.Token),
+		"created_at":        cb.CreatedAt,
+		"template":          cb.Template,
+		"filter":            cb.Filter,
+		"secret_configured": cb.Secret != "",
+	}
+}
+
+// handleConfigureCallback handles PATCH /api/sessions/{id}/callback
+func (s *Server) handleConfigureCallback(w http.ResponseWriter, r *http.Request, cs *session.CallbackStore, sessionID string) {
+	var opts session.CallbackOptions
+	if !parseJSONBody(w, r, &opts) {
+		return
+	}
+
+	cb, err := cs.Configure(opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, session.ErrCallbackNotFound):
+			http.Error(w, "No callback configured", http.StatusNotFound)
+		case errors.Is(err, session.ErrInvalidCallback):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			if s.logger != nil {
+				s.logger.Error("Failed to configure callback", "error", err, "session_id", sessionID)
+			}
+			http.Error(w, "Failed to configure callback", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSONOK(w, s.callbackStatus(cb))
+}
+
+// handleTestCallback handles POST /api/sessions/{id}/callback/test.
+// It applies the filter and the template to a sample payload without
+// triggering the prompt (signatures are not checked).
+func (s *Server) handleTestCallback(w http.ResponseWriter, r *http.Request, cs *session.CallbackStore, sessionID string) {
+	var req CallbackTestRequest
+	if !parseJSONBody(w, r, &req) {
+		return
+	}
+
+	cb, err := cs.Get()
+	if err != nil {
+		if err == session.ErrCallbackNotFound {
+			http.Error(w, "No callback configured", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get callback", http.StatusInternalServerError)
+		return
+	}
+	if req.Template != nil {
+		cb.Template = *req.Template
+	}
+	if req.Filter != nil {
+		cb.Filter = *req.Filter
+	}
+	if err := cb.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payload := session.CallbackPayload{Event: req.Event, Body: req.Payload}
+	matched, err := cb.Matches(payload)
+	if !matched {
+		resp := CallbackTestResponse{}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		writeJSONOK(w, resp)
+		return
+	}
+
+	// Render with the periodic prompt of the session, if any
+	var prompt string
+	if periodic, err := s.Store().Periodic(sessionID).Get(); err == nil {
+		prompt = periodic.Prompt
+		if s.periodicRunner != nil {
+			if resolved, err := s.periodicRunner.ResolvePrompt(sessionID, periodic); err == nil {
+				prompt = resolved
+			}
+		}
+	}
+	rendered, err := cb.RenderPrompt(prompt, payload)
+	if err != nil {
+		writeJSONOK(w, CallbackTestResponse{Triggered: true, Error: err.Error()})
+		return
+	}
+	writeJSONOK(w, CallbackTestResponse{Triggered: true, Prompt: rendered})
 }
 
 // handleGenerateCallback handles POST /api/sessions/{id}/callback
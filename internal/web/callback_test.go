@@ -1,12 +1,16 @@
 package web
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
+
+	"github.com/inercia/mitto/internal/session"
 )
 
 // TestCallbackIndex_RegisterAndLookup verifies basic registration and lookup.
@@ -251,3 +255,137 @@ func TestHandleCallbackTrigger_InvalidToken(t *testing.T) {
 		t.Errorf("Expected error code 'invalid_token', got %q", resp["error"])
 	}
 }
+
+// newCallbackTestServer creates a server with a session that has a callback
+// configured with the given options.
+func newCallbackTestServer(t *testing.T, opts session.CallbackOptions) (*Server, string, string) {
+	t.Helper()
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	const sid = "20260101-120000-abcd1234"
+	if err := store.Create(session.Metadata{SessionID: sid, ACPServer: "test", WorkingDir: "/tmp"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	token, err := store.Callback(sid).GenerateToken()
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := store.Callback(sid).Configure(opts); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if err := store.Periodic(sid).Set(&session.PeriodicPrompt{
+		Prompt:    "Check the repository.",
+		Frequency: session.Frequency{Value: 1, Unit: session.FrequencyHours},
+		Enabled:   true,
+	}); err != nil {
+		t.Fatalf("Set periodic: %v", err)
+	}
+
+	s := &Server{
+		store:               store,
+		callbackIndex:       NewCallbackIndex(),
+		callbackRateLimiter: NewCallbackRateLimiter(),
+	}
+	s.callbackIndex.Register(token, sid)
+	return s, sid, token
+}
+
+// TestHandleCallbackTrigger_Payload verifies the signature and filter checks on webhook payloads.
+func TestHandleCallbackTrigger_Payload(t *testing.T) {
+	secret := "s3cret"
+	filter := `$.ref == "refs/heads/main"`
+	template := "{{.prompt}} Push to {{.payload.ref}}"
+	s, _, token := newCallbackTestServer(t, session.CallbackOptions{Template: &template, Secret: &secret, Filter: &filter})
+
+	post := func(body string, headers map[string]string) *httptest.ResponseRecorder {
+		s.callbackRateLimiter.Remove(token) // Not testing rate limits here
+		req := httptest.NewRequest(http.MethodPost, "/api/callback/"+token, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		s.handleCallbackTrigger(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{"missing secret", `{"ref": "refs/heads/main"}`, nil, http.StatusUnauthorized, "invalid_signature"},
+		{"wrong secret", `{"ref": "refs/heads/main"}`, map[string]string{headerGitLabToken: "guess"}, http.StatusUnauthorized, "invalid_signature"},
+		{"not json", `ref=main`, map[string]string{headerGitLabToken: secret}, http.StatusBadRequest, "invalid_payload"},
+		{"filtered", `{"ref": "refs/heads/dev"}`, map[string]string{headerGitLabToken: secret}, http.StatusOK, "filtered"},
+		{"missing key filtered", `{"zen": "hi"}`, map[string]string{headerGitLabToken: secret}, http.StatusOK, "filtered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(tt.body, tt.headers)
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("status = %d, body = %s, want %d with %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+// TestHandleSessionCallback_ConfigureAndTest verifies PATCH and the test endpoint.
+func TestHandleSessionCallback_ConfigureAndTest(t *testing.T) {
+	s, sid, _ := newCallbackTestServer(t, session.CallbackOptions{})
+
+	do := func(method, subPath string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, "/api/sessions/"+sid+"/callback", bytes.NewReader(data))
+		rec := httptest.NewRecorder()
+		s.handleSessionCallback(rec, req, sid, subPath)
+		return rec
+	}
+
+	rec := do(http.MethodPatch, "", map[string]string{
+		"template": "{{.prompt}} ({{.event}} on {{.payload.repository.full_name}})",
+		"secret":   "s3cret",
+		"filter":   "$.repository.full_name",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var status map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if status["secret_configured"] != true || status["secret"] != nil || status["filter"] != "$.repository.full_name" {
+		t.Errorf("PATCH response = %v", status)
+	}
+
+	if rec := do(http.MethodPatch, "", map[string]string{"template": "{{.payload"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("PATCH with invalid template status = %d, want 400", rec.Code)
+	}
+
+	rec = do(http.MethodPost, "test", map[string]any{
+		"event":   "push",
+		"payload": map[string]any{"repository": map[string]any{"full_name": "inercia/mitto"}},
+	})
+	var resp CallbackTestResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v (%s)", err, rec.Body.String())
+	}
+	want := "Check the repository. (push on inercia/mitto)"
+	if !resp.Triggered || resp.Prompt != want || resp.Error != "" {
+		t.Errorf("test response = %+v, want prompt %q", resp, want)
+	}
+
+	rec = do(http.MethodPost, "test", map[string]any{"payload": map[string]any{"other": 1}})
+	resp = CallbackTestResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Triggered {
+		t.Errorf("test response for a filtered payload = %+v", resp)
+	}
+}
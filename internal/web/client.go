@@ -3,16 +3,23 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/coder/acp-go-sdk"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	mittoAcp "github.com/inercia/mitto/internal/acp"
 	"github.com/inercia/mitto/internal/conversion"
+	"github.com/inercia/mitto/internal/tracing"
 )
 
 // SeqProvider provides sequence numbers for event ordering.
@@ -52,10 +59,29 @@ type WebClient struct {
 	// onActivity is called on every streamed update from the agent (pre-buffering)
 	// to signal liveness for the prompt inactivity watchdog.
 	onActivity func()
+	// onFileChange receives the content of written files before and after the write.
+	onFileChange func(seq int64, toolCallID string, snap *mittoAcp.FileSnapshot)
+
+	// terminals handles ACP terminal/* requests. Falls back to webTerminalStub when nil.
+	terminals mittoAcp.TerminalHandler
 
 	// Stream buffer for all streaming events (markdown, thoughts, tool calls, etc.)
 	// This ensures correct ordering even when markdown content is buffered.
 	streamBuffer *StreamBuffer
+
+	// toolCalls holds the tool calls in progress (started or asking for
+	// permission, until they complete), with the paths they reported, to
+	// attribute file writes to them (see toolCallForWrite).
+	toolCallsMu sync.Mutex
+	toolCalls   map[string][]string
+
+	// traceContext returns the trace context of the prompt in progress, parent
+	// of the tool call spans (nil when tracing is not wired).
+	traceContext func() context.Context
+	// toolCallSpans holds the span of each tool call in progress, ended by the
+	// tool_call_update that completes it.
+	toolCallSpansMu sync.Mutex
+	toolCallSpans   map[acp.ToolCallId]trace.Span
 }
 
 // Ensure WebClient implements acp.Client
@@ -151,9 +177,19 @@ type WebClientConfig struct {
 	// any buffering. It signals that the agent is still alive and producing output,
 	// used by the prompt inactivity watchdog to detect a live-but-unresponsive agent.
 	OnActivity func()
+	// OnFileChange is called before OnFileWrite with the content of the file before
+	// and after the write, and the ID of the tool call that made it ("" if unknown).
+	// snap is nil when the file is too large or its previous content could not be read.
+	OnFileChange func(seq int64, toolCallID string, snap *mittoAcp.FileSnapshot)
+	// Terminals handles ACP terminal/* requests (create, output, wait, kill, release).
+	// If nil, terminal requests are answered by a stub that runs nothing.
+	Terminals mittoAcp.TerminalHandler
 	// FileLinksConfig configures file path detection and linking in agent messages.
 	// If nil, file linking is disabled.
 	FileLinksConfig *conversion.FileLinkerConfig
+	// TraceContext returns the trace context of the prompt in progress, used as
+	// the parent of the tool call and markdown flush spans. Optional.
+	TraceContext func() context.Context
 }
 
 // NewWebClient creates a new web-based ACP client.
@@ -164,12 +200,19 @@ func NewWebClient(config WebClientConfig) *WebClient {
 		logger:               config.Logger,
 		onFileWrite:          config.OnFileWrite,
 		onFileRead:           config.OnFileRead,
+		onFileChange:         config.OnFileChange,
 		onPermission:         config.OnPermission,
 		onAvailableCommands:  config.OnAvailableCommands,
 		onCurrentModeChanged: config.OnCurrentModeChanged,
 		onMittoToolCall:      config.OnMittoToolCall,
 		onContextUsageUpdate: config.OnContextUsageUpdate,
 		onActivity:           config.OnActivity,
+		terminals:            config.Terminals,
+		traceContext:         config.TraceContext,
+		toolCallSpans:        make(map[acp.ToolCallId]trace.Span),
+	}
+	if c.terminals == nil {
+		c.terminals = webTerminalStub
 	}
 
 	// Create stream buffer that handles all streaming events.
@@ -189,6 +232,7 @@ func NewWebClient(config WebClientConfig) *WebClient {
 		},
 		FileLinksConfig: config.FileLinksConfig,
 		SeqProvider:     config.SeqProvider,
+		TraceContext:    config.TraceContext,
 	})
 
 	return c
@@ -305,6 +349,10 @@ func (c *WebClient) SessionUpdate(ctx context.Context, params acp.SessionNotific
 			}
 		}
 
+		c.trackToolCall(string(u.ToolCall.ToolCallId), u.ToolCall.Locations)
+		metricToolCalls.Inc(toolKindLabel(u.ToolCall.Kind))
+		c.startToolCallSpan(u.ToolCall)
+
 		// Seq is assigned at emit time by StreamBuffer.
 		// Tool calls are buffered if we're in a markdown block, otherwise emitted immediately.
 		status := string(u.ToolCall.Status)
@@ -316,6 +364,14 @@ func (c *WebClient) SessionUpdate(ctx context.Context, params acp.SessionNotific
 		if u.ToolCallUpdate.Status != nil {
 			s := string(*u.ToolCallUpdate.Status)
 			status = &s
+			if *u.ToolCallUpdate.Status == acp.ToolCallStatusCompleted || *u.ToolCallUpdate.Status == acp.ToolCallStatusFailed {
+				c.finishToolCall(string(u.ToolCallUpdate.ToolCallId))
+				c.endToolCallSpan(u.ToolCallUpdate.ToolCallId, *u.ToolCallUpdate.Status)
+			} else if len(u.ToolCallUpdate.Locations) > 0 {
+				c.trackToolCall(string(u.ToolCallUpdate.ToolCallId), u.ToolCallUpdate.Locations)
+			}
+		} else if len(u.ToolCallUpdate.Locations) > 0 {
+			c.trackToolCall(string(u.ToolCallUpdate.ToolCallId), u.ToolCallUpdate.Locations)
 		}
 		c.streamBuffer.AddToolUpdate(string(u.ToolCallUpdate.ToolCallId), status)
 
@@ -366,6 +422,111 @@ func (c *WebClient) SessionUpdate(ctx context.Context, params acp.SessionNotific
 	return nil
 }
 
+// trackToolCall records a tool call in progress and the paths it reported.
+func (c *WebClient) trackToolCall(id string, locations []acp.ToolCallLocation) {
+	c.toolCallsMu.Lock()
+	defer c.toolCallsMu.Unlock()
+	if c.toolCalls == nil {
+		c.toolCalls = make(map[string][]string)
+	}
+	paths := c.toolCalls[id]
+	for _, l := range locations {
+		if l.Path != "" {
+			paths = append(paths, filepath.Clean(l.Path))
+		}
+	}
+	c.toolCalls[id] = paths
+}
+
+// finishToolCall stops attributing file writes to a tool call.
+func (c *WebClient) finishToolCall(id string) {
+	c.toolCallsMu.Lock()
+	defer c.toolCallsMu.Unlock()
+	delete(c.toolCalls, id)
+}
+
+// toolCallForWrite returns the tool call a write of path is attributed to:
+// the only tool call in progress that reported the path, or else the only
+// tool call in progress. With parallel tool calls that can't be told apart,
+// the write is not attributed ("").
+func (c *WebClient) toolCallForWrite(path string) string {
+	c.toolCallsMu.Lock()
+	defer c.toolCallsMu.Unlock()
+
+	path = filepath.Clean(path)
+	var byPath []string
+	var only string
+	for id, paths := range c.toolCalls {
+		only = id
+		if slices.Contains(paths, path) {
+			byPath = append(byPath, id)
+		}
+	}
+	switch {
+	case len(byPath) == 1:
+		return byPath[0]
+	case len(byPath) == 0 && len(c.toolCalls) == 1:
+		return only
+	default:
+		return ""
+	}
+}
+
+// startToolCallSpan starts the span of a tool call, child of the prompt span.
+func (c *WebClient) startToolCallSpan(tc *acp.SessionUpdateToolCall) {
+	ctx := context.Background()
+	if c.traceContext != nil {
+		ctx = c.traceContext()
+	}
+	_, span := tracing.Start(ctx, "tool_call",
+		attribute.String("mitto.tool_call_id", string(tc.ToolCallId)),
+		attribute.String("mitto.tool_kind", string(tc.Kind)),
+		attribute.String("mitto.tool_title", tc.Title))
+
+	c.toolCallSpansMu.Lock()
+	defer c.toolCallSpansMu.Unlock()
+	if previous, ok := c.toolCallSpans[tc.ToolCallId]; ok {
+		previous.End()
+	}
+	c.toolCallSpans[tc.ToolCallId] = span
+}
+
+// endToolCallSpan ends the span of a tool call with its final status.
+func (c *WebClient) endToolCallSpan(id acp.ToolCallId, status acp.ToolCallStatus) {
+	c.toolCallSpansMu.Lock()
+	span, ok := c.toolCallSpans[id]
+	delete(c.toolCallSpans, id)
+	c.toolCallSpansMu.Unlock()
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(attribute.String("mitto.tool_status", string(status)))
+	var err error
+	if status == acp.ToolCallStatusFailed {
+		err = errors.New("tool call failed")
+	}
+	tracing.End(span, err)
+}
+
+// EndToolCallSpans ends the spans of the tool calls the agent never completed,
+// and stops attributing file writes to them. Called when the prompt ends.
+func (c *WebClient) EndToolCallSpans() {
+	c.toolCallsMu.Lock()
+	c.toolCalls = nil
+	c.toolCallsMu.Unlock()
+
+	c.toolCallSpansMu.Lock()
+	spans := c.toolCallSpans
+	c.toolCallSpans = make(map[acp.ToolCallId]trace.Span)
+	c.toolCallSpansMu.Unlock()
+
+	for _, span := range spans {
+		span.SetAttributes(attribute.Bool("mitto.tool_incomplete", true))
+		span.End()
+	}
+}
+
 // getNextSeq returns the next sequence number from the provider.
 // Returns 0 if no provider is configured (for backward compatibility in tests).
 func (c *WebClient) getNextSeq() int64 {
@@ -381,6 +542,9 @@ func (c *WebClient) RequestPermission(ctx context.Context, params acp.RequestPer
 	// Permission dialogs are blocking, so we need to show all content first.
 	c.streamBuffer.Flush()
 
+	// Writes following a permission request can be made by the tool call asking for it.
+	c.trackToolCall(string(params.ToolCall.ToolCallId), params.ToolCall.Locations)
+
 	if c.autoApprove {
 		return c.autoApprovePermission(params)
 	}
@@ -402,13 +566,23 @@ func (c *WebClient) autoApprovePermission(params acp.RequestPermissionRequest) (
 }
 
 // WriteTextFile handles file write requests from the agent.
+// When OnFileChange is set, the file content is snapshotted before and after the write.
 func (c *WebClient) WriteTextFile(ctx context.Context, params acp.WriteTextFileRequest) (acp.WriteTextFileResponse, error) {
-	if err := mittoAcp.DefaultFileSystem.WriteTextFile(params.Path, params.Content); err != nil {
+	var snap *mittoAcp.FileSnapshot
+	if c.onFileChange != nil {
+		var err error
+		if snap, err = mittoAcp.WriteTextFileWithSnapshot(mittoAcp.DefaultFileSystem, params.Path, params.Content); err != nil {
+			return acp.WriteTextFileResponse{}, err
+		}
+	} else if err := mittoAcp.DefaultFileSystem.WriteTextFile(params.Path, params.Content); err != nil {
 		return acp.WriteTextFileResponse{}, err
 	}
 	// Assign seq AFTER success to avoid consuming a seq number on error,
 	// which would create a gap in the sequence (e.g., seq jumps from 992 to 994).
 	seq := c.getNextSeq()
+	if c.onFileChange != nil {
+		c.onFileChange(seq, c.toolCallForWrite(params.Path), snap)
+	}
 	if c.onFileWrite != nil {
 		c.onFileWrite(seq, params.Path, len(params.Content))
 	}
@@ -431,31 +605,32 @@ func (c *WebClient) ReadTextFile(ctx context.Context, params acp.ReadTextFileReq
 }
 
 // webTerminalStub is the shared stub handler for terminal operations.
+// It is used when no real terminal handler is configured.
 var webTerminalStub = &mittoAcp.StubTerminalHandler{}
 
 // CreateTerminal handles terminal creation requests.
 func (c *WebClient) CreateTerminal(ctx context.Context, params acp.CreateTerminalRequest) (acp.CreateTerminalResponse, error) {
-	return webTerminalStub.CreateTerminal(ctx, params)
+	return c.terminals.CreateTerminal(ctx, params)
 }
 
 // TerminalOutput handles requests to get terminal output.
 func (c *WebClient) TerminalOutput(ctx context.Context, params acp.TerminalOutputRequest) (acp.TerminalOutputResponse, error) {
-	return webTerminalStub.TerminalOutput(ctx, params)
+	return c.terminals.TerminalOutput(ctx, params)
 }
 
 // ReleaseTerminal handles terminal release requests.
 func (c *WebClient) ReleaseTerminal(ctx context.Context, params acp.ReleaseTerminalRequest) (acp.ReleaseTerminalResponse, error) {
-	return webTerminalStub.ReleaseTerminal(ctx, params)
+	return c.terminals.ReleaseTerminal(ctx, params)
 }
 
 // WaitForTerminalExit handles requests to wait for terminal exit.
 func (c *WebClient) WaitForTerminalExit(ctx context.Context, params acp.WaitForTerminalExitRequest) (acp.WaitForTerminalExitResponse, error) {
-	return webTerminalStub.WaitForTerminalExit(ctx, params)
+	return c.terminals.WaitForTerminalExit(ctx, params)
 }
 
 // KillTerminal handles requests to kill terminals.
 func (c *WebClient) KillTerminal(ctx context.Context, params acp.KillTerminalRequest) (acp.KillTerminalResponse, error) {
-	return webTerminalStub.KillTerminal(ctx, params)
+	return c.terminals.KillTerminal(ctx, params)
 }
 
 // FlushMarkdown forces a flush of any buffered content (markdown and pending events).
@@ -466,6 +641,7 @@ func (c *WebClient) FlushMarkdown() {
 // Close cleans up resources.
 func (c *WebClient) Close() {
 	c.streamBuffer.Close()
+	c.EndToolCallSpans()
 }
 
 // extractMittoSelfID extracts the self_id from a mitto_* tool call's RawInput.
@@ -10,6 +10,13 @@ import (
 	"time"
 
 	"github.com/coder/acp-go-sdk"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	mittoAcp "github.com/inercia/mitto/internal/acp"
+	"github.com/inercia/mitto/internal/tracing"
 )
 
 func TestNewWebClient(t *testing.T) {
@@ -174,6 +181,58 @@ func TestWebClient_SessionUpdate_ToolUpdate(t *testing.T) {
 	}
 }
 
+func TestWebClient_ToolCallSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	promptCtx, promptSpan := tracing.Start(context.Background(), "mitto.prompt")
+	client := NewWebClient(WebClientConfig{
+		TraceContext: func() context.Context { return promptCtx },
+	})
+	defer client.Close()
+
+	update := func(u acp.SessionUpdate) {
+		t.Helper()
+		if err := client.SessionUpdate(context.Background(), acp.SessionNotification{Update: u}); err != nil {
+			t.Fatalf("SessionUpdate failed: %v", err)
+		}
+	}
+	completed, failed := acp.ToolCallStatusCompleted, acp.ToolCallStatusFailed
+	update(acp.SessionUpdate{ToolCall: &acp.SessionUpdateToolCall{ToolCallId: "ok", Title: "Read", Kind: acp.ToolKindRead}})
+	update(acp.SessionUpdate{ToolCall: &acp.SessionUpdateToolCall{ToolCallId: "bad", Title: "Run", Kind: acp.ToolKindExecute}})
+	update(acp.SessionUpdate{ToolCall: &acp.SessionUpdateToolCall{ToolCallId: "lost", Title: "Fetch", Kind: acp.ToolKindFetch}})
+	update(acp.SessionUpdate{ToolCallUpdate: &acp.SessionToolCallUpdate{ToolCallId: "ok", Status: &completed}})
+	update(acp.SessionUpdate{ToolCallUpdate: &acp.SessionToolCallUpdate{ToolCallId: "bad", Status: &failed}})
+	client.EndToolCallSpans()
+	promptSpan.End()
+
+	statuses := make(map[string]codes.Code)
+	for _, span := range recorder.Ended() {
+		if span.Name() != "tool_call" {
+			continue
+		}
+		if span.Parent().SpanID() != promptSpan.SpanContext().SpanID() {
+			t.Errorf("tool_call span is not a child of the prompt span")
+		}
+		for _, attr := range span.Attributes() {
+			if attr.Key == "mitto.tool_call_id" {
+				statuses[attr.Value.AsString()] = span.Status().Code
+			}
+		}
+	}
+	want := map[string]codes.Code{"ok": codes.Unset, "bad": codes.Error, "lost": codes.Unset}
+	if len(statuses) != len(want) {
+		t.Fatalf("tool_call spans = %v, want %v", statuses, want)
+	}
+	for id, code := range want {
+		if got, ok := statuses[id]; !ok || got != code {
+			t.Errorf("tool_call %q status = %v, want %v", id, got, code)
+		}
+	}
+}
+
 func TestWebClient_SessionUpdate_Plan(t *testing.T) {
 	planCalled := false
 	var receivedEntries []PlanEntry
@@ -370,6 +429,78 @@ func TestWebClient_WriteTextFile_CreateDir(t *testing.T) {
 	}
 }
 
+func TestWebClient_WriteTextFile_FileChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "test.txt")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var toolCallIDs []string
+	var snaps []*mittoAcp.FileSnapshot
+	client := NewWebClient(WebClientConfig{
+		OnFileChange: func(seq int64, toolCallID string, snap *mittoAcp.FileSnapshot) {
+			toolCallIDs = append(toolCallIDs, toolCallID)
+			snaps = append(snaps, snap)
+		},
+	})
+	defer client.Close()
+
+	write := func(content string) {
+		t.Helper()
+		if _, err := client.WriteTextFile(context.Background(), acp.WriteTextFileRequest{Path: path, Content: content}); err != nil {
+			t.Fatalf("WriteTextFile failed: %v", err)
+		}
+	}
+	update := func(u acp.SessionUpdate) {
+		t.Helper()
+		if err := client.SessionUpdate(context.Background(), acp.SessionNotification{Update: u}); err != nil {
+			t.Fatalf("SessionUpdate failed: %v", err)
+		}
+	}
+
+	// Writes are attributed to the running tool call, until it completes
+	update(acp.SessionUpdate{ToolCall: &acp.SessionUpdateToolCall{ToolCallId: "tool-1", Title: "Edit", Status: acp.ToolCallStatusInProgress}})
+	write("new")
+	completed := acp.ToolCallStatusCompleted
+	update(acp.SessionUpdate{ToolCallUpdate: &acp.SessionToolCallUpdate{ToolCallId: "tool-1", Status: &completed}})
+	write("newer")
+
+	if len(snaps) != 2 || toolCallIDs[0] != "tool-1" || toolCallIDs[1] != "" {
+		t.Fatalf("tool call IDs = %q, want the first write attributed to tool-1", toolCallIDs)
+	}
+	if snaps[0] == nil || !snaps[0].Existed || snaps[0].Before != "old" || snaps[0].After != "new" {
+		t.Errorf("snapshot = %+v", snaps[0])
+	}
+
+	// A permission request attributes writes to the tool call asking for it
+	_, _ = client.RequestPermission(context.Background(), acp.RequestPermissionRequest{
+		ToolCall: acp.ToolCallUpdate{ToolCallId: "tool-2"},
+	})
+	write("newest")
+	if toolCallIDs[2] != "tool-2" {
+		t.Errorf("tool call ID = %q, want tool-2", toolCallIDs[2])
+	}
+
+	// With parallel tool calls, writes are attributed by the reported paths, or not at all
+	update(acp.SessionUpdate{ToolCall: &acp.SessionUpdateToolCall{ToolCallId: "tool-3", Title: "Edit", Status: acp.ToolCallStatusInProgress,
+		Locations: []acp.ToolCallLocation{{Path: filepath.Join(tmpDir, "other.txt")}}}})
+	write("parallel")
+	update(acp.SessionUpdate{ToolCallUpdate: &acp.SessionToolCallUpdate{ToolCallId: "tool-2",
+		Locations: []acp.ToolCallLocation{{Path: path}}}})
+	write("by path")
+	if toolCallIDs[3] != "" || toolCallIDs[4] != "tool-2" {
+		t.Errorf("tool call IDs = %q, want the parallel write unattributed and the next one attributed to tool-2", toolCallIDs[3:])
+	}
+
+	// Tool calls the agent never completed are forgotten when the prompt ends
+	client.EndToolCallSpans()
+	write("after prompt")
+	if toolCallIDs[5] != "" {
+		t.Errorf("tool call ID = %q, want none after the prompt", toolCallIDs[5])
+	}
+}
+
 func TestWebClient_ReadTextFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	path := filepath.Join(tmpDir, "test.txt")
@@ -551,6 +682,21 @@ func TestWebClient_TerminalMethods(t *testing.T) {
 	}
 }
 
+func TestWebClient_TerminalMethodsUseConfiguredHandler(t *testing.T) {
+	client := NewWebClient(WebClientConfig{
+		Terminals: &mittoAcp.StubTerminalHandler{TerminalID: "custom-term"},
+	})
+	defer client.Close()
+
+	createResp, err := client.CreateTerminal(context.Background(), acp.CreateTerminalRequest{Command: "true"})
+	if err != nil {
+		t.Fatalf("CreateTerminal failed: %v", err)
+	}
+	if createResp.TerminalId != "custom-term" {
+		t.Errorf("TerminalId = %q, want %q", createResp.TerminalId, "custom-term")
+	}
+}
+
 // TestWebClient_ToolCallFlushesBufferedMessage verifies that when a tool call arrives,
 // any buffered agent message is flushed first. This ensures correct event ordering:
 // the agent's explanation appears before the tool call in the event stream.
@@ -15,6 +15,7 @@ import (
 
 	"github.com/inercia/mitto/internal/agents"
 	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/audit"
 	configPkg "github.com/inercia/mitto/internal/config"
 	"github.com/inercia/mitto/internal/mcpserver"
 	"github.com/inercia/mitto/internal/runner"
@@ -111,6 +112,18 @@ func sanitizeWebConfig(cfg configPkg.WebConfig) configPkg.WebConfig {
 			simpleCopy.Password = "" // Never return the password to the client
 			authCopy.Simple = &simpleCopy
 		}
+		if cfg.Auth.OIDC != nil {
+			oidcCopy := *cfg.Auth.OIDC
+			oidcCopy.ClientSecret = "" // Nor the OIDC client secret
+			authCopy.OIDC = &oidcCopy
+		}
+		if len(cfg.Auth.Users) > 0 {
+			authCopy.Users = make([]configPkg.WebUser, len(cfg.Auth.Users))
+			for i, user := range cfg.Auth.Users {
+				user.PasswordHash = "" // Nor the password hashes of the users
+				authCopy.Users[i] = user
+			}
+		}
 		sanitized.Auth = &authCopy
 	}
 	return sanitized
@@ -137,15 +150,22 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 //     filterPromptsByEnabled (enabledWhen CEL expressions)
 //     with the context of the given session.
 func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	user := webUserFromContext(r.Context())
+
 	// Build complete config response including workspaces and ACP servers
 	response := map[string]interface{}{
-		"workspaces":      s.sessionManager.GetWorkspaces(),
+		"workspaces":      filterWorkspacesForUser(user, s.sessionManager.GetWorkspaces()),
 		"acp_servers":     []map[string]string{},
 		"web":             configPkg.WebConfig{},
 		"config_readonly": s.config.ConfigReadOnly,
 		"api_prefix":      s.apiPrefix, // Include API prefix for frontend to use
 	}
 
+	// The user account of the request, so that the UI can adapt to its role
+	if user != nil {
+		response["user"] = map[string]string{"username": user.Username, "role": user.Role}
+	}
+
 	// Include RC file path if config is from an RC file
 	if s.config.RCFilePath != "" {
 		response["rc_file_path"] = s.config.RCFilePath
@@ -155,7 +175,11 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 		// SECURITY: Sanitize web config to remove sensitive fields (auth password) before
 		// sending to the client.  Even authenticated users must not receive the password
 		// because it could be exfiltrated through XSS, dev-tools inspection, or screen-sharing.
-		response["web"] = sanitizeWebConfig(s.config.MittoConfig.Web)
+		webConfig := sanitizeWebConfig(s.config.MittoConfig.Web)
+		if !user.HasRole(configPkg.RoleAdmin) {
+			webConfig.Auth = nil
+		}
+		response["web"] = webConfig
 		// Indicate to the frontend whether a password already exists (in keychain or settings).
 		// The frontend uses this to distinguish "user left the field empty intentionally"
 		// from "field is empty because there was never a password" — without exposing the password itself.
@@ -241,10 +265,14 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	writeJSONWithETag(w, r, response)
 }
 
+// auditConfigTarget is the target of the configuration changes in the audit log.
+const auditConfigTarget = "settings"
+
 // handleSaveConfig handles POST /api/config.
 func (s *Server) handleSaveConfig(w http.ResponseWriter, r *http.Request) {
 	// Reject saves when config is read-only (loaded from --config file)
 	if s.config.ConfigReadOnly {
+		auditRequest(r, audit.ActionConfigUpdate, auditConfigTarget, audit.OutcomeDenied, "read_only")
 		http.Error(w, "Configuration is read-only (loaded from config file)", http.StatusForbidden)
 		return
 	}
@@ -272,12 +300,14 @@ func (s *Server) handleSaveConfig(w http.ResponseWriter, r *http.Request) {
 
 	// Validate request structure
 	if validationErr := s.validateConfigRequest(&req); validationErr != nil {
+		auditRequest(r, audit.ActionConfigUpdate, auditConfigTarget, audit.OutcomeFailure, validationErr.Message)
 		s.writeConfigError(w, validationErr)
 		return
 	}
 
 	// Check for workspace conflicts (workspaces being removed that have conversations)
 	if conflictErr := s.checkWorkspaceConflicts(&req); conflictErr != nil {
+		auditRequest(r, audit.ActionConfigUpdate, auditConfigTarget, audit.OutcomeFailure, conflictErr.Message)
 		s.writeConfigError(w, conflictErr)
 		return
 	}
@@ -292,9 +322,20 @@ func (s *Server) handleSaveConfig(w http.ResponseWriter, r *http.Request) {
 	for i, ws := range req.Workspaces {
 		tempWs := configPkg.WorkspaceSettings{RestrictedRunner: ws.RestrictedRunner}
 		if err := tempWs.ValidateRestrictedRunner(); err != nil {
+			message := fmt.Sprintf("workspaces[%d].restricted_runner: %s", i, err.Error())
+			auditRequest(r, audit.ActionConfigUpdate, auditConfigTarget, audit.OutcomeFailure, message)
+			s.writeConfigError(w, &configValidationError{
+				StatusCode: http.StatusBadRequest,
+				Message:    message,
+			})
+			return
+		}
+		if err := ws.ValidateResourceLimits(); err != nil {
+			message := fmt.Sprintf("workspaces[%d].%s", i, err.Error())
+			auditRequest(r, audit.ActionConfigUpdate, auditConfigTarget, audit.OutcomeFailure, message)
 			s.writeConfigError(w, &configValidationError{
 				StatusCode: http.StatusBadRequest,
-				Message:    fmt.Sprintf("workspaces[%d].restricted_runner: %s", i, err.Error()),
+				Message:    message,
 			})
 			return
 		}
@@ -323,6 +364,7 @@ func (s *Server) handleSaveConfig(w http.ResponseWriter, r *http.Request) {
 		if s.logger != nil {
 			s.logger.Error("Failed to build settings", "error", err)
 		}
+		auditRequest(r, audit.ActionConfigUpdate, auditConfigTarget, audit.OutcomeFailure, "build settings: "+err.Error())
 		http.Error(w, "Failed to build settings: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -345,12 +387,14 @@ func (s *Server) handleSaveConfig(w http.ResponseWriter, r *http.Request) {
 		if s.logger != nil {
 			s.logger.Error("Failed to save settings", "error", err)
 		}
+		auditRequest(r, audit.ActionConfigUpdate, auditConfigTarget, audit.OutcomeFailure, "save settings: "+err.Error())
 		http.Error(w, "Failed to save settings: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Apply changes to running server
 	s.applyConfigChanges(&req, settings)
+	auditRequest(r, audit.ActionConfigUpdate, auditConfigTarget, audit.OutcomeSuccess, "")
 
 	// Build response with applied changes info
 	writeJSONOK(w, map[string]interface{}{
@@ -469,7 +513,7 @@ func (s *Server) buildNewSettings(req *ConfigSaveRequest) (*configPkg.Settings,
 			// They are managed via prompt files with acps: field
 		}
 
-		// Preserve Cwd and RestrictedRunners from existing server if present.
+		// Preserve Cwd, RestrictedRunners, Pricing and PermissionRules from existing server if present.
 		// These fields are not exposed in the UI but should not be lost on save.
 		//
 		// Also restore any env var values that were masked ("***") in the GET /api/config
@@ -478,6 +522,8 @@ func (s *Server) buildNewSettings(req *ConfigSaveRequest) (*configPkg.Settings,
 		if existing, ok := existingServers[srv.Name]; ok {
 			newServer.Cwd = existing.Cwd
 			newServer.RestrictedRunners = existing.RestrictedRunners
+			newServer.Pricing = existing.Pricing
+			newServer.PermissionRules = existing.PermissionRules
 			if len(newServer.Env) > 0 && len(existing.Env) > 0 {
 				for k, v := range newServer.Env {
 					if v == "***" {
@@ -510,8 +556,16 @@ func (s *Server) buildNewSettings(req *ConfigSaveRequest) (*configPkg.Settings,
 	hasSimple := req.Web.Auth != nil && req.Web.Auth.Simple != nil
 	hasCloudflare := req.Web.Auth != nil && req.Web.Auth.Cloudflare != nil
 
-	if hasSimple || hasCloudflare {
-		newWebConfig.Auth = &configPkg.WebAuth{}
+	// User accounts and OpenID Connect are not edited in the settings dialog: keep them
+	var users []configPkg.WebUser
+	var oidcAuth *configPkg.OIDCAuth
+	if s.config.MittoConfig != nil && s.config.MittoConfig.Web.Auth != nil {
+		users = s.config.MittoConfig.Web.Auth.Users
+		oidcAuth = s.config.MittoConfig.Web.Auth.OIDC
+	}
+
+	if hasSimple || hasCloudflare || len(users) > 0 || oidcAuth != nil {
+		newWebConfig.Auth = &configPkg.WebAuth{Users: users, OIDC: oidcAuth}
 
 		// Simple auth (username/password)
 		if hasSimple {
@@ -608,6 +662,14 @@ func (s *Server) buildNewSettings(req *ConfigSaveRequest) (*configPkg.Settings,
 		permissionsConfig = s.config.MittoConfig.Permissions
 	}
 
+	// Usage budgets and webhooks are not exposed in the UI: preserve existing
+	var usageConfig *configPkg.UsageConfig
+	var webhooks []configPkg.WebhookConfig
+	if s.config.MittoConfig != nil {
+		usageConfig = s.config.MittoConfig.Usage
+		webhooks = s.config.MittoConfig.Webhooks
+	}
+
 	// Filter out file-sourced and builtin prompts — they should not be persisted to settings.json
 	// since they're already loaded from MITTO_DIR/prompts/ files on startup.
 	var settingsPrompts []configPkg.WebPrompt
@@ -625,6 +687,8 @@ func (s *Server) buildNewSettings(req *ConfigSaveRequest) (*configPkg.Settings,
 		Session:       sessionConfig,
 		Conversations: conversationsConfig,
 		Permissions:   permissionsConfig,
+		Usage:         usageConfig,
+		Webhooks:      webhooks,
 	}, nil
 }
 
@@ -658,6 +722,8 @@ func (s *Server) applyConfigChanges(req *ConfigSaveRequest, settings *configPkg.
 				Username: req.Web.Auth.Simple.Username,
 				Password: password,
 			},
+			OIDC:  settings.Web.Auth.OIDC,
+			Users: settings.Web.Auth.Users,
 		}
 	}
 
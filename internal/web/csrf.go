@@ -227,6 +227,13 @@ func (c *CSRFManager) CSRFMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Skip CSRF check for requests authenticated with API tokens: browsers
+		// don't add Authorization headers to cross-site requests by themselves
+		if strings.HasPrefix(bearerToken(r), apiTokenPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Skip CSRF check for WebSocket upgrade requests
 		if r.Header.Get("Upgrade") == "websocket" {
 			next.ServeHTTP(w, r)
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"sync"
+
+	"github.com/inercia/mitto/internal/config"
 )
 
 // GlobalEventsClient represents a connected client listening for global events.
@@ -14,6 +16,10 @@ type GlobalEventsClient struct {
 	done   chan struct{}
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// User account of the client (nil for full access). Restricted clients
+	// only get the events of the conversations of their workspaces.
+	user *config.WebUser
 }
 
 // GlobalEventsManager manages clients subscribed to global events.
@@ -51,10 +57,20 @@ func (m *GlobalEventsManager) Broadcast(msgType string, data interface{}) {
 	}
 	msgBytes, _ := json.Marshal(msg)
 
+	// The conversation of the event, for restricted clients
+	var target struct {
+		SessionID string `json:"session_id"`
+	}
+	_ = json.Unmarshal(msg.Data, &target)
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	for client := range m.clients {
+		if target.SessionID != "" && !client.user.HasAllWorkspaces() &&
+			!client.server.webUserCanAccessSession(client.user, target.SessionID) {
+			continue
+		}
 		client.wsConn.SendRaw(msgBytes)
 	}
 }
@@ -97,6 +113,7 @@ func (s *Server) handleGlobalEventsWS(w http.ResponseWriter, r *http.Request) {
 		done:   make(chan struct{}),
 		ctx:    ctx,
 		cancel: cancel,
+		user:   webUserFromContext(r.Context()),
 	}
 
 	s.eventsManager.Register(client)
@@ -47,6 +47,17 @@ func (fs *FileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
+	if user := webUserFromContext(r.Context()); !user.HasAllWorkspaces() {
+		uuid := ""
+		if ws := resolveOwningWorkspace(workspacePath, fs.sessionManager.GetWorkspaces()); ws != nil {
+			uuid = ws.UUID
+		}
+		if !user.CanAccessWorkspace(uuid, workspacePath) {
+			fs.logSecurityEvent("workspace_forbidden", workspacePath, relativePath, r)
+			http.Error(w, "Invalid workspace", http.StatusForbidden)
+			return
+		}
+	}
 
 	switch r.Method {
 	case http.MethodGet, http.MethodHead:
@@ -0,0 +1,339 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+// Types of the events of a headless run.
+const (
+	HeadlessEventSessionStarted = "session_started"
+	HeadlessEventAgentMessage   = "agent_message"
+	HeadlessEventAgentThought   = "agent_thought"
+	HeadlessEventToolCall       = "tool_call"
+	HeadlessEventToolUpdate     = "tool_update"
+	HeadlessEventPlan           = "plan"
+	HeadlessEventFileRead       = "file_read"
+	HeadlessEventFileWrite      = "file_write"
+	HeadlessEventPermission     = "permission"
+	HeadlessEventUIPrompt       = "ui_prompt"
+	HeadlessEventNotification   = "notification"
+	HeadlessEventError          = "error"
+	HeadlessEventResult         = "result"
+)
+
+// Status of the result event of a headless run.
+const (
+	HeadlessStatusCompleted = "completed"
+	HeadlessStatusError     = "error"
+	HeadlessStatusTimeout   = "timeout"
+	HeadlessStatusCancelled = "cancelled"
+)
+
+// HeadlessEvent is a progress event of a headless run, written as a JSON line
+// by `mitto run`.
+type HeadlessEvent struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"session_id,omitempty"`
+	Seq       int64     `json:"seq,omitempty"`
+	// ID is the tool call ID, or the request ID of permission and UI prompts.
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	// Status is the tool call status, the permission outcome, or the run result
+	// ("completed", "error", "timeout" or "cancelled").
+	Status string `json:"status,omitempty"`
+	// Text is the agent message (HTML), thought or notification text.
+	Text string      `json:"text,omitempty"`
+	Path string      `json:"path,omitempty"`
+	Size int         `json:"size,omitempty"`
+	Plan []PlanEntry `json:"plan,omitempty"`
+	// Error is the error message of error and result events.
+	Error string `json:"error,omitempty"`
+	// DurationMS is the duration of the run, in result events.
+	DurationMS int64 `json:"duration_ms,omitempty"`
+	// EventCount is the number of recorded events of the session, in result events.
+	EventCount int `json:"event_count,omitempty"`
+}
+
+// HeadlessConfig configures a headless run.
+type HeadlessConfig struct {
+	// MittoConfig is the Mitto configuration (agents, runners, permission rules...).
+	MittoConfig *config.Config
+	// Workspaces are the configured workspaces; Workspace must be one of them.
+	Workspaces []config.WorkspaceSettings
+	// Workspace is the UUID of the workspace the session runs in.
+	Workspace string
+	// SessionName is the name of the session (optional).
+	SessionName string
+	// AutoApprove approves the permission requests no rule decides.
+	AutoApprove bool
+	// Permissions is the permission policy file of the run (optional).
+	Permissions *config.PermissionPolicyFile
+	// OnEvent is called with the progress events of the run.
+	OnEvent func(HeadlessEvent)
+	Logger  *slog.Logger
+}
+
+// HeadlessRun is a recorded session driven without a browser: it is created
+// through the same SessionManager path as the web server's sessions (workspace
+// agent, processors, restricted runner, permission rules), and the permission
+// requests that would show a dialog are denied, as there is nobody to ask.
+type HeadlessRun struct {
+	store      *session.Store
+	sessionMgr *SessionManager
+	processMgr *ACPProcessManager
+	bs         *BackgroundSession
+	observer   *headlessObserver
+}
+
+// NewHeadlessRun opens the session store and creates the session of a headless run.
+// The session is created with ctx (which bounds the agent startup).
+func NewHeadlessRun(ctx context.Context, cfg HeadlessConfig) (*HeadlessRun, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	storeBackend := session.StoreBackendFile
+	if cfg.MittoConfig != nil && cfg.MittoConfig.Session != nil {
+		b, err := session.ParseStoreBackend(cfg.MittoConfig.Session.GetStoreBackend())
+		if err != nil {
+			return nil, err
+		}
+		storeBackend = b
+	}
+	store, err := session.DefaultStoreWithBackend(storeBackend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session store: %w", err)
+	}
+
+	sessionMgr := NewSessionManagerWithOptions(SessionManagerOptions{
+		Workspaces:  cfg.Workspaces,
+		AutoApprove: cfg.AutoApprove,
+		Logger:      logger,
+		FromCLI:     true, // Never save the workspaces
+	})
+	sessionMgr.SetStore(store)
+	processMgr := NewACPProcessManager(context.Background(), logger)
+	processMgr.DisableAuxiliary = true
+	processMgr.WorkspaceConfigProvider = sessionMgr.GetWorkspaceByUUID
+	sessionMgr.SetACPProcessManager(processMgr)
+	sessionMgr.Configure(cfg.MittoConfig)
+	sessionMgr.SetRunPermissions(cfg.Permissions)
+
+	h := &HeadlessRun{
+		store:      store,
+		sessionMgr: sessionMgr,
+		processMgr: processMgr,
+		observer:   newHeadlessObserver(cfg.OnEvent),
+	}
+	sessionMgr.SetOnPromptFailed(h.observer.promptFailed)
+
+	workspace := sessionMgr.GetWorkspaceByUUID(cfg.Workspace)
+	if workspace == nil {
+		h.Close()
+		return nil, fmt.Errorf("workspace %s not found", cfg.Workspace)
+	}
+	bs, err := sessionMgr.CreateSessionWithWorkspace(ctx, cfg.SessionName, workspace.WorkingDir, workspace)
+	if err != nil {
+		h.Close()
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	h.bs = bs
+	h.observer.bs = bs
+	bs.AddObserver(h.observer)
+	h.observer.emit(HeadlessEvent{Type: HeadlessEventSessionStarted, Title: cfg.SessionName, Path: workspace.WorkingDir})
+	return h, nil
+}
+
+// SessionID returns the ID of the session of the run.
+func (h *HeadlessRun) SessionID() string {
+	return h.bs.GetSessionID()
+}
+
+// EventCount returns the number of recorded events of the session.
+func (h *HeadlessRun) EventCount() int {
+	return h.bs.GetEventCount()
+}
+
+// Prompt sends a prompt and waits for the agent to finish its turn.
+// It returns an error when the prompt fails, and ctx.Err() (after cancelling
+// the turn) when ctx is done first.
+func (h *HeadlessRun) Prompt(ctx context.Context, message string) error {
+	done := h.observer.startTurn()
+	if err := h.bs.Prompt(message); err != nil {
+		return err
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		_ = h.bs.Cancel()
+		return ctx.Err()
+	}
+}
+
+// Close closes the session, stops the agent and closes the session store.
+func (h *HeadlessRun) Close() {
+	h.sessionMgr.CloseAll("headless_run_complete")
+	h.processMgr.Close()
+	if err := h.store.Close(); err != nil && h.sessionMgr.logger != nil {
+		h.sessionMgr.logger.Warn("Failed to close session store", "error", err)
+	}
+}
+
+// headlessObserver turns the session events into HeadlessEvents, and answers
+// the UI prompts the session shows.
+type headlessObserver struct {
+	onEvent func(HeadlessEvent)
+	bs      *BackgroundSession
+
+	mu   sync.Mutex
+	done chan error // Receives the outcome of the current turn
+}
+
+func newHeadlessObserver(onEvent func(HeadlessEvent)) *headlessObserver {
+	return &headlessObserver{onEvent: onEvent}
+}
+
+// emit sends an event to the OnEvent callback.
+func (o *headlessObserver) emit(ev HeadlessEvent) {
+	if o.onEvent == nil {
+		return
+	}
+	ev.Time = time.Now().UTC()
+	if o.bs != nil {
+		ev.SessionID = o.bs.GetSessionID()
+	}
+	o.onEvent(ev)
+}
+
+// startTurn returns the channel that receives the outcome of the next turn.
+func (o *headlessObserver) startTurn() <-chan error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.done = make(chan error, 1)
+	return o.done
+}
+
+// endTurn reports the outcome of the current turn, if any.
+func (o *headlessObserver) endTurn(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.done != nil {
+		o.done <- err
+		o.done = nil
+	}
+}
+
+// promptFailed is the OnPromptFailed callback of the session manager.
+func (o *headlessObserver) promptFailed(sessionID, message string) {
+	if o.bs != nil && sessionID != o.bs.GetSessionID() {
+		return
+	}
+	o.endTurn(errors.New(message))
+}
+
+func (o *headlessObserver) OnAgentMessage(seq int64, html string) {
+	o.emit(HeadlessEvent{Type: HeadlessEventAgentMessage, Seq: seq, Text: html})
+}
+
+func (o *headlessObserver) OnAgentThought(seq int64, text string) {
+	o.emit(HeadlessEvent{Type: HeadlessEventAgentThought, Seq: seq, Text: text})
+}
+
+func (o *headlessObserver) OnToolCall(seq int64, id, title, status string) {
+	o.emit(HeadlessEvent{Type: HeadlessEventToolCall, Seq: seq, ID: id, Title: title, Status: status})
+}
+
+func (o *headlessObserver) OnToolUpdate(seq int64, id string, status *string) {
+	ev := HeadlessEvent{Type: HeadlessEventToolUpdate, Seq: seq, ID: id}
+	if status != nil {
+		ev.Status = *status
+	}
+	o.emit(ev)
+}
+
+func (o *headlessObserver) OnPlan(seq int64, entries []PlanEntry) {
+	o.emit(HeadlessEvent{Type: HeadlessEventPlan, Seq: seq, Plan: entries})
+}
+
+func (o *headlessObserver) OnFileWrite(seq int64, path string, size int) {
+	o.emit(HeadlessEvent{Type: HeadlessEventFileWrite, Seq: seq, Path: path, Size: size})
+}
+
+func (o *headlessObserver) OnFileRead(seq int64, path string, size int) {
+	o.emit(HeadlessEvent{Type: HeadlessEventFileRead, Seq: seq, Path: path, Size: size})
+}
+
+func (o *headlessObserver) OnPromptComplete(eventCount int) {
+	o.endTurn(nil)
+}
+
+func (o *headlessObserver) OnError(message string) {
+	// Not every error ends the turn (e.g. the agent is restarted and the prompt
+	// retried): the turn ends with OnPromptComplete or promptFailed.
+	o.emit(HeadlessEvent{Type: HeadlessEventError, Error: message})
+}
+
+// OnUIPrompt answers the prompts that would wait for the user: permission
+// requests are denied, other prompts are dismissed.
+func (o *headlessObserver) OnUIPrompt(req UIPromptRequest) {
+	bs := o.bs
+	if req.Type != UIPromptTypePermission {
+		o.emit(HeadlessEvent{Type: HeadlessEventUIPrompt, ID: req.RequestID, Title: req.Question, Status: "dismissed"})
+		if bs != nil {
+			go bs.DismissPrompt(req.RequestID)
+		}
+		return
+	}
+
+	option, ok, _ := selectPermissionOption(req.Options, PermissionDecisionRequest{Action: PermissionActionDeny})
+	o.emit(HeadlessEvent{Type: HeadlessEventPermission, ID: req.RequestID, Title: req.Title, Status: "denied"})
+	if bs == nil {
+		return
+	}
+	// Answer asynchronously: observers are notified with the observers lock held
+	if ok {
+		go bs.HandleUIPromptAnswer(req.RequestID, option.ID, option.Label, "")
+	} else {
+		go bs.DismissPrompt(req.RequestID)
+	}
+}
+
+func (o *headlessObserver) OnNotification(req UINotifyRequest) {
+	o.emit(HeadlessEvent{Type: HeadlessEventNotification, Title: req.Title, Text: req.Message})
+}
+
+func (o *headlessObserver) OnActionButtons(buttons []ActionButton) {}
+
+func (o *headlessObserver) OnUserPrompt(seq int64, senderID, promptID, message string, imageIDs, fileIDs []string, promptName string) {
+}
+
+func (o *headlessObserver) OnQueueUpdated(queueLength int, action string, messageID string) {}
+
+func (o *headlessObserver) OnQueueReordered(messages []session.QueuedMessage) {}
+
+func (o *headlessObserver) OnQueueMessageSending(messageID string) {}
+
+func (o *headlessObserver) OnQueueMessageSent(messageID string) {}
+
+func (o *headlessObserver) OnAvailableCommandsUpdated(commands []AvailableCommand) {}
+
+func (o *headlessObserver) OnACPStopped(reason string) {}
+
+func (o *headlessObserver) OnACPStarted() {}
+
+func (o *headlessObserver) OnUIPromptDismiss(requestID string, reason string) {}
+
+func (o *headlessObserver) OnContextUsageUpdate(size, used int) {}
+
+func (o *headlessObserver) OnTerminalOutput(update TerminalOutputUpdate) {}
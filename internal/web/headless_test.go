@@ -0,0 +1,123 @@
+package web
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+func TestHeadlessObserver_DeniesPermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     []UIPromptOption
+		wantOption  string
+		wantTimeout bool
+	}{
+		{"reject option", testPermissionOptions, "reject", false},
+		{"no reject option", testPermissionOptions[:1], "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			bs := &BackgroundSession{
+				observers:   make(map[SessionObserver]struct{}),
+				ctx:         ctx,
+				cancel:      cancel,
+				persistedID: "20260101-120000-abcd1234",
+			}
+
+			var mu sync.Mutex
+			var events []HeadlessEvent
+			observer := newHeadlessObserver(func(ev HeadlessEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, ev)
+			})
+			observer.bs = bs
+			bs.AddObserver(observer)
+
+			resp, err := bs.UIPrompt(ctx, UIPromptRequest{
+				RequestID:      "tool-1",
+				Type:           UIPromptTypePermission,
+				Title:          "Run rm -rf build",
+				Options:        tt.options,
+				TimeoutSeconds: 5,
+				Blocking:       true,
+			})
+			if err != nil {
+				t.Fatalf("UIPrompt() error = %v", err)
+			}
+			if resp.OptionID != tt.wantOption || resp.TimedOut != tt.wantTimeout {
+				t.Errorf("response = %+v", resp)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(events) != 1 || events[0].Type != HeadlessEventPermission || events[0].Status != "denied" ||
+				events[0].ID != "tool-1" || events[0].SessionID != "20260101-120000-abcd1234" {
+				t.Errorf("events = %+v", events)
+			}
+		})
+	}
+}
+
+func TestHeadlessObserver_TurnOutcome(t *testing.T) {
+	bs := &BackgroundSession{persistedID: "20260101-120000-abcd1234"}
+	observer := newHeadlessObserver(nil)
+	observer.bs = bs
+
+	// Outcomes outside a turn are ignored
+	observer.OnPromptComplete(3)
+
+	done := observer.startTurn()
+	observer.OnError("AI agent restarted. Retrying your message automatically...")
+	observer.promptFailed("20260101-120000-ffff0000", "another session")
+	select {
+	case err := <-done:
+		t.Fatalf("turn ended early with %v", err)
+	default:
+	}
+	observer.OnPromptComplete(5)
+	if err := <-done; err != nil {
+		t.Errorf("completed turn error = %v", err)
+	}
+
+	done = observer.startTurn()
+	observer.promptFailed("20260101-120000-abcd1234", "rate limited")
+	select {
+	case err := <-done:
+		if err == nil || err.Error() != "rate limited" {
+			t.Errorf("failed turn error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failed turn did not end")
+	}
+}
+
+func TestSessionManager_RunPermissions(t *testing.T) {
+	sm := NewSessionManager("", "", false, nil)
+	workspace := &config.WorkspaceSettings{
+		WorkingDir: "/work",
+		PermissionRules: []config.PermissionRule{
+			{Name: "deny-secrets", When: `toolCall.pathMatches(".env*")`, Action: "deny"},
+		},
+	}
+	sm.SetRunPermissions(&config.PermissionPolicyFile{
+		Rules:   []config.PermissionRule{{Name: "allow-tests", When: `toolCall.commandMatches("go test *")`, Action: "approve"}},
+		Default: "deny",
+	})
+
+	policy := sm.permissionPolicy(workspace, "")
+	if policy.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", policy.Len())
+	}
+	ctx := &config.PromptEnabledContext{ToolCall: config.ToolCallContext{Kind: "edit", Paths: []string{"/work/main.go"}}}
+	ctx.Workspace.Folder = "/work"
+	if d := policy.Evaluate(ctx); d.Rule != "default" || d.Scope != config.PermissionScopeRun || d.Action != "deny" {
+		t.Errorf("Evaluate() = %+v, want the default rule of the run", d)
+	}
+}
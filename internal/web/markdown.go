@@ -2,12 +2,16 @@
 package web
 
 import (
+	"context"
 	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/inercia/mitto/internal/conversion"
 	"github.com/inercia/mitto/internal/logging"
+	"github.com/inercia/mitto/internal/tracing"
 )
 
 const (
@@ -55,9 +59,10 @@ type MarkdownBuffer struct {
 	inactivityTimer *time.Timer // Hard timeout (forces flush regardless of state)
 	flushTimeout    time.Duration
 	inCodeBlock     bool
-	inList          bool // Track if we're inside a list
-	inTable         bool // Track if we're inside a table
-	sawBlankLine    bool // Track if we saw a blank line (potential list/paragraph end)
+	inList          bool                   // Track if we're inside a list
+	inTable         bool                   // Track if we're inside a table
+	sawBlankLine    bool                   // Track if we saw a blank line (potential list/paragraph end)
+	traceContext    func() context.Context // Parent of the flush spans (optional)
 }
 
 // MarkdownBufferConfig holds configuration for creating a MarkdownBuffer.
@@ -68,6 +73,9 @@ type MarkdownBufferConfig struct {
 	// FileLinksConfig configures file path detection and linking.
 	// If nil, file linking is disabled.
 	FileLinksConfig *conversion.FileLinkerConfig
+	// TraceContext returns the trace context the flush spans are children of.
+	// If nil, the flushes are traced as root spans.
+	TraceContext func() context.Context
 }
 
 // NewMarkdownBuffer creates a new streaming Markdown buffer.
@@ -96,6 +104,7 @@ func NewMarkdownBufferWithConfig(cfg MarkdownBufferConfig) *MarkdownBuffer {
 		converter:    conversion.NewConverter(opts...),
 		onFlush:      cfg.OnFlush,
 		flushTimeout: defaultFlushTimeout,
+		traceContext: cfg.TraceContext,
 	}
 }
 
@@ -366,12 +375,22 @@ func (mb *MarkdownBuffer) flushLocked() {
 	contentLen := len(content)
 	mb.buffer.Reset()
 
+	ctx := context.Background()
+	if mb.traceContext != nil {
+		ctx = mb.traceContext()
+	}
+	_, span := tracing.Start(ctx, "markdown.flush",
+		attribute.Int("mitto.content_len", contentLen),
+		attribute.Bool("mitto.in_code_block", mb.inCodeBlock))
+	defer span.End()
+
 	// Check for unmatched formatting before converting
 	hasUnmatched := conversion.HasUnmatchedInlineFormatting(content)
 
 	// Convert to HTML using the converter (which handles sanitization)
 	htmlStr := mb.converter.ConvertToSafeHTML(content)
 	htmlLen := len(htmlStr)
+	span.SetAttributes(attribute.Int("mitto.html_len", htmlLen))
 
 	// Log flush for debugging message content issues
 	if hasUnmatched {
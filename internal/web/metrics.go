@@ -0,0 +1,223 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	acp "github.com/coder/acp-go-sdk"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/metrics"
+)
+
+// Metrics updated by the server as things happen. They are process-wide and
+// registered in the registry of every Server (see newMetricsRegistry).
+var (
+	metricHTTPRequests = metrics.NewCounter("mitto_http_requests_total",
+		"HTTP requests served, by method and status code.", "method", "code")
+	metricAuthFailures = metrics.NewCounter("mitto_auth_failures_total",
+		"Failed authentications, by reason (password, totp, oidc, api_token, session, forbidden).", "reason")
+	metricPromptDuration = metrics.NewHistogram("mitto_prompt_duration_seconds",
+		"Time the agent took to answer a prompt, by ACP server and outcome (stop reason or error).",
+		metrics.DefaultBuckets, "agent", "outcome")
+	metricToolCalls = metrics.NewCounter("mitto_tool_calls_total",
+		"Tool calls started by agents, by kind.", "kind")
+	metricACPProcessRestarts = metrics.NewCounter("mitto_acp_process_restarts_total",
+		"Restarts of shared ACP processes.")
+	metricSessionRestarts = metrics.NewCounter("mitto_acp_session_restarts_total",
+		"ACP restarts of conversations, by reason.", "reason")
+	metricGCActions = metrics.NewCounter("mitto_gc_actions_total",
+		"Decisions of the ACP process garbage collector, by action.", "action")
+	metricMemoryRecycles = metrics.NewCounter("mitto_acp_memory_recycles_total",
+		"Idle ACP processes stopped by the GC because their memory was over the threshold.")
+	metricPeriodicRuns = metrics.NewCounter("mitto_periodic_runs_total",
+		"Periodic prompts checked by the periodic runner, by result (delivered, skipped, errored).", "result")
+	metricResourceLimitExceeded = metrics.NewCounter("mitto_resource_limit_exceeded_total",
+		"Prompts cancelled for exceeding a resource limit of the workspace, by limit (memory, cpu, duration, processes).", "limit")
+)
+
+// GC actions (label values of mitto_gc_actions_total).
+const (
+	gcActionCloseIdleSession       = "close_idle_session"
+	gcActionSuspendPeriodicSession = "suspend_periodic_session"
+	gcActionStopIdleProcess        = "stop_idle_process"
+	gcActionDeferProcessStop       = "defer_process_stop"
+)
+
+// Auth failure reasons (label values of mitto_auth_failures_total).
+const (
+	authFailurePassword  = "password"
+	authFailureTOTP      = "totp"
+	authFailureOIDC      = "oidc"
+	authFailureAPIToken  = "api_token"
+	authFailureSession   = "session"
+	authFailureForbidden = "forbidden"
+)
+
+// toolKindLabel returns the label of a tool call kind, mapping the kinds not
+// defined by ACP to "other" to keep the number of series bounded.
+func toolKindLabel(kind acp.ToolKind) string {
+	switch kind {
+	case acp.ToolKindRead, acp.ToolKindEdit, acp.ToolKindDelete, acp.ToolKindMove,
+		acp.ToolKindSearch, acp.ToolKindExecute, acp.ToolKindThink, acp.ToolKindFetch,
+		acp.ToolKindSwitchMode:
+		return string(kind)
+	}
+	return string(acp.ToolKindOther)
+}
+
+// httpMethodLabel returns the label of an HTTP method, mapping non-standard
+// methods to "other".
+func httpMethodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "other"
+}
+
+// newMetricsRegistry creates the registry of the server, with the process-wide
+// metrics and gauges computed from the state of the server when scraped.
+func (s *Server) newMetricsRegistry() *metrics.Registry {
+	r := metrics.NewRegistry()
+	r.MustRegister(
+		metricHTTPRequests,
+		metricAuthFailures,
+		metricPromptDuration,
+		metricToolCalls,
+		metricACPProcessRestarts,
+		metricSessionRestarts,
+		metricGCActions,
+		metricMemoryRecycles,
+		metricPeriodicRuns,
+		metricResourceLimitExceeded,
+	)
+
+	r.MustRegister(
+		metrics.NewGaugeFunc("mitto_events_ws_clients",
+			"Clients connected to the global events WebSocket.",
+			func() float64 { return float64(s.eventsManager.ClientCount()) }),
+		metrics.NewGaugeFunc("mitto_session_ws_clients",
+			"Clients connected to conversation WebSockets.",
+			func() float64 { return float64(s.sessionManager.ConnectedClientCount()) }),
+		metrics.NewGaugeVecFunc("mitto_sessions",
+			"Running conversations, by state (running, prompting).",
+			[]string{"state"}, s.collectSessionStates),
+		metrics.NewGaugeVecFunc("mitto_queue_length",
+			"Messages waiting in the queues of running conversations, by workspace.",
+			[]string{"workspace"}, s.collectQueueLengths),
+	)
+
+	if s.acpProcessManager != nil {
+		r.MustRegister(
+			metrics.NewGaugeFunc("mitto_acp_processes",
+				"Running shared ACP processes.",
+				func() float64 { return float64(s.acpProcessManager.ProcessCount()) }),
+			metrics.NewGaugeVecFunc("mitto_acp_process_rss_bytes",
+				"Resident memory of the shared ACP processes (with their children), by workspace.",
+				[]string{"workspace"}, s.collectProcessRSS),
+			metrics.NewGaugeVecFunc("mitto_acp_active_rpcs",
+				"In-flight RPCs of the shared ACP processes, by workspace.",
+				[]string{"workspace"}, s.collectActiveRPCs),
+		)
+	}
+
+	if s.defense != nil {
+		r.MustRegister(metrics.NewGaugeFunc("mitto_defense_blocked_ips",
+			"IPs currently blocked by the scanner defense.",
+			func() float64 { return float64(s.defense.BlockedCount()) }))
+	}
+
+	return r
+}
+
+func (s *Server) collectSessionStates() []metrics.Sample {
+	var running, prompting int
+	for _, sessions := range s.sessionManager.GetSessionInfoByWorkspace() {
+		for _, info := range sessions {
+			running++
+			if info.IsPrompting {
+				prompting++
+			}
+		}
+	}
+	return []metrics.Sample{
+		{Value: float64(running), LabelValues: []string{"running"}},
+		{Value: float64(prompting), LabelValues: []string{"prompting"}},
+	}
+}
+
+func (s *Server) collectQueueLengths() []metrics.Sample {
+	var samples []metrics.Sample
+	for workspaceUUID, sessions := range s.sessionManager.GetSessionInfoByWorkspace() {
+		queued := 0
+		for _, info := range sessions {
+			queued += info.QueueLength
+		}
+		samples = append(samples, metrics.Sample{Value: float64(queued), LabelValues: []string{workspaceUUID}})
+	}
+	return samples
+}
+
+func (s *Server) collectProcessRSS() []metrics.Sample {
+	var samples []metrics.Sample
+	for workspaceUUID, p := range s.acpProcessManager.Processes() {
+		rss, err := p.RSSBytes()
+		if err != nil {
+			continue
+		}
+		samples = append(samples, metrics.Sample{Value: float64(rss), LabelValues: []string{workspaceUUID}})
+	}
+	return samples
+}
+
+func (s *Server) collectActiveRPCs() []metrics.Sample {
+	var samples []metrics.Sample
+	for workspaceUUID, p := range s.acpProcessManager.Processes() {
+		samples = append(samples, metrics.Sample{Value: float64(p.ActiveRPCs()), LabelValues: []string{workspaceUUID}})
+	}
+	return samples
+}
+
+// metricsMiddleware counts the requests by method and final status code.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapped := &accessLogResponseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+		next.ServeHTTP(wrapped, r)
+		metricHTTPRequests.Inc(httpMethodLabel(r.Method), strconv.Itoa(wrapped.statusCode))
+	})
+}
+
+// handleMetrics handles GET /metrics, serving the metrics in the Prometheus
+// text format. Like the internal listener, it is open to localhost; other
+// clients must be authenticated, and user accounts need the admin role.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !canReadMetrics(r) {
+		if s.logger != nil {
+			s.logger.Warn("Rejected metrics request",
+				"client_ip", getClientIPWithProxyCheck(r),
+				"user", authUserName(r.Context()),
+			)
+		}
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	s.metrics.Handler().ServeHTTP(w, r)
+}
+
+// canReadMetrics returns true if the client of a request can read the metrics.
+func canReadMetrics(r *http.Request) bool {
+	if !IsExternalConnection(r) && isLoopbackIP(getClientIPWithProxyCheck(r)) {
+		return true
+	}
+	// Requests from other clients only get here unauthenticated when the
+	// authentication is disabled
+	if authUserName(r.Context()) == "" {
+		return false
+	}
+	return webUserFromContext(r.Context()).HasRole(config.RoleAdmin)
+}
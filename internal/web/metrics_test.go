@@ -0,0 +1,104 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	acp "github.com/coder/acp-go-sdk"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+func newMetricsTestServer() *Server {
+	s := &Server{
+		sessionManager: NewSessionManager("", "test-server", false, nil),
+		eventsManager:  NewGlobalEventsManager(),
+	}
+	s.metrics = s.newMetricsRegistry()
+	return s
+}
+
+func TestServer_HandleMetrics(t *testing.T) {
+	s := newMetricsTestServer()
+	metricToolCalls.Inc(toolKindLabel(acp.ToolKindEdit))
+	metricToolCalls.Inc(toolKindLabel("custom"))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "127.0.0.1:12345"
+	rr := httptest.NewRecorder()
+	s.handleMetrics(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handleMetrics() status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"# TYPE mitto_prompt_duration_seconds histogram",
+		"# TYPE mitto_auth_failures_total counter",
+		"mitto_events_ws_clients 0\n",
+		"mitto_session_ws_clients 0\n",
+		`mitto_sessions{state="running"} 0`,
+		`mitto_tool_calls_total{kind="edit"}`,
+		`mitto_tool_calls_total{kind="other"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "mitto_defense_blocked_ips") {
+		t.Error("metrics contain the scanner defense gauge without a scanner defense")
+	}
+}
+
+func TestServer_HandleMetrics_Access(t *testing.T) {
+	s := newMetricsTestServer()
+	admin := &config.WebUser{Username: "alice", Role: config.RoleAdmin}
+	viewer := &config.WebUser{Username: "bob", Role: config.RoleViewer}
+
+	tests := []struct {
+		name     string
+		external bool
+		identity string
+		user     *config.WebUser
+		want     int
+	}{
+		{"localhost", false, "", nil, http.StatusOK},
+		{"external unauthenticated", true, "", nil, http.StatusForbidden},
+		{"external single user", true, "admin", nil, http.StatusOK},
+		{"external admin", true, "alice", admin, http.StatusOK},
+		{"external viewer", true, "bob", viewer, http.StatusForbidden},
+		{"external api token", true, "token:prometheus", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = "127.0.0.1:12345"
+			ctx := context.WithValue(req.Context(), ContextKeyExternalConnection, tt.external)
+			if tt.identity != "" {
+				ctx = context.WithValue(ctx, contextKeyAuthUser, tt.identity)
+			}
+			if tt.user != nil {
+				ctx = context.WithValue(ctx, contextKeyWebUser, tt.user)
+			}
+			rr := httptest.NewRecorder()
+			s.handleMetrics(rr, req.WithContext(ctx))
+			if rr.Code != tt.want {
+				t.Errorf("handleMetrics() status = %d, want %d", rr.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	handler := metricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "teapot", http.StatusTeapot)
+	}))
+	before := metricHTTPRequests.Value(http.MethodDelete, "418")
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/api/sessions/x", nil))
+	if got := metricHTTPRequests.Value(http.MethodDelete, "418"); got != before+1 {
+		t.Errorf("mitto_http_requests_total{method=DELETE,code=418} = %v, want %v", got, before+1)
+	}
+}
@@ -0,0 +1,133 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/inercia/mitto/internal/notify"
+)
+
+const (
+	// defaultWebhookDeliveriesLimit is the number of deliveries returned by default.
+	defaultWebhookDeliveriesLimit = 50
+	// maxWebhookDeliveriesLimit caps the number of deliveries returned.
+	maxWebhookDeliveriesLimit = 1000
+)
+
+// WebhookTestRequest is the request body of POST /api/webhooks/test.
+type WebhookTestRequest struct {
+	// Webhook is the name of the webhook to test (all webhooks if empty).
+	Webhook string `json:"webhook,omitempty"`
+}
+
+// WebhookTestResponse is the response of POST /api/webhooks/test.
+type WebhookTestResponse struct {
+	Deliveries []notify.Delivery `json:"deliveries"`
+}
+
+// WebhookDeliveriesResponse is the response of GET /api/webhooks/deliveries.
+type WebhookDeliveriesResponse struct {
+	Deliveries []notify.Delivery `json:"deliveries"`
+}
+
+// notifyEvent sends a lifecycle event of this session to the outbound webhooks.
+// The session name and parent are read from the stored metadata.
+func (bs *BackgroundSession) notifyEvent(eventType, message string, data map[string]any) {
+	if bs.notifier == nil {
+		return
+	}
+	ev := notify.Event{
+		Type:       eventType,
+		SessionID:  bs.persistedID,
+		WorkingDir: bs.workingDir,
+		Message:    message,
+		Data:       data,
+	}
+	if bs.store != nil {
+		if meta, err := bs.store.GetMetadata(bs.persistedID); err == nil {
+			ev.SessionName = meta.Name
+			ev.ParentSessionID = meta.ParentSessionID
+		}
+	}
+	bs.notifier.Notify(ev)
+}
+
+// notifySessionEvent sends a lifecycle event of a session to the outbound webhooks,
+// for events raised outside of the session (periodic runner, MCP server).
+func (s *Server) notifySessionEvent(sessionID, eventType, message string, data map[string]any) {
+	if s.notifier == nil {
+		return
+	}
+	ev := notify.Event{
+		Type:      eventType,
+		SessionID: sessionID,
+		Message:   message,
+		Data:      data,
+	}
+	if store := s.Store(); store != nil {
+		if meta, err := store.GetMetadata(sessionID); err == nil {
+			ev.SessionName = meta.Name
+			ev.WorkingDir = meta.WorkingDir
+			ev.ParentSessionID = meta.ParentSessionID
+		}
+	}
+	s.notifier.Notify(ev)
+}
+
+// handleWebhookDeliveries handles GET /api/webhooks/deliveries
+// It returns the most recent delivery attempts, newest first.
+//
+// Query parameters:
+//   - limit: maximum number of deliveries (default 50, max 1000)
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	limit := defaultWebhookDeliveriesLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_limit", "limit must be a positive integer")
+			return
+		}
+		limit = min(n, maxWebhookDeliveriesLimit)
+	}
+
+	deliveries, err := s.webhookLog.Recent(limit)
+	if err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, "delivery_log_error", err.Error())
+		return
+	}
+	if deliveries == nil {
+		deliveries = []notify.Delivery{}
+	}
+	writeJSONOK(w, WebhookDeliveriesResponse{Deliveries: deliveries})
+}
+
+// handleWebhookTest handles POST /api/webhooks/test
+// It sends a test event to a webhook (or to all of them) with a single attempt
+// and returns the deliveries.
+func (s *Server) handleWebhookTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	var req WebhookTestRequest
+	if r.ContentLength != 0 && !parseJSONBody(w, r, &req) {
+		return
+	}
+
+	if s.notifier == nil {
+		writeErrorJSON(w, http.StatusNotFound, "no_webhooks", "No webhook is configured")
+		return
+	}
+	deliveries := s.notifier.Test(r.Context(), req.Webhook)
+	if len(deliveries) == 0 {
+		writeErrorJSON(w, http.StatusNotFound, "webhook_not_found", "Webhook not found: "+req.Webhook)
+		return
+	}
+	writeJSONOK(w, WebhookTestResponse{Deliveries: deliveries})
+}
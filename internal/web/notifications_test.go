@@ -0,0 +1,84 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/notify"
+)
+
+func TestHandleWebhookTestAndDeliveries(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(notify.HeaderEvent) != notify.EventTest {
+			t.Errorf("event header = %q", r.Header.Get(notify.HeaderEvent))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer hook.Close()
+
+	webhookLog := notify.NewDeliveryLog(filepath.Join(t.TempDir(), "deliveries.jsonl"))
+	notifier := notify.New([]config.WebhookConfig{{Name: "ci", URL: hook.URL}}, webhookLog, nil)
+	defer notifier.Close()
+	server := &Server{notifier: notifier, webhookLog: webhookLog}
+
+	// Unknown webhook
+	w := httptest.NewRecorder()
+	server.handleWebhookTest(w, httptest.NewRequest(http.MethodPost, "/api/webhooks/test", strings.NewReader(`{"webhook":"nope"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("test unknown webhook: status = %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleWebhookTest(w, httptest.NewRequest(http.MethodPost, "/api/webhooks/test", strings.NewReader(`{"webhook":"ci"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("test webhook: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var testResp WebhookTestResponse
+	if err := json.NewDecoder(w.Body).Decode(&testResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(testResp.Deliveries) != 1 || !testResp.Deliveries[0].Success {
+		t.Errorf("deliveries = %+v", testResp.Deliveries)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleWebhookDeliveries(w, httptest.NewRequest(http.MethodGet, "/api/webhooks/deliveries?limit=10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("deliveries: status = %d", w.Code)
+	}
+	var listResp WebhookDeliveriesResponse
+	if err := json.NewDecoder(w.Body).Decode(&listResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(listResp.Deliveries) != 1 || listResp.Deliveries[0].Webhook != "ci" {
+		t.Errorf("deliveries = %+v", listResp.Deliveries)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleWebhookDeliveries(w, httptest.NewRequest(http.MethodGet, "/api/webhooks/deliveries?limit=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit: status = %d, want 400", w.Code)
+	}
+}
+
+func TestHandleWebhookTest_NoWebhooks(t *testing.T) {
+	server := &Server{}
+
+	w := httptest.NewRecorder()
+	server.handleWebhookTest(w, httptest.NewRequest(http.MethodPost, "/api/webhooks/test", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+
+	// Without a delivery log the list is empty
+	w = httptest.NewRecorder()
+	server.handleWebhookDeliveries(w, httptest.NewRequest(http.MethodGet, "/api/webhooks/deliveries", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"deliveries":[]`) {
+		t.Errorf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
@@ -16,6 +16,21 @@ type PlanEntry struct {
 	Status string `json:"status"`
 }
 
+// TerminalOutputUpdate describes new output or the exit of an agent terminal.
+// Terminals are commands the agent runs through the ACP terminal/* methods.
+type TerminalOutputUpdate struct {
+	// TerminalID identifies the terminal within the session.
+	TerminalID string `json:"terminal_id"`
+	// Output is the newly produced output chunk (empty on exit).
+	Output string `json:"output,omitempty"`
+	// Exited is true once the command has finished.
+	Exited bool `json:"exited,omitempty"`
+	// ExitCode is the process exit code (nil if still running or killed by a signal).
+	ExitCode *int `json:"exit_code,omitempty"`
+	// Signal is the signal that terminated the process, if any.
+	Signal *string `json:"signal,omitempty"`
+}
+
 // Type aliases for UI prompt types from mcpserver package.
 // This avoids duplication while keeping the types accessible in the web package.
 type (
@@ -155,4 +170,8 @@ type SessionObserver interface {
 	// OnContextUsageUpdate is called when the agent sends a context window usage update.
 	// size is the total context window size in tokens, used is how many tokens are currently in context.
 	OnContextUsageUpdate(size, used int)
+
+	// OnTerminalOutput is called when an agent terminal produces output or exits.
+	// Terminal output is streamed live only; it is not persisted as session events.
+	OnTerminalOutput(update TerminalOutputUpdate)
 }
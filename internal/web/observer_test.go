@@ -112,6 +112,10 @@ func (m *mockObserver) OnContextUsageUpdate(size, used int) {
 	// no-op for testing
 }
 
+func (m *mockObserver) OnTerminalOutput(update TerminalOutputUpdate) {
+	// no-op for testing
+}
+
 func TestSessionObserver_Interface(t *testing.T) {
 	// Verify mockObserver implements SessionObserver
 	var _ SessionObserver = (*mockObserver)(nil)
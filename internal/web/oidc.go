@@ -0,0 +1,380 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/inercia/mitto/internal/audit"
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/logging"
+)
+
+const (
+	// oidcLoginPath starts an OpenID Connect login (without the API prefix).
+	oidcLoginPath = "/api/auth/oidc/login"
+	// oidcCallbackPath is where the identity provider sends users back.
+	oidcCallbackPath = "/api/auth/oidc/callback"
+
+	// oidcStateCookieName binds a login to the browser that started it.
+	oidcStateCookieName = "mitto_oidc_state"
+
+	// oidcLoginTimeout is how long users have to log in at the provider.
+	oidcLoginTimeout = 10 * time.Minute
+
+	// maxPendingOIDCLogins bounds the logins waiting for their callback.
+	maxPendingOIDCLogins = 1000
+
+	// oidcHTTPTimeout bounds the requests to the identity provider.
+	oidcHTTPTimeout = 15 * time.Second
+)
+
+// pendingOIDCLogin is a login waiting for the callback of the provider.
+type pendingOIDCLogin struct {
+	codeVerifier string
+	nonce        string
+	expiresAt    time.Time
+}
+
+// oidcLogin implements the authorization code flow with PKCE against an
+// OpenID Connect provider.
+type oidcLogin struct {
+	config *config.OIDCAuth
+	client *http.Client // for the requests to the provider
+
+	mu       sync.Mutex
+	provider *oidc.Provider // discovered on first use
+	verifier *oidc.IDTokenVerifier
+	pending  map[string]*pendingOIDCLogin // by state
+	now      func() time.Time
+}
+
+// newOIDCLogin creates the OpenID Connect login of a configuration.
+func newOIDCLogin(cfg *config.OIDCAuth) *oidcLogin {
+	return &oidcLogin{
+		config:  cfg,
+		client:  &http.Client{Timeout: oidcHTTPTimeout},
+		pending: make(map[string]*pendingOIDCLogin),
+		now:     time.Now,
+	}
+}
+
+// discover returns the provider and ID token verifier, fetching the
+// discovery document of the issuer on first use. Failures are retried on the
+// next login, so that a provider that is down at startup doesn't disable it.
+func (o *oidcLogin) discover() (*oidc.Provider, *oidc.IDTokenVerifier, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.provider == nil {
+		ctx := oidc.ClientContext(context.Background(), o.client)
+		provider, err := oidc.NewProvider(ctx, o.config.Issuer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("oidc discovery failed for %s: %w", o.config.Issuer, err)
+		}
+		o.provider = provider
+		o.verifier = provider.VerifierContext(ctx, &oidc.Config{ClientID: o.config.ClientID})
+	}
+	return o.provider, o.verifier, nil
+}
+
+// oauth2Config returns the OAuth2 configuration of the client.
+func (o *oidcLogin) oauth2Config(provider *oidc.Provider, redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     o.config.ClientID,
+		ClientSecret: o.config.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURL,
+		Scopes:       o.config.GetScopes(),
+	}
+}
+
+// begin records a new login and returns its state.
+func (o *oidcLogin) begin(codeVerifier, nonce string) (string, error) {
+	state, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	for s, p := range o.pending {
+		if now.After(p.expiresAt) {
+			delete(o.pending, s)
+		}
+	}
+	if len(o.pending) >= maxPendingOIDCLogins {
+		return "", errors.New("too many pending logins")
+	}
+	o.pending[state] = &pendingOIDCLogin{
+		codeVerifier: codeVerifier,
+		nonce:        nonce,
+		expiresAt:    now.Add(oidcLoginTimeout),
+	}
+	return state, nil
+}
+
+// finish removes and returns the login of a state, if it has not expired.
+func (o *oidcLogin) finish(state string) (*pendingOIDCLogin, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	p, ok := o.pending[state]
+	if !ok {
+		return nil, false
+	}
+	delete(o.pending, state)
+	return p, !o.now().After(p.expiresAt)
+}
+
+// oidcIdentity is the identity of a user verified by the provider.
+type oidcIdentity struct {
+	Email  string
+	Groups []string
+}
+
+// exchange trades an authorization code for an ID token, and returns the
+// identity in it once verified.
+func (o *oidcLogin) exchange(ctx context.Context, redirectURL, code string, login *pendingOIDCLogin) (*oidcIdentity, error) {
+	provider, verifier, err := o.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, o.client)
+	token, err := o.oauth2Config(provider, redirectURL).Exchange(ctx, code, oauth2.VerifierOption(login.codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("no id_token in the token response")
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("id token validation failed: %w", err)
+	}
+	if idToken.Nonce != login.nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse id token claims: %w", err)
+	}
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return nil, errors.New("the id token has no email (is the 'email' scope requested?)")
+	}
+	if verified, _ := claims["email_verified"].(bool); !verified && !o.config.AllowUnverifiedEmails {
+		return nil, fmt.Errorf("the email %s is not verified", email)
+	}
+
+	identity := &oidcIdentity{Email: email}
+	switch groups := claims[o.config.GetGroupsClaim()].(type) {
+	case string:
+		identity.Groups = []string{groups}
+	case []any:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				identity.Groups = append(identity.Groups, s)
+			}
+		}
+	}
+	return identity, nil
+}
+
+// HasOIDC returns true if OpenID Connect login is configured.
+func (a *AuthManager) HasOIDC() bool {
+	return a.oidcLogin() != nil
+}
+
+// oidcLogin returns the OpenID Connect login, or nil if not configured.
+func (a *AuthManager) oidcLogin() *oidcLogin {
+	if a == nil {
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.oidc
+}
+
+// oidcRedirectURL returns the callback URL sent to the provider: the
+// configured one, or the callback of the host of the request. Plain HTTP is
+// only assumed for localhost, as providers reject it elsewhere anyway.
+func (a *AuthManager) oidcRedirectURL(o *oidcLogin, r *http.Request) string {
+	if o.config.RedirectURL != "" {
+		return o.config.RedirectURL
+	}
+	scheme := "https"
+	if r.TLS == nil && isLocalhostRequest(r) {
+		scheme = "http"
+	}
+	return scheme + "://" + r.Host + a.apiPrefix + oidcCallbackPath
+}
+
+// oidcFailed sends the browser back to the login page with an error.
+func oidcFailed(w http.ResponseWriter, r *http.Request, message string) {
+	metricAuthFailures.Inc(authFailureOIDC)
+	http.Redirect(w, r, "/auth.html?error="+url.QueryEscape(message), http.StatusFound)
+}
+
+// HandleOIDCLogin handles GET /api/auth/oidc/login, sending the browser to
+// the identity provider.
+func (a *AuthManager) HandleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	logger := logging.Auth()
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	o := a.oidcLogin()
+	if o == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	provider, _, err := o.discover()
+	if err != nil {
+		logger.Error("AUTH: OIDC login failed", "error", err)
+		oidcFailed(w, r, "The identity provider is not available.")
+		return
+	}
+
+	codeVerifier := oauth2.GenerateVerifier()
+	nonce, err := generateToken()
+	if err != nil {
+		oidcFailed(w, r, "Failed to start the login.")
+		return
+	}
+	state, err := o.begin(codeVerifier, nonce)
+	if err != nil {
+		logger.Warn("AUTH: OIDC login failed", "error", err)
+		oidcFailed(w, r, "Failed to start the login.")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   !isLocalhostRequest(r),
+		// Lax, so that the cookie is sent on the redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(oidcLoginTimeout.Seconds()),
+	})
+
+	authURL := o.oauth2Config(provider, a.oidcRedirectURL(o, r)).AuthCodeURL(state,
+		oauth2.S256ChallengeOption(codeVerifier), oidc.Nonce(nonce))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// HandleOIDCCallback handles GET /api/auth/oidc/callback: it verifies the
+// identity returned by the provider and creates a session for it.
+func (a *AuthManager) HandleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	logger := logging.Auth()
+	clientIP := getClientIPWithProxyCheck(r)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	o := a.oidcLogin()
+	if o == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		logger.Warn("AUTH: OIDC provider returned an error",
+			"error", errCode,
+			"description", query.Get("error_description"),
+			"client_ip", clientIP,
+		)
+		auditLogin(clientIP, "", "oidc", audit.OutcomeDenied, "provider_error")
+		oidcFailed(w, r, "The identity provider denied the login.")
+		return
+	}
+
+	// The state must be the one of this browser
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if state == "" || err != nil || cookie.Value != state {
+		logger.Warn("AUTH: OIDC callback with an invalid state", "client_ip", clientIP)
+		oidcFailed(w, r, "The login expired. Please try again.")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   !isLocalhostRequest(r),
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+	login, ok := o.finish(state)
+	if !ok {
+		oidcFailed(w, r, "The login expired. Please try again.")
+		return
+	}
+
+	identity, err := o.exchange(r.Context(), a.oidcRedirectURL(o, r), query.Get("code"), login)
+	if err != nil {
+		logger.Warn("AUTH: OIDC login failed", "error", err, "client_ip", clientIP)
+		auditLogin(clientIP, "", "oidc", audit.OutcomeDenied, "verification_failed")
+		oidcFailed(w, r, "The login could not be verified.")
+		return
+	}
+
+	if !o.config.IsAllowed(identity.Email, identity.Groups) {
+		logger.Warn("AUTH: OIDC identity not allowed",
+			"email", identity.Email,
+			"groups", identity.Groups,
+			"client_ip", clientIP,
+		)
+		auditLogin(clientIP, identity.Email, "oidc", audit.OutcomeDenied, "not_allowed")
+		oidcFailed(w, r, identity.Email+" is not allowed to access this server.")
+		return
+	}
+	username := "oidc:" + identity.Email
+	user, ok := a.lookupUserByEmail(identity.Email)
+	if !ok {
+		logger.Warn("AUTH: No user account for OIDC identity", "email", identity.Email)
+		auditLogin(clientIP, identity.Email, "oidc", audit.OutcomeDenied, "no_account")
+		oidcFailed(w, r, "There is no user account for "+identity.Email+".")
+		return
+	}
+	if user != nil {
+		username = user.Username
+	}
+
+	session, err := a.CreateSession(username)
+	if err != nil {
+		logger.Error("Failed to create session", "username", username, "error", err)
+		auditLogin(clientIP, username, "oidc", audit.OutcomeFailure, "session_error")
+		oidcFailed(w, r, "Failed to create session.")
+		return
+	}
+
+	logger.Info("Login successful",
+		"client_ip", clientIP,
+		"username", username,
+		"method", "oidc",
+	)
+	auditLogin(clientIP, username, "oidc", audit.OutcomeSuccess, "")
+	a.SetSessionCookie(w, r, session)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
@@ -0,0 +1,303 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	josejwt "github.com/go-jose/go-jose/v4/jwt"
+
+	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/config"
+)
+
+// mockOIDCIssuer is a minimal OpenID Connect provider: it authorizes every
+// request as the configured identity, and checks the PKCE verifier.
+type mockOIDCIssuer struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	email  string
+	groups []string
+	// unverified omits the email_verified claim
+	unverified bool
+
+	// Pending authorization codes, with their nonce and PKCE challenge
+	codes map[string][2]string
+}
+
+func newMockOIDCIssuer(t *testing.T, clientID string) *mockOIDCIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	m := &mockOIDCIssuer{key: key, clientID: clientID, codes: make(map[string][2]string)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONOK(w, map[string]any{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONOK(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test-key", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != clientID || q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "bad authorization request", http.StatusBadRequest)
+			return
+		}
+		code := "code-" + q.Get("state")[:8]
+		m.codes[code] = [2]string{q.Get("nonce"), q.Get("code_challenge")}
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code="+code+"&state="+q.Get("state"), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		pending, ok := m.codes[r.PostForm.Get("code")]
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != pending[1] {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		delete(m.codes, r.PostForm.Get("code"))
+		writeJSONOK(w, map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     m.signIDToken(t, pending[0]),
+		})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockOIDCIssuer) signIDToken(t *testing.T, nonce string) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: m.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test-key"),
+	)
+	if err != nil {
+		t.Errorf("create signer: %v", err)
+		return ""
+	}
+	claims := josejwt.Claims{
+		Issuer:   m.server.URL,
+		Audience: josejwt.Audience{m.clientID},
+		Subject:  "user-1",
+		IssuedAt: josejwt.NewNumericDate(time.Now()),
+		Expiry:   josejwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+	custom := map[string]any{
+		"email":  m.email,
+		"groups": m.groups,
+		"nonce":  nonce,
+	}
+	if !m.unverified {
+		custom["email_verified"] = true
+	}
+	token, err := josejwt.Signed(signer).Claims(claims).Claims(custom).Serialize()
+	if err != nil {
+		t.Errorf("sign ID token: %v", err)
+	}
+	return token
+}
+
+// oidcLoginFlow runs a login through the mock issuer, and returns the final
+// response of the callback.
+func oidcLoginFlow(t *testing.T, am *AuthManager) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://localhost:8080/mitto"+oidcLoginPath, nil)
+	am.HandleOIDCLogin(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want %d: %s", w.Code, http.StatusFound, w.Body.String())
+	}
+	var stateCookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oidcStateCookieName {
+			stateCookie = c
+		}
+	}
+	if stateCookie == nil {
+		t.Fatal("login did not set the state cookie")
+	}
+
+	// The browser logs in at the provider, which sends it back
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := noRedirects.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	callback := resp.Header.Get("Location")
+	if !strings.HasPrefix(callback, "http://localhost:8080/mitto"+oidcCallbackPath+"?") {
+		t.Fatalf("provider redirected to %q", callback)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", callback, nil)
+	req.AddCookie(stateCookie)
+	am.HandleOIDCCallback(w, req)
+	return w
+}
+
+func newOIDCTestAuthManager(t *testing.T, issuer *mockOIDCIssuer, auth *config.WebAuth) *AuthManager {
+	t.Helper()
+	t.Setenv(appdir.MittoDirEnv, t.TempDir())
+	appdir.ResetCache()
+	t.Cleanup(appdir.ResetCache)
+
+	auth.OIDC.Issuer = issuer.server.URL
+	auth.OIDC.ClientID = issuer.clientID
+	am := NewAuthManager(auth)
+	t.Cleanup(am.Close)
+	am.SetAPIPrefix("/mitto")
+	return am
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := newMockOIDCIssuer(t, "mitto")
+	am := newOIDCTestAuthManager(t, issuer, &config.WebAuth{
+		OIDC: &config.OIDCAuth{AllowedEmails: []string{"@example.com"}},
+	})
+	if !am.IsEnabled() || !am.HasOIDC() {
+		t.Fatal("OIDC login should enable authentication")
+	}
+
+	issuer.email = "alice@example.com"
+	w := oidcLoginFlow(t, am)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/" {
+		t.Fatalf("callback = %d %q, want a redirect to /", w.Code, w.Header().Get("Location"))
+	}
+	var sessionCookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			sessionCookie = c
+		}
+	}
+	if sessionCookie == nil {
+		t.Fatal("callback did not set the session cookie")
+	}
+	session, ok := am.ValidateSession(sessionCookie.Value)
+	if !ok || session.Username != "oidc:alice@example.com" {
+		t.Errorf("session = %+v, %v", session, ok)
+	}
+
+	// Identities outside the allowed emails are rejected
+	issuer.email = "mallory@evil.example"
+	w = oidcLoginFlow(t, am)
+	if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/auth.html?error=") {
+		t.Errorf("callback of a disallowed identity redirected to %q", loc)
+	}
+
+	// So are emails the provider hasn't verified
+	issuer.email = "alice@example.com"
+	issuer.unverified = true
+	w = oidcLoginFlow(t, am)
+	if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/auth.html?error=") {
+		t.Errorf("callback of an unverified email redirected to %q", loc)
+	}
+}
+
+func TestOIDCLogin_AllowUnverifiedEmails(t *testing.T) {
+	issuer := newMockOIDCIssuer(t, "mitto")
+	am := newOIDCTestAuthManager(t, issuer, &config.WebAuth{
+		OIDC: &config.OIDCAuth{AllowedEmails: []string{"@example.com"}, AllowUnverifiedEmails: true},
+	})
+
+	issuer.email = "alice@example.com"
+	issuer.unverified = true
+	w := oidcLoginFlow(t, am)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/" {
+		t.Errorf("callback = %d %q, want a redirect to /", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestOIDCLogin_Users(t *testing.T) {
+	issuer := newMockOIDCIssuer(t, "mitto")
+	am := newOIDCTestAuthManager(t, issuer, &config.WebAuth{
+		OIDC: &config.OIDCAuth{},
+		Users: []config.WebUser{
+			{Username: "bob", Email: "Bob@example.com", Role: config.RoleOperator},
+		},
+	})
+
+	issuer.email = "bob@example.com"
+	w := oidcLoginFlow(t, am)
+	var username string
+	for _, c := range w.Result().Cookies() {
+		if session, ok := am.ValidateSession(c.Value); ok && c.Name == sessionCookieName {
+			username = session.Username
+		}
+	}
+	if username != "bob" {
+		t.Errorf("session username = %q, want %q", username, "bob")
+	}
+
+	issuer.email = "carol@example.com"
+	w = oidcLoginFlow(t, am)
+	if loc := w.Header().Get("Location"); !strings.Contains(loc, url.QueryEscape("no user account")) {
+		t.Errorf("callback of an identity without user account redirected to %q", loc)
+	}
+}
+
+func TestOIDCCallback_InvalidState(t *testing.T) {
+	issuer := newMockOIDCIssuer(t, "mitto")
+	am := newOIDCTestAuthManager(t, issuer, &config.WebAuth{
+		OIDC: &config.OIDCAuth{AllowedGroups: []string{"dev"}},
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://localhost:8080/mitto"+oidcCallbackPath+"?code=x&state=forged", nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookieName, Value: "other"})
+	am.HandleOIDCCallback(w, req)
+	if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/auth.html?error=") {
+		t.Errorf("callback with a forged state redirected to %q", loc)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			t.Error("callback with a forged state set a session cookie")
+		}
+	}
+}
+
+func TestHandleAuthInfo_OIDC(t *testing.T) {
+	issuer := newMockOIDCIssuer(t, "mitto")
+	am := newOIDCTestAuthManager(t, issuer, &config.WebAuth{
+		OIDC: &config.OIDCAuth{AllowedEmails: []string{"alice@example.com"}},
+	})
+	s := &Server{authManager: am}
+
+	w := httptest.NewRecorder()
+	s.HandleAuthInfo(w, httptest.NewRequest("GET", "/api/auth-info", nil))
+	var info map[string]bool
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !info["oidc"] || info["simple"] {
+		t.Errorf("auth info = %v", info)
+	}
+}
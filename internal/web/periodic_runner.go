@@ -1,15 +1,20 @@
 package web
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/inercia/mitto/internal/config"
 	"github.com/inercia/mitto/internal/session"
+	"github.com/inercia/mitto/internal/worktree"
 )
 
 const (
@@ -45,11 +50,16 @@ type PeriodicAutoStoppedCallback func(sessionID string, periodic *session.Period
 // PromptResolverFunc resolves a prompt name to its full text for a given working directory.
 type PromptResolverFunc func(promptName string, workingDir string) (string, error)
 
+// PromptTransformFunc rewrites the resolved periodic prompt before it is delivered
+// (e.g. to render a callback payload into it).
+type PromptTransformFunc func(prompt string) (string, error)
+
 // PeriodicRunner manages scheduled periodic prompt delivery and session housekeeping.
 // It polls all sessions at regular intervals and:
 // - Delivers periodic prompts that are due
 // - Auto-archives sessions inactive beyond the configured threshold
 // - Cleans up archived sessions past their retention period
+// - Removes the git worktrees of deleted sessions
 type PeriodicRunner struct {
 	store          *session.Store
 	sessionManager *SessionManager
@@ -85,6 +95,13 @@ type PeriodicRunner struct {
 	// promptResolver resolves a prompt name to its text at execution time.
 	promptResolver PromptResolverFunc
 
+	// usageBudget pauses scheduled deliveries while a usage budget is exceeded (optional).
+	usageBudget *UsageBudget
+
+	// worktreesDir, when non-empty, is the directory of per-session git worktrees:
+	// worktrees of sessions that no longer exist are removed during each poll cycle.
+	worktreesDir string
+
 	// maxPeriodicIterations is the user-configured default cap on scheduled
 	// periodic runs. 0 means unlimited; the hardcoded backstop still applies.
 	maxPeriodicIterations int
@@ -177,6 +194,21 @@ func (r *PeriodicRunner) SetPromptResolver(resolver PromptResolverFunc) {
 	r.promptResolver = resolver
 }
 
+// SetUsageBudget sets the usage budget checked before delivering scheduled prompts.
+// While a budget is exceeded, due prompts and scheduled queue messages are held back
+// (and delivered once the budget allows it again); housekeeping still runs.
+func (r *PeriodicRunner) SetUsageBudget(budget *UsageBudget) {
+	r.usageBudget = budget
+}
+
+// SetWorktreesDir sets the directory of per-session git worktrees to clean up.
+// Pass an empty string to disable worktree cleanup.
+func (r *PeriodicRunner) SetWorktreesDir(dir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.worktreesDir = dir
+}
+
 // Start begins the periodic polling loop in a background goroutine.
 // It returns immediately. Call Stop() to stop the runner.
 func (r *PeriodicRunner) Start() {
@@ -233,6 +265,12 @@ func (r *PeriodicRunner) IsRunning() bool {
 //
 // Returns an error if the delivery fails or the session is not configured for periodic prompts.
 func (r *PeriodicRunner) TriggerNow(sessionID string, resetTimer bool) error {
+	return r.TriggerNowWithPrompt(sessionID, resetTimer, nil)
+}
+
+// TriggerNowWithPrompt is like TriggerNow, but the periodic prompt is passed
+// through transform (when not nil) before being delivered.
+func (r *PeriodicRunner) TriggerNowWithPrompt(sessionID string, resetTimer bool, transform PromptTransformFunc) error {
 	if r.store == nil {
 		return ErrSessionStoreNotAvailable
 	}
@@ -295,7 +333,7 @@ func (r *PeriodicRunner) TriggerNow(sessionID string, resetTimer bool) error {
 	}
 
 	// Deliver the prompt
-	return r.deliverPrompt(bs, meta.Name, periodic, periodicStore, resetTimer, true)
+	return r.deliverPrompt(bs, meta.Name, periodic, periodicStore, resetTimer, true, transform)
 }
 
 // pollLoop is the main polling loop that checks for due prompts.
@@ -369,11 +407,20 @@ func (r *PeriodicRunner) RunOnce() (delivered, skipped, errored int) {
 		return pi.Before(*pj) // most overdue (earliest NextScheduledAt) first
 	})
 
+	// Hold back scheduled deliveries while a usage budget is exceeded.
+	deliverable := sessions
+	if exceeded, reason := r.usageBudget.Exceeded(); exceeded {
+		if r.logger != nil {
+			r.logger.Info("Usage budget exceeded, pausing scheduled prompts", "reason", reason)
+		}
+		deliverable = nil
+	}
+
 	// Collect sessions that have due periodic prompts and need resuming.
 	// Process them with stagger delay to prevent thundering herd.
 	var lastResumeTime time.Time
 
-	for _, meta := range sessions {
+	for _, meta := range deliverable {
 		// Apply stagger delay between resume-triggering periodic checks.
 		// Only stagger when we actually resumed a session in a previous iteration.
 		if r.resumeStagger > 0 && !lastResumeTime.IsZero() {
@@ -401,7 +448,7 @@ func (r *PeriodicRunner) RunOnce() (delivered, skipped, errored int) {
 	}
 
 	// Check scheduled queue messages across all active sessions
-	r.checkScheduledQueues(sessions)
+	r.checkScheduledQueues(deliverable)
 
 	// Auto-archive inactive sessions
 	r.checkAutoArchive(sessions, now)
@@ -409,6 +456,13 @@ func (r *PeriodicRunner) RunOnce() (delivered, skipped, errored int) {
 	// Clean up archived sessions past retention
 	r.checkArchiveCleanup()
 
+	// Remove worktrees left behind by deleted sessions
+	r.checkWorktreeCleanup(sessions)
+
+	metricPeriodicRuns.Add(float64(delivered), "delivered")
+	metricPeriodicRuns.Add(float64(skipped), "skipped")
+	metricPeriodicRuns.Add(float64(errored), "errored")
+
 	if r.logger != nil {
 		r.logger.Debug("Periodic poll completed",
 			"delivered", delivered,
@@ -432,7 +486,7 @@ func (r *PeriodicRunner) sessionNeedsResume(meta session.Metadata, now time.Time
 		return false
 	}
 
-	if periodic.NextScheduledAt == nil || periodic.NextScheduledAt.After(now) {
+	if periodic.NextScheduledAt == nil || periodic.NextScheduledAt.After(now) || periodic.InBlackout(now) {
 		return false
 	}
 
@@ -526,11 +580,31 @@ func (r *PeriodicRunner) checkSession(meta session.Metadata, now time.Time) (del
 	scheduledAt := *periodic.NextScheduledAt
 	overdueBy := now.Sub(scheduledAt)
 
+	// A run that became due inside a blackout window (e.g. while Mitto was not
+	// running) is not sent: it is moved to the first fire time after the window.
+	if periodic.InBlackout(now) {
+		rescheduled, err := periodicStore.Reschedule()
+		if err != nil {
+			if r.logger != nil {
+				r.logger.Error("Failed to reschedule periodic prompt in blackout window",
+					"session_id", sessionID,
+					"error", err)
+			}
+			return 0, 0, 1
+		}
+		if r.logger != nil {
+			r.logger.Debug("Periodic prompt due in blackout window - rescheduled",
+				"session_id", sessionID,
+				"scheduled_at", scheduledAt,
+				"next_scheduled_at", rescheduled.NextScheduledAt)
+		}
+		return 0, 1, 0
+	}
+
 	// Calculate how many runs were missed (for logging purposes)
 	missedRuns := 0
-	if overdueBy > 0 && periodic.Frequency.Duration() > 0 {
-		// Number of full intervals that passed since scheduled time
-		missedRuns = int(overdueBy / periodic.Frequency.Duration())
+	if overdueBy > 0 {
+		missedRuns = periodic.MissedRuns(scheduledAt, now)
 	}
 
 	// Log the catch-up situation
@@ -653,7 +727,7 @@ func (r *PeriodicRunner) checkSession(meta session.Metadata, now time.Time) (del
 	}
 
 	// Deliver the prompt — normal scheduled runs always reset the timer.
-	if err := r.deliverPrompt(bs, meta.Name, periodic, periodicStore, true, false); err != nil {
+	if err := r.deliverPrompt(bs, meta.Name, periodic, periodicStore, true, false, nil); err != nil {
 		if r.logger != nil {
 			r.logger.Error("Failed to deliver periodic prompt",
 				"session_id", sessionID,
@@ -669,27 +743,23 @@ func (r *PeriodicRunner) checkSession(meta session.Metadata, now time.Time) (del
 // resetTimer controls whether RecordSent() is called when the prompt completes:
 //   - true  → schedule advances from now (normal behaviour)
 //   - false → schedule is left untouched (manual "run now" without resetting the timer)
-func (r *PeriodicRunner) deliverPrompt(bs *BackgroundSession, sessionName string, periodic *session.PeriodicPrompt, periodicStore *session.PeriodicStore, resetTimer bool, forced bool) error {
+//
+// transform, when not nil, rewrites the resolved prompt text before delivery.
+func (r *PeriodicRunner) deliverPrompt(bs *BackgroundSession, sessionName string, periodic *session.PeriodicPrompt, periodicStore *session.PeriodicStore, resetTimer bool, forced bool, transform PromptTransformFunc) error {
 	sessionID := bs.GetSessionID()
 
-	// Resolve prompt text from name if needed
-	promptText := periodic.Prompt
-	if periodic.PromptName != "" && r.promptResolver != nil {
-		sessionMeta, err := r.store.GetMetadata(sessionID)
-		if err != nil {
-			return fmt.Errorf("failed to get session metadata for prompt resolution: %w", err)
-		}
-		resolved, err := r.promptResolver(periodic.PromptName, sessionMeta.WorkingDir)
-		if err != nil {
-			return fmt.Errorf("failed to resolve prompt %q: %w", periodic.PromptName, err)
-		}
-		promptText = resolved
-		if r.logger != nil {
-			r.logger.Debug("Resolved periodic prompt name to text",
-				"session_id", sessionID,
-				"prompt_name", periodic.PromptName,
-				"prompt_preview", truncatePrompt(promptText, 100))
+	promptText, err := r.ResolvePrompt(sessionID, periodic)
+	if err != nil {
+		return err
+	}
+
+	// The prompt name badge only makes sense for the unmodified prompt
+	promptName := periodic.PromptName
+	if transform != nil {
+		if promptText, err = transform(promptText); err != nil {
+			return err
 		}
+		promptName = ""
 	}
 
 	if r.logger != nil {
@@ -706,8 +776,8 @@ func (r *PeriodicRunner) deliverPrompt(bs *BackgroundSession, sessionName string
 	// (e.g., ACP process crash).
 	meta := PromptMeta{
 		SenderID:         "periodic-runner",
-		PromptID:         "",                  // No client to confirm delivery to
-		PromptName:       periodic.PromptName, // Pass prompt name so UI can render a badge instead of full text
+		PromptID:         "",         // No client to confirm delivery to
+		PromptName:       promptName, // Pass prompt name so UI can render a badge instead of full text
 		IsPeriodicForced: forced,
 		FreshContext:     periodic.FreshContext,
 		OnComplete: func(err error) {
@@ -765,7 +835,7 @@ func (r *PeriodicRunner) deliverPrompt(bs *BackgroundSession, sessionName string
 							}
 						}
 						disabled := false
-						if disableErr := periodicStore.Update(nil, nil, nil, &disabled, nil, nil); disableErr != nil {
+						if disableErr := periodicStore.Update(nil, nil, nil, &disabled, nil, nil, nil); disableErr != nil {
 							if r.logger != nil {
 								r.logger.Warn("Failed to disable periodic after reaching iteration cap",
 									"session_id", sessionID,
@@ -801,6 +871,29 @@ func (r *PeriodicRunner) deliverPrompt(bs *BackgroundSession, sessionName string
 	return nil
 }
 
+// ResolvePrompt returns the text of a periodic prompt, resolving its prompt
+// name (if any) for the working directory of the session.
+func (r *PeriodicRunner) ResolvePrompt(sessionID string, periodic *session.PeriodicPrompt) (string, error) {
+	if periodic.PromptName == "" || r.promptResolver == nil {
+		return periodic.Prompt, nil
+	}
+	sessionMeta, err := r.store.GetMetadata(sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get session metadata for prompt resolution: %w", err)
+	}
+	resolved, err := r.promptResolver(periodic.PromptName, sessionMeta.WorkingDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve prompt %q: %w", periodic.PromptName, err)
+	}
+	if r.logger != nil {
+		r.logger.Debug("Resolved periodic prompt name to text",
+			"session_id", sessionID,
+			"prompt_name", periodic.PromptName,
+			"prompt_preview", truncatePrompt(resolved, 100))
+	}
+	return resolved, nil
+}
+
 // truncatePrompt truncates a string to maxLen characters, adding "..." if truncated.
 func truncatePrompt(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -954,3 +1047,69 @@ func (r *PeriodicRunner) checkArchiveCleanup() {
 			"retention_period", retentionPeriod)
 	}
 }
+
+// worktreeCleanupGrace protects worktrees of sessions being created, which exist
+// on disk shortly before the session metadata references them.
+const worktreeCleanupGrace = 10 * time.Minute
+
+// checkWorktreeCleanup removes the git worktrees whose session no longer exists.
+// The session branches are kept, so that no committed work is lost.
+func (r *PeriodicRunner) checkWorktreeCleanup(sessions []session.Metadata) {
+	r.mu.Lock()
+	root := r.worktreesDir
+	r.mu.Unlock()
+
+	if root == "" {
+		return
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+
+	inUse := make(map[string]bool)
+	for _, meta := range sessions {
+		if meta.Worktree != nil {
+			inUse[filepath.Clean(meta.Worktree.Path)] = true
+		}
+	}
+	// Running sessions keep their worktree even if their metadata doesn't
+	// record it (yet)
+	if r.sessionManager != nil {
+		for _, sessionID := range r.sessionManager.ListRunningSessions() {
+			bs := r.sessionManager.GetSession(sessionID)
+			if bs == nil {
+				continue
+			}
+			rel, err := filepath.Rel(root, bs.GetWorkingDir())
+			if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			inUse[filepath.Join(root, strings.SplitN(rel, string(filepath.Separator), 2)[0])] = true
+		}
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(root, entry.Name())
+		if !entry.IsDir() || inUse[path] {
+			continue
+		}
+		if info, err := entry.Info(); err != nil || time.Since(info.ModTime()) < worktreeCleanupGrace {
+			continue
+		}
+		repoDir := worktree.RepoDirOf(path)
+		if repoDir == "" {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), worktreeActionTimeout)
+		err := worktree.Remove(ctx, repoDir, path)
+		cancel()
+		if r.logger != nil {
+			if err != nil {
+				r.logger.Warn("Failed to remove orphaned worktree", "path", path, "error", err)
+			} else {
+				r.logger.Info("Removed orphaned worktree", "path", path, "repo_dir", repoDir)
+			}
+		}
+	}
+}
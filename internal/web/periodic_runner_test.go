@@ -750,7 +750,7 @@ func TestPeriodicRunner_ConfigCapAutoStop(t *testing.T) {
 	})
 
 	disabled := false
-	if err := periodicStore.Update(nil, nil, nil, &disabled, nil, nil); err != nil {
+	if err := periodicStore.Update(nil, nil, nil, &disabled, nil, nil, nil); err != nil {
 		t.Fatalf("periodicStore.Update(disable) error = %v", err)
 	}
 
@@ -794,3 +794,58 @@ func TestPeriodicRunner_DefaultMaxPeriodicIterations(t *testing.T) {
 			got, config.DefaultMaxPeriodicIterations)
 	}
 }
+
+func TestPeriodicRunner_RunOnceReschedulesInBlackout(t *testing.T) {
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	defer store.Close()
+
+	meta := session.Metadata{
+		SessionID:  "blackout-session",
+		ACPServer:  "test",
+		WorkingDir: "/tmp",
+	}
+	if err := store.Create(meta); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Overdue, but now is inside a blackout window
+	now := time.Now().UTC()
+	past := now.Add(-10 * time.Minute)
+	end := now.Add(time.Hour)
+	p := &session.PeriodicPrompt{
+		Prompt:    "Test prompt",
+		Frequency: session.Frequency{Value: 1, Unit: session.FrequencyHours},
+		Enabled:   true,
+		Schedule: session.Schedule{Blackouts: []session.BlackoutWindow{{
+			Start: now.Add(-time.Hour).Format("15:04"),
+			End:   end.Format("15:04"),
+		}}},
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		NextScheduledAt: &past,
+	}
+	path := filepath.Join(store.SessionDir("blackout-session"), "periodic.json")
+	if err := writeTestPeriodicFile(path, p); err != nil {
+		t.Fatalf("writeTestPeriodicFile() error = %v", err)
+	}
+
+	runner := NewPeriodicRunner(store, nil, nil)
+	delivered, skipped, errored := runner.RunOnce()
+	if delivered != 0 || skipped != 1 || errored != 0 {
+		t.Errorf("RunOnce() = (%d, %d, %d), want (0, 1, 0)", delivered, skipped, errored)
+	}
+
+	got, err := store.Periodic("blackout-session").Get()
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.NextScheduledAt == nil || got.NextScheduledAt.Before(end.Truncate(time.Minute)) {
+		t.Errorf("NextScheduledAt = %v, want the end of the blackout window (%s)", got.NextScheduledAt, end.Format("15:04"))
+	}
+	if got.IterationCount != 0 {
+		t.Errorf("IterationCount = %d, want 0", got.IterationCount)
+	}
+}
@@ -0,0 +1,265 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coder/acp-go-sdk"
+
+	"github.com/inercia/mitto/internal/audit"
+)
+
+// Permission decision actions of POST /api/permissions/{token}.
+const (
+	PermissionActionApprove = "approve"
+	PermissionActionDeny    = "deny"
+)
+
+// PendingPermission is a permission request waiting for a decision.
+type PendingPermission struct {
+	// Token is the approval token of the request, used in /api/permissions/{token}.
+	Token       string           `json:"token"`
+	SessionID   string           `json:"session_id"`
+	SessionName string           `json:"session_name,omitempty"`
+	RequestID   string           `json:"request_id"`
+	Title       string           `json:"title"`
+	Description string           `json:"description,omitempty"`
+	Options     []UIPromptOption `json:"options"`
+	// ExpiresAt is when the token expires.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PendingPermissionsResponse is the response of GET /api/permissions.
+type PendingPermissionsResponse struct {
+	Permissions []PendingPermission `json:"permissions"`
+}
+
+// PermissionDecisionRequest is the body of POST /api/permissions/{token}.
+// Either Action or OptionID must be set.
+type PermissionDecisionRequest struct {
+	// Action is "approve" (the agent's allow option) or "deny" (its reject option).
+	Action string `json:"action,omitempty"`
+	// OptionID selects one of the request options explicitly.
+	OptionID string `json:"option_id,omitempty"`
+}
+
+// PermissionDecisionResponse is the response of POST /api/permissions/{token}.
+type PermissionDecisionResponse struct {
+	// Status is "approved" or "denied".
+	Status    string `json:"status"`
+	SessionID string `json:"session_id"`
+	RequestID string `json:"request_id"`
+	// OptionID is the option selected (empty when the request was cancelled
+	// because the agent offered no reject option).
+	OptionID string `json:"option_id,omitempty"`
+}
+
+// handlePermissions handles GET /api/permissions
+// It lists the permission requests waiting for a decision, with their approval tokens.
+func (s *Server) handlePermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	user := webUserFromContext(r.Context())
+	permissions := []PendingPermission{}
+	if s.sessionManager != nil {
+		for _, sessionID := range s.sessionManager.ListRunningSessions() {
+			if !s.webUserCanAccessSession(user, sessionID) {
+				continue
+			}
+			bs := s.sessionManager.GetSession(sessionID)
+			if bs == nil {
+				continue
+			}
+			req := pendingPermissionRequest(bs)
+			if req == nil {
+				continue
+			}
+			token, expiresAt := s.permissionTokens.issue(sessionID, req.RequestID)
+			permissions = append(permissions, s.pendingPermission(sessionID, req, token, expiresAt))
+		}
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].SessionID < permissions[j].SessionID
+	})
+	writeJSONOK(w, PendingPermissionsResponse{Permissions: permissions})
+}
+
+// handlePermissionToken handles /api/permissions/{token}
+//   - GET: the permission request of the token
+//   - POST: approve or deny the request (PermissionDecisionRequest)
+func (s *Server) handlePermissionToken(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, s.apiPrefix+"/api/permissions/")
+	if token == "" || strings.Contains(token, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		methodNotAllowed(w)
+		return
+	}
+
+	claims, err := s.permissionTokens.Verify(token)
+	if err != nil {
+		if errors.Is(err, ErrPermissionTokenExpired) {
+			writeErrorJSON(w, http.StatusGone, "token_expired", "The approval token has expired")
+			return
+		}
+		writeErrorJSON(w, http.StatusNotFound, "invalid_token", "Invalid approval token")
+		return
+	}
+	if !s.webUserCanAccessSession(webUserFromContext(r.Context()), claims.SessionID) {
+		writeErrorJSON(w, http.StatusNotFound, "invalid_token", "Invalid approval token")
+		return
+	}
+
+	var bs *BackgroundSession
+	if s.sessionManager != nil {
+		bs = s.sessionManager.GetSession(claims.SessionID)
+	}
+	var req *UIPromptRequest
+	if bs != nil {
+		req = pendingPermissionRequest(bs)
+	}
+	if req == nil || req.RequestID != claims.RequestID {
+		writeErrorJSON(w, http.StatusNotFound, "permission_not_pending", "The permission request is no longer pending")
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		writeJSONOK(w, s.pendingPermission(claims.SessionID, req, token, claims.ExpiresAt))
+		return
+	}
+
+	var decision PermissionDecisionRequest
+	if !parseJSONBody(w, r, &decision) {
+		return
+	}
+	option, ok, err := selectPermissionOption(req.Options, decision)
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, "invalid_decision", err.Error())
+		return
+	}
+
+	resp := PermissionDecisionResponse{
+		Status:    "denied",
+		SessionID: claims.SessionID,
+		RequestID: req.RequestID,
+	}
+	actor := requestActor(r)
+	if !ok {
+		// No reject option: cancel the request
+		bs.DismissPrompt(req.RequestID)
+		auditPermissionAnswer(actor, claims.SessionID, req, nil, "approval_token")
+	} else {
+		bs.HandleUIPromptAnswer(req.RequestID, option.ID, option.Label, "")
+		auditPermissionAnswer(actor, claims.SessionID, req, &option, "approval_token")
+		resp.OptionID = option.ID
+		if isAllowPermissionKind(option.Kind) {
+			resp.Status = "approved"
+		}
+	}
+	if bs.logger != nil {
+		bs.logger.Info("permission_answered_with_token",
+			"request_id", req.RequestID,
+			"status", resp.Status,
+			"option_id", resp.OptionID,
+			"client_ip", getClientIPWithProxyCheck(r))
+	}
+	writeJSONOK(w, resp)
+}
+
+// pendingPermission builds the PendingPermission of a permission request.
+func (s *Server) pendingPermission(sessionID string, req *UIPromptRequest, token string, expiresAt time.Time) PendingPermission {
+	p := PendingPermission{
+		Token:       token,
+		SessionID:   sessionID,
+		RequestID:   req.RequestID,
+		Title:       req.Title,
+		Description: req.Description,
+		Options:     req.Options,
+		ExpiresAt:   expiresAt,
+	}
+	if store := s.Store(); store != nil {
+		if meta, err := store.GetMetadata(sessionID); err == nil {
+			p.SessionName = meta.Name
+		}
+	}
+	return p
+}
+
+// auditPermissionAnswer records the answer of a user to a permission request
+// in the audit log. A nil option denies the request.
+func auditPermissionAnswer(actor audit.Actor, sessionID string, req *UIPromptRequest, option *UIPromptOption, via string) {
+	outcome, optionID := audit.OutcomeDenied, ""
+	if option != nil {
+		optionID = option.ID
+		if isAllowPermissionKind(option.Kind) {
+			outcome = audit.OutcomeSuccess
+		}
+	}
+	audit.Record(audit.Event{
+		Actor:   actor,
+		Action:  audit.ActionPermissionAnswer,
+		Target:  sessionID,
+		Outcome: outcome,
+		Details: map[string]string{"title": req.Title, "option": optionID, "via": via},
+	})
+}
+
+// pendingPermissionRequest returns the permission request a session is blocked on, if any.
+func pendingPermissionRequest(bs *BackgroundSession) *UIPromptRequest {
+	req := bs.GetActiveUIPrompt()
+	if req == nil || req.Type != UIPromptTypePermission {
+		return nil
+	}
+	return req
+}
+
+// selectPermissionOption returns the option selected by a decision.
+// It returns false, without error, when denying a request that has no reject
+// option (the request should then be cancelled).
+func selectPermissionOption(options []UIPromptOption, decision PermissionDecisionRequest) (UIPromptOption, bool, error) {
+	if decision.OptionID != "" {
+		for _, opt := range options {
+			if opt.ID == decision.OptionID {
+				return opt, true, nil
+			}
+		}
+		return UIPromptOption{}, false, errors.New("unknown option: " + decision.OptionID)
+	}
+
+	var kinds []acp.PermissionOptionKind
+	switch decision.Action {
+	case PermissionActionApprove:
+		kinds = []acp.PermissionOptionKind{acp.PermissionOptionKindAllowOnce, acp.PermissionOptionKindAllowAlways}
+	case PermissionActionDeny:
+		kinds = []acp.PermissionOptionKind{acp.PermissionOptionKindRejectOnce, acp.PermissionOptionKindRejectAlways}
+	default:
+		return UIPromptOption{}, false, errors.New(`action must be "approve" or "deny", or option_id must be set`)
+	}
+	for _, kind := range kinds {
+		for _, opt := range options {
+			if opt.Kind == string(kind) {
+				return opt, true, nil
+			}
+		}
+	}
+	if decision.Action == PermissionActionApprove {
+		return UIPromptOption{}, false, errors.New("the request has no allow option")
+	}
+	return UIPromptOption{}, false, nil
+}
+
+// isAllowPermissionKind reports whether a permission option kind grants the permission.
+func isAllowPermissionKind(kind string) bool {
+	return kind == string(acp.PermissionOptionKindAllowOnce) || kind == string(acp.PermissionOptionKindAllowAlways)
+}
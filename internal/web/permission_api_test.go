@@ -0,0 +1,224 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPermissionTokens(t *testing.T) {
+	pt := NewPermissionTokens(time.Minute)
+	token := pt.Issue("20260101-120000-abcd1234", "tool-1")
+
+	claims, err := pt.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims.SessionID != "20260101-120000-abcd1234" || claims.RequestID != "tool-1" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// Tampered payload
+	payload, sig, _ := strings.Cut(token, ".")
+	if _, err := pt.Verify(payload[:len(payload)-2] + "AA." + sig); !errors.Is(err, ErrInvalidPermissionToken) {
+		t.Errorf("Verify(tampered) = %v, want ErrInvalidPermissionToken", err)
+	}
+	// Signed with another key
+	if _, err := NewPermissionTokens(time.Minute).Verify(token); !errors.Is(err, ErrInvalidPermissionToken) {
+		t.Errorf("Verify(other key) = %v, want ErrInvalidPermissionToken", err)
+	}
+	if _, err := pt.Verify("garbage"); !errors.Is(err, ErrInvalidPermissionToken) {
+		t.Errorf("Verify(garbage) = %v, want ErrInvalidPermissionToken", err)
+	}
+
+	pt.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := pt.Verify(token); !errors.Is(err, ErrPermissionTokenExpired) {
+		t.Errorf("Verify(expired) = %v, want ErrPermissionTokenExpired", err)
+	}
+
+	var nilTokens *PermissionTokens
+	if nilTokens.Issue("s", "r") != "" {
+		t.Error("nil PermissionTokens should issue empty tokens")
+	}
+}
+
+// newPermissionTestServer returns a server with a session blocked on a permission request.
+func newPermissionTestServer(t *testing.T, options []UIPromptOption) (*Server, chan UIPromptResponse) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	const sessionID = "20260101-120000-abcd1234"
+	bs := &BackgroundSession{
+		observers:   make(map[SessionObserver]struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+		persistedID: sessionID,
+	}
+	sm := NewSessionManager("", "", false, nil)
+	sm.sessions[sessionID] = bs
+	s := &Server{
+		sessionManager:   sm,
+		apiPrefix:        "/mitto",
+		permissionTokens: NewPermissionTokens(time.Minute),
+	}
+
+	respCh := make(chan UIPromptResponse, 1)
+	go func() {
+		resp, _ := bs.UIPrompt(ctx, UIPromptRequest{
+			RequestID:      "tool-1",
+			Type:           UIPromptTypePermission,
+			Title:          "Run go test ./...",
+			Options:        options,
+			TimeoutSeconds: 10,
+			Blocking:       true,
+		})
+		respCh <- resp
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for bs.GetActiveUIPrompt() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("permission prompt not started")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return s, respCh
+}
+
+var testPermissionOptions = []UIPromptOption{
+	{ID: "allow", Label: "Allow", Kind: "allow_once"},
+	{ID: "always", Label: "Always allow", Kind: "allow_always"},
+	{ID: "reject", Label: "Reject", Kind: "reject_once"},
+}
+
+func listPendingPermissions(t *testing.T, s *Server) []PendingPermission {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.handlePermissions(w, httptest.NewRequest(http.MethodGet, "/mitto/api/permissions", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: status = %d", w.Code)
+	}
+	var resp PendingPermissionsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return resp.Permissions
+}
+
+func TestHandlePermissionToken_Approve(t *testing.T) {
+	s, respCh := newPermissionTestServer(t, testPermissionOptions)
+
+	pending := listPendingPermissions(t, s)
+	if len(pending) != 1 || pending[0].RequestID != "tool-1" || pending[0].Title != "Run go test ./..." || pending[0].Token == "" {
+		t.Fatalf("pending = %+v", pending)
+	}
+	path := "/mitto/api/permissions/" + pending[0].Token
+
+	w := httptest.NewRecorder()
+	s.handlePermissionToken(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"request_id":"tool-1"`) {
+		t.Fatalf("get: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.handlePermissionToken(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"action":"approve"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("approve: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var decision PermissionDecisionResponse
+	if err := json.NewDecoder(w.Body).Decode(&decision); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if decision.Status != "approved" || decision.OptionID != "allow" {
+		t.Errorf("decision = %+v", decision)
+	}
+
+	select {
+	case resp := <-respCh:
+		if resp.OptionID != "allow" || resp.TimedOut {
+			t.Errorf("prompt response = %+v", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("permission prompt not answered")
+	}
+
+	// The request is no longer pending
+	w = httptest.NewRecorder()
+	s.handlePermissionToken(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"action":"approve"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("second approve: status = %d, want 404", w.Code)
+	}
+}
+
+func TestHandlePermissionToken_Deny(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     []UIPromptOption
+		body        string
+		wantCode    int
+		wantOption  string
+		wantTimeout bool
+	}{
+		{"reject option", testPermissionOptions, `{"action":"deny"}`, http.StatusOK, "reject", false},
+		{"explicit option", testPermissionOptions, `{"option_id":"always"}`, http.StatusOK, "always", false},
+		{"no reject option cancels", testPermissionOptions[:1], `{"action":"deny"}`, http.StatusOK, "", true},
+		{"unknown option", testPermissionOptions, `{"option_id":"nope"}`, http.StatusBadRequest, "", false},
+		{"unknown action", testPermissionOptions, `{"action":"maybe"}`, http.StatusBadRequest, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, respCh := newPermissionTestServer(t, tt.options)
+			token := s.permissionTokens.Issue("20260101-120000-abcd1234", "tool-1")
+
+			w := httptest.NewRecorder()
+			s.handlePermissionToken(w, httptest.NewRequest(http.MethodPost, "/mitto/api/permissions/"+token, strings.NewReader(tt.body)))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body = %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			select {
+			case resp := <-respCh:
+				if resp.OptionID != tt.wantOption || resp.TimedOut != tt.wantTimeout {
+					t.Errorf("prompt response = %+v", resp)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("permission prompt not answered")
+			}
+		})
+	}
+}
+
+func TestHandlePermissionToken_InvalidTokens(t *testing.T) {
+	s, _ := newPermissionTestServer(t, testPermissionOptions)
+
+	expired := NewPermissionTokens(time.Minute)
+	expired.key = s.permissionTokens.key
+	expired.now = func() time.Time { return time.Now().Add(-time.Hour) }
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{"forged", "abc.def", http.StatusNotFound},
+		{"expired", expired.Issue("20260101-120000-abcd1234", "tool-1"), http.StatusGone},
+		{"other request", s.permissionTokens.Issue("20260101-120000-abcd1234", "tool-2"), http.StatusNotFound},
+		{"other session", s.permissionTokens.Issue("20260101-120000-ffff0000", "tool-1"), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handlePermissionToken(w, httptest.NewRequest(http.MethodGet, "/mitto/api/permissions/"+tt.token, nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package web
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/coder/acp-go-sdk"
+
+	"github.com/inercia/mitto/internal/audit"
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+// toolCallPathKeys are the raw input keys holding the file paths a tool works on.
+var toolCallPathKeys = []string{
+	"path", "paths", "file_path", "filePath", "file_paths", "notebook_path",
+	"abs_path", "old_path", "new_path", "source", "destination", "target",
+}
+
+// toolCallCommandKeys are the raw input keys holding the command line a tool runs.
+var toolCallCommandKeys = []string{"command", "cmd", "commandLine", "script"}
+
+// toolCallContext extracts the CEL context of the tool call of a permission request.
+// Relative paths are resolved against workingDir.
+func toolCallContext(tc acp.ToolCallUpdate, workingDir string) config.ToolCallContext {
+	ctx := config.ToolCallContext{ID: string(tc.ToolCallId)}
+	if tc.Kind != nil {
+		ctx.Kind = string(*tc.Kind)
+	}
+	if tc.Title != nil {
+		ctx.Title = *tc.Title
+	}
+
+	seen := make(map[string]bool)
+	addPath := func(p string) {
+		if p == "" {
+			return
+		}
+		if !filepath.IsAbs(p) && workingDir != "" {
+			p = filepath.Join(workingDir, p)
+		}
+		p = filepath.Clean(p)
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		ctx.Paths = append(ctx.Paths, p)
+	}
+	for _, loc := range tc.Locations {
+		addPath(loc.Path)
+	}
+
+	if input, ok := tc.RawInput.(map[string]any); ok {
+		ctx.Input = input
+		for _, key := range toolCallPathKeys {
+			for _, p := range rawInputStrings(input[key]) {
+				addPath(p)
+			}
+		}
+		for _, key := range toolCallCommandKeys {
+			if cmd := rawInputStrings(input[key]); len(cmd) > 0 {
+				ctx.Command = strings.Join(cmd, " ")
+				break
+			}
+		}
+	}
+
+	for _, p := range ctx.Paths {
+		if !isPathWithin(workingDir, p) {
+			ctx.OutsideWorkspace = true
+			break
+		}
+	}
+	return ctx
+}
+
+// rawInputStrings returns the strings of a raw input value (a string or a list of strings).
+func rawInputStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []any:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				result = append(result, s)
+			}
+		}
+		return result
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// isPathWithin reports whether path is dir or inside it.
+func isPathWithin(dir, path string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// permissionPolicy returns the permission rules for sessions of acpServer in the
+// workspace (which may be nil), or nil if there are no rules.
+func (sm *SessionManager) permissionPolicy(workspace *config.WorkspaceSettings, acpServer string) *config.PermissionPolicy {
+	var workspaceRules, agentRules, globalRules []config.PermissionRule
+	if workspace != nil {
+		workspaceRules = workspace.PermissionRules
+	}
+	if sm.mittoConfig != nil {
+		if srv, err := sm.mittoConfig.GetServer(acpServer); err == nil {
+			agentRules = srv.PermissionRules
+		}
+		if sm.mittoConfig.Permissions != nil {
+			globalRules = sm.mittoConfig.Permissions.Rules
+		}
+	}
+	policy := config.NewPermissionPolicy(workspaceRules, agentRules, globalRules)
+	if sm.runPermissions != nil {
+		policy = policy.Prepend(config.PermissionScopeRun, sm.runPermissions.Rules).
+			Append(config.PermissionScopeRun, sm.runPermissions.DefaultRules())
+	}
+	return policy
+}
+
+// permissionContext builds the CEL context for evaluating the permission rules
+// against a permission request of the session.
+func (bs *BackgroundSession) permissionContext(tc acp.ToolCallUpdate) *config.PromptEnabledContext {
+	ctx := &config.PromptEnabledContext{}
+	ctx.Workspace.UUID = bs.workspaceUUID
+	ctx.Workspace.Folder = bs.workingDir
+	ctx.ACP.AutoApprove = bs.autoApprove
+	for _, srv := range bs.availableACPServers {
+		if srv.Current {
+			ctx.ACP.Name = srv.Name
+			ctx.ACP.Type = srv.Type
+			ctx.ACP.Tags = srv.Tags
+			break
+		}
+	}
+
+	ctx.Session.ID = bs.persistedID
+	if bs.store != nil && bs.persistedID != "" {
+		if meta, err := bs.store.GetMetadata(bs.persistedID); err == nil {
+			ctx.Session.Name = meta.Name
+			ctx.Session.IsChild = meta.ParentSessionID != ""
+			ctx.Session.IsAutoChild = meta.ChildOrigin == session.ChildOriginAuto
+			ctx.Session.ParentID = meta.ParentSessionID
+			ctx.ACP.Name = meta.ACPServer
+			ctx.Permissions.AutoApprovePermissions = session.GetFlagValue(meta.AdvancedSettings, session.FlagAutoApprovePermissions)
+		}
+	}
+	if ctx.ACP.Type == "" {
+		ctx.ACP.Type = ctx.ACP.Name
+	}
+
+	ctx.ToolCall = toolCallContext(tc, bs.workingDir)
+	return ctx
+}
+
+// evaluatePermissionPolicy returns the decision of the session's permission rules
+// for a permission request (an empty decision if no rule matched).
+func (bs *BackgroundSession) evaluatePermissionPolicy(params acp.RequestPermissionRequest) config.PermissionDecision {
+	if bs.permissionPolicy == nil {
+		return config.PermissionDecision{}
+	}
+	decision := bs.permissionPolicy.Evaluate(bs.permissionContext(params.ToolCall))
+	if decision.Error != "" {
+		bs.logger.Warn("permission_rule_error",
+			"tool_call_id", params.ToolCall.ToolCallId,
+			"rule", decision.Rule,
+			"scope", decision.Scope,
+			"error", decision.Error)
+	}
+	return decision
+}
+
+// recordPermission records a permission event with the rule that decided it (if any).
+// The decisions taken without the user are also recorded in the audit log (the
+// answers of the user are recorded where they are received, with the user).
+func (bs *BackgroundSession) recordPermission(title, selectedOption, outcome string, decision config.PermissionDecision) {
+	if outcome != "user_selected" {
+		auditOutcome := audit.OutcomeSuccess
+		if outcome == "policy_denied" || outcome == "timed_out" {
+			auditOutcome = audit.OutcomeDenied
+		}
+		details := map[string]string{"title": title, "decision": outcome, "option": selectedOption}
+		if decision.Rule != "" {
+			details["rule"] = decision.Rule
+			details["rule_scope"] = decision.Scope
+		}
+		audit.Record(audit.Event{
+			Actor:   audit.Actor{Session: bs.persistedID},
+			Action:  audit.ActionPermissionDecide,
+			Target:  bs.persistedID,
+			Outcome: auditOutcome,
+			Details: details,
+		})
+	}
+
+	if bs.recorder == nil {
+		return
+	}
+	if err := bs.recorder.RecordPermissionData(session.PermissionData{
+		Title:          title,
+		SelectedOption: selectedOption,
+		Outcome:        outcome,
+		Rule:           decision.Rule,
+		RuleScope:      decision.Scope,
+		RuleError:      decision.Error,
+	}); err != nil {
+		bs.logger.Warn("Failed to persist permission", "error", err)
+	}
+}
+
+// PermissionDryRunRequest is the body of POST /api/permissions/dry-run.
+type PermissionDryRunRequest struct {
+	// SessionID evaluates the rules that apply to an existing conversation.
+	SessionID string `json:"session_id,omitempty"`
+	// WorkingDir and ACPServer evaluate the rules of a workspace, without a conversation.
+	WorkingDir string `json:"working_dir,omitempty"`
+	ACPServer  string `json:"acp_server,omitempty"`
+	// ToolCall is the tool call of the simulated permission request.
+	ToolCall acp.ToolCallUpdate `json:"tool_call"`
+	// Rules, when set, are evaluated instead of the configured rules.
+	Rules []config.PermissionRule `json:"rules,omitempty"`
+}
+
+// PermissionDryRunResponse is the response of POST /api/permissions/dry-run.
+type PermissionDryRunResponse struct {
+	config.PermissionDecision
+	// Rules is the number of rules evaluated.
+	Rules int `json:"rules"`
+	// ToolCall is the tool call context the rules were evaluated against.
+	ToolCall config.ToolCallContext `json:"tool_call"`
+}
+
+// handlePermissionDryRun handles POST /api/permissions/dry-run.
+// It evaluates the permission rules against a simulated permission request and
+// returns the decision and the rule that matched, without contacting any agent.
+func (s *Server) handlePermissionDryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+	var req PermissionDryRunRequest
+	if !parseJSONBody(w, r, &req) {
+		return
+	}
+
+	var ctx *config.PromptEnabledContext
+	user := webUserFromContext(r.Context())
+	workingDir, acpServer := req.WorkingDir, req.ACPServer
+	if req.SessionID != "" {
+		// Conversations of other workspaces are reported as not found
+		if !s.webUserCanAccessSession(user, req.SessionID) {
+			writeErrorJSON(w, http.StatusNotFound, "session_not_found", "Session not found")
+			return
+		}
+		ctx = s.buildPromptEnabledContext(req.SessionID)
+		if ctx == nil {
+			writeErrorJSON(w, http.StatusNotFound, "session_not_found", "Session not found")
+			return
+		}
+		workingDir, acpServer = ctx.Workspace.Folder, ctx.ACP.Name
+	} else {
+		if workingDir == "" {
+			writeErrorJSON(w, http.StatusBadRequest, "missing_working_dir", "session_id or working_dir is required")
+			return
+		}
+		if !s.webUserCanAccessDir(user, workingDir) {
+			writeErrorJSON(w, http.StatusForbidden, "workspace_forbidden", "You don't have access to this workspace")
+			return
+		}
+		ctx = s.buildWorkspacePromptEnabledContext(workingDir)
+		if acpServer != "" {
+			ctx.ACP.Name = acpServer
+			ctx.ACP.Type = acpServer
+			if s.config.MittoConfig != nil {
+				if srv, err := s.config.MittoConfig.GetServer(acpServer); err == nil {
+					ctx.ACP.Type = srv.GetType()
+					ctx.ACP.Tags = srv.Tags
+				}
+			}
+		}
+	}
+	ctx.ToolCall = toolCallContext(req.ToolCall, workingDir)
+
+	var policy *config.PermissionPolicy
+	if len(req.Rules) > 0 {
+		if err := config.ValidatePermissionRules(req.Rules); err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_rule", err.Error())
+			return
+		}
+		policy = config.NewPermissionPolicy(req.Rules, nil, nil)
+	} else {
+		policy = s.sessionManager.permissionPolicy(s.sessionManager.GetWorkspaceByDirAndACP(workingDir, acpServer), acpServer)
+	}
+
+	decision := policy.Evaluate(ctx)
+	if len(req.Rules) > 0 && decision.Scope != "" {
+		decision.Scope = "request"
+	}
+	writeJSONOK(w, PermissionDryRunResponse{
+		PermissionDecision: decision,
+		Rules:              policy.Len(),
+		ToolCall:           ctx.ToolCall,
+	})
+}
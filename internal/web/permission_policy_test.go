@@ -0,0 +1,243 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coder/acp-go-sdk"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+func TestToolCallContext(t *testing.T) {
+	kind := acp.ToolKindExecute
+	title := "Run tests"
+	tc := acp.ToolCallUpdate{
+		ToolCallId: "tc-1",
+		Kind:       &kind,
+		Title:      &title,
+		Locations:  []acp.ToolCallLocation{{Path: "/work/main.go"}},
+		RawInput: map[string]any{
+			"command":   []any{"go", "test", "./..."},
+			"file_path": "pkg/util.go",
+			"paths":     []any{"/work/main.go", "/etc/hosts"},
+		},
+	}
+
+	ctx := toolCallContext(tc, "/work")
+	if ctx.ID != "tc-1" || ctx.Kind != "execute" || ctx.Title != "Run tests" {
+		t.Errorf("unexpected id/kind/title: %+v", ctx)
+	}
+	if ctx.Command != "go test ./..." {
+		t.Errorf("Command = %q, want %q", ctx.Command, "go test ./...")
+	}
+	want := []string{"/work/main.go", "/etc/hosts", "/work/pkg/util.go"}
+	if len(ctx.Paths) != len(want) {
+		t.Fatalf("Paths = %v, want %v", ctx.Paths, want)
+	}
+	for i := range want {
+		if ctx.Paths[i] != want[i] {
+			t.Errorf("Paths[%d] = %q, want %q", i, ctx.Paths[i], want[i])
+		}
+	}
+	if !ctx.OutsideWorkspace {
+		t.Error("OutsideWorkspace = false, want true for /etc/hosts")
+	}
+	if ctx.Input["file_path"] != "pkg/util.go" {
+		t.Errorf("Input = %v", ctx.Input)
+	}
+
+	inside := toolCallContext(acp.ToolCallUpdate{RawInput: map[string]any{"path": "src/a.go"}}, "/work")
+	if inside.OutsideWorkspace {
+		t.Error("OutsideWorkspace = true, want false for a path inside the workspace")
+	}
+}
+
+func TestBackgroundSession_OnPermission_Policy(t *testing.T) {
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	defer store.Close()
+	recorder := session.NewRecorder(store)
+	if err := recorder.Start("test", "/work", ""); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	bs := &BackgroundSession{
+		persistedID: recorder.SessionID(),
+		workingDir:  "/work",
+		store:       store,
+		recorder:    recorder,
+		logger:      newTestLogger(),
+		autoApprove: true,
+		permissionPolicy: config.NewPermissionPolicy([]config.PermissionRule{
+			{Name: "no-rm", When: `toolCall.commandMatches("rm *")`, Action: "deny"},
+			{Name: "reads", When: `toolCall.kind == "read"`, Action: "approve"},
+		}, nil, nil),
+	}
+	options := []acp.PermissionOption{
+		{OptionId: "allow", Kind: acp.PermissionOptionKindAllowOnce, Name: "Allow"},
+		{OptionId: "reject", Kind: acp.PermissionOptionKindRejectOnce, Name: "Reject"},
+	}
+	request := func(kind acp.ToolKind, command string) acp.RequestPermissionRequest {
+		title := string(kind)
+		return acp.RequestPermissionRequest{
+			Options: options,
+			ToolCall: acp.ToolCallUpdate{
+				ToolCallId: "tc",
+				Kind:       &kind,
+				Title:      &title,
+				RawInput:   map[string]any{"command": command},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		req     acp.RequestPermissionRequest
+		option  string
+		outcome string
+		rule    string
+	}{
+		{"denied by rule", request(acp.ToolKindExecute, "rm -rf /"), "reject", "policy_denied", "no-rm"},
+		{"approved by rule", request(acp.ToolKindRead, ""), "allow", "policy_approved", "reads"},
+		{"no rule matches", request(acp.ToolKindExecute, "ls"), "allow", "auto_approved", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := bs.onPermission(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("onPermission: %v", err)
+			}
+			if resp.Outcome.Selected == nil || string(resp.Outcome.Selected.OptionId) != tt.option {
+				t.Fatalf("selected = %+v, want %q", resp.Outcome.Selected, tt.option)
+			}
+
+			events, err := store.ReadEvents(recorder.SessionID())
+			if err != nil {
+				t.Fatalf("ReadEvents: %v", err)
+			}
+			last := events[len(events)-1]
+			data, ok := last.Data.(map[string]any)
+			if last.Type != session.EventTypePermission || !ok {
+				t.Fatalf("last event = %+v, want a permission event", last)
+			}
+			if data["outcome"] != tt.outcome {
+				t.Errorf("outcome = %v, want %q", data["outcome"], tt.outcome)
+			}
+			if rule, _ := data["rule"].(string); rule != tt.rule {
+				t.Errorf("rule = %q, want %q", rule, tt.rule)
+			}
+		})
+	}
+}
+
+func TestHandlePermissionDryRun(t *testing.T) {
+	workDir := t.TempDir()
+	server := &Server{
+		sessionManager: NewSessionManager("", "", false, nil),
+		config: Config{MittoConfig: &config.Config{
+			Permissions: &config.PermissionsConfig{Rules: []config.PermissionRule{
+				{Name: "no-push", When: `toolCall.commandMatches("git push *")`, Action: "deny"},
+			}},
+		}},
+	}
+	server.sessionManager.mittoConfig = server.config.MittoConfig
+
+	post := func(body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/permissions/dry-run", bytes.NewReader(data))
+		w := httptest.NewRecorder()
+		server.handlePermissionDryRun(w, req)
+		return w
+	}
+	toolCall := map[string]any{
+		"toolCallId": "tc-1",
+		"kind":       "execute",
+		"rawInput":   map[string]any{"command": "git push origin main"},
+	}
+
+	t.Run("configured rules", func(t *testing.T) {
+		w := post(map[string]any{"working_dir": workDir, "tool_call": toolCall})
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var resp PermissionDryRunResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Action != "deny" || resp.Rule != "no-push" || resp.Scope != "global" || resp.Rules != 1 {
+			t.Errorf("response = %+v", resp)
+		}
+		if resp.ToolCall.Command != "git push origin main" {
+			t.Errorf("tool call command = %q", resp.ToolCall.Command)
+		}
+	})
+
+	t.Run("request rules", func(t *testing.T) {
+		w := post(map[string]any{
+			"working_dir": workDir,
+			"tool_call":   toolCall,
+			"rules":       []map[string]any{{"when": `toolCall.kind == "execute"`, "action": "ask"}},
+		})
+		var resp PermissionDryRunResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Action != "ask" || resp.Scope != "request" {
+			t.Errorf("response = %+v", resp)
+		}
+	})
+
+	t.Run("invalid rule", func(t *testing.T) {
+		w := post(map[string]any{
+			"working_dir": workDir,
+			"tool_call":   toolCall,
+			"rules":       []map[string]any{{"when": "toolCall.kind ==", "action": "deny"}},
+		})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+
+	t.Run("missing target", func(t *testing.T) {
+		if w := post(map[string]any{"tool_call": toolCall}); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+
+	t.Run("other workspaces", func(t *testing.T) {
+		store, err := session.NewStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewStore failed: %v", err)
+		}
+		defer store.Close()
+		if err := store.Create(session.Metadata{SessionID: "s1", ACPServer: "test-server", WorkingDir: workDir}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		server.store = store
+		defer func() { server.store = nil }()
+
+		ann := &config.WebUser{Username: "ann", Role: config.RoleOperator, Workspaces: []string{"/home/ann"}}
+		postAs := func(body any) int {
+			data, _ := json.Marshal(body)
+			req := httptest.NewRequest(http.MethodPost, "/api/permissions/dry-run", bytes.NewReader(data))
+			req = req.WithContext(context.WithValue(req.Context(), contextKeyWebUser, ann))
+			w := httptest.NewRecorder()
+			server.handlePermissionDryRun(w, req)
+			return w.Code
+		}
+		if code := postAs(map[string]any{"session_id": "s1", "tool_call": toolCall}); code != http.StatusNotFound {
+			t.Errorf("session of another workspace: status = %d, want 404", code)
+		}
+		if code := postAs(map[string]any{"working_dir": workDir, "tool_call": toolCall}); code != http.StatusForbidden {
+			t.Errorf("folder of another workspace: status = %d, want 403", code)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPermissionTokenTTL is the lifetime of approval tokens. It matches the
+// timeout of the permission dialog.
+const DefaultPermissionTokenTTL = 5 * time.Minute
+
+var (
+	// ErrInvalidPermissionToken is returned for malformed or forged approval tokens.
+	ErrInvalidPermissionToken = errors.New("invalid approval token")
+
+	// ErrPermissionTokenExpired is returned for approval tokens past their expiry.
+	ErrPermissionTokenExpired = errors.New("approval token expired")
+)
+
+// PermissionTokenClaims identifies the permission request an approval token is for.
+type PermissionTokenClaims struct {
+	SessionID string
+	RequestID string
+	ExpiresAt time.Time
+}
+
+// PermissionTokens issues and verifies approval tokens: short-lived, signed
+// references to a pending permission request, so that the request can be
+// answered from outside the browser (CLI, notification links).
+//
+// Tokens are signed with a random key generated at startup, so they do not
+// survive a restart (neither do the permission requests they refer to).
+type PermissionTokens struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewPermissionTokens creates a token issuer with the given token lifetime
+// (DefaultPermissionTokenTTL if zero).
+func NewPermissionTokens(ttl time.Duration) *PermissionTokens {
+	if ttl <= 0 {
+		ttl = DefaultPermissionTokenTTL
+	}
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return &PermissionTokens{key: key, ttl: ttl, now: time.Now}
+}
+
+// Issue returns an approval token for a permission request of a session.
+// Safe to call on nil receiver (returns an empty token).
+func (pt *PermissionTokens) Issue(sessionID, requestID string) string {
+	token, _ := pt.issue(sessionID, requestID)
+	return token
+}
+
+// issue returns an approval token and its expiry time.
+func (pt *PermissionTokens) issue(sessionID, requestID string) (string, time.Time) {
+	if pt == nil {
+		return "", time.Time{}
+	}
+	expires := pt.now().Add(pt.ttl).Truncate(time.Second).UTC()
+	payload := base64.RawURLEncoding.EncodeToString([]byte(sessionID + "\n" + requestID + "\n" + strconv.FormatInt(expires.Unix(), 10)))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(pt.sign(payload)), expires
+}
+
+// Verify checks the signature and the expiry of an approval token and returns
+// the permission request it refers to.
+func (pt *PermissionTokens) Verify(token string) (PermissionTokenClaims, error) {
+	if pt == nil {
+		return PermissionTokenClaims{}, ErrInvalidPermissionToken
+	}
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return PermissionTokenClaims{}, ErrInvalidPermissionToken
+	}
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, pt.sign(payload)) {
+		return PermissionTokenClaims{}, ErrInvalidPermissionToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return PermissionTokenClaims{}, ErrInvalidPermissionToken
+	}
+	parts := strings.Split(string(raw), "\n")
+	if len(parts) != 3 {
+		return PermissionTokenClaims{}, ErrInvalidPermissionToken
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return PermissionTokenClaims{}, ErrInvalidPermissionToken
+	}
+	claims := PermissionTokenClaims{
+		SessionID: parts[0],
+		RequestID: parts[1],
+		ExpiresAt: time.Unix(expires, 0).UTC(),
+	}
+	if !pt.now().Before(claims.ExpiresAt) {
+		return claims, ErrPermissionTokenExpired
+	}
+	return claims, nil
+}
+
+// sign returns the HMAC-SHA256 of a token payload.
+func (pt *PermissionTokens) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, pt.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -166,7 +166,16 @@ func (s *Server) handleAddToQueue(w http.ResponseWriter, r *http.Request, queue
 		scheduledTime = &t
 	}
 
-	msg, err := queue.Add(req.Message, req.ImageIDs, req.FileIDs, clientID, scheduledTime, maxSize, req.Arguments, req.PromptName)
+	msg, err := queue.AddMessage(session.QueuedMessage{
+		Message:       req.Message,
+		ImageIDs:      req.ImageIDs,
+		FileIDs:       req.FileIDs,
+		ClientID:      clientID,
+		ScheduledTime: scheduledTime,
+		Arguments:     req.Arguments,
+		PromptName:    req.PromptName,
+		SentBy:        authUserName(r.Context()),
+	}, maxSize)
 	if err != nil {
 		if errors.Is(err, session.ErrQueueFull) {
 			writeErrorJSON(w, http.StatusConflict, "queue_full",
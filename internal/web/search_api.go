@@ -0,0 +1,98 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+// handleSearch handles GET /api/search
+// It performs a full-text search over the history of all conversations.
+//
+// Query parameters:
+//   - q: search text (required). All words must match; a trailing '*' matches a prefix.
+//   - workspace: workspace UUID to restrict results to (optional)
+//   - working_dir: workspace folder to restrict results to (optional, ignored if workspace is set)
+//   - since, until: time range, as RFC 3339 timestamps or durations ago like "24h" (optional)
+//   - archived: "true" or "false" to filter by archived status (optional)
+//   - limit: maximum number of hits (optional, default 20, max 200)
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	store := s.Store()
+	if store == nil {
+		http.Error(w, "Session store not available", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := session.SearchOptions{
+		Query:      query.Get("q"),
+		WorkingDir: query.Get("working_dir"),
+	}
+	if opts.Query == "" {
+		writeErrorJSON(w, http.StatusBadRequest, "invalid_query", "q query parameter is required")
+		return
+	}
+
+	if uuid := query.Get("workspace"); uuid != "" {
+		var ws *config.WorkspaceSettings
+		if s.sessionManager != nil {
+			ws = s.sessionManager.GetWorkspaceByUUID(uuid)
+		}
+		if ws == nil {
+			writeErrorJSON(w, http.StatusNotFound, "workspace_not_found", "Workspace not found: "+uuid)
+			return
+		}
+		opts.WorkingDir = ws.WorkingDir
+	}
+
+	var err error
+	if v := query.Get("since"); v != "" {
+		if opts.Since, err = session.ParseHistoryTime(v); err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_since", err.Error())
+			return
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		if opts.Until, err = session.ParseHistoryTime(v); err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_until", err.Error())
+			return
+		}
+	}
+	if v := query.Get("archived"); v != "" {
+		archived, err := strconv.ParseBool(v)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_archived", "archived must be true or false")
+			return
+		}
+		opts.Archived = &archived
+	}
+	if v := query.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit < 0 {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_limit", "limit must be a positive integer")
+			return
+		}
+	}
+
+	// Only the conversations of the workspaces of the user
+	if user := webUserFromContext(r.Context()); !user.HasAllWorkspaces() {
+		opts.CanAccess = func(dir string) bool { return s.webUserCanAccessDir(user, dir) }
+	}
+
+	result, err := store.SearchIndex().Search(opts)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("Failed to search sessions", "error", err)
+		}
+		http.Error(w, "Failed to search sessions", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONOK(w, result)
+}
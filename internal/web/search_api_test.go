@@ -0,0 +1,110 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+func TestHandleSearch(t *testing.T) {
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+
+	for _, meta := range []session.Metadata{
+		{SessionID: "s1", ACPServer: "test-server", WorkingDir: "/proj/a", Name: "Parser"},
+		{SessionID: "s2", ACPServer: "test-server", WorkingDir: "/proj/b", Name: "Other", Archived: true},
+	} {
+		if err := store.Create(meta); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if err := store.AppendEvent(meta.SessionID, session.Event{
+			Type: session.EventTypeAgentMessage,
+			Data: session.AgentMessageData{Text: "<p>Fixed the parser</p>"},
+		}); err != nil {
+			t.Fatalf("AppendEvent failed: %v", err)
+		}
+	}
+
+	sm := NewSessionManager("", "", false, nil)
+	sm.SetWorkspaces([]config.WorkspaceSettings{
+		{UUID: "ws-a", WorkingDir: "/proj/a", ACPServer: "test-server"},
+		{UUID: "ws-b", WorkingDir: "/proj/b", ACPServer: "test-server"},
+	})
+	server := &Server{
+		sessionManager: sm,
+		store:          store,
+	}
+
+	bob := &config.WebUser{Username: "bob", Role: config.RoleViewer, Workspaces: []string{"ws-b"}}
+	tests := []struct {
+		name      string
+		query     string
+		user      *config.WebUser
+		wantCode  int
+		wantTotal int
+	}{
+		{"all", "q=parser", nil, http.StatusOK, 2},
+		{"restricted user", "q=parser&limit=1", bob, http.StatusOK, 1},
+		{"working dir", "q=parser&working_dir=/proj/a", nil, http.StatusOK, 1},
+		{"archived", "q=parser&archived=false", nil, http.StatusOK, 1},
+		{"no match", "q=lexer", nil, http.StatusOK, 0},
+		{"missing query", "", nil, http.StatusBadRequest, 0},
+		{"unknown workspace", "q=parser&workspace=nope", nil, http.StatusNotFound, 0},
+		{"invalid since", "q=parser&since=yesterday", nil, http.StatusBadRequest, 0},
+		{"invalid archived", "q=parser&archived=maybe", nil, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/search?"+tt.query, nil)
+			if tt.user != nil {
+				req = req.WithContext(context.WithValue(req.Context(), contextKeyWebUser, tt.user))
+			}
+			w := httptest.NewRecorder()
+
+			server.handleSearch(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Status = %d, want %d (body: %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var result session.SearchResult
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if result.Total != tt.wantTotal || len(result.Hits) != tt.wantTotal {
+				t.Errorf("Total = %d with %d hits, want %d", result.Total, len(result.Hits), tt.wantTotal)
+			}
+			for _, hit := range result.Hits {
+				if tt.user != nil && !tt.user.HasAllWorkspaces() && hit.WorkingDir != "/proj/b" {
+					t.Errorf("hit %s is outside the workspaces of the user", hit.SessionID)
+				}
+				if !strings.Contains(hit.Snippet, "<mark>parser</mark>") {
+					t.Errorf("Snippet = %q, want highlighted match", hit.Snippet)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleSearch_MethodNotAllowed(t *testing.T) {
+	server := &Server{}
+	req := httptest.NewRequest(http.MethodPost, "/api/search?q=x", nil)
+	w := httptest.NewRecorder()
+
+	server.handleSearch(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
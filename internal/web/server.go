@@ -15,6 +15,7 @@ import (
 
 	builtinConfig "github.com/inercia/mitto/config"
 	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/audit"
 	"github.com/inercia/mitto/internal/auxiliary"
 	"github.com/inercia/mitto/internal/beads"
 	configPkg "github.com/inercia/mitto/internal/config"
@@ -22,8 +23,10 @@ import (
 	"github.com/inercia/mitto/internal/hooks"
 	"github.com/inercia/mitto/internal/logging"
 	"github.com/inercia/mitto/internal/mcpserver"
-	"github.com/inercia/mitto/internal/processors"
+	"github.com/inercia/mitto/internal/metrics"
+	"github.com/inercia/mitto/internal/notify"
 	"github.com/inercia/mitto/internal/session"
+	"github.com/inercia/mitto/internal/tracing"
 	mittoWeb "github.com/inercia/mitto/web"
 )
 
@@ -168,6 +171,22 @@ type Server struct {
 	// Periodic runner for scheduled prompt delivery
 	periodicRunner *PeriodicRunner
 
+	// Usage budgets (pause queued messages and scheduled prompts once exceeded)
+	usageBudget *UsageBudget
+
+	// Outbound webhook notifier (nil if no webhook is configured) and its delivery log
+	notifier   *notify.Notifier
+	webhookLog *notify.DeliveryLog
+
+	// Approval tokens of pending permission requests (answered via /api/permissions/{token})
+	permissionTokens *PermissionTokens
+
+	// API tokens: named, scoped credentials for scripts
+	apiTokens *APITokens
+
+	// Two-factor authentication of the password logins
+	totp *TOTPStore
+
 	// Callback index for mapping callback tokens to session IDs
 	callbackIndex       *CallbackIndex
 	callbackRateLimiter *CallbackRateLimiter
@@ -175,12 +194,21 @@ type Server struct {
 	// Access logger for security-relevant events (nil if disabled)
 	accessLogger *AccessLogger
 
+	// Audit log of security-relevant actions (nil if disabled)
+	auditLog *audit.Log
+
 	// MCP debug server for exposing debugging tools
 	mcpServer *mcpserver.Server
 
 	// Scanner defense for blocking malicious IPs at the connection level
 	defense *defense.ScannerDefense
 
+	// Prometheus metrics (nil if the metrics endpoint is disabled)
+	metrics *metrics.Registry
+
+	// Flushes the pending spans and stops the trace export (nil if tracing is disabled)
+	traceShutdown func(context.Context) error
+
 	// Prompts watcher for monitoring prompt file changes
 	promptsWatcher *configPkg.PromptsWatcher
 
@@ -232,7 +260,15 @@ func NewServer(config Config) (*Server, error) {
 	}
 
 	// Create session store for persistence
-	store, err := session.DefaultStore()
+	storeBackend := session.StoreBackendFile
+	if config.MittoConfig != nil && config.MittoConfig.Session != nil {
+		b, err := session.ParseStoreBackend(config.MittoConfig.Session.GetStoreBackend())
+		if err != nil {
+			return nil, err
+		}
+		storeBackend = b
+	}
+	store, err := session.DefaultStoreWithBackend(storeBackend)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session store: %w", err)
 	}
@@ -351,33 +387,8 @@ func NewServer(config Config) (*Server, error) {
 		})
 	}
 
-	// Set global conversations config for message processing
-	if config.MittoConfig != nil {
-		sessionMgr.SetGlobalConversations(config.MittoConfig.Conversations)
-	}
-
-	// Set full MittoConfig for agent-specific lookups
-	if config.MittoConfig != nil {
-		sessionMgr.SetMittoConfig(config.MittoConfig)
-	}
-
-	// Set global restricted runner config for sandboxed execution
-	if config.MittoConfig != nil && config.MittoConfig.RestrictedRunners != nil {
-		sessionMgr.SetGlobalRestrictedRunners(config.MittoConfig.RestrictedRunners)
-		logger.Info("Global restricted runners configured",
-			"runner_types", len(config.MittoConfig.RestrictedRunners))
-	}
-
-	// Load and set processors from processors directory
-	if processorsDir, err := appdir.ProcessorsDir(); err == nil {
-		procMgr := processors.NewManager(processorsDir, logger)
-		if err := procMgr.Load(); err != nil {
-			logger.Warn("Failed to load processors", "error", err)
-		} else if len(procMgr.Processors()) > 0 {
-			sessionMgr.SetProcessorManager(procMgr)
-			logger.Info("Loaded processors", "count", len(procMgr.Processors()))
-		}
-	}
+	// Conversations, agent and restricted runner settings, and processors
+	sessionMgr.Configure(config.MittoConfig)
 
 	// Initialize auth manager if auth is configured
 	var authMgr *AuthManager
@@ -479,6 +490,27 @@ func NewServer(config Config) (*Server, error) {
 		}
 	}
 
+	// Open the audit log (opt-in). It is the process-wide default log, so
+	// that the packages recording actions do not need a reference to it.
+	var auditLog *audit.Log
+	if config.MittoConfig != nil && config.MittoConfig.Web.AuditLog.IsEnabled() {
+		auditCfg := config.MittoConfig.Web.AuditLog
+		auditPath, err := auditCfg.GetPath()
+		if err == nil {
+			auditLog, err = audit.Open(audit.Config{
+				Path:       auditPath,
+				MaxSizeMB:  auditCfg.MaxSizeMB,
+				MaxBackups: auditCfg.MaxBackups,
+			})
+		}
+		if err != nil {
+			logger.Error("Failed to open the audit log, auditing is disabled", "error", err)
+		} else {
+			audit.SetDefault(auditLog)
+			logger.Info("Audit logging enabled", "path", auditPath)
+		}
+	}
+
 	eventsManager := NewGlobalEventsManager()
 
 	// Initialize auxiliary manager for workspace-scoped auxiliary tasks
@@ -538,6 +570,7 @@ func NewServer(config Config) (*Server, error) {
 		wsSecurityConfig:     wsSecurityConfig,
 		proxyChecker:         proxyChecker,
 		accessLogger:         accessLogger,
+		auditLog:             auditLog,
 		defense:              scannerDefense,
 		acpProcessManager:    acpProcessMgr,
 		auxiliaryManager:     auxiliaryManager,
@@ -627,7 +660,49 @@ func NewServer(config Config) (*Server, error) {
 		s.BroadcastACPStopped(sessionID, "auto_archived")
 		s.BroadcastSessionArchived(sessionID, true)
 	})
-	s.periodicRunner.SetOnPeriodicAutoStopped(s.BroadcastPeriodicUpdated)
+	s.periodicRunner.SetOnPeriodicAutoStopped(func(sessionID string, p *session.PeriodicPrompt) {
+		s.BroadcastPeriodicUpdated(sessionID, p)
+		s.notifySessionEvent(sessionID, configPkg.WebhookEventPeriodicMaxIterations,
+			"Periodic prompt disabled after reaching the maximum number of iterations",
+			map[string]any{"iteration_count": p.IterationCount})
+	})
+
+	// Usage budgets pause queued messages and scheduled prompts once exceeded
+	s.usageBudget = NewUsageBudget(store, config.MittoConfig)
+	sessionMgr.SetUsageBudget(s.usageBudget)
+	s.periodicRunner.SetUsageBudget(s.usageBudget)
+
+	// Outbound webhooks notify external services of conversation lifecycle events
+	if deliveriesPath, err := appdir.WebhookDeliveriesPath(); err == nil {
+		s.webhookLog = notify.NewDeliveryLog(deliveriesPath)
+	}
+	if config.MittoConfig != nil {
+		s.notifier = notify.New(config.MittoConfig.Webhooks, s.webhookLog, logger)
+	}
+	sessionMgr.SetNotifier(s.notifier)
+
+	// Approval tokens let pending permission requests be answered outside the browser
+	s.permissionTokens = NewPermissionTokens(DefaultPermissionTokenTTL)
+	sessionMgr.SetPermissionTokens(s.permissionTokens)
+
+	// API tokens are accepted as bearer tokens by the auth middleware
+	apiTokensPath, _ := appdir.APITokensPath()
+	s.apiTokens = NewAPITokens(apiTokensPath)
+	if authMgr != nil {
+		authMgr.SetAPITokens(s.apiTokens)
+	}
+
+	// Password logins ask for a code once their users enroll a second factor
+	totpPath, _ := appdir.TOTPPath()
+	s.totp = NewTOTPStore(totpPath)
+	if authMgr != nil {
+		authMgr.SetTOTPStore(s.totp)
+	}
+
+	// Remove the git worktrees left behind by deleted sessions
+	if worktreesDir, err := appdir.WorktreesDir(); err == nil {
+		s.periodicRunner.SetWorktreesDir(worktreesDir)
+	}
 
 	// Configure the global periodic-iteration safeguard (user default, bounded by backstop).
 	maxPeriodicIter := configPkg.DefaultMaxPeriodicIterations
@@ -694,6 +769,10 @@ func NewServer(config Config) (*Server, error) {
 	// The periodic runner is created after the MCP server, so we use a setter.
 	if s.mcpServer != nil {
 		s.mcpServer.SetPeriodicRunner(s.periodicRunner)
+		s.mcpServer.SetOnChildReport(func(childSessionID, parentSessionID, status, summary string) {
+			s.notifySessionEvent(childSessionID, configPkg.WebhookEventChildReport, summary,
+				map[string]any{"status": status})
+		})
 	}
 
 	// Build callback index from existing sessions
@@ -719,6 +798,8 @@ func NewServer(config Config) (*Server, error) {
 	if authMgr != nil {
 		mux.HandleFunc(apiPrefix+"/api/login", authMgr.HandleLogin)
 		mux.HandleFunc(apiPrefix+"/api/logout", authMgr.HandleLogout)
+		mux.HandleFunc(apiPrefix+oidcLoginPath, authMgr.HandleOIDCLogin)
+		mux.HandleFunc(apiPrefix+oidcCallbackPath, authMgr.HandleOIDCCallback)
 	}
 
 	// CSRF token endpoint (always available for getting tokens)
@@ -727,7 +808,22 @@ func NewServer(config Config) (*Server, error) {
 	// API routes - all use the API prefix for security through obscurity
 	mux.HandleFunc(apiPrefix+"/api/sessions", s.handleSessions)
 	mux.HandleFunc(apiPrefix+"/api/sessions/running", s.handleRunningSessions)
+	mux.HandleFunc(apiPrefix+"/api/sessions/import", s.handleSessionImport)
 	mux.HandleFunc(apiPrefix+"/api/sessions/", s.handleSessionDetail)
+	mux.HandleFunc(apiPrefix+"/api/search", s.handleSearch)
+	mux.HandleFunc(apiPrefix+"/api/usage", s.handleUsage)
+	mux.HandleFunc(apiPrefix+"/api/audit", s.handleAudit)
+	mux.HandleFunc(apiPrefix+"/api/webhooks/deliveries", s.handleWebhookDeliveries)
+	mux.HandleFunc(apiPrefix+"/api/webhooks/test", s.handleWebhookTest)
+	mux.HandleFunc(apiPrefix+"/api/permissions", s.handlePermissions)
+	mux.HandleFunc(apiPrefix+"/api/permissions/dry-run", s.handlePermissionDryRun)
+	mux.HandleFunc(apiPrefix+"/api/permissions/", s.handlePermissionToken)
+	mux.HandleFunc(apiPrefix+"/api/tokens", s.handleAPITokens)
+	mux.HandleFunc(apiPrefix+"/api/tokens/", s.handleAPITokenDetail)
+	mux.HandleFunc(apiPrefix+"/api/auth/totp", s.handleTOTPStatus)
+	mux.HandleFunc(apiPrefix+"/api/auth/totp/enroll", s.handleTOTPEnroll)
+	mux.HandleFunc(apiPrefix+"/api/auth/totp/confirm", s.handleTOTPConfirm)
+	mux.HandleFunc(apiPrefix+"/api/auth/totp/disable", s.handleTOTPDisable)
 	mux.HandleFunc(apiPrefix+"/api/workspaces", s.handleWorkspaces)
 	mux.HandleFunc(apiPrefix+"/api/workspaces/", s.handleWorkspaceDetail)
 	mux.HandleFunc(apiPrefix+"/api/workspace-prompts", s.handleWorkspacePrompts)
@@ -792,6 +888,33 @@ func NewServer(config Config) (*Server, error) {
 		mux.HandleFunc(apiPrefix+"/robots.txt", handleRobotsTxt)
 	}
 
+	// Prometheus metrics (opt-in, restricted to localhost or authenticated admins)
+	if config.MittoConfig != nil && config.MittoConfig.Web.Metrics.IsEnabled() {
+		s.metrics = s.newMetricsRegistry()
+		metricsPath := config.MittoConfig.Web.Metrics.GetPath()
+		mux.HandleFunc(metricsPath, s.handleMetrics)
+		if authMgr != nil {
+			authMgr.SetMetricsPath(metricsPath)
+		}
+		if apiPrefix != "" {
+			mux.HandleFunc(apiPrefix+metricsPath, s.handleMetrics)
+		}
+		logger.Info("Metrics endpoint enabled", "path", metricsPath)
+	}
+
+	// OpenTelemetry tracing (opt-in, exported to an OTLP/HTTP collector)
+	if config.MittoConfig != nil && config.MittoConfig.Tracing.IsEnabled() {
+		traceShutdown, err := tracing.Setup(context.Background(), config.MittoConfig.Tracing)
+		if err != nil {
+			logger.Warn("Failed to set up tracing", "error", err)
+		} else {
+			s.traceShutdown = traceShutdown
+			logger.Info("Tracing enabled",
+				"endpoint", config.MittoConfig.Tracing.GetEndpoint(),
+				"sample_ratio", config.MittoConfig.Tracing.GetSampleRatio())
+		}
+	}
+
 	// Static files: use filesystem directory if specified, otherwise use embedded assets
 	var staticFS fs.FS
 	if config.StaticDir != "" {
@@ -869,6 +992,11 @@ func NewServer(config Config) (*Server, error) {
 	// Wrap with logging middleware
 	handler = s.loggingMiddleware(handler)
 
+	// Count the requests by status code for the metrics endpoint
+	if s.metrics != nil {
+		handler = metricsMiddleware(handler)
+	}
+
 	// 8. Access logging for security-relevant events (outermost to capture final status)
 	if s.accessLogger != nil {
 		handler = s.accessLogger.Middleware(handler)
@@ -951,11 +1079,22 @@ func (s *Server) Shutdown() error {
 		s.periodicRunner.Stop()
 	}
 
+	// Cancel pending webhook retries
+	s.notifier.Close()
+
 	// Close access logger
 	if s.accessLogger != nil {
 		s.accessLogger.Close()
 	}
 
+	// Close the audit log
+	if s.auditLog != nil {
+		if audit.Default() == s.auditLog {
+			audit.SetDefault(nil)
+		}
+		s.auditLog.Close()
+	}
+
 	// Stop health monitor
 	s.healthMonitorMu.Lock()
 	if s.healthMonitor != nil {
@@ -979,6 +1118,15 @@ func (s *Server) Shutdown() error {
 		s.promptsWatcher.Close()
 	}
 
+	// Flush the pending spans (the prompts still running were ended above)
+	if s.traceShutdown != nil {
+		traceCtx, traceCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := s.traceShutdown(traceCtx); err != nil && s.logger != nil {
+			s.logger.Warn("Failed to flush traces", "error", err)
+		}
+		traceCancel()
+	}
+
 	// Shut down the HTTP server with a timeout so we don't hang indefinitely.
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -1071,11 +1219,13 @@ func (s *Server) HandleAuthInfo(w http.ResponseWriter, r *http.Request) {
 	info := map[string]bool{
 		"simple":     false,
 		"cloudflare": false,
+		"oidc":       false,
 	}
 
 	if s.authManager != nil {
 		info["simple"] = s.authManager.HasValidCredentials()
 		info["cloudflare"] = s.authManager.HasCloudflareAccess()
+		info["oidc"] = s.authManager.HasOIDC()
 	}
 
 	writeJSONOK(w, info)
@@ -1221,6 +1371,12 @@ func (s *Server) BroadcastPeriodicUpdated(sessionID string, periodic *session.Pe
 		if periodic.Frequency.At != "" {
 			data["frequency"].(map[string]interface{})["at"] = periodic.Frequency.At
 		}
+		if periodic.Cron != "" {
+			data["cron"] = periodic.Cron
+		}
+		if periodic.TimeZone != "" {
+			data["timezone"] = periodic.TimeZone
+		}
 		if periodic.NextScheduledAt != nil && !periodic.NextScheduledAt.IsZero() {
 			data["next_scheduled_at"] = periodic.NextScheduledAt.Format(time.RFC3339)
 		}
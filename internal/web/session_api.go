@@ -62,6 +62,9 @@ func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 	var workspace *config.WorkspaceSettings
 	workspaces := s.sessionManager.GetWorkspaces()
 
+	// A new conversation started from an isolated one goes to the workspace, not its worktree
+	req.WorkingDir = workspaceDir(req.WorkingDir)
+
 	if req.WorkingDir != "" {
 		// User specified a working directory - find matching workspace.
 		// If acp_server is also specified, match both (for duplicate workspaces with
@@ -134,6 +137,12 @@ func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.webUserCanAccessDir(webUserFromContext(r.Context()), req.WorkingDir) {
+		writeErrorJSON(w, http.StatusForbidden, "workspace_forbidden",
+			"You don't have access to this workspace")
+		return
+	}
+
 	// Note: The session manager already has the store set by the server at startup.
 	// No need to create a new store here.
 
@@ -318,6 +327,17 @@ func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only the conversations of the workspaces of the user
+	if user := webUserFromContext(r.Context()); !user.HasAllWorkspaces() {
+		visible := sessions[:0]
+		for _, meta := range sessions {
+			if s.webUserCanAccessDir(user, meta.WorkingDir) {
+				visible = append(visible, meta)
+			}
+		}
+		sessions = visible
+	}
+
 	// Sort by update time, most recently used first
 	sort.Slice(sessions, func(i, j int) bool {
 		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
@@ -374,6 +394,12 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Conversations of other workspaces are reported as not found
+	if !s.webUserCanAccessSession(webUserFromContext(r.Context()), sessionID) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
 	isEventsRequest := len(parts) > 1 && parts[1] == "events"
 	isWSRequest := len(parts) > 1 && parts[1] == "ws"
 	isImagesRequest := len(parts) > 1 && parts[1] == "images"
@@ -385,6 +411,9 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 	isSettingsRequest := len(parts) > 1 && parts[1] == "settings"
 	isPruneRequest := len(parts) > 1 && parts[1] == "prune"
 	isChangesRequest := len(parts) > 1 && parts[1] == "changes"
+	isExportRequest := len(parts) > 1 && parts[1] == "export"
+	isForkRequest := len(parts) > 1 && parts[1] == "fork"
+	isWorktreeRequest := len(parts) > 1 && parts[1] == "worktree"
 
 	// Handle WebSocket upgrade for per-session connections
 	if isWSRequest {
@@ -444,7 +473,12 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 
 	// Handle callback token operations
 	if isCallbackRequest {
-		s.handleSessionCallback(w, r, sessionID)
+		// Check for sub-paths like /callback/test
+		callbackSubPath := ""
+		if len(parts) > 2 {
+			callbackSubPath = parts[2]
+		}
+		s.handleSessionCallback(w, r, sessionID, callbackSubPath)
 		return
 	}
 
@@ -462,10 +496,34 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 
 	// Handle git changes operations
 	if isChangesRequest {
+		// File changes of a tool call: /api/sessions/{id}/changes/{toolCallId}[/revert]
+		if len(parts) > 2 && parts[2] != "" {
+			revert := len(parts) > 3 && parts[3] == "revert"
+			s.handleToolCallChanges(w, r, sessionID, parts[2], revert)
+			return
+		}
 		s.handleSessionChanges(w, r, sessionID)
 		return
 	}
 
+	// Handle conversation export
+	if isExportRequest {
+		s.handleSessionExport(w, r, sessionID)
+		return
+	}
+
+	// Handle conversation forking
+	if isForkRequest {
+		s.handleSessionFork(w, r, sessionID)
+		return
+	}
+
+	// Handle git worktree operations
+	if isWorktreeRequest {
+		s.handleSessionWorktree(w, r, sessionID)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		s.handleGetSession(w, r, sessionID, isEventsRequest)
@@ -858,6 +916,59 @@ func (s *Server) handleDeleteSession(w http.ResponseWriter, sessionID string) {
 	writeNoContent(w)
 }
 
+// SessionForkRequest is the request body for POST /api/sessions/{id}/fork.
+type SessionForkRequest struct {
+	// Seq is the sequence number of the last event to copy (0 = the whole conversation).
+	Seq int64 `json:"seq,omitempty"`
+	// Name of the new conversation (default: the source name with a "(fork)" suffix).
+	Name string `json:"name,omitempty"`
+}
+
+// handleSessionFork handles POST /api/sessions/{id}/fork
+// It creates a new child conversation with a copy of the events up to the given
+// sequence number. The agent-side state can't be cloned, so the fork starts a new
+// ACP session that is given the copied conversation history as context.
+func (s *Server) handleSessionFork(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	store := s.Store()
+	if store == nil {
+		http.Error(w, "Session store not available", http.StatusInternalServerError)
+		return
+	}
+
+	var req SessionForkRequest
+	if r.ContentLength != 0 && !parseJSONBody(w, r, &req) {
+		return
+	}
+
+	meta, err := store.Fork(sessionID, session.ForkOptions{AtSeq: req.Seq, Name: req.Name})
+	if err != nil {
+		switch {
+		case errors.Is(err, session.ErrSessionNotFound):
+			http.Error(w, "Session not found", http.StatusNotFound)
+		case errors.Is(err, session.ErrInvalidForkSeq):
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_seq", err.Error())
+		default:
+			if s.logger != nil {
+				s.logger.Error("Failed to fork session", "session_id", sessionID, "seq", req.Seq, "error", err)
+			}
+			http.Error(w, "Failed to fork session", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if s.sessionManager != nil {
+		s.sessionManager.BroadcastSessionCreated(meta.SessionID, meta.Name, meta.ACPServer, meta.WorkingDir,
+			meta.ParentSessionID, string(meta.ChildOrigin))
+	}
+
+	writeJSONCreated(w, meta)
+}
+
 // handleWorkspaces handles /api/workspaces
 // GET: List all workspaces
 // POST: Add a new workspace
@@ -877,7 +988,7 @@ func (s *Server) handleWorkspaces(w http.ResponseWriter, r *http.Request) {
 
 // handleGetWorkspaces returns the list of workspaces and available ACP servers
 func (s *Server) handleGetWorkspaces(w http.ResponseWriter, r *http.Request) {
-	workspaces := s.sessionManager.GetWorkspaces()
+	workspaces := filterWorkspacesForUser(webUserFromContext(r.Context()), s.sessionManager.GetWorkspaces())
 
 	// Get available ACP servers from config
 	var acpServers []map[string]string
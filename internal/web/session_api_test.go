@@ -2901,3 +2901,62 @@ func TestHandleWorkspacePrompts_DirGatesUseDirParamNotSession(t *testing.T) {
 		t.Errorf("ungated prompt missing, got %v", names)
 	}
 }
+
+func TestHandleSessionFork(t *testing.T) {
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+
+	sessionID := "20240115-103000-abcd1234"
+	if err := store.Create(session.Metadata{SessionID: sessionID, ACPServer: "test-server", Name: "Original"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for _, msg := range []string{"first", "second", "third"} {
+		if err := store.AppendEvent(sessionID, session.Event{
+			Type: session.EventTypeUserPrompt,
+			Data: session.UserPromptData{Message: msg},
+		}); err != nil {
+			t.Fatalf("AppendEvent failed: %v", err)
+		}
+	}
+
+	server := &Server{
+		sessionManager: NewSessionManager("", "", false, nil),
+		store:          store,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions/"+sessionID+"/fork", strings.NewReader(`{"seq": 2}`))
+	w := httptest.NewRecorder()
+	server.handleSessionFork(w, req, sessionID)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Status = %d, want %d (body: %s)", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var meta session.Metadata
+	if err := json.Unmarshal(w.Body.Bytes(), &meta); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if meta.ParentSessionID != sessionID || meta.ChildOrigin != session.ChildOriginFork || meta.Name != "Original (fork)" {
+		t.Errorf("fork metadata = %+v", meta)
+	}
+	if events, _ := store.ReadEvents(meta.SessionID); len(events) != 2 {
+		t.Errorf("fork events = %d, want 2", len(events))
+	}
+
+	// Fork points that don't exist are rejected
+	req = httptest.NewRequest(http.MethodPost, "/api/sessions/"+sessionID+"/fork", strings.NewReader(`{"seq": 10}`))
+	w = httptest.NewRecorder()
+	server.handleSessionFork(w, req, sessionID)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Status for invalid seq = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/sessions/missing/fork", nil)
+	w = httptest.NewRecorder()
+	server.handleSessionFork(w, req, "missing")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status for missing session = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/inercia/mitto/internal/session"
+	"github.com/inercia/mitto/internal/worktree"
 )
 
 // ChangedFile represents a file changed in the workspace.
@@ -32,6 +33,7 @@ const gitChangesTimeout = 15 * time.Second
 
 // handleSessionChanges handles GET /api/sessions/{id}/changes
 // Returns the list of files changed in the session's workspace (git status + numstat).
+// For sessions isolated in a git worktree, the changes are diffed against the base branch.
 func (s *Server) handleSessionChanges(w http.ResponseWriter, r *http.Request, sessionID string) {
 	if r.Method != http.MethodGet {
 		methodNotAllowed(w)
@@ -56,6 +58,14 @@ func (s *Server) handleSessionChanges(w http.ResponseWriter, r *http.Request, se
 		return
 	}
 
+	// Sessions isolated in a worktree report everything changed on their branch
+	if store := s.Store(); store != nil {
+		if meta, err := store.GetMetadata(sessionID); err == nil && meta.Worktree != nil {
+			writeJSONOK(w, worktreeChanges(ctx, workDir, meta.Worktree))
+			return
+		}
+	}
+
 	// Get branch name
 	branchCmd := exec.CommandContext(ctx, "git", "branch", "--show-current")
 	branchCmd.Dir = workDir
@@ -118,6 +128,68 @@ func (s *Server) handleSessionChanges(w http.ResponseWriter, r *http.Request, se
 	writeJSONOK(w, ChangesResponse{Files: files, IsGitRepo: true, Branch: branch})
 }
 
+// worktreeChanges lists the files changed in a session worktree since its branch
+// diverged from the base branch: committed and uncommitted changes, and untracked files.
+func worktreeChanges(ctx context.Context, workDir string, info *session.WorktreeInfo) ChangesResponse {
+	resp := ChangesResponse{Files: []ChangedFile{}, IsGitRepo: true, Branch: info.Branch}
+
+	base, err := worktree.MergeBase(ctx, worktreeFromInfo(info))
+	if err != nil {
+		resp.Error = "Failed to find the base of the session branch"
+		return resp
+	}
+
+	diffCmd := exec.CommandContext(ctx, "git", "diff", "--no-ext-diff", "--no-color", "--name-status", "-M", base)
+	diffCmd.Dir = workDir
+	diffOut, err := diffCmd.Output()
+	if err != nil {
+		resp.Error = "Failed to diff against the base branch"
+		return resp
+	}
+
+	// Parse name-status output ("M\tpath", "R100\told\tnew") into ordered map
+	fileMap := make(map[string]*ChangedFile)
+	fileOrder := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(diffOut)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		cf := &ChangedFile{Path: fields[len(fields)-1], Status: fields[0][:1]}
+		switch cf.Status {
+		case "R", "C":
+			if len(fields) > 2 {
+				cf.OldPath = fields[1]
+			}
+		case "A", "D":
+		default:
+			cf.Status = "M"
+		}
+		fileMap[cf.Path] = cf
+		fileOrder = append(fileOrder, cf.Path)
+	}
+
+	// Untracked files are not part of the diff
+	untrackedCmd := exec.CommandContext(ctx, "git", "ls-files", "--others", "--exclude-standard", "--full-name")
+	untrackedCmd.Dir = workDir
+	if untrackedOut, err := untrackedCmd.Output(); err == nil {
+		for _, path := range strings.Split(strings.TrimSpace(string(untrackedOut)), "\n") {
+			if path == "" || fileMap[path] != nil {
+				continue
+			}
+			fileMap[path] = &ChangedFile{Path: path, Status: "?"}
+			fileOrder = append(fileOrder, path)
+		}
+	}
+
+	mergeNumstat(ctx, workDir, fileMap, base, "--numstat")
+
+	for _, path := range fileOrder {
+		resp.Files = append(resp.Files, *fileMap[path])
+	}
+	return resp
+}
+
 // resolveSessionWorkingDir gets the working directory for a session from metadata or active session.
 func (s *Server) resolveSessionWorkingDir(sessionID string) string {
 	store := s.Store()
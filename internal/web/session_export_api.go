@@ -0,0 +1,78 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/inercia/mitto/internal/session"
+)
+
+// handleSessionExport handles GET /api/sessions/{id}/export
+// It downloads the conversation as a Markdown, HTML or JSON document.
+//
+// Query parameters:
+//   - format: "md" (default), "html" or "json"
+//   - zip: "true" to download a zip archive with the document and the session's images and files
+func (s *Server) handleSessionExport(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	store := s.Store()
+	if store == nil {
+		http.Error(w, "Session store not available", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := session.ExportOptions{Format: session.ExportFormatMarkdown}
+	if v := query.Get("format"); v != "" {
+		format, err := session.ParseExportFormat(v)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_format", err.Error())
+			return
+		}
+		opts.Format = format
+	}
+	if v := query.Get("zip"); v != "" {
+		zip, err := strconv.ParseBool(v)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_zip", "zip must be true or false")
+			return
+		}
+		opts.Zip = zip
+	}
+
+	meta, err := store.GetMetadata(sessionID)
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get session", http.StatusInternalServerError)
+		return
+	}
+
+	// Render into memory first so that errors can still be reported with a proper status.
+	var buf bytes.Buffer
+	if err := store.Export(&buf, sessionID, opts); err != nil {
+		if s.logger != nil {
+			s.logger.Error("Failed to export session", "session_id", sessionID, "format", opts.Format, "error", err)
+		}
+		http.Error(w, "Failed to export session", http.StatusInternalServerError)
+		return
+	}
+
+	contentType := opts.Format.ContentType()
+	if opts.Zip {
+		contentType = "application/zip"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", session.ExportFileName(meta, opts)))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	_, _ = w.Write(buf.Bytes())
+}
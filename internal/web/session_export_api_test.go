@@ -0,0 +1,69 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/inercia/mitto/internal/session"
+)
+
+func TestHandleSessionExport(t *testing.T) {
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+
+	sessionID := "20240115-103000-abcd1234"
+	if err := store.Create(session.Metadata{SessionID: sessionID, ACPServer: "test-server", Name: "My Chat"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := store.AppendEvent(sessionID, session.Event{
+		Type: session.EventTypeUserPrompt,
+		Data: session.UserPromptData{Message: "hello export"},
+	}); err != nil {
+		t.Fatalf("AppendEvent failed: %v", err)
+	}
+
+	server := &Server{store: store}
+
+	tests := []struct {
+		name            string
+		path            string
+		wantCode        int
+		wantContentType string
+		wantFilename    string
+	}{
+		{"default markdown", "/api/sessions/" + sessionID + "/export", http.StatusOK, "text/markdown; charset=utf-8", "my-chat-20240115.md"},
+		{"html", "/api/sessions/" + sessionID + "/export?format=html", http.StatusOK, "text/html; charset=utf-8", "my-chat-20240115.html"},
+		{"json zip", "/api/sessions/" + sessionID + "/export?format=json&zip=true", http.StatusOK, "application/zip", "my-chat-20240115.zip"},
+		{"invalid format", "/api/sessions/" + sessionID + "/export?format=pdf", http.StatusBadRequest, "", ""},
+		{"not found", "/api/sessions/20240115-103000-ffffffff/export", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			server.handleSessionDetail(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Status = %d, want %d (body: %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, tt.wantFilename) {
+				t.Errorf("Content-Disposition = %q, want filename %q", got, tt.wantFilename)
+			}
+			if tt.wantContentType != "application/zip" && !strings.Contains(w.Body.String(), "hello export") {
+				t.Errorf("body does not contain the prompt: %s", w.Body.String())
+			}
+		})
+	}
+}
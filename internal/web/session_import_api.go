@@ -0,0 +1,141 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+// SessionImportResponse is the response body for a successful import.
+type SessionImportResponse struct {
+	Sessions []session.ImportResult `json:"sessions"`
+}
+
+// handleSessionImport handles POST /api/sessions/import
+// The request body is a JSON export bundle, or a zip archive of one
+// (as produced by GET /api/sessions/{id}/export?format=json).
+//
+// Query parameters:
+//   - workspace: workspace UUID to import the conversation into (optional).
+//     The conversation keeps its original folder and ACP server if omitted,
+//     and the folder must then belong to a configured workspace.
+//
+// Imported conversations get the default flags of this machine.
+// Users restricted to some workspaces can only import into a workspace they
+// can access, given explicitly or as the folder of the conversation.
+func (s *Server) handleSessionImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	store := s.Store()
+	if store == nil {
+		http.Error(w, "Session store not available", http.StatusInternalServerError)
+		return
+	}
+
+	user := webUserFromContext(r.Context())
+	opts := session.ImportOptions{MigrationContext: buildMigrationContext(s.config.MittoConfig)}
+	var defaultFlags map[string]bool
+	if s.config.MittoConfig != nil && s.config.MittoConfig.Conversations != nil {
+		defaultFlags = s.config.MittoConfig.Conversations.DefaultFlags
+	}
+	opts.AdvancedSettings = session.DefaultFlagValues(defaultFlags)
+	if uuid := r.URL.Query().Get("workspace"); uuid != "" {
+		var ws *config.WorkspaceSettings
+		if s.sessionManager != nil {
+			ws = s.sessionManager.GetWorkspaceByUUID(uuid)
+		}
+		if ws == nil {
+			writeErrorJSON(w, http.StatusNotFound, "workspace_not_found", "Workspace not found: "+uuid)
+			return
+		}
+		if !s.webUserCanAccessDir(user, ws.WorkingDir) {
+			writeErrorJSON(w, http.StatusForbidden, "workspace_forbidden",
+				"You don't have access to this workspace")
+			return
+		}
+		opts.WorkingDir = ws.WorkingDir
+		opts.ACPServer = ws.ACPServer
+	}
+
+	// Spool the upload to a temporary file rather than memory
+	tmp, err := os.CreateTemp("", "mitto-import-*")
+	if err != nil {
+		http.Error(w, "Failed to import session", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, http.MaxBytesReader(w, r.Body, session.MaxExportBundleSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeErrorJSON(w, http.StatusRequestEntityTooLarge, "too_large", "Export bundle is too large")
+			return
+		}
+		writeErrorJSON(w, http.StatusBadRequest, "invalid_request", "Failed to read the export bundle")
+		return
+	}
+
+	bundle, err := session.ReadExportBundleFrom(tmp, size)
+	if err != nil {
+		if errors.Is(err, session.ErrExportBundleTooLarge) {
+			writeErrorJSON(w, http.StatusRequestEntityTooLarge, "too_large", err.Error())
+			return
+		}
+		writeErrorJSON(w, http.StatusBadRequest, "invalid_bundle", err.Error())
+		return
+	}
+
+	// Sessions keep the folder of the bundle unless moved to a workspace
+	bundles := []*session.ExportBundle{bundle}
+	if opts.WorkingDir == "" {
+		var workspaces []config.WorkspaceSettings
+		if s.sessionManager != nil {
+			workspaces = s.sessionManager.GetWorkspaces()
+		}
+		for _, b := range bundles {
+			if resolveOwningWorkspace(workspaceDir(b.Metadata.WorkingDir), workspaces) == nil {
+				writeErrorJSON(w, http.StatusBadRequest, "workspace_required",
+					"The folder of this conversation is not a configured workspace; choose a workspace to import it into")
+				return
+			}
+			if !s.webUserCanAccessDir(user, b.Metadata.WorkingDir) {
+				writeErrorJSON(w, http.StatusForbidden, "workspace_forbidden",
+					"You don't have access to the workspace of this conversation")
+				return
+			}
+		}
+	}
+
+	results, err := store.Import(bundles, opts)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("Failed to import session", "session_id", bundle.Metadata.SessionID, "error", err)
+		}
+		if errors.Is(err, session.ErrNotExportBundle) {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_bundle", err.Error())
+			return
+		}
+		http.Error(w, "Failed to import session", http.StatusInternalServerError)
+		return
+	}
+
+	if s.sessionManager != nil {
+		for _, result := range results {
+			if meta, err := store.GetMetadata(result.SessionID); err == nil {
+				s.sessionManager.BroadcastSessionCreated(meta.SessionID, meta.Name, meta.ACPServer, meta.WorkingDir,
+					meta.ParentSessionID, string(meta.ChildOrigin))
+			}
+		}
+	}
+
+	writeJSONCreated(w, SessionImportResponse{Sessions: results})
+}
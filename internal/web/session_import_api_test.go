@@ -0,0 +1,132 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+func TestHandleSessionImport(t *testing.T) {
+	src, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer src.Close()
+	if err := src.Create(session.Metadata{SessionID: "20240115-103000-abcd1234", ACPServer: "test-server", Name: "Moved", WorkingDir: "/home/bob/app"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := src.AppendEvent("20240115-103000-abcd1234", session.Event{
+		Type: session.EventTypeUserPrompt,
+		Data: session.UserPromptData{Message: "hello"},
+	}); err != nil {
+		t.Fatalf("AppendEvent failed: %v", err)
+	}
+	var bundle bytes.Buffer
+	if err := src.Export(&bundle, "20240115-103000-abcd1234", session.ExportOptions{Format: session.ExportFormatJSON, Zip: true}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	dst, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer dst.Close()
+	sm := NewSessionManager("", "", false, nil)
+	sm.SetWorkspaces([]config.WorkspaceSettings{
+		{UUID: "ws-ann", WorkingDir: "/home/ann/app", ACPServer: "test-server"},
+		{UUID: "ws-bob", WorkingDir: "/home/bob/app", ACPServer: "test-server"},
+	})
+	server := &Server{
+		sessionManager: sm,
+		store:          dst,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions/import", bytes.NewReader(bundle.Bytes()))
+	w := httptest.NewRecorder()
+	server.handleSessionImport(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Status = %d, want %d (body: %s)", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var resp SessionImportResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(resp.Sessions) != 1 || resp.Sessions[0].Events != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	meta, err := dst.GetMetadata(resp.Sessions[0].SessionID)
+	if err != nil || meta.Name != "Moved" {
+		t.Errorf("imported metadata = %+v (err %v)", meta, err)
+	}
+	if !meta.AdvancedSettings[session.FlagCanPromptUser] {
+		t.Errorf("AdvancedSettings = %v, want the default flags", meta.AdvancedSettings)
+	}
+
+	// Invalid bodies are rejected
+	req = httptest.NewRequest(http.MethodPost, "/api/sessions/import", strings.NewReader("# markdown"))
+	w = httptest.NewRecorder()
+	server.handleSessionImport(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// Unknown workspaces are rejected
+	req = httptest.NewRequest(http.MethodPost, "/api/sessions/import?workspace=nope", bytes.NewReader(bundle.Bytes()))
+	w = httptest.NewRecorder()
+	server.handleSessionImport(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// Conversations from folders that aren't workspaces need one
+	if err := src.Create(session.Metadata{SessionID: "20240115-103000-ef567890", ACPServer: "test-server", WorkingDir: "/srv/other"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	var other bytes.Buffer
+	if err := src.Export(&other, "20240115-103000-ef567890", session.ExportOptions{Format: session.ExportFormatJSON}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/sessions/import", bytes.NewReader(other.Bytes()))
+	w = httptest.NewRecorder()
+	server.handleSessionImport(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("import from an unknown folder: Status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/sessions/import?workspace=ws-ann", bytes.NewReader(other.Bytes()))
+	w = httptest.NewRecorder()
+	server.handleSessionImport(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("import from an unknown folder into a workspace: Status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	// Users restricted to some workspaces only import into those
+	ann := &config.WebUser{Username: "ann", Role: config.RoleOperator, Workspaces: []string{"ws-ann"}}
+	importAs := func(query string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/sessions/import"+query, bytes.NewReader(bundle.Bytes()))
+		req = req.WithContext(context.WithValue(req.Context(), contextKeyWebUser, ann))
+		w := httptest.NewRecorder()
+		server.handleSessionImport(w, req)
+		return w.Code
+	}
+	before, _ := dst.List()
+	if code := importAs(""); code != http.StatusForbidden {
+		t.Errorf("import into the folder of the bundle: Status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := importAs("?workspace=ws-bob"); code != http.StatusForbidden {
+		t.Errorf("import into another workspace: Status = %d, want %d", code, http.StatusForbidden)
+	}
+	if after, _ := dst.List(); len(after) != len(before) {
+		t.Errorf("forbidden imports created sessions: %d, want %d", len(after), len(before))
+	}
+	if code := importAs("?workspace=ws-ann"); code != http.StatusCreated {
+		t.Errorf("import into an accessible workspace: Status = %d, want %d", code, http.StatusCreated)
+	}
+}
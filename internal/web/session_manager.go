@@ -15,9 +15,11 @@ import (
 	"github.com/inercia/mitto/internal/auxiliary"
 	"github.com/inercia/mitto/internal/config"
 	"github.com/inercia/mitto/internal/mcpserver"
+	"github.com/inercia/mitto/internal/notify"
 	"github.com/inercia/mitto/internal/processors"
 	"github.com/inercia/mitto/internal/runner"
 	"github.com/inercia/mitto/internal/session"
+	"github.com/inercia/mitto/internal/worktree"
 )
 
 // MaxSessions is the maximum number of concurrent sessions allowed.
@@ -113,6 +115,22 @@ type SessionManager struct {
 	// mittoConfig contains the full Mitto configuration (for looking up agent configs).
 	mittoConfig *config.Config
 
+	// usageBudget enforces the usage budgets on queued messages (optional).
+	usageBudget *UsageBudget
+
+	// notifier sends lifecycle events to the outbound webhooks (optional).
+	notifier *notify.Notifier
+
+	// permissionTokens issues the approval tokens of permission requests (optional).
+	permissionTokens *PermissionTokens
+
+	// runPermissions is the permission policy file of `mitto run` (optional).
+	// Its rules are evaluated before the configured rules, its default after them.
+	runPermissions *config.PermissionPolicyFile
+
+	// onPromptFailed is called when a prompt of a session fails (optional).
+	onPromptFailed func(sessionID, message string)
+
 	// processorManager manages external command processors for message transformation.
 	processorManager *processors.Manager
 
@@ -248,6 +266,41 @@ func NewSessionManagerWithOptions(opts SessionManagerOptions) *SessionManager {
 	return sm
 }
 
+// Configure applies the Mitto configuration (global conversations, agent
+// settings and restricted runners) and loads the processors from the processors
+// directory. The web server and headless runs share it, so that their sessions
+// are created the same way.
+func (sm *SessionManager) Configure(mittoConfig *config.Config) {
+	if mittoConfig != nil {
+		// Global conversations config for message processing
+		sm.SetGlobalConversations(mittoConfig.Conversations)
+		// Full MittoConfig for agent-specific lookups
+		sm.SetMittoConfig(mittoConfig)
+		// Global restricted runner config for sandboxed execution
+		if mittoConfig.RestrictedRunners != nil {
+			sm.SetGlobalRestrictedRunners(mittoConfig.RestrictedRunners)
+			if sm.logger != nil {
+				sm.logger.Info("Global restricted runners configured",
+					"runner_types", len(mittoConfig.RestrictedRunners))
+			}
+		}
+	}
+
+	if processorsDir, err := appdir.ProcessorsDir(); err == nil {
+		procMgr := processors.NewManager(processorsDir, sm.logger)
+		if err := procMgr.Load(); err != nil {
+			if sm.logger != nil {
+				sm.logger.Warn("Failed to load processors", "error", err)
+			}
+		} else if len(procMgr.Processors()) > 0 {
+			sm.SetProcessorManager(procMgr)
+			if sm.logger != nil {
+				sm.logger.Info("Loaded processors", "count", len(procMgr.Processors()))
+			}
+		}
+	}
+}
+
 // SetGlobalConversations sets the global conversation processing configuration.
 // This is merged with workspace-specific configurations when creating sessions.
 func (sm *SessionManager) SetGlobalConversations(conv *config.ConversationsConfig) {
@@ -331,6 +384,7 @@ func (sm *SessionManager) GetWorkspace(workingDir string) *config.WorkspaceSetti
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
+	workingDir = workspaceDir(workingDir)
 	var first *config.WorkspaceSettings
 	for _, ws := range sm.workspaces {
 		if ws.WorkingDir == workingDir {
@@ -360,6 +414,7 @@ func (sm *SessionManager) GetWorkspaceByDirAndACP(workingDir, acpServer string)
 // IsDefault is preferred; otherwise the first match wins.
 // Caller must hold sm.mu.
 func (sm *SessionManager) getWorkspaceByDirAndACPLocked(workingDir, acpServer string) *config.WorkspaceSettings {
+	workingDir = workspaceDir(workingDir)
 	var first *config.WorkspaceSettings
 	for _, ws := range sm.workspaces {
 		if ws.WorkingDir == workingDir {
@@ -396,7 +451,7 @@ func (sm *SessionManager) resolveWorkspaceForACPLocked(workingDir, acpServer str
 	if acpServer != "" && sm.defaultWorkspace.ACPServer != acpServer {
 		return nil
 	}
-	if workingDir != "" && sm.defaultWorkspace.WorkingDir != "" && sm.defaultWorkspace.WorkingDir != workingDir {
+	if workingDir != "" && sm.defaultWorkspace.WorkingDir != "" && sm.defaultWorkspace.WorkingDir != workspaceDir(workingDir) {
 		return nil
 	}
 	return sm.defaultWorkspace
@@ -520,6 +575,7 @@ func (sm *SessionManager) GetWorkspacesForFolder(folder string) []config.Workspa
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
+	folder = workspaceDir(folder)
 	var result []config.WorkspaceSettings
 	seen := make(map[string]bool) // track by UUID to avoid duplicates
 
@@ -1135,6 +1191,34 @@ func (sm *SessionManager) getSharedProcess(workspace *config.WorkspaceSettings,
 	return process
 }
 
+// broadcastResourceLimitExceeded broadcasts an acp_error_permanent event when a
+// prompt of a session is cancelled for exceeding a resource limit of its workspace.
+func (sm *SessionManager) broadcastResourceLimitExceeded(sessionID string, err *ACPClassifiedError) {
+	sm.mu.RLock()
+	em := sm.eventsManager
+	store := sm.store
+	sm.mu.RUnlock()
+
+	if em == nil {
+		return
+	}
+
+	sessionName := ""
+	if store != nil {
+		if meta, metaErr := store.GetMetadata(sessionID); metaErr == nil {
+			sessionName = meta.Name
+		}
+	}
+	em.Broadcast(WSMsgTypeACPErrorPermanent, map[string]interface{}{
+		"session_id":    sessionID,
+		"session_name":  sessionName,
+		"error":         err.Error(),
+		"error_class":   err.Class.String(),
+		"user_message":  err.UserMessage,
+		"user_guidance": err.UserGuidance,
+	})
+}
+
 // BroadcastSessionCreated broadcasts a session_created event to all connected clients.
 // This is called when a new session is created (via HTTP API or MCP tools).
 func (sm *SessionManager) BroadcastSessionCreated(sessionID, name, acpServer, workingDir, parentSessionID, childOrigin string) {
@@ -1428,6 +1512,43 @@ func (sm *SessionManager) SetMittoConfig(cfg *config.Config) {
 	sm.mittoConfig = cfg
 }
 
+// SetUsageBudget sets the usage budget checked before sending queued messages.
+func (sm *SessionManager) SetUsageBudget(budget *UsageBudget) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.usageBudget = budget
+}
+
+// SetNotifier sets the outbound webhook notifier passed to new and resumed sessions.
+func (sm *SessionManager) SetNotifier(notifier *notify.Notifier) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.notifier = notifier
+}
+
+// SetPermissionTokens sets the issuer of approval tokens passed to new and resumed sessions.
+func (sm *SessionManager) SetPermissionTokens(tokens *PermissionTokens) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.permissionTokens = tokens
+}
+
+// SetRunPermissions sets the permission policy file applied to new and resumed
+// sessions, on top of the configured permission rules.
+func (sm *SessionManager) SetRunPermissions(file *config.PermissionPolicyFile) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.runPermissions = file
+}
+
+// SetOnPromptFailed sets the callback called when a prompt of a new or resumed
+// session fails, with the error shown to the user.
+func (sm *SessionManager) SetOnPromptFailed(fn func(sessionID, message string)) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.onPromptFailed = fn
+}
+
 // createRunner creates a restricted runner for the given workspace and agent.
 // workspace is optional — when provided, its RestrictedRunnerConfig (if set) overrides
 // any .mittorc workspace-level configuration for the same runner type.
@@ -1527,7 +1648,7 @@ func (sm *SessionManager) CreateSessionWithWorkspace(ctx context.Context, name,
 	} else {
 		// Try to find a workspace by working directory (first match)
 		for _, ws := range sm.workspaces {
-			if ws.WorkingDir == workingDir {
+			if ws.WorkingDir == workspaceDir(workingDir) {
 				foundWs = ws
 				break
 			}
@@ -1608,6 +1729,28 @@ func (sm *SessionManager) CreateSessionWithWorkspace(ctx context.Context, name,
 		effectiveWorkspace = foundWs
 	}
 
+	// Isolate the session in its own git worktree if the workspace asks for it.
+	// Sessions created inside an existing worktree (e.g. children) share it.
+	// The worktree is discarded again if the session cannot be created.
+	var persistedID string
+	var wt *worktree.Worktree
+	var wtInfo *session.WorktreeInfo
+	keepWorktree := false
+	if effectiveWorkspace != nil && effectiveWorkspace.Worktree && workspaceDir(workingDir) == workingDir {
+		persistedID = session.GenerateSessionID()
+		var err error
+		if wt, err = createSessionWorktree(ctx, workingDir, persistedID); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if !keepWorktree {
+				sm.discardWorktree(wt)
+			}
+		}()
+		wtInfo = worktreeInfo(wt, workingDir)
+		workingDir = wt.WorkingDir
+	}
+
 	// Create restricted runner if configured
 	r, err := sm.createRunner(workingDir, acpServer, effectiveWorkspace)
 	if err != nil {
@@ -1653,6 +1796,13 @@ func (sm *SessionManager) CreateSessionWithWorkspace(ctx context.Context, name,
 		}
 	}
 
+	// Permission rules of the workspace, the ACP server and the global config
+	policyWs := workspace
+	if policyWs == nil {
+		policyWs = foundWs
+	}
+	permissionPolicy := sm.permissionPolicy(policyWs, acpServer)
+
 	// Resolve shared ACP process for this workspace (if shared mode is enabled)
 	effectiveWs := workspace
 	if effectiveWs == nil {
@@ -1663,6 +1813,10 @@ func (sm *SessionManager) CreateSessionWithWorkspace(ctx context.Context, name,
 	}
 	sharedProcessStart := time.Now()
 	sharedProcess := sm.getSharedProcess(effectiveWs, acpCommand, acpCwd, acpEnv, r)
+	var resourceLimits *config.ResourceLimits
+	if effectiveWs != nil {
+		resourceLimits = effectiveWs.ResourceLimits
+	}
 	sharedProcessDuration := time.Since(sharedProcessStart)
 
 	// Ensure auxiliary sessions (title-gen, follow-up, etc.) are pre-warmed for
@@ -1684,8 +1838,8 @@ func (sm *SessionManager) CreateSessionWithWorkspace(ctx context.Context, name,
 
 	newBsStart := time.Now()
 	bs, err := NewBackgroundSession(BackgroundSessionConfig{
-		PersistedID:         "",  // Empty = generate fresh
-		CreationCtx:         ctx, // Propagate caller's context for the initial NewSession RPC
+		PersistedID:         persistedID, // Empty = generate fresh
+		CreationCtx:         ctx,         // Propagate caller's context for the initial NewSession RPC
 		ACPCommand:          acpCommand,
 		ACPCwd:              acpCwd,
 		Env:                 acpEnv,
@@ -1702,8 +1856,14 @@ func (sm *SessionManager) CreateSessionWithWorkspace(ctx context.Context, name,
 		FileLinksConfig:     fileLinksConfig,
 		APIPrefix:           sm.apiPrefix,
 		WorkspaceUUID:       workspaceUUID,
+		Worktree:            wtInfo,           // Recorded with the session metadata
 		MittoConfig:         sm.mittoConfig,   // Pass config for default flags
 		AvailableACPServers: availableServers, // Pre-computed workspace server list
+		UsageBudget:         sm.usageBudget,
+		Notifier:            sm.notifier,
+		PermissionTokens:    sm.permissionTokens,
+		OnPromptFailed:      sm.onPromptFailed,
+		PermissionPolicy:    permissionPolicy,
 		GlobalMCPServer:     sm.mcpServer,
 		AuxiliaryManager:    sm.auxiliaryManager,
 		SharedProcess:       sharedProcess,     // Shared ACP process (nil = legacy mode)
@@ -1771,6 +1931,8 @@ func (sm *SessionManager) CreateSessionWithWorkspace(ctx context.Context, name,
 		OnSelfDestruct: func(sessionID string) {
 			sm.deleteSessionAndChildren(sessionID, "self_destructed")
 		},
+		ResourceLimits:          resourceLimits,
+		OnResourceLimitExceeded: sm.broadcastResourceLimitExceeded,
 	})
 	if err != nil {
 		return nil, err
@@ -1791,6 +1953,7 @@ func (sm *SessionManager) CreateSessionWithWorkspace(ctx context.Context, name,
 	}
 	sm.sessions[bs.GetSessionID()] = bs
 	sm.mu.Unlock()
+	keepWorktree = true
 
 	newBsDuration := time.Since(newBsStart)
 
@@ -2251,6 +2414,10 @@ func (sm *SessionManager) ResumeSession(sessionID, sessionName, workingDir strin
 	// sessions, foundWs has already been resolved against the session's ACP server.
 	// Falling back again would risk mixing different ACP servers on the same folder.
 	sharedProcess := sm.getSharedProcess(foundWs, acpCommand, acpCwd, acpEnv, r)
+	var resourceLimits *config.ResourceLimits
+	if foundWs != nil {
+		resourceLimits = foundWs.ResourceLimits
+	}
 
 	// Build pruning configuration from global settings (with default)
 	pruneConfig := sm.buildPruneConfig()
@@ -2286,6 +2453,11 @@ func (sm *SessionManager) ResumeSession(sessionID, sessionName, workingDir strin
 		WorkspaceUUID:       workspaceUUID,
 		MittoConfig:         sm.mittoConfig,         // Pass config for default flags
 		AvailableACPServers: resumeAvailableServers, // Pre-computed workspace server list
+		UsageBudget:         sm.usageBudget,
+		Notifier:            sm.notifier,
+		PermissionTokens:    sm.permissionTokens,
+		OnPromptFailed:      sm.onPromptFailed,
+		PermissionPolicy:    sm.permissionPolicy(foundWs, acpServer),
 		GlobalMCPServer:     sm.mcpServer,
 		AuxiliaryManager:    sm.auxiliaryManager,
 		SharedProcess:       sharedProcess,     // Shared ACP process (nil = legacy mode)
@@ -2353,6 +2525,8 @@ func (sm *SessionManager) ResumeSession(sessionID, sessionName, workingDir strin
 		OnSelfDestruct: func(sessionID string) {
 			sm.deleteSessionAndChildren(sessionID, "self_destructed")
 		},
+		ResourceLimits:          resourceLimits,
+		OnResourceLimitExceeded: sm.broadcastResourceLimitExceeded,
 	})
 	// Release the startup semaphore now that the expensive ACP work is done.
 	// This happens on BOTH the success and error paths (both are immediately below).
@@ -2877,6 +3051,19 @@ func (sm *SessionManager) GetWorkspaceUUIDForSession(sessionID string) string {
 	return ""
 }
 
+// ConnectedClientCount returns the number of WebSocket clients connected to
+// the running sessions.
+func (sm *SessionManager) ConnectedClientCount() int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	count := 0
+	for _, bs := range sm.sessions {
+		count += bs.ConnectedClientCount()
+	}
+	return count
+}
+
 // GetSessionInfoByWorkspace returns session info grouped by workspace UUID.
 // Used by the ACP process GC to determine which processes are still needed.
 // The caller must NOT hold sm.mu when calling this method.
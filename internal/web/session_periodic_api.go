@@ -2,7 +2,10 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/inercia/mitto/internal/session"
 )
@@ -15,6 +18,8 @@ type PeriodicPromptRequest struct {
 	Enabled       bool              `json:"enabled"`
 	FreshContext  bool              `json:"fresh_context,omitempty"`
 	MaxIterations int               `json:"max_iterations,omitempty"`
+	// Cron, TimeZone and Blackouts are the optional calendar schedule.
+	session.Schedule
 }
 
 // PeriodicPromptPatchRequest is the request body for partial updates.
@@ -25,11 +30,40 @@ type PeriodicPromptPatchRequest struct {
 	Enabled       *bool              `json:"enabled,omitempty"`
 	FreshContext  *bool              `json:"fresh_context,omitempty"`
 	MaxIterations *int               `json:"max_iterations,omitempty"`
+	// Cron, TimeZone and Blackouts replace the corresponding schedule fields
+	// (an empty cron expression switches back to the frequency).
+	Cron      *string                   `json:"cron,omitempty"`
+	TimeZone  *string                   `json:"timezone,omitempty"`
+	Blackouts *[]session.BlackoutWindow `json:"blackouts,omitempty"`
+}
+
+// PeriodicPreviewRequest is the request body for POST /api/sessions/{id}/periodic/preview.
+type PeriodicPreviewRequest struct {
+	Frequency session.Frequency `json:"frequency"`
+	session.Schedule
+	// Count is the number of fire times to return (default 5).
+	Count int `json:"count,omitempty"`
+}
+
+// PeriodicPreviewResponse lists the next times a periodic prompt would be sent.
+type PeriodicPreviewResponse struct {
+	NextRuns []time.Time `json:"next_runs"`
+	TimeZone string      `json:"timezone"`
+}
+
+// defaultPeriodicPreviewRuns is the number of fire times previewed by default.
+const defaultPeriodicPreviewRuns = 5
+
+// isPeriodicValidationError reports whether err is caused by an invalid periodic configuration.
+func isPeriodicValidationError(err error) bool {
+	return errors.Is(err, session.ErrInvalidFrequency) || errors.Is(err, session.ErrInvalidSchedule) ||
+		errors.Is(err, session.ErrPromptEmpty) || errors.Is(err, session.ErrInvalidMaxIterations)
 }
 
 // handleSessionPeriodic handles periodic prompt operations for a session.
 // Routes: GET, PUT, PATCH, DELETE /api/sessions/{id}/periodic
 // Route: POST /api/sessions/{id}/periodic/run-now (immediate delivery)
+// Route: GET, POST /api/sessions/{id}/periodic/preview (next fire times)
 func (s *Server) handleSessionPeriodic(w http.ResponseWriter, r *http.Request, sessionID, subPath string) {
 	store := s.Store()
 	if store == nil {
@@ -48,6 +82,12 @@ func (s *Server) handleSessionPeriodic(w http.ResponseWriter, r *http.Request, s
 		return
 	}
 
+	// Previews don't modify anything
+	if subPath == "preview" {
+		s.handlePeriodicPreview(w, r, store.Periodic(sessionID))
+		return
+	}
+
 	// Prevent setting periodic on child sessions - only parents/top-level sessions can be periodic
 	if r.Method != http.MethodGet && meta.ParentSessionID != "" {
 		http.Error(w, "Cannot set periodic on a child conversation. Only parent or top-level conversations can be periodic.", http.StatusBadRequest)
@@ -108,10 +148,11 @@ func (s *Server) handleSetPeriodic(w http.ResponseWriter, r *http.Request, sessi
 		Enabled:       req.Enabled,
 		FreshContext:  req.FreshContext,
 		MaxIterations: req.MaxIterations,
+		Schedule:      req.Schedule,
 	}
 
 	if err := ps.Set(p); err != nil {
-		if err == session.ErrInvalidFrequency || err == session.ErrPromptEmpty || err == session.ErrInvalidMaxIterations {
+		if isPeriodicValidationError(err) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -155,12 +196,34 @@ func (s *Server) handlePatchPeriodic(w http.ResponseWriter, r *http.Request, ses
 		return
 	}
 
-	if err := ps.Update(req.Prompt, req.PromptName, req.Frequency, req.Enabled, req.FreshContext, req.MaxIterations); err != nil {
+	var schedule *session.Schedule
+	if req.Cron != nil || req.TimeZone != nil || req.Blackouts != nil {
+		existing, err := ps.Get()
 		if err == session.ErrPeriodicNotFound {
 			http.Error(w, "No periodic prompt configured", http.StatusNotFound)
 			return
+		} else if err != nil {
+			http.Error(w, "Failed to get periodic prompt", http.StatusInternalServerError)
+			return
 		}
-		if err == session.ErrInvalidFrequency || err == session.ErrPromptEmpty || err == session.ErrInvalidMaxIterations {
+		schedule = &existing.Schedule
+		if req.Cron != nil {
+			schedule.Cron = *req.Cron
+		}
+		if req.TimeZone != nil {
+			schedule.TimeZone = *req.TimeZone
+		}
+		if req.Blackouts != nil {
+			schedule.Blackouts = *req.Blackouts
+		}
+	}
+
+	if err := ps.Update(req.Prompt, req.PromptName, req.Frequency, req.Enabled, req.FreshContext, req.MaxIterations, schedule); err != nil {
+		if err == session.ErrPeriodicNotFound {
+			http.Error(w, "No periodic prompt configured", http.StatusNotFound)
+			return
+		}
+		if isPeriodicValidationError(err) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -200,6 +263,65 @@ func (s *Server) handlePatchPeriodic(w http.ResponseWriter, r *http.Request, ses
 	writeJSONOK(w, updated)
 }
 
+// handlePeriodicPreview handles /api/sessions/{id}/periodic/preview
+// GET: Returns the next fire times of the saved periodic prompt (?count=N, default 5).
+// POST: Returns the next fire times of the frequency and schedule in the body,
+// so that a schedule can be checked before saving it.
+func (s *Server) handlePeriodicPreview(w http.ResponseWriter, r *http.Request, ps *session.PeriodicStore) {
+	var (
+		p     *session.PeriodicPrompt
+		count = defaultPeriodicPreviewRuns
+	)
+	switch r.Method {
+	case http.MethodGet:
+		var err error
+		if p, err = ps.Get(); err != nil {
+			if err == session.ErrPeriodicNotFound {
+				http.Error(w, "No periodic prompt configured", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to get periodic prompt", http.StatusInternalServerError)
+			return
+		}
+		if c := r.URL.Query().Get("count"); c != "" {
+			n, err := strconv.Atoi(c)
+			if err != nil || n < 1 {
+				writeErrorJSON(w, http.StatusBadRequest, "invalid_count", "count must be a positive integer")
+				return
+			}
+			count = n
+		}
+	case http.MethodPost:
+		var req PeriodicPreviewRequest
+		if !parseJSONBody(w, r, &req) {
+			return
+		}
+		p = &session.PeriodicPrompt{Frequency: req.Frequency, Schedule: req.Schedule}
+		if req.Cron == "" {
+			if err := p.Frequency.Validate(); err != nil {
+				writeErrorJSON(w, http.StatusBadRequest, "invalid_schedule", err.Error())
+				return
+			}
+		}
+		if req.Count > 0 {
+			count = req.Count
+		}
+	default:
+		methodNotAllowed(w)
+		return
+	}
+
+	if err := p.Schedule.Validate(); err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, "invalid_schedule", err.Error())
+		return
+	}
+	runs := p.NextRuns(time.Now().UTC(), count)
+	if runs == nil {
+		runs = []time.Time{}
+	}
+	writeJSONOK(w, PeriodicPreviewResponse{NextRuns: runs, TimeZone: p.Location().String()})
+}
+
 // handleDeletePeriodic handles DELETE /api/sessions/{id}/periodic
 func (s *Server) handleDeletePeriodic(w http.ResponseWriter, sessionID string, ps *session.PeriodicStore) {
 	if err := ps.Delete(); err != nil {
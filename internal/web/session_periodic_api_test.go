@@ -0,0 +1,90 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/inercia/mitto/internal/session"
+)
+
+func TestHandlePeriodicPreview(t *testing.T) {
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	defer store.Close()
+
+	const sid = "20260101-120000-abcd1234"
+	if err := store.Create(session.Metadata{SessionID: sid, ACPServer: "test", WorkingDir: "/tmp"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	server := &Server{sessionManager: NewSessionManager("", "", false, nil), store: store}
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, "/api/sessions/"+sid+"/periodic/"+path, bytes.NewReader(data))
+		w := httptest.NewRecorder()
+		server.handleSessionPeriodic(w, req, sid, "preview")
+		return w
+	}
+
+	t.Run("no periodic", func(t *testing.T) {
+		if w := do(http.MethodGet, "preview", nil); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", w.Code)
+		}
+	})
+
+	t.Run("unsaved schedule", func(t *testing.T) {
+		w := do(http.MethodPost, "preview", map[string]any{"cron": "30 8 * * *", "count": 3})
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var resp PeriodicPreviewResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(resp.NextRuns) != 3 || resp.TimeZone != "UTC" {
+			t.Fatalf("response = %+v", resp)
+		}
+		for _, run := range resp.NextRuns {
+			if run.Hour() != 8 || run.Minute() != 30 {
+				t.Errorf("run at %s, want 08:30", run)
+			}
+		}
+	})
+
+	t.Run("invalid schedule", func(t *testing.T) {
+		if w := do(http.MethodPost, "preview", map[string]any{"cron": "not a cron"}); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+
+	t.Run("saved schedule", func(t *testing.T) {
+		if err := store.Periodic(sid).Set(&session.PeriodicPrompt{
+			Prompt:    "check",
+			Frequency: session.Frequency{Value: 1, Unit: session.FrequencyHours},
+			Enabled:   true,
+		}); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/sessions/"+sid+"/periodic/preview?count=4", nil)
+		w := httptest.NewRecorder()
+		server.handleSessionPeriodic(w, req, sid, "preview")
+		var resp PeriodicPreviewResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v (body %s)", err, w.Body.String())
+		}
+		if len(resp.NextRuns) != 4 {
+			t.Fatalf("next_runs = %v, want 4 runs", resp.NextRuns)
+		}
+		if d := resp.NextRuns[1].Sub(resp.NextRuns[0]); d.Hours() != 1 {
+			t.Errorf("interval = %s, want 1h", d)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/inercia/mitto/internal/session"
+)
+
+// ToolCallChangesResponse is the JSON response of GET /api/sessions/{id}/changes/{toolCallId}.
+type ToolCallChangesResponse struct {
+	ToolCallID string             `json:"tool_call_id"`
+	Files      []session.FileDiff `json:"files"`
+	// Diff is the unified diff of all the files written by the tool call.
+	Diff       string     `json:"diff"`
+	RevertedAt *time.Time `json:"reverted_at,omitempty"`
+}
+
+// newToolCallChangesResponse builds the response for the file changes of a tool call.
+func newToolCallChangesResponse(c *session.ToolCallChanges) ToolCallChangesResponse {
+	files := c.Diffs()
+	var diff strings.Builder
+	for _, f := range files {
+		diff.WriteString(f.Diff)
+	}
+	return ToolCallChangesResponse{
+		ToolCallID: c.ToolCallID,
+		Files:      files,
+		Diff:       diff.String(),
+		RevertedAt: c.RevertedAt,
+	}
+}
+
+// handleToolCallChanges handles /api/sessions/{id}/changes/{toolCallId}[/revert]
+// GET: Returns the unified diffs of the files written by the tool call
+// (?format=diff returns the plain unified diff).
+// POST .../revert: Restores the files to their content before the tool call.
+// Files modified since the tool call make the revert fail, unless ?force=true.
+func (s *Server) handleToolCallChanges(w http.ResponseWriter, r *http.Request, sessionID, toolCallID string, revert bool) {
+	if (revert && r.Method != http.MethodPost) || (!revert && r.Method != http.MethodGet) {
+		methodNotAllowed(w)
+		return
+	}
+
+	store := s.Store()
+	if store == nil {
+		http.Error(w, "Session store not available", http.StatusInternalServerError)
+		return
+	}
+	if _, err := store.GetMetadata(sessionID); err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get session", http.StatusInternalServerError)
+		return
+	}
+
+	var (
+		changes *session.ToolCallChanges
+		err     error
+	)
+	if revert {
+		changes, err = store.RevertToolCallChanges(sessionID, toolCallID, r.URL.Query().Get("force") == "true")
+	} else {
+		changes, err = store.GetToolCallChanges(sessionID, toolCallID)
+	}
+	switch {
+	case errors.Is(err, session.ErrToolCallChangesNotFound):
+		writeErrorJSON(w, http.StatusNotFound, "no_changes", "No file changes recorded for this tool call")
+		return
+	case errors.Is(err, session.ErrAlreadyReverted):
+		writeErrorJSON(w, http.StatusConflict, "already_reverted", "The changes of this tool call were already reverted")
+		return
+	case errors.Is(err, session.ErrRevertConflict):
+		writeErrorJSON(w, http.StatusConflict, "revert_conflict", err.Error())
+		return
+	case err != nil:
+		if s.logger != nil {
+			s.logger.Error("Failed to get tool call changes", "session_id", sessionID, "tool_call_id", toolCallID, "revert", revert, "error", err)
+		}
+		writeErrorJSON(w, http.StatusInternalServerError, "changes_failed", err.Error())
+		return
+	}
+
+	if revert && s.logger != nil {
+		s.logger.Info("Reverted tool call changes", "session_id", sessionID, "tool_call_id", toolCallID, "writes", len(changes.Changes))
+	}
+
+	resp := newToolCallChangesResponse(changes)
+	if r.URL.Query().Get("format") == "diff" {
+		w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+		_, _ = w.Write([]byte(resp.Diff))
+		return
+	}
+	writeJSONOK(w, resp)
+}
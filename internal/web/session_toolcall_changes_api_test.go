@@ -0,0 +1,75 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inercia/mitto/internal/session"
+)
+
+func TestHandleToolCallChanges(t *testing.T) {
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+
+	const sid = "20260101-120000-abcd1234"
+	// Not a git repository: reverting relies on the recorded snapshots only
+	dir := t.TempDir()
+	if err := store.Create(session.Metadata{SessionID: sid, ACPServer: "claude", WorkingDir: dir}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordFileChange(sid, "tool-1", session.FileChange{Seq: 2, Path: path, Existed: true, Before: "one\n", After: "one\ntwo\n"}); err != nil {
+		t.Fatalf("RecordFileChange failed: %v", err)
+	}
+
+	server := &Server{sessionManager: NewSessionManager("", "", false, nil), store: store}
+	do := func(method, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		server.handleSessionDetail(w, httptest.NewRequest(method, "/api/sessions/"+sid+"/"+target, nil))
+		return w
+	}
+
+	w := do(http.MethodGet, "changes/tool-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var resp ToolCallChangesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(resp.Files) != 1 || resp.Files[0].Additions != 1 || !strings.Contains(resp.Diff, "+two\n") || resp.RevertedAt != nil {
+		t.Errorf("response = %+v", resp)
+	}
+
+	if w := do(http.MethodGet, "changes/tool-1?format=diff"); !strings.HasPrefix(w.Body.String(), "--- "+path) {
+		t.Errorf("plain diff = %q", w.Body.String())
+	}
+	if w := do(http.MethodGet, "changes/tool-2"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown tool call: status = %d, want 404", w.Code)
+	}
+	if w := do(http.MethodGet, "changes/tool-1/revert"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET revert: status = %d, want 405", w.Code)
+	}
+
+	if w := do(http.MethodPost, "changes/tool-1/revert"); w.Code != http.StatusOK {
+		t.Fatalf("revert: status = %d, body %s", w.Code, w.Body.String())
+	}
+	if got, _ := os.ReadFile(path); string(got) != "one\n" {
+		t.Errorf("file after revert = %q, want %q", got, "one\n")
+	}
+	if w := do(http.MethodPost, "changes/tool-1/revert"); w.Code != http.StatusConflict {
+		t.Errorf("second revert: status = %d, want 409", w.Code)
+	}
+}
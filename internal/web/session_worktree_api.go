@@ -0,0 +1,198 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/session"
+	"github.com/inercia/mitto/internal/worktree"
+)
+
+// Worktree actions, available once the session is archived.
+const (
+	WorktreeActionMerge   = "merge"
+	WorktreeActionRebase  = "rebase"
+	WorktreeActionDiscard = "discard"
+)
+
+// worktreeActionTimeout bounds a merge, rebase or discard.
+const worktreeActionTimeout = 2 * time.Minute
+
+// WorktreeResponse is the response of GET /api/sessions/{id}/worktree.
+type WorktreeResponse struct {
+	session.WorktreeInfo
+	// Status is the state of the session branch relative to its base
+	// (omitted if it could not be determined, see Error).
+	Status *worktree.Status `json:"status,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// WorktreeActionRequest is the body of POST /api/sessions/{id}/worktree.
+type WorktreeActionRequest struct {
+	Action string `json:"action"` // "merge", "rebase" or "discard"
+}
+
+// workspaceDir maps a directory inside a session worktree back to the
+// corresponding directory of the workspace, so that sessions isolated in
+// worktrees resolve to their workspace. Other directories are returned unchanged.
+func workspaceDir(dir string) string {
+	root, err := appdir.WorktreesDir()
+	if err != nil {
+		return dir
+	}
+	return worktree.WorkspaceDir(root, dir)
+}
+
+// createSessionWorktree creates the git worktree of a new session, on the
+// session branch, from the repository containing dir.
+func createSessionWorktree(ctx context.Context, dir, sessionID string) (*worktree.Worktree, error) {
+	root, err := appdir.WorktreesDir()
+	if err != nil {
+		return nil, err
+	}
+	return worktree.Create(ctx, dir, filepath.Join(root, sessionID), worktree.BranchName(sessionID))
+}
+
+// discardWorktree removes the worktree and branch of a session that could not be created.
+func (sm *SessionManager) discardWorktree(wt *worktree.Worktree) {
+	ctx, cancel := context.WithTimeout(context.Background(), worktreeActionTimeout)
+	defer cancel()
+	if err := worktree.Discard(ctx, wt); err != nil && sm.logger != nil {
+		sm.logger.Warn("Failed to discard session worktree", "path", wt.Path, "error", err)
+	}
+}
+
+// worktreeInfo converts the worktree of a session in workspaceDir to its session metadata.
+func worktreeInfo(wt *worktree.Worktree, workspaceDir string) *session.WorktreeInfo {
+	return &session.WorktreeInfo{
+		Path:         wt.Path,
+		RepoDir:      wt.RepoDir,
+		WorkspaceDir: workspaceDir,
+		Branch:       wt.Branch,
+		BaseBranch:   wt.BaseBranch,
+		BaseCommit:   wt.BaseCommit,
+	}
+}
+
+// worktreeFromInfo converts session metadata to a worktree.
+func worktreeFromInfo(info *session.WorktreeInfo) *worktree.Worktree {
+	return &worktree.Worktree{
+		Path:       info.Path,
+		RepoDir:    info.RepoDir,
+		Branch:     info.Branch,
+		BaseBranch: info.BaseBranch,
+		BaseCommit: info.BaseCommit,
+	}
+}
+
+// handleSessionWorktree handles /api/sessions/{id}/worktree
+// GET: Returns the session's git worktree and the state of its branch.
+// POST: Merges, rebases or discards the session branch (body: {"action": "merge"}).
+// Actions are only allowed on archived sessions, so that no agent is working in the worktree.
+func (s *Server) handleSessionWorktree(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	store := s.Store()
+	if store == nil {
+		http.Error(w, "Session store not available", http.StatusInternalServerError)
+		return
+	}
+	meta, err := store.GetMetadata(sessionID)
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get session", http.StatusInternalServerError)
+		return
+	}
+	if meta.Worktree == nil {
+		writeErrorJSON(w, http.StatusNotFound, "no_worktree", "Session is not isolated in a git worktree")
+		return
+	}
+	wt := worktreeFromInfo(meta.Worktree)
+
+	if r.Method == http.MethodGet {
+		ctx, cancel := context.WithTimeout(r.Context(), gitChangesTimeout)
+		defer cancel()
+		resp := WorktreeResponse{WorktreeInfo: *meta.Worktree}
+		if status, err := worktree.GetStatus(ctx, wt); err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Status = &status
+		}
+		writeJSONOK(w, resp)
+		return
+	}
+
+	var req WorktreeActionRequest
+	if !parseJSONBody(w, r, &req) {
+		return
+	}
+	if !meta.Archived {
+		writeErrorJSON(w, http.StatusConflict, "session_not_archived", "Archive the conversation before merging, rebasing or discarding its worktree")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), worktreeActionTimeout)
+	defer cancel()
+	message := meta.Name
+	if message == "" {
+		message = "Conversation " + sessionID
+	}
+	switch req.Action {
+	case WorktreeActionMerge:
+		err = worktree.Merge(ctx, wt, message)
+	case WorktreeActionRebase:
+		err = worktree.Rebase(ctx, wt, message)
+	case WorktreeActionDiscard:
+		err = worktree.Discard(ctx, wt)
+	default:
+		writeErrorJSON(w, http.StatusBadRequest, "invalid_action", "Action must be merge, rebase or discard")
+		return
+	}
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("Worktree action failed", "session_id", sessionID, "action", req.Action, "error", err)
+		}
+		writeErrorJSON(w, http.StatusConflict, "worktree_action_failed", err.Error())
+		return
+	}
+
+	if s.logger != nil {
+		s.logger.Info("Worktree action completed", "session_id", sessionID, "action", req.Action, "branch", wt.Branch)
+	}
+	s.detachWorktree(store, meta.Worktree)
+	writeNoContent(w)
+}
+
+// detachWorktree moves the sessions working in a removed worktree (the session
+// itself and any children sharing it) back to the workspace folder.
+func (s *Server) detachWorktree(store *session.Store, info *session.WorktreeInfo) {
+	sessions, err := store.List()
+	if err != nil {
+		return
+	}
+	for _, meta := range sessions {
+		rel, err := filepath.Rel(info.Path, meta.WorkingDir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if err := store.UpdateMetadata(meta.SessionID, func(m *session.Metadata) {
+			m.WorkingDir = filepath.Join(info.RepoDir, rel)
+			if m.Worktree != nil && m.Worktree.Path == info.Path {
+				m.Worktree = nil
+			}
+		}); err != nil && s.logger != nil {
+			s.logger.Warn("Failed to detach session from worktree", "session_id", meta.SessionID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/mitto/internal/appdir"
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+// newWorktreeTestRepo creates a git repository with one commit on "main".
+func newWorktreeTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"init", "-q", "-b", "main"}, {"add", "-A"}, {"commit", "-q", "-m", "initial"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+	return repo
+}
+
+func TestHandleSessionWorktree(t *testing.T) {
+	repo := newWorktreeTestRepo(t)
+	t.Setenv(appdir.MittoDirEnv, t.TempDir())
+	appdir.ResetCache()
+	t.Cleanup(appdir.ResetCache)
+
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+
+	wt, err := createSessionWorktree(context.Background(), repo, "s1")
+	if err != nil {
+		t.Fatalf("createSessionWorktree failed: %v", err)
+	}
+	for _, meta := range []session.Metadata{
+		{SessionID: "s1", Name: "Add notes", ACPServer: "claude", WorkingDir: wt.WorkingDir, Worktree: worktreeInfo(wt, repo)},
+		{SessionID: "c1", ACPServer: "claude", WorkingDir: wt.WorkingDir, ParentSessionID: "s1"},
+	} {
+		if err := store.Create(meta); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	sm := NewSessionManager("", "", false, nil)
+	sm.SetWorkspaces([]config.WorkspaceSettings{{WorkingDir: repo, ACPServer: "claude", Worktree: true}})
+	if ws := sm.GetWorkspace(wt.WorkingDir); ws == nil || ws.WorkingDir != repo {
+		t.Errorf("GetWorkspace(worktree) = %+v, want the workspace of the repository", ws)
+	}
+	server := &Server{sessionManager: sm, store: store}
+
+	do := func(method, sub, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/sessions/s1/"+sub, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		if sub == "changes" {
+			server.handleSessionChanges(w, req, "s1")
+		} else {
+			server.handleSessionWorktree(w, req, "s1")
+		}
+		return w
+	}
+
+	// Committed, modified and untracked files are all reported against the base branch
+	for name, content := range map[string]string{"committed.txt": "a\n", "README.md": "hello\nworld\n"} {
+		if err := os.WriteFile(filepath.Join(wt.Path, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"add", "committed.txt"}, {"commit", "-q", "-m", "wip"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = wt.Path
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(wt.Path, "untracked.txt"), []byte("u\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := do(http.MethodGet, "changes", "")
+	var changes ChangesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &changes); err != nil {
+		t.Fatalf("Failed to unmarshal changes: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, f := range changes.Files {
+		statuses[f.Path] = f.Status
+	}
+	if changes.Branch != "mitto/s1" || statuses["committed.txt"] != "A" || statuses["README.md"] != "M" || statuses["untracked.txt"] != "?" {
+		t.Errorf("changes = %+v", changes)
+	}
+
+	w = do(http.MethodGet, "worktree", "")
+	var info WorktreeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatalf("Failed to unmarshal worktree: %v", err)
+	}
+	if info.Branch != "mitto/s1" || info.BaseBranch != "main" || info.Status == nil || info.Status.Ahead != 1 || !info.Status.Dirty {
+		t.Errorf("worktree = %+v", info)
+	}
+
+	if w := do(http.MethodPost, "worktree", `{"action":"merge"}`); w.Code != http.StatusConflict {
+		t.Errorf("merge before archiving: status = %d, want 409", w.Code)
+	}
+	if err := store.UpdateMetadata("s1", func(m *session.Metadata) { m.Archived = true }); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	if w := do(http.MethodPost, "worktree", `{"action":"squash"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid action: status = %d, want 400", w.Code)
+	}
+	if w := do(http.MethodPost, "worktree", `{"action":"merge"}`); w.Code != http.StatusNoContent {
+		t.Fatalf("merge: status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	if _, err := os.Stat(filepath.Join(repo, "untracked.txt")); err != nil {
+		t.Errorf("merged file missing from the repository: %v", err)
+	}
+	for _, id := range []string{"s1", "c1"} {
+		meta, err := store.GetMetadata(id)
+		if err != nil {
+			t.Fatalf("GetMetadata failed: %v", err)
+		}
+		if meta.Worktree != nil || meta.WorkingDir != repo {
+			t.Errorf("%s: working dir = %q, worktree = %+v; want moved back to the repository", id, meta.WorkingDir, meta.Worktree)
+		}
+	}
+	if w := do(http.MethodGet, "worktree", ""); w.Code != http.StatusNotFound {
+		t.Errorf("worktree after merge: status = %d, want 404", w.Code)
+	}
+}
+
+func TestPeriodicRunner_WorktreeCleanup(t *testing.T) {
+	repo := newWorktreeTestRepo(t)
+	t.Setenv(appdir.MittoDirEnv, t.TempDir())
+	appdir.ResetCache()
+	t.Cleanup(appdir.ResetCache)
+	root, err := appdir.WorktreesDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := make(map[string]string)
+	for _, sessionID := range []string{"recorded", "running", "orphaned"} {
+		wt, err := createSessionWorktree(context.Background(), repo, sessionID)
+		if err != nil {
+			t.Fatalf("createSessionWorktree failed: %v", err)
+		}
+		old := time.Now().Add(-2 * worktreeCleanupGrace)
+		if err := os.Chtimes(wt.Path, old, old); err != nil {
+			t.Fatal(err)
+		}
+		paths[sessionID] = wt.Path
+	}
+
+	// The running session doesn't record its worktree in its metadata
+	sm := NewSessionManager("", "", false, nil)
+	sm.sessions["running"] = &BackgroundSession{workingDir: paths["running"]}
+	runner := NewPeriodicRunner(nil, sm, nil)
+	runner.SetWorktreesDir(root)
+	runner.checkWorktreeCleanup([]session.Metadata{
+		{SessionID: "recorded", Worktree: &session.WorktreeInfo{Path: paths["recorded"]}},
+	})
+
+	for sessionID, path := range paths {
+		_, err := os.Stat(path)
+		if removed := os.IsNotExist(err); removed != (sessionID == "orphaned") {
+			t.Errorf("worktree of %s: removed = %v", sessionID, removed)
+		}
+	}
+}
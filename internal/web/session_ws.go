@@ -15,6 +15,7 @@ import (
 
 	acp "github.com/coder/acp-go-sdk"
 
+	"github.com/inercia/mitto/internal/audit"
 	"github.com/inercia/mitto/internal/config"
 	"github.com/inercia/mitto/internal/logging"
 	"github.com/inercia/mitto/internal/session"
@@ -83,6 +84,17 @@ type SessionWSClient struct {
 	// Session store for persistence operations
 	store *session.Store
 
+	// API token the connection was authenticated with (nil for other kinds of
+	// authentication). Its scopes limit the messages the client can send.
+	apiToken *APIToken
+
+	// User account of the connection (nil for full access), whose role limits
+	// the messages the client can send, and the identity prompts are sent as.
+	user     *config.WebUser
+	authUser string
+	// Client IP of the connection, recorded in the audit log.
+	clientIP string
+
 	// Seq tracking for deduplication - prevents sending the same event twice
 	// This is the core of the WebSocket-only architecture: the server guarantees
 	// no duplicates by tracking what has been sent to each client.
@@ -192,6 +204,10 @@ func (s *Server) handleSessionWS(w http.ResponseWriter, r *http.Request) {
 		ctx:       ctx,
 		cancel:    cancel,
 		store:     store,
+		apiToken:  apiTokenFromContext(r.Context()),
+		user:      webUserFromContext(r.Context()),
+		authUser:  authUserName(r.Context()),
+		clientIP:  clientIP,
 	}
 
 	// Try to get existing background session first
@@ -566,6 +582,15 @@ func (c *SessionWSClient) writePump() {
 }
 
 func (c *SessionWSClient) handleMessage(msg WSMessage) {
+	if scope := wsMessageScope(msg.Type); c.apiToken != nil && scope != "" && !c.apiToken.HasScope(scope) {
+		c.sendError("The API token lacks the " + scope + " scope")
+		return
+	}
+	if wsMessageScope(msg.Type) != "" && !c.user.HasRole(config.RoleOperator) {
+		c.sendError("The " + config.RoleOperator + " role is required")
+		return
+	}
+
 	switch msg.Type {
 	case WSMsgTypePrompt:
 		var data struct {
@@ -661,6 +686,19 @@ func (c *SessionWSClient) handleMessage(msg WSMessage) {
 	}
 }
 
+// wsMessageScope returns the API token scope needed to send a message type,
+// or "" for the messages that only read the conversation (the sessions:read
+// scope needed to connect is enough).
+func wsMessageScope(msgType string) string {
+	switch msgType {
+	case WSMsgTypePrompt, WSMsgTypeCancel, WSMsgTypeForceReset, WSMsgTypeSetConfigOption, WSMsgTypeUIPromptAnswer:
+		return ScopePromptsSend
+	case WSMsgTypePermissionAnswer:
+		return ScopePermissionsApprove
+	}
+	return ""
+}
+
 func (c *SessionWSClient) handlePromptWithMeta(message string, promptName string, promptID string, imageIDs, fileIDs []string) {
 	// If bgSession is nil, try to attach to a running session.
 	// This handles the case where the session was unarchived after this client connected.
@@ -680,6 +718,7 @@ func (c *SessionWSClient) handlePromptWithMeta(message string, promptName string
 	// Send prompt to background session with sender info for multi-client broadcast
 	meta := PromptMeta{
 		SenderID:   c.clientID,
+		SentBy:     c.authUser,
 		PromptID:   promptID,
 		PromptName: promptName,
 		ImageIDs:   imageIDs,
@@ -766,6 +805,17 @@ func (c *SessionWSClient) handleUIPromptAnswer(requestID, optionID, label, freeT
 			"has_free_text", freeText != "")
 	}
 
+	// Answers to permission requests are recorded in the audit log
+	if req := pendingPermissionRequest(c.bgSession); req != nil && req.RequestID == requestID {
+		for i := range req.Options {
+			if req.Options[i].ID == optionID {
+				actor := audit.Actor{IP: c.clientIP, User: c.authUser}
+				auditPermissionAnswer(actor, c.sessionID, req, &req.Options[i], "websocket")
+				break
+			}
+		}
+	}
+
 	// Forward the answer to the background session
 	c.bgSession.HandleUIPromptAnswer(requestID, optionID, label, freeText)
 }
@@ -2313,6 +2363,27 @@ func (c *SessionWSClient) OnContextUsageUpdate(size, used int) {
 	})
 }
 
+// OnTerminalOutput is called when an agent terminal produces output or exits.
+func (c *SessionWSClient) OnTerminalOutput(update TerminalOutputUpdate) {
+	data := map[string]interface{}{
+		"session_id":  c.sessionID,
+		"terminal_id": update.TerminalID,
+	}
+	if update.Output != "" {
+		data["output"] = update.Output
+	}
+	if update.Exited {
+		data["exited"] = true
+		if update.ExitCode != nil {
+			data["exit_code"] = *update.ExitCode
+		}
+		if update.Signal != nil {
+			data["signal"] = *update.Signal
+		}
+	}
+	c.sendMessage(WSMsgTypeTerminalOutput, data)
+}
+
 // actionButtonsKey builds a lightweight dedup key from a slice of buttons.
 // It concatenates "label\x00response" pairs separated by "\x01" so that
 // different label/response orderings produce different keys.
@@ -13,10 +13,13 @@ import (
 	"time"
 
 	"github.com/coder/acp-go-sdk"
+	"go.opentelemetry.io/otel/attribute"
 
 	mittoAcp "github.com/inercia/mitto/internal/acp"
+	"github.com/inercia/mitto/internal/config"
 	"github.com/inercia/mitto/internal/logging"
 	"github.com/inercia/mitto/internal/runner"
+	"github.com/inercia/mitto/internal/tracing"
 )
 
 const (
@@ -73,6 +76,10 @@ type SharedACPProcessConfig struct {
 	CanRestartGlobal func() bool
 	// RecordRestart is an optional callback to record a restart in the global tracker.
 	RecordRestart func()
+	// ResourceLimits are the resource limits of the workspace (nil = unlimited).
+	// A memory limit is enforced by the kernel when the process can be placed
+	// in its own cgroup.
+	ResourceLimits *config.ResourceLimits
 }
 
 // SessionHandle is returned when creating a new session on a SharedACPProcess.
@@ -105,6 +112,9 @@ type SharedACPProcess struct {
 	wait   func() error
 	cancel context.CancelFunc // for restricted runner processes
 
+	// sampler samples the resource usage of the process tree (nil with a restricted runner).
+	sampler resourceSampler
+
 	// Process death detection (Fix A: faster crash detection)
 	// processDone is closed when the ACP OS process exits, providing sub-second
 	// detection via OS-level liveness checks (signal 0 polling).
@@ -353,6 +363,12 @@ func (p *SharedACPProcess) doStartProcess() (string, error) {
 		signalStartupActivity = startACPStartupWatchdog(watchdogCtx, p.logger, acpCommand, p.config.ACPServer, -1)
 
 		startStderrMonitor(stderr, stderrCollector, onCrashDetected, signalStartupActivity)
+
+		p.sampler = nil // the pid of the runner-spawned process is not known
+		if p.config.ResourceLimits.IsEnabled() && p.logger != nil {
+			p.logger.Warn("resource limits are not supported with restricted runners, only the prompt duration is enforced",
+				"runner_type", p.config.Runner.Type())
+		}
 	} else {
 		cmd = exec.CommandContext(p.ctx, args[0], args[1:]...)
 		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
@@ -413,6 +429,8 @@ func (p *SharedACPProcess) doStartProcess() (string, error) {
 		}
 		signalStartupActivity = startACPStartupWatchdog(watchdogCtx, p.logger, acpCommand, p.config.ACPServer, pid)
 
+		p.sampler = newResourceSampler(pid, p.config.WorkspaceUUID, p.config.ResourceLimits, p.logger)
+
 		startStderrMonitor(stderrPipe, stderrCollector, onCrashDetected, signalStartupActivity)
 
 		wait = func() error {
@@ -425,8 +443,14 @@ func (p *SharedACPProcess) doStartProcess() (string, error) {
 	// Wrap wait function to also close processDone channel on process exit.
 	// The channel was pre-created above (before stderr monitors started).
 	origWait := wait
+	sampler := p.sampler
 	p.wait = func() error {
 		err := origWait()
+		if sampler != nil {
+			if closeErr := sampler.Close(); closeErr != nil && p.logger != nil {
+				p.logger.Debug("Failed to release the ACP process resource sampler", "error", closeErr)
+			}
+		}
 
 		// Log exit code and signal for crash telemetry
 		if err != nil && p.logger != nil {
@@ -551,6 +575,7 @@ func (p *SharedACPProcess) doStartProcess() (string, error) {
 				ReadTextFile:  true,
 				WriteTextFile: true,
 			},
+			Terminal: true,
 		},
 		ClientInfo: &acp.Implementation{
 			Name:    "mitto",
@@ -887,10 +912,21 @@ func (p *SharedACPProcess) ProcessDone() <-chan struct{} {
 }
 
 // Prompt sends a prompt to a specific session on this shared process.
-func (p *SharedACPProcess) Prompt(ctx context.Context, sessionID acp.SessionId, content []acp.ContentBlock) (acp.PromptResponse, error) {
+func (p *SharedACPProcess) Prompt(ctx context.Context, sessionID acp.SessionId, content []acp.ContentBlock) (resp acp.PromptResponse, err error) {
 	p.activeRPCs.Add(1)
 	defer p.activeRPCs.Add(-1)
 
+	ctx, span := tracing.Start(ctx, "acp.prompt",
+		attribute.String("mitto.acp_session_id", string(sessionID)),
+		attribute.String("mitto.acp_server", p.config.ACPServer),
+		attribute.String("mitto.workspace_uuid", p.config.WorkspaceUUID))
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attribute.String("mitto.stop_reason", string(resp.StopReason)))
+		}
+		tracing.End(span, err)
+	}()
+
 	p.mu.RLock()
 	conn := p.conn
 	p.mu.RUnlock()
@@ -928,6 +964,14 @@ func (p *SharedACPProcess) RSSBytes() (uint64, error) {
 	return processTreeRSS(pid)
 }
 
+// ResourceSampler returns the sampler of the resource usage of the process tree,
+// or nil if the process was started through a restricted runner.
+func (p *SharedACPProcess) ResourceSampler() resourceSampler {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.sampler
+}
+
 // Cancel cancels the current operation for a specific session.
 func (p *SharedACPProcess) Cancel(ctx context.Context, sessionID acp.SessionId) error {
 	p.mu.RLock()
@@ -1173,6 +1217,7 @@ func (p *SharedACPProcess) recordRestart() {
 	defer p.restartMu.Unlock()
 	p.restartCount++
 	p.restartTimes = append(p.restartTimes, time.Now())
+	metricACPProcessRestarts.Inc()
 }
 
 // Restart kills the old process and starts a new one.
@@ -2,6 +2,7 @@
 package web
 
 import (
+	"context"
 	"sync"
 
 	"github.com/inercia/mitto/internal/conversion"
@@ -46,6 +47,8 @@ type StreamBufferConfig struct {
 	// SeqProvider provides sequence numbers for event ordering.
 	// Seq is assigned at emit time (not receive time) to ensure contiguous numbers.
 	SeqProvider SeqProvider
+	// TraceContext returns the trace context of the markdown flush spans (optional).
+	TraceContext func() context.Context
 }
 
 // StreamBuffer buffers all streaming events and emits them in correct order.
@@ -82,6 +85,7 @@ func NewStreamBuffer(cfg StreamBufferConfig) *StreamBuffer {
 			sb.onMarkdownFlush(html)
 		},
 		FileLinksConfig: cfg.FileLinksConfig,
+		TraceContext:    cfg.TraceContext,
 	})
 
 	// Create thought buffer that coalesces thought chunks
@@ -0,0 +1,298 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inercia/mitto/internal/fileutil"
+)
+
+const (
+	// totpPeriod is the time step of the codes (RFC 6238 default).
+	totpPeriod = 30
+	// totpDigits is the number of digits of the codes.
+	totpDigits = 6
+	// totpSkew is the number of time steps accepted before and after the
+	// current one, to allow for clock drift.
+	totpSkew = 1
+	// totpSecretLength is the length of the secrets in bytes (RFC 4226 recommends 160 bits).
+	totpSecretLength = 20
+	// totpIssuer is the issuer shown by authenticator apps.
+	totpIssuer = "Mitto"
+
+	// recoveryCodeCount is the number of recovery codes given on enrollment.
+	recoveryCodeCount = 10
+)
+
+var (
+	// ErrTOTPNotEnrolled is returned when a user has no (pending) enrollment.
+	ErrTOTPNotEnrolled = errors.New("two-factor authentication is not enrolled")
+
+	// ErrTOTPAlreadyEnabled is returned when enrolling a user that already has
+	// two-factor authentication enabled.
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+	// ErrInvalidTOTPCode is returned for wrong, reused or expired codes.
+	ErrInvalidTOTPCode = errors.New("invalid two-factor authentication code")
+)
+
+// totpBase32 is the encoding of the secrets in provisioning URIs.
+var totpBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment is the two-factor authentication of a user.
+type TOTPEnrollment struct {
+	// Secret is the base32 shared secret of the authenticator app.
+	Secret string `json:"secret"`
+	// Enabled is false until the user confirms the enrollment with a code.
+	Enabled bool `json:"enabled"`
+	// RecoveryCodes are the SHA-256 hashes of the unused recovery codes.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	// LastStep is the time step of the last accepted code, so that codes
+	// can't be replayed.
+	LastStep  int64     `json:"last_step,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// totpFile is the structure of the totp.json file.
+type totpFile struct {
+	Users map[string]*TOTPEnrollment `json:"users"`
+}
+
+// TOTPStore keeps the RFC 6238 two-factor authentication enrollments of the
+// users that log in with a password. The file is read on every operation, so
+// that 'mitto users totp-reset' takes effect on a running server.
+type TOTPStore struct {
+	path string
+	mu   sync.Mutex
+	now  func() time.Time
+}
+
+// NewTOTPStore creates a store of two-factor authentication enrollments in
+// the given file.
+func NewTOTPStore(path string) *TOTPStore {
+	return &TOTPStore{path: path, now: time.Now}
+}
+
+// load reads the enrollments. Must be called with s.mu held.
+func (s *TOTPStore) load() (map[string]*TOTPEnrollment, error) {
+	var file totpFile
+	if err := fileutil.ReadJSON(s.path, &file); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+	if file.Users == nil {
+		file.Users = make(map[string]*TOTPEnrollment)
+	}
+	return file.Users, nil
+}
+
+// save writes the enrollments. Must be called with s.mu held.
+func (s *TOTPStore) save(users map[string]*TOTPEnrollment) error {
+	return fileutil.WriteJSONAtomic(s.path, totpFile{Users: users}, 0o600)
+}
+
+// Status returns whether a user has two-factor authentication enabled or
+// pending confirmation, and the number of unused recovery codes.
+func (s *TOTPStore) Status(username string) (enabled, pending bool, recoveryCodes int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return false, false, 0, err
+	}
+	e, ok := users[username]
+	if !ok {
+		return false, false, 0, nil
+	}
+	return e.Enabled, !e.Enabled, len(e.RecoveryCodes), nil
+}
+
+// IsEnabled returns true if a user must enter a code to log in. Errors
+// reading the file are reported as enabled, so that logins fail closed.
+func (s *TOTPStore) IsEnabled(username string) bool {
+	enabled, _, _, err := s.Status(username)
+	return enabled || err != nil
+}
+
+// Enroll starts the enrollment of a user, replacing any pending one, and
+// returns the new secret and its provisioning URI.
+func (s *TOTPStore) Enroll(username string) (secret, uri string, err error) {
+	raw := make([]byte, totpSecretLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret = totpBase32.EncodeToString(raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return "", "", err
+	}
+	if e, ok := users[username]; ok && e.Enabled {
+		return "", "", ErrTOTPAlreadyEnabled
+	}
+	users[username] = &TOTPEnrollment{Secret: secret, CreatedAt: s.now().UTC()}
+	if err := s.save(users); err != nil {
+		return "", "", err
+	}
+	return secret, TOTPProvisioningURI(username, secret), nil
+}
+
+// Confirm enables the pending enrollment of a user with a code of the
+// authenticator app, and returns the recovery codes (only known now).
+func (s *TOTPStore) Confirm(username, code string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	e, ok := users[username]
+	if !ok {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if e.Enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if !s.verifyCode(e, code) {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	e.RecoveryCodes = make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		h := hex.EncodeToString(b)
+		codes[i] = h[:5] + "-" + h[5:]
+		e.RecoveryCodes[i] = hashRecoveryCode(codes[i])
+	}
+	e.Enabled = true
+	if err := s.save(users); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a code of the authenticator app, or a recovery code (which
+// is then used up), of a user with two-factor authentication enabled.
+func (s *TOTPStore) Verify(username, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return err
+	}
+	e, ok := users[username]
+	if !ok || !e.Enabled {
+		return ErrTOTPNotEnrolled
+	}
+
+	if s.verifyCode(e, code) {
+		return s.save(users) // Persist LastStep
+	}
+	hash := hashRecoveryCode(code)
+	for i, h := range e.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			e.RecoveryCodes = append(e.RecoveryCodes[:i], e.RecoveryCodes[i+1:]...)
+			return s.save(users)
+		}
+	}
+	return ErrInvalidTOTPCode
+}
+
+// Reset removes the enrollment of a user. It returns false if there was none.
+func (s *TOTPStore) Reset(username string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := users[username]; !ok {
+		return false, nil
+	}
+	delete(users, username)
+	return true, s.save(users)
+}
+
+// verifyCode checks a code of the authenticator app against the time steps
+// around now, rejecting steps already used, and records the step.
+func (s *TOTPStore) verifyCode(e *TOTPEnrollment, code string) bool {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return false
+	}
+	secret, err := totpBase32.DecodeString(e.Secret)
+	if err != nil {
+		return false
+	}
+	step := s.now().Unix() / totpPeriod
+	for i := step - totpSkew; i <= step+totpSkew; i++ {
+		if i <= e.LastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, i)), []byte(code)) == 1 {
+			e.LastStep = i
+			return true
+		}
+	}
+	return false
+}
+
+// totpCode returns the code of a time step (RFC 6238 with HMAC-SHA1, as
+// supported by every authenticator app).
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226 section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%uint32(math.Pow10(totpDigits)))
+}
+
+// hashRecoveryCode returns the hash stored for a recovery code, ignoring
+// case, spaces and dashes.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// TOTPProvisioningURI returns the otpauth:// URI of a secret, which
+// authenticator apps import (usually as a QR code).
+func TOTPProvisioningURI(username, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", totpIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(totpIssuer + ":" + username)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
@@ -0,0 +1,165 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TOTPStatusResponse is the response of GET /api/auth/totp.
+type TOTPStatusResponse struct {
+	Username string `json:"username"`
+	Enabled  bool   `json:"enabled"`
+	// Pending is true while an enrollment waits for its confirmation code.
+	Pending           bool `json:"pending"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TOTPEnrollResponse is the response of POST /api/auth/totp/enroll.
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	// ProvisioningURI is the otpauth:// URI to import in authenticator apps.
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TOTPCodeRequest is the body of POST /api/auth/totp/confirm and /disable.
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TOTPConfirmResponse is the response of POST /api/auth/totp/confirm.
+type TOTPConfirmResponse struct {
+	// RecoveryCodes log in once each without the authenticator app. They are
+	// only returned here.
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// totpUser returns the user whose two-factor authentication a request
+// manages: the user logged in with a password, or the simple auth user for
+// requests that need no login (localhost). Other identities (Cloudflare
+// Access, OpenID Connect, API tokens...) don't log in with a password.
+func (s *Server) totpUser(r *http.Request) (string, bool) {
+	name := authUserName(r.Context())
+	if name == "" {
+		if s.config.MittoConfig != nil && s.config.MittoConfig.Web.Auth != nil &&
+			s.config.MittoConfig.Web.Auth.Simple != nil && s.config.MittoConfig.Web.Auth.Simple.Username != "" {
+			return s.config.MittoConfig.Web.Auth.Simple.Username, true
+		}
+		return "", false
+	}
+	if strings.Contains(name, ":") {
+		return "", false
+	}
+	return name, true
+}
+
+// totpRequest checks the method of a request and returns its user, writing
+// the error response otherwise.
+func (s *Server) totpRequest(w http.ResponseWriter, r *http.Request, method string) (string, bool) {
+	if r.Method != method {
+		methodNotAllowed(w)
+		return "", false
+	}
+	username, ok := s.totpUser(r)
+	if !ok {
+		writeErrorJSON(w, http.StatusBadRequest, "no_password_login",
+			"Two-factor authentication is only available for users that log in with a password")
+		return "", false
+	}
+	return username, true
+}
+
+// handleTOTPStatus handles GET /api/auth/totp.
+func (s *Server) handleTOTPStatus(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.totpRequest(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+	enabled, pending, left, err := s.totp.Status(username)
+	if err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	writeJSONOK(w, TOTPStatusResponse{
+		Username:          username,
+		Enabled:           enabled,
+		Pending:           pending,
+		RecoveryCodesLeft: left,
+	})
+}
+
+// handleTOTPEnroll handles POST /api/auth/totp/enroll
+// It starts an enrollment, returning the secret for the authenticator app.
+func (s *Server) handleTOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.totpRequest(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+	secret, uri, err := s.totp.Enroll(username)
+	if errors.Is(err, ErrTOTPAlreadyEnabled) {
+		writeErrorJSON(w, http.StatusConflict, "already_enabled", err.Error())
+		return
+	}
+	if err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	writeJSONOK(w, TOTPEnrollResponse{Secret: secret, ProvisioningURI: uri})
+}
+
+// handleTOTPConfirm handles POST /api/auth/totp/confirm
+// It enables two-factor authentication once the user enters a valid code.
+func (s *Server) handleTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.totpRequest(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+	var req TOTPCodeRequest
+	if !parseJSONBody(w, r, &req) {
+		return
+	}
+	codes, err := s.totp.Confirm(username, req.Code)
+	switch {
+	case errors.Is(err, ErrTOTPNotEnrolled):
+		writeErrorJSON(w, http.StatusNotFound, "not_enrolled", err.Error())
+	case errors.Is(err, ErrTOTPAlreadyEnabled):
+		writeErrorJSON(w, http.StatusConflict, "already_enabled", err.Error())
+	case errors.Is(err, ErrInvalidTOTPCode):
+		writeErrorJSON(w, http.StatusBadRequest, "invalid_code", err.Error())
+	case err != nil:
+		writeErrorJSON(w, http.StatusInternalServerError, "internal_error", err.Error())
+	default:
+		if s.logger != nil {
+			s.logger.Info("Two-factor authentication enabled", "username", username)
+		}
+		writeJSONOK(w, TOTPConfirmResponse{RecoveryCodes: codes})
+	}
+}
+
+// handleTOTPDisable handles POST /api/auth/totp/disable
+// Disabling requires a code (or a recovery code), unless the enrollment was
+// never confirmed.
+func (s *Server) handleTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.totpRequest(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+	var req TOTPCodeRequest
+	if !parseJSONBody(w, r, &req) {
+		return
+	}
+	if s.totp.IsEnabled(username) {
+		if err := s.totp.Verify(username, req.Code); err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_code", err.Error())
+			return
+		}
+	}
+	if _, err := s.totp.Reset(username); err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	if s.logger != nil {
+		s.logger.Info("Two-factor authentication disabled", "username", username)
+	}
+	writeNoContent(w)
+}
@@ -0,0 +1,177 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+func TestTOTPCode_RFC6238(t *testing.T) {
+	// Test vectors of RFC 6238 appendix B (SHA-1), truncated to 6 digits
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(secret, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+// newTestTOTPStore returns a store in a temporary directory, with a clock
+// the test controls.
+func newTestTOTPStore(t *testing.T) (*TOTPStore, *time.Time) {
+	t.Helper()
+	now := time.Unix(1700000000, 0)
+	store := NewTOTPStore(filepath.Join(t.TempDir(), "totp.json"))
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+// currentTOTPCode returns the code of the authenticator app for a secret.
+func currentTOTPCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	raw, err := totpBase32.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	return totpCode(raw, now.Unix()/totpPeriod)
+}
+
+func TestTOTPStore_Lifecycle(t *testing.T) {
+	store, now := newTestTOTPStore(t)
+
+	secret, uri, err := store.Enroll("alice")
+	if err != nil {
+		t.Fatalf("Enroll: %v", err)
+	}
+	if !strings.HasPrefix(uri, "otpauth://totp/Mitto:alice?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("provisioning URI = %q", uri)
+	}
+	if store.IsEnabled("alice") {
+		t.Error("enrollment should not be enabled before confirmation")
+	}
+
+	if _, err := store.Confirm("alice", "000000"); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Errorf("Confirm with a wrong code: err = %v", err)
+	}
+	codes, err := store.Confirm("alice", currentTOTPCode(t, secret, *now))
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+	if !store.IsEnabled("alice") {
+		t.Error("enrollment should be enabled after confirmation")
+	}
+	if _, _, err := store.Enroll("alice"); !errors.Is(err, ErrTOTPAlreadyEnabled) {
+		t.Errorf("Enroll of an enabled user: err = %v", err)
+	}
+
+	// The code used for the confirmation can't be replayed
+	if err := store.Verify("alice", currentTOTPCode(t, secret, *now)); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Errorf("Verify of a replayed code: err = %v", err)
+	}
+	*now = now.Add(totpPeriod * time.Second)
+	if err := store.Verify("alice", currentTOTPCode(t, secret, *now)); err != nil {
+		t.Errorf("Verify of the next code: %v", err)
+	}
+
+	// Recovery codes work once
+	if err := store.Verify("alice", strings.ToUpper(codes[0])); err != nil {
+		t.Errorf("Verify of a recovery code: %v", err)
+	}
+	if err := store.Verify("alice", codes[0]); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Errorf("Verify of a used recovery code: err = %v", err)
+	}
+	if _, _, left, _ := store.Status("alice"); left != recoveryCodeCount-1 {
+		t.Errorf("recovery codes left = %d, want %d", left, recoveryCodeCount-1)
+	}
+
+	// The store is persisted
+	reopened := NewTOTPStore(store.path)
+	if !reopened.IsEnabled("alice") || reopened.IsEnabled("bob") {
+		t.Error("reopened store lost the enrollments")
+	}
+
+	if ok, err := store.Reset("alice"); !ok || err != nil {
+		t.Errorf("Reset = %v, %v", ok, err)
+	}
+	if store.IsEnabled("alice") {
+		t.Error("enrollment should be removed after Reset")
+	}
+	if ok, _ := store.Reset("alice"); ok {
+		t.Error("Reset of a user without enrollment should return false")
+	}
+}
+
+func TestAuthManager_HandleLogin_TOTP(t *testing.T) {
+	am := NewAuthManager(&config.WebAuth{
+		Simple: &config.SimpleAuth{Username: "admin", Password: "secret"},
+	})
+	defer am.Close()
+
+	store, now := newTestTOTPStore(t)
+	am.SetTOTPStore(store)
+	secret, _, err := store.Enroll("admin")
+	if err != nil {
+		t.Fatalf("Enroll: %v", err)
+	}
+	if _, err := store.Confirm("admin", currentTOTPCode(t, secret, *now)); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	*now = now.Add(totpPeriod * time.Second)
+
+	login := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		am.HandleLogin(w, req)
+		return w
+	}
+
+	// The password alone asks for the code
+	w := login(`{"username":"admin","password":"secret"}`)
+	var resp LoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusUnauthorized || !resp.TOTPRequired {
+		t.Errorf("login without code = %d %+v, want 401 with totp_required", w.Code, resp)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("login without code set a cookie")
+	}
+
+	w = login(`{"username":"admin","password":"secret","code":"000000"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("login with a wrong code = %d, want 401", w.Code)
+	}
+
+	// The code is never checked without the right password
+	code := currentTOTPCode(t, secret, *now)
+	w = login(`{"username":"admin","password":"wrong","code":"` + code + `"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("login with a wrong password = %d, want 401", w.Code)
+	}
+
+	w = login(`{"username":"admin","password":"secret","code":"` + code + `"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("login with a valid code = %d, want 200: %s", w.Code, w.Body.String())
+	}
+}
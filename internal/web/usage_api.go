@@ -0,0 +1,116 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+// UsageResponse is the response of GET /api/usage.
+type UsageResponse struct {
+	session.UsageReport
+	// Currency is the currency of the costs.
+	Currency string `json:"currency"`
+	// Budget is the usage of the current day and month against the configured
+	// budgets (omitted when no budget is configured).
+	Budget *UsageBudgetStatus `json:"budget,omitempty"`
+}
+
+// handleUsage handles GET /api/usage
+// It reports the token usage and cost of conversations, grouped by a dimension.
+//
+// Query parameters:
+//   - group_by: session (default), workspace, agent or day
+//   - since, until: day range (inclusive), as dates (2024-01-15), RFC 3339 timestamps
+//     or durations ago like "168h" (optional)
+//   - workspace: workspace UUID to restrict the report to (optional)
+//   - working_dir: workspace folder to restrict the report to (optional, ignored if workspace is set)
+//   - acp_server: ACP server name to restrict the report to (optional)
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	store := s.Store()
+	if store == nil {
+		http.Error(w, "Session store not available", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	groupBy, err := session.ParseUsageGroupBy(query.Get("group_by"))
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, "invalid_group_by", err.Error())
+		return
+	}
+	q := session.UsageQuery{
+		GroupBy:    groupBy,
+		WorkingDir: query.Get("working_dir"),
+		ACPServer:  query.Get("acp_server"),
+	}
+
+	if uuid := query.Get("workspace"); uuid != "" {
+		var ws *config.WorkspaceSettings
+		if s.sessionManager != nil {
+			ws = s.sessionManager.GetWorkspaceByUUID(uuid)
+		}
+		if ws == nil {
+			writeErrorJSON(w, http.StatusNotFound, "workspace_not_found", "Workspace not found: "+uuid)
+			return
+		}
+		q.WorkingDir = ws.WorkingDir
+	}
+
+	// Users restricted to some workspaces get the usage of one of them
+	if user := webUserFromContext(r.Context()); !user.HasAllWorkspaces() {
+		if q.WorkingDir == "" || !s.webUserCanAccessDir(user, q.WorkingDir) {
+			writeErrorJSON(w, http.StatusForbidden, "workspace_forbidden",
+				"workspace or working_dir must be one of your workspaces")
+			return
+		}
+	}
+
+	if v := query.Get("since"); v != "" {
+		if q.Since, err = parseUsageDay(v); err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_since", err.Error())
+			return
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		if q.Until, err = parseUsageDay(v); err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid_until", err.Error())
+			return
+		}
+	}
+
+	report, err := store.UsageReport(q)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("Failed to compute usage report", "error", err)
+		}
+		http.Error(w, "Failed to compute usage report", http.StatusInternalServerError)
+		return
+	}
+
+	resp := UsageResponse{UsageReport: report, Currency: config.DefaultUsageCurrency}
+	if mittoConfig := s.config.MittoConfig; mittoConfig != nil {
+		resp.Currency = mittoConfig.Usage.GetCurrency()
+		if mittoConfig.Usage.HasBudget() {
+			status := s.usageBudget.Status()
+			resp.Budget = &status
+		}
+	}
+	writeJSONOK(w, resp)
+}
+
+// parseUsageDay parses a usage report bound: a date in local time, or any
+// format accepted by session.ParseHistoryTime.
+func parseUsageDay(v string) (time.Time, error) {
+	if t, err := time.ParseInLocation(session.UsageDayFormat, v, time.Local); err == nil {
+		return t, nil
+	}
+	return session.ParseHistoryTime(v)
+}
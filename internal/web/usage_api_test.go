@@ -0,0 +1,155 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+func TestHandleUsage(t *testing.T) {
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+
+	for _, meta := range []session.Metadata{
+		{SessionID: "s1", ACPServer: "claude", WorkingDir: "/proj/a", Name: "Parser"},
+		{SessionID: "s2", ACPServer: "auggie", WorkingDir: "/proj/b"},
+	} {
+		if err := store.Create(meta); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	now := time.Now()
+	if err := store.AddUsage("s1", now, session.UsageData{TotalTokens: 1000, Cost: 2.5}); err != nil {
+		t.Fatalf("AddUsage failed: %v", err)
+	}
+	if err := store.AddUsage("s1", now.AddDate(0, 0, -40), session.UsageData{TotalTokens: 500, Cost: 1}); err != nil {
+		t.Fatalf("AddUsage failed: %v", err)
+	}
+	if err := store.AddUsage("s2", now, session.UsageData{TotalTokens: 200, Estimated: true}); err != nil {
+		t.Fatalf("AddUsage failed: %v", err)
+	}
+
+	mittoConfig := &config.Config{Usage: &config.UsageConfig{Currency: "EUR", DailyBudget: 2}}
+	server := &Server{
+		config:         Config{MittoConfig: mittoConfig},
+		sessionManager: NewSessionManager("", "", false, nil),
+		store:          store,
+		usageBudget:    NewUsageBudget(store, mittoConfig),
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantCode   int
+		wantGroups int
+		wantTokens int64
+	}{
+		{"by session", "", http.StatusOK, 2, 1700},
+		{"by day", "group_by=day", http.StatusOK, 2, 1700},
+		{"by agent since today", "group_by=agent&since=" + now.Format("2006-01-02"), http.StatusOK, 2, 1200},
+		{"by workspace", "group_by=workspace&working_dir=/proj/a&since=720h", http.StatusOK, 1, 1000},
+		{"acp server", "acp_server=auggie", http.StatusOK, 1, 200},
+		{"invalid group", "group_by=month", http.StatusBadRequest, 0, 0},
+		{"invalid since", "since=yesterday", http.StatusBadRequest, 0, 0},
+		{"unknown workspace", "workspace=nope", http.StatusNotFound, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/usage?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			server.handleUsage(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Status = %d, want %d (body: %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp UsageResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(resp.Groups) != tt.wantGroups || resp.Total.TotalTokens != tt.wantTokens {
+				t.Errorf("groups = %d, total tokens = %d; want %d, %d", len(resp.Groups), resp.Total.TotalTokens, tt.wantGroups, tt.wantTokens)
+			}
+			if resp.Currency != "EUR" {
+				t.Errorf("Currency = %q, want EUR", resp.Currency)
+			}
+			if resp.Budget == nil || !resp.Budget.Exceeded || resp.Budget.DayCost != 2.5 {
+				t.Errorf("Budget = %+v, want the daily budget exceeded", resp.Budget)
+			}
+		})
+	}
+}
+
+func TestUsageBudget(t *testing.T) {
+	store, err := session.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+	if err := store.Create(session.Metadata{SessionID: "s1", ACPServer: "claude"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var nilBudget *UsageBudget
+	if exceeded, _ := nilBudget.Exceeded(); exceeded {
+		t.Error("nil budget should never be exceeded")
+	}
+
+	budget := NewUsageBudget(store, &config.Config{Usage: &config.UsageConfig{DailyTokenBudget: 1000}})
+	if exceeded, _ := budget.Exceeded(); exceeded {
+		t.Error("budget should not be exceeded without usage")
+	}
+
+	if err := store.AddUsage("s1", time.Now(), session.UsageData{TotalTokens: 1500}); err != nil {
+		t.Fatalf("AddUsage failed: %v", err)
+	}
+	// The status is cached until invalidated
+	if exceeded, _ := budget.Exceeded(); exceeded {
+		t.Error("cached budget status should not include new usage")
+	}
+	budget.Invalidate()
+	if exceeded, reason := budget.Exceeded(); !exceeded || reason == "" {
+		t.Errorf("Exceeded() = %v, %q; want the daily token budget exceeded", exceeded, reason)
+	}
+
+	// Paused queues are not processed
+	bs := &BackgroundSession{persistedID: "s1", store: store, usageBudget: budget}
+	if _, err := store.Queue("s1").Add("queued", nil, nil, "", nil, 0, nil, ""); err != nil {
+		t.Fatalf("Queue Add failed: %v", err)
+	}
+	if bs.processNextQueuedMessage() {
+		t.Error("processNextQueuedMessage should not send while the budget is exceeded")
+	}
+	if n, _ := store.Queue("s1").Len(); n != 1 {
+		t.Errorf("queue length = %d, want the message kept", n)
+	}
+
+	// Deleting a conversation doesn't remove its spend
+	if err := store.Create(session.Metadata{SessionID: "s2", ACPServer: "auggie"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := store.AddUsage("s2", time.Now(), session.UsageData{TotalTokens: 100, Estimated: true}); err != nil {
+		t.Fatalf("AddUsage failed: %v", err)
+	}
+	if err := store.Delete("s1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	budget.Invalidate()
+	if exceeded, _ := budget.Exceeded(); !exceeded {
+		t.Error("budget should stay exceeded after deleting the conversation")
+	}
+	if status := budget.Status(); status.DayTokens != 1600 || status.DayEstimatedTokens != 100 || status.MonthEstimatedTokens != 100 {
+		t.Errorf("Status() = %+v, want 1600 tokens today, 100 of them estimated", status)
+	}
+}
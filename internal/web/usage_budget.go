@@ -0,0 +1,115 @@
+package web
+
+import (
+	"sync"
+	"time"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+// usageBudgetCacheTTL is how long a computed budget status is reused.
+// Computing it reads the usage ledger of the month, so it is not done on every check.
+const usageBudgetCacheTTL = 30 * time.Second
+
+// UsageBudgetStatus is the usage of the current day and month, checked against the budgets.
+type UsageBudgetStatus struct {
+	DayCost     float64 `json:"day_cost"`
+	MonthCost   float64 `json:"month_cost"`
+	DayTokens   int64   `json:"day_tokens"`
+	MonthTokens int64   `json:"month_tokens"`
+	// DayEstimatedTokens and MonthEstimatedTokens are the part of the tokens
+	// that were estimated, for agents that don't report usage.
+	DayEstimatedTokens   int64 `json:"day_estimated_tokens,omitempty"`
+	MonthEstimatedTokens int64 `json:"month_estimated_tokens,omitempty"`
+	// Exceeded is true when a budget is exceeded: queued messages and
+	// scheduled periodic prompts are paused until the next day (or month).
+	Exceeded bool   `json:"exceeded"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// UsageBudget enforces the daily and monthly usage budgets (config.UsageConfig).
+// The usage is read from the store's usage ledger, so deleting or archiving
+// conversations doesn't lift a budget pause.
+// A nil *UsageBudget never reports the budget as exceeded.
+type UsageBudget struct {
+	store *session.Store
+	// cfg is the Mitto configuration; its Usage section is read on every check
+	// so that configuration changes are applied without restarting.
+	cfg *config.Config
+
+	mu        sync.Mutex
+	status    UsageBudgetStatus
+	checkedAt time.Time
+}
+
+// NewUsageBudget creates a usage budget checker for the sessions in the store.
+func NewUsageBudget(store *session.Store, cfg *config.Config) *UsageBudget {
+	return &UsageBudget{store: store, cfg: cfg}
+}
+
+// Invalidate discards the cached status, so that the next check includes
+// usage that was just recorded.
+func (b *UsageBudget) Invalidate() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkedAt = time.Time{}
+}
+
+// Status returns the usage of the current day and month and whether a budget is exceeded.
+func (b *UsageBudget) Status() UsageBudgetStatus {
+	if b == nil || b.store == nil {
+		return UsageBudgetStatus{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if !b.checkedAt.IsZero() && now.Sub(b.checkedAt) < usageBudgetCacheTTL &&
+		b.checkedAt.Format(session.UsageDayFormat) == now.Format(session.UsageDayFormat) {
+		return b.status
+	}
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	entries, err := b.store.UsageLedger(monthStart)
+	if err != nil {
+		// Keep the last known status: a transient store error should not lift a budget pause.
+		return b.status
+	}
+	today := now.Format(session.UsageDayFormat)
+	var day, month session.UsageTotals
+	for _, e := range entries {
+		month.Add(e.UsageData)
+		if e.Time.Local().Format(session.UsageDayFormat) == today {
+			day.Add(e.UsageData)
+		}
+	}
+
+	status := UsageBudgetStatus{
+		DayCost:              day.Cost,
+		MonthCost:            month.Cost,
+		DayTokens:            day.TotalTokens,
+		MonthTokens:          month.TotalTokens,
+		DayEstimatedTokens:   day.EstimatedTokens,
+		MonthEstimatedTokens: month.EstimatedTokens,
+	}
+	if b.cfg != nil && b.cfg.Usage != nil {
+		status.Reason = b.cfg.Usage.CheckBudget(day.Cost, month.Cost, day.TotalTokens, month.TotalTokens)
+		status.Exceeded = status.Reason != ""
+	}
+	b.status = status
+	b.checkedAt = now
+	return status
+}
+
+// Exceeded reports whether a usage budget is exceeded, and why.
+func (b *UsageBudget) Exceeded() (bool, string) {
+	if b == nil || b.cfg == nil || !b.cfg.Usage.HasBudget() {
+		return false, ""
+	}
+	status := b.Status()
+	return status.Exceeded, status.Reason
+}
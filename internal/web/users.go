@@ -0,0 +1,113 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/inercia/mitto/internal/config"
+	"github.com/inercia/mitto/internal/session"
+)
+
+// contextKeyWebUser is the context key used to store the *config.WebUser of
+// requests authenticated as one of the configured user accounts.
+const contextKeyWebUser contextKey = "webUser"
+
+// webUserFromContext returns the user account of a request, or nil when the
+// request has full access (no user accounts configured, localhost, allow
+// list or API tokens).
+func webUserFromContext(ctx context.Context) *config.WebUser {
+	user, _ := ctx.Value(contextKeyWebUser).(*config.WebUser)
+	return user
+}
+
+// requiredRoleForRequest returns the role needed for a request: reading needs
+// the viewer role, acting on conversations the operator role, and changing
+// anything else the admin role. API tokens, the audit log and webhook
+// deliveries need the admin role to be read too.
+func requiredRoleForRequest(r *http.Request, apiPrefix string) string {
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	if !strings.HasPrefix(path, "/api/") {
+		// Static files
+		return config.RoleViewer
+	}
+
+	readOr := func(role string) string {
+		if isStateChangingMethod(r.Method) {
+			return role
+		}
+		return config.RoleViewer
+	}
+
+	switch {
+	case path == "/api/tokens" || strings.HasPrefix(path, "/api/tokens/"), path == "/api/audit",
+		path == "/api/webhooks" || strings.HasPrefix(path, "/api/webhooks/"):
+		return config.RoleAdmin
+	case path == "/api/permissions" || strings.HasPrefix(path, "/api/permissions/"):
+		return config.RoleOperator
+	case path == "/api/sessions" || strings.HasPrefix(path, "/api/sessions/"),
+		path == "/api/search", path == "/api/usage", path == "/api/files", path == "/api/events",
+		path == "/api/aux/improve-prompt", path == "/api/badge-click", strings.HasPrefix(path, "/api/beads/"):
+		return readOr(config.RoleOperator)
+	case path == "/api/ui-preferences", path == "/api/logout",
+		path == "/api/auth/totp", strings.HasPrefix(path, "/api/auth/totp/"):
+		return config.RoleViewer
+	default:
+		return readOr(config.RoleAdmin)
+	}
+}
+
+// webUserCanAccessDir returns true if a user can access the workspace of a
+// folder (or of the worktree of a conversation).
+func (s *Server) webUserCanAccessDir(user *config.WebUser, dir string) bool {
+	if user.HasAllWorkspaces() {
+		return true
+	}
+	dir = workspaceDir(dir)
+	uuid := ""
+	if s.sessionManager != nil {
+		if ws := resolveOwningWorkspace(dir, s.sessionManager.GetWorkspaces()); ws != nil {
+			uuid = ws.UUID
+		}
+	}
+	return user.CanAccessWorkspace(uuid, dir)
+}
+
+// webUserCanAccessSession returns true if a user can access a conversation.
+// Unknown conversations are accessible, so that handlers report them as such.
+func (s *Server) webUserCanAccessSession(user *config.WebUser, sessionID string) bool {
+	if user.HasAllWorkspaces() {
+		return true
+	}
+	store := s.Store()
+	if store == nil {
+		return true
+	}
+	meta, err := store.GetMetadata(sessionID)
+	if err != nil {
+		return errors.Is(err, session.ErrSessionNotFound)
+	}
+	return s.webUserCanAccessDir(user, meta.WorkingDir)
+}
+
+// filterWorkspacesForUser returns the workspaces a user can access.
+func filterWorkspacesForUser(user *config.WebUser, workspaces []config.WorkspaceSettings) []config.WorkspaceSettings {
+	if user.HasAllWorkspaces() {
+		return workspaces
+	}
+	filtered := make([]config.WorkspaceSettings, 0, len(workspaces))
+	for _, ws := range workspaces {
+		if user.CanAccessWorkspace(ws.UUID, ws.WorkingDir) {
+			filtered = append(filtered, ws)
+		}
+	}
+	return filtered
+}
+
+// authUserName returns the identity a request was authenticated as (see
+// contextKeyAuthUser), or "" when it needed no authentication.
+func authUserName(ctx context.Context) string {
+	name, _ := ctx.Value(contextKeyAuthUser).(string)
+	return name
+}
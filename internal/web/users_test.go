@@ -0,0 +1,118 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/inercia/mitto/internal/config"
+)
+
+func TestRequiredRoleForRequest(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/mitto/index.html", config.RoleViewer},
+		{"GET", "/mitto/api/sessions", config.RoleViewer},
+		{"POST", "/mitto/api/sessions", config.RoleOperator},
+		{"DELETE", "/mitto/api/sessions/abc", config.RoleOperator},
+		{"GET", "/mitto/api/permissions", config.RoleOperator},
+		{"GET", "/mitto/api/config", config.RoleViewer},
+		{"POST", "/mitto/api/config", config.RoleAdmin},
+		{"PUT", "/mitto/api/ui-preferences", config.RoleViewer},
+		{"GET", "/mitto/api/tokens", config.RoleAdmin},
+		{"GET", "/mitto/api/audit", config.RoleAdmin},
+		{"GET", "/mitto/api/webhooks/deliveries", config.RoleAdmin},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := requiredRoleForRequest(req, "/mitto"); got != tt.want {
+			t.Errorf("requiredRoleForRequest(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFilterWorkspacesForUser(t *testing.T) {
+	workspaces := []config.WorkspaceSettings{
+		{UUID: "ws-1", WorkingDir: "/home/ann/app"},
+		{UUID: "ws-2", WorkingDir: "/home/bob/app"},
+		{UUID: "ws-3", WorkingDir: "/srv/shared"},
+	}
+	user := &config.WebUser{Username: "ann", Role: config.RoleOperator, Workspaces: []string{"/home/ann", "ws-3"}}
+
+	got := filterWorkspacesForUser(user, workspaces)
+	if len(got) != 2 || got[0].UUID != "ws-1" || got[1].UUID != "ws-3" {
+		t.Errorf("filterWorkspacesForUser() = %+v", got)
+	}
+	if got := filterWorkspacesForUser(nil, workspaces); len(got) != 3 {
+		t.Errorf("filterWorkspacesForUser(nil) returned %d workspaces, want 3", len(got))
+	}
+}
+
+func TestAuthMiddleware_Users(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("viewer-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	am := NewAuthManager(&config.WebAuth{
+		Simple: &config.SimpleAuth{Username: "admin", Password: "password"},
+		Users: []config.WebUser{
+			{Username: "ann", PasswordHash: string(hash), Role: config.RoleViewer, Workspaces: []string{"/home/ann"}},
+		},
+	})
+	defer am.Close()
+	am.SetAPIPrefix("/mitto")
+
+	if !am.ValidateCredentials("ann", "viewer-password") {
+		t.Error("ValidateCredentials() should accept the password of a user account")
+	}
+	if am.ValidateCredentials("ann", "password") {
+		t.Error("ValidateCredentials() should reject a wrong password")
+	}
+	if !am.ValidateCredentials("admin", "password") {
+		t.Error("ValidateCredentials() should still accept the simple auth credentials")
+	}
+
+	var gotUser *config.WebUser
+	var gotName string
+	middleware := am.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser = webUserFromContext(r.Context())
+		gotName = authUserName(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method, path, username string) int {
+		session, err := am.CreateSession(username)
+		if err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "192.168.1.100:12345"
+		req.AddCookie(&http.Cookie{Name: "mitto_session", Value: session.Token})
+		w := httptest.NewRecorder()
+		gotUser, gotName = nil, ""
+		middleware.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := serve("GET", "/mitto/api/sessions", "ann"); code != http.StatusOK {
+		t.Errorf("viewer GET status = %d, want %d", code, http.StatusOK)
+	} else if gotUser == nil || gotUser.Username != "ann" || gotName != "ann" {
+		t.Errorf("context user = %+v, name = %q", gotUser, gotName)
+	}
+	if code := serve("POST", "/mitto/api/sessions", "ann"); code != http.StatusForbidden {
+		t.Errorf("viewer POST status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := serve("POST", "/mitto/api/config", "admin"); code != http.StatusOK {
+		t.Errorf("simple auth user POST status = %d, want %d", code, http.StatusOK)
+	} else if gotUser != nil {
+		t.Errorf("simple auth user should have full access, got %+v", gotUser)
+	}
+	if code := serve("GET", "/mitto/api/sessions", "removed"); code != http.StatusUnauthorized {
+		t.Errorf("unknown user status = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
@@ -355,14 +355,15 @@ const (
 	WSMsgTypeSessionGone = "session_gone"
 
 	// WSMsgTypeACPErrorPermanent notifies that the ACP process encountered a permanent error
-	// that will not resolve by retrying. Includes actionable guidance for the user.
+	// that will not resolve by retrying, or that a prompt was cancelled for exceeding a
+	// resource limit of the workspace. Includes actionable guidance for the user.
 	// Data: {
 	//   "session_id": string,
 	//   "error": string,
 	//   "error_class": string,     // "permanent"
 	//   "user_message": string,    // What went wrong
 	//   "user_guidance": string,   // How to fix it
-	//   "command": string          // The ACP command that failed
+	//   "command": string          // The ACP command that failed (not for resource limits)
 	// }
 	WSMsgTypeACPErrorPermanent = "acp_error_permanent"
 
@@ -433,6 +434,13 @@ const (
 	// Sent when the agent sends a SessionUsageUpdate notification.
 	// Data: { "session_id": string, "size": int, "used": int }
 	WSMsgTypeContextUsageUpdate = "context_usage_update"
+
+	// WSMsgTypeTerminalOutput streams output from a terminal created by the agent
+	// via the ACP terminal/create method. A final message with exited=true is sent
+	// when the command finishes.
+	// Data: { "session_id": string, "terminal_id": string, "output": string (optional),
+	//         "exited": bool (optional), "exit_code": int (optional), "signal": string (optional) }
+	WSMsgTypeTerminalOutput = "terminal_output"
 )
 
 // =============================================================================
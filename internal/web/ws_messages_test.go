@@ -372,6 +372,7 @@ func (m *replayTestObserver) OnUIPrompt(_ UIPromptRequest)
 func (m *replayTestObserver) OnUIPromptDismiss(_ string, _ string)                          {}
 func (m *replayTestObserver) OnNotification(_ UINotifyRequest)                              {}
 func (m *replayTestObserver) OnContextUsageUpdate(_ int, _ int)                             {}
+func (m *replayTestObserver) OnTerminalOutput(_ TerminalOutputUpdate)                       {}
 
 func TestBufferedEvent_ReplayTo(t *testing.T) {
 	observer := &replayTestObserver{}
@@ -832,6 +833,7 @@ func (o *testReplayObserver) OnUIPrompt(req UIPromptRequest)
 func (o *testReplayObserver) OnUIPromptDismiss(requestID string, reason string)      {}
 func (o *testReplayObserver) OnNotification(req UINotifyRequest)                     {}
 func (o *testReplayObserver) OnContextUsageUpdate(size, used int)                    {}
+func (o *testReplayObserver) OnTerminalOutput(update TerminalOutputUpdate)           {}
 func (o *testReplayObserver) OnPermission(ctx context.Context, params acp.RequestPermissionRequest) (acp.RequestPermissionResponse, error) {
 	return acp.RequestPermissionResponse{}, nil
 }
@@ -0,0 +1,301 @@
+// Package worktree isolates conversations in dedicated git worktrees.
+//
+// Each isolated conversation gets its own worktree, checked out on a
+// per-session branch created from the workspace's current branch, so that
+// several agents working on the same repository don't overwrite each other's
+// edits. Once the conversation is done, the branch is merged or rebased into
+// the base branch, or discarded.
+//
+// All git invocations for worktrees are isolated here.
+package worktree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BranchPrefix is the prefix of the per-session branches.
+const BranchPrefix = "mitto/"
+
+// defaultTimeout bounds each git invocation.
+const defaultTimeout = 60 * time.Second
+
+// ErrNotGitRepo is returned by Create when the directory is not inside a git repository.
+var ErrNotGitRepo = errors.New("not a git repository")
+
+// Worktree describes a per-session git worktree.
+type Worktree struct {
+	// Path is the root of the worktree.
+	Path string
+	// WorkingDir is the directory the session works in: the worktree counterpart
+	// of the workspace folder (Path itself, unless the workspace is a subdirectory
+	// of the repository).
+	WorkingDir string
+	// RepoDir is the top-level directory of the main repository.
+	RepoDir string
+	// Branch is the per-session branch checked out in the worktree.
+	Branch string
+	// BaseBranch is the branch the worktree was created from
+	// (empty when the repository HEAD was detached).
+	BaseBranch string
+	// BaseCommit is the commit the worktree was created from.
+	BaseCommit string
+}
+
+// BaseRef returns the ref the worktree branch is compared with: the base
+// branch, or the base commit when the worktree was created from a detached HEAD.
+func (w *Worktree) BaseRef() string {
+	if w.BaseBranch != "" {
+		return w.BaseBranch
+	}
+	return w.BaseCommit
+}
+
+// Status is the state of a worktree branch relative to its base.
+type Status struct {
+	// Ahead is the number of commits in the session branch that are not in the base.
+	Ahead int `json:"ahead"`
+	// Behind is the number of commits in the base that are not in the session branch.
+	Behind int `json:"behind"`
+	// Dirty is true when the worktree has uncommitted changes.
+	Dirty bool `json:"dirty"`
+}
+
+// BranchName returns the name of the branch for a session.
+func BranchName(sessionID string) string {
+	return BranchPrefix + sessionID
+}
+
+// git runs a git command in dir and returns its trimmed standard output.
+// Failures include git's standard error in the message.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Create adds a worktree at path, on a new branch created from the current HEAD
+// of the repository containing dir. dir may be a subdirectory of the repository:
+// the returned WorkingDir is the corresponding directory in the worktree.
+func Create(ctx context.Context, dir, path, branch string) (*Worktree, error) {
+	toplevel, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotGitRepo, dir)
+	}
+	baseCommit, err := git(ctx, toplevel, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("repository has no commits: %w", err)
+	}
+	// Empty when HEAD is detached
+	baseBranch, _ := git(ctx, toplevel, "symbolic-ref", "--short", "-q", "HEAD")
+
+	rel, err := relativeDir(toplevel, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create worktrees directory: %w", err)
+	}
+	if _, err := git(ctx, toplevel, "worktree", "add", "-b", branch, path, baseCommit); err != nil {
+		return nil, err
+	}
+
+	return &Worktree{
+		Path:       path,
+		WorkingDir: filepath.Join(path, rel),
+		RepoDir:    toplevel,
+		Branch:     branch,
+		BaseBranch: baseBranch,
+		BaseCommit: baseCommit,
+	}, nil
+}
+
+// relativeDir returns the path of dir relative to the repository toplevel
+// (which git reports with symlinks resolved).
+func relativeDir(toplevel, dir string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(toplevel, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not inside the repository %s", dir, toplevel)
+	}
+	return rel, nil
+}
+
+// GetStatus returns the state of the worktree branch relative to its base.
+func GetStatus(ctx context.Context, w *Worktree) (Status, error) {
+	var st Status
+	out, err := git(ctx, w.Path, "rev-list", "--left-right", "--count", w.BaseRef()+"..."+w.Branch)
+	if err != nil {
+		return st, err
+	}
+	if fields := strings.Fields(out); len(fields) == 2 {
+		st.Behind, _ = strconv.Atoi(fields[0])
+		st.Ahead, _ = strconv.Atoi(fields[1])
+	}
+	porcelain, err := git(ctx, w.Path, "status", "--porcelain")
+	if err != nil {
+		return st, err
+	}
+	st.Dirty = porcelain != ""
+	return st, nil
+}
+
+// MergeBase returns the commit the worktree branch diverged from its base.
+// Diffing against it shows only the changes made in the session.
+func MergeBase(ctx context.Context, w *Worktree) (string, error) {
+	return git(ctx, w.Path, "merge-base", w.BaseRef(), "HEAD")
+}
+
+// commitPending commits any uncommitted changes in the worktree.
+func commitPending(ctx context.Context, w *Worktree, message string) error {
+	porcelain, err := git(ctx, w.Path, "status", "--porcelain")
+	if err != nil || porcelain == "" {
+		return err
+	}
+	if _, err := git(ctx, w.Path, "add", "-A"); err != nil {
+		return err
+	}
+	_, err = git(ctx, w.Path, "commit", "-q", "-m", message)
+	return err
+}
+
+// checkBaseCheckedOut verifies that the main repository has the base branch checked out,
+// as merging updates the branch and working tree of the main repository.
+func checkBaseCheckedOut(ctx context.Context, w *Worktree) error {
+	if w.BaseBranch == "" {
+		return errors.New("the worktree was created from a detached HEAD: there is no base branch to merge into")
+	}
+	current, _ := git(ctx, w.RepoDir, "symbolic-ref", "--short", "-q", "HEAD")
+	if current != w.BaseBranch {
+		return fmt.Errorf("the repository %s must have %s checked out (currently on %q)", w.RepoDir, w.BaseBranch, current)
+	}
+	return nil
+}
+
+// Merge commits any pending changes in the worktree with message, merges the
+// session branch into the base branch, and removes the worktree and branch.
+// On conflicts the merge is aborted and the worktree is kept.
+func Merge(ctx context.Context, w *Worktree, message string) error {
+	if err := checkBaseCheckedOut(ctx, w); err != nil {
+		return err
+	}
+	if err := commitPending(ctx, w, message); err != nil {
+		return err
+	}
+	if _, err := git(ctx, w.RepoDir, "merge", "--no-edit", w.Branch); err != nil {
+		_, _ = git(ctx, w.RepoDir, "merge", "--abort")
+		return err
+	}
+	return Discard(ctx, w)
+}
+
+// Rebase commits any pending changes in the worktree with message, rebases the
+// session branch onto the base branch, fast-forwards the base branch to it, and
+// removes the worktree and branch. On conflicts the rebase is aborted and the
+// worktree is kept.
+func Rebase(ctx context.Context, w *Worktree, message string) error {
+	if err := checkBaseCheckedOut(ctx, w); err != nil {
+		return err
+	}
+	if err := commitPending(ctx, w, message); err != nil {
+		return err
+	}
+	if _, err := git(ctx, w.Path, "rebase", w.BaseBranch); err != nil {
+		_, _ = git(ctx, w.Path, "rebase", "--abort")
+		return err
+	}
+	if _, err := git(ctx, w.RepoDir, "merge", "--ff-only", w.Branch); err != nil {
+		return err
+	}
+	return Discard(ctx, w)
+}
+
+// Discard removes the worktree, with any uncommitted changes, and deletes the session branch.
+func Discard(ctx context.Context, w *Worktree) error {
+	if err := Remove(ctx, w.RepoDir, w.Path); err != nil {
+		return err
+	}
+	_, err := git(ctx, w.RepoDir, "branch", "-D", w.Branch)
+	return err
+}
+
+// Remove removes the worktree at path, keeping its branch.
+// It is not an error if the worktree directory no longer exists.
+func Remove(ctx context.Context, repoDir, path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		_, err := git(ctx, repoDir, "worktree", "prune")
+		return err
+	}
+	_, err := git(ctx, repoDir, "worktree", "remove", "--force", path)
+	return err
+}
+
+// WorkspaceDir maps a directory inside a worktree under root back to the
+// corresponding directory of the main repository. Other directories are
+// returned unchanged. It only reads the worktree's .git file, without running git.
+func WorkspaceDir(root, dir string) string {
+	if root == "" || dir == "" {
+		return dir
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return dir
+	}
+	parts := strings.SplitN(rel, string(filepath.Separator), 2)
+	repoDir := RepoDirOf(filepath.Join(root, parts[0]))
+	if repoDir == "" {
+		return dir
+	}
+	if len(parts) == 2 {
+		return filepath.Join(repoDir, parts[1])
+	}
+	return repoDir
+}
+
+// RepoDirOf returns the top-level directory of the main repository of the
+// worktree at path, or "" if path is not a linked worktree of a non-bare repository.
+func RepoDirOf(path string) string {
+	data, err := os.ReadFile(filepath.Join(path, ".git"))
+	if err != nil {
+		return ""
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(path, gitDir)
+	}
+	// <repo>/.git/worktrees/<name>
+	commonDir := filepath.Dir(filepath.Dir(gitDir))
+	if filepath.Base(filepath.Dir(gitDir)) != "worktrees" || filepath.Base(commonDir) != ".git" {
+		return ""
+	}
+	return filepath.Dir(commonDir)
+}
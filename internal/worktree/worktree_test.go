@@ -0,0 +1,196 @@
+package worktree
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a git repository with one commit on branch "main",
+// and a "sub" subdirectory.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(repo, "sub", "a.txt"), "one\n")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "-A"},
+		{"commit", "-q", "-m", "initial"},
+	} {
+		runGit(t, repo, args...)
+	}
+	return repo
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := git(context.Background(), dir, args...)
+	if err != nil {
+		t.Fatalf("git %v failed: %v", args, err)
+	}
+	return out
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createTestWorktree(t *testing.T, repo, root, id string) *Worktree {
+	t.Helper()
+	w, err := Create(context.Background(), filepath.Join(repo, "sub"), filepath.Join(root, id), BranchName(id))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return w
+}
+
+func TestCreate(t *testing.T) {
+	repo := newTestRepo(t)
+	root := t.TempDir()
+	ctx := context.Background()
+
+	w := createTestWorktree(t, repo, root, "s1")
+	if w.RepoDir != repo || w.Branch != "mitto/s1" || w.BaseBranch != "main" || w.BaseCommit == "" {
+		t.Errorf("worktree = %+v", w)
+	}
+	if w.WorkingDir != filepath.Join(root, "s1", "sub") {
+		t.Errorf("WorkingDir = %q, want the sub directory in the worktree", w.WorkingDir)
+	}
+	if _, err := os.Stat(filepath.Join(w.WorkingDir, "a.txt")); err != nil {
+		t.Errorf("worktree is not checked out: %v", err)
+	}
+
+	if got := WorkspaceDir(root, w.WorkingDir); got != filepath.Join(repo, "sub") {
+		t.Errorf("WorkspaceDir(%q) = %q, want %q", w.WorkingDir, got, filepath.Join(repo, "sub"))
+	}
+	if got := WorkspaceDir(root, "/elsewhere"); got != "/elsewhere" {
+		t.Errorf("WorkspaceDir(/elsewhere) = %q", got)
+	}
+
+	if _, err := Create(ctx, t.TempDir(), filepath.Join(root, "s2"), BranchName("s2")); !errors.Is(err, ErrNotGitRepo) {
+		t.Errorf("Create outside a repository: err = %v, want ErrNotGitRepo", err)
+	}
+}
+
+func TestStatusAndMerge(t *testing.T) {
+	repo := newTestRepo(t)
+	root := t.TempDir()
+	ctx := context.Background()
+	w := createTestWorktree(t, repo, root, "s1")
+
+	writeFile(t, filepath.Join(w.WorkingDir, "b.txt"), "new\n")
+	st, err := GetStatus(ctx, w)
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if st.Ahead != 0 || st.Behind != 0 || !st.Dirty {
+		t.Errorf("status = %+v, want dirty", st)
+	}
+	if base, err := MergeBase(ctx, w); err != nil || base != w.BaseCommit {
+		t.Errorf("MergeBase = %q, %v; want %q", base, err, w.BaseCommit)
+	}
+
+	// The base branch must be checked out in the main repository
+	runGit(t, repo, "checkout", "-q", "-b", "other")
+	if err := Merge(ctx, w, "session changes"); err == nil {
+		t.Fatal("Merge should fail when the base branch is not checked out")
+	}
+	runGit(t, repo, "checkout", "-q", "main")
+
+	if err := Merge(ctx, w, "session changes"); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "sub", "b.txt")); err != nil {
+		t.Errorf("merged file missing: %v", err)
+	}
+	if _, err := os.Stat(w.Path); !os.IsNotExist(err) {
+		t.Errorf("worktree should be removed, stat err = %v", err)
+	}
+	if branches := runGit(t, repo, "branch", "--list", w.Branch); branches != "" {
+		t.Errorf("branch %s should be deleted", w.Branch)
+	}
+}
+
+func TestRebase(t *testing.T) {
+	repo := newTestRepo(t)
+	root := t.TempDir()
+	ctx := context.Background()
+	w := createTestWorktree(t, repo, root, "s1")
+
+	writeFile(t, filepath.Join(w.WorkingDir, "b.txt"), "session\n")
+	// The base branch moves on meanwhile
+	writeFile(t, filepath.Join(repo, "c.txt"), "base\n")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "-q", "-m", "base change")
+
+	if err := commitPending(ctx, w, "wip"); err != nil {
+		t.Fatalf("commitPending failed: %v", err)
+	}
+	st, err := GetStatus(ctx, w)
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if st.Ahead != 1 || st.Behind != 1 || st.Dirty {
+		t.Errorf("status = %+v, want 1 ahead and 1 behind", st)
+	}
+
+	if err := Rebase(ctx, w, "session changes"); err != nil {
+		t.Fatalf("Rebase failed: %v", err)
+	}
+	log := runGit(t, repo, "log", "--format=%s")
+	if !strings.HasPrefix(log, "wip\nbase change\n") {
+		t.Errorf("log = %q, want a linear history", log)
+	}
+}
+
+func TestDiscardAndRemove(t *testing.T) {
+	repo := newTestRepo(t)
+	root := t.TempDir()
+	ctx := context.Background()
+
+	w := createTestWorktree(t, repo, root, "s1")
+	writeFile(t, filepath.Join(w.WorkingDir, "b.txt"), "lost\n")
+	if err := Discard(ctx, w); err != nil {
+		t.Fatalf("Discard failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "sub", "b.txt")); !os.IsNotExist(err) {
+		t.Error("discarded changes should not reach the repository")
+	}
+	if branches := runGit(t, repo, "branch", "--list", w.Branch); branches != "" {
+		t.Errorf("branch %s should be deleted", w.Branch)
+	}
+
+	// Remove keeps the branch, and tolerates a missing directory
+	w = createTestWorktree(t, repo, root, "s2")
+	if err := os.RemoveAll(w.Path); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(ctx, repo, w.Path); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if branches := runGit(t, repo, "branch", "--list", w.Branch); branches == "" {
+		t.Errorf("branch %s should be kept", w.Branch)
+	}
+}
@@ -10,19 +10,43 @@ document.addEventListener("DOMContentLoaded", function () {
   const errorDiv = document.getElementById("error");
   const submitBtn = document.getElementById("submitBtn");
   const cloudflareMsg = document.getElementById("cloudflare-message");
+  const oidcLogin = document.getElementById("oidc-login");
+  const oidcBtn = document.getElementById("oidcBtn");
+  const oidcSeparator = document.getElementById("oidc-separator");
+  const codeField = document.getElementById("code-field");
+  const codeInput = document.getElementById("code");
 
   if (!form || !errorDiv || !submitBtn) {
     console.error("Required form elements not found");
     return;
   }
 
+  // Show the error of a failed single sign-on login (set by the server redirect)
+  const loginError = new URLSearchParams(window.location.search).get("error");
+  if (loginError) {
+    errorDiv.textContent = loginError;
+    errorDiv.classList.remove("hidden");
+  }
+
   // Fetch auth info to adapt the UI before showing the form
   fetch(getApiPrefix() + "/api/auth-info")
     .then(function (res) {
       return res.json();
     })
     .then(function (info) {
-      if (!info.simple && info.cloudflare) {
+      if (info.oidc && oidcLogin && oidcBtn) {
+        // OpenID Connect configured: offer single sign-on, and the login form
+        // only if simple auth is configured too
+        oidcBtn.href = getApiPrefix() + "/api/auth/oidc/login";
+        oidcLogin.style.display = "";
+        if (info.simple) {
+          if (oidcSeparator) {
+            oidcSeparator.style.display = "";
+          }
+        } else {
+          form.style.display = "none";
+        }
+      } else if (!info.simple && info.cloudflare) {
         // Only Cloudflare auth configured: hide login form, show message
         form.style.display = "none";
         if (cloudflareMsg) {
@@ -51,6 +75,7 @@ document.addEventListener("DOMContentLoaded", function () {
 
     const username = document.getElementById("username").value;
     const password = document.getElementById("password").value;
+    const code = codeInput ? codeInput.value.trim() : "";
 
     try {
       const response = await fetch(getApiPrefix() + "/api/login", {
@@ -58,7 +83,11 @@ document.addEventListener("DOMContentLoaded", function () {
         headers: {
           "Content-Type": "application/json",
         },
-        body: JSON.stringify({ username: username, password: password }),
+        body: JSON.stringify({
+          username: username,
+          password: password,
+          code: code,
+        }),
         credentials: "same-origin", // Include cookies in request and accept Set-Cookie
       });
 
@@ -69,6 +98,12 @@ document.addEventListener("DOMContentLoaded", function () {
         const data = await response.json().catch(function () {
           return {};
         });
+        if (data.totp_required && codeField && codeInput) {
+          // The password is valid: ask for the two-factor authentication code
+          codeField.style.display = "";
+          codeInput.required = true;
+          codeInput.focus();
+        }
         errorDiv.textContent = data.error || "Invalid username or password";
         errorDiv.classList.remove("hidden");
       }
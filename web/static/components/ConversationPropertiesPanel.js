@@ -277,6 +277,10 @@ export function ConversationPropertiesPanel({
   // Confirmation dialog state
   const [confirmDialog, setConfirmDialog] = useState(null);
 
+  // Git worktree state (only for conversations isolated in a worktree)
+  const [worktreeState, setWorktreeState] = useState(null);
+  const [worktreeError, setWorktreeError] = useState(null);
+
   // MCP Tools collapsible state
   const [isMcpToolsExpanded, setIsMcpToolsExpanded] = useState(false);
 
@@ -320,8 +324,26 @@ export function ConversationPropertiesPanel({
     setCallbackCopied(false);
     setFlagsError(null);
     setSavingFlags({});
+    setWorktreeState(null);
+    setWorktreeError(null);
   }, [sessionId, isOpen]);
 
+  // Fetch the worktree status when the panel opens on an isolated conversation
+  const hasWorktree = !!sessionInfo?.worktree;
+  const fetchWorktree = useCallback(async () => {
+    try {
+      const res = await authFetch(apiUrl(`/api/sessions/${sessionId}/worktree`));
+      setWorktreeState(res.ok ? await res.json() : null);
+    } catch (e) {
+      console.warn("Failed to fetch worktree status:", e);
+    }
+  }, [sessionId]);
+  useEffect(() => {
+    if (isOpen && sessionId && hasWorktree) {
+      fetchWorktree();
+    }
+  }, [isOpen, sessionId, hasWorktree, fetchWorktree]);
+
   // Fetch periodic config, callback config, flags, and session settings when panel opens
   useEffect(() => {
     if (!isOpen || !sessionId) return;
@@ -643,6 +665,50 @@ export function ConversationPropertiesPanel({
     });
   }, [sessionId]);
 
+  const handleWorktreeAction = useCallback(
+    (action) => {
+      const prompts = {
+        merge: {
+          title: "Merge Worktree",
+          message: "Commit any pending changes and merge the conversation branch into the base branch?",
+          confirmLabel: "Merge",
+          confirmVariant: "primary",
+        },
+        rebase: {
+          title: "Rebase Worktree",
+          message: "Commit any pending changes, rebase the conversation branch onto the base branch and fast-forward it?",
+          confirmLabel: "Rebase",
+          confirmVariant: "primary",
+        },
+        discard: {
+          title: "Discard Worktree",
+          message: "Discard the worktree and its branch? All changes made in this conversation will be lost.",
+          confirmLabel: "Discard",
+          confirmVariant: "danger",
+        },
+      };
+      setConfirmDialog({
+        ...prompts[action],
+        onConfirm: async () => {
+          setConfirmDialog(null);
+          setWorktreeError(null);
+          const res = await secureFetch(apiUrl(`/api/sessions/${sessionId}/worktree`), {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ action }),
+          });
+          if (res.ok) {
+            setWorktreeState(null);
+          } else {
+            const data = await res.json().catch(() => ({}));
+            setWorktreeError(data.message || `Failed to ${action} the worktree`);
+          }
+        },
+      });
+    },
+    [sessionId],
+  );
+
   // Animation state: track if we're closing to play exit animation
   const [isClosing, setIsClosing] = useState(false);
   const [shouldRender, setShouldRender] = useState(isOpen);
@@ -969,6 +1035,51 @@ export function ConversationPropertiesPanel({
           </div>
         </div>
 
+        <!-- Git Worktree Section (only for conversations isolated in a worktree) -->
+        ${worktreeState &&
+        html`
+          <div>
+            <label class="block text-sm font-medium text-mitto-text-secondary mb-2">
+              Git Worktree
+            </label>
+            <div class="text-sm text-mitto-text-300 space-y-1">
+              <div class="truncate" title=${worktreeState.path}>
+                <span class="font-mono">${worktreeState.branch}</span>
+                ${worktreeState.base_branch &&
+                html` from <span class="font-mono">${worktreeState.base_branch}</span>`}
+              </div>
+              ${worktreeState.status &&
+              html`
+                <div class="text-xs text-mitto-text-500">
+                  ${worktreeState.status.ahead} ahead, ${worktreeState.status.behind} behind
+                  ${worktreeState.status.dirty ? ", uncommitted changes" : ""}
+                </div>
+              `}
+            </div>
+            ${sessionInfo?.archived
+              ? html`
+                  <div class="flex gap-2 mt-2">
+                    <button type="button" class="btn btn-sm btn-ghost" onClick=${() => handleWorktreeAction("merge")}>
+                      Merge
+                    </button>
+                    <button type="button" class="btn btn-sm btn-ghost" onClick=${() => handleWorktreeAction("rebase")}>
+                      Rebase
+                    </button>
+                    <button type="button" class="btn btn-sm btn-ghost text-red-400" onClick=${() => handleWorktreeAction("discard")}>
+                      Discard
+                    </button>
+                  </div>
+                `
+              : html`
+                  <p class="text-xs text-mitto-text-500 mt-2">
+                    Archive the conversation to merge, rebase or discard its branch.
+                  </p>
+                `}
+            ${worktreeError &&
+            html`<p class="text-xs text-red-400 mt-2">${worktreeError}</p>`}
+          </div>
+        `}
+
         <!-- Session Config Options Section -->
         <!-- Renders all config options dynamically based on type -->
         <!-- Supports: select (dropdown), toggle (future), unknown types gracefully ignored -->
@@ -15,6 +15,7 @@ import {
   LightningIcon,
   RobotIcon,
   PersonIcon,
+  DuplicateIcon,
   HourglassIcon,
   QuestionMarkIcon,
   TrashIcon,
@@ -489,7 +490,13 @@ export function SessionItem({
                               <${PersonIcon} className="w-4 h-4" />
                             </span>
                           `
-                        : null}
+                        : session.child_origin === "fork"
+                          ? html`
+                              <span class="shrink-0 text-mitto-text-300" title="Forked conversation">
+                                <${DuplicateIcon} className="w-4 h-4" />
+                              </span>
+                            `
+                          : null}
                   ${session.isWaitingForChildren
                     ? html`
                         <span class="shrink-0 text-mitto-warning animate-pulse" title="Waiting for child conversations">
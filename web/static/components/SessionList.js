@@ -510,7 +510,10 @@ export function SessionList({
     const storedSession = storedSessions.find(
       (s) => s.session_id === session.session_id,
     );
+    // Sessions isolated in a git worktree belong to the workspace folder
     return (
+      session.worktree?.workspace_dir ||
+      storedSession?.worktree?.workspace_dir ||
       session.working_dir ||
       storedSession?.working_dir ||
       getGlobalWorkingDir(session.session_id) ||
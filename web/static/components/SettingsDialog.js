@@ -20,6 +20,7 @@ import {
   getWorkspaceVisualInfo,
   getBasename,
 } from "../lib.js";
+import { TwoFactorAuthPanel } from "./TwoFactorAuthPanel.js";
 
 // Import components
 import {
@@ -3670,6 +3671,7 @@ export function SettingsDialog({
                                     />
                                   </div>
                                 </div>
+                                <${TwoFactorAuthPanel} />
                               `}
                             </div>
 
@@ -0,0 +1,190 @@
+// Mitto Web Interface - Two-Factor Authentication Panel Component
+// Enrolls and disables the TOTP second factor of the password login
+
+const { useState, useEffect, useCallback, html } = window.preact;
+
+import { secureFetch } from "../utils/csrf.js";
+import { apiUrl } from "../utils/api.js";
+
+/**
+ * Read the error message of a failed API response.
+ * @param {Response} res - The response
+ * @returns {Promise<string>} The message
+ */
+async function errorMessage(res) {
+  const data = await res.json().catch(() => ({}));
+  return data.message || data.error || `Request failed (${res.status})`;
+}
+
+/**
+ * Two-factor authentication settings of the user logged in with a password.
+ * Enrolling shows the secret and provisioning URI for the authenticator app,
+ * and, once confirmed with a code, the recovery codes (shown only once).
+ */
+export function TwoFactorAuthPanel() {
+  const [status, setStatus] = useState(null);
+  const [enrollment, setEnrollment] = useState(null);
+  const [recoveryCodes, setRecoveryCodes] = useState(null);
+  const [code, setCode] = useState("");
+  const [error, setError] = useState("");
+  const [busy, setBusy] = useState(false);
+
+  const loadStatus = useCallback(async () => {
+    try {
+      const res = await secureFetch(apiUrl("/api/auth/totp"));
+      setStatus(res.ok ? await res.json() : null);
+    } catch (err) {
+      setStatus(null);
+    }
+  }, []);
+
+  useEffect(() => {
+    loadStatus();
+  }, [loadStatus]);
+
+  const post = async (path, body) => {
+    setBusy(true);
+    setError("");
+    try {
+      const res = await secureFetch(apiUrl(path), {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(body || {}),
+      });
+      if (!res.ok) {
+        setError(await errorMessage(res));
+        return null;
+      }
+      return res.status === 204 ? {} : await res.json();
+    } catch (err) {
+      setError(err.message);
+      return null;
+    } finally {
+      setBusy(false);
+    }
+  };
+
+  const handleEnroll = async () => {
+    const data = await post("/api/auth/totp/enroll");
+    if (data) {
+      setEnrollment(data);
+      setRecoveryCodes(null);
+      setCode("");
+    }
+  };
+
+  const handleConfirm = async () => {
+    const data = await post("/api/auth/totp/confirm", { code });
+    if (data) {
+      setEnrollment(null);
+      setRecoveryCodes(data.recovery_codes || []);
+      setCode("");
+      loadStatus();
+    }
+  };
+
+  const handleDisable = async () => {
+    const data = await post("/api/auth/totp/disable", { code });
+    if (data) {
+      setEnrollment(null);
+      setRecoveryCodes(null);
+      setCode("");
+      loadStatus();
+    }
+  };
+
+  // Not available (e.g. logged in through Cloudflare Access)
+  if (!status) {
+    return null;
+  }
+
+  return html`
+    <div class="pl-7 space-y-2">
+      <div class="flex items-center gap-2">
+        <span class="text-sm font-medium">Two-factor authentication</span>
+        <span class="text-xs text-mitto-text-muted">
+          ${status.enabled
+            ? `Enabled for ${status.username} (${status.recovery_codes_left} recovery codes left)`
+            : "Disabled"}
+        </span>
+      </div>
+
+      ${recoveryCodes &&
+      html`
+        <div class="text-xs space-y-1">
+          <div class="text-mitto-text-muted">
+            Save these recovery codes: each one logs in once without the
+            authenticator app, and they won't be shown again.
+          </div>
+          <pre class="font-mono p-2 rounded bg-mitto-surface-3 select-all">
+${recoveryCodes.join("\n")}</pre
+          >
+        </div>
+      `}
+      ${enrollment &&
+      html`
+        <div class="text-xs space-y-1">
+          <div class="text-mitto-text-muted">
+            Add this key to your authenticator app (or make a QR code of the
+            URI), then enter the code it shows.
+          </div>
+          <div class="font-mono select-all break-all">
+            ${enrollment.secret}
+          </div>
+          <div class="font-mono select-all break-all text-mitto-text-muted">
+            ${enrollment.provisioning_uri}
+          </div>
+        </div>
+      `}
+      ${(enrollment || status.enabled) &&
+      html`
+        <div class="flex items-center gap-2">
+          <input
+            type="text"
+            inputmode="numeric"
+            autocomplete="one-time-code"
+            value=${code}
+            onInput=${(e) => setCode(e.target.value)}
+            placeholder=${enrollment ? "123456" : "Code or recovery code"}
+            class="input input-sm w-44"
+          />
+          ${enrollment
+            ? html`<button
+                type="button"
+                class="btn btn-sm btn-primary"
+                disabled=${busy || !code.trim()}
+                onClick=${handleConfirm}
+              >
+                Confirm
+              </button>`
+            : html`<button
+                type="button"
+                class="btn btn-sm"
+                disabled=${busy || !code.trim()}
+                onClick=${handleDisable}
+              >
+                Disable
+              </button>`}
+        </div>
+      `}
+      ${!enrollment &&
+      !status.enabled &&
+      html`
+        <button
+          type="button"
+          class="btn btn-sm"
+          disabled=${busy}
+          onClick=${handleEnroll}
+        >
+          Enable
+        </button>
+      `}
+      ${error &&
+      html`
+        <div role="alert" class="alert alert-error alert-soft text-xs">
+          ${error}
+        </div>
+      `}
+    </div>
+  `;
+}
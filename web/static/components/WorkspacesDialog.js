@@ -121,6 +121,7 @@ export function WorkspacesDialog({ isOpen, onClose, onSave, initialWorkingDir, i
   const [editRunner, setEditRunner] = useState("exec");
   const [editRunnerConfig, setEditRunnerConfig] = useState(null);
   const [editAutoApprove, setEditAutoApprove] = useState(false);
+  const [editWorktree, setEditWorktree] = useState(false);
   const [editIsDefault, setEditIsDefault] = useState(false);
   const [editAcpCommandOverride, setEditAcpCommandOverride] = useState("");
   const [editAutoChildren, setEditAutoChildren] = useState([]);
@@ -324,6 +325,7 @@ export function WorkspacesDialog({ isOpen, onClose, onSave, initialWorkingDir, i
     setEditRunner(selectedWorkspace.restricted_runner || "exec");
     setEditRunnerConfig(selectedWorkspace.restricted_runner_config || null);
     setEditAutoApprove(selectedWorkspace.auto_approve === true);
+    setEditWorktree(selectedWorkspace.worktree === true);
     setEditIsDefault(selectedWorkspace.is_default === true);
     setEffectiveConfig(null);
     setMcpTools(null);
@@ -753,6 +755,7 @@ export function WorkspacesDialog({ isOpen, onClose, onSave, initialWorkingDir, i
       restricted_runner: editRunner,
       restricted_runner_config: editRunner !== "exec" ? editRunnerConfig : undefined,
       auto_approve: editAutoApprove || undefined,
+      worktree: editWorktree || undefined,
       is_default: editIsDefault || undefined,
       acp_command_override: editAcpCommandOverride || undefined,
     };
@@ -2213,6 +2216,18 @@ export function WorkspacesDialog({ isOpen, onClose, onSave, initialWorkingDir, i
                         />
                         <span class="text-sm">Auto-approve tool calls</span>
                       </label>
+                      <label class="flex items-center gap-3 cursor-pointer">
+                        <input
+                          type="checkbox"
+                          checked=${editWorktree}
+                          onChange=${(e) => setEditWorktree(e.target.checked)}
+                          class="checkbox checkbox-sm"
+                        />
+                        <span class="text-sm">Isolate conversations in git worktrees</span>
+                      </label>
+                      <p class="text-xs text-mitto-text-muted -mt-2 ml-7">
+                        Each conversation works on its own branch, which can be merged, rebased or discarded once archived.
+                      </p>
                       <label class="flex items-center gap-3 cursor-pointer">
                         <input
                           type="checkbox"
@@ -107,6 +107,9 @@ const KEEPALIVE_MAX_MISSED_DEFAULT = 2; // Force reconnect after 2 missed keepal
 const KEEPALIVE_MAX_MISSED_LARGE_SESSION = 4; // For sessions with 500+ events
 const LARGE_SESSION_SEQ_THRESHOLD = 500;
 
+// Maximum characters of agent terminal output kept per terminal in session state.
+const MAX_TERMINAL_OUTPUT_CHARS = 64 * 1024;
+
 // Sync tolerance: only request sync if client is more than N sequences behind server.
 // This avoids excessive sync requests during normal streaming where the markdown buffer
 // may hold content briefly before flushing to the UI. A tolerance of 2 prevents
@@ -3121,6 +3124,37 @@ export function useWebSocket({ onActiveSessionRemovedRef } = {}) {
         break;
       }
 
+      case "terminal_output": {
+        // Output (or exit) of a command the agent runs via ACP terminal/create.
+        // Kept in session state only; terminal output is not persisted.
+        const terminalId = msg.data.terminal_id;
+        setSessions((prev) => {
+          const session = prev[sessionId];
+          if (!session) return prev;
+          const terminals = session.terminals || {};
+          const current = terminals[terminalId] || { output: "", exited: false };
+          const output = (current.output + (msg.data.output || "")).slice(
+            -MAX_TERMINAL_OUTPUT_CHARS,
+          );
+          return {
+            ...prev,
+            [sessionId]: {
+              ...session,
+              terminals: {
+                ...terminals,
+                [terminalId]: {
+                  output,
+                  exited: current.exited || !!msg.data.exited,
+                  exit_code: msg.data.exit_code ?? current.exit_code,
+                  signal: msg.data.signal ?? current.signal,
+                },
+              },
+            },
+          };
+        });
+        break;
+      }
+
       case "config_option_changed":
         // Config option changed (by user or agent)
         // Update the current_value for the specified config option in session info